@@ -28,6 +28,16 @@ server:
     # Enable this if you use Claude Code with a subscription
     allow_subscription: true
 
+    # Optional: Per-client virtual keys. key_hash is the SHA-256 hex digest of the key:
+    #   printf '%s' "$KEY" | sha256sum
+    # clients:
+    #   - name: "alice"
+    #     key_hash: "${ALICE_KEY_HASH}"
+    #     allowed_models: ["claude-sonnet"]
+    #     allowed_providers: ["anthropic"]
+    #     budget:
+    #       tokens_per_day: 2000000
+
 # ============================================================================
 # Routing Configuration
 # ============================================================================
@@ -85,6 +95,39 @@ providers:
   #     "claude-sonnet-4-5-20250929": "qwen3:32b"
   #     "claude-haiku-4-5-20251001": "qwen3:8b"
 
+  # --------------------------------------------------------------------------
+  # OpenAI-compatible (Optional - OpenAI, OpenRouter, DeepSeek, Groq, vLLM, LM Studio)
+  # Requests are translated to the Chat Completions API. base_url includes /v1.
+  # Uncomment to enable
+  # --------------------------------------------------------------------------
+  # - name: "deepseek"
+  #   type: "openai"
+  #   enabled: true
+  #   base_url: "https://api.deepseek.com/v1"
+  #
+  #   keys:
+  #     - key: "${DEEPSEEK_API_KEY}"
+  #
+  #   model_mapping:
+  #     "claude-sonnet-4-5-20250929": "deepseek-chat"
+  #     "claude-haiku-4-5-20251001": "deepseek-chat"
+
+  # --------------------------------------------------------------------------
+  # Google Gemini (Optional - Gemini API with an AI Studio key)
+  # Requests are translated to generateContent; thinking and tools are supported.
+  # Uncomment to enable
+  # --------------------------------------------------------------------------
+  # - name: "gemini"
+  #   type: "gemini"
+  #   enabled: true
+  #
+  #   keys:
+  #     - key: "${GEMINI_API_KEY}"
+  #
+  #   model_mapping:
+  #     "claude-sonnet-4-5-20250929": "gemini-2.5-pro"
+  #     "claude-haiku-4-5-20251001": "gemini-2.5-flash"
+
 # ============================================================================
 # gRPC Management API (for TUI/CLI)
 # ============================================================================
@@ -101,10 +144,43 @@ logging:
 # ============================================================================
 # Metrics
 # ============================================================================
+# Prometheus metrics on the proxy listener (no auth, like /health).
 metrics:
   enabled: true
-  prometheus_endpoint: "/metrics"
-  prometheus_listen: "127.0.0.1:9100"
+  path: "/metrics"
+
+# ============================================================================
+# Observability
+# ============================================================================
+# OpenTelemetry trace export over OTLP (grpc or http).
+observability:
+  tracing:
+    enabled: false
+    protocol: "grpc"
+    endpoint: "localhost:4317"
+    insecure: true
+
+# ============================================================================
+# Budgets
+# ============================================================================
+# Optional: Token and spend caps for the whole relay. Client budgets are set
+# per virtual key; usd_per_month budgets need a price per model.
+# budgets:
+#   global:
+#     tokens_per_month: 500000000
+#     usd_per_month: 2000
+#   prices:
+#     claude-sonnet:
+#       input_per_mtok: 3
+#       output_per_mtok: 15
+
+# ============================================================================
+# Admin API
+# ============================================================================
+# Optional: Inspect and control the running relay under /admin.
+# admin:
+#   enabled: true
+#   token: "${CC_RELAY_ADMIN_TOKEN}"
 
 # ============================================================================
 # Cache Configuration
@@ -6,9 +6,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/cache"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 )
 
 const (
@@ -23,7 +26,9 @@ const (
 // Helper functions to create zero-filled config structs for testing.
 func emptyRoutingConfig() config.RoutingConfig {
 	return config.RoutingConfig{
-		ModelMapping: nil, Strategy: "",
+		ModelMapping: nil, QualityFloor: nil, Rules: nil, Strategy: "",
+		SessionAffinity: config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+		Hedging:         config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 		DefaultProvider: "", FailoverTimeout: 0, Debug: false,
 	}
 }
@@ -62,13 +67,77 @@ func emptyCacheConfig() cache.Config {
 	}
 }
 
+func emptyMetricsConfig() config.MetricsConfig {
+	return config.MetricsConfig{Path: "", Enabled: false}
+}
+
+func emptyObservabilityConfig() config.ObservabilityConfig {
+	return config.ObservabilityConfig{
+		Tracing: config.TracingConfig{
+			Headers:     nil,
+			Endpoint:    "",
+			Protocol:    "",
+			ServiceName: "",
+			SampleRatio: 0,
+			Enabled:     false,
+			Insecure:    false,
+		},
+	}
+}
+
+func emptyAdminConfig() config.AdminConfig {
+	return config.AdminConfig{Token: "", Enabled: false}
+}
+
+func emptyResponseCacheConfig() config.ResponseCacheConfig {
+	return config.ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: 0, Enabled: false}
+}
+
+func emptyKeyStateConfig() config.KeyStateConfig {
+	return config.KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: false}
+}
+
+func emptyAccessConfig() config.AccessConfig {
+	return config.AccessConfig{DefaultEffect: "", Policies: nil}
+}
+
+func emptyDLPConfig() dlp.Config {
+	return dlp.Config{Action: "", Detectors: nil, Patterns: nil, MinEntropy: 0}
+}
+
+func emptyRateLimitsConfig() config.RateLimitsConfig {
+	none := ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0}
+	return config.RateLimitsConfig{Clients: none, IPs: none}
+}
+
+func emptyBudgetsConfig() budget.Config {
+	return budget.Config{
+		Prices:           nil,
+		StatePath:        "",
+		Global:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+		SoftLimitPercent: 0,
+	}
+}
+
 func emptyAuthConfig() config.AuthConfig {
 	return config.AuthConfig{
-		APIKey: "", BearerSecret: "",
-		AllowBearer: false, AllowSubscription: false,
+		Clients: nil,
+		APIKey:  "", BearerSecret: "",
+		JWT: emptyJWTConfig(), AllowBearer: false, AllowSubscription: false,
+	}
+}
+
+func emptyJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer: "", JWKSURL: "", JWKSFile: "", ClientClaim: "", GroupsClaim: "",
+		Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
 	}
 }
 
+func emptyTLSConfig() config.TLSConfig {
+	return config.TLSConfig{CertFile: "", KeyFile: "", ClientCAFile: "", ClientAuth: "", MinVersion: ""}
+}
+
 func emptyKeyConfig(key string) config.KeyConfig {
 	return config.KeyConfig{
 		Key: key, RPMLimit: 0, ITPMLimit: 0,
@@ -78,12 +147,12 @@ func emptyKeyConfig(key string) config.KeyConfig {
 
 func emptyProviderConfig() config.ProviderConfig {
 	return config.ProviderConfig{
-		ModelMapping: nil, AWSRegion: "",
+		ModelMapping: nil, Prices: nil, ModelLimits: nil, AWSRegion: "",
 		GCPProjectID: "", AzureAPIVersion: "",
 		Name: "", Type: "", BaseURL: "",
 		AzureDeploymentID: "", AWSAccessKeyID: "",
 		AzureResourceName: "", AWSSecretAccessKey: "",
-		GCPRegion: "", Keys: nil, Models: nil,
+		GCPRegion: "", Trust: "", Keys: nil, Models: nil,
 		Pooling: config.PoolingConfig{Strategy: "", Enabled: false},
 		Enabled: false,
 	}
@@ -99,13 +168,23 @@ func TestValidateConfigValid(t *testing.T) {
 	provider.Keys = []config.KeyConfig{emptyKeyConfig("test-api-key")}
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -124,13 +203,23 @@ func TestValidateConfigNoListen(t *testing.T) {
 	t.Parallel()
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        "",
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -154,13 +243,23 @@ func TestValidateConfigNoAPIKey(t *testing.T) {
 	t.Parallel()
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        "",
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -188,13 +287,23 @@ func TestValidateConfigNoEnabledProvider(t *testing.T) {
 	provider.Enabled = false
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -223,13 +332,23 @@ func TestValidateConfigProviderNoKeys(t *testing.T) {
 	provider.Keys = []config.KeyConfig{}
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -261,13 +380,23 @@ func TestValidateConfigMultipleProviders(t *testing.T) {
 	provider2.Keys = []config.KeyConfig{emptyKeyConfig("key2")}
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
@@ -286,13 +415,23 @@ func TestValidateConfigEmptyProviders(t *testing.T) {
 	t.Parallel()
 
 	cfg := &config.Config{
-		Routing: emptyRoutingConfig(),
-		Logging: emptyLoggingConfig(),
-		Health:  emptyHealthConfig(),
-		Cache:   emptyCacheConfig(),
+		Routing:       emptyRoutingConfig(),
+		Logging:       emptyLoggingConfig(),
+		Health:        emptyHealthConfig(),
+		Cache:         emptyCacheConfig(),
+		Metrics:       emptyMetricsConfig(),
+		Observability: emptyObservabilityConfig(),
+		Budgets:       emptyBudgetsConfig(),
+		Admin:         emptyAdminConfig(),
+		ResponseCache: emptyResponseCacheConfig(),
+		KeyState:      emptyKeyStateConfig(),
+		Access:        emptyAccessConfig(),
+		DLP:           emptyDLPConfig(),
+		RateLimits:    emptyRateLimitsConfig(),
 		Server: config.ServerConfig{
 			Listen:        defaultListenAddr,
 			APIKey:        defaultAPIKey,
+			TLS:           emptyTLSConfig(),
 			Auth:          emptyAuthConfig(),
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
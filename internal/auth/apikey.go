@@ -34,9 +34,10 @@ func (a *APIKeyAuthenticator) Validate(r *http.Request) Result {
 
 	if providedKey == "" {
 		return Result{
-			Valid: false,
-			Type:  TypeAPIKey,
-			Error: "missing x-api-key header",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeAPIKey,
+			Error:  "missing x-api-key header",
 		}
 	}
 
@@ -44,16 +45,18 @@ func (a *APIKeyAuthenticator) Validate(r *http.Request) Result {
 	providedHash := sha256.Sum256([]byte(providedKey))
 	if subtle.ConstantTimeCompare(providedHash[:], a.expectedHash[:]) != 1 {
 		return Result{
-			Valid: false,
-			Type:  TypeAPIKey,
-			Error: "invalid x-api-key",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeAPIKey,
+			Error:  "invalid x-api-key",
 		}
 	}
 
 	return Result{
-		Valid: true,
-		Type:  TypeAPIKey,
-		Error: "",
+		Client: nil,
+		Valid:  true,
+		Type:   TypeAPIKey,
+		Error:  "",
 	}
 }
 
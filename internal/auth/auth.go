@@ -1,6 +1,8 @@
 // Package auth provides authentication mechanisms for cc-relay.
-// It supports multiple authentication methods including API keys and
-// OAuth Bearer tokens used by Claude Code subscriptions.
+// It supports multiple authentication methods including API keys,
+// OAuth Bearer tokens used by Claude Code subscriptions, per-client
+// virtual keys, JWTs issued by an identity provider and mTLS client
+// certificates.
 package auth
 
 import "net/http"
@@ -13,12 +15,21 @@ const (
 	TypeAPIKey Type = "api_key"
 	// TypeBearer represents Authorization: Bearer token authentication.
 	TypeBearer Type = "bearer"
+	// TypeVirtualKey represents a per-client virtual key, sent as x-api-key or Bearer token.
+	TypeVirtualKey Type = "virtual_key"
+	// TypeJWT represents a JWT issued by an identity provider, sent as Bearer token.
+	TypeJWT Type = "jwt"
+	// TypeClientCert represents a client certificate verified by the TLS listener.
+	TypeClientCert Type = "client_cert"
 	// TypeNone represents no authentication or failed auth with no valid type.
 	TypeNone Type = "none"
 )
 
 // Result contains the outcome of an authentication attempt.
 type Result struct {
+	// Client is the identity behind a virtual key, JWT or client certificate.
+	// Nil for shared credentials.
+	Client *Client
 	// Type indicates which authentication method was used (or attempted).
 	Type Type
 	// Error contains the error message if authentication failed.
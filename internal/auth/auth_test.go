@@ -43,6 +43,9 @@ func TestAuthTypes(t *testing.T) {
 	}{
 		{"api_key type", auth.TypeAPIKey, "api_key"},
 		{"bearer type", auth.TypeBearer, "bearer"},
+		{"virtual_key type", auth.TypeVirtualKey, "virtual_key"},
+		{"jwt type", auth.TypeJWT, "jwt"},
+		{"client_cert type", auth.TypeClientCert, "client_cert"},
 		{"none type", auth.TypeNone, "none"},
 	}
 
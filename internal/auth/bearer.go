@@ -41,18 +41,20 @@ func (a *BearerAuthenticator) Validate(r *http.Request) Result {
 
 	if authHeader == "" {
 		return Result{
-			Valid: false,
-			Type:  TypeBearer,
-			Error: "missing authorization header",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeBearer,
+			Error:  "missing authorization header",
 		}
 	}
 
 	// Check for "Bearer " prefix (case insensitive)
 	if len(authHeader) < 7 || !strings.EqualFold(authHeader[:6], "bearer") {
 		return Result{
-			Valid: false,
-			Type:  TypeBearer,
-			Error: "invalid authorization scheme",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeBearer,
+			Error:  "invalid authorization scheme",
 		}
 	}
 
@@ -61,9 +63,10 @@ func (a *BearerAuthenticator) Validate(r *http.Request) Result {
 
 	if token == "" {
 		return Result{
-			Valid: false,
-			Type:  TypeBearer,
-			Error: "empty bearer token",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeBearer,
+			Error:  "empty bearer token",
 		}
 	}
 
@@ -74,17 +77,19 @@ func (a *BearerAuthenticator) Validate(r *http.Request) Result {
 		// CRITICAL: Constant-time comparison prevents timing attacks
 		if subtle.ConstantTimeCompare(tokenHash[:], a.secretHash[:]) != 1 {
 			return Result{
-				Valid: false,
-				Type:  TypeBearer,
-				Error: "invalid bearer token",
+				Client: nil,
+				Valid:  false,
+				Type:   TypeBearer,
+				Error:  "invalid bearer token",
 			}
 		}
 	}
 
 	return Result{
-		Valid: true,
-		Type:  TypeBearer,
-		Error: "",
+		Client: nil,
+		Valid:  true,
+		Type:   TypeBearer,
+		Error:  "",
 	}
 }
 
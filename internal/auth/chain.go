@@ -27,9 +27,10 @@ func (c *ChainAuthenticator) Validate(request *http.Request) Result {
 	// Handle empty chain case
 	if len(c.authenticators) == 0 {
 		return Result{
-			Valid: false,
-			Type:  TypeNone,
-			Error: "no authentication configured",
+			Client: nil,
+			Valid:  false,
+			Type:   TypeNone,
+			Error:  "no authentication configured",
 		}
 	}
 
@@ -42,14 +43,15 @@ func (c *ChainAuthenticator) Validate(request *http.Request) Result {
 			return acc
 		}
 		return auth.Validate(request)
-	}, Result{Valid: false, Type: TypeNone, Error: ""})
+	}, Result{Client: nil, Valid: false, Type: TypeNone, Error: ""})
 
 	// If still not valid, normalize the error response
 	if !result.Valid {
 		return Result{
-			Valid: false,
-			Type:  TypeNone,
-			Error: result.Error,
+			Client: nil,
+			Valid:  false,
+			Type:   TypeNone,
+			Error:  result.Error,
 		}
 	}
 
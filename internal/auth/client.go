@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Client is the identity resolved from a virtual key, JWT or client certificate.
+type Client struct {
+	// Metadata is free-form information about the client, e.g. team or owner.
+	Metadata map[string]string
+	// Name uniquely identifies the client.
+	Name string
+	// AllowedModels are model name prefixes the client may request. Empty allows all.
+	AllowedModels []string
+	// AllowedProviders are the providers the client may be routed to. Empty allows all.
+	AllowedProviders []string
+}
+
+// AllowsModel reports whether the client may request model.
+// Entries match by prefix, so "claude-sonnet" allows "claude-sonnet-4-5".
+func (c *Client) AllowsModel(model string) bool {
+	if c == nil || len(c.AllowedModels) == 0 {
+		return true
+	}
+	for _, prefix := range c.AllowedModels {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsProvider reports whether the client's requests may be routed to the named provider.
+func (c *Client) AllowsProvider(name string) bool {
+	if c == nil || len(c.AllowedProviders) == 0 {
+		return true
+	}
+	return slices.Contains(c.AllowedProviders, name)
+}
+
+type clientContextKey struct{}
+
+// WithClient returns a copy of ctx carrying the authenticated client.
+func WithClient(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// ClientFromContext returns the authenticated client, or nil if the request
+// was not authenticated as a client.
+func ClientFromContext(ctx context.Context) *Client {
+	client, ok := ctx.Value(clientContextKey{}).(*Client)
+	if !ok {
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// ClientCert maps the names a verified client certificate may carry to the
+// client it identifies.
+type ClientCert struct {
+	Client *Client
+	// Names are matched against the certificate's subject common name and its
+	// DNS, email and URI subject alternative names.
+	Names []string
+}
+
+// ClientCertAuthenticator resolves the client certificate of an mTLS
+// connection to a Client. Only certificates the TLS listener verified against
+// its client CAs are considered, so the certificate itself is the credential
+// and no key needs to be sent.
+type ClientCertAuthenticator struct {
+	clients map[string]*Client
+}
+
+// NewClientCertAuthenticator creates an authenticator for the given mappings.
+func NewClientCertAuthenticator(certs []ClientCert) *ClientCertAuthenticator {
+	clients := make(map[string]*Client)
+	for _, cert := range certs {
+		for _, name := range cert.Names {
+			clients[name] = cert.Client
+		}
+	}
+	return &ClientCertAuthenticator{clients: clients}
+}
+
+// Validate returns the client named by the verified client certificate.
+func (a *ClientCertAuthenticator) Validate(r *http.Request) Result {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return clientCertFailure("missing client certificate")
+	}
+
+	for _, name := range certNames(r.TLS.VerifiedChains[0][0]) {
+		if client, ok := a.clients[name]; ok {
+			return Result{
+				Client: client,
+				Valid:  true,
+				Type:   TypeClientCert,
+				Error:  "",
+			}
+		}
+	}
+	return clientCertFailure("client certificate not authorized")
+}
+
+// Type returns the authentication type (client_cert).
+func (a *ClientCertAuthenticator) Type() Type {
+	return TypeClientCert
+}
+
+func clientCertFailure(msg string) Result {
+	return Result{
+		Client: nil,
+		Valid:  false,
+		Type:   TypeClientCert,
+		Error:  msg,
+	}
+}
+
+// certNames returns the identities in a certificate: the subject common name
+// first, then the subject alternative names.
+func certNames(cert *x509.Certificate) []string {
+	names := make([]string, 0, 1+len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs))
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
@@ -0,0 +1,101 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+// withVerifiedCert returns a request as the TLS listener would pass it on after
+// verifying cert.
+func withVerifiedCert(cert *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+// TestClientCertAuthenticatorValidate tests that certificate names resolve to their client.
+func TestClientCertAuthenticatorValidate(t *testing.T) {
+	t.Parallel()
+
+	ci := &auth.Client{Metadata: nil, Name: "ci", AllowedModels: nil, AllowedProviders: nil}
+	deploy := &auth.Client{Metadata: nil, Name: "deploy", AllowedModels: nil, AllowedProviders: nil}
+	authenticator := auth.NewClientCertAuthenticator([]auth.ClientCert{
+		{Client: ci, Names: []string{"ci-runner", "ci@example.com"}},
+		{Client: deploy, Names: []string{"spiffe://example.com/deploy"}},
+	})
+	spiffe, err := url.Parse("spiffe://example.com/deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cert       *x509.Certificate
+		wantClient *auth.Client
+		name       string
+		wantErrMsg string
+		wantValid  bool
+	}{
+		{
+			name:       "common name",
+			cert:       &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner"}},
+			wantClient: ci, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name: "email SAN",
+			cert: &x509.Certificate{
+				Subject: pkix.Name{CommonName: "runner-7"}, EmailAddresses: []string{"ci@example.com"},
+			},
+			wantClient: ci, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name:       "URI SAN",
+			cert:       &x509.Certificate{URIs: []*url.URL{spiffe}},
+			wantClient: deploy, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name: "unknown names",
+			cert: &x509.Certificate{
+				Subject: pkix.Name{CommonName: "laptop"}, DNSNames: []string{"laptop.example.com"},
+			},
+			wantClient: nil, wantValid: false, wantErrMsg: "client certificate not authorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := authenticator.Validate(withVerifiedCert(tt.cert))
+			assertAuthResult(t, result, tt.wantValid, auth.TypeClientCert, tt.wantErrMsg)
+			if result.Client != tt.wantClient {
+				t.Errorf("Client = %v, want %v", result.Client, tt.wantClient)
+			}
+		})
+	}
+}
+
+// TestClientCertAuthenticatorUnverified tests that only verified certificates count.
+func TestClientCertAuthenticatorUnverified(t *testing.T) {
+	t.Parallel()
+
+	ci := &auth.Client{Metadata: nil, Name: "ci", AllowedModels: nil, AllowedProviders: nil}
+	authenticator := auth.NewClientCertAuthenticator([]auth.ClientCert{{Client: ci, Names: []string{"ci-runner"}}})
+
+	plain := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	assertAuthResult(t, authenticator.Validate(plain), false, auth.TypeClientCert, "missing client certificate")
+
+	// A certificate the listener did not verify, e.g. with client_auth optional
+	// and no client CA match, shows up in PeerCertificates only.
+	unverified := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	unverified.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "ci-runner"}}},
+	}
+	assertAuthResult(t, authenticator.Validate(unverified), false, auth.TypeClientCert, "missing client certificate")
+}
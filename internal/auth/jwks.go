@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// jwksMinReload is the least time between two loads of a JWKS, so tokens
+	// signed with unknown keys cannot make the relay hammer the issuer.
+	jwksMinReload = 10 * time.Second
+
+	// jwksFetchTimeout bounds fetching a JWKS from its URL.
+	jwksFetchTimeout = 10 * time.Second
+
+	// jwksMaxBytes is the largest JWKS document read.
+	jwksMaxBytes = 1 << 20
+
+	// minRSAKeyBits is the smallest RSA key accepted for signatures.
+	minRSAKeyBits = 2048
+)
+
+var (
+	errUnknownKey     = errors.New("signing key not found in JWKS")
+	errNoUsableKeys   = errors.New("JWKS contains no usable signing keys")
+	errUnsupportedKey = errors.New("unsupported key")
+)
+
+// jwk is a JSON Web Key (RFC 7517). Only public keys that sign JWTs are used:
+// RSA, EC on the NIST curves, and Ed25519.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key from a JWKS.
+type publicKey struct {
+	key crypto.PublicKey
+	// alg restricts the key to one algorithm when the JWK names one.
+	alg string
+}
+
+// JWKS is a set of keys for verifying JWT signatures, loaded from a file or
+// URL. It is reloaded every refresh interval so rotated keys are picked up,
+// and early when a token names a key it does not know. Scheduled reloads run
+// in the background while the loaded keys keep serving requests.
+type JWKS struct {
+	load        func(ctx context.Context) ([]byte, error)
+	keys        map[string]publicKey
+	loadedAt    time.Time
+	attemptedAt time.Time
+	err         error
+	source      string
+	refresh     time.Duration
+	mu          sync.Mutex // Protects the fields above
+	loading     sync.Mutex // Serializes loads
+}
+
+// NewFileJWKS creates a JWKS read from a local file, for issuers that cannot
+// be reached from the relay.
+func NewFileJWKS(path string, refresh time.Duration) *JWKS {
+	path = filepath.Clean(path)
+	return newJWKS(path, refresh, func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// NewURLJWKS creates a JWKS fetched from an issuer's jwks_uri.
+func NewURLJWKS(url string, refresh time.Duration) *JWKS {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	return newJWKS(url, refresh, func(ctx context.Context) ([]byte, error) {
+		return fetchJWKS(ctx, client, url)
+	})
+}
+
+func newJWKS(source string, refresh time.Duration, load func(ctx context.Context) ([]byte, error)) *JWKS {
+	return &JWKS{
+		load:        load,
+		keys:        nil,
+		loadedAt:    time.Time{},
+		attemptedAt: time.Time{},
+		err:         nil,
+		source:      source,
+		refresh:     refresh,
+		mu:          sync.Mutex{},
+		loading:     sync.Mutex{},
+	}
+}
+
+func fetchJWKS(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	// The fetch outlives a client that gives up: the keys serve every request.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr // Body is fully read
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, jwksMaxBytes))
+}
+
+// key returns the key with the given ID. A known key is returned at once,
+// starting a background reload when the set is due for one. Requests only
+// wait for a load when no keys have loaded yet or the key is unknown, and
+// then share a single load. A failed reload keeps the previous keys.
+func (s *JWKS) key(ctx context.Context, kid string) (publicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, found := s.lookup(kid)
+	if found && s.due(now, true) {
+		s.attemptedAt = now
+		go s.reload(context.WithoutCancel(ctx), now)
+	}
+	s.mu.Unlock()
+	if found {
+		return key, nil
+	}
+	return s.loadKey(ctx, kid)
+}
+
+// loadKey reloads the set unless that was attempted too recently, and then
+// looks the key up again.
+func (s *JWKS) loadKey(ctx context.Context, kid string) (publicKey, error) {
+	s.loading.Lock()
+	defer s.loading.Unlock()
+
+	// A load finished while waiting may already hold the key
+	s.mu.Lock()
+	now := time.Now()
+	_, found := s.lookup(kid)
+	reload := !found && s.due(now, false)
+	if reload {
+		s.attemptedAt = now
+	}
+	s.mu.Unlock()
+	if reload {
+		s.store(ctx, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if s.keys == nil {
+		return publicKey{key: nil, alg: ""}, fmt.Errorf("JWKS unavailable: %w", s.err)
+	}
+	return publicKey{key: nil, alg: ""}, errUnknownKey
+}
+
+// due reports whether the set should be reloaded: every refresh interval,
+// or early for an unknown key, but never twice within jwksMinReload.
+// The caller must hold s.mu.
+func (s *JWKS) due(now time.Time, found bool) bool {
+	sinceLoad := now.Sub(s.loadedAt)
+	stale := sinceLoad >= s.refresh || (!found && sinceLoad >= jwksMinReload)
+	return stale && now.Sub(s.attemptedAt) >= jwksMinReload
+}
+
+// reload loads the set in the background.
+func (s *JWKS) reload(ctx context.Context, now time.Time) {
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	s.store(ctx, now)
+}
+
+// store loads the set and replaces the keys with it. The caller must hold
+// s.loading.
+func (s *JWKS) store(ctx context.Context, now time.Time) {
+	data, err := s.load(ctx)
+	var keys map[string]publicKey
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = err
+		log.Ctx(ctx).Warn().Err(err).Str("jwks", s.source).Msg("failed to load JWKS")
+		return
+	}
+	s.keys, s.loadedAt, s.err = keys, now, nil
+}
+
+// lookup finds a key by ID. A token without a key ID is accepted by a set
+// holding a single key.
+func (s *JWKS) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// parseJWKS returns the signing keys of a JWKS document by key ID. Keys of
+// other types or uses are skipped.
+func parseJWKS(data []byte) (map[string]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for idx := range set.Keys {
+		entry := &set.Keys[idx]
+		if entry.Use != "" && entry.Use != "sig" {
+			continue
+		}
+		key, err := entry.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", entry.Kid, err)
+		}
+		keys[entry.Kid] = publicKey{key: key, alg: entry.Alg}
+	}
+	if len(keys) == 0 {
+		return nil, errNoUsableKeys
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		return k.rsaKey()
+	case "EC":
+		return k.ecKey()
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+func (k *jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	modulus := new(big.Int).SetBytes(n)
+	if modulus.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+}
+
+func (k *jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errUnsupportedKey
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	x, errX := base64.RawURLEncoding.DecodeString(k.X)
+	y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+	if errX != nil || errY != nil || len(x) != size || len(y) != size {
+		return nil, errors.New("invalid EC point")
+	}
+	point := make([]byte, 0, 1+2*size)
+	point = append(point, 4) // Uncompressed point
+	point = append(point, x...)
+	point = append(point, y...)
+	return ecdsa.ParseUncompressedPublicKey(curve, point)
+}
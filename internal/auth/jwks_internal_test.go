@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// ed25519JWKS returns a JWKS document holding a new Ed25519 key for each kid.
+func ed25519JWKS(t *testing.T, kids ...string) []byte {
+	t.Helper()
+	keys := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		public, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		keys = append(keys, map[string]string{
+			"kty": "OKP", "crv": "Ed25519", "kid": kid, "x": base64.RawURLEncoding.EncodeToString(public),
+		})
+	}
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("marshal JWKS: %v", err)
+	}
+	return data
+}
+
+// blockingLoader serves JWKS documents sent on docs, one per load.
+type blockingLoader struct {
+	docs  chan []byte
+	loads chan struct{}
+}
+
+func newBlockingLoader() *blockingLoader {
+	return &blockingLoader{docs: make(chan []byte, 1), loads: make(chan struct{}, 4)}
+}
+
+func (l *blockingLoader) load(ctx context.Context) ([]byte, error) {
+	l.loads <- struct{}{}
+	select {
+	case doc := <-l.docs:
+		return doc, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// age moves the set's last load and attempt into the past.
+func (s *JWKS) age(by time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = s.loadedAt.Add(-by)
+	s.attemptedAt = s.attemptedAt.Add(-by)
+}
+
+func TestJWKSRefreshesInBackground(t *testing.T) {
+	t.Parallel()
+
+	loader := newBlockingLoader()
+	set := newJWKS("test", time.Hour, loader.load)
+	ctx := context.Background()
+
+	loader.docs <- ed25519JWKS(t, "old")
+	if _, err := set.key(ctx, "old"); err != nil {
+		t.Fatalf("key(old) on first load error = %v", err)
+	}
+	<-loader.loads
+
+	// A due refresh does not hold up requests for known keys
+	set.age(2 * time.Hour)
+	start := time.Now()
+	if _, err := set.key(ctx, "old"); err != nil {
+		t.Fatalf("key(old) while refreshing error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("key(old) waited %v for the refresh", elapsed)
+	}
+	<-loader.loads
+	if _, err := set.key(ctx, "old"); err != nil {
+		t.Errorf("key(old) during the refresh error = %v", err)
+	}
+
+	loader.docs <- ed25519JWKS(t, "new")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := set.key(ctx, "new"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("key(new) not found after the background refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(loader.loads) != 0 {
+		t.Errorf("%d extra loads, want the background refresh only", len(loader.loads))
+	}
+}
+
+func TestJWKSLoadsForUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	loader := newBlockingLoader()
+	set := newJWKS("test", time.Hour, loader.load)
+	ctx := context.Background()
+
+	loader.docs <- ed25519JWKS(t, "old")
+	if _, err := set.key(ctx, "old"); err != nil {
+		t.Fatalf("key(old) error = %v", err)
+	}
+	<-loader.loads
+
+	// Unknown keys wait for a reload, but at most one per jwksMinReload
+	if _, err := set.key(ctx, "rotated"); !errors.Is(err, errUnknownKey) {
+		t.Errorf("key(rotated) right after a load error = %v, want errUnknownKey", err)
+	}
+	set.age(jwksMinReload)
+	loader.docs <- ed25519JWKS(t, "old", "rotated")
+	if _, err := set.key(ctx, "rotated"); err != nil {
+		t.Errorf("key(rotated) after reloading error = %v", err)
+	}
+	<-loader.loads
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTGroup grants the permissions of a client to tokens that list the group
+// in their groups claim.
+type JWTGroup struct {
+	// Name is the group as it appears in the token.
+	Name string
+	// AllowedModels are model name prefixes members may request. Empty allows all.
+	AllowedModels []string
+	// AllowedProviders are the providers members may be routed to. Empty allows all.
+	AllowedProviders []string
+}
+
+// JWTOptions configure a JWTAuthenticator.
+type JWTOptions struct {
+	// Keys verify token signatures.
+	Keys *JWKS
+	// Issuer is the required iss claim. Empty accepts any issuer.
+	Issuer string
+	// ClientClaim is the claim naming the client, usually sub.
+	ClientClaim string
+	// GroupsClaim is the claim listing the client's groups.
+	GroupsClaim string
+	// Audiences are accepted aud values; a token must carry one of them.
+	// Empty accepts any audience.
+	Audiences []string
+	// Groups map token groups to permissions. When set, a token must be in at
+	// least one of them. Empty gives every valid token full access.
+	Groups []JWTGroup
+	// Leeway is the clock skew tolerated when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// JWTAuthenticator accepts JWTs signed by an identity provider, such as
+// SSO-issued OIDC tokens, and resolves them to a Client named by a claim.
+// Like virtual keys, the token may be sent as Bearer token or x-api-key.
+type JWTAuthenticator struct {
+	groups map[string]*JWTGroup
+	opts   JWTOptions
+}
+
+// NewJWTAuthenticator creates a JWT authenticator.
+func NewJWTAuthenticator(opts JWTOptions) *JWTAuthenticator {
+	groups := make(map[string]*JWTGroup, len(opts.Groups))
+	for idx := range opts.Groups {
+		groups[opts.Groups[idx].Name] = &opts.Groups[idx]
+	}
+	return &JWTAuthenticator{groups: groups, opts: opts}
+}
+
+// Validate verifies the presented JWT and returns the client it identifies.
+func (a *JWTAuthenticator) Validate(r *http.Request) Result {
+	token := presentedKey(r)
+	if token == "" {
+		return jwtFailure("missing bearer token")
+	}
+	// Anything else is left to authenticators of opaque tokens.
+	if strings.Count(token, ".") != 2 {
+		return jwtFailure("bearer token is not a JWT")
+	}
+
+	claims, err := a.verify(r.Context(), token)
+	if err != nil {
+		return jwtFailure("invalid JWT: " + err.Error())
+	}
+	client, err := a.client(claims)
+	if err != nil {
+		return jwtFailure("JWT not permitted: " + err.Error())
+	}
+
+	return Result{
+		Client: client,
+		Valid:  true,
+		Type:   TypeJWT,
+		Error:  "",
+	}
+}
+
+// Type returns the authentication type (jwt).
+func (a *JWTAuthenticator) Type() Type {
+	return TypeJWT
+}
+
+func jwtFailure(msg string) Result {
+	return Result{
+		Client: nil,
+		Valid:  false,
+		Type:   TypeJWT,
+		Error:  msg,
+	}
+}
+
+type jwtHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+// verify checks the token's signature and registered claims and returns its claims.
+func (a *JWTAuthenticator) verify(ctx context.Context, token string) (map[string]any, error) {
+	headerPart, rest, _ := strings.Cut(token, ".")
+	payloadPart, signaturePart, _ := strings.Cut(rest, ".")
+
+	var header jwtHeader
+	if err := decodeJWTPart(headerPart, &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	if len(header.Crit) > 0 {
+		return nil, fmt.Errorf("unsupported critical header %q", header.Crit[0])
+	}
+	algorithm, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	key, err := a.opts.Keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, fmt.Errorf("key %q is not for algorithm %s", header.Kid, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signaturePart)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	if err = algorithm.verify(key.key, []byte(headerPart+"."+payloadPart), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err = decodeJWTPart(payloadPart, &claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if err = a.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, dst any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// checkClaims checks expiry, issuer and audience. Tokens must expire.
+func (a *JWTAuthenticator) checkClaims(claims map[string]any, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing exp claim")
+	}
+	if now.Add(-a.opts.Leeway).After(time.Unix(int64(exp), 0)) {
+		return errors.New("token expired")
+	}
+	if nbf, hasNbf := claims["nbf"].(float64); hasNbf && now.Add(a.opts.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if a.opts.Issuer != "" && claims["iss"] != a.opts.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if len(a.opts.Audiences) > 0 {
+		audiences := claimStrings(claims["aud"])
+		if !slices.ContainsFunc(a.opts.Audiences, func(aud string) bool { return slices.Contains(audiences, aud) }) {
+			return errors.New("unexpected audience")
+		}
+	}
+	return nil
+}
+
+// client maps the token's claims to a client and its permissions.
+func (a *JWTAuthenticator) client(claims map[string]any) (*Client, error) {
+	name, ok := claims[a.opts.ClientClaim].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("missing %s claim", a.opts.ClientClaim)
+	}
+	client := &Client{
+		Metadata:         nil,
+		Name:             name,
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+	}
+	if len(a.groups) == 0 {
+		return client, nil
+	}
+	if err := a.grantGroups(client, claimStrings(claims[a.opts.GroupsClaim])); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// grantGroups gives client the permissions of the configured groups it is a
+// member of. A member of several groups gets the permissions of all of them.
+func (a *JWTAuthenticator) grantGroups(client *Client, groupNames []string) error {
+	var matched []string
+	allModels, allProviders := false, false
+	for _, groupName := range groupNames {
+		group, found := a.groups[groupName]
+		if !found || slices.Contains(matched, groupName) {
+			continue
+		}
+		matched = append(matched, groupName)
+		allModels = allModels || len(group.AllowedModels) == 0
+		allProviders = allProviders || len(group.AllowedProviders) == 0
+		client.AllowedModels = append(client.AllowedModels, group.AllowedModels...)
+		client.AllowedProviders = append(client.AllowedProviders, group.AllowedProviders...)
+	}
+	if len(matched) == 0 {
+		return errors.New("token is in no configured group")
+	}
+
+	if allModels {
+		client.AllowedModels = nil
+	}
+	if allProviders {
+		client.AllowedProviders = nil
+	}
+	client.Metadata = map[string]string{"groups": strings.Join(matched, ",")}
+	return nil
+}
+
+// claimStrings reads a claim holding a string or a list of strings. A string
+// may list several values separated by spaces, like the scope claim.
+func claimStrings(value any) []string {
+	switch typed := value.(type) {
+	case string:
+		return strings.Fields(typed)
+	case []any:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// jwtAlgorithm verifies signatures of one JWS algorithm (RFC 7518).
+type jwtAlgorithm struct {
+	verify func(key crypto.PublicKey, input, signature []byte) error
+}
+
+// jwtAlgorithms are the accepted asymmetric algorithms. Symmetric HS* and
+// "none" are rejected: a JWKS publishes public keys only.
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"RS256": {verify: verifyPKCS1v15(crypto.SHA256)},
+	"RS384": {verify: verifyPKCS1v15(crypto.SHA384)},
+	"RS512": {verify: verifyPKCS1v15(crypto.SHA512)},
+	"PS256": {verify: verifyPSS(crypto.SHA256)},
+	"PS384": {verify: verifyPSS(crypto.SHA384)},
+	"PS512": {verify: verifyPSS(crypto.SHA512)},
+	"ES256": {verify: verifyECDSA(crypto.SHA256, elliptic.P256())},
+	"ES384": {verify: verifyECDSA(crypto.SHA384, elliptic.P384())},
+	"ES512": {verify: verifyECDSA(crypto.SHA512, elliptic.P521())},
+	"EdDSA": {verify: verifyEd25519},
+}
+
+var (
+	errKeyType          = errors.New("key type does not match algorithm")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+func digest(hash crypto.Hash, input []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(input)
+	return hasher.Sum(nil)
+}
+
+func verifyPKCS1v15(hash crypto.Hash) func(crypto.PublicKey, []byte, []byte) error {
+	return func(key crypto.PublicKey, input, signature []byte) error {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		if rsa.VerifyPKCS1v15(rsaKey, hash, digest(hash, input), signature) != nil {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+func verifyPSS(hash crypto.Hash) func(crypto.PublicKey, []byte, []byte) error {
+	return func(key crypto.PublicKey, input, signature []byte) error {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKeyType
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+		if rsa.VerifyPSS(rsaKey, hash, digest(hash, input), signature, opts) != nil {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+// verifyECDSA verifies a signature encoded as R || S, each padded to the
+// curve size.
+func verifyECDSA(hash crypto.Hash, curve elliptic.Curve) func(crypto.PublicKey, []byte, []byte) error {
+	size := (curve.Params().BitSize + 7) / 8
+	return func(key crypto.PublicKey, input, signature []byte) error {
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != curve {
+			return errKeyType
+		}
+		if len(signature) != 2*size {
+			return errInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest(hash, input), r, s) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+}
+
+func verifyEd25519(key crypto.PublicKey, input, signature []byte) error {
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return errKeyType
+	}
+	if !ed25519.Verify(edKey, input, signature) {
+		return errInvalidSignature
+	}
+	return nil
+}
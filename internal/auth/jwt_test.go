@@ -0,0 +1,456 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "cc-relay"
+)
+
+// testSigner signs tokens with one key and describes it as a JWK.
+type testSigner struct {
+	sign func(input []byte) ([]byte, error)
+	jwk  map[string]string
+	alg  string
+	kid  string
+}
+
+type testSigners struct {
+	rs256, ps256, es256, eddsa, impostor *testSigner
+}
+
+// signers generates the test keys once; RSA key generation is slow.
+var signers = sync.OnceValue(func() testSigners {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	return testSigners{
+		rs256:    rsaSigner("rsa-1", "RS256", rsaKey, false),
+		ps256:    rsaSigner("rsa-1", "PS256", rsaKey, true),
+		es256:    ecSigner("ec-1", ecKey),
+		eddsa:    edSigner("ed-1", edPublic, edPrivate),
+		impostor: rsaSigner("rsa-1", "RS256", otherRSAKey, false),
+	}
+})
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaSigner(kid, alg string, key *rsa.PrivateKey, pss bool) *testSigner {
+	return &testSigner{
+		sign: func(input []byte) ([]byte, error) {
+			digest := sha256.Sum256(input)
+			if pss {
+				opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+				return rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], opts)
+			}
+			return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		},
+		jwk: map[string]string{
+			"kty": "RSA", "kid": kid,
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		},
+		alg: alg,
+		kid: kid,
+	}
+}
+
+func ecSigner(kid string, key *ecdsa.PrivateKey) *testSigner {
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return &testSigner{
+		sign: func(input []byte) ([]byte, error) {
+			digest := sha256.Sum256(input)
+			r, s, signErr := ecdsa.Sign(rand.Reader, key, digest[:])
+			if signErr != nil {
+				return nil, signErr
+			}
+			return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...), nil
+		},
+		jwk: map[string]string{
+			"kty": "EC", "kid": kid, "crv": "P-256", "alg": "ES256", "use": "sig",
+			"x": b64(point[1:33]), "y": b64(point[33:]),
+		},
+		alg: "ES256",
+		kid: kid,
+	}
+}
+
+func edSigner(kid string, public ed25519.PublicKey, private ed25519.PrivateKey) *testSigner {
+	return &testSigner{
+		sign: func(input []byte) ([]byte, error) {
+			return ed25519.Sign(private, input), nil
+		},
+		jwk: map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(public)},
+		alg: "EdDSA",
+		kid: kid,
+	}
+}
+
+func (s *testSigner) token(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	return signedToken(t, map[string]any{"alg": s.alg, "kid": s.kid, "typ": "JWT"}, claims, s.sign)
+}
+
+func signedToken(
+	t *testing.T,
+	header, claims map[string]any,
+	sign func(input []byte) ([]byte, error),
+) string {
+	t.Helper()
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	input := b64(headerJSON) + "." + b64(claimsJSON)
+	signature, err := sign([]byte(input))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return input + "." + b64(signature)
+}
+
+func jwksDocument(t *testing.T, keys ...*testSigner) []byte {
+	t.Helper()
+	jwks := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		jwks = append(jwks, key.jwk)
+	}
+	data, err := json.Marshal(map[string]any{"keys": jwks})
+	if err != nil {
+		t.Fatalf("marshal JWKS: %v", err)
+	}
+	return data
+}
+
+func writeJWKS(t *testing.T, keys ...*testSigner) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, keys...), 0o600); err != nil {
+		t.Fatalf("write JWKS: %v", err)
+	}
+	return path
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":    "alice@example.com",
+		"iss":    testIssuer,
+		"aud":    testAudience,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"ml"},
+	}
+}
+
+func newJWTAuthenticator(keys *auth.JWKS, groups []auth.JWTGroup) *auth.JWTAuthenticator {
+	return auth.NewJWTAuthenticator(auth.JWTOptions{
+		Keys:        keys,
+		Issuer:      testIssuer,
+		ClientClaim: "sub",
+		GroupsClaim: "groups",
+		Audiences:   []string{testAudience},
+		Groups:      groups,
+		Leeway:      time.Minute,
+	})
+}
+
+func jwtRequest(token string) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// TestJWTAuthenticatorAlgorithms verifies tokens signed with every supported key type.
+func TestJWTAuthenticatorAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	keys := signers()
+	jwks := auth.NewFileJWKS(writeJWKS(t, keys.rs256, keys.es256, keys.eddsa), time.Hour)
+	authenticator := newJWTAuthenticator(jwks, nil)
+
+	for _, signer := range []*testSigner{keys.rs256, keys.ps256, keys.es256, keys.eddsa} {
+		t.Run(signer.alg, func(t *testing.T) {
+			t.Parallel()
+
+			result := authenticator.Validate(jwtRequest(signer.token(t, validClaims())))
+			assertAuthResult(t, result, true, auth.TypeJWT, "")
+			if result.Client == nil || result.Client.Name != "alice@example.com" {
+				t.Errorf("Client = %v, want alice@example.com", result.Client)
+			}
+		})
+	}
+}
+
+// TestJWTAuthenticatorRejects verifies signature and claim checks.
+func TestJWTAuthenticatorRejects(t *testing.T) {
+	t.Parallel()
+
+	keys := signers()
+	authenticator := newJWTAuthenticator(auth.NewFileJWKS(writeJWKS(t, keys.rs256, keys.es256), time.Hour), nil)
+
+	withClaim := func(name string, value any) func(t *testing.T) string {
+		return func(t *testing.T) string {
+			t.Helper()
+			claims := validClaims()
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+			return keys.rs256.token(t, claims)
+		}
+	}
+
+	tests := []struct {
+		token      func(t *testing.T) string
+		name       string
+		wantErrMsg string
+		wantValid  bool
+	}{
+		{
+			name: "expired", token: withClaim("exp", time.Now().Add(-time.Hour).Unix()),
+			wantErrMsg: "invalid JWT: token expired", wantValid: false,
+		},
+		{
+			name: "expired within leeway", token: withClaim("exp", time.Now().Add(-30*time.Second).Unix()),
+			wantErrMsg: "", wantValid: true,
+		},
+		{
+			name: "not valid yet", token: withClaim("nbf", time.Now().Add(time.Hour).Unix()),
+			wantErrMsg: "invalid JWT: token not valid yet", wantValid: false,
+		},
+		{
+			name: "without expiry", token: withClaim("exp", nil),
+			wantErrMsg: "invalid JWT: missing exp claim", wantValid: false,
+		},
+		{
+			name: "other issuer", token: withClaim("iss", "https://evil.example.com"),
+			wantErrMsg: "invalid JWT: unexpected issuer", wantValid: false,
+		},
+		{
+			name: "other audience", token: withClaim("aud", "another-app"),
+			wantErrMsg: "invalid JWT: unexpected audience", wantValid: false,
+		},
+		{
+			name: "audience list", token: withClaim("aud", []string{"another-app", testAudience}),
+			wantErrMsg: "", wantValid: true,
+		},
+		{
+			name: "without subject", token: withClaim("sub", nil),
+			wantErrMsg: "JWT not permitted: missing sub claim", wantValid: false,
+		},
+		{
+			name: "signed with another key", token: func(t *testing.T) string {
+				t.Helper()
+				return keys.impostor.token(t, validClaims())
+			},
+			wantErrMsg: "invalid JWT: invalid signature", wantValid: false,
+		},
+		{
+			name: "unknown key", token: func(t *testing.T) string {
+				t.Helper()
+				return keys.eddsa.token(t, validClaims())
+			},
+			wantErrMsg: "invalid JWT: signing key not found in JWKS", wantValid: false,
+		},
+		{
+			name: "algorithm the key is not for", token: func(t *testing.T) string {
+				t.Helper()
+				header := map[string]any{"alg": "ES384", "kid": keys.es256.kid}
+				return signedToken(t, header, validClaims(), keys.es256.sign)
+			},
+			wantErrMsg: `invalid JWT: key "ec-1" is not for algorithm ES384`, wantValid: false,
+		},
+		{
+			name: "unsigned", token: func(t *testing.T) string {
+				t.Helper()
+				header := map[string]any{"alg": "none", "kid": keys.rs256.kid}
+				return signedToken(t, header, validClaims(), func([]byte) ([]byte, error) { return nil, nil })
+			},
+			wantErrMsg: `invalid JWT: unsupported algorithm "none"`, wantValid: false,
+		},
+		{
+			name: "opaque token", token: func(*testing.T) string { return "sk-ant-oat01-opaque" },
+			wantErrMsg: "bearer token is not a JWT", wantValid: false,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := authenticator.Validate(jwtRequest(testCase.token(t)))
+			assertAuthResult(t, result, testCase.wantValid, auth.TypeJWT, testCase.wantErrMsg)
+		})
+	}
+}
+
+// TestJWTAuthenticatorGroups verifies that groups grant their permissions.
+func TestJWTAuthenticatorGroups(t *testing.T) {
+	t.Parallel()
+
+	keys := signers()
+	authenticator := newJWTAuthenticator(auth.NewFileJWKS(writeJWKS(t, keys.eddsa), time.Hour), []auth.JWTGroup{
+		{Name: "ml", AllowedModels: []string{"claude-sonnet"}, AllowedProviders: []string{"anthropic"}},
+		{Name: "eng", AllowedModels: []string{"claude-haiku"}, AllowedProviders: nil},
+		{Name: "admins", AllowedModels: nil, AllowedProviders: nil},
+	})
+
+	tests := []struct {
+		groups        any
+		name          string
+		wantErrMsg    string
+		wantGroups    string
+		wantModels    []string
+		wantProviders []string
+	}{
+		{
+			name: "one group", groups: []string{"ml", "unmapped"}, wantErrMsg: "", wantGroups: "ml",
+			wantModels: []string{"claude-sonnet"}, wantProviders: []string{"anthropic"},
+		},
+		{
+			name: "permissions add up", groups: []string{"ml", "eng"}, wantErrMsg: "", wantGroups: "ml,eng",
+			wantModels: []string{"claude-sonnet", "claude-haiku"}, wantProviders: nil,
+		},
+		{
+			name: "unrestricted group", groups: []string{"ml", "admins"}, wantErrMsg: "", wantGroups: "ml,admins",
+			wantModels: nil, wantProviders: nil,
+		},
+		{
+			name: "space separated", groups: "eng ml", wantErrMsg: "", wantGroups: "eng,ml",
+			wantModels: []string{"claude-haiku", "claude-sonnet"}, wantProviders: nil,
+		},
+		{
+			name: "no configured group", groups: []string{"sales"}, wantGroups: "",
+			wantErrMsg: "JWT not permitted: token is in no configured group", wantModels: nil, wantProviders: nil,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims := validClaims()
+			claims["groups"] = testCase.groups
+			result := authenticator.Validate(jwtRequest(keys.eddsa.token(t, claims)))
+			assertAuthResult(t, result, testCase.wantErrMsg == "", auth.TypeJWT, testCase.wantErrMsg)
+			if result.Client == nil {
+				return
+			}
+			if got := result.Client.Metadata["groups"]; got != testCase.wantGroups {
+				t.Errorf("groups metadata = %q, want %q", got, testCase.wantGroups)
+			}
+			if !slices.Equal(result.Client.AllowedModels, testCase.wantModels) {
+				t.Errorf("AllowedModels = %v, want %v", result.Client.AllowedModels, testCase.wantModels)
+			}
+			if !slices.Equal(result.Client.AllowedProviders, testCase.wantProviders) {
+				t.Errorf("AllowedProviders = %v, want %v", result.Client.AllowedProviders, testCase.wantProviders)
+			}
+		})
+	}
+}
+
+// TestURLJWKS verifies keys are fetched once and reused until refresh.
+func TestURLJWKS(t *testing.T) {
+	t.Parallel()
+
+	keys := signers()
+	document := jwksDocument(t, keys.es256)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		if _, err := writer.Write(document); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	authenticator := newJWTAuthenticator(auth.NewURLJWKS(server.URL, time.Hour), nil)
+	for range 3 {
+		result := authenticator.Validate(jwtRequest(keys.es256.token(t, validClaims())))
+		assertAuthResult(t, result, true, auth.TypeJWT, "")
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+// TestURLJWKSUnavailable verifies an unreachable JWKS rejects tokens.
+func TestURLJWKSUnavailable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	authenticator := newJWTAuthenticator(auth.NewURLJWKS(server.URL, time.Hour), nil)
+	result := authenticator.Validate(jwtRequest(signers().es256.token(t, validClaims())))
+	assertAuthResult(t, result, false, auth.TypeJWT, "invalid JWT: JWKS unavailable: unexpected status 500")
+}
+
+// TestChainAuthenticatorJWTBeforeBearer verifies JWTs resolve to a client
+// while opaque tokens still reach bearer passthrough.
+func TestChainAuthenticatorJWTBeforeBearer(t *testing.T) {
+	t.Parallel()
+
+	keys := signers()
+	jwtAuth := newJWTAuthenticator(auth.NewFileJWKS(writeJWKS(t, keys.eddsa), time.Hour), nil)
+	chainAuth := auth.NewChainAuthenticator(jwtAuth, auth.NewBearerAuthenticator(""))
+
+	result := chainAuth.Validate(jwtRequest(keys.eddsa.token(t, validClaims())))
+	assertAuthResult(t, result, true, auth.TypeJWT, "")
+	if result.Client == nil {
+		t.Error("Client = nil, want the token's client")
+	}
+
+	result = chainAuth.Validate(jwtRequest("sk-ant-oat01-subscription"))
+	assertAuthResult(t, result, true, auth.TypeBearer, "")
+	if result.Client != nil {
+		t.Errorf("Client = %v, want nil for passthrough", result.Client)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// VirtualKey is a client key as stored in config: the client it identifies and
+// the hex-encoded SHA-256 hash of the key. The key itself is never stored.
+type VirtualKey struct {
+	Client  *Client
+	KeyHash string
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of key, the form virtual keys
+// are configured in.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// VirtualKeyAuthenticator resolves per-client virtual keys to a Client.
+// The key may be sent as x-api-key or as an Authorization: Bearer token, so
+// both Claude Code API key and auth token setups work unchanged.
+type VirtualKeyAuthenticator struct {
+	clients map[[sha256.Size]byte]*Client
+}
+
+// NewVirtualKeyAuthenticator creates an authenticator for the given keys.
+// Keys whose hash is not a valid hex-encoded SHA-256 digest are skipped;
+// config validation rejects them before they get here.
+//
+// Lookup is by hash of the presented key, so a timing difference reveals
+// nothing an attacker can use to guess a key.
+func NewVirtualKeyAuthenticator(keys []VirtualKey) *VirtualKeyAuthenticator {
+	clients := make(map[[sha256.Size]byte]*Client, len(keys))
+	for _, key := range keys {
+		decoded, err := hex.DecodeString(key.KeyHash)
+		if err != nil || len(decoded) != sha256.Size {
+			continue
+		}
+		clients[[sha256.Size]byte(decoded)] = key.Client
+	}
+	return &VirtualKeyAuthenticator{clients: clients}
+}
+
+// Validate looks up the presented key and returns the matching client.
+func (a *VirtualKeyAuthenticator) Validate(r *http.Request) Result {
+	key := presentedKey(r)
+	if key == "" {
+		return Result{
+			Client: nil,
+			Valid:  false,
+			Type:   TypeVirtualKey,
+			Error:  "missing x-api-key header or bearer token",
+		}
+	}
+
+	client, ok := a.clients[sha256.Sum256([]byte(key))]
+	if !ok {
+		return Result{
+			Client: nil,
+			Valid:  false,
+			Type:   TypeVirtualKey,
+			Error:  "invalid virtual key",
+		}
+	}
+
+	return Result{
+		Client: client,
+		Valid:  true,
+		Type:   TypeVirtualKey,
+		Error:  "",
+	}
+}
+
+// Type returns the authentication type (virtual_key).
+func (a *VirtualKeyAuthenticator) Type() Type {
+	return TypeVirtualKey
+}
+
+// presentedKey returns the x-api-key header, or the Bearer token if there is none.
+func presentedKey(r *http.Request) string {
+	if key := r.Header.Get("x-api-key"); key != "" {
+		return key
+	}
+	authHeader := r.Header.Get("Authorization")
+	if len(authHeader) < 7 || !strings.EqualFold(authHeader[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(authHeader[7:])
+}
@@ -0,0 +1,189 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+const (
+	aliceKey = "sk-relay-alice-0123456789"
+	bobKey   = "sk-relay-bob-9876543210"
+)
+
+func newVirtualKeyAuthenticator() (*auth.VirtualKeyAuthenticator, *auth.Client, *auth.Client) {
+	alice := &auth.Client{
+		Metadata:         map[string]string{"team": "platform"},
+		Name:             "alice",
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+	}
+	bob := &auth.Client{
+		Metadata:         nil,
+		Name:             "bob",
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+	}
+	return auth.NewVirtualKeyAuthenticator([]auth.VirtualKey{
+		{Client: alice, KeyHash: auth.HashKey(aliceKey)},
+		{Client: bob, KeyHash: auth.HashKey(bobKey)},
+		{Client: bob, KeyHash: "not-a-hash"},
+	}), alice, bob
+}
+
+// TestVirtualKeyAuthenticatorValidate tests that virtual keys resolve to their client.
+func TestVirtualKeyAuthenticatorValidate(t *testing.T) {
+	t.Parallel()
+
+	authenticator, alice, bob := newVirtualKeyAuthenticator()
+
+	tests := []struct {
+		wantClient *auth.Client
+		name       string
+		apiKey     string
+		authHeader string
+		wantErrMsg string
+		wantValid  bool
+	}{
+		{
+			name: "x-api-key", apiKey: aliceKey, authHeader: "",
+			wantClient: alice, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name: "bearer token", apiKey: "", authHeader: "Bearer " + bobKey,
+			wantClient: bob, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name: "x-api-key takes precedence", apiKey: aliceKey, authHeader: "Bearer " + bobKey,
+			wantClient: alice, wantValid: true, wantErrMsg: "",
+		},
+		{
+			name: "unknown key", apiKey: "sk-relay-mallory", authHeader: "",
+			wantClient: nil, wantValid: false, wantErrMsg: "invalid virtual key",
+		},
+		{
+			name: "key matching an invalid hash", apiKey: "not-a-hash", authHeader: "",
+			wantClient: nil, wantValid: false, wantErrMsg: "invalid virtual key",
+		},
+		{
+			name: "non-bearer authorization", apiKey: "", authHeader: "Basic " + aliceKey,
+			wantClient: nil, wantValid: false, wantErrMsg: "missing x-api-key header or bearer token",
+		},
+		{
+			name: "no credentials", apiKey: "", authHeader: "",
+			wantClient: nil, wantValid: false, wantErrMsg: "missing x-api-key header or bearer token",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/v1/messages", http.NoBody)
+			if testCase.apiKey != "" {
+				req.Header.Set("x-api-key", testCase.apiKey)
+			}
+			if testCase.authHeader != "" {
+				req.Header.Set("Authorization", testCase.authHeader)
+			}
+
+			result := authenticator.Validate(req)
+			assertAuthResult(t, result, testCase.wantValid, auth.TypeVirtualKey, testCase.wantErrMsg)
+			if result.Client != testCase.wantClient {
+				t.Errorf("Client = %v, want %v", result.Client, testCase.wantClient)
+			}
+		})
+	}
+}
+
+// TestChainAuthenticatorKeepsClient verifies the chain passes the resolved client through.
+func TestChainAuthenticatorKeepsClient(t *testing.T) {
+	t.Parallel()
+
+	virtualKeys, alice, _ := newVirtualKeyAuthenticator()
+	chainAuth := auth.NewChainAuthenticator(virtualKeys, auth.NewAPIKeyAuthenticator(testSecretKey))
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", aliceKey)
+	result := chainAuth.Validate(req)
+	assertAuthResult(t, result, true, auth.TypeVirtualKey, "")
+	if result.Client != alice {
+		t.Errorf("Client = %v, want alice", result.Client)
+	}
+
+	req.Header.Set("x-api-key", testSecretKey)
+	result = chainAuth.Validate(req)
+	assertAuthResult(t, result, true, auth.TypeAPIKey, "")
+	if result.Client != nil {
+		t.Errorf("Client = %v, want nil for the shared key", result.Client)
+	}
+}
+
+// TestClientAllows tests model prefix and provider restrictions.
+func TestClientAllows(t *testing.T) {
+	t.Parallel()
+
+	restricted := &auth.Client{
+		Metadata:         nil,
+		Name:             "restricted",
+		AllowedModels:    []string{"claude-sonnet", "claude-haiku-4"},
+		AllowedProviders: []string{"anthropic"},
+	}
+	unrestricted := &auth.Client{Metadata: nil, Name: "open", AllowedModels: nil, AllowedProviders: nil}
+	var unauthenticated *auth.Client
+
+	tests := []struct {
+		client       *auth.Client
+		name         string
+		model        string
+		provider     string
+		wantModel    bool
+		wantProvider bool
+	}{
+		{restricted, "allowed prefix", "claude-sonnet-4-5", "anthropic", true, true},
+		{restricted, "other model and provider", "claude-opus-4", "zai", false, false},
+		{restricted, "missing model", "", "anthropic", false, true},
+		{unrestricted, "no restrictions", "glm-4.6", "zai", true, true},
+		{unauthenticated, "nil client", "glm-4.6", "zai", true, true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := testCase.client.AllowsModel(testCase.model); got != testCase.wantModel {
+				t.Errorf("AllowsModel(%q) = %v, want %v", testCase.model, got, testCase.wantModel)
+			}
+			if got := testCase.client.AllowsProvider(testCase.provider); got != testCase.wantProvider {
+				t.Errorf("AllowsProvider(%q) = %v, want %v", testCase.provider, got, testCase.wantProvider)
+			}
+		})
+	}
+}
+
+// TestClientContext tests storing the client in a request context.
+func TestClientContext(t *testing.T) {
+	t.Parallel()
+
+	if client := auth.ClientFromContext(context.Background()); client != nil {
+		t.Errorf("ClientFromContext() = %v, want nil", client)
+	}
+
+	client := &auth.Client{Metadata: nil, Name: "alice", AllowedModels: nil, AllowedProviders: nil}
+	if got := auth.ClientFromContext(auth.WithClient(context.Background(), client)); got != client {
+		t.Errorf("ClientFromContext() = %v, want %v", got, client)
+	}
+}
+
+// TestHashKey verifies keys hash to lowercase hex SHA-256.
+func TestHashKey(t *testing.T) {
+	t.Parallel()
+
+	const want = "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	if got := auth.HashKey("secret"); got != want {
+		t.Errorf("HashKey() = %q, want %q", got, want)
+	}
+}
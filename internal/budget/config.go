@@ -0,0 +1,136 @@
+// Package budget enforces token and spend budgets for cc-relay.
+//
+// Budgets cap how many tokens, and how many US dollars, a client or the
+// whole relay may use per day or month. Usage is read from response usage
+// blocks and priced from a per-model price table. Counters live in the
+// Olric cache in HA mode, so every node sees the same totals and they
+// outlive a restarting node; otherwise they are kept in memory and saved to
+// the file at Config.StatePath.
+//
+// A request is refused once any applicable budget is used up (hard limit).
+// Past the soft limit percentage requests still pass but carry warnings.
+package budget
+
+import "strings"
+
+// DefaultSoftLimitPercent is the share of a budget after which warnings are sent.
+const DefaultSoftLimitPercent = 80
+
+// Limit names, as used in config keys and warning headers.
+const (
+	LimitTokensPerDay   = "tokens_per_day"
+	LimitTokensPerMonth = "tokens_per_month"
+	LimitUSDPerMonth    = "usd_per_month"
+)
+
+// Config defines relay-wide budget settings.
+type Config struct {
+	// Prices maps model name prefixes to prices, matched by longest prefix.
+	// USD budgets only count requests for models with a price.
+	Prices map[string]Price `yaml:"prices" toml:"prices"`
+
+	// StatePath is the JSON file usage is saved to when it is not counted in
+	// the HA cache, so budgets survive a restart. Required for budgets
+	// outside HA mode; changing it requires a restart.
+	StatePath string `yaml:"state_path" toml:"state_path"`
+
+	// Global limits usage across all requests, whoever sent them.
+	Global Limits `yaml:"global" toml:"global"`
+
+	// SoftLimitPercent is the share of a budget after which responses carry a
+	// warning header. Defaults to 80. Set to 100 to only warn once a budget is used up.
+	SoftLimitPercent int `yaml:"soft_limit_percent" toml:"soft_limit_percent"`
+}
+
+// GetSoftLimitPercent returns the soft limit percentage or the default 80.
+func (c *Config) GetSoftLimitPercent() int {
+	if c.SoftLimitPercent <= 0 {
+		return DefaultSoftLimitPercent
+	}
+	return c.SoftLimitPercent
+}
+
+// PriceFor returns the price of model using the longest matching prefix.
+func (c *Config) PriceFor(model string) (Price, bool) {
+	var (
+		best  Price
+		found bool
+		depth int
+	)
+	for prefix, price := range c.Prices {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > depth) {
+			best, found, depth = price, true, len(prefix)
+		}
+	}
+	return best, found
+}
+
+// Limits are the budgets for one scope. Zero values are unlimited.
+// Days and months are calendar periods in UTC.
+type Limits struct {
+	// TokensPerDay caps input, output and cache tokens per day.
+	TokensPerDay int64 `yaml:"tokens_per_day" toml:"tokens_per_day"`
+
+	// TokensPerMonth caps input, output and cache tokens per month.
+	TokensPerMonth int64 `yaml:"tokens_per_month" toml:"tokens_per_month"`
+
+	// USDPerMonth caps spend per month, priced from Config.Prices.
+	USDPerMonth float64 `yaml:"usd_per_month" toml:"usd_per_month"`
+}
+
+// IsZero returns true if no limit is set.
+func (l Limits) IsZero() bool {
+	return l.TokensPerDay == 0 && l.TokensPerMonth == 0 && l.USDPerMonth == 0
+}
+
+// Price is the USD price of a model per million tokens.
+type Price struct {
+	// InputPerMTok is the price of a million input tokens.
+	InputPerMTok float64 `yaml:"input_per_mtok" toml:"input_per_mtok"`
+
+	// OutputPerMTok is the price of a million output tokens.
+	OutputPerMTok float64 `yaml:"output_per_mtok" toml:"output_per_mtok"`
+
+	// CacheWritePerMTok is the price of a million cache write tokens.
+	// Defaults to the input price.
+	CacheWritePerMTok float64 `yaml:"cache_write_per_mtok" toml:"cache_write_per_mtok"`
+
+	// CacheReadPerMTok is the price of a million cache read tokens.
+	// Defaults to the input price.
+	CacheReadPerMTok float64 `yaml:"cache_read_per_mtok" toml:"cache_read_per_mtok"`
+}
+
+// Cost returns the price of usage in USD.
+// Unset cache prices fall back to the input price, so a budget is overcounted
+// rather than undercounted when the table is incomplete.
+func (p Price) Cost(usage Usage) float64 {
+	cacheWrite := p.CacheWritePerMTok
+	if cacheWrite == 0 {
+		cacheWrite = p.InputPerMTok
+	}
+	cacheRead := p.CacheReadPerMTok
+	if cacheRead == 0 {
+		cacheRead = p.InputPerMTok
+	}
+	total := float64(usage.InputTokens)*p.InputPerMTok +
+		float64(usage.OutputTokens)*p.OutputPerMTok +
+		float64(usage.CacheCreationTokens)*cacheWrite +
+		float64(usage.CacheReadTokens)*cacheRead
+	return total / tokensPerMTok
+}
+
+// tokensPerMTok is the number of tokens prices are quoted for.
+const tokensPerMTok = 1_000_000
+
+// Usage is the token accounting of one response.
+type Usage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+}
+
+// Total returns all tokens the response used.
+func (u Usage) Total() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationTokens + u.CacheReadTokens
+}
@@ -0,0 +1,102 @@
+package budget_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+)
+
+func price(input, output, cacheWrite, cacheRead float64) budget.Price {
+	return budget.Price{
+		InputPerMTok:      input,
+		OutputPerMTok:     output,
+		CacheWritePerMTok: cacheWrite,
+		CacheReadPerMTok:  cacheRead,
+	}
+}
+
+func TestPriceForLongestPrefix(t *testing.T) {
+	t.Parallel()
+	cfg := budget.Config{
+		Prices: map[string]budget.Price{
+			"claude":        price(1, 1, 0, 0),
+			"claude-opus":   price(15, 75, 0, 0),
+			"claude-opus-4": price(5, 25, 0, 0),
+		},
+		StatePath:        "",
+		Global:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+		SoftLimitPercent: 0,
+	}
+
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantFound bool
+	}{
+		{model: "claude-opus-4-1", wantInput: 5, wantFound: true},
+		{model: "claude-opus-3", wantInput: 15, wantFound: true},
+		{model: "claude-sonnet-4-5", wantInput: 1, wantFound: true},
+		{model: "gpt-4o", wantInput: 0, wantFound: false},
+	}
+	for _, tt := range tests {
+		got, found := cfg.PriceFor(tt.model)
+		if found != tt.wantFound || got.InputPerMTok != tt.wantInput {
+			t.Errorf("PriceFor(%q) = %v, %v; want input %v, %v", tt.model, got, found, tt.wantInput, tt.wantFound)
+		}
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	t.Parallel()
+	usage := budget.Usage{
+		InputTokens:         1_000_000,
+		OutputTokens:        100_000,
+		CacheCreationTokens: 200_000,
+		CacheReadTokens:     2_000_000,
+	}
+
+	tests := []struct {
+		name  string
+		price budget.Price
+		want  float64
+	}{
+		// 3 + 1.5 + 0.75 + 0.6
+		{name: "all prices set", price: price(3, 15, 3.75, 0.3), want: 5.85},
+		// 3 + 1.5 + 0.6 + 6: cache tokens cost as much as input tokens
+		{name: "cache prices default to input", price: price(3, 15, 0, 0), want: 11.1},
+	}
+	for _, tt := range tests {
+		if got := tt.price.Cost(usage); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Cost() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetSoftLimitPercent(t *testing.T) {
+	t.Parallel()
+	cfg := budget.Config{
+		Prices:           nil,
+		StatePath:        "",
+		Global:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+		SoftLimitPercent: 0,
+	}
+	if got := cfg.GetSoftLimitPercent(); got != budget.DefaultSoftLimitPercent {
+		t.Errorf("GetSoftLimitPercent() = %d, want default %d", got, budget.DefaultSoftLimitPercent)
+	}
+
+	cfg.SoftLimitPercent = 95
+	if got := cfg.GetSoftLimitPercent(); got != 95 {
+		t.Errorf("GetSoftLimitPercent() = %d, want 95", got)
+	}
+}
+
+func TestLimitsIsZero(t *testing.T) {
+	t.Parallel()
+	if !(budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0}).IsZero() {
+		t.Error("empty limits should be zero")
+	}
+	if (budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0.5}).IsZero() {
+		t.Error("limits with a spend cap should not be zero")
+	}
+}
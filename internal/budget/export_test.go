@@ -0,0 +1,17 @@
+package budget
+
+import (
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+// NewTrackerAt creates a tracker backed by c whose clock is now (for testing).
+func NewTrackerAt(c cache.Cache, now func() time.Time) *Tracker {
+	tracker := NewTracker(c)
+	tracker.now = now
+	if _, shared := c.(cache.Counter); !shared {
+		tracker.counter = cache.NewLocalCounter(now)
+	}
+	return tracker
+}
@@ -0,0 +1,203 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+const (
+	// usdScale converts USD to the integer micro-dollars counters hold.
+	usdScale = 1_000_000
+
+	// periodGrace keeps counters past the end of their period, so nodes whose
+	// clocks lag slightly behind still find them.
+	periodGrace = time.Hour
+
+	globalScopeName   = "global"
+	clientScopePrefix = "client:"
+)
+
+// Scope is a set of limits and whose usage they apply to.
+type Scope struct {
+	// Name keys the scope's counters, e.g. "global" or "client:alice".
+	Name   string
+	Limits Limits
+}
+
+// GlobalScope returns the scope counting every request through the relay.
+func GlobalScope(limits Limits) Scope {
+	return Scope{Name: globalScopeName, Limits: limits}
+}
+
+// ClientScope returns the scope counting the named client's requests.
+func ClientScope(client string, limits Limits) Scope {
+	return Scope{Name: clientScopePrefix + client, Limits: limits}
+}
+
+// LimitStatus is how much of one limit of one scope has been used.
+type LimitStatus struct {
+	// ResetAt is when the limit's period ends.
+	ResetAt time.Time
+	Scope   string
+	Limit   string
+	Used    float64
+	Max     float64
+}
+
+// Percent returns the used share of the limit, rounded down.
+func (s LimitStatus) Percent() int {
+	return int(s.Used * 100 / s.Max)
+}
+
+// Status is the outcome of a budget check.
+type Status struct {
+	// Exceeded lists used up limits. The request must be refused.
+	Exceeded []LimitStatus
+	// Warnings lists limits past the soft limit.
+	Warnings []LimitStatus
+}
+
+// window is one of the periods a scope's usage is counted over.
+type window struct {
+	name    string
+	usd     bool
+	monthly bool
+}
+
+// windows are all counted periods, in the order limits are reported.
+var windows = []window{
+	{name: LimitTokensPerDay, usd: false, monthly: false},
+	{name: LimitTokensPerMonth, usd: false, monthly: true},
+	{name: LimitUSDPerMonth, usd: true, monthly: true},
+}
+
+// limit returns the configured maximum for w, or 0 when unlimited.
+func (l Limits) limit(w window) float64 {
+	switch w.name {
+	case LimitTokensPerDay:
+		return float64(l.TokensPerDay)
+	case LimitTokensPerMonth:
+		return float64(l.TokensPerMonth)
+	default:
+		return l.USDPerMonth
+	}
+}
+
+// Tracker counts usage against budgets.
+// Counters are kept in the cache when it supports atomic counters (Olric),
+// so HA nodes share them; otherwise they are kept in process memory, or in a
+// cache.FileCounter that outlives a restart.
+type Tracker struct {
+	counter cache.Counter
+	now     func() time.Time
+}
+
+// NewTracker creates a tracker backed by c, which may be nil.
+func NewTracker(c cache.Cache) *Tracker {
+	counter, ok := c.(cache.Counter)
+	if !ok {
+		counter = cache.NewLocalCounter(time.Now)
+	}
+	return NewTrackerWithCounter(counter)
+}
+
+// NewTrackerWithCounter creates a tracker counting in counter.
+func NewTrackerWithCounter(counter cache.Counter) *Tracker {
+	return &Tracker{counter: counter, now: time.Now}
+}
+
+// Check reports the limits of scopes that are used up or past softPercent.
+// Only limits that are set are checked.
+func (t *Tracker) Check(ctx context.Context, scopes []Scope, softPercent int) (Status, error) {
+	now := t.now()
+	status := Status{Exceeded: nil, Warnings: nil}
+	for _, scope := range scopes {
+		for _, w := range windows {
+			maximum := scope.Limits.limit(w)
+			if maximum <= 0 {
+				continue
+			}
+			raw, err := t.counter.Incr(ctx, counterKey(scope.Name, w, now), 0, counterTTL(w, now))
+			if err != nil {
+				return status, fmt.Errorf("read %s budget %s: %w", scope.Name, w.name, err)
+			}
+
+			entry := LimitStatus{
+				ResetAt: periodEnd(now, w.monthly),
+				Scope:   scope.Name,
+				Limit:   w.name,
+				Used:    fromCounter(raw, w),
+				Max:     maximum,
+			}
+			switch {
+			case entry.Used >= entry.Max:
+				status.Exceeded = append(status.Exceeded, entry)
+			case entry.Used*100 >= entry.Max*float64(softPercent):
+				status.Warnings = append(status.Warnings, entry)
+			}
+		}
+	}
+	return status, nil
+}
+
+// Record adds usage and its cost in USD to every period of scopes.
+// All periods are counted, not only limited ones, so a limit added by a
+// config reload applies to usage earlier in its period.
+func (t *Tracker) Record(ctx context.Context, scopes []Scope, usage Usage, cost float64) error {
+	now := t.now()
+	tokens := usage.Total()
+	micros := int64(math.Round(cost * usdScale))
+
+	var errs []error
+	for _, scope := range scopes {
+		for _, w := range windows {
+			delta := tokens
+			if w.usd {
+				delta = micros
+			}
+			if delta <= 0 {
+				continue
+			}
+			if _, err := t.counter.Incr(ctx, counterKey(scope.Name, w, now), delta, counterTTL(w, now)); err != nil {
+				errs = append(errs, fmt.Errorf("record %s budget %s: %w", scope.Name, w.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// counterKey builds the cache key: "budget:{scope}:{limit}:{period}".
+func counterKey(scope string, w window, now time.Time) string {
+	layout := time.DateOnly
+	if w.monthly {
+		layout = "2006-01"
+	}
+	return fmt.Sprintf("budget:%s:%s:%s", scope, w.name, now.UTC().Format(layout))
+}
+
+// counterTTL keeps a counter until its period is over.
+func counterTTL(w window, now time.Time) time.Duration {
+	return periodEnd(now, w.monthly).Sub(now) + periodGrace
+}
+
+// fromCounter converts a raw counter value to tokens or USD.
+func fromCounter(raw int64, w window) float64 {
+	if w.usd {
+		return float64(raw) / usdScale
+	}
+	return float64(raw)
+}
+
+// periodEnd returns when the day or month containing now ends, in UTC.
+func periodEnd(now time.Time, monthly bool) time.Time {
+	now = now.UTC()
+	if monthly {
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
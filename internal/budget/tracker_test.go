@@ -0,0 +1,251 @@
+package budget_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+// fakeClock is a settable clock for the tracker.
+type fakeClock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// counterCache is a cache.Cache that supports cache.Counter, recording keys.
+type counterCache struct {
+	cache.Cache
+	values map[string]int64
+	err    error
+	mu     sync.Mutex
+}
+
+func newCounterCache() *counterCache {
+	return &counterCache{Cache: nil, values: make(map[string]int64), err: nil, mu: sync.Mutex{}}
+}
+
+func (c *counterCache) Incr(_ context.Context, key string, delta int64, _ time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.values[key] += delta
+	return c.values[key], nil
+}
+
+func (c *counterCache) value(key string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func tokens(n int64) budget.Usage {
+	return budget.Usage{InputTokens: n, OutputTokens: 0, CacheCreationTokens: 0, CacheReadTokens: 0}
+}
+
+func newTestTracker(t *testing.T, c cache.Cache) (*budget.Tracker, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC), mu: sync.Mutex{}}
+	return budget.NewTrackerAt(c, clock.Now), clock
+}
+
+func TestTrackerSoftAndHardLimits(t *testing.T) {
+	t.Parallel()
+	tracker, _ := newTestTracker(t, nil)
+	ctx := context.Background()
+	scopes := []budget.Scope{budget.ClientScope("alice", budget.Limits{
+		TokensPerDay: 1000, TokensPerMonth: 0, USDPerMonth: 0,
+	})}
+
+	steps := []struct {
+		record       int64
+		wantWarnings int
+		wantExceeded int
+	}{
+		{record: 700, wantWarnings: 0, wantExceeded: 0},
+		{record: 150, wantWarnings: 1, wantExceeded: 0},
+		{record: 150, wantWarnings: 0, wantExceeded: 1},
+	}
+	for i, step := range steps {
+		if err := tracker.Record(ctx, scopes, tokens(step.record), 0); err != nil {
+			t.Fatalf("step %d: Record failed: %v", i, err)
+		}
+		status, err := tracker.Check(ctx, scopes, 80)
+		if err != nil {
+			t.Fatalf("step %d: Check failed: %v", i, err)
+		}
+		if len(status.Warnings) != step.wantWarnings || len(status.Exceeded) != step.wantExceeded {
+			t.Errorf("step %d: got %d warnings and %d exceeded, want %d and %d",
+				i, len(status.Warnings), len(status.Exceeded), step.wantWarnings, step.wantExceeded)
+		}
+	}
+
+	status, err := tracker.Check(ctx, scopes, 80)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	exceeded := status.Exceeded[0]
+	if exceeded.Scope != "client:alice" || exceeded.Limit != budget.LimitTokensPerDay {
+		t.Errorf("unexpected exceeded limit: %+v", exceeded)
+	}
+	if exceeded.Used != 1000 || exceeded.Max != 1000 || exceeded.Percent() != 100 {
+		t.Errorf("expected 1000 of 1000 used, got %+v", exceeded)
+	}
+	if want := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC); !exceeded.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", exceeded.ResetAt, want)
+	}
+}
+
+func TestTrackerPeriodsReset(t *testing.T) {
+	t.Parallel()
+	tracker, clock := newTestTracker(t, nil)
+	ctx := context.Background()
+	scopes := []budget.Scope{budget.GlobalScope(budget.Limits{
+		TokensPerDay: 100, TokensPerMonth: 150, USDPerMonth: 0,
+	})}
+
+	if err := tracker.Record(ctx, scopes, tokens(100), 0); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// The next day the daily budget is fresh but the monthly one is not
+	clock.Set(time.Date(2026, 10, 17, 0, 0, 1, 0, time.UTC))
+	status, err := tracker.Check(ctx, scopes, 100)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 0 {
+		t.Errorf("expected no exhausted budget on a new day, got %+v", status.Exceeded)
+	}
+	if err := tracker.Record(ctx, scopes, tokens(50), 0); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	status, err = tracker.Check(ctx, scopes, 100)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 1 || status.Exceeded[0].Limit != budget.LimitTokensPerMonth {
+		t.Errorf("expected the monthly budget to be exhausted, got %+v", status.Exceeded)
+	}
+
+	// A new month resets everything
+	clock.Set(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+	status, err = tracker.Check(ctx, scopes, 100)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 0 {
+		t.Errorf("expected no exhausted budget in a new month, got %+v", status.Exceeded)
+	}
+}
+
+func TestTrackerSpend(t *testing.T) {
+	t.Parallel()
+	tracker, _ := newTestTracker(t, nil)
+	ctx := context.Background()
+	scopes := []budget.Scope{budget.ClientScope("alice", budget.Limits{
+		TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 1,
+	})}
+
+	for range 2 {
+		if err := tracker.Record(ctx, scopes, tokens(10), 0.6); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	status, err := tracker.Check(ctx, scopes, 80)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 1 || status.Exceeded[0].Used != 1.2 {
+		t.Errorf("expected $1.20 of $1 spent, got %+v", status.Exceeded)
+	}
+}
+
+func TestTrackerScopesAreSeparate(t *testing.T) {
+	t.Parallel()
+	tracker, _ := newTestTracker(t, nil)
+	ctx := context.Background()
+	limits := budget.Limits{TokensPerDay: 100, TokensPerMonth: 0, USDPerMonth: 0}
+
+	alice := []budget.Scope{budget.GlobalScope(limits), budget.ClientScope("alice", limits)}
+	if err := tracker.Record(ctx, alice, tokens(100), 0); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	status, err := tracker.Check(ctx, []budget.Scope{budget.ClientScope("bob", limits)}, 80)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 0 {
+		t.Errorf("alice's usage should not count against bob, got %+v", status.Exceeded)
+	}
+
+	status, err = tracker.Check(ctx, []budget.Scope{budget.GlobalScope(limits)}, 80)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(status.Exceeded) != 1 {
+		t.Errorf("alice's usage should count against the global budget, got %+v", status.Exceeded)
+	}
+}
+
+func TestTrackerUsesCacheCounter(t *testing.T) {
+	t.Parallel()
+	counters := newCounterCache()
+	tracker, _ := newTestTracker(t, counters)
+	scopes := []budget.Scope{budget.ClientScope("alice", budget.Limits{
+		TokensPerDay: 1000, TokensPerMonth: 0, USDPerMonth: 0,
+	})}
+
+	if err := tracker.Record(context.Background(), scopes, tokens(42), 0.25); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// Every period is counted so limits added later see earlier usage
+	want := map[string]int64{
+		"budget:client:alice:tokens_per_day:2026-10-16": 42,
+		"budget:client:alice:tokens_per_month:2026-10":  42,
+		"budget:client:alice:usd_per_month:2026-10":     250_000,
+	}
+	for key, value := range want {
+		if got := counters.value(key); got != value {
+			t.Errorf("counter %s = %d, want %d", key, got, value)
+		}
+	}
+}
+
+func TestTrackerCheckError(t *testing.T) {
+	t.Parallel()
+	counters := newCounterCache()
+	counters.err = cache.ErrClosed
+	tracker, _ := newTestTracker(t, counters)
+	scopes := []budget.Scope{budget.GlobalScope(budget.Limits{
+		TokensPerDay: 1000, TokensPerMonth: 0, USDPerMonth: 0,
+	})}
+
+	if _, err := tracker.Check(context.Background(), scopes, 80); !errors.Is(err, cache.ErrClosed) {
+		t.Errorf("Check error = %v, want ErrClosed", err)
+	}
+	if err := tracker.Record(context.Background(), scopes, tokens(1), 0); !errors.Is(err, cache.ErrClosed) {
+		t.Errorf("Record error = %v, want ErrClosed", err)
+	}
+}
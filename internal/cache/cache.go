@@ -131,6 +131,23 @@ type Pinger interface {
 	Ping(ctx context.Context) error
 }
 
+// Counter is an optional interface for caches that support atomic integer counters.
+// Only the distributed backend implements it, so counters are shared across the
+// cluster; local caches may evict or drop entries and are not suitable.
+//
+// Use type assertion to check if a cache implements this interface:
+//
+//	if c, ok := c.(cache.Counter); ok {
+//		total, err := c.Incr(ctx, "requests", 1, time.Hour)
+//	}
+type Counter interface {
+	// Incr atomically adds delta to the counter stored at key and returns the new value.
+	// A missing key starts at zero and expires after ttl.
+	// An Incr with delta 0 reads the counter.
+	// Returns ErrClosed if the cache has been closed.
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
 // ClusterInfo is an optional interface for distributed caches that support clustering.
 // Use type assertion to check if a cache implements this interface:
 //
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalCounter implements Counter in process memory. It backs counters, such
+// as budgets and client rate limits, when the cache is local or disabled:
+// Ristretto may drop writes under its admission policy, which would silently
+// lose counts.
+type LocalCounter struct {
+	values map[string]localValue
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+type localValue struct {
+	expiresAt time.Time
+	value     int64
+}
+
+// NewLocalCounter creates an empty counter whose expiry clock is now.
+func NewLocalCounter(now func() time.Time) *LocalCounter {
+	return &LocalCounter{values: make(map[string]localValue), now: now, mu: sync.Mutex{}}
+}
+
+// Incr adds delta to the counter at key and returns the new value.
+// Expired counters are dropped whenever a new one is created.
+func (c *LocalCounter) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry, ok := c.values[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		c.dropExpired(now)
+		entry = localValue{expiresAt: now.Add(ttl), value: 0}
+	}
+	entry.value += delta
+	c.values[key] = entry
+	return entry.value, nil
+}
+
+// dropExpired removes expired counters. The caller must hold c.mu.
+func (c *LocalCounter) dropExpired(now time.Time) {
+	for key, entry := range c.values {
+		if !now.Before(entry.expiresAt) {
+			delete(c.values, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+func TestLocalCounterIncr(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	counter := cache.NewLocalCounter(func() time.Time { return now })
+	ctx := context.Background()
+
+	steps := []struct {
+		key   string
+		delta int64
+		want  int64
+	}{
+		{"requests", 3, 3},
+		{"requests", 0, 3},
+		{"requests", -1, 2},
+		{"tokens", 5, 5},
+	}
+	for _, step := range steps {
+		got, err := counter.Incr(ctx, step.key, step.delta, time.Minute)
+		if err != nil || got != step.want {
+			t.Fatalf("Incr(%q, %d) = %d, %v, want %d", step.key, step.delta, got, err, step.want)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if got, err := counter.Incr(ctx, "requests", 1, time.Minute); err != nil || got != 1 {
+		t.Errorf("Incr() after the TTL = %d, %v, want a new counter at 1", got, err)
+	}
+}
+
+func TestLocalCounterCanceledContext(t *testing.T) {
+	t.Parallel()
+
+	counter := cache.NewLocalCounter(time.Now)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := counter.Incr(ctx, "requests", 1, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Incr() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFileCounterRestoresSavedCounts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	path := filepath.Join(t.TempDir(), "counters.json")
+	ctx := context.Background()
+
+	counter, err := cache.NewFileCounter(path, clock)
+	if err != nil {
+		t.Fatalf("NewFileCounter() without a file error = %v", err)
+	}
+	if _, err = counter.Incr(ctx, "daily", 7, time.Hour); err != nil {
+		t.Fatalf("Incr() error = %v", err)
+	}
+	if _, err = counter.Incr(ctx, "minute", 3, time.Minute); err != nil {
+		t.Fatalf("Incr() error = %v", err)
+	}
+	if err = counter.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	now = now.Add(30 * time.Minute)
+	restored, err := cache.NewFileCounter(path, clock)
+	if err != nil {
+		t.Fatalf("NewFileCounter() error = %v", err)
+	}
+	if got, err := restored.Incr(ctx, "daily", 1, time.Hour); err != nil || got != 8 {
+		t.Errorf("Incr() after restore = %d, %v, want the saved count plus 1", got, err)
+	}
+	if got, err := restored.Incr(ctx, "minute", 1, time.Minute); err != nil || got != 1 {
+		t.Errorf("Incr() of an expired count = %d, %v, want a new counter at 1", got, err)
+	}
+}
+
+func TestFileCounterRejectsCorruptFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "counters.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := cache.NewFileCounter(path, time.Now); err == nil {
+		t.Error("NewFileCounter() with a corrupt file error = nil, want an error")
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileCounter is a LocalCounter whose counts are saved to a JSON file, so
+// they outlive a restart without a distributed cache. Counts are restored
+// when it is created and written by Save, which callers run periodically and
+// on shutdown; counts since the last save are lost if the process crashes.
+type FileCounter struct {
+	*LocalCounter
+	path string
+}
+
+// savedCounter is a counter as stored in the file.
+type savedCounter struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Value     int64     `json:"value"`
+}
+
+// NewFileCounter creates a counter saved to path, restoring the unexpired
+// counts saved there. A missing file holds no counts.
+func NewFileCounter(path string, now func() time.Time) (*FileCounter, error) {
+	counter := &FileCounter{LocalCounter: NewLocalCounter(now), path: filepath.Clean(path)}
+
+	data, err := os.ReadFile(counter.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return counter, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cache: load counters: %w", err)
+	}
+	var saved map[string]savedCounter
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("cache: decode counters %s: %w", counter.path, err)
+	}
+
+	current := now()
+	for key, entry := range saved {
+		if current.Before(entry.ExpiresAt) {
+			counter.values[key] = localValue{expiresAt: entry.ExpiresAt, value: entry.Value}
+		}
+	}
+	return counter, nil
+}
+
+// Save writes the unexpired counts to the file, replacing it atomically.
+func (c *FileCounter) Save() error {
+	c.mu.Lock()
+	now := c.now()
+	saved := make(map[string]savedCounter, len(c.values))
+	for key, entry := range c.values {
+		if now.Before(entry.expiresAt) {
+			saved[key] = savedCounter{ExpiresAt: entry.expiresAt, Value: entry.value}
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cache: encode counters: %w", err)
+	}
+	if err = WriteFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("cache: save counters: %w", err)
+	}
+	return nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// over path, so readers never see a partly written file.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil {
+			_ = removeErr // The file is gone once renamed into place
+		}
+	}()
+
+	_, writeErr := tmp.Write(data)
+	if closeErr := tmp.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -132,6 +132,7 @@ var (
 	_ StatsProvider = (*olricCache)(nil)
 	_ Pinger        = (*olricCache)(nil)
 	_ ClusterInfo   = (*olricCache)(nil)
+	_ Counter       = (*olricCache)(nil)
 )
 
 // newOlricCache creates a new Olric distributed cache with the given configuration.
@@ -492,6 +493,56 @@ func (o *olricCache) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// Incr atomically adds delta to the counter at key and returns the new value.
+// The expiry is set when the increment creates the key, so later increments
+// do not extend it.
+// Returns ErrClosed if the cache has been closed.
+func (o *olricCache) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if o.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if o.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	value, err := o.dmap.Incr(ctx, key, int(delta))
+	if err != nil {
+		o.log.Debug().
+			Str("key", key).
+			Int64("delta", delta).
+			Err(err).
+			Msg("cache incr error")
+		return 0, err
+	}
+
+	if int64(value) == delta && ttl > 0 {
+		if expireErr := o.dmap.Expire(ctx, key, ttl); expireErr != nil {
+			o.log.Debug().
+				Str("key", key).
+				Dur("ttl", ttl).
+				Err(expireErr).
+				Msg("cache incr: failed to set expiry")
+			return 0, expireErr
+		}
+	}
+
+	o.log.Debug().
+		Str("key", key).
+		Int64("delta", delta).
+		Int("value", value).
+		Msg("cache incr")
+
+	return int64(value), nil
+}
+
 // Close releases resources associated with the cache.
 // After Close is called, all operations will return ErrClosed.
 // Close is idempotent.
@@ -123,6 +123,51 @@ func TestOlricCacheSetWithTTLExpires(t *testing.T) {
 	}
 }
 
+func TestOlricCacheIncr(t *testing.T) {
+	t.Parallel()
+	testCache := newTestOlricCache(t)
+	ctx := context.Background()
+
+	ttl := 500 * time.Millisecond
+	for _, step := range []struct {
+		delta int64
+		want  int64
+	}{{delta: 0, want: 0}, {delta: 5, want: 5}, {delta: 7, want: 12}, {delta: 0, want: 12}} {
+		got, err := testCache.Incr(ctx, "counter", step.delta, ttl)
+		if err != nil {
+			t.Fatalf("Incr(%d) failed: %v", step.delta, err)
+		}
+		if got != step.want {
+			t.Errorf("Incr(%d) = %d, want %d", step.delta, got, step.want)
+		}
+	}
+
+	// The expiry set on creation is not extended by later increments
+	time.Sleep(ttl + 500*time.Millisecond)
+
+	got, err := testCache.Incr(ctx, "counter", 1, ttl)
+	if err != nil {
+		t.Fatalf("Incr after expiry failed: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Incr after expiry = %d, want 1", got)
+	}
+}
+
+func TestOlricCacheIncrAfterClose(t *testing.T) {
+	t.Parallel()
+	testCache := newTestOlricCache(t)
+
+	if err := testCache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	_, err := testCache.Incr(context.Background(), "counter", 1, time.Minute)
+	if !errors.Is(err, cache.ErrClosed) {
+		t.Errorf("Incr after Close returned %v, want ErrClosed", err)
+	}
+}
+
 func TestOlricCacheDelete(t *testing.T) {
 	t.Parallel()
 	testCache := newTestOlricCache(t)
@@ -3,11 +3,17 @@ package config
 
 import (
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+	"github.com/omarluq/cc-relay/internal/rules"
 	"github.com/rs/zerolog"
 	"github.com/samber/mo"
 )
@@ -43,12 +49,21 @@ const StrategyLeastLoaded = "least_loaded"
 
 // Config represents the complete cc-relay configuration.
 type Config struct {
-	Providers []ProviderConfig `yaml:"providers" toml:"providers"`
-	Routing   RoutingConfig    `yaml:"routing" toml:"routing"`
-	Logging   LoggingConfig    `yaml:"logging" toml:"logging"`
-	Health    health.Config    `yaml:"health" toml:"health"`
-	Server    ServerConfig     `yaml:"server" toml:"server"`
-	Cache     cache.Config     `yaml:"cache" toml:"cache"`
+	Providers     []ProviderConfig    `yaml:"providers" toml:"providers"`
+	Access        AccessConfig        `yaml:"access" toml:"access"`
+	DLP           dlp.Config          `yaml:"dlp" toml:"dlp"`
+	Metrics       MetricsConfig       `yaml:"metrics" toml:"metrics"`
+	Admin         AdminConfig         `yaml:"admin" toml:"admin"`
+	Observability ObservabilityConfig `yaml:"observability" toml:"observability"`
+	Health        health.Config       `yaml:"health" toml:"health"`
+	KeyState      KeyStateConfig      `yaml:"key_state" toml:"key_state"`
+	Logging       LoggingConfig       `yaml:"logging" toml:"logging"`
+	Budgets       budget.Config       `yaml:"budgets" toml:"budgets"`
+	Routing       RoutingConfig       `yaml:"routing" toml:"routing"`
+	Server        ServerConfig        `yaml:"server" toml:"server"`
+	Cache         cache.Config        `yaml:"cache" toml:"cache"`
+	ResponseCache ResponseCacheConfig `yaml:"response_cache" toml:"response_cache"`
+	RateLimits    RateLimitsConfig    `yaml:"rate_limits" toml:"rate_limits"`
 }
 
 // RoutingConfig defines provider-level routing strategy behavior.
@@ -60,14 +75,34 @@ type RoutingConfig struct {
 	// Only used when Strategy is "model_based".
 	ModelMapping map[string]string `yaml:"model_mapping" toml:"model_mapping"`
 
+	// QualityFloor maps model name prefixes to the minimum quality a
+	// provider must serve them with, matched by longest prefix.
+	// Only used when Strategy is "cost_optimized".
+	// Example: {"claude-opus": 9} keeps opus requests off weaker substitutes.
+	QualityFloor map[string]int `yaml:"quality_floor" toml:"quality_floor"`
+
 	// Strategy defines the provider selection algorithm.
-	// Options: round_robin, weighted_round_robin, shuffle, failover (default), model_based
+	// Options: round_robin, weighted_round_robin, shuffle, failover (default), model_based,
+	// least_loaded, weighted_failover, latency_aware, cost_optimized
 	Strategy string `yaml:"strategy" toml:"strategy"`
 
 	// DefaultProvider is the fallback provider when no model mapping matches.
 	// Only used when Strategy is "model_based".
 	DefaultProvider string `yaml:"default_provider" toml:"default_provider"`
 
+	// Rules route matching requests to a provider set, strategy or model.
+	// Evaluated in order; the first matching rule applies. Requests no rule
+	// matches are routed by the settings above.
+	Rules []rules.Rule `yaml:"rules" toml:"rules"`
+
+	// SessionAffinity keeps each conversation on the provider and key that
+	// served it, so consecutive turns read from a warm prompt cache.
+	SessionAffinity SessionAffinityConfig `yaml:"session_affinity" toml:"session_affinity"`
+
+	// Hedging sends latency-sensitive requests to a second provider when the
+	// first is slow to start responding, and keeps whichever starts first.
+	Hedging HedgingConfig `yaml:"hedging" toml:"hedging"`
+
 	// FailoverTimeout is the timeout in milliseconds for failover attempts.
 	// When a provider fails, the router will try the next provider within this timeout.
 	// Default: 5000ms (5 seconds)
@@ -96,24 +131,183 @@ func (r *RoutingConfig) GetFailoverTimeoutOption() mo.Option[time.Duration] {
 	return mo.Some(time.Duration(r.FailoverTimeout) * time.Millisecond)
 }
 
+// QualityFloorFor returns the quality floor for requests for model, or 0 if none applies.
+func (r *RoutingConfig) QualityFloorFor(model string) int {
+	floor, _ := longestPrefixMatch(r.QualityFloor, model)
+	return floor
+}
+
 // IsDebugEnabled returns true if routing debug headers are enabled.
 func (r *RoutingConfig) IsDebugEnabled() bool {
 	return r.Debug
 }
 
+// DefaultSessionAffinityTTL is how long a session stays pinned after its last
+// request when routing.session_affinity.ttl_ms is not set.
+const DefaultSessionAffinityTTL = time.Hour
+
+// SessionAffinityConfig controls sticky sessions. A session is identified by
+// the Header value if the request carries it, otherwise by the client identity
+// and a hash of the system prompt and first user message, which stay the same
+// across the turns of a conversation.
+type SessionAffinityConfig struct {
+	// Header names a request header carrying an explicit session ID.
+	// Example: "X-Session-ID". Optional.
+	Header string `yaml:"header" toml:"header"`
+
+	// TTLMS is how long in milliseconds a session stays pinned after its last
+	// request. Default: 3600000 (1 hour), the longest prompt cache TTL.
+	TTLMS int `yaml:"ttl_ms" toml:"ttl_ms"`
+
+	// Enabled turns session affinity on.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// GetTTL returns the session TTL with default fallback.
+func (s *SessionAffinityConfig) GetTTL() time.Duration {
+	if s.TTLMS <= 0 {
+		return DefaultSessionAffinityTTL
+	}
+	return time.Duration(s.TTLMS) * time.Millisecond
+}
+
+// Hedging defaults used when routing.hedging leaves a setting unset.
+const (
+	// DefaultHedgePercentile is the percentile of a provider's observed time
+	// to first byte that a request waits before it is hedged.
+	DefaultHedgePercentile = 95
+
+	// DefaultHedgeDelay is the hedge delay used until enough of a provider's
+	// responses have been timed.
+	DefaultHedgeDelay = 500 * time.Millisecond
+)
+
+// HedgingConfig controls hedged requests. A request is sent to the provider
+// routing selects; if no response bytes arrive within the Percentile of that
+// provider's recently observed time to first byte, the request is also sent
+// to a second provider. The first response to start streaming is sent to the
+// client and the other is cancelled.
+type HedgingConfig struct {
+	// Models lists the model name prefixes whose requests are hedged.
+	// Example: ["claude-haiku", "claude-3-5-haiku"]. Default: every model.
+	Models []string `yaml:"models" toml:"models"`
+
+	// Percentile of the provider's time to first byte to wait before hedging,
+	// between 0 and 100 exclusive. Default: 95.
+	Percentile float64 `yaml:"percentile" toml:"percentile"`
+
+	// DelayMS is the hedge delay in milliseconds used until enough of a
+	// provider's responses have been timed. Default: 500.
+	DelayMS int `yaml:"delay_ms" toml:"delay_ms"`
+
+	// Enabled turns hedging on.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// AppliesTo reports whether requests for model are hedged.
+func (h *HedgingConfig) AppliesTo(model string) bool {
+	if !h.Enabled {
+		return false
+	}
+	if len(h.Models) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(h.Models, func(prefix string) bool {
+		return strings.HasPrefix(model, prefix)
+	})
+}
+
+// GetPercentile returns the hedge percentile with default fallback.
+func (h *HedgingConfig) GetPercentile() float64 {
+	if h.Percentile <= 0 {
+		return DefaultHedgePercentile
+	}
+	return h.Percentile
+}
+
+// GetDelay returns the initial hedge delay with default fallback.
+func (h *HedgingConfig) GetDelay() time.Duration {
+	if h.DelayMS <= 0 {
+		return DefaultHedgeDelay
+	}
+	return time.Duration(h.DelayMS) * time.Millisecond
+}
+
 // ServerConfig defines server-level settings.
 type ServerConfig struct {
 	Listen        string     `yaml:"listen" toml:"listen"`
 	APIKey        string     `json:"-" yaml:"api_key" toml:"api_key"` // Legacy: use Auth.APIKey instead
+	TLS           TLSConfig  `yaml:"tls" toml:"tls"`
 	Auth          AuthConfig `yaml:"auth" toml:"auth"`
 	TimeoutMS     int        `yaml:"timeout_ms" toml:"timeout_ms"`
 	MaxConcurrent int        `yaml:"max_concurrent" toml:"max_concurrent"`
 	MaxBodyBytes  int64      `yaml:"max_body_bytes" toml:"max_body_bytes"` // Max request body size (0 = unlimited)
-	EnableHTTP2   bool       `yaml:"enable_http2" toml:"enable_http2"`     // Enable HTTP/2 cleartext (h2c) support
+	EnableHTTP2   bool       `yaml:"enable_http2" toml:"enable_http2"`     // Enable HTTP/2 (h2c, or over TLS)
+}
+
+// TLS client authentication modes for server.tls.client_auth.
+const (
+	// TLSClientAuthRequire rejects connections without a valid client certificate.
+	TLSClientAuthRequire = "require"
+	// TLSClientAuthOptional verifies client certificates when one is sent.
+	TLSClientAuthOptional = "optional"
+)
+
+// TLS versions for server.tls.min_version.
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// TLSConfig enables TLS on the listener. Certificates and client CAs are read
+// again on every config reload, so a renewed certificate is picked up by
+// reloading the config.
+type TLSConfig struct {
+	// CertFile is the PEM certificate chain served to clients.
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+
+	// KeyFile is the PEM private key of CertFile.
+	KeyFile string `yaml:"key_file" toml:"key_file"`
+
+	// ClientCAFile is a PEM bundle of CAs that sign client certificates.
+	// Setting it enables mutual TLS.
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file"`
+
+	// ClientAuth is "require" (default) or "optional". Optional accepts
+	// connections without a certificate, which then authenticate another way.
+	ClientAuth string `yaml:"client_auth" toml:"client_auth"`
+
+	// MinVersion is the lowest TLS version accepted: "1.2" (default) or "1.3".
+	MinVersion string `yaml:"min_version" toml:"min_version"`
+}
+
+// IsEnabled returns true if a server certificate is configured.
+func (t *TLSConfig) IsEnabled() bool {
+	return t.CertFile != ""
+}
+
+// GetClientAuth returns the client authentication mode with default fallback.
+func (t *TLSConfig) GetClientAuth() string {
+	if t.ClientAuth == "" {
+		return TLSClientAuthRequire
+	}
+	return t.ClientAuth
+}
+
+// GetMinVersion returns the minimum TLS version with default fallback.
+func (t *TLSConfig) GetMinVersion() string {
+	if t.MinVersion == "" {
+		return TLSVersion12
+	}
+	return t.MinVersion
 }
 
 // AuthConfig defines authentication settings for the proxy.
 type AuthConfig struct {
+	// Clients are virtual API keys, one per client. A request authenticated with
+	// one of them carries the client's identity through logging, metrics and routing.
+	Clients []ClientKeyConfig `yaml:"clients" toml:"clients"`
+
 	// APIKey is the expected value for x-api-key header authentication.
 	// If empty, API key authentication is disabled.
 	APIKey string `json:"-" yaml:"api_key" toml:"api_key"`
@@ -122,6 +316,9 @@ type AuthConfig struct {
 	// If empty but AllowBearer is true, any bearer token is accepted.
 	BearerSecret string `json:"-" yaml:"bearer_secret" toml:"bearer_secret"`
 
+	// JWT accepts tokens issued by an identity provider, mapping their claims to a client.
+	JWT JWTConfig `yaml:"jwt" toml:"jwt"`
+
 	// AllowBearer enables Authorization: Bearer token authentication.
 	// Used by Claude Code subscription users.
 	AllowBearer bool `yaml:"allow_bearer" toml:"allow_bearer"`
@@ -134,7 +331,7 @@ type AuthConfig struct {
 
 // IsEnabled returns true if any authentication method is configured.
 func (a *AuthConfig) IsEnabled() bool {
-	return a.APIKey != "" || a.AllowBearer || a.AllowSubscription
+	return a.APIKey != "" || a.AllowBearer || a.AllowSubscription || len(a.Clients) > 0 || a.JWT.IsEnabled()
 }
 
 // IsBearerEnabled returns true if Bearer token authentication is enabled.
@@ -143,6 +340,141 @@ func (a *AuthConfig) IsBearerEnabled() bool {
 	return a.AllowBearer || a.AllowSubscription
 }
 
+// ClientKeyConfig defines a virtual API key issued to a single client.
+// Clients send the key as x-api-key or as a Bearer token; only its hash is configured.
+// Clients may instead, or also, authenticate with a TLS client certificate.
+type ClientKeyConfig struct {
+	// Metadata is free-form client information (team, owner, cost center).
+	// It is attached to the client's log lines.
+	Metadata map[string]string `yaml:"metadata" toml:"metadata"`
+
+	// Name identifies the client in logs, metrics and traces. Must be unique.
+	Name string `yaml:"name" toml:"name"`
+
+	// KeyHash is the hex-encoded SHA-256 hash of the client's key.
+	// Optional for clients that authenticate with a certificate.
+	KeyHash string `json:"-" yaml:"key_hash" toml:"key_hash"`
+
+	// CertNames identify the client by TLS client certificate: the subject
+	// common name or a DNS, email or URI subject alternative name.
+	// Requires server.tls.client_ca_file.
+	CertNames []string `yaml:"cert_names" toml:"cert_names"`
+
+	// AllowedModels restricts the models the client may request, matched by
+	// prefix like routing.model_mapping. Empty allows all models.
+	AllowedModels []string `yaml:"allowed_models" toml:"allowed_models"`
+
+	// AllowedProviders restricts the providers the client's requests are routed to.
+	// Empty allows all providers.
+	AllowedProviders []string `yaml:"allowed_providers" toml:"allowed_providers"`
+
+	// Budget caps the client's token usage and spend. Empty is unlimited.
+	Budget budget.Limits `yaml:"budget" toml:"budget"`
+
+	// RateLimit caps the client's requests and tokens per minute and its
+	// requests in flight. Empty uses rate_limits.clients.
+	RateLimit ratelimit.Limits `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+const (
+	// DefaultJWTClientClaim is the claim naming the client when jwt.client_claim is unset.
+	DefaultJWTClientClaim = "sub"
+
+	// DefaultJWTGroupsClaim is the claim listing groups when jwt.groups_claim is unset.
+	DefaultJWTGroupsClaim = "groups"
+
+	// DefaultJWKSRefresh is how often the JWKS is reloaded when jwt.jwks_refresh_ms is unset.
+	DefaultJWKSRefresh = time.Hour
+
+	// DefaultJWTLeeway is the clock skew tolerated when jwt.leeway_ms is unset.
+	DefaultJWTLeeway = time.Minute
+)
+
+// JWTConfig accepts JWTs issued by an identity provider, such as SSO-issued
+// OIDC tokens. Tokens are verified against the provider's JWKS and resolved
+// to a client named by a claim, with permissions granted by group.
+type JWTConfig struct {
+	// Issuer is the required iss claim. Empty accepts any issuer.
+	Issuer string `yaml:"issuer" toml:"issuer"`
+
+	// JWKSURL is where the provider publishes its signing keys (its jwks_uri).
+	JWKSURL string `yaml:"jwks_url" toml:"jwks_url"`
+
+	// JWKSFile is a local JWKS, for issuers the relay cannot reach.
+	// Exactly one of JWKSURL and JWKSFile enables JWT authentication.
+	JWKSFile string `yaml:"jwks_file" toml:"jwks_file"`
+
+	// ClientClaim names the client in logs, metrics and routing rules. Default: sub.
+	ClientClaim string `yaml:"client_claim" toml:"client_claim"`
+
+	// GroupsClaim lists the groups of the client. Default: groups.
+	GroupsClaim string `yaml:"groups_claim" toml:"groups_claim"`
+
+	// Audiences are accepted aud values. Empty accepts any audience.
+	Audiences []string `yaml:"audiences" toml:"audiences"`
+
+	// Groups grant permissions by group. When set, tokens must be in at least
+	// one of them; otherwise every valid token has full access.
+	Groups []JWTGroupConfig `yaml:"groups" toml:"groups"`
+
+	// JWKSRefreshMS is how often the JWKS is reloaded. Default: 1 hour.
+	JWKSRefreshMS int `yaml:"jwks_refresh_ms" toml:"jwks_refresh_ms"`
+
+	// LeewayMS is the clock skew tolerated when checking exp and nbf. Default: 1 minute.
+	LeewayMS int `yaml:"leeway_ms" toml:"leeway_ms"`
+}
+
+// JWTGroupConfig grants permissions to the members of a group.
+type JWTGroupConfig struct {
+	// Name is the group as it appears in the groups claim.
+	Name string `yaml:"name" toml:"name"`
+
+	// AllowedModels restricts the models members may request, matched by prefix.
+	// Empty allows all models.
+	AllowedModels []string `yaml:"allowed_models" toml:"allowed_models"`
+
+	// AllowedProviders restricts the providers members' requests are routed to.
+	// Empty allows all providers.
+	AllowedProviders []string `yaml:"allowed_providers" toml:"allowed_providers"`
+}
+
+// IsEnabled returns true if a JWKS is configured.
+func (j *JWTConfig) IsEnabled() bool {
+	return j.JWKSURL != "" || j.JWKSFile != ""
+}
+
+// GetClientClaim returns the client name claim with default fallback.
+func (j *JWTConfig) GetClientClaim() string {
+	if j.ClientClaim == "" {
+		return DefaultJWTClientClaim
+	}
+	return j.ClientClaim
+}
+
+// GetGroupsClaim returns the groups claim with default fallback.
+func (j *JWTConfig) GetGroupsClaim() string {
+	if j.GroupsClaim == "" {
+		return DefaultJWTGroupsClaim
+	}
+	return j.GroupsClaim
+}
+
+// GetJWKSRefresh returns the JWKS reload interval with default fallback.
+func (j *JWTConfig) GetJWKSRefresh() time.Duration {
+	if j.JWKSRefreshMS <= 0 {
+		return DefaultJWKSRefresh
+	}
+	return time.Duration(j.JWKSRefreshMS) * time.Millisecond
+}
+
+// GetLeeway returns the tolerated clock skew with default fallback.
+func (j *JWTConfig) GetLeeway() time.Duration {
+	if j.LeewayMS <= 0 {
+		return DefaultJWTLeeway
+	}
+	return time.Duration(j.LeewayMS) * time.Millisecond
+}
+
 // GetEffectiveAPIKey returns the API key from Auth config or falls back to legacy ServerConfig.APIKey.
 func (s *ServerConfig) GetEffectiveAPIKey() string {
 	if s.Auth.APIKey != "" {
@@ -151,24 +483,324 @@ func (s *ServerConfig) GetEffectiveAPIKey() string {
 	return s.APIKey
 }
 
+// DefaultMetricsPath is the path the Prometheus endpoint is served on when
+// metrics.path is not set.
+const DefaultMetricsPath = "/metrics"
+
+// MetricsConfig controls the Prometheus metrics endpoint.
+type MetricsConfig struct {
+	// Path is the HTTP path metrics are served on. Default: /metrics.
+	Path string `yaml:"path" toml:"path"`
+
+	// Enabled exposes the metrics endpoint on the server listener.
+	// The endpoint does not require authentication, like /health.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// GetPath returns the metrics path with default fallback.
+func (m *MetricsConfig) GetPath() string {
+	if m.Path == "" {
+		return DefaultMetricsPath
+	}
+	return m.Path
+}
+
+// AdminPath is the path prefix of the admin API.
+const AdminPath = "/admin"
+
+// AdminConfig controls the admin API served under /admin.
+type AdminConfig struct {
+	// Token authenticates admin requests as "Authorization: Bearer <token>".
+	// It is separate from client credentials, which never grant admin access.
+	// Supports environment variable expansion, e.g. ${CC_RELAY_ADMIN_TOKEN}.
+	Token string `yaml:"token" toml:"token"`
+
+	// Enabled exposes the admin API on the server listener.
+	// Enabling it requires a restart; the token is hot-reloadable.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// Response cache defaults used when response_cache leaves a setting unset.
+const (
+	// DefaultResponseCacheTTL is how long a cached response is replayed.
+	DefaultResponseCacheTTL = 10 * time.Minute
+
+	// DefaultResponseCacheMaxEntryBytes is the largest response body cached.
+	DefaultResponseCacheMaxEntryBytes = 1 << 20
+)
+
+// ResponseCacheConfig controls the exact-match response cache for
+// /v1/messages. Responses are cached for deterministic requests, those with
+// temperature 0, and for requests that opt in with the X-CC-Relay-Cache
+// header. Entries are stored in the cache backend configured under cache.
+type ResponseCacheConfig struct {
+	// TTLMS is how long in milliseconds a response is replayed.
+	// Default: 600000 (10 minutes).
+	TTLMS int `yaml:"ttl_ms" toml:"ttl_ms"`
+
+	// MaxEntryBytes is the largest response body that is cached; larger
+	// responses are passed through uncached. Default: 1048576 (1 MiB).
+	MaxEntryBytes int `yaml:"max_entry_bytes" toml:"max_entry_bytes"`
+
+	// Enabled turns the response cache on.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// GetTTL returns the response TTL with default fallback.
+func (r *ResponseCacheConfig) GetTTL() time.Duration {
+	if r.TTLMS <= 0 {
+		return DefaultResponseCacheTTL
+	}
+	return time.Duration(r.TTLMS) * time.Millisecond
+}
+
+// GetMaxEntryBytes returns the maximum cached body size with default fallback.
+func (r *ResponseCacheConfig) GetMaxEntryBytes() int {
+	if r.MaxEntryBytes <= 0 {
+		return DefaultResponseCacheMaxEntryBytes
+	}
+	return r.MaxEntryBytes
+}
+
+// DefaultKeyStateSaveInterval is how often key state is saved when
+// key_state.save_interval_ms is unset.
+const DefaultKeyStateSaveInterval = 10 * time.Second
+
+// KeyStateConfig controls persisting what key pools learn from provider
+// responses (rate limits, remaining capacity and 429 cooldowns), so a
+// restarted relay does not retry keys it knows are exhausted.
+type KeyStateConfig struct {
+	// Path is the JSON file key state is saved to. If empty, key state is
+	// saved to the cache backend configured under cache.
+	Path string `yaml:"path" toml:"path"`
+
+	// SaveIntervalMS is how often key state is saved in milliseconds. It is
+	// also saved on shutdown. Default: 10000 (10 seconds).
+	SaveIntervalMS int `yaml:"save_interval_ms" toml:"save_interval_ms"`
+
+	// Enabled turns key state persistence on.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+// GetSaveInterval returns the save interval with default fallback.
+func (k *KeyStateConfig) GetSaveInterval() time.Duration {
+	if k.SaveIntervalMS <= 0 {
+		return DefaultKeyStateSaveInterval
+	}
+	return time.Duration(k.SaveIntervalMS) * time.Millisecond
+}
+
+// AccessConfig holds access policies, which allow or deny requests by client,
+// model, provider and request features after the client is authenticated.
+type AccessConfig struct {
+	// DefaultEffect applies to requests no policy matches: "allow" (default)
+	// or "deny".
+	DefaultEffect string `yaml:"default_effect" toml:"default_effect"`
+
+	// Policies are evaluated in order; the first matching policy decides.
+	Policies []policy.Policy `yaml:"policies" toml:"policies"`
+}
+
+// IsEnabled returns true if any request can be denied.
+func (a *AccessConfig) IsEnabled() bool {
+	return len(a.Policies) > 0 || a.GetDefaultEffect() == policy.EffectDeny
+}
+
+// GetDefaultEffect returns the default effect with default fallback.
+func (a *AccessConfig) GetDefaultEffect() string {
+	if a.DefaultEffect == "" {
+		return policy.EffectAllow
+	}
+	return a.DefaultEffect
+}
+
+// RateLimitsConfig holds the limits enforced on inbound requests, before they
+// reach a provider. Requests and tokens are shared by all instances when the
+// cache runs in HA mode; requests in flight are limited per instance.
+type RateLimitsConfig struct {
+	// Clients apply to each client without its own rate_limit.
+	Clients ratelimit.Limits `yaml:"clients" toml:"clients"`
+
+	// IPs apply to each client IP address, for requests without a client.
+	IPs ratelimit.Limits `yaml:"ips" toml:"ips"`
+}
+
+// ClientLimits returns the limits for the named client: its own rate_limit,
+// or the clients default.
+func (r *RateLimitsConfig) ClientLimits(clients []ClientKeyConfig, name string) ratelimit.Limits {
+	for idx := range clients {
+		if clients[idx].Name == name && !clients[idx].RateLimit.IsZero() {
+			return clients[idx].RateLimit
+		}
+	}
+	return r.Clients
+}
+
+// Tracing export protocols.
+const (
+	TracingProtocolGRPC = "grpc"
+	TracingProtocolHTTP = "http"
+)
+
+// DefaultTracingServiceName is the service.name reported with exported spans.
+const DefaultTracingServiceName = "cc-relay"
+
+// ObservabilityConfig groups telemetry export settings.
+type ObservabilityConfig struct {
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+}
+
+// TracingConfig controls OpenTelemetry trace export over OTLP.
+type TracingConfig struct {
+	// Headers are sent with every export, e.g. credentials for a hosted collector.
+	Headers map[string]string `yaml:"headers" toml:"headers"`
+
+	// Endpoint is the collector address, as host:port or a full URL.
+	// When empty, the OTEL_EXPORTER_OTLP_* environment variables and the
+	// exporter defaults (localhost:4317 for grpc, localhost:4318 for http) apply.
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+
+	// Protocol is the OTLP transport: "grpc" (default) or "http".
+	Protocol string `yaml:"protocol" toml:"protocol"`
+
+	// ServiceName is reported as service.name. Default: cc-relay.
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+
+	// SampleRatio is the fraction of new traces to record, between 0 and 1.
+	// Zero means 1 (record everything). Sampling decisions propagated by the
+	// client in traceparent are always honored.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+
+	// Enabled turns on span creation and export.
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+
+	// Insecure disables TLS to the collector.
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+}
+
+// GetProtocol returns the OTLP protocol with default fallback.
+func (t *TracingConfig) GetProtocol() string {
+	if t.Protocol == "" {
+		return TracingProtocolGRPC
+	}
+	return t.Protocol
+}
+
+// GetServiceName returns the service name with default fallback.
+func (t *TracingConfig) GetServiceName() string {
+	if t.ServiceName == "" {
+		return DefaultTracingServiceName
+	}
+	return t.ServiceName
+}
+
+// GetSampleRatio returns the sample ratio, treating zero as 1.
+func (t *TracingConfig) GetSampleRatio() float64 {
+	if t.SampleRatio == 0 {
+		return 1
+	}
+	return t.SampleRatio
+}
+
 // ProviderConfig defines configuration for a backend LLM provider.
 type ProviderConfig struct {
-	ModelMapping       map[string]string `yaml:"model_mapping" toml:"model_mapping"`
-	AWSRegion          string            `yaml:"aws_region" toml:"aws_region"`
-	GCPProjectID       string            `yaml:"gcp_project_id" toml:"gcp_project_id"`
-	AzureAPIVersion    string            `yaml:"azure_api_version" toml:"azure_api_version"`
-	Name               string            `yaml:"name" toml:"name"`
-	Type               string            `yaml:"type" toml:"type"`
-	BaseURL            string            `yaml:"base_url" toml:"base_url"`
-	AzureDeploymentID  string            `yaml:"azure_deployment_id" toml:"azure_deployment_id"`
-	AWSAccessKeyID     string            `yaml:"aws_access_key_id" toml:"aws_access_key_id"`
-	AzureResourceName  string            `yaml:"azure_resource_name" toml:"azure_resource_name"`
-	AWSSecretAccessKey string            `yaml:"aws_secret_access_key" toml:"aws_secret_access_key"`
-	GCPRegion          string            `yaml:"gcp_region" toml:"gcp_region"`
-	Keys               []KeyConfig       `yaml:"keys" toml:"keys"`
-	Models             []string          `yaml:"models" toml:"models"`
-	Pooling            PoolingConfig     `yaml:"pooling" toml:"pooling"`
-	Enabled            bool              `yaml:"enabled" toml:"enabled"`
+	ModelMapping       map[string]string      `yaml:"model_mapping" toml:"model_mapping"`
+	Prices             map[string]ModelPrice  `yaml:"prices" toml:"prices"`             // By requested model prefix
+	ModelLimits        map[string]ModelLimits `yaml:"model_limits" toml:"model_limits"` // By requested model prefix
+	AWSRegion          string                 `yaml:"aws_region" toml:"aws_region"`
+	GCPProjectID       string                 `yaml:"gcp_project_id" toml:"gcp_project_id"`
+	AzureAPIVersion    string                 `yaml:"azure_api_version" toml:"azure_api_version"`
+	Name               string                 `yaml:"name" toml:"name"`
+	Type               string                 `yaml:"type" toml:"type"`
+	BaseURL            string                 `yaml:"base_url" toml:"base_url"`
+	AzureDeploymentID  string                 `yaml:"azure_deployment_id" toml:"azure_deployment_id"`
+	AWSAccessKeyID     string                 `yaml:"aws_access_key_id" toml:"aws_access_key_id"`
+	AzureResourceName  string                 `yaml:"azure_resource_name" toml:"azure_resource_name"`
+	AWSSecretAccessKey string                 `yaml:"aws_secret_access_key" toml:"aws_secret_access_key"`
+	GCPRegion          string                 `yaml:"gcp_region" toml:"gcp_region"`
+	Trust              string                 `yaml:"trust" toml:"trust"` // DLP trust level: untrusted (default), trusted
+	Keys               []KeyConfig            `yaml:"keys" toml:"keys"`
+	Models             []string               `yaml:"models" toml:"models"`
+	Pooling            PoolingConfig          `yaml:"pooling" toml:"pooling"`
+	Enabled            bool                   `yaml:"enabled" toml:"enabled"`
+}
+
+// ModelPrice is what a provider charges for requests for a model, in USD per
+// million tokens, and how capable the model serving them is. Providers key
+// prices by the model name prefix clients request, before model mapping.
+// Used by cost_optimized routing and to count spend budgets.
+type ModelPrice struct {
+	InputPerMTok      float64 `yaml:"input_per_mtok" toml:"input_per_mtok"`
+	OutputPerMTok     float64 `yaml:"output_per_mtok" toml:"output_per_mtok"`
+	CacheWritePerMTok float64 `yaml:"cache_write_per_mtok" toml:"cache_write_per_mtok"` // Default: input price
+	CacheReadPerMTok  float64 `yaml:"cache_read_per_mtok" toml:"cache_read_per_mtok"`   // Default: input price
+
+	// Quality rates the model the provider serves these requests with, on a
+	// scale of the operator's choosing; higher is more capable. Compared against
+	// routing.quality_floor. Unset (0) never meets a floor.
+	Quality int `yaml:"quality" toml:"quality"`
+}
+
+// BudgetPrice returns the price for counting spend budgets.
+func (p ModelPrice) BudgetPrice() budget.Price {
+	return budget.Price{
+		InputPerMTok:      p.InputPerMTok,
+		OutputPerMTok:     p.OutputPerMTok,
+		CacheWritePerMTok: p.CacheWritePerMTok,
+		CacheReadPerMTok:  p.CacheReadPerMTok,
+	}
+}
+
+// IsTrusted reports whether DLP lets the provider receive sensitive data.
+func (p *ProviderConfig) IsTrusted() bool {
+	return p.Trust == dlp.TrustTrusted
+}
+
+// PriceFor returns the provider's price for requests for model, using the
+// longest matching prefix.
+func (p *ProviderConfig) PriceFor(model string) (ModelPrice, bool) {
+	return longestPrefixMatch(p.Prices, model)
+}
+
+// ModelLimits describes how large a request a provider can serve for a model.
+// Providers key limits by the model name prefix clients request. Requests
+// that exceed a provider's limits are routed to another provider. Unset (0)
+// means unlimited.
+type ModelLimits struct {
+	// ContextWindow is the most input tokens the model accepts.
+	ContextWindow int `yaml:"context_window" toml:"context_window"`
+	// MaxOutputTokens is the largest max_tokens the model accepts.
+	MaxOutputTokens int `yaml:"max_output_tokens" toml:"max_output_tokens"`
+}
+
+// Fits reports whether a request with the given input token estimate and
+// max_tokens is within the limits.
+func (l ModelLimits) Fits(inputTokens, maxTokens int) bool {
+	if l.ContextWindow > 0 && inputTokens > l.ContextWindow {
+		return false
+	}
+	return l.MaxOutputTokens <= 0 || maxTokens <= l.MaxOutputTokens
+}
+
+// LimitsFor returns the provider's limits for requests for model, using the
+// longest matching prefix.
+func (p *ProviderConfig) LimitsFor(model string) (ModelLimits, bool) {
+	return longestPrefixMatch(p.ModelLimits, model)
+}
+
+// longestPrefixMatch returns the value whose key is the longest prefix of model.
+func longestPrefixMatch[V any](values map[string]V, model string) (V, bool) {
+	var (
+		best  V
+		found bool
+		depth int
+	)
+	for prefix, value := range values {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > depth) {
+			best, found, depth = value, true, len(prefix)
+		}
+	}
+	return best, found
 }
 
 // PoolingConfig defines key pool behavior for a provider.
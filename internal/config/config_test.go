@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 	"github.com/rs/zerolog"
 )
 
@@ -14,9 +16,11 @@ func zeroServerConfig() config.ServerConfig {
 	return config.ServerConfig{
 		Listen: "",
 		APIKey: "",
+		TLS:    config.MakeTestTLSConfig(),
 		Auth: config.AuthConfig{
-			APIKey: "", BearerSecret: "",
-			AllowBearer: false, AllowSubscription: false,
+			Clients: nil,
+			APIKey:  "", BearerSecret: "",
+			JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false,
 		},
 		TimeoutMS: 0, MaxConcurrent: 0, MaxBodyBytes: 0, EnableHTTP2: false,
 	}
@@ -25,7 +29,9 @@ func zeroServerConfig() config.ServerConfig {
 // zeroRoutingConfig returns a RoutingConfig with all fields zeroed.
 func zeroRoutingConfig() config.RoutingConfig {
 	return config.RoutingConfig{
-		ModelMapping: nil, Strategy: "", DefaultProvider: "",
+		ModelMapping: nil, QualityFloor: nil, Rules: nil, Strategy: "", DefaultProvider: "",
+		SessionAffinity: config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+		Hedging:         config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 		FailoverTimeout: 0, Debug: false,
 	}
 }
@@ -33,10 +39,10 @@ func zeroRoutingConfig() config.RoutingConfig {
 // zeroProviderConfig returns a ProviderConfig with all fields zeroed.
 func zeroProviderConfig() config.ProviderConfig {
 	return config.ProviderConfig{
-		ModelMapping: nil, AWSRegion: "", GCPProjectID: "",
+		ModelMapping: nil, Prices: nil, ModelLimits: nil, AWSRegion: "", GCPProjectID: "",
 		AzureAPIVersion: "", Name: "", Type: "", BaseURL: "",
 		AzureDeploymentID: "", AWSAccessKeyID: "", AzureResourceName: "",
-		AWSSecretAccessKey: "", GCPRegion: "",
+		AWSSecretAccessKey: "", GCPRegion: "", Trust: "",
 		Keys: nil, Models: nil,
 		Pooling: config.PoolingConfig{Strategy: "", Enabled: false},
 		Enabled: false,
@@ -46,8 +52,9 @@ func zeroProviderConfig() config.ProviderConfig {
 // zeroAuthConfig returns an AuthConfig with all fields zeroed.
 func zeroAuthConfig() config.AuthConfig {
 	return config.AuthConfig{
-		APIKey: "", BearerSecret: "",
-		AllowBearer: false, AllowSubscription: false,
+		Clients: nil,
+		APIKey:  "", BearerSecret: "",
+		JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false,
 	}
 }
 
@@ -176,38 +183,55 @@ func TestAuthConfigIsEnabled(t *testing.T) {
 		{"no auth configured", zeroAuthConfig(), false},
 		{
 			"api key only",
-			config.AuthConfig{APIKey: testKeyDashValue, BearerSecret: "",
-				AllowBearer: false, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: testKeyDashValue, BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false},
 			true,
 		},
 		{
 			"bearer only",
-			config.AuthConfig{APIKey: "", BearerSecret: "",
-				AllowBearer: true, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: true, AllowSubscription: false},
 			true,
 		},
 		{
 			"both configured",
-			config.AuthConfig{APIKey: testKeyDashValue, BearerSecret: "",
-				AllowBearer: true, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: testKeyDashValue, BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: true, AllowSubscription: false},
 			true,
 		},
 		{
 			"bearer secret without allow bearer",
-			config.AuthConfig{APIKey: "", BearerSecret: "secret",
-				AllowBearer: false, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "secret",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false},
 			false,
 		},
 		{
 			"subscription only",
-			config.AuthConfig{APIKey: "", BearerSecret: "",
-				AllowBearer: false, AllowSubscription: true},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: true},
 			true,
 		},
 		{
 			"subscription and api key",
-			config.AuthConfig{APIKey: testKeyDashValue, BearerSecret: "",
-				AllowBearer: false, AllowSubscription: true},
+			config.AuthConfig{Clients: nil, APIKey: testKeyDashValue, BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: true},
+			true,
+		},
+		{
+			"virtual keys only",
+			config.AuthConfig{Clients: []config.ClientKeyConfig{{
+				Metadata: nil, Name: "alice", KeyHash: "", CertNames: nil, AllowedModels: nil, AllowedProviders: nil,
+				Budget: config.MakeTestBudgetLimits(), RateLimit: config.MakeTestRateLimits(),
+			}}, APIKey: "", BearerSecret: "", JWT: config.MakeTestJWTConfig(),
+				AllowBearer: false, AllowSubscription: false},
+			true,
+		},
+		{
+			"jwt only",
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "", JWT: config.JWTConfig{
+				Issuer: "", JWKSURL: "https://sso.example.com/jwks.json", JWKSFile: "", ClientClaim: "",
+				GroupsClaim: "", Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
+			}, AllowBearer: false, AllowSubscription: false},
 			true,
 		},
 	}
@@ -233,32 +257,32 @@ func TestAuthConfigIsBearerEnabled(t *testing.T) {
 		{"no bearer configured", zeroAuthConfig(), false},
 		{
 			"allow_bearer true",
-			config.AuthConfig{APIKey: "", BearerSecret: "",
-				AllowBearer: true, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: true, AllowSubscription: false},
 			true,
 		},
 		{
 			"allow_subscription true",
-			config.AuthConfig{APIKey: "", BearerSecret: "",
-				AllowBearer: false, AllowSubscription: true},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: true},
 			true,
 		},
 		{
 			"both bearer and subscription",
-			config.AuthConfig{APIKey: "", BearerSecret: "",
-				AllowBearer: true, AllowSubscription: true},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: true, AllowSubscription: true},
 			true,
 		},
 		{
 			"api key only does not enable bearer",
-			config.AuthConfig{APIKey: testKeyDashValue, BearerSecret: "",
-				AllowBearer: false, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: testKeyDashValue, BearerSecret: "",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false},
 			false,
 		},
 		{
 			"bearer secret without allow flag",
-			config.AuthConfig{APIKey: "", BearerSecret: "secret",
-				AllowBearer: false, AllowSubscription: false},
+			config.AuthConfig{Clients: nil, APIKey: "", BearerSecret: "secret",
+				JWT: config.MakeTestJWTConfig(), AllowBearer: false, AllowSubscription: false},
 			false,
 		},
 	}
@@ -348,6 +372,48 @@ func TestDebugOptionsGetMaxBodyLogSize(t *testing.T) {
 	}
 }
 
+func TestMetricsConfigGetPath(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.MetricsConfig{Path: "", Enabled: true}
+	if got := cfg.GetPath(); got != config.DefaultMetricsPath {
+		t.Errorf("GetPath() = %q, want %q", got, config.DefaultMetricsPath)
+	}
+
+	cfg.Path = "/internal/metrics"
+	if got := cfg.GetPath(); got != "/internal/metrics" {
+		t.Errorf("GetPath() = %q, want %q", got, "/internal/metrics")
+	}
+}
+
+func TestTracingConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	var cfg config.TracingConfig
+	if got := cfg.GetProtocol(); got != config.TracingProtocolGRPC {
+		t.Errorf("GetProtocol() = %q, want %q", got, config.TracingProtocolGRPC)
+	}
+	if got := cfg.GetServiceName(); got != config.DefaultTracingServiceName {
+		t.Errorf("GetServiceName() = %q, want %q", got, config.DefaultTracingServiceName)
+	}
+	if got := cfg.GetSampleRatio(); got != 1 {
+		t.Errorf("GetSampleRatio() = %v, want 1", got)
+	}
+
+	cfg.Protocol = config.TracingProtocolHTTP
+	cfg.ServiceName = "relay-prod"
+	cfg.SampleRatio = 0.25
+	if got := cfg.GetProtocol(); got != config.TracingProtocolHTTP {
+		t.Errorf("GetProtocol() = %q, want %q", got, config.TracingProtocolHTTP)
+	}
+	if got := cfg.GetServiceName(); got != "relay-prod" {
+		t.Errorf("GetServiceName() = %q, want %q", got, "relay-prod")
+	}
+	if got := cfg.GetSampleRatio(); got != 0.25 {
+		t.Errorf("GetSampleRatio() = %v, want 0.25", got)
+	}
+}
+
 func TestDebugOptionsIsEnabled(t *testing.T) {
 	t.Parallel()
 
@@ -642,6 +708,94 @@ func TestRoutingConfigOptionOrElsePattern(t *testing.T) {
 	}
 }
 
+func TestRoutingConfigQualityFloorFor(t *testing.T) {
+	t.Parallel()
+
+	routing := zeroRoutingConfig()
+	routing.QualityFloor = map[string]int{"claude": 3, "claude-opus": 9}
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"claude-opus-4", 9},
+		{"claude-sonnet-4", 3},
+		{"gpt-4o", 0},
+	}
+	for _, testCase := range tests {
+		if got := routing.QualityFloorFor(testCase.model); got != testCase.want {
+			t.Errorf("QualityFloorFor(%q) = %d, want %d", testCase.model, got, testCase.want)
+		}
+	}
+}
+
+func TestProviderConfigPriceFor(t *testing.T) {
+	t.Parallel()
+
+	provider := zeroProviderConfig()
+	provider.Prices = map[string]config.ModelPrice{
+		"claude":      {InputPerMTok: 1, OutputPerMTok: 5, CacheWritePerMTok: 0, CacheReadPerMTok: 0, Quality: 5},
+		"claude-opus": {InputPerMTok: 15, OutputPerMTok: 75, CacheWritePerMTok: 0, CacheReadPerMTok: 0, Quality: 9},
+	}
+
+	price, ok := provider.PriceFor("claude-opus-4")
+	if !ok || price.Quality != 9 {
+		t.Errorf("PriceFor(claude-opus-4) = %+v, %v, want the claude-opus price", price, ok)
+	}
+	if got := price.BudgetPrice(); got.InputPerMTok != 15 || got.OutputPerMTok != 75 {
+		t.Errorf("BudgetPrice() = %+v, want input 15 and output 75", got)
+	}
+	if price, ok := provider.PriceFor("claude-haiku-4"); !ok || price.Quality != 5 {
+		t.Errorf("PriceFor(claude-haiku-4) = %+v, %v, want the claude price", price, ok)
+	}
+	if _, ok := provider.PriceFor("gpt-4o"); ok {
+		t.Error("PriceFor(gpt-4o) = true, want false for an unpriced model")
+	}
+}
+
+func TestProviderConfigLimitsFor(t *testing.T) {
+	t.Parallel()
+
+	provider := zeroProviderConfig()
+	provider.ModelLimits = map[string]config.ModelLimits{
+		"qwen":     {ContextWindow: 32768, MaxOutputTokens: 8192},
+		"qwen3-1m": {ContextWindow: 1000000, MaxOutputTokens: 0},
+	}
+
+	limits, ok := provider.LimitsFor("qwen3-1m-instruct")
+	if !ok || limits.ContextWindow != 1000000 {
+		t.Errorf("LimitsFor(qwen3-1m-instruct) = %+v, %v, want the qwen3-1m limits", limits, ok)
+	}
+	if _, ok := provider.LimitsFor("claude-opus-4"); ok {
+		t.Error("LimitsFor(claude-opus-4) = true, want false for a model without limits")
+	}
+}
+
+func TestModelLimitsFits(t *testing.T) {
+	t.Parallel()
+
+	limits := config.ModelLimits{ContextWindow: 32768, MaxOutputTokens: 8192}
+	tests := []struct {
+		inputTokens int
+		maxTokens   int
+		want        bool
+	}{
+		{30000, 4096, true},
+		{32768, 8192, true},
+		{40000, 4096, false},
+		{1000, 16384, false},
+	}
+	for _, tt := range tests {
+		if got := limits.Fits(tt.inputTokens, tt.maxTokens); got != tt.want {
+			t.Errorf("Fits(%d, %d) = %v, want %v", tt.inputTokens, tt.maxTokens, got, tt.want)
+		}
+	}
+
+	if !(config.ModelLimits{ContextWindow: 0, MaxOutputTokens: 0}).Fits(1<<30, 1<<20) {
+		t.Error("Fits() = false for unset limits, want true")
+	}
+}
+
 func TestProviderConfigGetAzureAPIVersion(t *testing.T) {
 	t.Parallel()
 
@@ -760,3 +914,183 @@ func TestProviderConfigValidateCloudConfigAzure(t *testing.T) {
 		}
 	})
 }
+
+func TestSessionAffinityConfigGetTTL(t *testing.T) {
+	t.Parallel()
+
+	unset := config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: true}
+	if got := unset.GetTTL(); got != config.DefaultSessionAffinityTTL {
+		t.Errorf("GetTTL() = %v, want default %v", got, config.DefaultSessionAffinityTTL)
+	}
+	set := config.SessionAffinityConfig{Header: "", TTLMS: 90000, Enabled: true}
+	if got := set.GetTTL(); got != 90*time.Second {
+		t.Errorf("GetTTL() = %v, want 90s", got)
+	}
+}
+
+func TestHedgingConfig(t *testing.T) {
+	t.Parallel()
+
+	hedging := config.HedgingConfig{Models: []string{"claude-haiku"}, Percentile: 0, DelayMS: 0, Enabled: true}
+	if !hedging.AppliesTo("claude-haiku-4-5") || hedging.AppliesTo("claude-opus-4") {
+		t.Error("AppliesTo() should match the configured model prefixes only")
+	}
+	if got := hedging.GetPercentile(); got != config.DefaultHedgePercentile {
+		t.Errorf("GetPercentile() = %v, want default %v", got, config.DefaultHedgePercentile)
+	}
+	if got := hedging.GetDelay(); got != config.DefaultHedgeDelay {
+		t.Errorf("GetDelay() = %v, want default %v", got, config.DefaultHedgeDelay)
+	}
+
+	hedging.Models = nil
+	if !hedging.AppliesTo("claude-opus-4") {
+		t.Error("AppliesTo() without models should match every model")
+	}
+	hedging.Enabled = false
+	if hedging.AppliesTo("claude-haiku-4-5") {
+		t.Error("AppliesTo() should be false when hedging is disabled")
+	}
+}
+
+func TestResponseCacheConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	unset := config.ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: 0, Enabled: true}
+	if got := unset.GetTTL(); got != config.DefaultResponseCacheTTL {
+		t.Errorf("GetTTL() = %v, want default %v", got, config.DefaultResponseCacheTTL)
+	}
+	if got := unset.GetMaxEntryBytes(); got != config.DefaultResponseCacheMaxEntryBytes {
+		t.Errorf("GetMaxEntryBytes() = %d, want default %d", got, config.DefaultResponseCacheMaxEntryBytes)
+	}
+
+	set := config.ResponseCacheConfig{TTLMS: 30000, MaxEntryBytes: 4096, Enabled: true}
+	if got := set.GetTTL(); got != 30*time.Second {
+		t.Errorf("GetTTL() = %v, want 30s", got)
+	}
+	if got := set.GetMaxEntryBytes(); got != 4096 {
+		t.Errorf("GetMaxEntryBytes() = %d, want 4096", got)
+	}
+}
+
+func TestKeyStateConfigGetSaveInterval(t *testing.T) {
+	t.Parallel()
+
+	unset := config.KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: true}
+	if got := unset.GetSaveInterval(); got != config.DefaultKeyStateSaveInterval {
+		t.Errorf("GetSaveInterval() = %v, want default %v", got, config.DefaultKeyStateSaveInterval)
+	}
+
+	set := config.KeyStateConfig{Path: "", SaveIntervalMS: 2500, Enabled: true}
+	if got := set.GetSaveInterval(); got != 2500*time.Millisecond {
+		t.Errorf("GetSaveInterval() = %v, want 2.5s", got)
+	}
+}
+
+func TestAccessConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	access := config.MakeTestAccessConfig()
+	if access.IsEnabled() {
+		t.Error("IsEnabled() = true without policies")
+	}
+	if got := access.GetDefaultEffect(); got != policy.EffectAllow {
+		t.Errorf("GetDefaultEffect() = %q, want %q", got, policy.EffectAllow)
+	}
+
+	access.DefaultEffect = policy.EffectDeny
+	if !access.IsEnabled() {
+		t.Error("IsEnabled() = false with default_effect deny")
+	}
+	if got := access.GetDefaultEffect(); got != policy.EffectDeny {
+		t.Errorf("GetDefaultEffect() = %q, want %q", got, policy.EffectDeny)
+	}
+}
+
+func TestRateLimitsConfigClientLimits(t *testing.T) {
+	t.Parallel()
+
+	limits := config.MakeTestRateLimitsConfig()
+	limits.Clients.RPM = 60
+	own := ratelimit.Limits{RPM: 0, TPM: 50_000, MaxConcurrent: 0}
+	clients := []config.ClientKeyConfig{{
+		Metadata: nil, Name: "alice", KeyHash: "", CertNames: nil, AllowedModels: nil, AllowedProviders: nil,
+		Budget: config.MakeTestBudgetLimits(), RateLimit: own,
+	}}
+
+	if got := limits.ClientLimits(clients, "alice"); got != own {
+		t.Errorf("ClientLimits(alice) = %+v, want the client's own %+v", got, own)
+	}
+	if got := limits.ClientLimits(clients, "bob"); got != limits.Clients {
+		t.Errorf("ClientLimits(bob) = %+v, want rate_limits.clients %+v", got, limits.Clients)
+	}
+}
+
+func TestJWTConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	jwt := config.MakeTestJWTConfig()
+	if jwt.IsEnabled() {
+		t.Error("IsEnabled() = true without a JWKS")
+	}
+	if got := jwt.GetClientClaim(); got != config.DefaultJWTClientClaim {
+		t.Errorf("GetClientClaim() = %q, want %q", got, config.DefaultJWTClientClaim)
+	}
+	if got := jwt.GetGroupsClaim(); got != config.DefaultJWTGroupsClaim {
+		t.Errorf("GetGroupsClaim() = %q, want %q", got, config.DefaultJWTGroupsClaim)
+	}
+	if got := jwt.GetJWKSRefresh(); got != config.DefaultJWKSRefresh {
+		t.Errorf("GetJWKSRefresh() = %v, want %v", got, config.DefaultJWKSRefresh)
+	}
+	if got := jwt.GetLeeway(); got != config.DefaultJWTLeeway {
+		t.Errorf("GetLeeway() = %v, want %v", got, config.DefaultJWTLeeway)
+	}
+
+	jwt.JWKSFile = "/etc/cc-relay/jwks.json"
+	jwt.ClientClaim = "email"
+	jwt.GroupsClaim = "roles"
+	jwt.JWKSRefreshMS = 60000
+	jwt.LeewayMS = 5000
+	if !jwt.IsEnabled() {
+		t.Error("IsEnabled() = false with a JWKS file")
+	}
+	if got := jwt.GetClientClaim(); got != "email" {
+		t.Errorf("GetClientClaim() = %q, want email", got)
+	}
+	if got := jwt.GetGroupsClaim(); got != "roles" {
+		t.Errorf("GetGroupsClaim() = %q, want roles", got)
+	}
+	if got := jwt.GetJWKSRefresh(); got != time.Minute {
+		t.Errorf("GetJWKSRefresh() = %v, want 1m", got)
+	}
+	if got := jwt.GetLeeway(); got != 5*time.Second {
+		t.Errorf("GetLeeway() = %v, want 5s", got)
+	}
+}
+
+func TestTLSConfigDefaults(t *testing.T) {
+	t.Parallel()
+
+	tlsCfg := config.MakeTestTLSConfig()
+	if tlsCfg.IsEnabled() {
+		t.Error("IsEnabled() = true without a certificate")
+	}
+	if got := tlsCfg.GetClientAuth(); got != config.TLSClientAuthRequire {
+		t.Errorf("GetClientAuth() = %q, want %q", got, config.TLSClientAuthRequire)
+	}
+	if got := tlsCfg.GetMinVersion(); got != config.TLSVersion12 {
+		t.Errorf("GetMinVersion() = %q, want %q", got, config.TLSVersion12)
+	}
+
+	tlsCfg.CertFile = "/etc/cc-relay/tls.crt"
+	tlsCfg.ClientAuth = config.TLSClientAuthOptional
+	tlsCfg.MinVersion = config.TLSVersion13
+	if !tlsCfg.IsEnabled() {
+		t.Error("IsEnabled() = false with a certificate")
+	}
+	if got := tlsCfg.GetClientAuth(); got != config.TLSClientAuthOptional {
+		t.Errorf("GetClientAuth() = %q, want %q", got, config.TLSClientAuthOptional)
+	}
+	if got := tlsCfg.GetMinVersion(); got != config.TLSVersion13 {
+		t.Errorf("GetMinVersion() = %q, want %q", got, config.TLSVersion13)
+	}
+}
@@ -1,10 +1,11 @@
 package config_test
 
 const (
-	strategyLeastLoaded = "least_loaded"
-	strategyModelBased  = "model_based"
-	testKeyDashValue    = "test-key"
-	providerTypeZAI     = "zai"
-	configFormatYAML    = "yaml"
-	configFormatTOML    = "toml"
+	strategyLeastLoaded   = "least_loaded"
+	strategyModelBased    = "model_based"
+	strategyCostOptimized = "cost_optimized"
+	testKeyDashValue      = "test-key"
+	providerTypeZAI       = "zai"
+	configFormatYAML      = "yaml"
+	configFormatTOML      = "toml"
 )
@@ -6,8 +6,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 )
 
 // DetectFormat exports detectFormat for testing.
@@ -42,12 +45,94 @@ func LoadFromReaderWithFormatForTest(r io.Reader, format TestFormat) (*Config, e
 // MakeTestConfig returns a minimal valid Config with all fields set.
 func MakeTestConfig() *Config {
 	return &Config{
-		Providers: []ProviderConfig{},
-		Routing:   MakeTestRoutingConfig(),
-		Logging:   MakeTestLoggingConfig(),
-		Health:    MakeTestHealthConfig(),
-		Server:    MakeTestServerConfig(),
-		Cache:     MakeTestCacheConfig(),
+		Providers:     []ProviderConfig{},
+		Routing:       MakeTestRoutingConfig(),
+		Logging:       MakeTestLoggingConfig(),
+		Health:        MakeTestHealthConfig(),
+		Server:        MakeTestServerConfig(),
+		Cache:         MakeTestCacheConfig(),
+		Metrics:       MakeTestMetricsConfig(),
+		Observability: MakeTestObservabilityConfig(),
+		Budgets:       MakeTestBudgetsConfig(),
+		Admin:         MakeTestAdminConfig(),
+		ResponseCache: MakeTestResponseCacheConfig(),
+		KeyState:      MakeTestKeyStateConfig(),
+		Access:        MakeTestAccessConfig(),
+		DLP:           MakeTestDLPConfig(),
+		RateLimits:    MakeTestRateLimitsConfig(),
+	}
+}
+
+// MakeTestAdminConfig returns an AdminConfig with the admin API disabled.
+func MakeTestAdminConfig() AdminConfig {
+	return AdminConfig{Token: "", Enabled: false}
+}
+
+// MakeTestResponseCacheConfig returns a disabled ResponseCacheConfig.
+func MakeTestResponseCacheConfig() ResponseCacheConfig {
+	return ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: 0, Enabled: false}
+}
+
+// MakeTestKeyStateConfig returns a KeyStateConfig with persistence disabled.
+func MakeTestKeyStateConfig() KeyStateConfig {
+	return KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: false}
+}
+
+// MakeTestAccessConfig returns an AccessConfig with no policies.
+func MakeTestAccessConfig() AccessConfig {
+	return AccessConfig{DefaultEffect: "", Policies: nil}
+}
+
+// MakeTestBudgetsConfig returns a budget.Config with no budgets.
+func MakeTestBudgetsConfig() budget.Config {
+	return budget.Config{
+		Prices:           nil,
+		StatePath:        "",
+		Global:           MakeTestBudgetLimits(),
+		SoftLimitPercent: 0,
+	}
+}
+
+// MakeTestBudgetLimits returns unlimited budget.Limits.
+func MakeTestBudgetLimits() budget.Limits {
+	return budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0}
+}
+
+// MakeTestDLPConfig returns a dlp.Config with nothing to detect.
+func MakeTestDLPConfig() dlp.Config {
+	return dlp.Config{Action: "", Detectors: nil, Patterns: nil, MinEntropy: 0}
+}
+
+// MakeTestRateLimits returns unlimited ratelimit.Limits.
+func MakeTestRateLimits() ratelimit.Limits {
+	return ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0}
+}
+
+// MakeTestRateLimitsConfig returns a RateLimitsConfig with no limits.
+func MakeTestRateLimitsConfig() RateLimitsConfig {
+	return RateLimitsConfig{Clients: MakeTestRateLimits(), IPs: MakeTestRateLimits()}
+}
+
+// MakeTestObservabilityConfig returns an ObservabilityConfig with tracing disabled.
+func MakeTestObservabilityConfig() ObservabilityConfig {
+	return ObservabilityConfig{
+		Tracing: TracingConfig{
+			Headers:     nil,
+			Endpoint:    "",
+			Protocol:    "",
+			ServiceName: "",
+			SampleRatio: 0,
+			Enabled:     false,
+			Insecure:    false,
+		},
+	}
+}
+
+// MakeTestMetricsConfig returns a disabled MetricsConfig with all fields set.
+func MakeTestMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		Path:    "",
+		Enabled: false,
 	}
 }
 
@@ -56,6 +141,7 @@ func MakeTestServerConfig() ServerConfig {
 	return ServerConfig{
 		Listen:        "127.0.0.1:8787",
 		APIKey:        "",
+		TLS:           MakeTestTLSConfig(),
 		Auth:          MakeTestAuthConfig(),
 		TimeoutMS:     60000,
 		MaxConcurrent: 0,
@@ -67,17 +153,34 @@ func MakeTestServerConfig() ServerConfig {
 // MakeTestAuthConfig returns a minimal AuthConfig with all fields set.
 func MakeTestAuthConfig() AuthConfig {
 	return AuthConfig{
+		Clients:           nil,
 		APIKey:            "",
 		BearerSecret:      "",
+		JWT:               MakeTestJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	}
 }
 
+// MakeTestJWTConfig returns a disabled JWTConfig.
+func MakeTestJWTConfig() JWTConfig {
+	return JWTConfig{
+		Issuer: "", JWKSURL: "", JWKSFile: "", ClientClaim: "", GroupsClaim: "",
+		Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
+	}
+}
+
+// MakeTestTLSConfig returns a disabled TLSConfig.
+func MakeTestTLSConfig() TLSConfig {
+	return TLSConfig{CertFile: "", KeyFile: "", ClientCAFile: "", ClientAuth: "", MinVersion: ""}
+}
+
 // MakeTestProviderConfig returns a minimal ProviderConfig with all fields set.
 func MakeTestProviderConfig() ProviderConfig {
 	return ProviderConfig{
 		ModelMapping:       map[string]string{},
+		Prices:             nil,
+		ModelLimits:        nil,
 		AWSRegion:          "",
 		GCPProjectID:       "",
 		AzureAPIVersion:    "",
@@ -89,6 +192,7 @@ func MakeTestProviderConfig() ProviderConfig {
 		AzureResourceName:  "",
 		AWSSecretAccessKey: "",
 		GCPRegion:          "",
+		Trust:              "",
 		Keys:               []KeyConfig{},
 		Models:             []string{},
 		Pooling:            MakeTestPoolingConfig(),
@@ -142,6 +246,10 @@ func MakeTestDebugOptions() DebugOptions {
 func MakeTestRoutingConfig() RoutingConfig {
 	return RoutingConfig{
 		ModelMapping:    map[string]string{},
+		QualityFloor:    nil,
+		Rules:           nil,
+		SessionAffinity: SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+		Hedging:         HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 		Strategy:        "",
 		DefaultProvider: "",
 		FailoverTimeout: 5000,
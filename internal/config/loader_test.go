@@ -3,6 +3,7 @@ package config_test
 import (
 	"errors"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"os"
 	"strings"
 	"testing"
@@ -112,6 +113,101 @@ logging:
 	assertLoggingConfig(t, cfg)
 }
 
+func TestLoadBudgets(t *testing.T) {
+	t.Parallel()
+
+	yamlContent := `server:
+  listen: "` + defaultListenAddr + `"
+  auth:
+    clients:
+      - name: "alice"
+        key_hash: "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+        budget:
+          tokens_per_day: 2000000
+          usd_per_month: 150
+
+budgets:
+  soft_limit_percent: 90
+  state_path: "/var/lib/cc-relay/budgets.json"
+  global:
+    tokens_per_month: 500000000
+  prices:
+    claude-sonnet:
+      input_per_mtok: 3
+      output_per_mtok: 15
+      cache_read_per_mtok: 0.3
+
+providers:
+  - name: "` + testProviderType + `"
+    type: "` + testProviderType + `"
+    enabled: true
+    keys:
+      - key: "sk-ant-test"
+`
+
+	cfg, err := config.LoadFromReaderForTest(strings.NewReader(yamlContent))
+	if err != nil {
+		t.Fatalf("config.LoadFromReader failed: %v", err)
+	}
+
+	clientBudget := cfg.Server.Auth.Clients[0].Budget
+	if clientBudget.TokensPerDay != 2000000 || clientBudget.USDPerMonth != 150 {
+		t.Errorf("Unexpected client budget: %+v", clientBudget)
+	}
+	if cfg.Budgets.Global.TokensPerMonth != 500000000 {
+		t.Errorf("Expected global tokens_per_month=500000000, got %d", cfg.Budgets.Global.TokensPerMonth)
+	}
+	if cfg.Budgets.StatePath != "/var/lib/cc-relay/budgets.json" {
+		t.Errorf("Expected state_path=/var/lib/cc-relay/budgets.json, got %q", cfg.Budgets.StatePath)
+	}
+	if cfg.Budgets.GetSoftLimitPercent() != 90 {
+		t.Errorf("Expected soft_limit_percent=90, got %d", cfg.Budgets.GetSoftLimitPercent())
+	}
+	price, ok := cfg.Budgets.PriceFor("claude-sonnet-4-5")
+	if !ok || price.OutputPerMTok != 15 || price.CacheReadPerMTok != 0.3 {
+		t.Errorf("Unexpected price for claude-sonnet-4-5: %+v (found=%v)", price, ok)
+	}
+}
+
+func TestLoadDLP(t *testing.T) {
+	t.Parallel()
+
+	yamlContent := `server:
+  listen: "` + defaultListenAddr + `"
+
+dlp:
+  action: "restrict"
+  detectors: ["aws_access_key", "private_key"]
+  min_entropy: 4.2
+  patterns:
+    - name: "ticket"
+      regex: 'SEC-\d{4}'
+
+providers:
+  - name: "` + testProviderType + `"
+    type: "` + testProviderType + `"
+    enabled: true
+    trust: "trusted"
+    keys:
+      - key: "sk-ant-test"
+`
+
+	cfg, err := config.LoadFromReaderForTest(strings.NewReader(yamlContent))
+	if err != nil {
+		t.Fatalf("config.LoadFromReader failed: %v", err)
+	}
+
+	if cfg.DLP.GetAction() != dlp.ActionRestrict || len(cfg.DLP.Detectors) != 2 || cfg.DLP.GetMinEntropy() != 4.2 {
+		t.Errorf("Unexpected dlp config: %+v", cfg.DLP)
+	}
+	if len(cfg.DLP.Patterns) != 1 || cfg.DLP.Patterns[0].Regex != `SEC-\d{4}` {
+		t.Errorf("Unexpected dlp patterns: %+v", cfg.DLP.Patterns)
+	}
+	if !cfg.Providers[0].IsTrusted() {
+		t.Errorf("Expected provider to be trusted, got trust %q", cfg.Providers[0].Trust)
+	}
+}
+
 func TestLoadEnvironmentExpansion(t *testing.T) {
 	t.Parallel()
 
@@ -396,6 +492,56 @@ format = "json"
 	assertLoggingConfig(t, cfg)
 }
 
+func TestLoadTOMLRoutingRules(t *testing.T) {
+	t.Parallel()
+
+	tomlContent := `[server]
+listen = "` + defaultListenAddr + `"
+
+[[providers]]
+name = "` + testProviderType + `"
+type = "` + testProviderType + `"
+enabled = true
+
+[[providers.keys]]
+key = "sk-ant-test"
+
+[[routing.rules]]
+name = "agents"
+strategy = "least_loaded"
+providers = ["` + testProviderType + `"]
+rewrite_model = "claude-sonnet-4-5"
+
+[routing.rules.match]
+models = ["claude-opus"]
+has_tools = true
+time_of_day = "22:00-06:00"
+timezone = "Europe/Berlin"
+
+[routing.rules.match.headers]
+X-Team = "infra"
+`
+
+	cfg, err := config.LoadFromReaderWithFormatForTest(strings.NewReader(tomlContent), config.TestFormatTOML)
+	if err != nil {
+		t.Fatalf("config.LoadFromReaderWithFormat failed: %v", err)
+	}
+
+	if len(cfg.Routing.Rules) != 1 {
+		t.Fatalf("Expected 1 routing rule, got %d", len(cfg.Routing.Rules))
+	}
+	rule := cfg.Routing.Rules[0]
+	if rule.Name != "agents" || rule.Strategy != strategyLeastLoaded || rule.RewriteModel != "claude-sonnet-4-5" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+	if rule.Match.HasTools == nil || !*rule.Match.HasTools || rule.Match.HasImages != nil {
+		t.Errorf("Expected has_tools=true and has_images unset, got %v and %v", rule.Match.HasTools, rule.Match.HasImages)
+	}
+	if rule.Match.Headers["X-Team"] != "infra" || rule.Match.Timezone != "Europe/Berlin" {
+		t.Errorf("Unexpected rule match: %+v", rule.Match)
+	}
+}
+
 func TestLoadTOMLEnvironmentExpansion(t *testing.T) {
 	t.Parallel()
 
@@ -1,7 +1,10 @@
 // Package config provides configuration loading and parsing for cc-relay.
 package config
 
-import "sync/atomic"
+import (
+	"sync/atomic"
+	"time"
+)
 
 // Runtime provides atomic access to configuration for hot-reload support.
 // It uses sync/atomic.Pointer for lock-free reads, allowing in-flight requests
@@ -58,3 +61,16 @@ func (r *Runtime) Store(cfg *Config) {
 
 // RuntimeConfigGetter interface implementation.
 var _ RuntimeConfigGetter = (*Runtime)(nil)
+
+// Version identifies the configuration a process is running with.
+type Version struct {
+	// LoadedAt is when the configuration was loaded.
+	LoadedAt time.Time `json:"loaded_at"`
+
+	// Path is the configuration file.
+	Path string `json:"path"`
+
+	// Generation is 1 for the configuration loaded at startup and counts up
+	// with every reload. Generations are per process.
+	Generation uint64 `json:"generation"`
+}
@@ -2,9 +2,20 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/dlp"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+	"github.com/omarluq/cc-relay/internal/rules"
 )
 
 // Provider type constants.
@@ -41,6 +52,8 @@ var validRoutingStrategies = map[string]bool{
 	"model_based":          true,
 	StrategyLeastLoaded:    true,
 	"weighted_failover":    true,
+	"latency_aware":        true,
+	"cost_optimized":       true,
 }
 
 // Valid keypool strategies.
@@ -58,6 +71,8 @@ var validProviderTypes = map[string]bool{
 	"zai":           true,
 	"minimax":       true,
 	"ollama":        true,
+	"openai":        true,
+	"gemini":        true,
 	ProviderBedrock: true,
 	ProviderVertex:  true,
 	ProviderAzure:   true,
@@ -81,6 +96,33 @@ var validLogFormats = map[string]bool{
 	"pretty":  true,
 }
 
+// Valid OTLP trace export protocols.
+var validTracingProtocols = map[string]bool{
+	"":                  true, // Empty defaults to grpc
+	TracingProtocolGRPC: true,
+	TracingProtocolHTTP: true,
+}
+
+// Valid access policy effects.
+var validPolicyEffects = map[string]bool{
+	"":                 true, // Empty defaults to allow for access.default_effect
+	policy.EffectAllow: true,
+	policy.EffectDeny:  true,
+}
+
+var validDLPActions = map[string]bool{
+	"":                 true, // Empty defaults to mask
+	dlp.ActionMask:     true,
+	dlp.ActionBlock:    true,
+	dlp.ActionRestrict: true,
+}
+
+var validTrustLevels = map[string]bool{
+	"":                 true, // Empty defaults to untrusted
+	dlp.TrustUntrusted: true,
+	dlp.TrustTrusted:   true,
+}
+
 // Validate checks the configuration for errors.
 // It validates all required fields, valid values, and cross-field constraints.
 // Returns a ValidationError containing all errors found, or nil if valid.
@@ -88,9 +130,21 @@ func (c *Config) Validate() error {
 	errs := &ValidationError{Errors: nil}
 
 	validateServer(c, errs)
+	validateTLS(c, errs)
+	validateAuthClients(c, errs)
+	validateAuthJWT(c, errs)
 	validateProviders(c, errs)
 	validateRouting(c, errs)
 	validateLogging(c, errs)
+	validateMetrics(c, errs)
+	validateTracing(c, errs)
+	validateBudgets(c, errs)
+	validateAdmin(c, errs)
+	validateResponseCache(c, errs)
+	validateKeyState(c, errs)
+	validateAccess(c, errs)
+	validateRateLimits(c, errs)
+	validateDLP(c, errs)
 
 	return errs.ToError()
 }
@@ -125,6 +179,152 @@ func validateServer(cfg *Config, errs *ValidationError) {
 	}
 }
 
+// validateAuthClients validates the virtual client keys in server.auth.clients.
+func validateAuthClients(cfg *Config, errs *ValidationError) {
+	providerNames := providerNameSet(cfg)
+
+	seenNames := make(map[string]bool)
+	seenHashes := make(map[string]bool)
+	seenCertNames := make(map[string]bool)
+	for idx := range cfg.Server.Auth.Clients {
+		client := &cfg.Server.Auth.Clients[idx]
+		prefix := fmt.Sprintf("server.auth.clients[%d]", idx)
+
+		if client.Name == "" {
+			errs.Addf("%s.name is required", prefix)
+		} else {
+			if seenNames[client.Name] {
+				errs.Addf("duplicate auth client name: %s", client.Name)
+			}
+			seenNames[client.Name] = true
+			prefix = fmt.Sprintf("server.auth.clients[%s]", client.Name)
+		}
+
+		// Clients identified by certificate alone need no key.
+		if client.KeyHash != "" || len(client.CertNames) == 0 {
+			validateClientKeyHash(client.KeyHash, prefix, seenHashes, errs)
+		}
+		validateClientCertNames(cfg, client.CertNames, prefix, seenCertNames, errs)
+
+		for _, name := range client.AllowedProviders {
+			if !providerNames[name] {
+				errs.Addf("%s.allowed_providers references unknown provider %q", prefix, name)
+			}
+		}
+	}
+}
+
+// validateClientKeyHash checks a client's key hash and that no other client uses it.
+func validateClientKeyHash(keyHash, prefix string, seenHashes map[string]bool, errs *ValidationError) {
+	hash := strings.ToLower(keyHash)
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		errs.Addf("%s.key_hash must be a hex-encoded SHA-256 hash (64 hex characters)", prefix)
+		return
+	}
+	if seenHashes[hash] {
+		errs.Addf("%s.key_hash is already used by another client", prefix)
+	}
+	seenHashes[hash] = true
+}
+
+// validateClientCertNames checks a client's certificate names. Certificates
+// are only verified with a client CA, and each name identifies one client.
+func validateClientCertNames(
+	cfg *Config, certNames []string, prefix string, seenCertNames map[string]bool, errs *ValidationError,
+) {
+	if len(certNames) > 0 && cfg.Server.TLS.ClientCAFile == "" {
+		errs.Addf("%s.cert_names requires server.tls.client_ca_file", prefix)
+	}
+	for _, name := range certNames {
+		switch {
+		case name == "":
+			errs.Addf("%s.cert_names must not contain empty names", prefix)
+		case seenCertNames[name]:
+			errs.Addf("%s.cert_names: %q is already used by another client", prefix, name)
+		}
+		seenCertNames[name] = true
+	}
+}
+
+// validateTLS validates the listener TLS settings in server.tls.
+func validateTLS(cfg *Config, errs *ValidationError) {
+	tlsCfg := &cfg.Server.TLS
+	if (tlsCfg.CertFile == "") != (tlsCfg.KeyFile == "") {
+		errs.Add("server.tls.cert_file and server.tls.key_file must be set together")
+	}
+	if tlsCfg.ClientCAFile != "" && !tlsCfg.IsEnabled() {
+		errs.Add("server.tls.client_ca_file requires cert_file and key_file")
+	}
+	if tlsCfg.ClientAuth != "" && tlsCfg.ClientCAFile == "" {
+		errs.Add("server.tls.client_auth requires client_ca_file")
+	}
+	if !slices.Contains([]string{"", TLSClientAuthRequire, TLSClientAuthOptional}, tlsCfg.ClientAuth) {
+		errs.Addf("server.tls.client_auth must be %q or %q (got %q)",
+			TLSClientAuthRequire, TLSClientAuthOptional, tlsCfg.ClientAuth)
+	}
+	if !slices.Contains([]string{"", TLSVersion12, TLSVersion13}, tlsCfg.MinVersion) {
+		errs.Addf("server.tls.min_version must be %q or %q (got %q)", TLSVersion12, TLSVersion13, tlsCfg.MinVersion)
+	}
+}
+
+// providerNameSet returns the names of the configured providers.
+func providerNameSet(cfg *Config) map[string]bool {
+	providerNames := make(map[string]bool, len(cfg.Providers))
+	for idx := range cfg.Providers {
+		providerNames[cfg.Providers[idx].Name] = true
+	}
+	return providerNames
+}
+
+// validateAuthJWT validates JWT authentication in server.auth.jwt.
+func validateAuthJWT(cfg *Config, errs *ValidationError) {
+	jwt := &cfg.Server.Auth.JWT
+	validateJWKSSource(jwt, errs)
+	if jwt.JWKSRefreshMS < 0 {
+		errs.Add("server.auth.jwt.jwks_refresh_ms must be >= 0")
+	}
+	if jwt.LeewayMS < 0 {
+		errs.Add("server.auth.jwt.leeway_ms must be >= 0")
+	}
+	validateJWTGroups(cfg, errs)
+}
+
+// validateJWKSSource checks that JWT settings come with exactly one JWKS.
+func validateJWKSSource(jwt *JWTConfig, errs *ValidationError) {
+	configured := jwt.Issuer != "" || len(jwt.Audiences) > 0 || len(jwt.Groups) > 0
+	switch {
+	case jwt.JWKSURL != "" && jwt.JWKSFile != "":
+		errs.Add("server.auth.jwt: only one of jwks_url and jwks_file may be set")
+	case jwt.JWKSURL != "" && !strings.HasPrefix(jwt.JWKSURL, "https://") && !strings.HasPrefix(jwt.JWKSURL, "http://"):
+		errs.Addf("server.auth.jwt.jwks_url must be an http or https URL (got %q)", jwt.JWKSURL)
+	case configured && !jwt.IsEnabled():
+		errs.Add("server.auth.jwt requires jwks_url or jwks_file")
+	}
+}
+
+// validateJWTGroups validates the group permissions in server.auth.jwt.groups.
+func validateJWTGroups(cfg *Config, errs *ValidationError) {
+	jwt := &cfg.Server.Auth.JWT
+	providerNames := providerNameSet(cfg)
+	seenNames := make(map[string]bool, len(jwt.Groups))
+	for idx := range jwt.Groups {
+		group := &jwt.Groups[idx]
+		if group.Name == "" {
+			errs.Addf("server.auth.jwt.groups[%d].name is required", idx)
+			continue
+		}
+		if seenNames[group.Name] {
+			errs.Addf("duplicate server.auth.jwt group: %s", group.Name)
+		}
+		seenNames[group.Name] = true
+		for _, name := range group.AllowedProviders {
+			if !providerNames[name] {
+				errs.Addf("server.auth.jwt.groups[%s].allowed_providers references unknown provider %q", group.Name, name)
+			}
+		}
+	}
+}
+
 // validateListenAddress validates a listen address in host:port format.
 func validateListenAddress(addr string, errs *ValidationError) {
 	host, port, err := net.SplitHostPort(addr)
@@ -188,7 +388,7 @@ func validateProvider(provider *ProviderConfig, index int, seenNames map[string]
 	if provider.Type == "" {
 		errs.Addf("%s is required", prefix("type"))
 	} else if !validProviderTypes[provider.Type] {
-		errs.Addf("%s is invalid (got %q, valid: anthropic, zai, ollama, bedrock, vertex, azure)",
+		errs.Addf("%s is invalid (got %q, valid: anthropic, zai, minimax, ollama, openai, gemini, bedrock, vertex, azure)",
 			prefix("type"), provider.Type)
 	}
 
@@ -204,6 +404,37 @@ func validateProvider(provider *ProviderConfig, index int, seenNames map[string]
 	if provider.Pooling.Strategy != "" && !validPoolingStrategies[provider.Pooling.Strategy] {
 		errs.Addf("%s is invalid (got %q)", prefix("pooling.strategy"), provider.Pooling.Strategy)
 	}
+
+	validateProviderPrices(provider, prefix, errs)
+	validateProviderModelLimits(provider, prefix, errs)
+}
+
+// validateProviderModelLimits validates a provider's model limits.
+func validateProviderModelLimits(provider *ProviderConfig, prefix func(string) string, errs *ValidationError) {
+	for model, limits := range provider.ModelLimits {
+		field := prefix(fmt.Sprintf("model_limits[%s]", model))
+		if limits.ContextWindow < 0 || limits.MaxOutputTokens < 0 {
+			errs.Addf("%s must not be negative", field)
+		}
+		if limits.ContextWindow > 0 && limits.MaxOutputTokens > limits.ContextWindow {
+			errs.Addf("%s.max_output_tokens must be <= context_window (got %d > %d)",
+				field, limits.MaxOutputTokens, limits.ContextWindow)
+		}
+	}
+}
+
+// validateProviderPrices validates a provider's price table.
+func validateProviderPrices(provider *ProviderConfig, prefix func(string) string, errs *ValidationError) {
+	for model, price := range provider.Prices {
+		field := prefix(fmt.Sprintf("prices[%s]", model))
+		if price.InputPerMTok < 0 || price.OutputPerMTok < 0 ||
+			price.CacheWritePerMTok < 0 || price.CacheReadPerMTok < 0 {
+			errs.Addf("%s must not be negative", field)
+		}
+		if price.Quality < 0 {
+			errs.Addf("%s.quality must be >= 0 (got %d)", field, price.Quality)
+		}
+	}
 }
 
 // validateCloudProviderConfig validates cloud provider-specific fields.
@@ -276,24 +507,111 @@ func validateKeyRateLimits(keyCfg *KeyConfig, prefix func(string) string, errs *
 	}
 }
 
+// validateHedging validates routing.hedging.
+func validateHedging(hedging *HedgingConfig, errs *ValidationError) {
+	if hedging.Percentile < 0 || hedging.Percentile >= 100 {
+		errs.Addf("routing.hedging.percentile must be between 0 and 100 (got %g)", hedging.Percentile)
+	}
+	if hedging.DelayMS < 0 {
+		errs.Add("routing.hedging.delay_ms must be >= 0")
+	}
+	for idx, prefix := range hedging.Models {
+		if prefix == "" {
+			errs.Addf("routing.hedging.models[%d] must not be empty", idx)
+		}
+	}
+}
+
 // validateRouting validates the routing configuration section.
 func validateRouting(cfg *Config, errs *ValidationError) {
-	// Strategy must be valid if set
-	if cfg.Routing.Strategy != "" && !validRoutingStrategies[cfg.Routing.Strategy] {
-		errs.Addf("routing.strategy is invalid (got %q, valid: failover, round_robin, "+
-			"weighted_round_robin, shuffle, model_based, least_loaded, weighted_failover)",
-			cfg.Routing.Strategy)
-	}
+	validateRoutingStrategy(cfg, errs)
 
 	// FailoverTimeout must be non-negative
 	if cfg.Routing.FailoverTimeout < 0 {
 		errs.Add("routing.failover_timeout must be >= 0")
 	}
 
+	for model, floor := range cfg.Routing.QualityFloor {
+		if floor < 0 {
+			errs.Addf("routing.quality_floor[%s] must be >= 0 (got %d)", model, floor)
+		}
+	}
+
+	if cfg.Routing.SessionAffinity.TTLMS < 0 {
+		errs.Add("routing.session_affinity.ttl_ms must be >= 0")
+	}
+	validateHedging(&cfg.Routing.Hedging, errs)
+
+	seenRules := make(map[string]bool, len(cfg.Routing.Rules))
+	for idx := range cfg.Routing.Rules {
+		validateRoutingRule(cfg, idx, seenRules, errs)
+	}
+}
+
+// validateRoutingStrategy validates the strategy and the settings it requires.
+func validateRoutingStrategy(cfg *Config, errs *ValidationError) {
+	// Strategy must be valid if set
+	if cfg.Routing.Strategy != "" && !validRoutingStrategies[cfg.Routing.Strategy] {
+		errs.Addf("routing.strategy is invalid (got %q, valid: failover, round_robin, "+
+			"weighted_round_robin, shuffle, model_based, least_loaded, weighted_failover, latency_aware, cost_optimized)",
+			cfg.Routing.Strategy)
+	}
+
 	// Model-based routing requires model_mapping
 	if cfg.Routing.Strategy == "model_based" && len(cfg.Routing.ModelMapping) == 0 {
 		errs.Add("routing.model_mapping is required when strategy is model_based")
 	}
+
+	// Cost-optimized routing needs something to compare
+	if cfg.Routing.Strategy == "cost_optimized" && !hasProviderPrices(cfg) {
+		errs.Add("routing.strategy cost_optimized requires prices on at least one provider")
+	}
+}
+
+// validateRoutingRule validates a single routing rule.
+func validateRoutingRule(cfg *Config, index int, seenNames map[string]bool, errs *ValidationError) {
+	rule := &cfg.Routing.Rules[index]
+	prefix := fmt.Sprintf("routing.rules[%d]", index)
+	if rule.Name == "" {
+		errs.Addf("%s.name is required", prefix)
+	} else {
+		if seenNames[rule.Name] {
+			errs.Addf("duplicate routing rule name: %s", rule.Name)
+		}
+		seenNames[rule.Name] = true
+		prefix = fmt.Sprintf("routing.rules[%s]", rule.Name)
+	}
+
+	if rule.Strategy != "" && (!validRoutingStrategies[rule.Strategy] || rule.Strategy == "model_based") {
+		errs.Addf("%s.strategy is invalid (got %q, model_based is not supported in rules)", prefix, rule.Strategy)
+	}
+	for _, name := range rule.Providers {
+		if !slices.ContainsFunc(cfg.Providers, func(provider ProviderConfig) bool { return provider.Name == name }) {
+			errs.Addf("%s.providers references unknown provider %q", prefix, name)
+		}
+	}
+	validateRuleMatch(&rule.Match, prefix+".match", errs)
+}
+
+// validateRuleMatch validates the conditions of a routing rule.
+func validateRuleMatch(match *rules.Match, prefix string, errs *ValidationError) {
+	if match.TimeOfDay != "" {
+		if _, _, err := rules.ParseTimeOfDay(match.TimeOfDay); err != nil {
+			errs.Addf("%s.time_of_day must be HH:MM-HH:MM (got %q)", prefix, match.TimeOfDay)
+		}
+	}
+	if match.Timezone != "" {
+		if _, err := time.LoadLocation(match.Timezone); err != nil {
+			errs.Addf("%s.timezone is invalid (got %q)", prefix, match.Timezone)
+		}
+	}
+	if match.MinInputTokens < 0 || match.MaxInputTokens < 0 {
+		errs.Addf("%s input token bounds must be >= 0", prefix)
+	}
+	if match.MaxInputTokens > 0 && match.MaxInputTokens < match.MinInputTokens {
+		errs.Addf("%s.max_input_tokens must be >= min_input_tokens (got %d < %d)",
+			prefix, match.MaxInputTokens, match.MinInputTokens)
+	}
 }
 
 // validateLogging validates the logging configuration section.
@@ -315,3 +633,236 @@ func validateLogging(cfg *Config, errs *ValidationError) {
 		errs.Add("logging.debug_options.max_body_log_size must be >= 0")
 	}
 }
+
+// reservedRoutePaths are served by the proxy and cannot be used for metrics.
+var reservedRoutePaths = map[string]bool{
+	"/health":                   true,
+	"/v1/messages":              true,
+	"/v1/messages/count_tokens": true,
+	"/v1/chat/completions":      true,
+	"/v1/models":                true,
+	"/v1/providers":             true,
+}
+
+// validateMetrics validates the metrics configuration section.
+func validateMetrics(cfg *Config, errs *ValidationError) {
+	if cfg.Metrics.Path == "" {
+		return
+	}
+	if !strings.HasPrefix(cfg.Metrics.Path, "/") || strings.ContainsAny(cfg.Metrics.Path, " {}") {
+		errs.Addf("metrics.path must be an absolute URL path (got %q)", cfg.Metrics.Path)
+		return
+	}
+	if reservedRoutePaths[cfg.Metrics.Path] {
+		errs.Addf("metrics.path %q conflicts with a proxy route", cfg.Metrics.Path)
+	}
+	if cfg.Admin.Enabled && (cfg.Metrics.Path == AdminPath || strings.HasPrefix(cfg.Metrics.Path, AdminPath+"/")) {
+		errs.Addf("metrics.path %q conflicts with the admin API", cfg.Metrics.Path)
+	}
+}
+
+// validateAdmin validates the admin API section.
+func validateAdmin(cfg *Config, errs *ValidationError) {
+	if cfg.Admin.Enabled && cfg.Admin.Token == "" {
+		errs.Add("admin.token is required when admin.enabled is true")
+	}
+}
+
+// validateResponseCache validates the response_cache section.
+func validateResponseCache(cfg *Config, errs *ValidationError) {
+	if cfg.ResponseCache.TTLMS < 0 {
+		errs.Add("response_cache.ttl_ms must be >= 0")
+	}
+	if cfg.ResponseCache.MaxEntryBytes < 0 {
+		errs.Add("response_cache.max_entry_bytes must be >= 0")
+	}
+}
+
+// validateKeyState validates the key_state section.
+func validateKeyState(cfg *Config, errs *ValidationError) {
+	if cfg.KeyState.SaveIntervalMS < 0 {
+		errs.Add("key_state.save_interval_ms must be >= 0")
+	}
+}
+
+// validateAccess validates the access section and its policies.
+func validateAccess(cfg *Config, errs *ValidationError) {
+	if !validPolicyEffects[cfg.Access.DefaultEffect] {
+		errs.Addf("access.default_effect is invalid (got %q, valid: allow, deny)", cfg.Access.DefaultEffect)
+	}
+	seenNames := make(map[string]bool, len(cfg.Access.Policies))
+	for idx := range cfg.Access.Policies {
+		validatePolicy(cfg, idx, seenNames, errs)
+	}
+}
+
+// validatePolicy validates a single access policy.
+func validatePolicy(cfg *Config, index int, seenNames map[string]bool, errs *ValidationError) {
+	accessPolicy := &cfg.Access.Policies[index]
+	prefix := fmt.Sprintf("access.policies[%d]", index)
+	if accessPolicy.Name == "" {
+		errs.Addf("%s.name is required", prefix)
+	} else {
+		if seenNames[accessPolicy.Name] {
+			errs.Addf("duplicate access policy name: %s", accessPolicy.Name)
+		}
+		seenNames[accessPolicy.Name] = true
+		prefix = fmt.Sprintf("access.policies[%s]", accessPolicy.Name)
+	}
+
+	if accessPolicy.Effect == "" || !validPolicyEffects[accessPolicy.Effect] {
+		errs.Addf("%s.effect must be allow or deny (got %q)", prefix, accessPolicy.Effect)
+	}
+	for _, name := range accessPolicy.Match.Providers {
+		if !slices.ContainsFunc(cfg.Providers, func(provider ProviderConfig) bool { return provider.Name == name }) {
+			errs.Addf("%s.match.providers references unknown provider %q", prefix, name)
+		}
+	}
+	if accessPolicy.Match.MaxTokensAbove < 0 {
+		errs.Addf("%s.match.max_tokens_above must be >= 0", prefix)
+	}
+}
+
+// validateDLP validates the dlp section and provider trust levels.
+func validateDLP(cfg *Config, errs *ValidationError) {
+	validateTrustLevels(cfg, errs)
+
+	dlpCfg := &cfg.DLP
+	if !validDLPActions[dlpCfg.Action] {
+		errs.Addf("dlp.action is invalid (got %q, valid: mask, block, restrict)", dlpCfg.Action)
+	}
+	if dlpCfg.MinEntropy < 0 {
+		errs.Add("dlp.min_entropy must be >= 0")
+	}
+	for _, name := range dlpCfg.Detectors {
+		if !slices.Contains(dlp.Detectors(), name) {
+			errs.Addf("dlp.detectors has unknown detector %q (valid: %s)", name, strings.Join(dlp.Detectors(), ", "))
+		}
+	}
+	seenNames := make(map[string]bool, len(dlpCfg.Patterns))
+	for idx := range dlpCfg.Patterns {
+		validateDLPPattern(cfg, idx, seenNames, errs)
+	}
+
+	if dlpCfg.IsEnabled() && dlpCfg.GetAction() == dlp.ActionRestrict &&
+		!slices.ContainsFunc(cfg.Providers, func(provider ProviderConfig) bool { return provider.IsTrusted() }) {
+		errs.Add("dlp.action restrict requires at least one provider with trust: trusted")
+	}
+}
+
+// validateTrustLevels validates the DLP trust level of each provider.
+func validateTrustLevels(cfg *Config, errs *ValidationError) {
+	for idx := range cfg.Providers {
+		if provider := &cfg.Providers[idx]; !validTrustLevels[provider.Trust] {
+			errs.Addf("providers[%s].trust is invalid (got %q, valid: trusted, untrusted)", provider.Name, provider.Trust)
+		}
+	}
+}
+
+// validateDLPPattern validates a single custom DLP pattern.
+func validateDLPPattern(cfg *Config, index int, seenNames map[string]bool, errs *ValidationError) {
+	pattern := &cfg.DLP.Patterns[index]
+	prefix := fmt.Sprintf("dlp.patterns[%d]", index)
+	if pattern.Name == "" {
+		errs.Addf("%s.name is required", prefix)
+	} else {
+		if seenNames[pattern.Name] || slices.Contains(dlp.Detectors(), pattern.Name) {
+			errs.Addf("duplicate dlp pattern name: %s", pattern.Name)
+		}
+		seenNames[pattern.Name] = true
+		prefix = fmt.Sprintf("dlp.patterns[%s]", pattern.Name)
+	}
+
+	if pattern.Regex == "" {
+		errs.Addf("%s.regex is required", prefix)
+	} else if _, err := dlp.CompilePattern(pattern.Regex); err != nil {
+		errs.Addf("%s.regex is invalid: %v", prefix, err)
+	}
+}
+
+// validateRateLimits validates the rate_limits section and per-client rate limits.
+func validateRateLimits(cfg *Config, errs *ValidationError) {
+	validateRateLimitValues(cfg.RateLimits.Clients, "rate_limits.clients", errs)
+	validateRateLimitValues(cfg.RateLimits.IPs, "rate_limits.ips", errs)
+	for idx := range cfg.Server.Auth.Clients {
+		client := &cfg.Server.Auth.Clients[idx]
+		validateRateLimitValues(client.RateLimit, fmt.Sprintf("server.auth.clients[%s].rate_limit", client.Name), errs)
+	}
+}
+
+// validateRateLimitValues validates one set of rate limits.
+func validateRateLimitValues(limits ratelimit.Limits, prefix string, errs *ValidationError) {
+	if limits.RPM < 0 || limits.TPM < 0 || limits.MaxConcurrent < 0 {
+		errs.Addf("%s rpm, tpm and max_concurrent must be >= 0", prefix)
+	}
+}
+
+// validateTracing validates the observability.tracing section.
+func validateTracing(cfg *Config, errs *ValidationError) {
+	tracing := cfg.Observability.Tracing
+	if !validTracingProtocols[tracing.Protocol] {
+		errs.Addf("observability.tracing.protocol is invalid (got %q, valid: grpc, http)", tracing.Protocol)
+	}
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		errs.Addf("observability.tracing.sample_ratio must be between 0 and 1 (got %g)", tracing.SampleRatio)
+	}
+}
+
+// validateBudgets validates the budgets section and per-client budgets.
+func validateBudgets(cfg *Config, errs *ValidationError) {
+	budgets := &cfg.Budgets
+	if budgets.SoftLimitPercent < 0 || budgets.SoftLimitPercent > 100 {
+		errs.Addf("budgets.soft_limit_percent must be between 0 and 100 (got %d)", budgets.SoftLimitPercent)
+	}
+	for prefix, price := range budgets.Prices {
+		if price.InputPerMTok < 0 || price.OutputPerMTok < 0 ||
+			price.CacheWritePerMTok < 0 || price.CacheReadPerMTok < 0 {
+			errs.Addf("budgets.prices[%s] must not be negative", prefix)
+		}
+	}
+
+	spendLimited := validateBudgetLimits("budgets.global", budgets.Global, errs)
+	for idx := range cfg.Server.Auth.Clients {
+		client := &cfg.Server.Auth.Clients[idx]
+		prefix := fmt.Sprintf("server.auth.clients[%s].budget", client.Name)
+		if validateBudgetLimits(prefix, client.Budget, errs) {
+			spendLimited = true
+		}
+	}
+	if spendLimited && len(budgets.Prices) == 0 && !hasProviderPrices(cfg) {
+		errs.Add("budgets.prices is required when a usd_per_month budget is set and no provider has prices")
+	}
+	// Without the HA cache, usage would start over on every restart
+	if hasBudgets(cfg) && cfg.Cache.Mode != cache.ModeHA && budgets.StatePath == "" {
+		errs.Add("budgets.state_path is required when a budget is set and cache.mode is not ha")
+	}
+}
+
+// hasBudgets reports whether the relay or any client has a budget limit.
+func hasBudgets(cfg *Config) bool {
+	return !cfg.Budgets.Global.IsZero() || slices.ContainsFunc(cfg.Server.Auth.Clients, func(client ClientKeyConfig) bool {
+		return !client.Budget.IsZero()
+	})
+}
+
+// hasProviderPrices reports whether any provider has a price table.
+func hasProviderPrices(cfg *Config) bool {
+	return slices.ContainsFunc(cfg.Providers, func(provider ProviderConfig) bool {
+		return len(provider.Prices) > 0
+	})
+}
+
+// validateBudgetLimits validates one set of budget limits and reports whether
+// it caps spend.
+func validateBudgetLimits(prefix string, limits budget.Limits, errs *ValidationError) bool {
+	if limits.TokensPerDay < 0 {
+		errs.Addf("%s.tokens_per_day must not be negative", prefix)
+	}
+	if limits.TokensPerMonth < 0 {
+		errs.Addf("%s.tokens_per_month must not be negative", prefix)
+	}
+	if limits.USDPerMonth < 0 {
+		errs.Addf("%s.usd_per_month must not be negative", prefix)
+	}
+	return limits.USDPerMonth > 0
+}
@@ -7,7 +7,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/cache"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+	"github.com/omarluq/cc-relay/internal/rules"
 )
 
 const (
@@ -248,7 +254,7 @@ func TestValidateValidProviderTypes(t *testing.T) {
 	t.Parallel()
 
 	validTypes := []string{
-		testProviderType, providerTypeZAI, "minimax", "ollama",
+		testProviderType, providerTypeZAI, "minimax", "ollama", "openai", "gemini",
 		testTypeBedrock, testTypeVertex, testTypeAzure,
 	}
 
@@ -371,7 +377,7 @@ func TestValidateValidRoutingStrategies(t *testing.T) {
 
 	validStrategies := []string{
 		"", strategyFailover, strategyRoundRobin, "weighted_round_robin", "shuffle",
-		strategyModelBased, strategyLeastLoaded, "weighted_failover",
+		strategyModelBased, strategyLeastLoaded, "weighted_failover", "latency_aware", strategyCostOptimized,
 	}
 
 	for _, strategy := range validStrategies {
@@ -387,6 +393,11 @@ func TestValidateValidRoutingStrategies(t *testing.T) {
 			if strategy == strategyModelBased {
 				cfg.Routing.ModelMapping = map[string]string{"claude": testProviderType}
 			}
+			// cost_optimized requires provider prices
+			if strategy == strategyCostOptimized {
+				cfg = configWithPricedProvider(testPrice(3, 0))
+				cfg.Routing.Strategy = strategy
+			}
 
 			err := cfg.Validate()
 			if err != nil {
@@ -396,6 +407,313 @@ func TestValidateValidRoutingStrategies(t *testing.T) {
 	}
 }
 
+func configWithPricedProvider(price config.ModelPrice) *config.Config {
+	cfg := configWithSingleProvider(testListenAddr)
+	cfg.Providers[0].Prices = map[string]config.ModelPrice{"claude-sonnet": price}
+	return cfg
+}
+
+func testPrice(inputPerMTok float64, quality int) config.ModelPrice {
+	return config.ModelPrice{
+		InputPerMTok: inputPerMTok, OutputPerMTok: 15, CacheWritePerMTok: 0, CacheReadPerMTok: 0, Quality: quality,
+	}
+}
+
+func TestValidateCostOptimized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cfg     *config.Config
+		name    string
+		wantErr string
+	}{
+		{name: "priced provider", cfg: configWithPricedProvider(testPrice(3, 8)), wantErr: ""},
+		{
+			name:    "no prices",
+			cfg:     configWithSingleProvider(testListenAddr),
+			wantErr: "routing.strategy cost_optimized requires prices on at least one provider",
+		},
+		{
+			name:    "negative price",
+			cfg:     configWithPricedProvider(testPrice(-1, 8)),
+			wantErr: "provider[test].prices[claude-sonnet] must not be negative",
+		},
+		{
+			name:    "negative quality",
+			cfg:     configWithPricedProvider(testPrice(3, -2)),
+			wantErr: "provider[test].prices[claude-sonnet].quality must be >= 0 (got -2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.cfg.Routing.Strategy = strategyCostOptimized
+			tt.cfg.Routing.QualityFloor = map[string]int{"claude-sonnet": 5}
+
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateNegativeQualityFloor(t *testing.T) {
+	t.Parallel()
+
+	cfg := configWithPricedProvider(testPrice(3, 8))
+	cfg.Routing.QualityFloor = map[string]int{"claude-opus": -1}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "routing.quality_floor[claude-opus] must be >= 0 (got -1)") {
+		t.Errorf("Expected quality_floor error, got: %v", err)
+	}
+}
+
+func TestValidateSessionAffinityTTL(t *testing.T) {
+	t.Parallel()
+
+	cfg := configWithListen(defaultListenAddr)
+	cfg.Routing = config.MakeTestRoutingConfig()
+	cfg.Routing.SessionAffinity = config.SessionAffinityConfig{Header: "", TTLMS: -1, Enabled: true}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "routing.session_affinity.ttl_ms must be >= 0") {
+		t.Errorf("Expected session_affinity.ttl_ms error, got: %v", err)
+	}
+}
+
+func TestValidateResponseCache(t *testing.T) {
+	t.Parallel()
+
+	cfg := configWithListen(defaultListenAddr)
+	cfg.ResponseCache = config.ResponseCacheConfig{TTLMS: -1, MaxEntryBytes: -1, Enabled: true}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected response_cache errors, got nil")
+	}
+	for _, want := range []string{
+		"response_cache.ttl_ms must be >= 0",
+		"response_cache.max_entry_bytes must be >= 0",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q error, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateKeyState(t *testing.T) {
+	t.Parallel()
+
+	cfg := configWithListen(defaultListenAddr)
+	cfg.KeyState = config.KeyStateConfig{Path: "", SaveIntervalMS: -1, Enabled: true}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected key_state error, got nil")
+	}
+	if !strings.Contains(err.Error(), "key_state.save_interval_ms must be >= 0") {
+		t.Errorf("Expected key_state.save_interval_ms error, got: %v", err)
+	}
+}
+
+func TestValidateHedging(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		wantErr string
+		hedging config.HedgingConfig
+	}{
+		{
+			name:    "valid",
+			wantErr: "",
+			hedging: config.HedgingConfig{Models: []string{"claude-haiku"}, Percentile: 90, DelayMS: 300, Enabled: true},
+		},
+		{
+			name:    "percentile too high",
+			wantErr: "routing.hedging.percentile must be between 0 and 100 (got 100)",
+			hedging: config.HedgingConfig{Models: nil, Percentile: 100, DelayMS: 0, Enabled: true},
+		},
+		{
+			name:    "negative delay",
+			wantErr: "routing.hedging.delay_ms must be >= 0",
+			hedging: config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: -1, Enabled: true},
+		},
+		{
+			name:    "empty model",
+			wantErr: "routing.hedging.models[0] must not be empty",
+			hedging: config.HedgingConfig{Models: []string{""}, Percentile: 0, DelayMS: 0, Enabled: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithListen(defaultListenAddr)
+			cfg.Routing = config.MakeTestRoutingConfig()
+			cfg.Routing.Hedging = tt.hedging
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProviderModelLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		wantErr string
+		limits  config.ModelLimits
+	}{
+		{name: "valid", wantErr: "", limits: config.ModelLimits{ContextWindow: 32768, MaxOutputTokens: 8192}},
+		{name: "window only", wantErr: "", limits: config.ModelLimits{ContextWindow: 32768, MaxOutputTokens: 0}},
+		{
+			name:    "negative",
+			wantErr: "provider[test].model_limits[qwen] must not be negative",
+			limits:  config.ModelLimits{ContextWindow: -1, MaxOutputTokens: 0},
+		},
+		{
+			name:    "output larger than window",
+			wantErr: "provider[test].model_limits[qwen].max_output_tokens must be <= context_window (got 9000 > 8192)",
+			limits:  config.ModelLimits{ContextWindow: 8192, MaxOutputTokens: 9000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Providers[0].ModelLimits = map[string]config.ModelLimits{"qwen": tt.limits}
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func routingRule(name, strategy string, providerNames ...string) rules.Rule {
+	return rules.Rule{
+		Match: rules.Match{
+			Headers: nil, HasTools: nil, HasThinking: nil, HasImages: nil, TimeOfDay: "", Timezone: "",
+			Models: []string{"claude-opus"}, Clients: nil, MinInputTokens: 0, MaxInputTokens: 0,
+		},
+		Name:         name,
+		Strategy:     strategy,
+		RewriteModel: "",
+		Providers:    providerNames,
+	}
+}
+
+func TestValidateRoutingRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mutate  func(rule *rules.Rule)
+		name    string
+		wantErr string
+	}{
+		{name: "valid rule", mutate: func(*rules.Rule) {}, wantErr: ""},
+		{
+			name:    "missing name",
+			mutate:  func(rule *rules.Rule) { rule.Name = "" },
+			wantErr: "routing.rules[0].name is required",
+		},
+		{
+			name:    "unknown strategy",
+			mutate:  func(rule *rules.Rule) { rule.Strategy = "fastest" },
+			wantErr: `routing.rules[opus].strategy is invalid (got "fastest"`,
+		},
+		{
+			name:    "model_based strategy",
+			mutate:  func(rule *rules.Rule) { rule.Strategy = strategyModelBased },
+			wantErr: "model_based is not supported in rules",
+		},
+		{
+			name:    "unknown provider",
+			mutate:  func(rule *rules.Rule) { rule.Providers = []string{"missing"} },
+			wantErr: `routing.rules[opus].providers references unknown provider "missing"`,
+		},
+		{
+			name:    "invalid time of day",
+			mutate:  func(rule *rules.Rule) { rule.Match.TimeOfDay = "9am-5pm" },
+			wantErr: `routing.rules[opus].match.time_of_day must be HH:MM-HH:MM (got "9am-5pm")`,
+		},
+		{
+			name:    "invalid timezone",
+			mutate:  func(rule *rules.Rule) { rule.Match.Timezone = "Mars/Olympus" },
+			wantErr: `routing.rules[opus].match.timezone is invalid (got "Mars/Olympus")`,
+		},
+		{
+			name: "inverted token bounds",
+			mutate: func(rule *rules.Rule) {
+				rule.Match.MinInputTokens = 1000
+				rule.Match.MaxInputTokens = 100
+			},
+			wantErr: "routing.rules[opus].match.max_input_tokens must be >= min_input_tokens (got 100 < 1000)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			rule := routingRule("opus", strategyLeastLoaded, testProviderName)
+			tt.mutate(&rule)
+			cfg.Routing.Rules = []rules.Rule{rule}
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateDuplicateRoutingRules(t *testing.T) {
+	t.Parallel()
+
+	cfg := configWithSingleProvider(testListenAddr)
+	cfg.Routing.Rules = []rules.Rule{routingRule("opus", ""), routingRule("opus", "")}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "duplicate routing rule name: opus") {
+		t.Errorf("Expected duplicate rule error, got: %v", err)
+	}
+}
+
 func TestValidateModelBasedRequiresMapping(t *testing.T) {
 	t.Parallel()
 
@@ -727,3 +1045,801 @@ func TestValidateServerFields(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateMetricsPath(t *testing.T) {
+	t.Parallel()
+
+	validPaths := []string{"", "/metrics", "/internal/metrics"}
+	for _, path := range validPaths {
+		t.Run("valid/"+path, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Metrics = config.MetricsConfig{Path: path, Enabled: true}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Unexpected validation error: %v", err)
+			}
+		})
+	}
+
+	invalidPaths := []string{"metrics", "/metrics/{name}", "/health", "/v1/messages"}
+	for _, path := range invalidPaths {
+		t.Run("invalid/"+path, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Metrics = config.MetricsConfig{Path: path, Enabled: true}
+			err := cfg.Validate()
+			if err == nil {
+				t.Errorf("Expected validation error for metrics.path %q", path)
+			} else if !strings.Contains(err.Error(), "metrics.path") {
+				t.Errorf("Expected 'metrics.path' in error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTracing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		protocol    string
+		wantErr     string
+		sampleRatio float64
+	}{
+		{name: "defaults", protocol: "", sampleRatio: 0, wantErr: ""},
+		{name: "grpc", protocol: "grpc", sampleRatio: 1, wantErr: ""},
+		{name: "http", protocol: "http", sampleRatio: 0.1, wantErr: ""},
+		{name: "unknown protocol", protocol: "zipkin", sampleRatio: 0, wantErr: "observability.tracing.protocol"},
+		{name: "negative ratio", protocol: "", sampleRatio: -0.5, wantErr: "observability.tracing.sample_ratio"},
+		{name: "ratio above one", protocol: "", sampleRatio: 2, wantErr: "observability.tracing.sample_ratio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Observability.Tracing.Enabled = true
+			cfg.Observability.Tracing.Protocol = tt.protocol
+			cfg.Observability.Tracing.SampleRatio = tt.sampleRatio
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuthClients(t *testing.T) {
+	t.Parallel()
+
+	const (
+		aliceHash = "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+		bobHash   = "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"
+	)
+	client := func(name, keyHash string, providers ...string) config.ClientKeyConfig {
+		return config.ClientKeyConfig{
+			Metadata:         nil,
+			Name:             name,
+			KeyHash:          keyHash,
+			CertNames:        nil,
+			AllowedModels:    nil,
+			AllowedProviders: providers,
+			Budget:           config.MakeTestBudgetLimits(),
+			RateLimit:        config.MakeTestRateLimits(),
+		}
+	}
+	certClient := func(name, keyHash string, certNames ...string) config.ClientKeyConfig {
+		certCfg := client(name, keyHash)
+		certCfg.CertNames = certNames
+		return certCfg
+	}
+
+	tests := []struct {
+		name    string
+		wantErr string
+		clients []config.ClientKeyConfig
+	}{
+		{
+			name:    "valid clients",
+			clients: []config.ClientKeyConfig{client("alice", aliceHash, testProviderName), client("bob", bobHash)},
+			wantErr: "",
+		},
+		{
+			name:    "missing name",
+			clients: []config.ClientKeyConfig{client("", aliceHash)},
+			wantErr: "server.auth.clients[0].name is required",
+		},
+		{
+			name:    "duplicate name",
+			clients: []config.ClientKeyConfig{client("alice", aliceHash), client("alice", bobHash)},
+			wantErr: "duplicate auth client name: alice",
+		},
+		{
+			name:    "plaintext key instead of hash",
+			clients: []config.ClientKeyConfig{client("alice", "sk-relay-alice")},
+			wantErr: "server.auth.clients[alice].key_hash must be a hex-encoded SHA-256 hash",
+		},
+		{
+			name:    "duplicate hash ignoring case",
+			clients: []config.ClientKeyConfig{client("alice", bobHash), client("bob", strings.ToLower(bobHash))},
+			wantErr: "server.auth.clients[bob].key_hash is already used by another client",
+		},
+		{
+			name:    "unknown provider",
+			clients: []config.ClientKeyConfig{client("alice", aliceHash, "missing")},
+			wantErr: `server.auth.clients[alice].allowed_providers references unknown provider "missing"`,
+		},
+		{
+			name:    "certificate client without key",
+			clients: []config.ClientKeyConfig{certClient("ci", "", "ci.example.com")},
+			wantErr: "",
+		},
+		{
+			name:    "certificate client with invalid key",
+			clients: []config.ClientKeyConfig{certClient("ci", "sk-relay-ci", "ci.example.com")},
+			wantErr: "server.auth.clients[ci].key_hash must be a hex-encoded SHA-256 hash",
+		},
+		{
+			name: "duplicate certificate name",
+			clients: []config.ClientKeyConfig{
+				certClient("ci", "", "ci.example.com"), certClient("deploy", "", "ci.example.com"),
+			},
+			wantErr: `server.auth.clients[deploy].cert_names: "ci.example.com" is already used by another client`,
+		},
+		{
+			name:    "empty certificate name",
+			clients: []config.ClientKeyConfig{certClient("ci", "", "")},
+			wantErr: "server.auth.clients[ci].cert_names must not contain empty names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Server.TLS = testTLSWithClientCA()
+			cfg.Server.Auth.Clients = tt.clients
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuthJWT(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mutate  func(jwt *config.JWTConfig)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "valid", wantErr: "",
+			mutate: func(jwt *config.JWTConfig) {
+				jwt.JWKSURL = "https://sso.example.com/.well-known/jwks.json"
+				jwt.Issuer = "https://sso.example.com"
+				jwt.Groups = []config.JWTGroupConfig{
+					{Name: "ml", AllowedModels: nil, AllowedProviders: []string{testProviderName}},
+				}
+			},
+		},
+		{
+			name: "both sources", wantErr: "only one of jwks_url and jwks_file may be set",
+			mutate: func(jwt *config.JWTConfig) {
+				jwt.JWKSURL = "https://sso.example.com/jwks.json"
+				jwt.JWKSFile = "/etc/cc-relay/jwks.json"
+			},
+		},
+		{
+			name: "url without scheme", wantErr: "server.auth.jwt.jwks_url must be an http or https URL",
+			mutate: func(jwt *config.JWTConfig) { jwt.JWKSURL = "sso.example.com/jwks.json" },
+		},
+		{
+			name: "settings without source", wantErr: "server.auth.jwt requires jwks_url or jwks_file",
+			mutate: func(jwt *config.JWTConfig) { jwt.Issuer = "https://sso.example.com" },
+		},
+		{
+			name: "negative durations", wantErr: "server.auth.jwt.leeway_ms must be >= 0",
+			mutate: func(jwt *config.JWTConfig) {
+				jwt.JWKSFile = "/etc/cc-relay/jwks.json"
+				jwt.LeewayMS = -1
+			},
+		},
+		{
+			name: "duplicate group", wantErr: "duplicate server.auth.jwt group: ml",
+			mutate: func(jwt *config.JWTConfig) {
+				jwt.JWKSFile = "/etc/cc-relay/jwks.json"
+				jwt.Groups = []config.JWTGroupConfig{
+					{Name: "ml", AllowedModels: nil, AllowedProviders: nil},
+					{Name: "ml", AllowedModels: nil, AllowedProviders: nil},
+				}
+			},
+		},
+		{
+			name: "unknown provider", wantErr: `server.auth.jwt.groups[ml].allowed_providers references unknown provider`,
+			mutate: func(jwt *config.JWTConfig) {
+				jwt.JWKSFile = "/etc/cc-relay/jwks.json"
+				jwt.Groups = []config.JWTGroupConfig{{Name: "ml", AllowedModels: nil, AllowedProviders: []string{"missing"}}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			tt.mutate(&cfg.Server.Auth.JWT)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// testTLSWithClientCA returns TLS settings that verify client certificates.
+func testTLSWithClientCA() config.TLSConfig {
+	return config.TLSConfig{
+		CertFile:     "/etc/cc-relay/tls.crt",
+		KeyFile:      "/etc/cc-relay/tls.key",
+		ClientCAFile: "/etc/cc-relay/clients-ca.crt",
+		ClientAuth:   "",
+		MinVersion:   "",
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	t.Parallel()
+
+	certClient := config.ClientKeyConfig{
+		Metadata:         nil,
+		Name:             "ci",
+		KeyHash:          "",
+		CertNames:        []string{"ci.example.com"},
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+		Budget:           config.MakeTestBudgetLimits(),
+		RateLimit:        config.MakeTestRateLimits(),
+	}
+
+	tests := []struct {
+		mutate  func(cfg *config.Config)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "valid", wantErr: "",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.ClientAuth = config.TLSClientAuthOptional
+				cfg.Server.TLS.MinVersion = config.TLSVersion13
+			},
+		},
+		{
+			name: "certificate without key", wantErr: "server.tls.cert_file and server.tls.key_file must be set together",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.KeyFile = ""
+			},
+		},
+		{
+			name: "client CA without certificate", wantErr: "server.tls.client_ca_file requires cert_file and key_file",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.CertFile = ""
+				cfg.Server.TLS.KeyFile = ""
+			},
+		},
+		{
+			name: "client auth without client CA", wantErr: "server.tls.client_auth requires client_ca_file",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.ClientCAFile = ""
+				cfg.Server.TLS.ClientAuth = config.TLSClientAuthRequire
+			},
+		},
+		{
+			name: "unknown client auth", wantErr: `server.tls.client_auth must be "require" or "optional"`,
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.ClientAuth = "request"
+			},
+		},
+		{
+			name: "unknown min version", wantErr: `server.tls.min_version must be "1.2" or "1.3"`,
+			mutate: func(cfg *config.Config) {
+				cfg.Server.TLS = testTLSWithClientCA()
+				cfg.Server.TLS.MinVersion = "1.1"
+			},
+		},
+		{
+			name: "certificate names without client CA", wantErr: "server.auth.clients[ci].cert_names requires server.tls.client_ca_file",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.Auth.Clients = []config.ClientKeyConfig{certClient}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateBudgets(t *testing.T) {
+	t.Parallel()
+
+	const aliceHash = "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	prices := map[string]budget.Price{
+		"claude-sonnet": {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 0, CacheReadPerMTok: 0},
+	}
+
+	tests := []struct {
+		mutate  func(cfg *config.Config)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "token budgets need no prices",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.Global.TokensPerDay = 1_000_000
+			},
+			wantErr: "",
+		},
+		{
+			name: "spend budget with prices",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.Prices = prices
+				cfg.Server.Auth.Clients[0].Budget.USDPerMonth = 50
+			},
+			wantErr: "",
+		},
+		{
+			name: "spend budget without prices",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.Auth.Clients[0].Budget.USDPerMonth = 50
+			},
+			wantErr: "budgets.prices is required when a usd_per_month budget is set",
+		},
+		{
+			name: "spend budget with provider prices",
+			mutate: func(cfg *config.Config) {
+				cfg.Providers[0].Prices = map[string]config.ModelPrice{"claude-sonnet": testPrice(3, 0)}
+				cfg.Server.Auth.Clients[0].Budget.USDPerMonth = 50
+			},
+			wantErr: "",
+		},
+		{
+			name: "negative client limit",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.Auth.Clients[0].Budget.TokensPerMonth = -1
+			},
+			wantErr: "server.auth.clients[alice].budget.tokens_per_month must not be negative",
+		},
+		{
+			name: "negative price",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.Prices = map[string]budget.Price{
+					"gpt": {InputPerMTok: -1, OutputPerMTok: 0, CacheWritePerMTok: 0, CacheReadPerMTok: 0},
+				}
+			},
+			wantErr: "budgets.prices[gpt] must not be negative",
+		},
+		{
+			name: "soft limit over 100",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.SoftLimitPercent = 120
+			},
+			wantErr: "budgets.soft_limit_percent must be between 0 and 100 (got 120)",
+		},
+		{
+			name: "budget without state path",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.StatePath = ""
+				cfg.Server.Auth.Clients[0].Budget.TokensPerDay = 1_000_000
+			},
+			wantErr: "budgets.state_path is required when a budget is set and cache.mode is not ha",
+		},
+		{
+			name: "budget in HA mode needs no state path",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.StatePath = ""
+				cfg.Cache.Mode = cache.ModeHA
+				cfg.Budgets.Global.TokensPerMonth = 1_000_000
+			},
+			wantErr: "",
+		},
+		{
+			name: "no budget needs no state path",
+			mutate: func(cfg *config.Config) {
+				cfg.Budgets.StatePath = ""
+			},
+			wantErr: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Server.Auth.Clients = []config.ClientKeyConfig{{
+				Metadata:         nil,
+				Name:             "alice",
+				KeyHash:          aliceHash,
+				CertNames:        nil,
+				AllowedModels:    nil,
+				AllowedProviders: nil,
+				Budget:           config.MakeTestBudgetLimits(),
+				RateLimit:        config.MakeTestRateLimits(),
+			}}
+			cfg.Budgets.StatePath = "budgets.json"
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAdmin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		metricsPath string
+		wantErr     string
+		admin       config.AdminConfig
+	}{
+		{
+			name:        "disabled without token",
+			admin:       config.AdminConfig{Token: "", Enabled: false},
+			metricsPath: "",
+			wantErr:     "",
+		},
+		{
+			name:        "enabled with token",
+			admin:       config.AdminConfig{Token: "admin-secret", Enabled: true},
+			metricsPath: "",
+			wantErr:     "",
+		},
+		{
+			name:        "enabled without token",
+			admin:       config.AdminConfig{Token: "", Enabled: true},
+			metricsPath: "",
+			wantErr:     "admin.token is required when admin.enabled is true",
+		},
+		{
+			name:        "metrics under admin path",
+			admin:       config.AdminConfig{Token: "admin-secret", Enabled: true},
+			metricsPath: "/admin/metrics",
+			wantErr:     `metrics.path "/admin/metrics" conflicts with the admin API`,
+		},
+		{
+			name:        "metrics under admin path with admin disabled",
+			admin:       config.AdminConfig{Token: "", Enabled: false},
+			metricsPath: "/admin/metrics",
+			wantErr:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Admin = tt.admin
+			cfg.Metrics = config.MetricsConfig{Path: tt.metricsPath, Enabled: true}
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func accessPolicy(name, effect string, providerNames ...string) policy.Policy {
+	return policy.Policy{
+		Name:    name,
+		Effect:  effect,
+		Message: "",
+		Match: policy.Match{
+			Metadata:       nil,
+			HasTools:       nil,
+			HasThinking:    nil,
+			Clients:        nil,
+			Models:         nil,
+			ProviderModels: nil,
+			Providers:      providerNames,
+			MaxTokensAbove: 0,
+		},
+	}
+}
+
+func TestValidateAccess(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mutate  func(cfg *config.Config)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "valid", wantErr: "",
+			mutate: func(cfg *config.Config) {
+				cfg.Access.DefaultEffect = policy.EffectDeny
+				cfg.Access.Policies = []policy.Policy{accessPolicy("direct", policy.EffectAllow, testProviderName)}
+			},
+		},
+		{
+			name: "unknown default effect", wantErr: `access.default_effect is invalid (got "block", valid: allow, deny)`,
+			mutate: func(cfg *config.Config) {
+				cfg.Access.DefaultEffect = "block"
+			},
+		},
+		{
+			name: "missing name", wantErr: "access.policies[0].name is required",
+			mutate: func(cfg *config.Config) {
+				cfg.Access.Policies = []policy.Policy{accessPolicy("", policy.EffectDeny)}
+			},
+		},
+		{
+			name: "duplicate name", wantErr: "duplicate access policy name: opus",
+			mutate: func(cfg *config.Config) {
+				cfg.Access.Policies = []policy.Policy{
+					accessPolicy("opus", policy.EffectDeny), accessPolicy("opus", policy.EffectAllow),
+				}
+			},
+		},
+		{
+			name: "missing effect", wantErr: `access.policies[opus].effect must be allow or deny (got "")`,
+			mutate: func(cfg *config.Config) {
+				cfg.Access.Policies = []policy.Policy{accessPolicy("opus", "")}
+			},
+		},
+		{
+			name: "unknown provider", wantErr: `access.policies[opus].match.providers references unknown provider "missing"`,
+			mutate: func(cfg *config.Config) {
+				cfg.Access.Policies = []policy.Policy{accessPolicy("opus", policy.EffectDeny, "missing")}
+			},
+		},
+		{
+			name: "negative max tokens", wantErr: "access.policies[opus].match.max_tokens_above must be >= 0",
+			mutate: func(cfg *config.Config) {
+				cfg.Access.Policies = []policy.Policy{accessPolicy("opus", policy.EffectDeny)}
+				cfg.Access.Policies[0].Match.MaxTokensAbove = -1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mutate  func(cfg *config.Config)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "valid", wantErr: "",
+			mutate: func(cfg *config.Config) {
+				cfg.RateLimits.Clients = ratelimit.Limits{RPM: 60, TPM: 100_000, MaxConcurrent: 4}
+				cfg.RateLimits.IPs = ratelimit.Limits{RPM: 10, TPM: 0, MaxConcurrent: 0}
+				cfg.Server.Auth.Clients[0].RateLimit.RPM = 600
+			},
+		},
+		{
+			name: "negative client default", wantErr: "rate_limits.clients rpm, tpm and max_concurrent must be >= 0",
+			mutate: func(cfg *config.Config) {
+				cfg.RateLimits.Clients.TPM = -1
+			},
+		},
+		{
+			name: "negative ip limit", wantErr: "rate_limits.ips rpm, tpm and max_concurrent must be >= 0",
+			mutate: func(cfg *config.Config) {
+				cfg.RateLimits.IPs.MaxConcurrent = -1
+			},
+		},
+		{
+			name:    "negative client limit",
+			wantErr: "server.auth.clients[alice].rate_limit rpm, tpm and max_concurrent must be >= 0",
+			mutate: func(cfg *config.Config) {
+				cfg.Server.Auth.Clients[0].RateLimit.RPM = -1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			cfg.Server.Auth.Clients = []config.ClientKeyConfig{{
+				Metadata:         nil,
+				Name:             "alice",
+				KeyHash:          "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+				CertNames:        nil,
+				AllowedModels:    nil,
+				AllowedProviders: nil,
+				Budget:           config.MakeTestBudgetLimits(),
+				RateLimit:        config.MakeTestRateLimits(),
+			}}
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateDLP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mutate  func(cfg *config.Config)
+		name    string
+		wantErr string
+	}{
+		{
+			name: "valid", wantErr: "",
+			mutate: func(cfg *config.Config) {
+				cfg.Providers[0].Trust = dlp.TrustTrusted
+				cfg.DLP.Action = dlp.ActionRestrict
+				cfg.DLP.Detectors = dlp.Detectors()
+				cfg.DLP.Patterns = []dlp.Pattern{{Name: "ticket", Regex: `SEC-\d+`}}
+				cfg.DLP.MinEntropy = 4
+			},
+		},
+		{
+			name: "unknown trust level", wantErr: `providers[test].trust is invalid (got "high"`,
+			mutate: func(cfg *config.Config) {
+				cfg.Providers[0].Trust = "high"
+			},
+		},
+		{
+			name: "unknown action", wantErr: `dlp.action is invalid (got "drop", valid: mask, block, restrict)`,
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Action = "drop"
+			},
+		},
+		{
+			name: "negative min entropy", wantErr: "dlp.min_entropy must be >= 0",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.MinEntropy = -1
+			},
+		},
+		{
+			name: "unknown detector", wantErr: `dlp.detectors has unknown detector "ssn"`,
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Detectors = []string{dlp.DetectorEmail, "ssn"}
+			},
+		},
+		{
+			name: "missing pattern name", wantErr: "dlp.patterns[0].name is required",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Patterns = []dlp.Pattern{{Name: "", Regex: `SEC-\d+`}}
+			},
+		},
+		{
+			name: "pattern named like a detector", wantErr: "duplicate dlp pattern name: email",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Patterns = []dlp.Pattern{{Name: dlp.DetectorEmail, Regex: `@corp\.internal`}}
+			},
+		},
+		{
+			name: "missing regex", wantErr: "dlp.patterns[ticket].regex is required",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Patterns = []dlp.Pattern{{Name: "ticket", Regex: ""}}
+			},
+		},
+		{
+			name: "invalid regex", wantErr: "dlp.patterns[ticket].regex is invalid",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Patterns = []dlp.Pattern{{Name: "ticket", Regex: `SEC-(\d+`}}
+			},
+		},
+		{
+			name: "restrict without trusted provider", wantErr: "dlp.action restrict requires at least one provider",
+			mutate: func(cfg *config.Config) {
+				cfg.DLP.Action = dlp.ActionRestrict
+				cfg.DLP.Detectors = []string{dlp.DetectorEmail}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := configWithSingleProvider(testListenAddr)
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
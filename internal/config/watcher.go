@@ -31,6 +31,7 @@ type Watcher struct {
 	callbacks     []ReloadCallback
 	debounceDelay time.Duration
 	mu            sync.RWMutex
+	reloadMu      sync.Mutex
 	closed        bool
 }
 
@@ -68,6 +69,7 @@ func NewWatcher(path string, opts ...WatcherOption) (*Watcher, error) {
 		callbacks:     make([]ReloadCallback, 0),
 		debounceDelay: 100 * time.Millisecond,
 		mu:            sync.RWMutex{},
+		reloadMu:      sync.Mutex{},
 		closed:        false,
 	}
 
@@ -181,16 +183,28 @@ func (w *Watcher) cleanupTimer(timer *time.Timer) {
 	}
 }
 
-// triggerReload loads the config and invokes all registered callbacks.
+// triggerReload reloads the config after a file change.
 func (w *Watcher) triggerReload() {
+	if err := w.Reload(); err != nil {
+		log.Error().Err(err).Str("path", w.path).Msg("failed to reload config")
+	}
+}
+
+// Reload loads the config file and invokes all registered callbacks, as if
+// the file had changed. Reloads are serialized, so callbacks of two reloads
+// never interleave. Returns the load error; the running config is then kept.
+func (w *Watcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
 	cfg, err := Load(w.path)
 	if err != nil {
-		log.Error().Err(err).Str("path", w.path).Msg("failed to reload config")
-		return
+		return err
 	}
 
 	log.Info().Str("path", w.path).Msg("config file reloaded")
 	w.invokeCallbacks(cfg)
+	return nil
 }
 
 // invokeCallbacks calls all registered callbacks with the new config.
@@ -412,3 +412,33 @@ logging:
 		t.Fatalf("Failed to write test config: %v", err)
 	}
 }
+
+func TestWatcherReload(t *testing.T) {
+	t.Parallel()
+
+	fix := config.NewTestWatcher(t)
+
+	var callCount atomic.Int32
+	fix.Watcher.OnReload(func(_ *config.Config) error {
+		callCount.Add(1)
+		return nil
+	})
+
+	// Reload runs callbacks synchronously without a file change or Watch.
+	if err := fix.Watcher.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if callCount.Load() != 1 {
+		t.Errorf("Expected 1 callback after Reload, got %d", callCount.Load())
+	}
+
+	if err := os.WriteFile(fix.ConfigPath, []byte("invalid: yaml: :::"), 0o600); err != nil {
+		t.Fatalf("Failed to write invalid config: %v", err)
+	}
+	if err := fix.Watcher.Reload(); err == nil {
+		t.Error("Expected Reload to fail for invalid config")
+	}
+	if callCount.Load() != 1 {
+		t.Errorf("Expected no callback for invalid config, got %d", callCount.Load())
+	}
+}
@@ -0,0 +1,81 @@
+package di
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+// budgetSaveInterval is how often budget usage is saved to budgets.state_path.
+const budgetSaveInterval = 10 * time.Second
+
+// BudgetService wraps the budget tracker for DI.
+type BudgetService struct {
+	Tracker *budget.Tracker
+	file    *cache.FileCounter
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBudgets creates the budget tracker on the main cache backend.
+// The tracker is always created; budgets are read from the live config, so
+// budgets added by a reload are enforced without a restart. Without a
+// cache that supports counters, usage is restored from budgets.state_path
+// and saved there periodically and on shutdown.
+func NewBudgets(i do.Injector) (*BudgetService, error) {
+	cacheSvc := do.MustInvoke[*CacheService](i)
+	cfgSvc := do.MustInvoke[*ConfigService](i)
+	svc := &BudgetService{
+		Tracker: nil,
+		file:    nil,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	statePath := cfgSvc.Config.Budgets.StatePath
+	if _, shared := cacheSvc.Cache.(cache.Counter); shared || statePath == "" {
+		svc.Tracker = budget.NewTracker(cacheSvc.Cache)
+		return svc, nil
+	}
+
+	file, err := cache.NewFileCounter(statePath, time.Now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore budget usage: %w", err)
+	}
+	svc.file = file
+	svc.Tracker = budget.NewTrackerWithCounter(file)
+	go svc.run()
+	return svc, nil
+}
+
+func (s *BudgetService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(budgetSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.file.Save(); err != nil {
+				log.Warn().Err(err).Msg("failed to save budget usage")
+			}
+		}
+	}
+}
+
+// Shutdown implements do.Shutdowner, saving budget usage one last time.
+func (s *BudgetService) Shutdown() error {
+	if s.file == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	return s.file.Save()
+}
@@ -0,0 +1,21 @@
+package di
+
+import (
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+// ClientLimitsService wraps the per-client and per-IP rate limiter for DI.
+type ClientLimitsService struct {
+	Limiter *proxy.ClientRateLimiter
+}
+
+// NewClientLimits creates the client rate limiter on the main cache backend,
+// so requests and tokens per minute are shared across HA nodes. Like budgets,
+// limits are read from the live config and apply as soon as they are reloaded.
+func NewClientLimits(i do.Injector) (*ClientLimitsService, error) {
+	cacheSvc := do.MustInvoke[*CacheService](i)
+
+	return &ClientLimitsService{Limiter: proxy.NewClientRateLimiter(cacheSvc.Cache)}, nil
+}
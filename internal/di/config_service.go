@@ -2,8 +2,10 @@ package di
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/samber/do/v2"
@@ -16,11 +18,15 @@ import (
 // requests to continue uninterrupted while new requests use reloaded config.
 type ConfigService struct {
 	config  atomic.Pointer[config.Config]
+	version atomic.Pointer[config.Version]
 	watcher *config.Watcher
 	Config  *config.Config
 	path    string
 }
 
+// errReloadUnavailable is returned by Reload when no watcher could be created.
+var errReloadUnavailable = errors.New("config reload unavailable: hot-reload is disabled")
+
 // Get returns the current configuration via atomic load (lock-free read).
 // This is the preferred method for accessing config during request handling.
 func (c *ConfigService) Get() *config.Config {
@@ -41,6 +47,11 @@ func (c *ConfigService) StartWatching(ctx context.Context) {
 		c.config.Store(newCfg)
 		// Keep legacy Config pointer in sync for backward compatibility.
 		c.Config = newCfg
+		c.version.Store(&config.Version{
+			LoadedAt:   time.Now(),
+			Path:       c.path,
+			Generation: c.Version().Generation + 1,
+		})
 		log.Info().Str("path", c.path).Msg("config hot-reloaded successfully")
 		return nil
 	})
@@ -55,6 +66,23 @@ func (c *ConfigService) StartWatching(ctx context.Context) {
 	log.Info().Str("path", c.path).Msg("config file watcher started")
 }
 
+// Version returns the version of the current configuration.
+func (c *ConfigService) Version() config.Version {
+	if v := c.version.Load(); v != nil {
+		return *v
+	}
+	return config.Version{LoadedAt: time.Time{}, Path: c.path, Generation: 0}
+}
+
+// Reload reloads the config file now, running the same callbacks as a file
+// change. Returns an error if the file fails to load; the current config is kept.
+func (c *ConfigService) Reload() error {
+	if c.watcher == nil {
+		return errReloadUnavailable
+	}
+	return c.watcher.Reload()
+}
+
 // Shutdown implements do.Shutdowner for graceful watcher cleanup.
 func (c *ConfigService) Shutdown() error {
 	if c.watcher != nil {
@@ -75,6 +103,7 @@ func NewConfig(i do.Injector) (*ConfigService, error) {
 
 	svc := &ConfigService{
 		config:  atomic.Pointer[config.Config]{},
+		version: atomic.Pointer[config.Version]{},
 		watcher: nil,
 		Config:  cfg,
 		path:    path,
@@ -82,6 +111,7 @@ func NewConfig(i do.Injector) (*ConfigService, error) {
 
 	// Store initial config in atomic pointer
 	svc.config.Store(cfg)
+	svc.version.Store(&config.Version{LoadedAt: time.Now(), Path: path, Generation: 1})
 
 	// Create watcher (warn on failure, don't error - hot-reload is optional)
 	watcher, err := config.NewWatcher(path)
@@ -2,6 +2,8 @@ package di_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/di"
 	"github.com/omarluq/cc-relay/internal/router"
 )
@@ -318,3 +321,177 @@ func TestRouterService(t *testing.T) {
 		}
 	})
 }
+
+func TestMetricsService(t *testing.T) {
+	t.Parallel()
+	t.Run("metrics are disabled by default", func(t *testing.T) {
+		t.Parallel()
+		container, err := di.NewContainer(createTempConfigFile(t))
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownContainer(t, container) })
+
+		metricsSvc, err := di.Invoke[*di.MetricsService](container)
+		require.NoError(t, err)
+		assert.Nil(t, metricsSvc.Metrics)
+	})
+
+	t.Run("handler serves metrics when enabled", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		cfg := validConfig + "      - key: test-key-2\nmetrics:\n  enabled: true\n  path: /internal/metrics\n"
+		require.NoError(t, os.WriteFile(path, []byte(cfg), 0o600))
+
+		container, err := di.NewContainer(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownContainer(t, container) })
+
+		handlerSvc, err := di.Invoke[*di.HandlerService](container)
+		require.NoError(t, err)
+
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/internal/metrics", http.NoBody)
+		rec := httptest.NewRecorder()
+		handlerSvc.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `cc_relay_keypool_keys{provider="anthropic"} 2`)
+		assert.Contains(t, rec.Body.String(), "cc_relay_inflight_requests 0")
+	})
+}
+
+func TestBudgetService(t *testing.T) {
+	t.Parallel()
+	container, err := di.NewContainer(createTempConfigFile(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+
+	budgetSvc, err := di.Invoke[*di.BudgetService](container)
+	require.NoError(t, err)
+	assert.NotNil(t, budgetSvc.Tracker, "the tracker exists even without budgets so reloads can add them")
+}
+
+func TestBudgetServiceKeepsUsageAcrossRestarts(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	statePath := filepath.Join(dir, "budgets.json")
+	cfg := validConfig + "budgets:\n  state_path: " + statePath + "\n  global:\n    tokens_per_day: 1000\n"
+	require.NoError(t, os.WriteFile(path, []byte(cfg), 0o600))
+	scopes := []budget.Scope{budget.GlobalScope(budget.Limits{TokensPerDay: 1000, TokensPerMonth: 0, USDPerMonth: 0})}
+	usage := budget.Usage{InputTokens: 600, OutputTokens: 400, CacheCreationTokens: 0, CacheReadTokens: 0}
+
+	container, err := di.NewContainer(path)
+	require.NoError(t, err)
+	budgetSvc, err := di.Invoke[*di.BudgetService](container)
+	require.NoError(t, err)
+	require.NoError(t, budgetSvc.Tracker.Record(context.Background(), scopes, usage, 0))
+	require.NoError(t, container.Shutdown())
+
+	container, err = di.NewContainer(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+	budgetSvc, err = di.Invoke[*di.BudgetService](container)
+	require.NoError(t, err)
+	status, err := budgetSvc.Tracker.Check(context.Background(), scopes, budget.DefaultSoftLimitPercent)
+	require.NoError(t, err)
+	assert.Len(t, status.Exceeded, 1, "usage before the restart still counts")
+}
+
+func TestClientLimitsService(t *testing.T) {
+	t.Parallel()
+	container, err := di.NewContainer(createTempConfigFile(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+
+	limitsSvc, err := di.Invoke[*di.ClientLimitsService](container)
+	require.NoError(t, err)
+	assert.NotNil(t, limitsSvc.Limiter, "the limiter exists even without rate limits so reloads can add them")
+}
+
+func TestSessionService(t *testing.T) {
+	t.Parallel()
+	container, err := di.NewContainer(createTempConfigFile(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+
+	sessionSvc, err := di.Invoke[*di.SessionService](container)
+	require.NoError(t, err)
+	assert.NotNil(t, sessionSvc.Store, "the store exists even without session affinity so reloads can enable it")
+}
+
+func TestResponseCacheService(t *testing.T) {
+	t.Parallel()
+	container, err := di.NewContainer(createTempConfigFile(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+
+	responseCacheSvc, err := di.Invoke[*di.ResponseCacheService](container)
+	require.NoError(t, err)
+	assert.NotNil(t, responseCacheSvc.Cache, "the cache exists even when disabled so reloads can enable it")
+}
+
+func TestKeyStateService(t *testing.T) {
+	t.Parallel()
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		container, err := di.NewContainer(createTempConfigFile(t))
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownContainer(t, container) })
+
+		keyStateSvc, err := di.Invoke[*di.KeyStateService](container)
+		require.NoError(t, err)
+		assert.NoError(t, keyStateSvc.Shutdown())
+	})
+
+	t.Run("saves key state to the file on shutdown", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		statePath := filepath.Join(dir, "key-state.json")
+		cfg := validConfig + "      - key: test-key-2\n" +
+			"key_state:\n  enabled: true\n  path: " + statePath + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(cfg), 0o600))
+
+		container, err := di.NewContainer(path)
+		require.NoError(t, err)
+		_, err = di.Invoke[*di.KeyStateService](container)
+		require.NoError(t, err)
+		require.NoError(t, container.Shutdown())
+
+		data, err := os.ReadFile(filepath.Clean(statePath))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"configured"`)
+	})
+}
+
+func TestTracingService(t *testing.T) {
+	t.Parallel()
+	t.Run("tracing is disabled by default", func(t *testing.T) {
+		t.Parallel()
+		container, err := di.NewContainer(createTempConfigFile(t))
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownContainer(t, container) })
+
+		tracingSvc, err := di.Invoke[*di.TracingService](container)
+		require.NoError(t, err)
+		assert.Nil(t, tracingSvc.Tracing)
+	})
+
+	t.Run("exporter is created when enabled", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		cfg := validConfig + "observability:\n  tracing:\n    enabled: true\n" +
+			"    protocol: http\n    endpoint: 127.0.0.1:4318\n    insecure: true\n"
+		require.NoError(t, os.WriteFile(path, []byte(cfg), 0o600))
+
+		container, err := di.NewContainer(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownContainer(t, container) })
+
+		tracingSvc, err := di.Invoke[*di.TracingService](container)
+		require.NoError(t, err)
+		assert.NotNil(t, tracingSvc.Tracing)
+
+		_, err = di.Invoke[*di.HandlerService](container)
+		require.NoError(t, err)
+	})
+}
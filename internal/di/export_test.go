@@ -4,10 +4,13 @@ import (
 	"net/http"
 	"sync/atomic"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/cache"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"github.com/omarluq/cc-relay/internal/health"
 	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 	"github.com/omarluq/cc-relay/internal/router"
 )
 
@@ -30,6 +33,10 @@ func MustTestConfig() config.Config {
 		Providers: []config.ProviderConfig{},
 		Routing: config.RoutingConfig{
 			ModelMapping:    map[string]string{},
+			QualityFloor:    nil,
+			Rules:           nil,
+			SessionAffinity: config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+			Hedging:         config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 			DefaultProvider: "",
 			Strategy:        "",
 			FailoverTimeout: 0,
@@ -61,9 +68,12 @@ func MustTestConfig() config.Config {
 		Server: config.ServerConfig{
 			Listen: ":8787",
 			APIKey: "",
+			TLS:    testTLSConfig(),
 			Auth: config.AuthConfig{
+				Clients:           nil,
 				APIKey:            "",
 				BearerSecret:      "",
+				JWT:               testJWTConfig(),
 				AllowBearer:       false,
 				AllowSubscription: false,
 			},
@@ -93,6 +103,36 @@ func MustTestConfig() config.Config {
 				BufferItems: 0,
 			},
 		},
+		Metrics: config.MetricsConfig{
+			Path:    "",
+			Enabled: false,
+		},
+		Observability: config.ObservabilityConfig{
+			Tracing: config.TracingConfig{
+				Headers:     nil,
+				Endpoint:    "",
+				Protocol:    "",
+				ServiceName: "",
+				SampleRatio: 0,
+				Enabled:     false,
+				Insecure:    false,
+			},
+		},
+		Budgets: budget.Config{
+			Prices:           nil,
+			StatePath:        "",
+			Global:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+			SoftLimitPercent: 0,
+		},
+		Admin: config.AdminConfig{
+			Token:   "",
+			Enabled: false,
+		},
+		ResponseCache: config.ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: 0, Enabled: false},
+		KeyState:      config.KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: false},
+		Access:        config.AccessConfig{DefaultEffect: "", Policies: nil},
+		DLP:           testDLPConfig(),
+		RateLimits:    testRateLimitsConfig(),
 	}
 }
 
@@ -100,6 +140,10 @@ func MustTestConfig() config.Config {
 func MustTestRoutingConfig(strategy string) config.RoutingConfig {
 	return config.RoutingConfig{
 		ModelMapping:    map[string]string{},
+		QualityFloor:    nil,
+		Rules:           nil,
+		SessionAffinity: config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+		Hedging:         config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 		DefaultProvider: "",
 		Strategy:        strategy,
 		FailoverTimeout: 5000,
@@ -111,6 +155,8 @@ func MustTestRoutingConfig(strategy string) config.RoutingConfig {
 func MustTestProviderConfig(name, pType, baseURL string, keys []config.KeyConfig) config.ProviderConfig {
 	return config.ProviderConfig{
 		ModelMapping:       map[string]string{},
+		Prices:             nil,
+		ModelLimits:        nil,
 		AWSRegion:          "",
 		GCPProjectID:       "",
 		AzureAPIVersion:    "",
@@ -122,6 +168,7 @@ func MustTestProviderConfig(name, pType, baseURL string, keys []config.KeyConfig
 		AzureResourceName:  "",
 		AWSSecretAccessKey: "",
 		GCPRegion:          "",
+		Trust:              "",
 		Models:             nil,
 		Pooling: config.PoolingConfig{
 			Enabled:  false,
@@ -145,6 +192,28 @@ func MustTestKeyConfig(key string) config.KeyConfig {
 	}
 }
 
+// testJWTConfig creates a disabled JWTConfig for testing.
+func testDLPConfig() dlp.Config {
+	return dlp.Config{Action: "", Detectors: nil, Patterns: nil, MinEntropy: 0}
+}
+
+func testRateLimitsConfig() config.RateLimitsConfig {
+	none := ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0}
+	return config.RateLimitsConfig{Clients: none, IPs: none}
+}
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer: "", JWKSURL: "", JWKSFile: "", ClientClaim: "", GroupsClaim: "",
+		Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
+	}
+}
+
+// testTLSConfig creates a disabled TLSConfig for testing.
+func testTLSConfig() config.TLSConfig {
+	return config.TLSConfig{CertFile: "", KeyFile: "", ClientCAFile: "", ClientAuth: "", MinVersion: ""}
+}
+
 // mustTestHealthConfig creates a minimal health.Config for testing.
 func MustTestHealthConfig() health.Config {
 	return health.Config{
@@ -165,9 +234,12 @@ func MustTestServerConfig(listen string) config.ServerConfig {
 	return config.ServerConfig{
 		Listen: listen,
 		APIKey: "",
+		TLS:    testTLSConfig(),
 		Auth: config.AuthConfig{
+			Clients:           nil,
 			APIKey:            "",
 			BearerSecret:      "",
+			JWT:               testJWTConfig(),
 			AllowBearer:       false,
 			AllowSubscription: false,
 		},
@@ -185,6 +257,7 @@ func MustTestProviderInfo(provider providers.Provider, weight, priority int) rou
 		IsHealthy: func() bool { return true },
 		Weight:    weight,
 		Priority:  priority,
+		Prices:    nil,
 	}
 }
 
@@ -193,6 +266,7 @@ func NewConfigServiceUninitialized() *ConfigService {
 	cfg := MustTestConfig()
 	svc := &ConfigService{
 		config:  atomic.Pointer[config.Config]{},
+		version: atomic.Pointer[config.Version]{},
 		watcher: nil,
 		Config:  nil,
 		path:    "",
@@ -219,6 +293,7 @@ func NewRouterServiceWithConfigService(cfgSvc *ConfigService) *RouterService {
 func NewConfigServiceWithConfig(cfg *config.Config) *ConfigService {
 	svc := &ConfigService{
 		config:  atomic.Pointer[config.Config]{},
+		version: atomic.Pointer[config.Version]{},
 		watcher: nil,
 		Config:  cfg,
 		path:    "",
@@ -231,6 +306,7 @@ func NewConfigServiceWithConfig(cfg *config.Config) *ConfigService {
 func NewConfigServiceWithNilWatcher(cfg *config.Config) *ConfigService {
 	svc := &ConfigService{
 		config:  atomic.Pointer[config.Config]{},
+		version: atomic.Pointer[config.Version]{},
 		watcher: nil,
 		Config:  cfg,
 		path:    "",
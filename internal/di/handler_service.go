@@ -25,7 +25,15 @@ func NewProxyHandler(injector do.Injector) (*HandlerService, error) {
 	providerInfoSvc := do.MustInvoke[*ProviderInfoService](injector)
 	trackerSvc := do.MustInvoke[*HealthTrackerService](injector)
 	sigCacheSvc := do.MustInvoke[*SignatureCacheService](injector)
+	budgetSvc := do.MustInvoke[*BudgetService](injector)
+	rateLimitSvc := do.MustInvoke[*ClientLimitsService](injector)
+	sessionSvc := do.MustInvoke[*SessionService](injector)
+	responseCacheSvc := do.MustInvoke[*ResponseCacheService](injector)
 	concurrencySvc := do.MustInvoke[*ConcurrencyService](injector)
+	metricsSvc := do.MustInvoke[*MetricsService](injector)
+	tracingSvc := do.MustInvoke[*TracingService](injector)
+	// Restores persisted key state into the pools before requests are served.
+	do.MustInvoke[*KeyStateService](injector)
 
 	// Use SetupRoutesWithLiveKeyPools for full hot-reload support:
 	// - Live provider info (enabled/disabled, weights, priorities)
@@ -35,6 +43,7 @@ func NewProxyHandler(injector do.Injector) (*HandlerService, error) {
 	liveRouter := router.NewLiveRouter(routerSvc.GetRouterAsFunc())
 	handler, err := proxy.SetupRoutesWithLiveKeyPools(&proxy.RoutesOptions{
 		ConfigProvider:     cfgSvc,
+		ConfigReloader:     cfgSvc, // Admin API config reload and version
 		Provider:           providerSvc.GetPrimaryProvider(),
 		ProviderInfosFunc:  providerInfoSvc.Get, // Hot-reloadable provider info
 		ProviderRouter:     liveRouter,          // Live router for strategy changes
@@ -47,6 +56,12 @@ func NewProxyHandler(injector do.Injector) (*HandlerService, error) {
 		HealthTracker:      trackerSvc.Tracker,
 		SignatureCache:     sigCacheSvc.Cache,
 		ConcurrencyLimiter: concurrencySvc.Limiter, // Hot-reloadable concurrency limit
+		Metrics:            metricsSvc.Metrics,     // Nil unless metrics.enabled
+		Budgets:            budgetSvc.Tracker,      // Enforces the live config's budgets
+		ClientLimits:       rateLimitSvc.Limiter,   // Per-client and per-IP rate limits
+		Sessions:           sessionSvc.Store,       // Session affinity pins
+		ResponseCache:      responseCacheSvc.Cache, // Replays deterministic responses
+		Tracing:            tracingSvc.Tracing,     // Nil unless observability.tracing.enabled
 		ProviderPools:      nil,
 		ProviderKeys:       nil,
 		ProviderInfos:      nil,
@@ -2,10 +2,13 @@ package di_test
 
 import (
 	"context"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/di"
@@ -201,6 +204,41 @@ func TestConfigServiceGetVsDirect(t *testing.T) {
 		"Config field should also be updated after hot-reload")
 }
 
+// TestConfigServiceReload verifies that Reload applies the config file on
+// demand and that each successful reload bumps the config version.
+func TestConfigServiceReload(t *testing.T) {
+	t.Parallel()
+
+	path := createTempConfigFile(t)
+	container, err := di.NewContainer(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownContainer(t, container) })
+
+	cfgSvc, err := di.Invoke[*di.ConfigService](container)
+	require.NoError(t, err)
+	// Register the reload callback without watching the file, so only
+	// explicit reloads apply the edits below.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfgSvc.StartWatching(ctx)
+
+	initial := cfgSvc.Version()
+	assert.Equal(t, uint64(1), initial.Generation)
+	assert.Equal(t, path, initial.Path)
+
+	updated := strings.Replace(validConfig, "level: info", "level: debug", 1)
+	require.NoError(t, os.WriteFile(path, []byte(updated), 0o600))
+	require.NoError(t, cfgSvc.Reload())
+	assert.Equal(t, "debug", cfgSvc.Get().Logging.Level)
+	assert.Equal(t, uint64(2), cfgSvc.Version().Generation)
+	assert.False(t, cfgSvc.Version().LoadedAt.Before(initial.LoadedAt))
+
+	require.NoError(t, os.WriteFile(path, []byte("invalid: yaml: :::"), 0o600))
+	require.Error(t, cfgSvc.Reload())
+	assert.Equal(t, "debug", cfgSvc.Get().Logging.Level, "a failed reload keeps the running config")
+	assert.Equal(t, uint64(2), cfgSvc.Version().Generation)
+}
+
 // BenchmarkHotReload_GetRouter benchmarks the per-request router creation.
 // This establishes a baseline for hot-reload performance overhead.
 func BenchmarkHotReloadGetRouter(b *testing.B) {
@@ -72,6 +72,15 @@ func watchConfig(
 	})
 }
 
+// carryKeyState restores what a replaced pool learned about its keys into the
+// pool replacing it, so a config reload does not forget rate limits and
+// cooldowns of keys it left unchanged.
+func carryKeyState(previous, pool *keypool.KeyPool) {
+	if previous != nil {
+		pool.Restore(previous.Snapshot())
+	}
+}
+
 func initKeyPoolService(
 	cfgSvc *ConfigService,
 	rebuild func(*config.Config) error,
@@ -115,6 +124,7 @@ func (s *KeyPoolService) RebuildFrom(cfg *config.Config) error {
 		if err != nil {
 			return fmt.Errorf("failed to create key pool for provider %s: %w", providerCfg.Name, err)
 		}
+		carryKeyState(s.Get(), pool)
 
 		s.data.Store(&keyPoolData{ProviderName: providerCfg.Name, Pool: pool})
 		s.Pool = pool
@@ -178,6 +188,7 @@ func (s *KeyPoolMapService) GetKeys() map[string]string {
 // RebuildFrom rebuilds key pools from the given config.
 // Called from reload callbacks to create pools for newly enabled providers.
 func (s *KeyPoolMapService) RebuildFrom(cfg *config.Config) error {
+	previous := s.GetPools()
 	pools := make(map[string]*keypool.KeyPool)
 	keys := make(map[string]string)
 	var rebuildErr error
@@ -206,6 +217,7 @@ func (s *KeyPoolMapService) RebuildFrom(cfg *config.Config) error {
 			rebuildErr = err
 			continue // Log and skip, don't fail the entire reload
 		}
+		carryKeyState(previous[providerCfg.Name], pool)
 
 		pools[providerCfg.Name] = pool
 	}
@@ -0,0 +1,129 @@
+package di
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/samber/do/v2"
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/keypool"
+)
+
+// keyStateTimeout bounds loading and saving key state.
+const keyStateTimeout = 10 * time.Second
+
+// KeyStateService persists what the key pools learn from provider responses
+// when key_state is enabled: state is restored into the pools on startup,
+// then saved periodically and on shutdown. Pools rebuilt on config reload
+// carry state over themselves, so the saver always reads the live pools.
+type KeyStateService struct {
+	store      keypool.StateStore
+	poolSvc    *KeyPoolService
+	poolMapSvc *KeyPoolMapService
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewKeyState restores persisted key state and starts saving it. Persistence
+// is configured at startup; changing key_state requires a restart.
+func NewKeyState(i do.Injector) (*KeyStateService, error) {
+	cfgSvc := do.MustInvoke[*ConfigService](i)
+	svc := &KeyStateService{
+		store:      nil,
+		poolSvc:    do.MustInvoke[*KeyPoolService](i),
+		poolMapSvc: do.MustInvoke[*KeyPoolMapService](i),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	cfg := cfgSvc.Config.KeyState
+	if !cfg.Enabled {
+		return svc, nil
+	}
+	if cfg.Path != "" {
+		svc.store = keypool.NewFileStateStore(cfg.Path)
+	} else {
+		svc.store = keypool.NewCacheStateStore(do.MustInvoke[*CacheService](i).Cache)
+	}
+
+	svc.restore()
+	go svc.run(cfg.GetSaveInterval())
+	return svc, nil
+}
+
+// pools returns the live key pools. Pools of the multi-provider map come
+// first, since those are the ones requests are routed through.
+func (s *KeyStateService) pools() []*keypool.KeyPool {
+	pools := lo.Values(s.poolMapSvc.GetPools())
+	if primary := s.poolSvc.Get(); primary != nil {
+		pools = append(pools, primary)
+	}
+	return lo.Compact(pools)
+}
+
+// restore loads the stored state of every configured key into the pools.
+// Failures are logged: starting with fresh key state is always safe.
+func (s *KeyStateService) restore() {
+	ctx, cancel := context.WithTimeout(context.Background(), keyStateTimeout)
+	defer cancel()
+
+	pools := s.pools()
+	keyIDs := lo.Uniq(lo.FlatMap(pools, func(pool *keypool.KeyPool, _ int) []string {
+		return lo.Map(pool.Keys(), func(key *keypool.KeyMetadata, _ int) string { return key.ID })
+	}))
+	states, err := s.store.Load(ctx, keyIDs)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to load key state, starting fresh")
+	}
+
+	restored := 0
+	for _, pool := range pools {
+		restored += pool.Restore(states)
+	}
+	log.Info().Int("restored_keys", restored).Msg("key state restored")
+}
+
+// save stores the current state of every key.
+func (s *KeyStateService) save(ctx context.Context) error {
+	states := lo.UniqBy(lo.FlatMap(s.pools(), func(pool *keypool.KeyPool, _ int) []keypool.KeyState {
+		return pool.Snapshot()
+	}), func(state keypool.KeyState) string { return state.ID })
+	if len(states) == 0 {
+		return nil
+	}
+	return s.store.Save(ctx, states)
+}
+
+func (s *KeyStateService) run(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), keyStateTimeout)
+			if err := s.save(ctx); err != nil {
+				log.Warn().Err(err).Msg("failed to save key state")
+			}
+			cancel()
+		}
+	}
+}
+
+// Shutdown implements do.Shutdowner, saving key state one last time.
+func (s *KeyStateService) Shutdown() error {
+	if s.store == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyStateTimeout)
+	defer cancel()
+	return s.save(ctx)
+}
@@ -0,0 +1,41 @@
+package di
+
+import (
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/metrics"
+)
+
+// MetricsService wraps the Prometheus metrics for DI.
+type MetricsService struct {
+	// Metrics is nil when metrics.enabled is false.
+	Metrics *metrics.Metrics
+}
+
+// NewMetrics creates the Prometheus metrics when enabled in configuration.
+// Key pools are read through the live accessor, so pools rebuilt on
+// hot-reload are reported without recreating the metrics.
+func NewMetrics(i do.Injector) (*MetricsService, error) {
+	cfgSvc := do.MustInvoke[*ConfigService](i)
+	if !cfgSvc.Config.Metrics.Enabled {
+		return &MetricsService{Metrics: nil}, nil
+	}
+
+	poolMapSvc := do.MustInvoke[*KeyPoolMapService](i)
+	trackerSvc := do.MustInvoke[*HealthTrackerService](i)
+	concurrencySvc := do.MustInvoke[*ConcurrencyService](i)
+	cacheSvc := do.MustInvoke[*CacheService](i)
+
+	var cacheStats cache.StatsProvider
+	if statsProvider, ok := cacheSvc.Cache.(cache.StatsProvider); ok {
+		cacheStats = statsProvider
+	}
+
+	return &MetricsService{Metrics: metrics.New(metrics.Options{
+		KeyPools:      poolMapSvc.GetPools,
+		InFlight:      concurrencySvc.Limiter.CurrentInFlight,
+		HealthTracker: trackerSvc.Tracker,
+		Cache:         cacheStats,
+	})}, nil
+}
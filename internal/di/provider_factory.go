@@ -20,10 +20,12 @@ const (
 	ProviderTypeBedrock   = "bedrock"
 	ProviderTypeVertex    = "vertex"
 	ProviderTypeAzure     = "azure"
+	ProviderTypeOpenAI    = "openai"
+	ProviderTypeGemini    = "gemini"
 )
 
 // supportedProviderTypes is the list of supported provider types for error messages.
-const supportedProviderTypes = "anthropic, zai, minimax, ollama, bedrock, vertex, azure"
+const supportedProviderTypes = "anthropic, zai, minimax, ollama, openai, gemini, bedrock, vertex, azure"
 
 // createCloudProvider creates a cloud provider (bedrock, vertex, azure) with validation.
 func createCloudProvider(ctx context.Context, providerConfig *config.ProviderConfig) (providers.Provider, error) {
@@ -82,6 +84,14 @@ func createProvider(ctx context.Context, providerConfig *config.ProviderConfig)
 		return providers.NewOllamaProvider(
 			providerConfig.Name, providerConfig.BaseURL, providerConfig.Models, providerConfig.ModelMapping,
 		), nil
+	case ProviderTypeOpenAI:
+		return providers.NewOpenAIProvider(
+			providerConfig.Name, providerConfig.BaseURL, providerConfig.Models, providerConfig.ModelMapping,
+		), nil
+	case ProviderTypeGemini:
+		return providers.NewGeminiProvider(
+			providerConfig.Name, providerConfig.BaseURL, providerConfig.Models, providerConfig.ModelMapping,
+		), nil
 	case ProviderTypeBedrock, ProviderTypeVertex, ProviderTypeAzure:
 		return createCloudProvider(ctx, providerConfig)
 	default:
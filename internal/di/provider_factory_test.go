@@ -15,6 +15,8 @@ import (
 func baseProviderConfig(name, pType string) config.ProviderConfig {
 	return config.ProviderConfig{
 		ModelMapping:       map[string]string{},
+		Prices:             nil,
+		ModelLimits:        nil,
 		AWSRegion:          "",
 		GCPProjectID:       "",
 		AzureAPIVersion:    "",
@@ -26,6 +28,7 @@ func baseProviderConfig(name, pType string) config.ProviderConfig {
 		AzureResourceName:  "",
 		AWSSecretAccessKey: "",
 		GCPRegion:          "",
+		Trust:              "",
 		Models:             nil,
 		Pooling:            config.PoolingConfig{Enabled: false, Strategy: ""},
 		Keys:               nil,
@@ -245,6 +248,8 @@ func TestCreateCloudProviderNonCloudType(t *testing.T) {
 		di.ProviderTypeZAI,
 		di.ProviderTypeMiniMax,
 		di.ProviderTypeOllama,
+		di.ProviderTypeOpenAI,
+		di.ProviderTypeGemini,
 	}
 
 	for _, pType := range nonCloudTypes {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/samber/do/v2"
+	"github.com/samber/lo"
 
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/router"
@@ -80,12 +81,27 @@ func (s *ProviderInfoService) RebuildFrom(cfg *config.Config) {
 			Weight:    weight,
 			Priority:  priority,
 			IsHealthy: s.trackerSvc.Tracker.IsHealthyFunc(providerName),
+			Prices:    routerPrices(providerCfg.Prices),
 		})
 	}
 
 	s.infos.Store(&providerInfos)
 }
 
+// routerPrices converts a provider's price table for cost_optimized routing.
+func routerPrices(prices map[string]config.ModelPrice) map[string]router.ModelPrice {
+	if len(prices) == 0 {
+		return nil
+	}
+	return lo.MapValues(prices, func(price config.ModelPrice, _ string) router.ModelPrice {
+		return router.ModelPrice{
+			InputPerMTok:  price.InputPerMTok,
+			OutputPerMTok: price.OutputPerMTok,
+			Quality:       price.Quality,
+		}
+	})
+}
+
 // StartWatching begins watching config changes for provider info updates.
 // Registers a callback with the config watcher to rebuild provider info on reload.
 func (s *ProviderInfoService) StartWatching() {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -750,6 +751,20 @@ func TestNewHTTPServer(t *testing.T) {
 		assert.NotNil(t, serverSvc.Server)
 	})
 
+	t.Run("fails when the TLS certificate cannot be loaded", func(t *testing.T) {
+		t.Parallel()
+		missing := filepath.Join(t.TempDir(), "missing")
+		cfg := strings.Replace(singleKeyConfig, `  listen: ":8787"`, `  listen: ":8787"
+  tls:
+    cert_file: `+missing+`.crt
+    key_file: `+missing+`.key`, 1)
+		injector := createTestInjector(t, cfg)
+		defer shutdownInjector(t, injector)
+
+		_, err := do.Invoke[*di.ServerService](injector)
+		require.ErrorContains(t, err, "load TLS certificate")
+	})
+
 	t.Run("Shutdown handles nil server", func(t *testing.T) {
 		t.Parallel()
 		serverSvc := &di.ServerService{Server: nil}
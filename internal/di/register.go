@@ -10,14 +10,21 @@ import "github.com/samber/do/v2"
 // 4. Providers (depends on Config)
 // 5. KeyPool (depends on Config) - primary provider only
 // 6. KeyPoolMap (depends on Config) - all providers
-// 7. Router (depends on Config)
-// 8. HealthTracker (depends on Config, Logger)
-// 9. Checker (depends on HealthTracker, Config, Logger)
-// 10. ProviderInfo (depends on Config, Providers, HealthTracker)
-// 11. SignatureCache (depends on Cache)
-// 12. Concurrency (depends on Config) - global request limiter
-// 13. Handler (depends on all above services)
-// 14. Server (depends on Handler, Config).
+// 7. KeyState (depends on Config, Cache, KeyPool, KeyPoolMap) - persisted key state
+// 8. Router (depends on Config)
+// 9. HealthTracker (depends on Config, Logger)
+// 10. Checker (depends on HealthTracker, Config, Logger)
+// 11. ProviderInfo (depends on Config, Providers, HealthTracker)
+// 12. SignatureCache (depends on Cache)
+// 13. Budgets (depends on Cache)
+// 14. ClientLimits (depends on Cache) - per-client and per-IP rate limits
+// 15. Sessions (depends on Cache)
+// 16. ResponseCache (depends on Cache)
+// 17. Concurrency (depends on Config) - global request limiter
+// 18. Metrics (depends on Config, KeyPoolMap, HealthTracker, Concurrency, Cache)
+// 19. Tracing (depends on Config)
+// 20. Handler (depends on all above services)
+// 21. Server (depends on Handler, Config).
 func RegisterSingletons(injector do.Injector) {
 	do.Provide(injector, NewConfig)
 	do.Provide(injector, NewLogger)
@@ -25,12 +32,19 @@ func RegisterSingletons(injector do.Injector) {
 	do.Provide(injector, NewProviderMap)
 	do.Provide(injector, NewKeyPool)
 	do.Provide(injector, NewKeyPoolMap)
+	do.Provide(injector, NewKeyState)
 	do.Provide(injector, NewRouter)
 	do.Provide(injector, NewHealthTracker)
 	do.Provide(injector, NewChecker)
 	do.Provide(injector, NewProviderInfo)
 	do.Provide(injector, NewSignatureCache)
+	do.Provide(injector, NewBudgets)
+	do.Provide(injector, NewClientLimits)
+	do.Provide(injector, NewSessions)
+	do.Provide(injector, NewResponseCache)
 	do.Provide(injector, NewConcurrencyService)
+	do.Provide(injector, NewMetrics)
+	do.Provide(injector, NewTracing)
 	do.Provide(injector, NewProxyHandler)
 	do.Provide(injector, NewHTTPServer)
 }
@@ -0,0 +1,21 @@
+package di
+
+import (
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+// ResponseCacheService wraps the exact-match response cache for DI.
+type ResponseCacheService struct {
+	Cache *proxy.ResponseCache
+}
+
+// NewResponseCache creates the response cache on the main cache backend.
+// Like the session store, it exists regardless of response_cache.enabled,
+// which the handler checks on every request.
+func NewResponseCache(i do.Injector) (*ResponseCacheService, error) {
+	cacheSvc := do.MustInvoke[*CacheService](i)
+
+	return &ResponseCacheService{Cache: proxy.NewResponseCache(cacheSvc.Cache)}, nil
+}
@@ -2,10 +2,12 @@ package di
 
 import (
 	"context"
+	"crypto/tls"
 	"time"
 
 	"github.com/samber/do/v2"
 
+	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/proxy"
 )
 
@@ -19,18 +21,38 @@ type ServerService struct {
 // Wires cfg.Server.TimeoutMS into proxy.ServerOptions.WriteTimeout. ReadTimeout
 // and IdleTimeout are intentionally not exposed in config — they protect against
 // slowloris and tune keep-alive, and the defaults from proxy.NewServer are sane.
+//
+// When server.tls is set, the certificate, key and client CAs are loaded here,
+// so bad files fail startup, and reloaded whenever the config is.
 func NewHTTPServer(i do.Injector) (*ServerService, error) {
 	cfgSvc := do.MustInvoke[*ConfigService](i)
 	handlerSvc := do.MustInvoke[*HandlerService](i)
 
 	srvCfg := cfgSvc.Config.Server
 
+	var tlsConfig *tls.Config
+	if srvCfg.TLS.IsEnabled() {
+		reloader, err := proxy.NewTLSReloader(srvCfg.TLS, srvCfg.EnableHTTP2)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = reloader.TLSConfig()
+		watchConfig(
+			cfgSvc,
+			func(newCfg *config.Config) error { return reloader.Reload(newCfg.Server.TLS) },
+			"failed to reload TLS certificates, keeping the previous ones",
+			"TLS certificates reloaded after config reload",
+			false,
+		)
+	}
+
 	// ReadTimeout / IdleTimeout intentionally left zero so proxy.NewServer
 	// applies its defaults (slowloris guard / keep-alive). They are not
 	// user-configurable. WriteTimeout is the public `server.timeout_ms` knob.
 	server := proxy.NewServer(proxy.ServerOptions{
 		Addr:         srvCfg.Listen,
 		Handler:      handlerSvc.Handler,
+		TLSConfig:    tlsConfig,
 		WriteTimeout: time.Duration(srvCfg.TimeoutMS) * time.Millisecond,
 		ReadTimeout:  0,
 		IdleTimeout:  0,
@@ -0,0 +1,21 @@
+package di
+
+import (
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+// SessionService wraps the session affinity store for DI.
+type SessionService struct {
+	Store *proxy.SessionStore
+}
+
+// NewSessions creates the session affinity store on the main cache backend.
+// The store is always created; routing.session_affinity is read from the live
+// config, so enabling it by a reload takes effect without a restart.
+func NewSessions(i do.Injector) (*SessionService, error) {
+	cacheSvc := do.MustInvoke[*CacheService](i)
+
+	return &SessionService{Store: proxy.NewSessionStore(cacheSvc.Cache)}, nil
+}
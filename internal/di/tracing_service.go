@@ -0,0 +1,40 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/do/v2"
+
+	"github.com/omarluq/cc-relay/internal/tracing"
+)
+
+// TracingService wraps the OpenTelemetry tracer provider for DI.
+type TracingService struct {
+	// Tracing is nil when observability.tracing.enabled is false.
+	Tracing *tracing.Tracing
+}
+
+// NewTracing creates the OTLP trace exporter when enabled in configuration.
+func NewTracing(i do.Injector) (*TracingService, error) {
+	cfgSvc := do.MustInvoke[*ConfigService](i)
+	tracingCfg := cfgSvc.Config.Observability.Tracing
+	if !tracingCfg.Enabled {
+		return &TracingService{Tracing: nil}, nil
+	}
+
+	t, err := tracing.New(context.Background(), &tracingCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing: %w", err)
+	}
+
+	return &TracingService{Tracing: t}, nil
+}
+
+// Shutdown implements do.Shutdowner, flushing spans still buffered for export.
+func (t *TracingService) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return t.Tracing.Shutdown(ctx)
+}
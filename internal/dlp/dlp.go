@@ -0,0 +1,305 @@
+// Package dlp detects sensitive data in request prompts for cc-relay.
+//
+// The text of the system prompt, of messages and of tool results in an
+// Anthropic Messages request is scanned by built-in detectors for secrets and email addresses, and by
+// custom regular expressions. Depending on the configured action, what they
+// find is masked before the request goes to a provider that is not trusted,
+// the request is refused, or it is only routed to trusted providers.
+package dlp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Actions taken on requests containing sensitive data.
+const (
+	// ActionMask replaces sensitive data before the request goes to an untrusted provider.
+	ActionMask = "mask"
+	// ActionBlock refuses the request.
+	ActionBlock = "block"
+	// ActionRestrict routes the request to trusted providers only.
+	ActionRestrict = "restrict"
+)
+
+// Provider trust levels.
+const (
+	// TrustUntrusted providers never receive sensitive data. This is the default.
+	TrustUntrusted = "untrusted"
+	// TrustTrusted providers receive requests unchanged.
+	TrustTrusted = "trusted"
+)
+
+// Built-in detectors.
+const (
+	DetectorAWSAccessKey = "aws_access_key"
+	DetectorPrivateKey   = "private_key"
+	DetectorJWT          = "jwt"
+	DetectorHighEntropy  = "high_entropy"
+	DetectorEmail        = "email"
+)
+
+// DefaultMinEntropy is the Shannon entropy, in bits per character, above
+// which the high_entropy detector reports a token.
+const DefaultMinEntropy = 4.5
+
+// minEntropyLength is the shortest token the high_entropy detector considers.
+const minEntropyLength = 32
+
+// ErrInvalidPattern is returned for custom patterns that do not compile.
+var ErrInvalidPattern = errors.New("dlp: invalid pattern")
+
+// Built-in detectors are checked in this order, so a JWT or key is reported
+// as such rather than as a high-entropy token.
+var builtins = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY(?: BLOCK)?-----` +
+		`(?:[\s\S]*?-----END [A-Z ]*PRIVATE KEY(?: BLOCK)?-----|[\s\S]*)`), DetectorPrivateKey},
+	{regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16}\b|` +
+		`(?i:aws_?secret_?access_?key)["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}`), DetectorAWSAccessKey},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`), DetectorJWT},
+	{regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`), DetectorEmail},
+	{regexp.MustCompile(`[A-Za-z0-9+/_=-]{` + strconv.Itoa(minEntropyLength) + `,}`), DetectorHighEntropy},
+}
+
+// Detectors returns the names of the built-in detectors.
+func Detectors() []string {
+	names := make([]string, 0, len(builtins))
+	for _, builtin := range builtins {
+		names = append(names, builtin.name)
+	}
+	return names
+}
+
+// Config defines what is detected and what happens to requests containing it.
+type Config struct {
+	// Action is "mask" (default), "block" or "restrict".
+	Action string `yaml:"action" toml:"action"`
+
+	// Detectors lists the built-in detectors to run.
+	Detectors []string `yaml:"detectors" toml:"detectors"`
+
+	// Patterns are custom regular expressions to detect.
+	Patterns []Pattern `yaml:"patterns" toml:"patterns"`
+
+	// MinEntropy is the entropy above which high_entropy reports a token. Default: 4.5.
+	MinEntropy float64 `yaml:"min_entropy" toml:"min_entropy"`
+}
+
+// Pattern is a custom regular expression to detect, in RE2 syntax.
+type Pattern struct {
+	// Name identifies the pattern in logs, errors and masks.
+	Name  string `yaml:"name" toml:"name"`
+	Regex string `yaml:"regex" toml:"regex"`
+}
+
+// IsEnabled returns true if any detector or pattern is configured.
+func (c *Config) IsEnabled() bool {
+	return len(c.Detectors) > 0 || len(c.Patterns) > 0
+}
+
+// GetAction returns the configured action or the default "mask".
+func (c *Config) GetAction() string {
+	if c.Action == "" {
+		return ActionMask
+	}
+	return c.Action
+}
+
+// GetMinEntropy returns the high_entropy threshold or the default 4.5.
+func (c *Config) GetMinEntropy() float64 {
+	if c.MinEntropy <= 0 {
+		return DefaultMinEntropy
+	}
+	return c.MinEntropy
+}
+
+// Finding is how often one detector or pattern matched in a request.
+type Finding struct {
+	// Detector is the built-in detector or custom pattern name.
+	Detector string
+	Count    int
+}
+
+// Scan returns what cfg detects in body, without changing it.
+func Scan(cfg *Config, body []byte) ([]Finding, error) {
+	_, findings, err := Mask(cfg, body)
+	return findings, err
+}
+
+// Mask replaces what cfg detects in body with "[REDACTED:<detector>]" and
+// returns the masked body and its findings. Body is returned unchanged if
+// nothing is found.
+func Mask(cfg *Config, body []byte) ([]byte, []Finding, error) {
+	matchers, err := compile(cfg)
+	if err != nil {
+		return body, nil, err
+	}
+
+	counts := make(map[string]int)
+	masked := body
+	for _, field := range textFields(body) {
+		text := field.text
+		for _, matcher := range matchers {
+			text = matcher.mask(text, counts)
+		}
+		if text == field.text {
+			continue
+		}
+		if masked, err = sjson.SetBytes(masked, field.path, text); err != nil {
+			return body, nil, fmt.Errorf("dlp: mask %s: %w", field.path, err)
+		}
+	}
+	return masked, findings(matchers, counts), nil
+}
+
+// matcher masks the matches of one detector or pattern.
+type matcher struct {
+	pattern    *regexp.Regexp
+	name       string
+	minEntropy float64
+}
+
+func (m *matcher) mask(text string, counts map[string]int) string {
+	return m.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if m.minEntropy > 0 && !isHighEntropy(match, m.minEntropy) {
+			return match
+		}
+		counts[m.name]++
+		return "[REDACTED:" + m.name + "]"
+	})
+}
+
+// compile returns the matchers of cfg, built-in detectors first.
+func compile(cfg *Config) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(cfg.Detectors)+len(cfg.Patterns))
+	for _, builtin := range builtins {
+		if !slices.Contains(cfg.Detectors, builtin.name) {
+			continue
+		}
+		minEntropy := 0.0
+		if builtin.name == DetectorHighEntropy {
+			minEntropy = cfg.GetMinEntropy()
+		}
+		matchers = append(matchers, matcher{pattern: builtin.pattern, name: builtin.name, minEntropy: minEntropy})
+	}
+	for _, pattern := range cfg.Patterns {
+		compiled, err := CompilePattern(pattern.Regex)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher{pattern: compiled, name: pattern.Name, minEntropy: 0})
+	}
+	return matchers, nil
+}
+
+// patterns caches compiled custom patterns by source, since the config is
+// scanned with on every request.
+var patterns sync.Map
+
+// CompilePattern compiles a custom pattern, caching the result.
+func CompilePattern(source string) (*regexp.Regexp, error) {
+	if cached, ok := patterns.Load(source); ok {
+		if compiled, isRegexp := cached.(*regexp.Regexp); isRegexp {
+			return compiled, nil
+		}
+	}
+	compiled, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+	patterns.Store(source, compiled)
+	return compiled, nil
+}
+
+// findings returns the counts in matcher order.
+func findings(matchers []matcher, counts map[string]int) []Finding {
+	var result []Finding
+	for _, m := range matchers {
+		if count := counts[m.name]; count > 0 {
+			result = append(result, Finding{Detector: m.name, Count: count})
+			delete(counts, m.name)
+		}
+	}
+	return result
+}
+
+// isHighEntropy reports whether token mixes upper case letters, lower case
+// letters and digits, and its Shannon entropy is at least minEntropy. Hashes,
+// paths and identifiers rarely pass both.
+func isHighEntropy(token string, minEntropy float64) bool {
+	var upper, lower, digit bool
+	freq := make(map[rune]int)
+	for _, r := range token {
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+		digit = digit || unicode.IsDigit(r)
+		freq[r]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	entropy := 0.0
+	for _, n := range freq {
+		p := float64(n) / float64(len(token))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// textField is a string in the request body that is scanned.
+type textField struct {
+	path string
+	text string
+}
+
+// textFields returns the text of the system prompt and of every message,
+// text block and tool result.
+func textFields(body []byte) []textField {
+	fields := appendContent(nil, "system", gjson.GetBytes(body, "system"))
+	gjson.GetBytes(body, "messages").ForEach(func(msgIdx, message gjson.Result) bool {
+		fields = appendContent(fields, "messages."+msgIdx.String()+".content", message.Get("content"))
+		return true
+	})
+	return fields
+}
+
+// appendContent appends the text of content, which is a string or an array
+// of blocks, found at path.
+func appendContent(fields []textField, path string, content gjson.Result) []textField {
+	if content.Type == gjson.String {
+		return append(fields, textField{path: path, text: content.String()})
+	}
+	content.ForEach(func(blockIdx, block gjson.Result) bool {
+		blockPath := path + "." + blockIdx.String()
+		switch block.Get("type").String() {
+		case "text":
+			fields = append(fields, textField{path: blockPath + ".text", text: block.Get("text").String()})
+		case "tool_result":
+			fields = appendContent(fields, blockPath+".content", block.Get("content"))
+		}
+		return true
+	})
+	return fields
+}
+
+// Describe lists findings as "name (count)", for logs and error messages.
+func Describe(findings []Finding) string {
+	parts := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		parts = append(parts, fmt.Sprintf("%s (%d)", finding.Detector, finding.Count))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,126 @@
+// Package gemini translates between the Anthropic Messages API and the Google
+// Gemini generateContent API.
+//
+// Messages requests are converted into generateContent requests, and Gemini
+// responses, both JSON and SSE, are converted back into Messages responses and
+// events. Gemini thought signatures are carried in the signature field of
+// thinking blocks, so they survive the round trip through Anthropic clients.
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidRequest is returned when a Messages body cannot be translated.
+var ErrInvalidRequest = errors.New("gemini: invalid messages request")
+
+// ErrInvalidResponse is returned when a Gemini response body is not valid JSON.
+var ErrInvalidResponse = errors.New("gemini: invalid generateContent response")
+
+// SkipThoughtSignature is the thought signature Gemini accepts in place of a
+// real one. It is sent on function calls that have no signature, such as tool
+// calls made by another model earlier in the conversation.
+const SkipThoughtSignature = "skip_thought_signature_validator"
+
+// Gemini content roles.
+const (
+	roleUser  = "user"
+	roleModel = "model"
+)
+
+// Anthropic content block types.
+const (
+	blockTypeText             = "text"
+	blockTypeImage            = "image"
+	blockTypeDocument         = "document"
+	blockTypeToolUse          = "tool_use"
+	blockTypeToolResult       = "tool_result"
+	blockTypeThinking         = "thinking"
+	blockTypeRedactedThinking = "redacted_thinking"
+)
+
+// Anthropic stop reasons.
+const (
+	stopReasonEndTurn   = "end_turn"
+	stopReasonMaxTokens = "max_tokens"
+	stopReasonToolUse   = "tool_use"
+	stopReasonRefusal   = "refusal"
+)
+
+// generateContentRequest is the body of a generateContent request.
+type generateContentRequest struct {
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	ToolConfig        *toolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+	Contents          []content         `json:"contents"`
+	Tools             []tool            `json:"tools,omitempty"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+// part is a Gemini content part. Exactly one of the data fields is set.
+type part struct {
+	InlineData       *blob             `json:"inlineData,omitempty"`
+	FileData         *fileData         `json:"fileData,omitempty"`
+	FunctionCall     *functionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *functionResponse `json:"functionResponse,omitempty"`
+	Text             string            `json:"text,omitempty"`
+	ThoughtSignature string            `json:"thoughtSignature,omitempty"`
+}
+
+type blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type fileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type functionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+type functionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type tool struct {
+	FunctionDeclarations []functionDeclaration `json:"functionDeclarations"`
+}
+
+type functionDeclaration struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description,omitempty"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema,omitempty"`
+}
+
+type toolConfig struct {
+	FunctionCallingConfig functionCallingConfig `json:"functionCallingConfig"`
+}
+
+type functionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type generationConfig struct {
+	Temperature     *float64        `json:"temperature,omitempty"`
+	TopP            *float64        `json:"topP,omitempty"`
+	TopK            *int64          `json:"topK,omitempty"`
+	ThinkingConfig  *thinkingConfig `json:"thinkingConfig,omitempty"`
+	StopSequences   []string        `json:"stopSequences,omitempty"`
+	MaxOutputTokens int64           `json:"maxOutputTokens,omitempty"`
+}
+
+type thinkingConfig struct {
+	ThinkingBudget  *int64 `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool   `json:"includeThoughts"`
+}
@@ -0,0 +1,319 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// dynamicThinkingBudget lets Gemini decide how much to think.
+const dynamicThinkingBudget = -1
+
+// FromAnthropicRequest converts a Messages request body into a generateContent
+// request body. The model and stream fields are not part of the result: Gemini
+// takes both from the request URL.
+//
+// Thinking text is never sent back, since Gemini only needs the signature to
+// restore its reasoning state. Each signature is attached to the part that
+// follows its thinking block, which is where Gemini returned it.
+func FromAnthropicRequest(body []byte) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("%w: body is not valid JSON", ErrInvalidRequest)
+	}
+	parsed := gjson.ParseBytes(body)
+
+	messages := parsed.Get("messages").Array()
+	toolNames := toolUseNames(messages)
+	contents := make([]content, 0, len(messages))
+	for _, msg := range messages {
+		converted := contentFrom(msg, toolNames)
+		if len(converted.Parts) > 0 {
+			contents = append(contents, converted)
+		}
+	}
+
+	request := generateContentRequest{
+		SystemInstruction: systemInstruction(parsed.Get("system")),
+		ToolConfig:        nil,
+		GenerationConfig:  generationConfigFrom(parsed),
+		Contents:          contents,
+		Tools:             toolsFrom(parsed.Get("tools")),
+	}
+	if len(request.Tools) > 0 {
+		request.ToolConfig = toolConfigFrom(parsed.Get("tool_choice"))
+	}
+
+	return json.Marshal(request)
+}
+
+// systemInstruction converts a string or text-block system prompt.
+func systemInstruction(system gjson.Result) *content {
+	var parts []part
+	if !system.IsArray() {
+		if text := system.String(); text != "" {
+			parts = append(parts, textPart(text))
+		}
+	}
+	for _, block := range system.Array() {
+		if text := block.Get("text").String(); block.Get("type").String() == blockTypeText && text != "" {
+			parts = append(parts, textPart(text))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return &content{Role: "", Parts: parts}
+}
+
+// toolUseNames maps tool_use IDs to tool names. Gemini matches function
+// responses by name, while Messages tool results only carry the ID.
+func toolUseNames(messages []gjson.Result) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, block := range msg.Get("content").Array() {
+			if block.Get("type").String() == blockTypeToolUse {
+				names[block.Get("id").String()] = block.Get("name").String()
+			}
+		}
+	}
+	return names
+}
+
+// contentFrom converts one message into a Gemini content.
+func contentFrom(msg gjson.Result, toolNames map[string]string) content {
+	role := roleUser
+	if msg.Get("role").String() == "assistant" {
+		role = roleModel
+	}
+
+	blocks := msg.Get("content")
+	if !blocks.IsArray() {
+		if text := blocks.String(); text != "" {
+			return content{Role: role, Parts: []part{textPart(text)}}
+		}
+		return content{Role: role, Parts: nil}
+	}
+
+	var parts []part
+	pendingSignature := ""
+	for _, block := range blocks.Array() {
+		blockType := block.Get("type").String()
+		if blockType == blockTypeThinking || blockType == blockTypeRedactedThinking {
+			if signature := block.Get("signature").String(); signature != "" {
+				pendingSignature = signature
+			}
+			continue
+		}
+		converted, ok := partFrom(block, toolNames)
+		if !ok {
+			continue
+		}
+		converted.ThoughtSignature, pendingSignature = pendingSignature, ""
+		parts = append(parts, converted)
+	}
+	if pendingSignature != "" && len(parts) > 0 {
+		parts[len(parts)-1].ThoughtSignature = pendingSignature
+	}
+	if role == roleModel {
+		signFunctionCalls(parts)
+	}
+	return content{Role: role, Parts: parts}
+}
+
+// signFunctionCalls gives the first function call of a model turn the skip
+// signature when it has none. Gemini rejects unsigned function calls from
+// thinking models, and turns produced by other providers never carry one.
+func signFunctionCalls(parts []part) {
+	for i := range parts {
+		if parts[i].FunctionCall == nil {
+			continue
+		}
+		if parts[i].ThoughtSignature == "" {
+			parts[i].ThoughtSignature = SkipThoughtSignature
+		}
+		return
+	}
+}
+
+// partFrom converts a content block other than thinking. It reports false for
+// blocks that have no Gemini equivalent.
+func partFrom(block gjson.Result, toolNames map[string]string) (part, bool) {
+	converted := emptyPart()
+	switch block.Get("type").String() {
+	case blockTypeText:
+		converted.Text = block.Get("text").String()
+		return converted, converted.Text != ""
+	case blockTypeImage, blockTypeDocument:
+		return mediaPart(block.Get("source"))
+	case blockTypeToolUse:
+		converted.FunctionCall = &functionCall{
+			Name: block.Get("name").String(),
+			Args: objectOrEmpty(block.Get("input")),
+		}
+		return converted, true
+	case blockTypeToolResult:
+		id := block.Get("tool_use_id").String()
+		name, ok := toolNames[id]
+		if !ok {
+			name = id
+		}
+		converted.FunctionResponse = &functionResponse{Name: name, Response: toolResponse(block)}
+		return converted, true
+	default:
+		return converted, false
+	}
+}
+
+// mediaPart converts an image or document source.
+func mediaPart(source gjson.Result) (part, bool) {
+	converted := emptyPart()
+	switch source.Get("type").String() {
+	case "base64":
+		converted.InlineData = &blob{
+			MimeType: source.Get("media_type").String(),
+			Data:     source.Get("data").String(),
+		}
+		return converted, true
+	case "url":
+		converted.FileData = &fileData{MimeType: "", FileURI: source.Get("url").String()}
+		return converted, true
+	default:
+		return converted, false
+	}
+}
+
+// toolResponse wraps tool_result content in the object Gemini expects.
+// Failed tool calls are reported under "error" rather than "content".
+func toolResponse(block gjson.Result) json.RawMessage {
+	key := "content"
+	if block.Get("is_error").Bool() {
+		key = "error"
+	}
+	// Marshaling a map of strings cannot fail.
+	out, _ := json.Marshal(map[string]string{key: resultText(block.Get("content"))})
+	return out
+}
+
+// resultText flattens string or text-block tool_result content.
+func resultText(resultContent gjson.Result) string {
+	if !resultContent.IsArray() {
+		return resultContent.String()
+	}
+	var texts []string
+	for _, block := range resultContent.Array() {
+		if block.Get("type").String() == blockTypeText {
+			texts = append(texts, block.Get("text").String())
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// toolsFrom converts client tools into function declarations. Server tools
+// have no input_schema and are dropped.
+func toolsFrom(tools gjson.Result) []tool {
+	var declarations []functionDeclaration
+	for _, definition := range tools.Array() {
+		schema := definition.Get("input_schema")
+		if !schema.IsObject() {
+			continue
+		}
+		declarations = append(declarations, functionDeclaration{
+			ParametersJSONSchema: json.RawMessage(schema.Raw),
+			Name:                 definition.Get("name").String(),
+			Description:          definition.Get("description").String(),
+		})
+	}
+	if len(declarations) == 0 {
+		return nil
+	}
+	return []tool{{FunctionDeclarations: declarations}}
+}
+
+// toolConfigFrom maps Messages tool_choice onto a function calling mode.
+func toolConfigFrom(choice gjson.Result) *toolConfig {
+	config := functionCallingConfig{Mode: "", AllowedFunctionNames: nil}
+	switch choice.Get("type").String() {
+	case "auto":
+		config.Mode = "AUTO"
+	case "any":
+		config.Mode = "ANY"
+	case "tool":
+		config.Mode = "ANY"
+		config.AllowedFunctionNames = []string{choice.Get("name").String()}
+	case "none":
+		config.Mode = "NONE"
+	default:
+		return nil
+	}
+	return &toolConfig{FunctionCallingConfig: config}
+}
+
+// generationConfigFrom collects sampling parameters and the thinking config.
+func generationConfigFrom(parsed gjson.Result) *generationConfig {
+	config := generationConfig{
+		Temperature:     optionalFloat(parsed.Get("temperature")),
+		TopP:            optionalFloat(parsed.Get("top_p")),
+		TopK:            nil,
+		ThinkingConfig:  thinkingConfigFrom(parsed.Get("thinking")),
+		StopSequences:   nil,
+		MaxOutputTokens: parsed.Get("max_tokens").Int(),
+	}
+	if topK := parsed.Get("top_k"); topK.Exists() {
+		value := topK.Int()
+		config.TopK = &value
+	}
+	for _, sequence := range parsed.Get("stop_sequences").Array() {
+		config.StopSequences = append(config.StopSequences, sequence.String())
+	}
+	return &config
+}
+
+// thinkingConfigFrom maps the Messages thinking config. Adaptive thinking has
+// no fixed budget, so Gemini is asked to pick one.
+func thinkingConfigFrom(thinking gjson.Result) *thinkingConfig {
+	var budget int64
+	switch thinking.Get("type").String() {
+	case "enabled":
+		budget = thinking.Get("budget_tokens").Int()
+	case "adaptive":
+		budget = dynamicThinkingBudget
+	default:
+		return nil
+	}
+	return &thinkingConfig{ThinkingBudget: &budget, IncludeThoughts: true}
+}
+
+func optionalFloat(value gjson.Result) *float64 {
+	if !value.Exists() {
+		return nil
+	}
+	f := value.Float()
+	return &f
+}
+
+// objectOrEmpty returns value when it is a JSON object and {} otherwise.
+func objectOrEmpty(value gjson.Result) json.RawMessage {
+	if !value.IsObject() {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(value.Raw)
+}
+
+func textPart(text string) part {
+	converted := emptyPart()
+	converted.Text = text
+	return converted
+}
+
+func emptyPart() part {
+	return part{
+		InlineData:       nil,
+		FileData:         nil,
+		FunctionCall:     nil,
+		FunctionResponse: nil,
+		Text:             "",
+		ThoughtSignature: "",
+	}
+}
@@ -0,0 +1,145 @@
+package gemini_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/gemini"
+)
+
+func convertRequest(t *testing.T, body string) gjson.Result {
+	t.Helper()
+
+	out, err := gemini.FromAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+	return gjson.ParseBytes(out)
+}
+
+func TestFromAnthropicRequestBasic(t *testing.T) {
+	t.Parallel()
+
+	out := convertRequest(t, `{
+		"model": "gemini-2.5-pro",
+		"max_tokens": 1024,
+		"stream": true,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"temperature": 0.5,
+		"top_k": 40,
+		"stop_sequences": ["END"],
+		"messages": [
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": [{"type": "text", "text": "Hello"}]}
+		]
+	}`)
+
+	assert.False(t, out.Get("model").Exists())
+	assert.False(t, out.Get("stream").Exists())
+	assert.JSONEq(t, `{"parts":[{"text":"Be brief."}]}`, out.Get("systemInstruction").Raw)
+	assert.JSONEq(t, `[
+		{"role":"user","parts":[{"text":"Hi"}]},
+		{"role":"model","parts":[{"text":"Hello"}]}
+	]`, out.Get("contents").Raw)
+	assert.JSONEq(t, `{"temperature":0.5,"topK":40,"stopSequences":["END"],"maxOutputTokens":1024}`,
+		out.Get("generationConfig").Raw)
+}
+
+func TestFromAnthropicRequestTools(t *testing.T) {
+	t.Parallel()
+
+	out := convertRequest(t, `{
+		"model": "gemini-2.5-flash",
+		"max_tokens": 100,
+		"tools": [
+			{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}},
+			{"type": "web_search_20250305", "name": "web_search"}
+		],
+		"tool_choice": {"type": "tool", "name": "get_weather"},
+		"messages": [
+			{"role": "user", "content": "Weather?"},
+			{"role": "assistant", "content": [
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "Sunny"}]},
+				{"type": "tool_result", "tool_use_id": "toolu_2", "content": "boom", "is_error": true}
+			]}
+		]
+	}`)
+
+	assert.JSONEq(t, `[{"functionDeclarations":[
+		{"name":"get_weather","description":"Weather","parametersJsonSchema":{"type":"object"}}
+	]}]`, out.Get("tools").Raw)
+	assert.JSONEq(t, `{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["get_weather"]}}`,
+		out.Get("toolConfig").Raw)
+	assert.JSONEq(t, `{"functionCall":{"name":"get_weather","args":{"city":"Paris"}},`+
+		`"thoughtSignature":"skip_thought_signature_validator"}`, out.Get("contents.1.parts.0").Raw)
+	assert.JSONEq(t, `[
+		{"functionResponse":{"name":"get_weather","response":{"content":"Sunny"}}},
+		{"functionResponse":{"name":"toolu_2","response":{"error":"boom"}}}
+	]`, out.Get("contents.2.parts").Raw)
+}
+
+func TestFromAnthropicRequestThinkingSignatures(t *testing.T) {
+	t.Parallel()
+
+	out := convertRequest(t, `{
+		"model": "gemini-2.5-pro",
+		"max_tokens": 100,
+		"thinking": {"type": "enabled", "budget_tokens": 2048},
+		"messages": [
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Let me think", "signature": "sig-call"},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {}},
+				{"type": "tool_use", "id": "toolu_2", "name": "lookup", "input": {}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Done"},
+				{"type": "thinking", "thinking": "", "signature": "sig-trailing"}
+			]}
+		]
+	}`)
+
+	assert.JSONEq(t, `{"thinkingBudget":2048,"includeThoughts":true}`,
+		out.Get("generationConfig.thinkingConfig").Raw)
+	assert.Equal(t, "sig-call", out.Get("contents.1.parts.0.thoughtSignature").String())
+	assert.False(t, out.Get("contents.1.parts.1.thoughtSignature").Exists(),
+		"only the first function call of a turn carries a signature")
+	assert.Len(t, out.Get("contents.2.parts").Array(), 1)
+	assert.Equal(t, "sig-trailing", out.Get("contents.2.parts.0.thoughtSignature").String())
+}
+
+func TestFromAnthropicRequestAdaptiveThinking(t *testing.T) {
+	t.Parallel()
+
+	out := convertRequest(t, `{"model":"m","max_tokens":10,"thinking":{"type":"adaptive"},`+
+		`"messages":[{"role":"user","content":"Hi"}]}`)
+
+	assert.Equal(t, int64(-1), out.Get("generationConfig.thinkingConfig.thinkingBudget").Int())
+}
+
+func TestFromAnthropicRequestMedia(t *testing.T) {
+	t.Parallel()
+
+	out := convertRequest(t, `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBOR"}},
+		{"type":"document","source":{"type":"url","url":"https://example.com/a.pdf"}},
+		{"type":"text","text":"Describe"}
+	]}]}`)
+
+	assert.JSONEq(t, `[
+		{"inlineData":{"mimeType":"image/png","data":"iVBOR"}},
+		{"fileData":{"fileUri":"https://example.com/a.pdf"}},
+		{"text":"Describe"}
+	]`, out.Get("contents.0.parts").Raw)
+}
+
+func TestFromAnthropicRequestInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := gemini.FromAnthropicRequest([]byte(`{`))
+	require.ErrorIs(t, err, gemini.ErrInvalidRequest)
+}
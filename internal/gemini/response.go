@@ -0,0 +1,224 @@
+package gemini
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	messageIDPrefix = "msg_"
+	toolUseIDPrefix = "toolu_"
+)
+
+// messagesResponse is a non-streaming Messages response.
+type messagesResponse struct {
+	StopReason   *string         `json:"stop_reason"`
+	StopSequence *string         `json:"stop_sequence"`
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Role         string          `json:"role"`
+	Model        string          `json:"model"`
+	Content      []responseBlock `json:"content"`
+	Usage        messagesUsage   `json:"usage"`
+}
+
+// responseBlock is a content block in a Messages response. Fields are tagged
+// so that each block type only carries its own members.
+type responseBlock struct {
+	Signature *string         `json:"signature,omitempty"`
+	Thinking  *string         `json:"thinking,omitempty"`
+	Text      *string         `json:"text,omitempty"`
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type messagesUsage struct {
+	InputTokens          int64 `json:"input_tokens"`
+	CacheReadInputTokens int64 `json:"cache_read_input_tokens"`
+	OutputTokens         int64 `json:"output_tokens"`
+}
+
+// ToAnthropicResponse converts a generateContent response body into a Messages
+// response body. Only the first candidate is used.
+//
+// Thought parts become thinking blocks. A signature Gemini attached to any
+// other part is kept in an empty thinking block placed in front of it, so the
+// client sends it back in the position FromAnthropicRequest expects.
+func ToAnthropicResponse(body []byte) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, ErrInvalidResponse
+	}
+	parsed := gjson.ParseBytes(body)
+	candidate := parsed.Get("candidates.0")
+
+	content := []responseBlock{}
+	hasToolUse := false
+	for _, candidatePart := range candidate.Get("content.parts").Array() {
+		blocks := blocksFromPart(candidatePart)
+		for i := range blocks {
+			hasToolUse = hasToolUse || blocks[i].Type == blockTypeToolUse
+		}
+		content = append(content, blocks...)
+	}
+
+	stopReason := StopReason(candidate.Get("finishReason").String(), hasToolUse)
+	return json.Marshal(messagesResponse{
+		StopReason:   &stopReason,
+		StopSequence: nil,
+		ID:           messageIDPrefix + parsed.Get("responseId").String(),
+		Type:         "message",
+		Role:         "assistant",
+		Model:        parsed.Get("modelVersion").String(),
+		Content:      content,
+		Usage:        messagesUsageFrom(parsed.Get("usageMetadata")),
+	})
+}
+
+// blocksFromPart converts one response part into Messages content blocks.
+func blocksFromPart(candidatePart gjson.Result) []responseBlock {
+	signature := candidatePart.Get("thoughtSignature").String()
+	if candidatePart.Get("thought").Bool() {
+		return []responseBlock{thinkingBlock(candidatePart.Get("text").String(), signature)}
+	}
+
+	var blocks []responseBlock
+	if signature != "" {
+		blocks = append(blocks, thinkingBlock("", signature))
+	}
+	if call := candidatePart.Get("functionCall"); call.Exists() {
+		return append(blocks, toolUseBlock(call))
+	}
+	if text := candidatePart.Get("text").String(); text != "" {
+		return append(blocks, textBlock(text))
+	}
+	return blocks
+}
+
+// ToAnthropicError converts a Gemini error body into a Messages error body.
+func ToAnthropicError(statusCode int, body []byte) []byte {
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	// Marshaling a struct of strings cannot fail.
+	out, _ := json.Marshal(anthropicError{
+		Type: "error",
+		Error: anthropicErrorDetail{
+			Type:    ErrorType(gjson.GetBytes(body, "error.status").String()),
+			Message: message,
+		},
+	})
+	return out
+}
+
+type anthropicError struct {
+	Type  string               `json:"type"`
+	Error anthropicErrorDetail `json:"error"`
+}
+
+type anthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ErrorType maps a Google RPC status onto the Messages API error type.
+func ErrorType(status string) string {
+	switch status {
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		return "invalid_request_error"
+	case "UNAUTHENTICATED":
+		return "authentication_error"
+	case "PERMISSION_DENIED":
+		return "permission_error"
+	case "NOT_FOUND":
+		return "not_found_error"
+	case "RESOURCE_EXHAUSTED":
+		return "rate_limit_error"
+	case "UNAVAILABLE":
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// StopReason maps a Gemini finishReason onto a Messages stop_reason. Gemini
+// finishes tool calls with STOP, so hasToolUse selects tool_use instead.
+func StopReason(finishReason string, hasToolUse bool) string {
+	switch finishReason {
+	case "MAX_TOKENS":
+		return stopReasonMaxTokens
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return stopReasonRefusal
+	}
+	if hasToolUse {
+		return stopReasonToolUse
+	}
+	return stopReasonEndTurn
+}
+
+// messagesUsageFrom maps usageMetadata onto Messages usage. Gemini counts
+// cached tokens as part of the prompt and thinking tokens separately from
+// the candidates, while Messages does the opposite for both.
+func messagesUsageFrom(usage gjson.Result) messagesUsage {
+	cached := usage.Get("cachedContentTokenCount").Int()
+	return messagesUsage{
+		InputTokens:          max(usage.Get("promptTokenCount").Int()-cached, 0),
+		CacheReadInputTokens: cached,
+		OutputTokens:         usage.Get("candidatesTokenCount").Int() + usage.Get("thoughtsTokenCount").Int(),
+	}
+}
+
+// toolUseID returns the function call ID, or a generated one. Gemini only
+// sets IDs on some models, and Messages requires them to pair tool results.
+func toolUseID(call gjson.Result) string {
+	if id := call.Get("id").String(); id != "" {
+		return id
+	}
+	return toolUseIDPrefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+func textBlock(text string) responseBlock {
+	return responseBlock{
+		Input:     nil,
+		Signature: nil,
+		Thinking:  nil,
+		Text:      &text,
+		Type:      blockTypeText,
+		ID:        "",
+		Name:      "",
+	}
+}
+
+func thinkingBlock(thinking, signature string) responseBlock {
+	return responseBlock{
+		Input:     nil,
+		Signature: &signature,
+		Thinking:  &thinking,
+		Text:      nil,
+		Type:      blockTypeThinking,
+		ID:        "",
+		Name:      "",
+	}
+}
+
+func toolUseBlock(call gjson.Result) responseBlock {
+	return responseBlock{
+		Input:     objectOrEmpty(call.Get("args")),
+		Signature: nil,
+		Thinking:  nil,
+		Text:      nil,
+		Type:      blockTypeToolUse,
+		ID:        toolUseID(call),
+		Name:      call.Get("name").String(),
+	}
+}
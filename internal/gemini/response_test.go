@@ -0,0 +1,101 @@
+package gemini_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/gemini"
+)
+
+func TestToAnthropicResponseText(t *testing.T) {
+	t.Parallel()
+
+	out, err := gemini.ToAnthropicResponse([]byte(`{
+		"responseId": "abc",
+		"modelVersion": "gemini-2.5-pro",
+		"candidates": [{"content": {"role": "model", "parts": [
+			{"text": "Pondering", "thought": true},
+			{"text": "Hello", "thoughtSignature": "sig-1"}
+		]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 20, "cachedContentTokenCount": 5,
+			"candidatesTokenCount": 3, "thoughtsTokenCount": 7}
+	}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"id": "msg_abc",
+		"type": "message",
+		"role": "assistant",
+		"model": "gemini-2.5-pro",
+		"content": [
+			{"type": "thinking", "thinking": "Pondering", "signature": ""},
+			{"type": "thinking", "thinking": "", "signature": "sig-1"},
+			{"type": "text", "text": "Hello"}
+		],
+		"stop_reason": "end_turn",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 15, "cache_read_input_tokens": 5, "output_tokens": 10}
+	}`, string(out))
+}
+
+func TestToAnthropicResponseFunctionCall(t *testing.T) {
+	t.Parallel()
+
+	out, err := gemini.ToAnthropicResponse([]byte(`{"candidates":[{"content":{"parts":[
+		{"functionCall":{"name":"lookup","args":{"q":"x"}}},
+		{"functionCall":{"id":"call-2","name":"lookup","args":null}}
+	]},"finishReason":"STOP"}]}`))
+	require.NoError(t, err)
+
+	parsed := gjson.ParseBytes(out)
+	assert.Equal(t, "tool_use", parsed.Get("stop_reason").String())
+	assert.True(t, strings.HasPrefix(parsed.Get("content.0.id").String(), "toolu_"))
+	assert.JSONEq(t, `{"q":"x"}`, parsed.Get("content.0.input").Raw)
+	assert.Equal(t, "call-2", parsed.Get("content.1.id").String())
+	assert.JSONEq(t, `{}`, parsed.Get("content.1.input").Raw)
+}
+
+func TestToAnthropicResponseInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := gemini.ToAnthropicResponse([]byte(`not json`))
+	require.ErrorIs(t, err, gemini.ErrInvalidResponse)
+}
+
+func TestToAnthropicError(t *testing.T) {
+	t.Parallel()
+
+	out := gemini.ToAnthropicError(429, []byte(`{"error":{"code":429,"message":"Quota exceeded",`+
+		`"status":"RESOURCE_EXHAUSTED"}}`))
+	assert.JSONEq(t, `{"type":"error","error":{"type":"rate_limit_error","message":"Quota exceeded"}}`, string(out))
+
+	out = gemini.ToAnthropicError(502, nil)
+	assert.JSONEq(t, `{"type":"error","error":{"type":"api_error","message":"Bad Gateway"}}`, string(out))
+}
+
+func TestStopReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		finishReason string
+		want         string
+		hasToolUse   bool
+	}{
+		{name: "stop", finishReason: "STOP", want: "end_turn", hasToolUse: false},
+		{name: "stop with tools", finishReason: "STOP", want: "tool_use", hasToolUse: true},
+		{name: "max tokens", finishReason: "MAX_TOKENS", want: "max_tokens", hasToolUse: true},
+		{name: "safety", finishReason: "SAFETY", want: "refusal", hasToolUse: false},
+		{name: "unknown", finishReason: "", want: "end_turn", hasToolUse: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, gemini.StopReason(tt.finishReason, tt.hasToolUse))
+		})
+	}
+}
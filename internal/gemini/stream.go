@@ -0,0 +1,244 @@
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	eventMessageStart      = "message_start"
+	eventMessageDelta      = "message_delta"
+	eventMessageStop       = "message_stop"
+	eventContentBlockStart = "content_block_start"
+	eventContentBlockDelta = "content_block_delta"
+	eventContentBlockStop  = "content_block_stop"
+	eventPing              = "ping"
+	eventError             = "error"
+	sseDataPrefix          = "data:"
+)
+
+// MessagesStream converts a streamGenerateContent SSE stream (alt=sse) into a
+// Messages SSE stream. Events are produced as the upstream body is read.
+//
+// Gemini sends each function call whole, so every tool_use block is emitted
+// with a single input_json_delta. Usage metadata is repeated on every chunk;
+// the last one seen is reported with message_delta.
+type MessagesStream struct {
+	src          io.ReadCloser
+	reader       *bufio.Reader
+	finishReason string
+	openBlock    string
+	usage        gjson.Result
+	out          bytes.Buffer
+	blockIndex   int64
+	started      bool
+	finished     bool
+	sawToolCall  bool
+}
+
+// NewMessagesStream wraps a streamGenerateContent SSE body.
+func NewMessagesStream(src io.ReadCloser) *MessagesStream {
+	return &MessagesStream{
+		src:          src,
+		reader:       bufio.NewReader(src),
+		usage:        gjson.Result{},
+		out:          bytes.Buffer{},
+		finishReason: "",
+		openBlock:    "",
+		blockIndex:   -1,
+		started:      false,
+		finished:     false,
+		sawToolCall:  false,
+	}
+}
+
+// Read implements io.Reader.
+func (s *MessagesStream) Read(buf []byte) (int, error) {
+	for s.out.Len() == 0 && !s.finished {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.processLine(line)
+		}
+		if errors.Is(err, io.EOF) {
+			s.end()
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if s.out.Len() == 0 {
+		return 0, io.EOF
+	}
+	return s.out.Read(buf)
+}
+
+// Close closes the upstream body.
+func (s *MessagesStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *MessagesStream) processLine(line []byte) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte(sseDataPrefix))
+	if !ok || s.finished {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if !gjson.ValidBytes(payload) {
+		return
+	}
+
+	chunk := gjson.ParseBytes(payload)
+	if chunk.Get("error").Exists() {
+		s.emit(eventError, ToAnthropicError(http.StatusInternalServerError, payload))
+		s.finished = true
+		return
+	}
+	s.processChunk(chunk)
+}
+
+func (s *MessagesStream) processChunk(chunk gjson.Result) {
+	if !s.started {
+		s.started = true
+		s.emitMessageStart(chunk)
+	}
+	if usage := chunk.Get("usageMetadata"); usage.IsObject() {
+		s.usage = usage
+	}
+
+	candidate := chunk.Get("candidates.0")
+	for _, candidatePart := range candidate.Get("content.parts").Array() {
+		s.processPart(candidatePart)
+	}
+	if reason := candidate.Get("finishReason").String(); reason != "" {
+		s.finishReason = reason
+	}
+}
+
+// processPart emits the events for one part. A signature on a non-thought part
+// belongs to the thinking that preceded it, so it closes the open thinking
+// block, or is sent in an empty one when the model did not stream thoughts.
+func (s *MessagesStream) processPart(candidatePart gjson.Result) {
+	text := candidatePart.Get("text").String()
+	signature := candidatePart.Get("thoughtSignature").String()
+
+	if candidatePart.Get("thought").Bool() {
+		s.ensureBlock(blockTypeThinking, `{"type":"thinking","thinking":"","signature":""}`)
+		if text != "" {
+			s.emitDelta(s.blockIndex, "thinking", "thinking_delta", text)
+		}
+		if signature != "" {
+			s.emitDelta(s.blockIndex, "signature", "signature_delta", signature)
+		}
+		return
+	}
+
+	if signature != "" {
+		s.ensureBlock(blockTypeThinking, `{"type":"thinking","thinking":"","signature":""}`)
+		s.emitDelta(s.blockIndex, "signature", "signature_delta", signature)
+		s.closeBlock()
+	}
+	if call := candidatePart.Get("functionCall"); call.Exists() {
+		s.emitToolUse(call)
+		return
+	}
+	if text != "" {
+		s.ensureBlock(blockTypeText, `{"type":"text","text":""}`)
+		s.emitDelta(s.blockIndex, "text", "text_delta", text)
+	}
+}
+
+// emitToolUse sends a complete tool_use block for a function call.
+func (s *MessagesStream) emitToolUse(call gjson.Result) {
+	s.closeBlock()
+	s.blockIndex++
+	s.sawToolCall = true
+
+	start := `{"type":"tool_use","id":"","name":"","input":{}}`
+	start, _ = sjson.Set(start, "id", toolUseID(call))
+	start, _ = sjson.Set(start, "name", call.Get("name").String())
+	s.emitBlockStart(s.blockIndex, start)
+	s.emitDelta(s.blockIndex, "partial_json", "input_json_delta", string(objectOrEmpty(call.Get("args"))))
+	s.openBlock = blockTypeToolUse
+	s.closeBlock()
+}
+
+// ensureBlock opens a block of blockType unless one is already open.
+func (s *MessagesStream) ensureBlock(blockType, contentBlock string) {
+	if s.openBlock == blockType {
+		return
+	}
+	s.closeBlock()
+	s.blockIndex++
+	s.openBlock = blockType
+	s.emitBlockStart(s.blockIndex, contentBlock)
+}
+
+func (s *MessagesStream) closeBlock() {
+	if s.openBlock == "" {
+		return
+	}
+	s.openBlock = ""
+	event, _ := sjson.SetBytes([]byte(`{"type":"content_block_stop"}`), "index", s.blockIndex)
+	s.emit(eventContentBlockStop, event)
+}
+
+// end finishes the converted stream. Streams that never produced a chunk are
+// left empty rather than turned into an empty message.
+func (s *MessagesStream) end() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+	if !s.started {
+		return
+	}
+	s.closeBlock()
+
+	usage := messagesUsageFrom(s.usage)
+	event := []byte(`{"type":"message_delta","delta":{"stop_sequence":null}}`)
+	event, _ = sjson.SetBytes(event, "delta.stop_reason", StopReason(s.finishReason, s.sawToolCall))
+	event, _ = sjson.SetBytes(event, "usage.input_tokens", usage.InputTokens)
+	event, _ = sjson.SetBytes(event, "usage.cache_read_input_tokens", usage.CacheReadInputTokens)
+	event, _ = sjson.SetBytes(event, "usage.output_tokens", usage.OutputTokens)
+	s.emit(eventMessageDelta, event)
+	s.emit(eventMessageStop, []byte(`{"type":"message_stop"}`))
+}
+
+func (s *MessagesStream) emitMessageStart(chunk gjson.Result) {
+	event := []byte(`{"type":"message_start","message":{"id":"","type":"message","role":"assistant","model":"",` +
+		`"content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}}`)
+	event, _ = sjson.SetBytes(event, "message.id", messageIDPrefix+chunk.Get("responseId").String())
+	event, _ = sjson.SetBytes(event, "message.model", chunk.Get("modelVersion").String())
+	usage := messagesUsageFrom(chunk.Get("usageMetadata"))
+	event, _ = sjson.SetBytes(event, "message.usage.input_tokens", usage.InputTokens)
+	s.emit(eventMessageStart, event)
+	s.emit(eventPing, []byte(`{"type":"ping"}`))
+}
+
+func (s *MessagesStream) emitBlockStart(index int64, contentBlock string) {
+	event := []byte(`{"type":"content_block_start"}`)
+	event, _ = sjson.SetBytes(event, "index", index)
+	event, _ = sjson.SetRawBytes(event, "content_block", []byte(contentBlock))
+	s.emit(eventContentBlockStart, event)
+}
+
+func (s *MessagesStream) emitDelta(index int64, field, deltaType, value string) {
+	event := []byte(`{"type":"content_block_delta"}`)
+	event, _ = sjson.SetBytes(event, "index", index)
+	event, _ = sjson.SetBytes(event, "delta.type", deltaType)
+	event, _ = sjson.SetBytes(event, "delta."+field, value)
+	s.emit(eventContentBlockDelta, event)
+}
+
+func (s *MessagesStream) emit(event string, data []byte) {
+	s.out.WriteString("event: ")
+	s.out.WriteString(event)
+	s.out.WriteString("\ndata: ")
+	s.out.Write(data)
+	s.out.WriteString("\n\n")
+}
@@ -0,0 +1,119 @@
+package gemini_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/gemini"
+)
+
+// streamEvent is one converted Messages SSE event.
+type streamEvent struct {
+	name string
+	data gjson.Result
+}
+
+// readMessagesStream converts a Gemini SSE stream and parses the resulting events.
+// Chunks are framed with CRLF line endings, as Gemini sends them.
+func readMessagesStream(t *testing.T, chunks ...string) []streamEvent {
+	t.Helper()
+
+	var upstream strings.Builder
+	for _, chunk := range chunks {
+		upstream.WriteString("data: " + chunk + "\r\n\r\n")
+	}
+	out, err := io.ReadAll(gemini.NewMessagesStream(io.NopCloser(strings.NewReader(upstream.String()))))
+	require.NoError(t, err)
+	if len(out) == 0 {
+		return nil
+	}
+
+	var events []streamEvent
+	for _, frame := range strings.Split(strings.TrimSpace(string(out)), "\n\n") {
+		name, data, ok := strings.Cut(frame, "\ndata: ")
+		require.True(t, ok, frame)
+		events = append(events, streamEvent{name: strings.TrimPrefix(name, "event: "), data: gjson.Parse(data)})
+	}
+	return events
+}
+
+func eventNames(events []streamEvent) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	return names
+}
+
+func TestMessagesStreamThinkingAndText(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"responseId":"r1","modelVersion":"gemini-2.5-pro","candidates":[{"content":{"parts":[`+
+			`{"text":"Hmm","thought":true}]}}],"usageMetadata":{"promptTokenCount":12}}`,
+		`{"responseId":"r1","candidates":[{"content":{"parts":[{"text":"Hel","thoughtSignature":"sig-1"}]}}]}`,
+		`{"responseId":"r1","candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"STOP"}],`+
+			`"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":2,"thoughtsTokenCount":5}}`,
+	)
+
+	assert.Equal(t, []string{
+		"message_start", "ping",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, eventNames(events))
+
+	assert.Equal(t, "msg_r1", events[0].data.Get("message.id").String())
+	assert.Equal(t, int64(12), events[0].data.Get("message.usage.input_tokens").Int())
+	assert.Equal(t, "Hmm", events[3].data.Get("delta.thinking").String())
+	assert.Equal(t, "sig-1", events[4].data.Get("delta.signature").String())
+	assert.Equal(t, "Hel", events[7].data.Get("delta.text").String())
+	assert.JSONEq(t, `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},`+
+		`"usage":{"input_tokens":12,"cache_read_input_tokens":0,"output_tokens":7}}`, events[10].data.Raw)
+}
+
+func TestMessagesStreamFunctionCall(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"responseId":"r2","candidates":[{"content":{"parts":[{"functionCall":{"id":"c1","name":"lookup",`+
+			`"args":{"q":"x"}},"thoughtSignature":"sig-2"}]},"finishReason":"STOP"}]}`,
+	)
+
+	assert.Equal(t, []string{
+		"message_start", "ping",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, eventNames(events))
+
+	assert.Equal(t, "thinking", events[2].data.Get("content_block.type").String())
+	assert.Equal(t, "sig-2", events[3].data.Get("delta.signature").String())
+	assert.JSONEq(t, `{"type":"tool_use","id":"c1","name":"lookup","input":{}}`,
+		events[5].data.Get("content_block").Raw)
+	assert.JSONEq(t, `{"q":"x"}`, events[6].data.Get("delta.partial_json").String())
+	assert.Equal(t, "tool_use", events[8].data.Get("delta.stop_reason").String())
+}
+
+func TestMessagesStreamError(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}`,
+	)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "error", events[0].name)
+	assert.Equal(t, "overloaded_error", events[0].data.Get("error.type").String())
+}
+
+func TestMessagesStreamEmpty(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, readMessagesStream(t))
+}
@@ -57,16 +57,3 @@ func (c *Checker) CheckAllProviders() {
 func (t *Tracker) HasCircuits() bool {
 	return t.circuits != nil
 }
-
-// AllStates returns a snapshot of all provider circuit states.
-// Useful for debugging and monitoring.
-func (t *Tracker) AllStates() map[string]State {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
-	states := make(map[string]State, len(t.circuits))
-	for name, cb := range t.circuits {
-		states[name] = cb.State()
-	}
-	return states
-}
@@ -9,25 +9,33 @@ import (
 // Tracker manages per-provider circuit breakers.
 // It provides thread-safe access to circuit breakers and exposes
 // IsHealthyFunc closures for integration with the router.
+//
+// Operators can also pin a circuit open or drain a provider. Both take the
+// provider out of routing until released, whatever its circuit reports.
 type Tracker struct {
-	circuits map[string]*CircuitBreaker
-	logger   *zerolog.Logger
-	config   CircuitBreakerConfig
-	mu       sync.RWMutex
+	circuits   map[string]*CircuitBreaker
+	forcedOpen map[string]struct{}
+	drained    map[string]struct{}
+	logger     *zerolog.Logger
+	config     CircuitBreakerConfig
+	mu         sync.RWMutex
 }
 
 // NewTracker creates a new Tracker with the given configuration.
 func NewTracker(cfg CircuitBreakerConfig, logger *zerolog.Logger) *Tracker {
 	return &Tracker{
-		circuits: make(map[string]*CircuitBreaker),
-		config:   cfg,
-		logger:   logger,
-		mu:       sync.RWMutex{},
+		circuits:   make(map[string]*CircuitBreaker),
+		forcedOpen: make(map[string]struct{}),
+		drained:    make(map[string]struct{}),
+		config:     cfg,
+		logger:     logger,
+		mu:         sync.RWMutex{},
 	}
 }
 
 // Reset replaces the tracker configuration and clears existing circuits.
 // This is used to apply hot-reload changes consistently across providers.
+// Operator overrides (forced open circuits, drained providers) are kept.
 func (t *Tracker) Reset(cfg CircuitBreakerConfig, logger *zerolog.Logger) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -77,9 +85,12 @@ func (t *Tracker) GetOrCreateCircuit(providerName string) *CircuitBreaker {
 //   - CLOSED: Normal operation, requests flow through
 //   - HALF-OPEN: Testing recovery, probe requests are allowed
 //
-// A provider is unhealthy only if the circuit is OPEN.
+// A provider is unhealthy if the circuit is OPEN or the provider is drained.
 func (t *Tracker) IsHealthyFunc(providerName string) func() bool {
 	return func() bool {
+		if t.IsDrained(providerName) || t.IsForcedOpen(providerName) {
+			return false
+		}
 		breaker := t.GetOrCreateCircuit(providerName)
 		// OPEN = unhealthy, CLOSED/HALF-OPEN = healthy
 		return breaker.State() != StateOpen
@@ -87,18 +98,107 @@ func (t *Tracker) IsHealthyFunc(providerName string) func() bool {
 }
 
 // GetState returns the current state of a provider's circuit breaker.
-// Returns StateClosed if no circuit exists for the provider (healthy by default).
+// Returns StateClosed if no circuit exists for the provider (healthy by default),
+// and StateOpen while the circuit is forced open.
 func (t *Tracker) GetState(providerName string) State {
 	t.mu.RLock()
 	breaker, exists := t.circuits[providerName]
+	_, forced := t.forcedOpen[providerName]
 	t.mu.RUnlock()
 
+	if forced {
+		return StateOpen
+	}
 	if !exists {
 		return StateClosed
 	}
 	return breaker.State()
 }
 
+// AllStates returns a snapshot of all provider circuit states.
+// Circuits are created lazily, so providers that have not been routed to or
+// reported on yet are absent unless they are forced open.
+func (t *Tracker) AllStates() map[string]State {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	states := make(map[string]State, len(t.circuits)+len(t.forcedOpen))
+	for name, breaker := range t.circuits {
+		states[name] = breaker.State()
+	}
+	for name := range t.forcedOpen {
+		states[name] = StateOpen
+	}
+	return states
+}
+
+// ForceOpen pins a provider's circuit open until ForceClose is called.
+// Failures and successes are still recorded on the underlying circuit.
+func (t *Tracker) ForceOpen(providerName string) {
+	t.mu.Lock()
+	t.forcedOpen[providerName] = struct{}{}
+	t.mu.Unlock()
+
+	t.logOverride(providerName, "circuit forced open")
+}
+
+// ForceClose releases a forced open circuit and resets the provider's circuit
+// to CLOSED with cleared failure counts.
+func (t *Tracker) ForceClose(providerName string) {
+	t.mu.Lock()
+	delete(t.forcedOpen, providerName)
+	t.circuits[providerName] = NewCircuitBreaker(providerName, t.config, t.logger)
+	t.mu.Unlock()
+
+	t.logOverride(providerName, "circuit forced closed")
+}
+
+// IsForcedOpen returns true if the provider's circuit is pinned open.
+func (t *Tracker) IsForcedOpen(providerName string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, forced := t.forcedOpen[providerName]
+	return forced
+}
+
+// SetDrained drains a provider, or returns it to routing.
+// A drained provider gets no new requests; requests already sent to it complete.
+func (t *Tracker) SetDrained(providerName string, drained bool) {
+	t.mu.Lock()
+	if drained {
+		t.drained[providerName] = struct{}{}
+	} else {
+		delete(t.drained, providerName)
+	}
+	t.mu.Unlock()
+
+	if drained {
+		t.logOverride(providerName, "provider drained")
+	} else {
+		t.logOverride(providerName, "provider undrained")
+	}
+}
+
+// IsDrained returns true if the provider is drained.
+func (t *Tracker) IsDrained(providerName string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, drained := t.drained[providerName]
+	return drained
+}
+
+func (t *Tracker) logOverride(providerName, msg string) {
+	t.mu.RLock()
+	logger := t.logger
+	t.mu.RUnlock()
+
+	if logger != nil {
+		logger.Info().Str("provider", providerName).Msg(msg)
+	}
+}
+
 // RecordSuccess records a successful operation for a provider.
 // When the circuit is OPEN, the success is not recorded (gobreaker limitation)
 // and a debug log is emitted to make this visible.
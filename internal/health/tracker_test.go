@@ -316,3 +316,88 @@ func TestTrackerResetClearsCircuitsAndAppliesNewConfig(t *testing.T) {
 		t.Errorf("expected StateOpen after 1 failure with new threshold=1, got %s", got.String())
 	}
 }
+
+func TestTrackerForceOpenAndClose(t *testing.T) {
+	t.Parallel()
+	logger := zerolog.Nop()
+	cfg := health.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenDurationMS:   30000,
+		HalfOpenProbes:   1,
+	}
+
+	tracker := health.NewTracker(cfg, &logger)
+	isHealthy := tracker.IsHealthyFunc("provider-a")
+
+	tracker.ForceOpen("provider-a")
+	if isHealthy() {
+		t.Error("expected forced open provider to be unhealthy")
+	}
+	if got := tracker.GetState("provider-a"); got != health.StateOpen {
+		t.Errorf("expected StateOpen while forced, got %s", got.String())
+	}
+	if got := tracker.AllStates()["provider-a"]; got != health.StateOpen {
+		t.Errorf("expected forced circuit in AllStates as open, got %s", got.String())
+	}
+
+	// Successes do not release a forced circuit.
+	tracker.RecordSuccess("provider-a")
+	if !tracker.IsForcedOpen("provider-a") || isHealthy() {
+		t.Error("expected circuit to stay forced open after a success")
+	}
+
+	// Forcing closed releases the pin and clears a naturally open circuit.
+	tracker.RecordFailure("provider-a", errors.New("boom"))
+	tracker.RecordFailure("provider-a", errors.New("boom"))
+	tracker.ForceClose("provider-a")
+	if tracker.IsForcedOpen("provider-a") {
+		t.Error("expected ForceClose to release the forced open circuit")
+	}
+	if got := tracker.GetState("provider-a"); got != health.StateClosed {
+		t.Errorf("expected StateClosed after ForceClose, got %s", got.String())
+	}
+	if !isHealthy() {
+		t.Error("expected provider to be healthy after ForceClose")
+	}
+}
+
+func TestTrackerDrain(t *testing.T) {
+	t.Parallel()
+	logger := zerolog.Nop()
+	cfg := health.CircuitBreakerConfig{OpenDurationMS: 0, FailureThreshold: 0, HalfOpenProbes: 0}
+
+	tracker := health.NewTracker(cfg, &logger)
+	isHealthy := tracker.IsHealthyFunc("provider-a")
+
+	tracker.SetDrained("provider-a", true)
+	if !tracker.IsDrained("provider-a") || isHealthy() {
+		t.Error("expected drained provider to be unhealthy")
+	}
+	if got := tracker.GetState("provider-a"); got != health.StateClosed {
+		t.Errorf("expected draining to leave the circuit closed, got %s", got.String())
+	}
+
+	tracker.SetDrained("provider-a", false)
+	if tracker.IsDrained("provider-a") || !isHealthy() {
+		t.Error("expected undrained provider to be healthy")
+	}
+}
+
+func TestTrackerResetKeepsOverrides(t *testing.T) {
+	t.Parallel()
+	logger := zerolog.Nop()
+	cfg := health.CircuitBreakerConfig{OpenDurationMS: 0, FailureThreshold: 0, HalfOpenProbes: 0}
+
+	tracker := health.NewTracker(cfg, &logger)
+	tracker.ForceOpen("provider-a")
+	tracker.SetDrained("provider-b", true)
+
+	tracker.Reset(cfg, &logger)
+
+	if !tracker.IsForcedOpen("provider-a") {
+		t.Error("expected forced open circuit to survive Reset")
+	}
+	if !tracker.IsDrained("provider-b") {
+		t.Error("expected drained provider to survive Reset")
+	}
+}
@@ -45,6 +45,9 @@ type KeyMetadata struct {
 	Weight        int
 	mu            sync.RWMutex
 	Healthy       bool
+	// Disabled is set by an operator and keeps the key out of selection
+	// regardless of its health or remaining capacity.
+	Disabled bool
 }
 
 // NewKeyMetadata creates a new KeyMetadata with the given API key and rate limits.
@@ -84,18 +87,19 @@ func NewKeyMetadata(apiKey string, rpm, itpm, otpm int) *KeyMetadata {
 		Weight:        1, // Default weight
 		mu:            sync.RWMutex{},
 		Healthy:       true,
+		Disabled:      false,
 	}
 }
 
 // GetCapacityScore returns a 0-1 score representing remaining capacity.
 // Higher score means more capacity available.
-// Returns 0 if unhealthy or in cooldown.
+// Returns 0 if unhealthy, disabled or in cooldown.
 func (k *KeyMetadata) GetCapacityScore() float64 {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
 	// Unavailable keys have 0 capacity
-	if !k.Healthy || time.Now().Before(k.CooldownUntil) {
+	if !k.available() {
 		return 0.0
 	}
 
@@ -121,12 +125,43 @@ func (k *KeyMetadata) GetCapacityScore() float64 {
 	return (rpmScore + tpmScore) / 2.0
 }
 
-// IsAvailable returns true if the key is healthy and not in cooldown.
+// IsAvailable returns true if the key is healthy, enabled and not in cooldown.
 func (k *KeyMetadata) IsAvailable() bool {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	return k.Healthy && time.Now().After(k.CooldownUntil)
+	return k.available()
+}
+
+// available reports whether the key can be selected. The caller must hold k.mu.
+func (k *KeyMetadata) available() bool {
+	return k.Healthy && !k.Disabled && time.Now().After(k.CooldownUntil)
+}
+
+// Stats returns a snapshot of the key's rate limit state.
+func (k *KeyMetadata) Stats() KeyStats {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	lastError := ""
+	if k.LastError != nil {
+		lastError = k.LastError.Error()
+	}
+	return KeyStats{
+		CooldownUntil: k.CooldownUntil,
+		LastErrorAt:   k.LastErrorAt,
+		ID:            k.ID,
+		LastError:     lastError,
+		RPMLimit:      k.RPMLimit,
+		RPMRemaining:  k.RPMRemaining,
+		ITPMLimit:     k.ITPMLimit,
+		ITPMRemaining: k.ITPMRemaining,
+		OTPMLimit:     k.OTPMLimit,
+		OTPMRemaining: k.OTPMRemaining,
+		Available:     k.available(),
+		Healthy:       k.Healthy,
+		Disabled:      k.Disabled,
+	}
 }
 
 // UpdateFromHeaders parses Anthropic rate limit headers and updates the key's state.
@@ -201,6 +236,35 @@ func (k *KeyMetadata) parseLimits(
 	}
 }
 
+// tokenWindow is the rate limit window for ITPM/OTPM counters that are not
+// refreshed by provider headers.
+const tokenWindow = time.Minute
+
+// RecordUsage deducts the tokens reported in a response body from the
+// remaining ITPM/OTPM counters. Counters with no known limit are left alone.
+// If the counter's reset time has passed (or was never learned from headers),
+// a new one-minute window starts at full capacity before deducting, so keys for
+// providers without rate limit headers still recover.
+func (k *KeyMetadata) RecordUsage(inputTokens, outputTokens int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	deductTokens(now, inputTokens, k.ITPMLimit, &k.ITPMRemaining, &k.ITPMResetAt)
+	deductTokens(now, outputTokens, k.OTPMLimit, &k.OTPMRemaining, &k.OTPMResetAt)
+}
+
+func deductTokens(now time.Time, tokens, limit int, remaining *int, resetAt *time.Time) {
+	if limit <= 0 || tokens <= 0 {
+		return
+	}
+	if !now.Before(*resetAt) {
+		*remaining = limit
+		*resetAt = now.Add(tokenWindow)
+	}
+	*remaining = max(*remaining-tokens, 0)
+}
+
 // SetCooldown marks the key as unavailable until the specified time.
 // Used when a 429 response includes a retry-after header.
 func (k *KeyMetadata) SetCooldown(until time.Time) {
@@ -210,6 +274,15 @@ func (k *KeyMetadata) SetCooldown(until time.Time) {
 	k.CooldownUntil = until
 }
 
+// SetDisabled takes the key out of selection, or returns it, until the pool
+// is rebuilt.
+func (k *KeyMetadata) SetDisabled(disabled bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.Disabled = disabled
+}
+
 // MarkUnhealthy marks the key as unhealthy with the given error.
 // Unhealthy keys are skipped during selection.
 func (k *KeyMetadata) MarkUnhealthy(err error) {
@@ -235,9 +308,9 @@ func (k *KeyMetadata) String() string {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	return fmt.Sprintf("Key[%s] rpm=%d/%d itpm=%d/%d otpm=%d/%d healthy=%v",
+	return fmt.Sprintf("Key[%s] rpm=%d/%d itpm=%d/%d otpm=%d/%d healthy=%v disabled=%v",
 		k.ID, k.RPMRemaining, k.RPMLimit,
 		k.ITPMRemaining, k.ITPMLimit,
 		k.OTPMRemaining, k.OTPMLimit,
-		k.Healthy)
+		k.Healthy, k.Disabled)
 }
@@ -52,12 +52,13 @@ type KeyConfig struct {
 // KeyPool manages multiple API keys with rate limiting and intelligent selection.
 // All methods are safe for concurrent use.
 type KeyPool struct {
-	selector KeySelector
-	keyMap   map[string]*KeyMetadata
-	limiters map[string]ratelimit.RateLimiter
-	provider string
-	keys     []*KeyMetadata
-	mu       sync.RWMutex
+	selector   KeySelector
+	keyMap     map[string]*KeyMetadata
+	limiters   map[string]ratelimit.RateLimiter
+	configured map[string]KeyLimits
+	provider   string
+	keys       []*KeyMetadata
+	mu         sync.RWMutex
 }
 
 // NewKeyPool creates a new KeyPool with the given configuration.
@@ -74,12 +75,13 @@ func NewKeyPool(provider string, cfg PoolConfig) (*KeyPool, error) {
 	}
 
 	pool := &KeyPool{
-		selector: selector,
-		keyMap:   make(map[string]*KeyMetadata, len(cfg.Keys)),
-		limiters: make(map[string]ratelimit.RateLimiter, len(cfg.Keys)),
-		provider: provider,
-		keys:     make([]*KeyMetadata, 0, len(cfg.Keys)),
-		mu:       sync.RWMutex{},
+		selector:   selector,
+		keyMap:     make(map[string]*KeyMetadata, len(cfg.Keys)),
+		limiters:   make(map[string]ratelimit.RateLimiter, len(cfg.Keys)),
+		configured: make(map[string]KeyLimits, len(cfg.Keys)),
+		provider:   provider,
+		keys:       make([]*KeyMetadata, 0, len(cfg.Keys)),
+		mu:         sync.RWMutex{},
 	}
 
 	// Initialize keys and limiters
@@ -102,6 +104,7 @@ func NewKeyPool(provider string, cfg PoolConfig) (*KeyPool, error) {
 		pool.keys = append(pool.keys, key)
 		pool.keyMap[key.ID] = key
 		pool.limiters[key.ID] = limiter
+		pool.configured[key.ID] = KeyLimits{RPM: keyCfg.RPMLimit, ITPM: keyCfg.ITPMLimit, OTPM: keyCfg.OTPMLimit}
 
 		log.Debug().
 			Str("provider", provider).
@@ -124,10 +127,24 @@ func NewKeyPool(provider string, cfg PoolConfig) (*KeyPool, error) {
 	return pool, nil
 }
 
+type preferredKeyContextKey struct{}
+
+// WithPreferredKey returns a context asking GetKey to use the key with keyID
+// while it is available and has capacity, instead of applying the strategy.
+// Used to keep a conversation on the key whose prompt cache it has warmed.
+func WithPreferredKey(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, preferredKeyContextKey{}, keyID)
+}
+
 // GetKey selects the best available key from the pool using the configured strategy.
+// A key preferred with WithPreferredKey is returned when it has capacity.
 // Returns (keyID, apiKey, error).
 // Returns ErrAllKeysExhausted if no keys have capacity.
 func (p *KeyPool) GetKey(ctx context.Context) (keyID, apiKey string, err error) {
+	if key, ok := p.preferredKey(ctx); ok {
+		return key.ID, key.APIKey, nil
+	}
+
 	p.mu.RLock()
 	// Make a copy of keys slice for selector (avoid holding lock during selection)
 	availableKeys := make([]*KeyMetadata, len(p.keys))
@@ -186,6 +203,29 @@ func (p *KeyPool) GetKey(ctx context.Context) (keyID, apiKey string, err error)
 	return "", "", ErrAllKeysExhausted
 }
 
+// preferredKey returns the key preferred by ctx if it is available and its
+// rate limiter admits the request.
+func (p *KeyPool) preferredKey(ctx context.Context) (*KeyMetadata, bool) {
+	keyID, ok := ctx.Value(preferredKeyContextKey{}).(string)
+	if !ok || keyID == "" {
+		return nil, false
+	}
+
+	p.mu.RLock()
+	key, found := p.keyMap[keyID]
+	limiter := p.limiters[keyID]
+	p.mu.RUnlock()
+
+	if !found || !key.IsAvailable() || !limiter.Allow(ctx) {
+		return nil, false
+	}
+	log.Debug().
+		Str("provider", p.provider).
+		Str("key_id", key.ID).
+		Msg("Selected preferred key from pool")
+	return key, true
+}
+
 // UpdateKeyFromHeaders updates a key's rate limit state from response headers.
 // Returns ErrKeyNotFound if the key ID is not in the pool.
 func (p *KeyPool) UpdateKeyFromHeaders(keyID string, headers http.Header) error {
@@ -227,6 +267,41 @@ func (p *KeyPool) UpdateKeyFromHeaders(keyID string, headers http.Header) error
 	return nil
 }
 
+// RecordUsage charges the token usage reported by a response against a key.
+// The key's ITPM/OTPM counters are updated immediately and the tokens are
+// recorded in its rate limiter without blocking, putting the key in debt if
+// its TPM budget is already spent.
+// Returns ErrKeyNotFound if the key ID is not in the pool.
+func (p *KeyPool) RecordUsage(ctx context.Context, keyID string, inputTokens, outputTokens int) error {
+	p.mu.RLock()
+	key, ok := p.keyMap[keyID]
+	limiter := p.limiters[keyID]
+	p.mu.RUnlock()
+
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.RecordUsage(inputTokens, outputTokens)
+
+	total := inputTokens + outputTokens
+	if total <= 0 {
+		return nil
+	}
+	if err := limiter.RecordTokens(ctx, total); err != nil {
+		return fmt.Errorf("keypool: record %d tokens for key %s: %w", total, keyID, err)
+	}
+
+	log.Debug().
+		Str("provider", p.provider).
+		Str("key_id", keyID).
+		Int("input_tokens", inputTokens).
+		Int("output_tokens", outputTokens).
+		Msg("Recorded token usage for key")
+
+	return nil
+}
+
 // MarkKeyExhausted marks a key as unavailable until the cooldown expires.
 // Used when a 429 response includes a retry-after header.
 func (p *KeyPool) MarkKeyExhausted(keyID string, retryAfter time.Duration) {
@@ -253,6 +328,28 @@ func (p *KeyPool) MarkKeyExhausted(keyID string, retryAfter time.Duration) {
 		Msg("Key marked as exhausted with cooldown")
 }
 
+// SetKeyDisabled takes a key out of selection, or returns it.
+// The flag lives on the pool's key, so it is lost when the pool is rebuilt on config reload.
+// Returns ErrKeyNotFound if the key ID is not in the pool.
+func (p *KeyPool) SetKeyDisabled(keyID string, disabled bool) error {
+	p.mu.RLock()
+	key, ok := p.keyMap[keyID]
+	p.mu.RUnlock()
+
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.SetDisabled(disabled)
+
+	log.Info().
+		Str("provider", p.provider).
+		Str("key_id", keyID).
+		Bool("disabled", disabled).
+		Msg("Key availability changed by operator")
+	return nil
+}
+
 // GetEarliestResetTime returns the duration until the earliest rate limit reset.
 // Used for setting retry-after headers when all keys are exhausted.
 // Returns 60s default if no reset times are set.
@@ -316,6 +413,30 @@ func (p *KeyPool) GetStats() PoolStats {
 	}, PoolStats{TotalKeys: len(p.keys), AvailableKeys: 0, ExhaustedKeys: 0, TotalRPM: 0, RemainingRPM: 0})
 }
 
+// KeyStats is a point-in-time snapshot of a single key's rate limit state.
+type KeyStats struct {
+	CooldownUntil time.Time `json:"cooldown_until"`
+	LastErrorAt   time.Time `json:"last_error_at"`
+	ID            string    `json:"id"`
+	LastError     string    `json:"last_error,omitempty"`
+	RPMLimit      int       `json:"rpm_limit"`
+	RPMRemaining  int       `json:"rpm_remaining"`
+	ITPMLimit     int       `json:"itpm_limit"`
+	ITPMRemaining int       `json:"itpm_remaining"`
+	OTPMLimit     int       `json:"otpm_limit"`
+	OTPMRemaining int       `json:"otpm_remaining"`
+	Available     bool      `json:"available"`
+	Healthy       bool      `json:"healthy"`
+	Disabled      bool      `json:"disabled"`
+}
+
+// GetKeyStats returns a snapshot of every key in the pool, in pool order.
+func (p *KeyPool) GetKeyStats() []KeyStats {
+	return lo.Map(p.Keys(), func(key *KeyMetadata, _ int) KeyStats {
+		return key.Stats()
+	})
+}
+
 // Keys returns a copy of the keys slice for external iteration.
 // Callers can safely iterate over the returned slice.
 func (p *KeyPool) Keys() []*KeyMetadata {
@@ -251,6 +251,42 @@ func TestGetKeySkipsUnavailable(t *testing.T) {
 	})
 }
 
+func TestGetKeyPreferredKey(t *testing.T) {
+	t.Parallel()
+	t.Run("returns the preferred key", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(3, keypool.StrategyRoundRobin)
+		preferred := pool.GetKeys()[2].ID
+		ctx := keypool.WithPreferredKey(context.Background(), preferred)
+
+		for range 3 {
+			keyID, _, err := pool.GetKey(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, preferred, keyID)
+		}
+	})
+
+	t.Run("falls back to the strategy when the preferred key is unavailable", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(2, strategyLeastLoaded)
+		preferred := pool.GetKeys()[0].ID
+		pool.MarkKeyExhausted(preferred, 10*time.Second)
+
+		keyID, _, err := pool.GetKey(keypool.WithPreferredKey(context.Background(), preferred))
+		require.NoError(t, err)
+		assert.Equal(t, pool.GetKeys()[1].ID, keyID)
+	})
+
+	t.Run("ignores unknown keys", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(2, strategyLeastLoaded)
+
+		keyID, _, err := pool.GetKey(keypool.WithPreferredKey(context.Background(), "unknown"))
+		require.NoError(t, err)
+		assert.NotEmpty(t, keyID)
+	})
+}
+
 func TestUpdateKeyFromHeaders(t *testing.T) {
 	t.Parallel()
 
@@ -331,6 +367,106 @@ func TestUpdateKeyFromHeaders(t *testing.T) {
 	})
 }
 
+func TestRecordUsage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("deducts tokens from remaining capacity", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+		key := pool.GetKeys()[0]
+
+		err := pool.RecordUsage(context.Background(), key.ID, 1200, 300)
+		require.NoError(t, err)
+
+		assert.Equal(t, 28800, key.GetITPMRemaining())
+		assert.Equal(t, 29700, key.GetOTPMRemaining())
+		assert.Positive(t, pool.GetLimiters()[key.ID].GetUsage().TokensUsed)
+	})
+
+	t.Run("deducts from header-reported remaining within the window", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+		key := pool.GetKeys()[0]
+
+		headers := http.Header{}
+		headers.Set("anthropic-ratelimit-input-tokens-remaining", "10000")
+		headers.Set("anthropic-ratelimit-input-tokens-reset", time.Now().Add(time.Minute).Format(time.RFC3339))
+		require.NoError(t, pool.UpdateKeyFromHeaders(key.ID, headers))
+
+		require.NoError(t, pool.RecordUsage(context.Background(), key.ID, 500, 0))
+
+		assert.Equal(t, 9500, key.GetITPMRemaining())
+	})
+
+	t.Run("clamps remaining at zero", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+		key := pool.GetKeys()[0]
+
+		err := pool.RecordUsage(context.Background(), key.ID, 40000, 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, key.GetITPMRemaining())
+		assert.Equal(t, 30000, key.GetOTPMRemaining())
+	})
+
+	t.Run("does not block once the TPM budget is spent", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+		key := pool.GetKeys()[0]
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		for range 3 {
+			require.NoError(t, pool.RecordUsage(ctx, key.ID, 30000, 20000))
+		}
+
+		assert.Equal(t, 0, pool.GetLimiters()[key.ID].GetUsage().TokensRemaining)
+	})
+
+	t.Run("charges usage larger than the TPM limit", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+		key := pool.GetKeys()[0]
+
+		require.NoError(t, pool.RecordUsage(context.Background(), key.ID, 60000, 30000))
+
+		assert.Equal(t, 0, key.GetITPMRemaining())
+		assert.Equal(t, 0, pool.GetLimiters()[key.ID].GetUsage().TokensRemaining)
+	})
+
+	t.Run("leaves unlimited counters untouched", func(t *testing.T) {
+		t.Parallel()
+		pool, err := keypool.NewKeyPool("test-provider", keypool.PoolConfig{
+			Strategy: strategyLeastLoaded,
+			Keys: []keypool.KeyConfig{{
+				APIKey:    "sk-unlimited",
+				RPMLimit:  0,
+				ITPMLimit: 0,
+				OTPMLimit: 0,
+				Priority:  1,
+				Weight:    1,
+			}},
+		})
+		require.NoError(t, err)
+		key := pool.GetKeys()[0]
+
+		require.NoError(t, pool.RecordUsage(context.Background(), key.ID, 100, 100))
+
+		assert.Equal(t, 0, key.GetITPMRemaining())
+		assert.Equal(t, 0, key.GetOTPMRemaining())
+	})
+
+	t.Run("returns error for unknown keyID", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+
+		err := pool.RecordUsage(context.Background(), "unknown-key-id", 10, 10)
+
+		assert.ErrorIs(t, err, keypool.ErrKeyNotFound)
+	})
+}
+
 func TestMarkKeyExhausted(t *testing.T) {
 	t.Parallel()
 	t.Run("sets cooldown period", func(t *testing.T) {
@@ -384,6 +520,36 @@ func TestMarkKeyExhausted(t *testing.T) {
 	})
 }
 
+func TestSetKeyDisabled(t *testing.T) {
+	t.Parallel()
+	t.Run("disabled key is skipped until enabled", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(2, strategyLeastLoaded)
+		ctx := context.Background()
+		keys := pool.GetKeys()
+
+		require.NoError(t, pool.SetKeyDisabled(keys[0].ID, true))
+		assert.False(t, keys[0].IsAvailable())
+		for range 5 {
+			keyID, _, err := pool.GetKey(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, keys[1].ID, keyID)
+		}
+
+		require.NoError(t, pool.SetKeyDisabled(keys[0].ID, false))
+		assert.True(t, keys[0].IsAvailable())
+	})
+
+	t.Run("returns error for unknown key", func(t *testing.T) {
+		t.Parallel()
+		pool := newTestPool(1, strategyLeastLoaded)
+
+		err := pool.SetKeyDisabled("unknown-key-id", true)
+
+		assert.ErrorIs(t, err, keypool.ErrKeyNotFound)
+	})
+}
+
 func TestGetEarliestResetTime(t *testing.T) {
 	t.Parallel()
 	t.Run("returns time to earliest reset", func(t *testing.T) {
@@ -466,6 +632,37 @@ func TestGetStats(t *testing.T) {
 	})
 }
 
+func TestGetKeyStats(t *testing.T) {
+	t.Parallel()
+	pool := newTestPool(2, strategyLeastLoaded)
+	keys := pool.GetKeys()
+
+	headers := newTestHeaders(25, time.Now().Add(time.Minute))
+	require.NoError(t, pool.UpdateKeyFromHeaders(keys[0].ID, headers))
+	pool.MarkKeyExhausted(keys[1].ID, 10*time.Second)
+	keys[1].MarkUnhealthy(fmt.Errorf("upstream failure"))
+	require.NoError(t, pool.SetKeyDisabled(keys[1].ID, true))
+
+	stats := pool.GetKeyStats()
+
+	require.Len(t, stats, 2)
+	assert.Equal(t, keys[0].ID, stats[0].ID)
+	assert.Equal(t, 50, stats[0].RPMLimit)
+	assert.Equal(t, 25, stats[0].RPMRemaining)
+	assert.Equal(t, 30000, stats[0].ITPMRemaining)
+	assert.Equal(t, 30000, stats[0].OTPMRemaining)
+	assert.True(t, stats[0].Available)
+	assert.True(t, stats[0].Healthy)
+	assert.False(t, stats[0].Disabled)
+	assert.Empty(t, stats[0].LastError)
+	assert.Equal(t, keys[1].ID, stats[1].ID)
+	assert.False(t, stats[1].Available)
+	assert.False(t, stats[1].Healthy)
+	assert.True(t, stats[1].Disabled)
+	assert.Equal(t, "upstream failure", stats[1].LastError)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), stats[1].CooldownUntil, time.Second)
+}
+
 func TestConcurrencyGetKey(t *testing.T) {
 	t.Parallel()
 	pool := newTestPool(5, strategyLeastLoaded)
@@ -0,0 +1,136 @@
+package keypool
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// KeyLimits are the per-minute limits configured for a key.
+type KeyLimits struct {
+	RPM  int `json:"rpm"`
+	ITPM int `json:"itpm"`
+	OTPM int `json:"otpm"`
+}
+
+// KeyState is a snapshot of what a key has learned from provider responses:
+// its real limits, the remaining capacity of its current windows and any
+// cooldown from a 429. Snapshots are persisted so a restarted relay, or a
+// pool rebuilt on config reload, does not retry keys it knows are exhausted.
+type KeyState struct {
+	RPMResetAt    time.Time `json:"rpm_reset_at"`
+	ITPMResetAt   time.Time `json:"itpm_reset_at"`
+	OTPMResetAt   time.Time `json:"otpm_reset_at"`
+	CooldownUntil time.Time `json:"cooldown_until"`
+	ID            string    `json:"id"`
+	// Configured are the limits the key was configured with when the
+	// snapshot was taken. State is only restored to a key configured with
+	// the same limits.
+	Configured    KeyLimits `json:"configured"`
+	RPMLimit      int       `json:"rpm_limit"`
+	RPMRemaining  int       `json:"rpm_remaining"`
+	ITPMLimit     int       `json:"itpm_limit"`
+	ITPMRemaining int       `json:"itpm_remaining"`
+	OTPMLimit     int       `json:"otpm_limit"`
+	OTPMRemaining int       `json:"otpm_remaining"`
+}
+
+// snapshot returns the key's learned state.
+func (k *KeyMetadata) snapshot(configured KeyLimits) KeyState {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return KeyState{
+		RPMResetAt:    k.RPMResetAt,
+		ITPMResetAt:   k.ITPMResetAt,
+		OTPMResetAt:   k.OTPMResetAt,
+		CooldownUntil: k.CooldownUntil,
+		ID:            k.ID,
+		Configured:    configured,
+		RPMLimit:      k.RPMLimit,
+		RPMRemaining:  k.RPMRemaining,
+		ITPMLimit:     k.ITPMLimit,
+		ITPMRemaining: k.ITPMRemaining,
+		OTPMLimit:     k.OTPMLimit,
+		OTPMRemaining: k.OTPMRemaining,
+	}
+}
+
+// restore applies a snapshot of the key's state. Learned limits are kept;
+// remaining capacity only while its window is still open, and cooldowns only
+// while they last.
+func (k *KeyMetadata) restore(state *KeyState, now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	restoreWindow(now, state.RPMLimit, state.RPMRemaining, state.RPMResetAt,
+		&k.RPMLimit, &k.RPMRemaining, &k.RPMResetAt)
+	restoreWindow(now, state.ITPMLimit, state.ITPMRemaining, state.ITPMResetAt,
+		&k.ITPMLimit, &k.ITPMRemaining, &k.ITPMResetAt)
+	restoreWindow(now, state.OTPMLimit, state.OTPMRemaining, state.OTPMResetAt,
+		&k.OTPMLimit, &k.OTPMRemaining, &k.OTPMResetAt)
+	if now.Before(state.CooldownUntil) {
+		k.CooldownUntil = state.CooldownUntil
+	}
+}
+
+func restoreWindow(
+	now time.Time, limit, remaining int, resetAt time.Time,
+	dstLimit, dstRemaining *int, dstResetAt *time.Time,
+) {
+	if limit > 0 {
+		*dstLimit = limit
+	}
+	if now.Before(resetAt) {
+		*dstRemaining = min(remaining, *dstLimit)
+		*dstResetAt = resetAt
+		return
+	}
+	*dstRemaining = *dstLimit
+}
+
+// Snapshot returns the learned state of every key in the pool, in pool order.
+func (p *KeyPool) Snapshot() []KeyState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	states := make([]KeyState, 0, len(p.keys))
+	for _, key := range p.keys {
+		states = append(states, key.snapshot(p.configured[key.ID]))
+	}
+	return states
+}
+
+// Restore applies snapshots to the pool's keys with the same ID. A snapshot
+// is skipped if the key's configured limits have changed since it was taken,
+// so a config change is never overridden by state learned under the old one.
+// Returns how many keys were restored.
+func (p *KeyPool) Restore(states []KeyState) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	restored := 0
+	for idx := range states {
+		state := &states[idx]
+		key, ok := p.keyMap[state.ID]
+		if !ok || p.configured[state.ID] != state.Configured {
+			continue
+		}
+		key.restore(state, now)
+
+		key.mu.RLock()
+		rpm, tpm := key.RPMLimit, key.ITPMLimit+key.OTPMLimit
+		key.mu.RUnlock()
+		p.limiters[state.ID].SetLimit(rpm, tpm)
+		restored++
+	}
+
+	if restored > 0 {
+		log.Debug().
+			Str("provider", p.provider).
+			Int("restored_keys", restored).
+			Msg("Restored key state")
+	}
+	return restored
+}
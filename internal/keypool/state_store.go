@@ -0,0 +1,129 @@
+package keypool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+const (
+	// stateKeyPrefix namespaces key state snapshots in the shared cache.
+	stateKeyPrefix = "keystate:"
+
+	// stateTTL is how long a snapshot is kept in the cache without being
+	// saved again. Keys that leave the config expire with it.
+	stateTTL = 24 * time.Hour
+)
+
+// StateStore persists key state snapshots by key ID.
+type StateStore interface {
+	// Save stores the snapshots of every key in use.
+	Save(ctx context.Context, states []KeyState) error
+	// Load returns the stored snapshots of the keys with the given IDs.
+	// Keys without a snapshot are left out.
+	Load(ctx context.Context, keyIDs []string) ([]KeyState, error)
+}
+
+// CacheStateStore stores snapshots in a cache.Cache. With the HA cache the
+// snapshots are shared by every instance and outlive a restart of one.
+type CacheStateStore struct {
+	cache cache.Cache
+}
+
+// NewCacheStateStore creates a state store on the given cache backend.
+func NewCacheStateStore(c cache.Cache) *CacheStateStore {
+	return &CacheStateStore{cache: c}
+}
+
+// Save implements StateStore.
+func (s *CacheStateStore) Save(ctx context.Context, states []KeyState) error {
+	var errs []error
+	for idx := range states {
+		data, err := json.Marshal(&states[idx])
+		if err == nil {
+			err = s.cache.SetWithTTL(ctx, stateKeyPrefix+states[idx].ID, data, stateTTL)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("keypool: save state of key %s: %w", states[idx].ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Load implements StateStore.
+func (s *CacheStateStore) Load(ctx context.Context, keyIDs []string) ([]KeyState, error) {
+	states := make([]KeyState, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		data, err := s.cache.Get(ctx, stateKeyPrefix+keyID)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return states, fmt.Errorf("keypool: load state of key %s: %w", keyID, err)
+		}
+		var state KeyState
+		if err = json.Unmarshal(data, &state); err != nil {
+			return states, fmt.Errorf("keypool: decode state of key %s: %w", keyID, err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// FileStateStore stores snapshots in a local JSON file, replaced atomically
+// on every save.
+type FileStateStore struct {
+	path string
+}
+
+// NewFileStateStore creates a state store writing to path.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: filepath.Clean(path)}
+}
+
+// Save implements StateStore.
+func (s *FileStateStore) Save(_ context.Context, states []KeyState) error {
+	byID := make(map[string]KeyState, len(states))
+	for idx := range states {
+		byID[states[idx].ID] = states[idx]
+	}
+	data, err := json.MarshalIndent(byID, "", "  ")
+	if err != nil {
+		return fmt.Errorf("keypool: encode key state: %w", err)
+	}
+
+	if err = cache.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("keypool: save key state: %w", err)
+	}
+	return nil
+}
+
+// Load implements StateStore. A missing file holds no snapshots.
+func (s *FileStateStore) Load(_ context.Context, keyIDs []string) ([]KeyState, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keypool: load key state: %w", err)
+	}
+	var byID map[string]KeyState
+	if err = json.Unmarshal(data, &byID); err != nil {
+		return nil, fmt.Errorf("keypool: decode key state %s: %w", s.path, err)
+	}
+
+	states := make([]KeyState, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		if state, ok := byID[keyID]; ok {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,163 @@
+package keypool_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/keypool"
+)
+
+func newStatePool(t *testing.T, rpm int) *keypool.KeyPool {
+	t.Helper()
+	pool, err := keypool.NewKeyPool("test-provider", keypool.PoolConfig{
+		Strategy: strategyLeastLoaded,
+		Keys: []keypool.KeyConfig{
+			{APIKey: "sk-state-1", RPMLimit: rpm, ITPMLimit: 0, OTPMLimit: 0, Priority: 1, Weight: 1},
+			{APIKey: "sk-state-2", RPMLimit: rpm, ITPMLimit: 0, OTPMLimit: 0, Priority: 1, Weight: 1},
+		},
+	})
+	require.NoError(t, err)
+	return pool
+}
+
+// learnState makes the pool's first key learn a limit from headers and go
+// into cooldown, returning its ID.
+func learnState(t *testing.T, pool *keypool.KeyPool) string {
+	t.Helper()
+	keyID := pool.Keys()[0].ID
+	headers := http.Header{}
+	headers.Set("anthropic-ratelimit-requests-limit", "500")
+	headers.Set("anthropic-ratelimit-requests-remaining", "7")
+	headers.Set("anthropic-ratelimit-requests-reset", time.Now().Add(time.Minute).Format(time.RFC3339))
+	require.NoError(t, pool.UpdateKeyFromHeaders(keyID, headers))
+	pool.MarkKeyExhausted(keyID, time.Minute)
+	return keyID
+}
+
+func TestKeyPoolRestore(t *testing.T) {
+	t.Parallel()
+
+	previous := newStatePool(t, 50)
+	keyID := learnState(t, previous)
+
+	pool := newStatePool(t, 50)
+	assert.Equal(t, 2, pool.Restore(previous.Snapshot()))
+
+	stats := pool.GetKeyStats()
+	assert.Equal(t, keyID, stats[0].ID)
+	assert.Equal(t, 500, stats[0].RPMLimit, "the learned limit is restored")
+	assert.Equal(t, 7, stats[0].RPMRemaining)
+	assert.False(t, stats[0].Available, "the cooldown is restored")
+	assert.True(t, stats[1].Available)
+}
+
+func TestKeyPoolRestoreSkipsChangedConfig(t *testing.T) {
+	t.Parallel()
+
+	previous := newStatePool(t, 50)
+	learnState(t, previous)
+
+	pool := newStatePool(t, 60)
+	assert.Equal(t, 0, pool.Restore(previous.Snapshot()))
+	assert.Equal(t, 60, pool.GetKeyStats()[0].RPMLimit)
+	assert.True(t, pool.GetKeyStats()[0].Available)
+}
+
+func TestKeyPoolRestoreExpiredState(t *testing.T) {
+	t.Parallel()
+
+	previous := newStatePool(t, 50)
+	states := previous.Snapshot()
+	states[0].RPMLimit = 500
+	states[0].RPMRemaining = 0
+	states[0].RPMResetAt = time.Now().Add(-time.Second)
+	states[0].CooldownUntil = time.Now().Add(-time.Second)
+
+	pool := newStatePool(t, 50)
+	pool.Restore(states)
+
+	stats := pool.GetKeyStats()[0]
+	assert.Equal(t, 500, stats.RPMLimit, "learned limits outlive their window")
+	assert.Equal(t, 500, stats.RPMRemaining, "a window that has reset starts full")
+	assert.True(t, stats.Available, "an expired cooldown is not restored")
+}
+
+// stateCache is a synchronous in-memory cache.Cache.
+type stateCache struct {
+	values map[string][]byte
+	mu     sync.Mutex
+}
+
+func (c *stateCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c *stateCache) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *stateCache) SetWithTTL(ctx context.Context, key string, value []byte, _ time.Duration) error {
+	return c.Set(ctx, key, value)
+}
+
+func (c *stateCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *stateCache) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.values[key]
+	return ok, nil
+}
+
+func (c *stateCache) Close() error { return nil }
+
+func TestStateStores(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]keypool.StateStore{
+		"file":  keypool.NewFileStateStore(filepath.Join(t.TempDir(), "key-state.json")),
+		"cache": keypool.NewCacheStateStore(&stateCache{values: make(map[string][]byte), mu: sync.Mutex{}}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			empty, err := store.Load(ctx, []string{"missing"})
+			require.NoError(t, err)
+			assert.Empty(t, empty)
+
+			previous := newStatePool(t, 50)
+			keyID := learnState(t, previous)
+			require.NoError(t, store.Save(ctx, previous.Snapshot()))
+
+			states, err := store.Load(ctx, []string{keyID, "missing"})
+			require.NoError(t, err)
+			require.Len(t, states, 1)
+			assert.Equal(t, keyID, states[0].ID)
+			assert.Equal(t, 500, states[0].RPMLimit)
+		})
+	}
+}
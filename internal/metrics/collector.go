@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stateCollector reads key pool, circuit breaker and cache state on every
+// scrape. Providers and keys appear and disappear with config reloads, so the
+// metrics are built from the current state instead of being kept in vectors.
+type stateCollector struct {
+	opts              Options
+	poolKeys          *prometheus.Desc
+	poolAvailableKeys *prometheus.Desc
+	poolRPMLimit      *prometheus.Desc
+	poolRPMRemaining  *prometheus.Desc
+	keyAvailable      *prometheus.Desc
+	keyRPMRemaining   *prometheus.Desc
+	keyITPMRemaining  *prometheus.Desc
+	keyOTPMRemaining  *prometheus.Desc
+	circuitState      *prometheus.Desc
+	cacheHits         *prometheus.Desc
+	cacheMisses       *prometheus.Desc
+	cacheEvictions    *prometheus.Desc
+	cacheKeys         *prometheus.Desc
+	cacheBytes        *prometheus.Desc
+}
+
+func newStateCollector(opts Options) *stateCollector {
+	providerLabels := []string{labelProvider}
+	keyLabels := []string{labelProvider, labelKeyID}
+	return &stateCollector{
+		opts: opts,
+		poolKeys: desc("keypool_keys",
+			"Keys configured in the provider's key pool.", providerLabels),
+		poolAvailableKeys: desc("keypool_available_keys",
+			"Keys that are healthy and not cooling down after a rate limit.", providerLabels),
+		poolRPMLimit: desc("keypool_rpm_limit",
+			"Sum of the requests-per-minute limits of the pool's keys.", providerLabels),
+		poolRPMRemaining: desc("keypool_rpm_remaining",
+			"Sum of the requests-per-minute capacity left on the pool's keys.", providerLabels),
+		keyAvailable: desc("key_available",
+			"1 if the key is healthy and not cooling down, 0 otherwise.", keyLabels),
+		keyRPMRemaining: desc("key_rpm_remaining",
+			"Requests-per-minute capacity left on the key.", keyLabels),
+		keyITPMRemaining: desc("key_input_tpm_remaining",
+			"Input-tokens-per-minute capacity left on the key.", keyLabels),
+		keyOTPMRemaining: desc("key_output_tpm_remaining",
+			"Output-tokens-per-minute capacity left on the key.", keyLabels),
+		circuitState: desc("circuit_breaker_state",
+			"Provider circuit breaker state: 0 closed, 1 half-open, 2 open.", providerLabels),
+		cacheHits:      desc("cache_hits_total", "Cache lookups that found a value.", nil),
+		cacheMisses:    desc("cache_misses_total", "Cache lookups that found no value.", nil),
+		cacheEvictions: desc("cache_evictions_total", "Values evicted from the cache.", nil),
+		cacheKeys:      desc("cache_keys", "Keys currently stored in the cache.", nil),
+		cacheBytes:     desc("cache_bytes", "Approximate bytes used by cached values.", nil),
+	}
+}
+
+func desc(name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+}
+
+// Describe implements prometheus.Collector.
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.poolKeys, c.poolAvailableKeys, c.poolRPMLimit, c.poolRPMRemaining,
+		c.keyAvailable, c.keyRPMRemaining, c.keyITPMRemaining, c.keyOTPMRemaining,
+		c.circuitState,
+		c.cacheHits, c.cacheMisses, c.cacheEvictions, c.cacheKeys, c.cacheBytes,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collectKeyPools(ch)
+	c.collectCircuits(ch)
+	c.collectCache(ch)
+}
+
+func (c *stateCollector) collectKeyPools(ch chan<- prometheus.Metric) {
+	if c.opts.KeyPools == nil {
+		return
+	}
+	for provider, pool := range c.opts.KeyPools() {
+		if pool == nil {
+			continue
+		}
+		stats := pool.GetStats()
+		gauge(ch, c.poolKeys, stats.TotalKeys, provider)
+		gauge(ch, c.poolAvailableKeys, stats.AvailableKeys, provider)
+		gauge(ch, c.poolRPMLimit, stats.TotalRPM, provider)
+		gauge(ch, c.poolRPMRemaining, stats.RemainingRPM, provider)
+
+		for _, key := range pool.GetKeyStats() {
+			available := 0
+			if key.Available {
+				available = 1
+			}
+			gauge(ch, c.keyAvailable, available, provider, key.ID)
+			gauge(ch, c.keyRPMRemaining, key.RPMRemaining, provider, key.ID)
+			gauge(ch, c.keyITPMRemaining, key.ITPMRemaining, provider, key.ID)
+			gauge(ch, c.keyOTPMRemaining, key.OTPMRemaining, provider, key.ID)
+		}
+	}
+}
+
+// collectCircuits exports gobreaker's state values directly; they are ordered
+// closed, half-open, open, so alerts can use "> 0" for any degraded circuit.
+func (c *stateCollector) collectCircuits(ch chan<- prometheus.Metric) {
+	if c.opts.HealthTracker == nil {
+		return
+	}
+	for provider, state := range c.opts.HealthTracker.AllStates() {
+		gauge(ch, c.circuitState, int(state), provider)
+	}
+}
+
+func (c *stateCollector) collectCache(ch chan<- prometheus.Metric) {
+	if c.opts.Cache == nil {
+		return
+	}
+	stats := c.opts.Cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.cacheHits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.cacheMisses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.cacheEvictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.cacheKeys, prometheus.GaugeValue, float64(stats.KeyCount))
+	ch <- prometheus.MustNewConstMetric(c.cacheBytes, prometheus.GaugeValue, float64(stats.BytesUsed))
+}
+
+func gauge(ch chan<- prometheus.Metric, d *prometheus.Desc, value int, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(value), labels...)
+}
@@ -0,0 +1,164 @@
+// Package metrics exposes cc-relay runtime metrics in the Prometheus format.
+//
+// Request, latency, failover and hedging metrics are recorded by the proxy handler as
+// requests complete. Key pool, circuit breaker, concurrency and cache metrics
+// are read from their owners when the endpoint is scraped, so they always
+// reflect hot-reloaded state and cost nothing between scrapes.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/keypool"
+)
+
+// namespace prefixes every metric name.
+const namespace = "cc_relay"
+
+const (
+	labelProvider = "provider"
+	labelModel    = "model"
+	labelStatus   = "status"
+	labelTrigger  = "trigger"
+	labelKeyID    = "key_id"
+	labelClient   = "client"
+	labelOutcome  = "outcome"
+)
+
+// requestDurationBuckets span quick count_tokens calls through long
+// extended-thinking generations.
+var requestDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// timeToFirstByteBuckets cover the time until the first SSE event is written.
+var timeToFirstByteBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60}
+
+// Options provides the sources read at scrape time. Every field is optional;
+// metrics for a nil source are not exported.
+type Options struct {
+	// KeyPools returns the live key pools by provider name.
+	KeyPools func() map[string]*keypool.KeyPool
+
+	// InFlight returns the number of requests currently being served.
+	InFlight func() int64
+
+	// HealthTracker supplies circuit breaker states.
+	HealthTracker *health.Tracker
+
+	// Cache supplies cache hit and miss counts.
+	Cache cache.StatsProvider
+}
+
+// Metrics records proxy metrics and serves them for Prometheus scrapes.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	ttfb      *prometheus.HistogramVec
+	failovers *prometheus.CounterVec
+	hedges    *prometheus.CounterVec
+}
+
+// New creates a Metrics instance with its own registry. Go runtime and process
+// metrics are registered alongside the cc-relay metrics.
+func New(opts Options) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Proxied requests by provider, requested model, client and response status.",
+		}, []string{labelProvider, labelModel, labelClient, labelStatus}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time from receiving a request to finishing its response, streams included.",
+			Buckets:   requestDurationBuckets,
+		}, []string{labelProvider, labelModel, labelClient, labelStatus}),
+		ttfb: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stream_time_to_first_byte_seconds",
+			Help:      "Time from receiving a streaming request to writing the first SSE bytes.",
+			Buckets:   timeToFirstByteBuckets,
+		}, []string{labelProvider, labelModel}),
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failover_attempts_total",
+			Help:      "Provider attempts abandoned in favor of the next provider, by failover trigger.",
+		}, []string{labelProvider, labelTrigger}),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hedged_requests_total",
+			Help:      "Requests hedged to a second provider, by primary provider and which attempt was used.",
+		}, []string{labelProvider, labelOutcome}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.ttfb, m.failovers, m.hedges,
+		newStateCollector(opts),
+	)
+	if opts.InFlight != nil {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "inflight_requests",
+			Help:      "Requests currently being served.",
+		}, func() float64 {
+			return float64(opts.InFlight())
+		}))
+	}
+	return m
+}
+
+// Handler returns the HTTP handler that serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a completed request. Provider is empty for requests
+// rejected before a provider was selected, and client is empty unless the
+// request was authenticated with a virtual key. Model should come from a
+// bounded set, since every distinct value is a new series.
+func (m *Metrics) ObserveRequest(provider, model, client string, status int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	statusLabel := strconv.Itoa(status)
+	m.requests.WithLabelValues(provider, model, client, statusLabel).Inc()
+	m.duration.WithLabelValues(provider, model, client, statusLabel).Observe(elapsed.Seconds())
+}
+
+// ObserveTimeToFirstByte records how long a streaming response took to start.
+func (m *Metrics) ObserveTimeToFirstByte(provider, model string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.ttfb.WithLabelValues(provider, model).Observe(elapsed.Seconds())
+}
+
+// IncFailover records an attempt on provider that was abandoned because of trigger.
+func (m *Metrics) IncFailover(provider, trigger string) {
+	if m == nil {
+		return
+	}
+	m.failovers.WithLabelValues(provider, trigger).Inc()
+}
+
+// IncHedge records a request to provider that was hedged to a second provider.
+// Outcome is "primary" or "hedge" for the attempt whose response was used, or
+// "failed" if neither succeeded.
+func (m *Metrics) IncHedge(provider, outcome string) {
+	if m == nil {
+		return
+	}
+	m.hedges.WithLabelValues(provider, outcome).Inc()
+}
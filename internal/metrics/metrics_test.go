@@ -0,0 +1,179 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/metrics"
+)
+
+type fakeCacheStats struct {
+	stats cache.Stats
+}
+
+func (f fakeCacheStats) Stats() cache.Stats {
+	return f.stats
+}
+
+func emptyOptions() metrics.Options {
+	return metrics.Options{KeyPools: nil, InFlight: nil, HealthTracker: nil, Cache: nil}
+}
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestObserveRequest(t *testing.T) {
+	t.Parallel()
+	m := metrics.New(emptyOptions())
+
+	m.ObserveRequest("anthropic", "claude-sonnet-4-5", "alice", http.StatusOK, 1500*time.Millisecond)
+	m.ObserveRequest("anthropic", "claude-sonnet-4-5", "alice", http.StatusOK, 200*time.Millisecond)
+	m.ObserveRequest("zai", "glm-4.6", "", http.StatusTooManyRequests, 50*time.Millisecond)
+
+	out := scrape(t, m)
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="alice",model="claude-sonnet-4-5",provider="anthropic",status="200"} 2`)
+	assert.Contains(t, out, `cc_relay_requests_total{client="",model="glm-4.6",provider="zai",status="429"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_request_duration_seconds_bucket{client="alice",model="claude-sonnet-4-5",provider="anthropic",`+
+			`status="200",le="1"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_request_duration_seconds_count{client="alice",model="claude-sonnet-4-5",provider="anthropic",`+
+			`status="200"} 2`)
+}
+
+func TestObserveTimeToFirstByte(t *testing.T) {
+	t.Parallel()
+	m := metrics.New(emptyOptions())
+
+	m.ObserveTimeToFirstByte("anthropic", "claude-sonnet-4-5", 300*time.Millisecond)
+
+	out := scrape(t, m)
+	assert.Contains(t, out,
+		`cc_relay_stream_time_to_first_byte_seconds_bucket{model="claude-sonnet-4-5",provider="anthropic",le="0.5"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_stream_time_to_first_byte_seconds_bucket{model="claude-sonnet-4-5",provider="anthropic",le="0.25"} 0`)
+}
+
+func TestIncFailover(t *testing.T) {
+	t.Parallel()
+	m := metrics.New(emptyOptions())
+
+	m.IncFailover("anthropic", "status_code")
+	m.IncFailover("anthropic", "status_code")
+
+	assert.Contains(t, scrape(t, m), `cc_relay_failover_attempts_total{provider="anthropic",trigger="status_code"} 2`)
+}
+
+func TestIncHedge(t *testing.T) {
+	t.Parallel()
+	m := metrics.New(emptyOptions())
+
+	m.IncHedge("anthropic", "hedge")
+
+	assert.Contains(t, scrape(t, m), `cc_relay_hedged_requests_total{outcome="hedge",provider="anthropic"} 1`)
+}
+
+func TestNilMetricsRecordsNothing(t *testing.T) {
+	t.Parallel()
+	var m *metrics.Metrics
+
+	assert.NotPanics(t, func() {
+		m.ObserveRequest("anthropic", "model", "", http.StatusOK, time.Second)
+		m.ObserveTimeToFirstByte("anthropic", "model", time.Second)
+		m.IncFailover("anthropic", "timeout")
+		m.IncHedge("anthropic", "primary")
+	})
+}
+
+func TestKeyPoolMetrics(t *testing.T) {
+	t.Parallel()
+	pool, err := keypool.NewKeyPool("anthropic", keypool.PoolConfig{
+		Strategy: keypool.StrategyLeastLoaded,
+		Keys: []keypool.KeyConfig{
+			{APIKey: "sk-one", RPMLimit: 50, ITPMLimit: 1000, OTPMLimit: 500, Priority: 1, Weight: 1},
+			{APIKey: "sk-two", RPMLimit: 50, ITPMLimit: 1000, OTPMLimit: 500, Priority: 1, Weight: 1},
+		},
+	})
+	require.NoError(t, err)
+	keys := pool.GetKeyStats()
+	pool.MarkKeyExhausted(keys[1].ID, time.Minute)
+
+	opts := emptyOptions()
+	opts.KeyPools = func() map[string]*keypool.KeyPool {
+		return map[string]*keypool.KeyPool{"anthropic": pool, "ollama": nil}
+	}
+	out := scrape(t, metrics.New(opts))
+
+	assert.Contains(t, out, `cc_relay_keypool_keys{provider="anthropic"} 2`)
+	assert.Contains(t, out, `cc_relay_keypool_available_keys{provider="anthropic"} 1`)
+	assert.Contains(t, out, `cc_relay_keypool_rpm_limit{provider="anthropic"} 100`)
+	assert.Contains(t, out, `cc_relay_key_available{key_id="`+keys[0].ID+`",provider="anthropic"} 1`)
+	assert.Contains(t, out, `cc_relay_key_available{key_id="`+keys[1].ID+`",provider="anthropic"} 0`)
+	assert.Contains(t, out, `cc_relay_key_input_tpm_remaining{key_id="`+keys[0].ID+`",provider="anthropic"} 1000`)
+	assert.Contains(t, out, `cc_relay_key_output_tpm_remaining{key_id="`+keys[0].ID+`",provider="anthropic"} 500`)
+	assert.NotContains(t, out, `provider="ollama"`)
+}
+
+func TestCircuitBreakerMetrics(t *testing.T) {
+	t.Parallel()
+	logger := zerolog.Nop()
+	tracker := health.NewTracker(health.CircuitBreakerConfig{
+		OpenDurationMS:   60000,
+		FailureThreshold: 1,
+		HalfOpenProbes:   1,
+	}, &logger)
+	tracker.RecordSuccess("anthropic")
+	tracker.RecordFailure("zai", errors.New("HTTP 503"))
+
+	opts := emptyOptions()
+	opts.HealthTracker = tracker
+	out := scrape(t, metrics.New(opts))
+
+	assert.Contains(t, out, `cc_relay_circuit_breaker_state{provider="anthropic"} 0`)
+	assert.Contains(t, out, `cc_relay_circuit_breaker_state{provider="zai"} 2`)
+}
+
+func TestInFlightAndCacheMetrics(t *testing.T) {
+	t.Parallel()
+	opts := emptyOptions()
+	opts.InFlight = func() int64 { return 3 }
+	opts.Cache = fakeCacheStats{stats: cache.Stats{Hits: 7, Misses: 2, KeyCount: 4, BytesUsed: 1024, Evictions: 1}}
+	out := scrape(t, metrics.New(opts))
+
+	assert.Contains(t, out, "cc_relay_inflight_requests 3")
+	assert.Contains(t, out, "cc_relay_cache_hits_total 7")
+	assert.Contains(t, out, "cc_relay_cache_misses_total 2")
+	assert.Contains(t, out, "cc_relay_cache_evictions_total 1")
+	assert.Contains(t, out, "cc_relay_cache_keys 4")
+	assert.Contains(t, out, "cc_relay_cache_bytes 1024")
+}
+
+func TestOptionalSourcesAreOmitted(t *testing.T) {
+	t.Parallel()
+	out := scrape(t, metrics.New(emptyOptions()))
+
+	assert.NotContains(t, out, "cc_relay_inflight_requests")
+	assert.NotContains(t, out, "cc_relay_cache_hits_total")
+	assert.NotContains(t, out, "cc_relay_circuit_breaker_state")
+}
@@ -0,0 +1,153 @@
+// Package openai translates between the OpenAI Chat Completions API and the
+// Anthropic Messages API.
+//
+// The relay speaks Messages internally: every provider, key pool and router
+// sees Anthropic-format bodies. This package converts OpenAI requests into that
+// format on the way in and converts Messages responses, both JSON and SSE,
+// back into Chat Completions responses on the way out.
+//
+// The reverse direction serves OpenAI-compatible upstreams: Messages requests
+// are converted into Chat Completions requests, and their responses and
+// streams are converted back into Messages responses and events.
+package openai
+
+import "errors"
+
+// ErrInvalidRequest is returned when a Chat Completions body cannot be translated.
+var ErrInvalidRequest = errors.New("openai: invalid chat completions request")
+
+// ErrInvalidResponse is returned when a Messages response body is not valid JSON.
+var ErrInvalidResponse = errors.New("openai: invalid messages response")
+
+// ErrInvalidMessagesRequest is returned when a Messages body cannot be translated.
+var ErrInvalidMessagesRequest = errors.New("openai: invalid messages request")
+
+// ErrInvalidChatResponse is returned when a Chat Completions response body is not valid JSON.
+var ErrInvalidChatResponse = errors.New("openai: invalid chat completions response")
+
+// Object types used in Chat Completions responses.
+const (
+	ObjectChatCompletion      = "chat.completion"
+	ObjectChatCompletionChunk = "chat.completion.chunk"
+)
+
+// Finish reasons reported in Chat Completions choices.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleDeveloper = "developer"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// toolTypeFunction is the only tool type the Chat Completions API defines.
+const toolTypeFunction = "function"
+
+// ChatCompletion is a non-streaming Chat Completions response.
+type ChatCompletion struct {
+	Usage   *Usage   `json:"usage,omitempty"`
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Created int64    `json:"created"`
+}
+
+// Choice is a single completion choice.
+type Choice struct {
+	FinishReason string  `json:"finish_reason"`
+	Message      Message `json:"message"`
+	Index        int     `json:"index"`
+}
+
+// Message is an assistant message in a Chat Completions response.
+type Message struct {
+	Content   *string    `json:"content"`
+	Role      string     `json:"role"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ToolCall is a function call requested by the model.
+// Index is only set in streaming deltas.
+type ToolCall struct {
+	Index    *int         `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds a function name and its JSON-encoded arguments.
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// Usage reports token consumption for a completion.
+type Usage struct {
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	PromptTokens        int64                `json:"prompt_tokens"`
+	CompletionTokens    int64                `json:"completion_tokens"`
+	TotalTokens         int64                `json:"total_tokens"`
+}
+
+// PromptTokensDetails breaks down prompt tokens served from the prompt cache.
+type PromptTokensDetails struct {
+	CachedTokens int64 `json:"cached_tokens"`
+}
+
+// ChatCompletionChunk is a single streamed Chat Completions event.
+type ChatCompletionChunk struct {
+	Usage   *Usage        `json:"usage,omitempty"`
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Model   string        `json:"model"`
+	Choices []ChunkChoice `json:"choices"`
+	Created int64         `json:"created"`
+}
+
+// ChunkChoice is the incremental update to a choice within a chunk.
+type ChunkChoice struct {
+	FinishReason *string `json:"finish_reason"`
+	Delta        Delta   `json:"delta"`
+	Index        int     `json:"index"`
+}
+
+// Delta carries the content added by a chunk.
+type Delta struct {
+	Content   *string    `json:"content,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ErrorResponse matches the OpenAI error response format.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error.
+type ErrorDetail struct {
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+}
+
+// NewErrorResponse builds an OpenAI-format error with the given type and message.
+func NewErrorResponse(errType, message string) ErrorResponse {
+	return ErrorResponse{
+		Error: ErrorDetail{
+			Param:   nil,
+			Code:    nil,
+			Message: message,
+			Type:    errType,
+		},
+	}
+}
@@ -0,0 +1,391 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// DefaultMaxTokens is used when a Chat Completions request sets no output limit.
+// The Messages API requires max_tokens, while OpenAI treats it as optional.
+const DefaultMaxTokens = 4096
+
+// maxTemperature is the upper bound of the Messages API temperature range.
+// OpenAI accepts values up to 2.
+const maxTemperature = 1.0
+
+const dataURLPrefix = "data:"
+
+// Content block types used in Messages requests.
+const (
+	blockTypeText       = "text"
+	blockTypeImage      = "image"
+	blockTypeToolUse    = "tool_use"
+	blockTypeToolResult = "tool_result"
+	imageSourceBase64   = "base64"
+	imageSourceURL      = "url"
+	contentPartImageURL = "image_url"
+	contentPartRefusal  = "refusal"
+)
+
+// messagesRequest is the subset of the Messages API request that
+// Chat Completions requests can express.
+type messagesRequest struct {
+	Temperature   *float64          `json:"temperature,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	Metadata      *requestMetadata  `json:"metadata,omitempty"`
+	ToolChoice    *toolChoice       `json:"tool_choice,omitempty"`
+	Model         string            `json:"model"`
+	System        string            `json:"system,omitempty"`
+	Messages      []messagesMessage `json:"messages"`
+	Tools         []messagesTool    `json:"tools,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	MaxTokens     int64             `json:"max_tokens"`
+	Stream        bool              `json:"stream,omitempty"`
+}
+
+type requestMetadata struct {
+	UserID string `json:"user_id"`
+}
+
+type toolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type messagesTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type messagesMessage struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a Messages content block; only the fields for its Type are set.
+type contentBlock struct {
+	Source    *imageSource    `json:"source,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+var emptyObjectSchema = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// ToAnthropicRequest converts a Chat Completions request body into a Messages request body.
+// System and developer messages become the system prompt, tool calls and tool
+// results become tool_use/tool_result blocks, and image_url parts become image blocks.
+// Consecutive messages that map to the same role are merged, as the Messages API expects
+// alternating turns.
+func ToAnthropicRequest(body []byte) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("%w: body is not valid JSON", ErrInvalidRequest)
+	}
+	parsed := gjson.ParseBytes(body)
+	if !parsed.IsObject() {
+		return nil, fmt.Errorf("%w: body must be a JSON object", ErrInvalidRequest)
+	}
+	if n := parsed.Get("n"); n.Exists() && n.Int() > 1 {
+		return nil, fmt.Errorf("%w: n > 1 is not supported", ErrInvalidRequest)
+	}
+
+	system, messages, err := convertMessages(parsed.Get("messages"))
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := convertTools(parsed.Get("tools"))
+	if err != nil {
+		return nil, err
+	}
+
+	request := messagesRequest{
+		Temperature:   optionalFloat(parsed.Get("temperature"), maxTemperature),
+		TopP:          optionalFloat(parsed.Get("top_p"), 1),
+		Metadata:      convertUser(parsed.Get("user")),
+		ToolChoice:    convertToolChoice(parsed.Get("tool_choice"), parsed.Get("parallel_tool_calls")),
+		Model:         parsed.Get("model").String(),
+		System:        system,
+		Messages:      messages,
+		Tools:         tools,
+		StopSequences: convertStop(parsed.Get("stop")),
+		MaxTokens:     convertMaxTokens(parsed),
+		Stream:        parsed.Get("stream").Bool(),
+	}
+
+	return json.Marshal(request)
+}
+
+// convertMessages splits system prompts from the conversation and converts each turn.
+func convertMessages(list gjson.Result) (system string, messages []messagesMessage, err error) {
+	if !list.IsArray() || len(list.Array()) == 0 {
+		return "", nil, fmt.Errorf("%w: messages must be a non-empty array", ErrInvalidRequest)
+	}
+
+	var systemParts []string
+	for idx, msg := range list.Array() {
+		role := msg.Get("role").String()
+		if role == RoleSystem || role == RoleDeveloper {
+			if text := contentText(msg.Get("content")); text != "" {
+				systemParts = append(systemParts, text)
+			}
+			continue
+		}
+
+		converted, convErr := convertMessage(role, msg)
+		if convErr != nil {
+			return "", nil, fmt.Errorf("messages[%d]: %w", idx, convErr)
+		}
+		if len(converted.Content) == 0 {
+			continue
+		}
+		messages = appendMerged(messages, converted)
+	}
+
+	if len(messages) == 0 {
+		return "", nil, fmt.Errorf("%w: messages must contain a user or assistant message", ErrInvalidRequest)
+	}
+	return strings.Join(systemParts, "\n\n"), messages, nil
+}
+
+// appendMerged appends msg, folding it into the previous turn when the roles match.
+func appendMerged(messages []messagesMessage, msg messagesMessage) []messagesMessage {
+	if last := len(messages) - 1; last >= 0 && messages[last].Role == msg.Role {
+		messages[last].Content = append(messages[last].Content, msg.Content...)
+		return messages
+	}
+	return append(messages, msg)
+}
+
+func convertMessage(role string, msg gjson.Result) (messagesMessage, error) {
+	switch role {
+	case RoleUser:
+		blocks, err := convertUserContent(msg.Get("content"))
+		return messagesMessage{Role: RoleUser, Content: blocks}, err
+	case RoleAssistant:
+		return messagesMessage{Role: RoleAssistant, Content: convertAssistantContent(msg)}, nil
+	case RoleTool:
+		block := contentBlock{
+			Source:    nil,
+			Type:      blockTypeToolResult,
+			Text:      "",
+			ID:        "",
+			Name:      "",
+			ToolUseID: msg.Get("tool_call_id").String(),
+			Content:   contentText(msg.Get("content")),
+			Input:     nil,
+		}
+		return messagesMessage{Role: RoleUser, Content: []contentBlock{block}}, nil
+	default:
+		return messagesMessage{Role: "", Content: nil}, fmt.Errorf("%w: unsupported role %q", ErrInvalidRequest, role)
+	}
+}
+
+// convertUserContent handles string content and text/image_url content parts.
+func convertUserContent(content gjson.Result) ([]contentBlock, error) {
+	if !content.IsArray() {
+		return textBlocks(content.String()), nil
+	}
+
+	blocks := make([]contentBlock, 0, len(content.Array()))
+	for _, part := range content.Array() {
+		switch partType := part.Get("type").String(); partType {
+		case blockTypeText:
+			blocks = append(blocks, textBlocks(part.Get("text").String())...)
+		case contentPartImageURL:
+			blocks = append(blocks, imageBlock(part.Get("image_url.url").String()))
+		default:
+			return nil, fmt.Errorf("%w: unsupported content part type %q", ErrInvalidRequest, partType)
+		}
+	}
+	return blocks, nil
+}
+
+// convertAssistantContent converts assistant text followed by any tool calls.
+func convertAssistantContent(msg gjson.Result) []contentBlock {
+	blocks := textBlocks(contentText(msg.Get("content")))
+
+	for _, call := range msg.Get("tool_calls").Array() {
+		input := json.RawMessage(call.Get("function.arguments").String())
+		if !json.Valid(input) || !gjson.ParseBytes(input).IsObject() {
+			input = json.RawMessage(`{}`)
+		}
+		blocks = append(blocks, contentBlock{
+			Source:    nil,
+			Type:      blockTypeToolUse,
+			Text:      "",
+			ID:        call.Get("id").String(),
+			Name:      call.Get("function.name").String(),
+			ToolUseID: "",
+			Content:   "",
+			Input:     input,
+		})
+	}
+	return blocks
+}
+
+// contentText flattens string content or the text and refusal parts of array content.
+func contentText(content gjson.Result) string {
+	if !content.IsArray() {
+		return content.String()
+	}
+	var parts []string
+	for _, part := range content.Array() {
+		switch part.Get("type").String() {
+		case blockTypeText:
+			parts = append(parts, part.Get("text").String())
+		case contentPartRefusal:
+			parts = append(parts, part.Get("refusal").String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// textBlocks returns a single text block, or none for empty text,
+// which the Messages API rejects.
+func textBlocks(text string) []contentBlock {
+	if text == "" {
+		return nil
+	}
+	return []contentBlock{{
+		Source:    nil,
+		Type:      blockTypeText,
+		Text:      text,
+		ID:        "",
+		Name:      "",
+		ToolUseID: "",
+		Content:   "",
+		Input:     nil,
+	}}
+}
+
+// imageBlock converts an image_url (data URL or remote URL) into an image block.
+func imageBlock(url string) contentBlock {
+	source := &imageSource{Type: imageSourceURL, MediaType: "", Data: "", URL: url}
+
+	if rest, ok := strings.CutPrefix(url, dataURLPrefix); ok {
+		if meta, data, found := strings.Cut(rest, ","); found {
+			mediaType, _, _ := strings.Cut(meta, ";")
+			source = &imageSource{Type: imageSourceBase64, MediaType: mediaType, Data: data, URL: ""}
+		}
+	}
+
+	return contentBlock{
+		Source:    source,
+		Type:      blockTypeImage,
+		Text:      "",
+		ID:        "",
+		Name:      "",
+		ToolUseID: "",
+		Content:   "",
+		Input:     nil,
+	}
+}
+
+func convertTools(tools gjson.Result) ([]messagesTool, error) {
+	if !tools.IsArray() {
+		return nil, nil
+	}
+
+	converted := make([]messagesTool, 0, len(tools.Array()))
+	for idx, tool := range tools.Array() {
+		if toolType := tool.Get("type").String(); toolType != toolTypeFunction {
+			return nil, fmt.Errorf("%w: tools[%d]: unsupported tool type %q", ErrInvalidRequest, idx, toolType)
+		}
+		schema := emptyObjectSchema
+		if params := tool.Get("function.parameters"); params.IsObject() {
+			schema = json.RawMessage(params.Raw)
+		}
+		converted = append(converted, messagesTool{
+			Name:        tool.Get("function.name").String(),
+			Description: tool.Get("function.description").String(),
+			InputSchema: schema,
+		})
+	}
+	return converted, nil
+}
+
+// convertToolChoice maps "none"/"auto"/"required" and named functions onto Messages tool_choice.
+// parallel_tool_calls=false is expressed as disable_parallel_tool_use.
+func convertToolChoice(choice, parallel gjson.Result) *toolChoice {
+	disableParallel := parallel.Exists() && !parallel.Bool()
+
+	var converted *toolChoice
+	switch {
+	case choice.IsObject():
+		converted = &toolChoice{Type: "tool", Name: choice.Get("function.name").String(), DisableParallelToolUse: false}
+	case choice.String() == "required":
+		converted = &toolChoice{Type: "any", Name: "", DisableParallelToolUse: false}
+	case choice.String() == "none":
+		converted = &toolChoice{Type: "none", Name: "", DisableParallelToolUse: false}
+	case choice.String() == "auto" || disableParallel:
+		converted = &toolChoice{Type: "auto", Name: "", DisableParallelToolUse: false}
+	default:
+		return nil
+	}
+
+	if converted.Type != "none" {
+		converted.DisableParallelToolUse = disableParallel
+	}
+	return converted
+}
+
+// convertMaxTokens prefers max_completion_tokens over the deprecated max_tokens.
+func convertMaxTokens(parsed gjson.Result) int64 {
+	if limit := parsed.Get("max_completion_tokens"); limit.Exists() && limit.Int() > 0 {
+		return limit.Int()
+	}
+	if limit := parsed.Get("max_tokens"); limit.Exists() && limit.Int() > 0 {
+		return limit.Int()
+	}
+	return DefaultMaxTokens
+}
+
+// convertStop accepts stop as a single string or an array of strings.
+func convertStop(stop gjson.Result) []string {
+	if !stop.IsArray() {
+		if stop.String() == "" {
+			return nil
+		}
+		return []string{stop.String()}
+	}
+	sequences := make([]string, 0, len(stop.Array()))
+	for _, seq := range stop.Array() {
+		if seq.String() != "" {
+			sequences = append(sequences, seq.String())
+		}
+	}
+	return sequences
+}
+
+func convertUser(user gjson.Result) *requestMetadata {
+	if user.String() == "" {
+		return nil
+	}
+	return &requestMetadata{UserID: user.String()}
+}
+
+// optionalFloat returns value clamped to upper, or nil when it is absent.
+func optionalFloat(value gjson.Result, upper float64) *float64 {
+	if value.Type != gjson.Number {
+		return nil
+	}
+	clamped := min(value.Float(), upper)
+	return &clamped
+}
@@ -0,0 +1,212 @@
+package openai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+func TestToAnthropicRequestBasic(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"model": "claude-sonnet-4",
+		"messages": [
+			{"role": "system", "content": "Be terse."},
+			{"role": "developer", "content": [{"type": "text", "text": "Use English."}]},
+			{"role": "user", "content": "Hello"}
+		],
+		"max_tokens": 100,
+		"temperature": 1.5,
+		"top_p": 0.9,
+		"stop": "END",
+		"stream": true,
+		"user": "alice"
+	}`
+
+	got, err := openai.ToAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"model": "claude-sonnet-4",
+		"system": "Be terse.\n\nUse English.",
+		"messages": [{"role": "user", "content": [{"type": "text", "text": "Hello"}]}],
+		"max_tokens": 100,
+		"temperature": 1,
+		"top_p": 0.9,
+		"stop_sequences": ["END"],
+		"stream": true,
+		"metadata": {"user_id": "alice"}
+	}`, string(got))
+}
+
+func TestToAnthropicRequestMaxTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "default when absent",
+			body: `{"model":"m","messages":[{"role":"user","content":"hi"}]}`,
+			want: "4096",
+		},
+		{
+			name: "max_completion_tokens wins",
+			body: `{"model":"m","max_tokens":10,"max_completion_tokens":20,"messages":[{"role":"user","content":"hi"}]}`,
+			want: "20",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := openai.ToAnthropicRequest([]byte(testCase.body))
+			require.NoError(t, err)
+			assert.Contains(t, string(got), `"max_tokens":`+testCase.want)
+		})
+	}
+}
+
+func TestToAnthropicRequestToolConversation(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"model": "claude-sonnet-4",
+		"messages": [
+			{"role": "user", "content": "Weather in Paris and Rome?"},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "weather", "arguments": "not json"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"},
+			{"role": "tool", "tool_call_id": "call_2", "content": [{"type": "text", "text": "Rain"}]},
+			{"role": "user", "content": "Thanks"}
+		],
+		"tools": [
+			{"type": "function", "function": {"name": "weather", "description": "Get weather",
+				"parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}},
+			{"type": "function", "function": {"name": "noop"}}
+		],
+		"tool_choice": "required",
+		"parallel_tool_calls": false
+	}`
+
+	got, err := openai.ToAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"model": "claude-sonnet-4",
+		"max_tokens": 4096,
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "Weather in Paris and Rome?"}]},
+			{"role": "assistant", "content": [
+				{"type": "tool_use", "id": "call_1", "name": "weather", "input": {"city": "Paris"}},
+				{"type": "tool_use", "id": "call_2", "name": "weather", "input": {}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": "Sunny"},
+				{"type": "tool_result", "tool_use_id": "call_2", "content": "Rain"},
+				{"type": "text", "text": "Thanks"}
+			]}
+		],
+		"tools": [
+			{"name": "weather", "description": "Get weather",
+				"input_schema": {"type": "object", "properties": {"city": {"type": "string"}}}},
+			{"name": "noop", "input_schema": {"type": "object", "properties": {}}}
+		],
+		"tool_choice": {"type": "any", "disable_parallel_tool_use": true}
+	}`, string(got))
+}
+
+func TestToAnthropicRequestToolChoice(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		fields string
+		want   string
+	}{
+		{name: "none", fields: `"tool_choice":"none"`, want: `{"type":"none"}`},
+		{name: "auto", fields: `"tool_choice":"auto"`, want: `{"type":"auto"}`},
+		{
+			name:   "named function",
+			fields: `"tool_choice":{"type":"function","function":{"name":"weather"}}`,
+			want:   `{"type":"tool","name":"weather"}`,
+		},
+		{
+			name:   "parallel disabled without choice",
+			fields: `"parallel_tool_calls":false`,
+			want:   `{"type":"auto","disable_parallel_tool_use":true}`,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			body := `{"model":"m","messages":[{"role":"user","content":"hi"}],` + testCase.fields + `}`
+			got, err := openai.ToAnthropicRequest([]byte(body))
+			require.NoError(t, err)
+			assert.Contains(t, string(got), `"tool_choice":`+testCase.want)
+		})
+	}
+}
+
+func TestToAnthropicRequestImages(t *testing.T) {
+	t.Parallel()
+
+	body := `{"model": "m", "messages": [{"role": "user", "content": [
+		{"type": "text", "text": "What is this?"},
+		{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}},
+		{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg", "detail": "high"}}
+	]}]}`
+
+	got, err := openai.ToAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"model": "m", "max_tokens": 4096, "messages": [{"role": "user", "content": [
+		{"type": "text", "text": "What is this?"},
+		{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+		{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.jpg"}}
+	]}]}`, string(got))
+}
+
+func TestToAnthropicRequestInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "not json", body: `{"model":`},
+		{name: "not an object", body: `[]`},
+		{name: "missing messages", body: `{"model":"m"}`},
+		{name: "only system messages", body: `{"model":"m","messages":[{"role":"system","content":"x"}]}`},
+		{name: "unknown role", body: `{"model":"m","messages":[{"role":"function","content":"x"}]}`},
+		{
+			name: "unsupported content part",
+			body: `{"model":"m","messages":[{"role":"user","content":[{"type":"input_audio"}]}]}`,
+		},
+		{
+			name: "unsupported tool type",
+			body: `{"model":"m","messages":[{"role":"user","content":"x"}],"tools":[{"type":"web_search"}]}`,
+		},
+		{name: "multiple choices", body: `{"model":"m","n":2,"messages":[{"role":"user","content":"x"}]}`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := openai.ToAnthropicRequest([]byte(testCase.body))
+			assert.ErrorIs(t, err, openai.ErrInvalidRequest)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// completionIDPrefix is prepended to Messages IDs so they read as Chat Completions IDs.
+const completionIDPrefix = "chatcmpl-"
+
+// FromAnthropicResponse converts a Messages JSON response into a Chat Completions response.
+// Text blocks are concatenated into the message content, tool_use blocks become tool
+// calls, and thinking blocks are dropped since Chat Completions has no place for them.
+func FromAnthropicResponse(body []byte, created int64) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, ErrInvalidResponse
+	}
+	parsed := gjson.ParseBytes(body)
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range parsed.Get("content").Array() {
+		switch block.Get("type").String() {
+		case blockTypeText:
+			text.WriteString(block.Get("text").String())
+		case blockTypeToolUse:
+			toolCalls = append(toolCalls, ToolCall{
+				Index: nil,
+				ID:    block.Get("id").String(),
+				Type:  toolTypeFunction,
+				Function: FunctionCall{
+					Name:      block.Get("name").String(),
+					Arguments: toolArguments(block.Get("input")),
+				},
+			})
+		}
+	}
+
+	var content *string
+	if text.Len() > 0 || len(toolCalls) == 0 {
+		joined := text.String()
+		content = &joined
+	}
+
+	completion := ChatCompletion{
+		Usage:  convertUsage(parsed.Get("usage")),
+		ID:     completionIDPrefix + parsed.Get("id").String(),
+		Object: ObjectChatCompletion,
+		Model:  parsed.Get("model").String(),
+		Choices: []Choice{{
+			Message:      Message{Content: content, Role: RoleAssistant, ToolCalls: toolCalls},
+			FinishReason: FinishReason(parsed.Get("stop_reason").String()),
+			Index:        0,
+		}},
+		Created: created,
+	}
+	return json.Marshal(completion)
+}
+
+// FromAnthropicError converts a Messages error body into an OpenAI error response.
+// Bodies that are not Anthropic errors keep their raw text as the message.
+func FromAnthropicError(statusCode int, body []byte) ErrorResponse {
+	parsed := gjson.ParseBytes(body)
+	errType := parsed.Get("error.type").String()
+	message := parsed.Get("error.message").String()
+
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	if errType == "" {
+		errType = "api_error"
+	}
+	return NewErrorResponse(errType, message)
+}
+
+// FinishReason maps a Messages stop_reason onto a Chat Completions finish_reason.
+func FinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens", "model_context_window_exceeded":
+		return FinishReasonLength
+	case "tool_use":
+		return FinishReasonToolCalls
+	case "refusal":
+		return FinishReasonContentFilter
+	default:
+		return FinishReasonStop
+	}
+}
+
+// convertUsage maps Messages usage onto Chat Completions usage.
+// OpenAI prompt tokens include cached tokens, so cache reads and writes are added to input tokens.
+func convertUsage(usage gjson.Result) *Usage {
+	if !usage.Exists() {
+		return nil
+	}
+	cacheRead := usage.Get("cache_read_input_tokens").Int()
+	prompt := usage.Get("input_tokens").Int() + usage.Get("cache_creation_input_tokens").Int() + cacheRead
+	completion := usage.Get("output_tokens").Int()
+
+	return &Usage{
+		PromptTokensDetails: &PromptTokensDetails{CachedTokens: cacheRead},
+		PromptTokens:        prompt,
+		CompletionTokens:    completion,
+		TotalTokens:         prompt + completion,
+	}
+}
+
+// toolArguments renders a tool_use input object as the JSON string OpenAI expects.
+func toolArguments(input gjson.Result) string {
+	if !input.IsObject() {
+		return "{}"
+	}
+	return input.Raw
+}
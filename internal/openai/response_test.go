@@ -0,0 +1,139 @@
+package openai_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+const testCreated = 1700000000
+
+func TestFromAnthropicResponseText(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4",
+		"content": [
+			{"type": "thinking", "thinking": "hmm", "signature": "sig"},
+			{"type": "text", "text": "Hello"},
+			{"type": "text", "text": " world"}
+		],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 20,
+			"cache_creation_input_tokens": 3}
+	}`
+
+	got, err := openai.FromAnthropicResponse([]byte(body), testCreated)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-msg_1",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "claude-sonnet-4",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello world"},
+			"finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 33, "completion_tokens": 5, "total_tokens": 38,
+			"prompt_tokens_details": {"cached_tokens": 20}}
+	}`, string(got))
+}
+
+func TestFromAnthropicResponseToolUse(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"id": "msg_2", "model": "claude-sonnet-4",
+		"content": [{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}],
+		"stop_reason": "tool_use"
+	}`
+
+	got, err := openai.FromAnthropicResponse([]byte(body), testCreated)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-msg_2",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "claude-sonnet-4",
+		"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+			"role": "assistant", "content": null,
+			"tool_calls": [{"id": "toolu_1", "type": "function",
+				"function": {"name": "weather", "arguments": "{\"city\": \"Paris\"}"}}]
+		}}]
+	}`, string(got))
+}
+
+func TestFromAnthropicResponseInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := openai.FromAnthropicResponse([]byte("not json"), testCreated)
+	assert.ErrorIs(t, err, openai.ErrInvalidResponse)
+}
+
+func TestFinishReason(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"end_turn":      openai.FinishReasonStop,
+		"stop_sequence": openai.FinishReasonStop,
+		"pause_turn":    openai.FinishReasonStop,
+		"max_tokens":    openai.FinishReasonLength,
+		"tool_use":      openai.FinishReasonToolCalls,
+		"refusal":       openai.FinishReasonContentFilter,
+	}
+
+	for stopReason, want := range tests {
+		t.Run(stopReason, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, want, openai.FinishReason(stopReason))
+		})
+	}
+}
+
+func TestFromAnthropicError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		body     string
+		wantType string
+		wantMsg  string
+		status   int
+	}{
+		{
+			name:     "anthropic error",
+			body:     `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`,
+			wantType: "rate_limit_error",
+			wantMsg:  "slow down",
+			status:   http.StatusTooManyRequests,
+		},
+		{
+			name:     "plain text body",
+			body:     "upstream exploded\n",
+			wantType: "api_error",
+			wantMsg:  "upstream exploded",
+			status:   http.StatusBadGateway,
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			wantType: "api_error",
+			wantMsg:  "Service Unavailable",
+			status:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := openai.FromAnthropicError(testCase.status, []byte(testCase.body))
+			assert.Equal(t, testCase.wantType, got.Error.Type)
+			assert.Equal(t, testCase.wantMsg, got.Error.Message)
+		})
+	}
+}
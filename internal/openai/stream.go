@@ -0,0 +1,191 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// StreamDone is the data payload that terminates a Chat Completions stream.
+const StreamDone = "[DONE]"
+
+// Messages streaming event and delta types.
+const (
+	eventMessageStart      = "message_start"
+	eventContentBlockStart = "content_block_start"
+	eventContentBlockDelta = "content_block_delta"
+	eventMessageDelta      = "message_delta"
+	eventMessageStop       = "message_stop"
+	eventError             = "error"
+	deltaTypeText          = "text_delta"
+	deltaTypeInputJSON     = "input_json_delta"
+)
+
+// StreamConverter translates Messages SSE events into Chat Completions chunks.
+// It is stateful and must see every data payload of one stream, in order.
+// A StreamConverter is not safe for concurrent use.
+type StreamConverter struct {
+	toolIndexes  map[int64]int
+	id           string
+	model        string
+	usage        gjson.Result
+	created      int64
+	includeUsage bool
+	done         bool
+}
+
+// NewStreamConverter creates a converter for a single stream.
+// When includeUsage is set (stream_options.include_usage), a final chunk with
+// empty choices and the total usage is emitted before the stream ends.
+func NewStreamConverter(created int64, includeUsage bool) *StreamConverter {
+	return &StreamConverter{
+		toolIndexes:  make(map[int64]int),
+		usage:        gjson.Result{},
+		id:           "",
+		model:        "",
+		created:      created,
+		includeUsage: includeUsage,
+		done:         false,
+	}
+}
+
+// Convert translates one Messages SSE data payload into zero or more chunk payloads.
+// Pings, block stops and thinking deltas produce no output. An error event is
+// converted into an OpenAI error payload.
+func (c *StreamConverter) Convert(payload []byte) ([][]byte, error) {
+	event := gjson.ParseBytes(payload)
+
+	switch event.Get("type").String() {
+	case eventMessageStart:
+		message := event.Get("message")
+		c.id = completionIDPrefix + message.Get("id").String()
+		c.model = message.Get("model").String()
+		c.usage = message.Get("usage")
+		empty := ""
+		return c.chunk(Delta{Content: &empty, Role: RoleAssistant, ToolCalls: nil}, nil)
+
+	case eventContentBlockStart:
+		return c.convertBlockStart(event.Get("index").Int(), event.Get("content_block"))
+
+	case eventContentBlockDelta:
+		return c.convertBlockDelta(event.Get("index").Int(), event.Get("delta"))
+
+	case eventMessageDelta:
+		c.mergeUsage(event.Get("usage"))
+		reason := FinishReason(event.Get("delta.stop_reason").String())
+		return c.chunk(Delta{Content: nil, Role: "", ToolCalls: nil}, &reason)
+
+	case eventMessageStop:
+		c.done = true
+		if !c.includeUsage {
+			return nil, nil
+		}
+		return c.marshal(ChatCompletionChunk{
+			Usage:   convertUsage(c.usage),
+			ID:      c.id,
+			Object:  ObjectChatCompletionChunk,
+			Model:   c.model,
+			Choices: []ChunkChoice{},
+			Created: c.created,
+		})
+
+	case eventError:
+		c.done = true
+		data, err := json.Marshal(FromAnthropicError(http.StatusInternalServerError, payload))
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{data}, nil
+	}
+	return nil, nil
+}
+
+// Done reports whether the stream has ended with message_stop or an error event.
+func (c *StreamConverter) Done() bool {
+	return c.done
+}
+
+func (c *StreamConverter) convertBlockStart(index int64, block gjson.Result) ([][]byte, error) {
+	switch block.Get("type").String() {
+	case blockTypeText:
+		if text := block.Get("text").String(); text != "" {
+			return c.chunk(Delta{Content: &text, Role: "", ToolCalls: nil}, nil)
+		}
+	case blockTypeToolUse:
+		toolIndex := len(c.toolIndexes)
+		c.toolIndexes[index] = toolIndex
+		call := ToolCall{
+			Index:    &toolIndex,
+			ID:       block.Get("id").String(),
+			Type:     toolTypeFunction,
+			Function: FunctionCall{Name: block.Get("name").String(), Arguments: ""},
+		}
+		return c.chunk(Delta{Content: nil, Role: "", ToolCalls: []ToolCall{call}}, nil)
+	}
+	return nil, nil
+}
+
+func (c *StreamConverter) convertBlockDelta(index int64, delta gjson.Result) ([][]byte, error) {
+	switch delta.Get("type").String() {
+	case deltaTypeText:
+		text := delta.Get("text").String()
+		return c.chunk(Delta{Content: &text, Role: "", ToolCalls: nil}, nil)
+	case deltaTypeInputJSON:
+		toolIndex, ok := c.toolIndexes[index]
+		partial := delta.Get("partial_json").String()
+		if !ok || partial == "" {
+			return nil, nil
+		}
+		call := ToolCall{
+			Index:    &toolIndex,
+			ID:       "",
+			Type:     "",
+			Function: FunctionCall{Name: "", Arguments: partial},
+		}
+		return c.chunk(Delta{Content: nil, Role: "", ToolCalls: []ToolCall{call}}, nil)
+	}
+	return nil, nil
+}
+
+// mergeUsage overlays message_delta usage on the usage reported at message_start.
+// message_delta carries cumulative output tokens and, for some providers, final input counts.
+func (c *StreamConverter) mergeUsage(delta gjson.Result) {
+	if !delta.Exists() {
+		return
+	}
+	merged := c.usage.Raw
+	if merged == "" {
+		merged = "{}"
+	}
+	delta.ForEach(func(key, value gjson.Result) bool {
+		if value.Type != gjson.Number {
+			return true
+		}
+		if updated, err := sjson.SetRaw(merged, key.String(), value.Raw); err == nil {
+			merged = updated
+		}
+		return true
+	})
+	c.usage = gjson.Parse(merged)
+}
+
+func (c *StreamConverter) chunk(delta Delta, finishReason *string) ([][]byte, error) {
+	return c.marshal(ChatCompletionChunk{
+		Usage:   nil,
+		ID:      c.id,
+		Object:  ObjectChatCompletionChunk,
+		Model:   c.model,
+		Choices: []ChunkChoice{{FinishReason: finishReason, Delta: delta, Index: 0}},
+		Created: c.created,
+	})
+}
+
+func (c *StreamConverter) marshal(chunk ChatCompletionChunk) ([][]byte, error) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{data}, nil
+}
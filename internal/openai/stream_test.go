@@ -0,0 +1,106 @@
+package openai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+// convertAll feeds each payload to the converter and collects the emitted chunks.
+func convertAll(t *testing.T, converter *openai.StreamConverter, payloads ...string) []string {
+	t.Helper()
+
+	var chunks []string
+	for _, payload := range payloads {
+		out, err := converter.Convert([]byte(payload))
+		require.NoError(t, err)
+		for _, chunk := range out {
+			chunks = append(chunks, string(chunk))
+		}
+	}
+	return chunks
+}
+
+func TestStreamConverterText(t *testing.T) {
+	t.Parallel()
+
+	converter := openai.NewStreamConverter(testCreated, false)
+	chunks := convertAll(t, converter,
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4","usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+	)
+	assert.False(t, converter.Done())
+
+	rest := convertAll(t, converter, `{"type":"message_stop"}`)
+	assert.Empty(t, rest, "usage chunk is only sent when requested")
+	assert.True(t, converter.Done())
+
+	require.Len(t, chunks, 3)
+	const prefix = `{"id":"chatcmpl-msg_1","object":"chat.completion.chunk","model":"claude-sonnet-4",`
+	assert.JSONEq(t, prefix+`"choices":[{"index":0,"delta":{"role":"assistant","content":""},`+
+		`"finish_reason":null}],"created":1700000000}`, chunks[0])
+	assert.JSONEq(t, prefix+`"choices":[{"index":0,"delta":{"content":"Hi"},`+
+		`"finish_reason":null}],"created":1700000000}`, chunks[1])
+	assert.JSONEq(t, prefix+`"choices":[{"index":0,"delta":{},`+
+		`"finish_reason":"stop"}],"created":1700000000}`, chunks[2])
+}
+
+func TestStreamConverterToolCalls(t *testing.T) {
+	t.Parallel()
+
+	converter := openai.NewStreamConverter(testCreated, false)
+	chunks := convertAll(t, converter,
+		`{"type":"message_start","message":{"id":"msg_1","model":"m"}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"a"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"x\":1}"}}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"b"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`,
+	)
+
+	require.Len(t, chunks, 5)
+	assert.Contains(t, chunks[1],
+		`"tool_calls":[{"index":0,"id":"toolu_1","type":"function","function":{"name":"a","arguments":""}}]`)
+	assert.Contains(t, chunks[2], `"tool_calls":[{"index":0,"function":{"arguments":"{\"x\":1}"}}]`)
+	assert.Contains(t, chunks[3], `"tool_calls":[{"index":1,"id":"toolu_2"`)
+	assert.Contains(t, chunks[4], `"finish_reason":"tool_calls"`)
+}
+
+func TestStreamConverterIncludeUsage(t *testing.T) {
+	t.Parallel()
+
+	converter := openai.NewStreamConverter(testCreated, true)
+	chunks := convertAll(t, converter,
+		`{"type":"message_start","message":{"id":"msg_1","model":"m",`+
+			`"usage":{"input_tokens":10,"cache_read_input_tokens":4,"output_tokens":1}}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	)
+
+	require.Len(t, chunks, 3)
+	assert.JSONEq(t, `{"id":"chatcmpl-msg_1","object":"chat.completion.chunk","model":"m","choices":[],`+
+		`"created":1700000000,"usage":{"prompt_tokens":14,"completion_tokens":7,"total_tokens":21,`+
+		`"prompt_tokens_details":{"cached_tokens":4}}}`, chunks[2])
+}
+
+func TestStreamConverterError(t *testing.T) {
+	t.Parallel()
+
+	converter := openai.NewStreamConverter(testCreated, false)
+	chunks := convertAll(t, converter,
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+
+	require.Len(t, chunks, 1)
+	assert.JSONEq(t, `{"error":{"message":"Overloaded","type":"overloaded_error","param":null,"code":null}}`,
+		chunks[0])
+	assert.True(t, converter.Done())
+}
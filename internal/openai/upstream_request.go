@@ -0,0 +1,295 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// chatRequest is a Chat Completions request sent to an OpenAI-compatible backend.
+type chatRequest struct {
+	Temperature       *float64        `json:"temperature,omitempty"`
+	TopP              *float64        `json:"top_p,omitempty"`
+	StreamOptions     *streamOptions  `json:"stream_options,omitempty"`
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+	ToolChoice        json.RawMessage `json:"tool_choice,omitempty"`
+	Model             string          `json:"model"`
+	User              string          `json:"user,omitempty"`
+	Messages          []chatMessage   `json:"messages"`
+	Tools             []chatTool      `json:"tools,omitempty"`
+	Stop              []string        `json:"stop,omitempty"`
+	MaxTokens         int64           `json:"max_tokens,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatMessage is a Chat Completions message. Content is a string, a slice of
+// content parts, or nil for assistant turns that only carry tool calls.
+type chatMessage struct {
+	Content    any        `json:"content"`
+	Role       string     `json:"role"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+}
+
+type chatContentPart struct {
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// FromAnthropicRequest converts a Messages request body into a Chat Completions
+// request body for an OpenAI-compatible backend.
+//
+// The system prompt becomes a leading system message, tool_use blocks become
+// tool_calls and tool_result blocks become tool messages. Thinking blocks and
+// the thinking configuration are dropped: Chat Completions has no way to send
+// reasoning back to the model. Streaming requests ask for usage in the final
+// chunk so token accounting keeps working.
+func FromAnthropicRequest(body []byte) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("%w: body is not valid JSON", ErrInvalidMessagesRequest)
+	}
+	parsed := gjson.ParseBytes(body)
+
+	messages := make([]chatMessage, 0, len(parsed.Get("messages").Array())+1)
+	if system := systemText(parsed.Get("system")); system != "" {
+		messages = append(messages, chatMessage{Content: system, Role: RoleSystem, ToolCallID: "", ToolCalls: nil})
+	}
+	for _, msg := range parsed.Get("messages").Array() {
+		if msg.Get("role").String() == RoleAssistant {
+			messages = append(messages, assistantMessage(msg.Get("content")))
+			continue
+		}
+		messages = append(messages, userMessages(msg.Get("content"))...)
+	}
+
+	tools := chatTools(parsed.Get("tools"))
+	stream := parsed.Get("stream").Bool()
+
+	request := chatRequest{
+		Temperature:       optionalFloat(parsed.Get("temperature"), maxTemperature),
+		TopP:              optionalFloat(parsed.Get("top_p"), 1),
+		StreamOptions:     nil,
+		ParallelToolCalls: nil,
+		ToolChoice:        nil,
+		Model:             parsed.Get("model").String(),
+		User:              parsed.Get("metadata.user_id").String(),
+		Messages:          messages,
+		Tools:             tools,
+		Stop:              stringArray(parsed.Get("stop_sequences")),
+		MaxTokens:         parsed.Get("max_tokens").Int(),
+		Stream:            stream,
+	}
+	if stream {
+		request.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	if len(tools) > 0 {
+		request.ToolChoice, request.ParallelToolCalls = chatToolChoice(parsed.Get("tool_choice"))
+	}
+
+	return json.Marshal(request)
+}
+
+// systemText flattens a string or text-block system prompt.
+func systemText(system gjson.Result) string {
+	if !system.IsArray() {
+		return system.String()
+	}
+	var parts []string
+	for _, block := range system.Array() {
+		if block.Get("type").String() == blockTypeText {
+			parts = append(parts, block.Get("text").String())
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// assistantMessage converts assistant text and tool_use blocks.
+func assistantMessage(content gjson.Result) chatMessage {
+	msg := chatMessage{Content: nil, Role: RoleAssistant, ToolCallID: "", ToolCalls: nil}
+	if !content.IsArray() {
+		msg.Content = content.String()
+		return msg
+	}
+
+	var text strings.Builder
+	for _, block := range content.Array() {
+		switch block.Get("type").String() {
+		case blockTypeText:
+			text.WriteString(block.Get("text").String())
+		case blockTypeToolUse:
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				Index: nil,
+				ID:    block.Get("id").String(),
+				Type:  toolTypeFunction,
+				Function: FunctionCall{
+					Name:      block.Get("name").String(),
+					Arguments: toolArguments(block.Get("input")),
+				},
+			})
+		}
+	}
+	if text.Len() > 0 || len(msg.ToolCalls) == 0 {
+		msg.Content = text.String()
+	}
+	return msg
+}
+
+// userMessages converts a user turn. Tool results become tool messages, which
+// must directly follow the assistant tool calls, so they are emitted first.
+func userMessages(content gjson.Result) []chatMessage {
+	if !content.IsArray() {
+		return []chatMessage{{Content: content.String(), Role: RoleUser, ToolCallID: "", ToolCalls: nil}}
+	}
+
+	var messages []chatMessage
+	var parts []chatContentPart
+	for _, block := range content.Array() {
+		switch block.Get("type").String() {
+		case blockTypeToolResult:
+			messages = append(messages, chatMessage{
+				Content:    toolResultText(block),
+				Role:       RoleTool,
+				ToolCallID: block.Get("tool_use_id").String(),
+				ToolCalls:  nil,
+			})
+		case blockTypeText:
+			parts = append(parts, chatContentPart{ImageURL: nil, Type: blockTypeText, Text: block.Get("text").String()})
+		case blockTypeImage:
+			if url := imageURL(block.Get("source")); url != "" {
+				parts = append(parts, chatContentPart{
+					ImageURL: &chatImageURL{URL: url},
+					Type:     contentPartImageURL,
+					Text:     "",
+				})
+			}
+		}
+	}
+
+	if len(parts) > 0 {
+		messages = append(messages, chatMessage{Content: userContent(parts), Role: RoleUser, ToolCallID: "", ToolCalls: nil})
+	}
+	return messages
+}
+
+// userContent collapses text-only content to a plain string, which every
+// OpenAI-compatible server accepts, and keeps content parts for images.
+func userContent(parts []chatContentPart) any {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != blockTypeText {
+			return parts
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// toolResultText flattens tool_result content. Tool messages are text-only,
+// so images returned by tools are dropped.
+func toolResultText(block gjson.Result) string {
+	text := contentText(block.Get("content"))
+	if block.Get("is_error").Bool() && text != "" {
+		return "Error: " + text
+	}
+	return text
+}
+
+// imageURL converts a base64 or URL image source into an image_url value.
+func imageURL(source gjson.Result) string {
+	switch source.Get("type").String() {
+	case imageSourceBase64:
+		return dataURLPrefix + source.Get("media_type").String() + ";base64," + source.Get("data").String()
+	case imageSourceURL:
+		return source.Get("url").String()
+	default:
+		return ""
+	}
+}
+
+// chatTools converts client tools. Server tools (web search, code execution)
+// have no input_schema and cannot run on an OpenAI-compatible backend.
+func chatTools(tools gjson.Result) []chatTool {
+	var converted []chatTool
+	for _, tool := range tools.Array() {
+		schema := tool.Get("input_schema")
+		if !schema.IsObject() {
+			continue
+		}
+		converted = append(converted, chatTool{
+			Type: toolTypeFunction,
+			Function: chatFunction{
+				Name:        tool.Get("name").String(),
+				Description: tool.Get("description").String(),
+				Parameters:  json.RawMessage(schema.Raw),
+			},
+		})
+	}
+	return converted
+}
+
+// chatToolChoice maps Messages tool_choice onto tool_choice and parallel_tool_calls.
+func chatToolChoice(choice gjson.Result) (toolChoice json.RawMessage, parallelToolCalls *bool) {
+	if choice.Get("disable_parallel_tool_use").Bool() {
+		parallel := false
+		parallelToolCalls = &parallel
+	}
+
+	switch choice.Get("type").String() {
+	case "auto":
+		return json.RawMessage(`"auto"`), parallelToolCalls
+	case "any":
+		return json.RawMessage(`"required"`), parallelToolCalls
+	case "none":
+		return json.RawMessage(`"none"`), nil
+	case "tool":
+		named, err := json.Marshal(chatNamedToolChoice{
+			Type:     toolTypeFunction,
+			Function: chatFunctionName{Name: choice.Get("name").String()},
+		})
+		if err != nil {
+			return nil, parallelToolCalls
+		}
+		return named, parallelToolCalls
+	default:
+		return nil, parallelToolCalls
+	}
+}
+
+type chatNamedToolChoice struct {
+	Type     string           `json:"type"`
+	Function chatFunctionName `json:"function"`
+}
+
+type chatFunctionName struct {
+	Name string `json:"name"`
+}
+
+func stringArray(values gjson.Result) []string {
+	var out []string
+	for _, value := range values.Array() {
+		out = append(out, value.String())
+	}
+	return out
+}
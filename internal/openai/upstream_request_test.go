@@ -0,0 +1,140 @@
+package openai_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+func TestFromAnthropicRequestBasic(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"model": "deepseek-chat",
+		"system": [{"type": "text", "text": "Be terse."}, {"type": "text", "text": "Use English."}],
+		"messages": [{"role": "user", "content": "Hello"}],
+		"max_tokens": 100,
+		"temperature": 0.5,
+		"stop_sequences": ["END"],
+		"stream": true,
+		"thinking": {"type": "enabled", "budget_tokens": 1024},
+		"metadata": {"user_id": "alice"}
+	}`
+
+	got, err := openai.FromAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"model": "deepseek-chat",
+		"messages": [
+			{"role": "system", "content": "Be terse.\n\nUse English."},
+			{"role": "user", "content": "Hello"}
+		],
+		"max_tokens": 100,
+		"temperature": 0.5,
+		"stop": ["END"],
+		"stream": true,
+		"stream_options": {"include_usage": true},
+		"user": "alice"
+	}`, string(got))
+}
+
+func TestFromAnthropicRequestToolConversation(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"model": "m",
+		"max_tokens": 10,
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "call the tool", "signature": "sig"},
+				{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": [{"type": "text", "text": "Sunny"}]},
+				{"type": "text", "text": "Thanks"}
+			]}
+		],
+		"tools": [
+			{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}},
+			{"type": "web_search_20250305", "name": "web_search"}
+		],
+		"tool_choice": {"type": "tool", "name": "get_weather", "disable_parallel_tool_use": true}
+	}`
+
+	got, err := openai.FromAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"model": "m",
+		"max_tokens": 10,
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "Sunny"},
+			{"role": "user", "content": "Thanks"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "description": "Weather",
+			"parameters": {"type": "object"}}}],
+		"tool_choice": {"type": "function", "function": {"name": "get_weather"}},
+		"parallel_tool_calls": false
+	}`, string(got))
+}
+
+func TestFromAnthropicRequestImages(t *testing.T) {
+	t.Parallel()
+
+	body := `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[
+		{"type":"text","text":"Compare"},
+		{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},
+		{"type":"image","source":{"type":"url","url":"https://example.com/cat.jpg"}}
+	]}]}`
+
+	got, err := openai.FromAnthropicRequest([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[
+		{"type":"text","text":"Compare"},
+		{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},
+		{"type":"image_url","image_url":{"url":"https://example.com/cat.jpg"}}
+	]}]}`, string(got))
+}
+
+func TestFromAnthropicRequestToolChoice(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		choice string
+		want   string
+	}{
+		{name: "auto", choice: `{"type":"auto"}`, want: `"auto"`},
+		{name: "any", choice: `{"type":"any"}`, want: `"required"`},
+		{name: "none", choice: `{"type":"none"}`, want: `"none"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			body := `{"model":"m","max_tokens":1,"messages":[{"role":"user","content":"hi"}],` +
+				`"tools":[{"name":"a","input_schema":{"type":"object"}}],"tool_choice":` + tt.choice + `}`
+			got, err := openai.FromAnthropicRequest([]byte(body))
+			require.NoError(t, err)
+			assert.Contains(t, string(got), `"tool_choice":`+tt.want)
+		})
+	}
+}
+
+func TestFromAnthropicRequestInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := openai.FromAnthropicRequest([]byte(`{"model":`))
+	require.ErrorIs(t, err, openai.ErrInvalidMessagesRequest)
+}
@@ -0,0 +1,234 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	messageIDPrefix = "msg_"
+	blockTypeThink  = "thinking"
+)
+
+// Messages stop reasons produced from Chat Completions finish reasons.
+const (
+	stopReasonEndTurn   = "end_turn"
+	stopReasonMaxTokens = "max_tokens"
+	stopReasonToolUse   = "tool_use"
+	stopReasonRefusal   = "refusal"
+)
+
+// messagesResponse is a non-streaming Messages response.
+type messagesResponse struct {
+	StopReason   *string         `json:"stop_reason"`
+	StopSequence *string         `json:"stop_sequence"`
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Role         string          `json:"role"`
+	Model        string          `json:"model"`
+	Content      []responseBlock `json:"content"`
+	Usage        messagesUsage   `json:"usage"`
+}
+
+// responseBlock is a content block in a Messages response. Fields are tagged
+// so that each block type only carries its own members.
+type responseBlock struct {
+	Signature *string         `json:"signature,omitempty"`
+	Thinking  *string         `json:"thinking,omitempty"`
+	Text      *string         `json:"text,omitempty"`
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+type messagesUsage struct {
+	InputTokens          int64 `json:"input_tokens"`
+	CacheReadInputTokens int64 `json:"cache_read_input_tokens"`
+	OutputTokens         int64 `json:"output_tokens"`
+}
+
+// ToAnthropicResponse converts a Chat Completions response body into a Messages
+// response body. Only the first choice is used. Reasoning returned by
+// DeepSeek-style servers (reasoning_content or reasoning) becomes a thinking
+// block without a signature.
+func ToAnthropicResponse(body []byte) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, ErrInvalidChatResponse
+	}
+	parsed := gjson.ParseBytes(body)
+	choice := parsed.Get("choices.0")
+	message := choice.Get("message")
+
+	var content []responseBlock
+	if reasoning := reasoningText(message); reasoning != "" {
+		signature := ""
+		content = append(content, thinkingBlock(reasoning, &signature))
+	}
+	if text := message.Get("content").String(); text != "" {
+		content = append(content, textBlock(text))
+	}
+	for _, call := range message.Get("tool_calls").Array() {
+		content = append(content, toolUseBlock(call))
+	}
+	if content == nil {
+		content = []responseBlock{}
+	}
+
+	stopReason := StopReason(choice.Get("finish_reason").String())
+	return json.Marshal(messagesResponse{
+		StopReason:   &stopReason,
+		StopSequence: nil,
+		ID:           messageID(parsed.Get("id").String()),
+		Type:         "message",
+		Role:         RoleAssistant,
+		Model:        parsed.Get("model").String(),
+		Content:      content,
+		Usage:        messagesUsageFrom(parsed.Get("usage")),
+	})
+}
+
+// ToAnthropicError converts an error body from an OpenAI-compatible server into
+// a Messages error body. The error type is derived from the status code, since
+// OpenAI error types do not match Anthropic ones.
+func ToAnthropicError(statusCode int, body []byte) []byte {
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		// Some servers (vLLM, LM Studio) return {"error":"..."} or plain text.
+		message = gjson.GetBytes(body, "error").String()
+	}
+	if message == "" || !gjson.ValidBytes(body) {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	// Marshaling a struct of strings cannot fail.
+	out, _ := json.Marshal(anthropicError{
+		Type:  "error",
+		Error: anthropicErrorDetail{Type: ErrorType(statusCode), Message: message},
+	})
+	return out
+}
+
+type anthropicError struct {
+	Type  string               `json:"type"`
+	Error anthropicErrorDetail `json:"error"`
+}
+
+type anthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ErrorType maps an HTTP status code onto the Messages API error type.
+func ErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// StopReason maps a Chat Completions finish_reason onto a Messages stop_reason.
+func StopReason(finishReason string) string {
+	switch finishReason {
+	case FinishReasonLength:
+		return stopReasonMaxTokens
+	case FinishReasonToolCalls, "function_call":
+		return stopReasonToolUse
+	case FinishReasonContentFilter:
+		return stopReasonRefusal
+	default:
+		return stopReasonEndTurn
+	}
+}
+
+// reasoningText returns the reasoning a server attached to a message or delta.
+func reasoningText(message gjson.Result) string {
+	if reasoning := message.Get("reasoning_content").String(); reasoning != "" {
+		return reasoning
+	}
+	return message.Get("reasoning").String()
+}
+
+// messagesUsageFrom maps Chat Completions usage onto Messages usage.
+// OpenAI prompt tokens include cached tokens, which Messages reports separately.
+func messagesUsageFrom(usage gjson.Result) messagesUsage {
+	cached := usage.Get("prompt_tokens_details.cached_tokens").Int()
+	return messagesUsage{
+		InputTokens:          max(usage.Get("prompt_tokens").Int()-cached, 0),
+		CacheReadInputTokens: cached,
+		OutputTokens:         usage.Get("completion_tokens").Int(),
+	}
+}
+
+// messageID derives a Messages ID from a completion ID.
+func messageID(id string) string {
+	if strings.HasPrefix(id, messageIDPrefix) {
+		return id
+	}
+	return messageIDPrefix + strings.TrimPrefix(id, completionIDPrefix)
+}
+
+func textBlock(text string) responseBlock {
+	return responseBlock{
+		Input:     nil,
+		Signature: nil,
+		Thinking:  nil,
+		Text:      &text,
+		Type:      blockTypeText,
+		ID:        "",
+		Name:      "",
+	}
+}
+
+func thinkingBlock(thinking string, signature *string) responseBlock {
+	return responseBlock{
+		Input:     nil,
+		Signature: signature,
+		Thinking:  &thinking,
+		Text:      nil,
+		Type:      blockTypeThink,
+		ID:        "",
+		Name:      "",
+	}
+}
+
+func toolUseBlock(call gjson.Result) responseBlock {
+	return responseBlock{
+		Input:     toolInput(call.Get("function.arguments").String()),
+		Signature: nil,
+		Thinking:  nil,
+		Text:      nil,
+		Type:      blockTypeToolUse,
+		ID:        call.Get("id").String(),
+		Name:      call.Get("function.name").String(),
+	}
+}
+
+// toolInput parses tool call arguments. Models occasionally emit invalid JSON;
+// the Messages API requires an object, so those arguments become {}.
+func toolInput(arguments string) json.RawMessage {
+	if !gjson.Valid(arguments) || !gjson.Parse(arguments).IsObject() {
+		return json.RawMessage(`{}`)
+	}
+	return json.RawMessage(arguments)
+}
@@ -0,0 +1,126 @@
+package openai_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+func TestToAnthropicResponseText(t *testing.T) {
+	t.Parallel()
+
+	body := `{"id":"chatcmpl-123","object":"chat.completion","model":"deepseek-reasoner",
+		"choices":[{"index":0,"message":{"role":"assistant","content":"Hello!","reasoning_content":"greet"},
+		"finish_reason":"stop"}],
+		"usage":{"prompt_tokens":12,"completion_tokens":5,"total_tokens":17,
+		"prompt_tokens_details":{"cached_tokens":4}}}`
+
+	got, err := openai.ToAnthropicResponse([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"id": "msg_123",
+		"type": "message",
+		"role": "assistant",
+		"model": "deepseek-reasoner",
+		"content": [
+			{"type": "thinking", "thinking": "greet", "signature": ""},
+			{"type": "text", "text": "Hello!"}
+		],
+		"stop_reason": "end_turn",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 8, "cache_read_input_tokens": 4, "output_tokens": 5}
+	}`, string(got))
+}
+
+func TestToAnthropicResponseToolCalls(t *testing.T) {
+	t.Parallel()
+
+	body := `{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":null,
+		"tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"a","arguments":"{\"x\":1}"}},
+			{"id":"call_2","type":"function","function":{"name":"b","arguments":"not json"}}
+		]},"finish_reason":"tool_calls"}]}`
+
+	got, err := openai.ToAnthropicResponse([]byte(body))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "m",
+		"content": [
+			{"type": "tool_use", "id": "call_1", "name": "a", "input": {"x": 1}},
+			{"type": "tool_use", "id": "call_2", "name": "b", "input": {}}
+		],
+		"stop_reason": "tool_use",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 0, "cache_read_input_tokens": 0, "output_tokens": 0}
+	}`, string(got))
+}
+
+func TestToAnthropicResponseInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	_, err := openai.ToAnthropicResponse([]byte(`<html>`))
+	require.ErrorIs(t, err, openai.ErrInvalidChatResponse)
+}
+
+func TestToAnthropicError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		body   string
+		want   string
+		status int
+	}{
+		{
+			name:   "openai error object",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			want:   `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limit reached"}}`,
+		},
+		{
+			name:   "string error",
+			status: http.StatusNotFound,
+			body:   `{"error":"model not loaded"}`,
+			want:   `{"type":"error","error":{"type":"not_found_error","message":"model not loaded"}}`,
+		},
+		{
+			name:   "plain text",
+			status: http.StatusBadGateway,
+			body:   "upstream down\n",
+			want:   `{"type":"error","error":{"type":"api_error","message":"upstream down"}}`,
+		},
+		{
+			name:   "empty body",
+			status: http.StatusServiceUnavailable,
+			body:   "",
+			want:   `{"type":"error","error":{"type":"overloaded_error","message":"Service Unavailable"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.JSONEq(t, tt.want, string(openai.ToAnthropicError(tt.status, []byte(tt.body))))
+		})
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "end_turn", openai.StopReason("stop"))
+	assert.Equal(t, "max_tokens", openai.StopReason("length"))
+	assert.Equal(t, "tool_use", openai.StopReason("tool_calls"))
+	assert.Equal(t, "tool_use", openai.StopReason("function_call"))
+	assert.Equal(t, "refusal", openai.StopReason("content_filter"))
+	assert.Equal(t, "end_turn", openai.StopReason(""))
+}
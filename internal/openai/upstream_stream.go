@@ -0,0 +1,229 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	eventContentBlockStop = "content_block_stop"
+	eventPing             = "ping"
+	deltaTypeThinking     = "thinking_delta"
+	sseDataPrefix         = "data:"
+)
+
+// MessagesStream converts a Chat Completions SSE stream into a Messages SSE
+// stream. Events are produced lazily as the upstream body is read, so the
+// converted stream keeps the latency of the original.
+//
+// Chat Completions only reports usage in the last chunk (when the request set
+// stream_options.include_usage), so message_start carries zero input tokens
+// and the final counts are sent with message_delta.
+type MessagesStream struct {
+	src        io.ReadCloser
+	reader     *bufio.Reader
+	toolBlocks map[int64]int64
+	stopReason string
+	openBlock  string
+	usage      gjson.Result
+	out        bytes.Buffer
+	blockIndex int64
+	started    bool
+	finished   bool
+}
+
+// NewMessagesStream wraps a Chat Completions SSE body.
+func NewMessagesStream(src io.ReadCloser) *MessagesStream {
+	return &MessagesStream{
+		src:        src,
+		reader:     bufio.NewReader(src),
+		toolBlocks: make(map[int64]int64),
+		usage:      gjson.Result{},
+		out:        bytes.Buffer{},
+		stopReason: "",
+		openBlock:  "",
+		blockIndex: -1,
+		started:    false,
+		finished:   false,
+	}
+}
+
+// Read implements io.Reader.
+func (s *MessagesStream) Read(buf []byte) (int, error) {
+	for s.out.Len() == 0 && !s.finished {
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			s.processLine(line)
+		}
+		if errors.Is(err, io.EOF) {
+			s.finish()
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if s.out.Len() == 0 {
+		return 0, io.EOF
+	}
+	return s.out.Read(buf)
+}
+
+// Close closes the upstream body.
+func (s *MessagesStream) Close() error {
+	return s.src.Close()
+}
+
+func (s *MessagesStream) processLine(line []byte) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte(sseDataPrefix))
+	if !ok || s.finished {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if string(payload) == StreamDone {
+		s.finish()
+		return
+	}
+	if !gjson.ValidBytes(payload) {
+		return
+	}
+
+	chunk := gjson.ParseBytes(payload)
+	if chunk.Get("error").Exists() {
+		s.emit(eventError, ToAnthropicError(http.StatusInternalServerError, payload))
+		s.finished = true
+		return
+	}
+	s.processChunk(chunk)
+}
+
+func (s *MessagesStream) processChunk(chunk gjson.Result) {
+	if !s.started {
+		s.started = true
+		s.emitMessageStart(chunk)
+	}
+	if usage := chunk.Get("usage"); usage.IsObject() {
+		s.usage = usage
+	}
+
+	choice := chunk.Get("choices.0")
+	delta := choice.Get("delta")
+	if reasoning := reasoningText(delta); reasoning != "" {
+		s.ensureBlock(blockTypeThink, `{"type":"thinking","thinking":"","signature":""}`)
+		s.emitDelta(s.blockIndex, "thinking", deltaTypeThinking, reasoning)
+	}
+	if text := delta.Get("content").String(); text != "" {
+		s.ensureBlock(blockTypeText, `{"type":"text","text":""}`)
+		s.emitDelta(s.blockIndex, "text", deltaTypeText, text)
+	}
+	for _, call := range delta.Get("tool_calls").Array() {
+		s.processToolCall(call)
+	}
+	if reason := choice.Get("finish_reason").String(); reason != "" {
+		s.stopReason = StopReason(reason)
+	}
+}
+
+// processToolCall starts a tool_use block for each new tool call index and
+// forwards argument fragments as input_json_delta events.
+func (s *MessagesStream) processToolCall(call gjson.Result) {
+	toolIndex := call.Get("index").Int()
+	block, known := s.toolBlocks[toolIndex]
+	if !known {
+		s.closeBlock()
+		s.blockIndex++
+		block = s.blockIndex
+		s.toolBlocks[toolIndex] = block
+		s.openBlock = blockTypeToolUse
+
+		start := `{"type":"tool_use","id":"","name":"","input":{}}`
+		start, _ = sjson.Set(start, "id", call.Get("id").String())
+		start, _ = sjson.Set(start, "name", call.Get("function.name").String())
+		s.emitBlockStart(block, start)
+	}
+	if arguments := call.Get("function.arguments").String(); arguments != "" {
+		s.emitDelta(block, "partial_json", deltaTypeInputJSON, arguments)
+	}
+}
+
+// ensureBlock opens a block of blockType unless one is already open.
+func (s *MessagesStream) ensureBlock(blockType, contentBlock string) {
+	if s.openBlock == blockType {
+		return
+	}
+	s.closeBlock()
+	s.blockIndex++
+	s.openBlock = blockType
+	s.emitBlockStart(s.blockIndex, contentBlock)
+}
+
+func (s *MessagesStream) closeBlock() {
+	if s.openBlock == "" {
+		return
+	}
+	s.openBlock = ""
+	event, _ := sjson.SetBytes([]byte(`{"type":"content_block_stop"}`), "index", s.blockIndex)
+	s.emit(eventContentBlockStop, event)
+}
+
+// finish ends the converted stream. Streams that never produced a chunk are
+// left empty rather than turned into an empty message.
+func (s *MessagesStream) finish() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+	if !s.started {
+		return
+	}
+	s.closeBlock()
+
+	stopReason := s.stopReason
+	if stopReason == "" {
+		stopReason = stopReasonEndTurn
+	}
+	usage := messagesUsageFrom(s.usage)
+	event := []byte(`{"type":"message_delta","delta":{"stop_sequence":null}}`)
+	event, _ = sjson.SetBytes(event, "delta.stop_reason", stopReason)
+	event, _ = sjson.SetBytes(event, "usage.input_tokens", usage.InputTokens)
+	event, _ = sjson.SetBytes(event, "usage.cache_read_input_tokens", usage.CacheReadInputTokens)
+	event, _ = sjson.SetBytes(event, "usage.output_tokens", usage.OutputTokens)
+	s.emit(eventMessageDelta, event)
+	s.emit(eventMessageStop, []byte(`{"type":"message_stop"}`))
+}
+
+func (s *MessagesStream) emitMessageStart(chunk gjson.Result) {
+	event := []byte(`{"type":"message_start","message":{"id":"","type":"message","role":"assistant","model":"",` +
+		`"content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}}`)
+	event, _ = sjson.SetBytes(event, "message.id", messageID(chunk.Get("id").String()))
+	event, _ = sjson.SetBytes(event, "message.model", chunk.Get("model").String())
+	s.emit(eventMessageStart, event)
+	s.emit(eventPing, []byte(`{"type":"ping"}`))
+}
+
+func (s *MessagesStream) emitBlockStart(index int64, contentBlock string) {
+	event := []byte(`{"type":"content_block_start"}`)
+	event, _ = sjson.SetBytes(event, "index", index)
+	event, _ = sjson.SetRawBytes(event, "content_block", []byte(contentBlock))
+	s.emit(eventContentBlockStart, event)
+}
+
+func (s *MessagesStream) emitDelta(index int64, field, deltaType, value string) {
+	event := []byte(`{"type":"content_block_delta"}`)
+	event, _ = sjson.SetBytes(event, "index", index)
+	event, _ = sjson.SetBytes(event, "delta.type", deltaType)
+	event, _ = sjson.SetBytes(event, "delta."+field, value)
+	s.emit(eventContentBlockDelta, event)
+}
+
+func (s *MessagesStream) emit(event string, data []byte) {
+	s.out.WriteString("event: ")
+	s.out.WriteString(event)
+	s.out.WriteString("\ndata: ")
+	s.out.Write(data)
+	s.out.WriteString("\n\n")
+}
@@ -0,0 +1,127 @@
+package openai_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+// streamEvent is one converted Messages SSE event.
+type streamEvent struct {
+	name string
+	data gjson.Result
+}
+
+// readMessagesStream converts a Chat Completions stream and parses the resulting events.
+func readMessagesStream(t *testing.T, chunks ...string) []streamEvent {
+	t.Helper()
+
+	var upstream strings.Builder
+	for _, chunk := range chunks {
+		upstream.WriteString("data: " + chunk + "\n\n")
+	}
+	out, err := io.ReadAll(openai.NewMessagesStream(io.NopCloser(strings.NewReader(upstream.String()))))
+	require.NoError(t, err)
+
+	var events []streamEvent
+	for _, frame := range strings.Split(strings.TrimSpace(string(out)), "\n\n") {
+		name, data, ok := strings.Cut(frame, "\ndata: ")
+		require.True(t, ok, frame)
+		events = append(events, streamEvent{name: strings.TrimPrefix(name, "event: "), data: gjson.Parse(data)})
+	}
+	return events
+}
+
+func eventNames(events []streamEvent) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	return names
+}
+
+func TestMessagesStreamText(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"reasoning_content":"hmm"}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":4,`+
+			`"prompt_tokens_details":{"cached_tokens":6}}}`,
+		openai.StreamDone,
+	)
+
+	assert.Equal(t, []string{
+		"message_start", "ping",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, eventNames(events))
+
+	assert.Equal(t, "msg_1", events[0].data.Get("message.id").String())
+	assert.Equal(t, "thinking", events[2].data.Get("content_block.type").String())
+	assert.Equal(t, "hmm", events[3].data.Get("delta.thinking").String())
+	assert.Equal(t, int64(1), events[5].data.Get("index").Int())
+	assert.Equal(t, "Hel", events[6].data.Get("delta.text").String())
+	assert.JSONEq(t, `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},`+
+		`"usage":{"input_tokens":4,"cache_read_input_tokens":6,"output_tokens":4}}`, events[9].data.Raw)
+}
+
+func TestMessagesStreamToolCalls(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{"content":"Checking"}}]}`,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{"tool_calls":[`+
+			`{"index":0,"id":"call_1","type":"function","function":{"name":"a","arguments":""}}]}}]}`,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{"tool_calls":[`+
+			`{"index":0,"function":{"arguments":"{\"x\":1}"}}]}}]}`,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{"tool_calls":[`+
+			`{"index":1,"id":"call_2","type":"function","function":{"name":"b","arguments":"{}"}}]}}]}`,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	)
+
+	assert.Equal(t, []string{
+		"message_start", "ping",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, eventNames(events))
+
+	assert.JSONEq(t, `{"type":"tool_use","id":"call_1","name":"a","input":{}}`,
+		events[5].data.Get("content_block").Raw)
+	assert.JSONEq(t, `{"type":"input_json_delta","partial_json":"{\"x\":1}"}`, events[6].data.Get("delta").Raw)
+	assert.Equal(t, int64(2), events[8].data.Get("index").Int())
+	assert.Equal(t, "tool_use", events[11].data.Get("delta.stop_reason").String())
+}
+
+func TestMessagesStreamError(t *testing.T) {
+	t.Parallel()
+
+	events := readMessagesStream(t,
+		`{"id":"c","model":"m","choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+		`{"error":{"message":"backend crashed"}}`,
+	)
+
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, "error", last.name)
+	assert.JSONEq(t, `{"type":"error","error":{"type":"api_error","message":"backend crashed"}}`, last.data.Raw)
+}
+
+func TestMessagesStreamEmpty(t *testing.T) {
+	t.Parallel()
+
+	out, err := io.ReadAll(openai.NewMessagesStream(io.NopCloser(strings.NewReader(""))))
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
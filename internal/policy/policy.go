@@ -0,0 +1,187 @@
+// Package policy evaluates access policies for cc-relay.
+//
+// Policies are evaluated in order and the first policy whose conditions all
+// match a request decides whether it is allowed. A request no policy matches
+// gets the default effect. Provider conditions can only be checked once
+// routing considers a provider for the request, so requests are evaluated on
+// arrival, and again for every provider they may be sent to.
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+// Policy effects.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Policy allows or denies the requests its Match selects.
+type Policy struct {
+	// Name identifies the policy in logs and error messages.
+	Name string `yaml:"name" toml:"name"`
+
+	// Effect is "allow" or "deny".
+	Effect string `yaml:"effect" toml:"effect"`
+
+	// Message is returned to clients the policy denies. Default names the policy.
+	Message string `yaml:"message" toml:"message"`
+
+	// Match selects the requests the policy applies to. An empty match applies to all.
+	Match Match `yaml:"match" toml:"match"`
+}
+
+// Match holds a policy's conditions. Every condition that is set must hold.
+type Match struct {
+	// Metadata maps client metadata keys to the values they must have. A
+	// comma-separated metadata value, such as JWT groups, matches any of its items.
+	Metadata map[string]string `yaml:"metadata" toml:"metadata"`
+
+	// HasTools and HasThinking require the request to define tools or enable
+	// extended thinking, or, if false, not to.
+	HasTools    *bool `yaml:"has_tools" toml:"has_tools"`
+	HasThinking *bool `yaml:"has_thinking" toml:"has_thinking"`
+
+	// Clients are client names, any of which must match. Requests without a
+	// client never match.
+	Clients []string `yaml:"clients" toml:"clients"`
+
+	// Models are requested model name prefixes, any of which must match.
+	Models []string `yaml:"models" toml:"models"`
+
+	// ProviderModels are prefixes of the model sent to the provider, after its
+	// model mapping, any of which must match.
+	ProviderModels []string `yaml:"provider_models" toml:"provider_models"`
+
+	// Providers are provider names, any of which must match.
+	Providers []string `yaml:"providers" toml:"providers"`
+
+	// MaxTokensAbove matches requests asking for more than this many output
+	// tokens. Zero is unbounded.
+	MaxTokensAbove int `yaml:"max_tokens_above" toml:"max_tokens_above"`
+}
+
+// Request holds what policies match on.
+type Request struct {
+	// Metadata is the client's metadata.
+	Metadata map[string]string
+	// Provider is the provider considered for the request, or "" on arrival.
+	Provider string
+	// ProviderModel is the model Provider is asked for.
+	ProviderModel string
+
+	rules.Request
+}
+
+// MaxTokens returns the max_tokens of the request, or 0 if it has none.
+func (r *Request) MaxTokens() int {
+	return int(gjson.GetBytes(r.Body, "max_tokens").Int())
+}
+
+// Decision is the outcome of evaluating policies against a request.
+type Decision struct {
+	// Policy is the policy that decided, or nil for the default effect.
+	Policy *Policy
+	// Allowed reports whether the request may proceed.
+	Allowed bool
+	// Pending is true when a policy with provider conditions might decide,
+	// and the request names no provider yet. The request is allowed for now.
+	Pending bool
+}
+
+// Message describes why the request was denied.
+func (d Decision) Message() string {
+	switch {
+	case d.Policy == nil:
+		return "request denied by the default access policy"
+	case d.Policy.Message != "":
+		return d.Policy.Message
+	default:
+		return fmt.Sprintf("request denied by access policy %q", d.Policy.Name)
+	}
+}
+
+// Decide evaluates policies against req. Requests no policy matches get
+// defaultEffect.
+func Decide(policies []Policy, defaultEffect string, req *Request) Decision {
+	for idx := range policies {
+		policy := &policies[idx]
+		if !policy.Match.matchesRequest(req) {
+			continue
+		}
+		if policy.Match.hasProviderConditions() {
+			if req.Provider == "" {
+				return Decision{Policy: nil, Allowed: true, Pending: true}
+			}
+			if !policy.Match.matchesProvider(req) {
+				continue
+			}
+		}
+		return Decision{Policy: policy, Allowed: policy.Effect != EffectDeny, Pending: false}
+	}
+	return Decision{Policy: nil, Allowed: defaultEffect != EffectDeny, Pending: false}
+}
+
+// matchesRequest checks the conditions known when the request arrives.
+// Cheap conditions are checked first so the body is only inspected when needed.
+func (m *Match) matchesRequest(req *Request) bool {
+	return m.matchesClient(req) &&
+		matchesPrefix(m.Models, req.Model) &&
+		matchesFlag(m.HasTools, req.HasTools) &&
+		matchesFlag(m.HasThinking, req.HasThinking) &&
+		(m.MaxTokensAbove <= 0 || req.MaxTokens() > m.MaxTokensAbove)
+}
+
+func (m *Match) matchesClient(req *Request) bool {
+	if len(m.Clients) > 0 && (req.Client == "" || !slices.Contains(m.Clients, req.Client)) {
+		return false
+	}
+	for key, want := range m.Metadata {
+		if !matchesMetadata(req.Metadata[key], want) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMetadata reports whether a metadata value, or one of its
+// comma-separated items, is want.
+func matchesMetadata(value, want string) bool {
+	if value == want {
+		return true
+	}
+	return slices.ContainsFunc(strings.Split(value, ","), func(item string) bool {
+		return strings.TrimSpace(item) == want
+	})
+}
+
+func (m *Match) hasProviderConditions() bool {
+	return len(m.Providers) > 0 || len(m.ProviderModels) > 0
+}
+
+func (m *Match) matchesProvider(req *Request) bool {
+	if len(m.Providers) > 0 && !slices.Contains(m.Providers, req.Provider) {
+		return false
+	}
+	return matchesPrefix(m.ProviderModels, req.ProviderModel)
+}
+
+// matchesPrefix reports whether value starts with one of prefixes, which
+// holds if there are none.
+func matchesPrefix(prefixes []string, value string) bool {
+	return len(prefixes) == 0 || slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(value, prefix)
+	})
+}
+
+// matchesFlag checks a presence condition, which holds if unset.
+func matchesFlag(want *bool, has func() bool) bool {
+	return want == nil || *want == has()
+}
@@ -0,0 +1,174 @@
+package policy_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+const toolsBody = `{"model":"claude-opus-4","max_tokens":8192,"tools":[{"name":"bash"}],"messages":[]}`
+
+func newRequest(client, model, body string) *policy.Request {
+	return &policy.Request{
+		Metadata:      nil,
+		Provider:      "",
+		ProviderModel: "",
+		Request: rules.Request{
+			Time:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+			Header: http.Header{},
+			Model:  model,
+			Client: client,
+			Body:   []byte(body),
+		},
+	}
+}
+
+func flag(value bool) *bool {
+	return &value
+}
+
+func emptyMatch() *policy.Match {
+	return &policy.Match{
+		Metadata:       nil,
+		HasTools:       nil,
+		HasThinking:    nil,
+		Clients:        nil,
+		Models:         nil,
+		ProviderModels: nil,
+		Providers:      nil,
+		MaxTokensAbove: 0,
+	}
+}
+
+func newPolicy(name, effect string, match *policy.Match) policy.Policy {
+	return policy.Policy{Name: name, Effect: effect, Message: "", Match: *match}
+}
+
+func TestDecideFirstMatchWins(t *testing.T) {
+	t.Parallel()
+
+	ciOpus := emptyMatch()
+	ciOpus.Clients = []string{"ci"}
+	ciOpus.Models = []string{"claude-opus"}
+	opus := emptyMatch()
+	opus.Models = []string{"claude-opus"}
+	policies := []policy.Policy{
+		newPolicy("ci-opus", policy.EffectAllow, ciOpus),
+		newPolicy("no-opus", policy.EffectDeny, opus),
+	}
+
+	tests := []struct {
+		client, model string
+		defaultEffect string
+		want          bool
+	}{
+		{"ci", "claude-opus-4", policy.EffectDeny, true},
+		{"alice", "claude-opus-4", policy.EffectAllow, false},
+		{"", "claude-opus-4", policy.EffectAllow, false},
+		{"alice", "claude-sonnet-4", policy.EffectAllow, true},
+		{"alice", "claude-sonnet-4", policy.EffectDeny, false},
+	}
+	for _, tt := range tests {
+		got := policy.Decide(policies, tt.defaultEffect, newRequest(tt.client, tt.model, "{}"))
+		if got.Allowed != tt.want || got.Pending {
+			t.Errorf("Decide(%q, %q, default %s) = %+v, want allowed %v",
+				tt.client, tt.model, tt.defaultEffect, got, tt.want)
+		}
+	}
+}
+
+func TestDecideRequestFeatures(t *testing.T) {
+	t.Parallel()
+
+	tools := emptyMatch()
+	tools.HasTools = flag(true)
+	large := emptyMatch()
+	large.MaxTokensAbove = 4096
+
+	for _, match := range []*policy.Match{tools, large} {
+		policies := []policy.Policy{newPolicy("deny", policy.EffectDeny, match)}
+		if policy.Decide(policies, policy.EffectAllow, newRequest("", "claude-opus-4", toolsBody)).Allowed {
+			t.Errorf("Decide(%+v) allowed a request with tools and max_tokens 8192", *match)
+		}
+		if !policy.Decide(policies, policy.EffectAllow, newRequest("", "claude-opus-4", `{"max_tokens":1024}`)).Allowed {
+			t.Errorf("Decide(%+v) denied a plain request", *match)
+		}
+	}
+}
+
+func TestDecideMetadata(t *testing.T) {
+	t.Parallel()
+
+	admins := emptyMatch()
+	admins.Metadata = map[string]string{"groups": "admins"}
+	policies := []policy.Policy{newPolicy("admins", policy.EffectAllow, admins)}
+
+	req := newRequest("alice", "claude-opus-4", "{}")
+	req.Metadata = map[string]string{"groups": "dev, admins"}
+	if !policy.Decide(policies, policy.EffectDeny, req).Allowed {
+		t.Error("Decide() denied a client whose groups include admins")
+	}
+	req.Metadata = map[string]string{"groups": "administrators"}
+	if policy.Decide(policies, policy.EffectDeny, req).Allowed {
+		t.Error("Decide() allowed a client whose groups do not include admins")
+	}
+}
+
+func TestDecideProviderConditions(t *testing.T) {
+	t.Parallel()
+
+	external := emptyMatch()
+	external.Providers = []string{"openrouter"}
+	mapped := emptyMatch()
+	mapped.ProviderModels = []string{"anthropic.claude-opus"}
+	policies := []policy.Policy{
+		newPolicy("no-openrouter", policy.EffectDeny, external),
+		newPolicy("no-bedrock-opus", policy.EffectDeny, mapped),
+	}
+
+	req := newRequest("alice", "claude-opus-4", "{}")
+	if got := policy.Decide(policies, policy.EffectAllow, req); !got.Pending || !got.Allowed {
+		t.Errorf("Decide() without a provider = %+v, want pending", got)
+	}
+
+	req.Provider, req.ProviderModel = "openrouter", "anthropic/claude-opus-4"
+	got := policy.Decide(policies, policy.EffectAllow, req)
+	if got.Allowed || got.Pending || got.Policy.Name != "no-openrouter" {
+		t.Errorf("Decide(openrouter) = %+v, want denied by no-openrouter", got)
+	}
+
+	req.Provider, req.ProviderModel = "bedrock", "anthropic.claude-opus-4-v1:0"
+	if got = policy.Decide(policies, policy.EffectAllow, req); got.Allowed {
+		t.Errorf("Decide(bedrock) = %+v, want denied by no-bedrock-opus", got)
+	}
+
+	req.Provider, req.ProviderModel = "anthropic", "claude-opus-4"
+	if got = policy.Decide(policies, policy.EffectAllow, req); !got.Allowed || got.Policy != nil {
+		t.Errorf("Decide(anthropic) = %+v, want the default effect", got)
+	}
+}
+
+func TestDecisionMessage(t *testing.T) {
+	t.Parallel()
+
+	named := newPolicy("no-opus", policy.EffectDeny, emptyMatch())
+	custom := named
+	custom.Message = "opus is reserved for the platform team"
+
+	tests := []struct {
+		decision policy.Decision
+		want     string
+	}{
+		{policy.Decision{Policy: nil, Allowed: false, Pending: false}, "request denied by the default access policy"},
+		{policy.Decision{Policy: &named, Allowed: false, Pending: false}, `request denied by access policy "no-opus"`},
+		{policy.Decision{Policy: &custom, Allowed: false, Pending: false}, custom.Message},
+	}
+	for _, tt := range tests {
+		if got := tt.decision.Message(); got != tt.want {
+			t.Errorf("Message() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -45,3 +45,13 @@ func NewAnthropicProvider(name, baseURL string, models []string, modelMapping ma
 func (p *AnthropicProvider) SupportsTransparentAuth() bool {
 	return true
 }
+
+// TransformCountTokensRequest forwards count_tokens requests unchanged.
+func (p *AnthropicProvider) TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error) {
+	return body, p.baseURL + CountTokensEndpoint, nil
+}
+
+// TransformCountTokensResponse returns the response unchanged; it is already in Anthropic format.
+func (p *AnthropicProvider) TransformCountTokensResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -16,6 +17,8 @@ import (
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 const (
@@ -31,6 +34,10 @@ const (
 
 	// ContentTypeEventStream is the Content-Type for Bedrock streaming responses.
 	ContentTypeEventStream = "application/vnd.amazon.eventstream"
+
+	// bedrockCountTokensMaxTokens fills the max_tokens field InvokeModel requires.
+	// It does not affect the input token count.
+	bedrockCountTokensMaxTokens = 1
 )
 
 // DefaultBedrockModels are the default Claude models available on Bedrock.
@@ -243,6 +250,45 @@ func (p *BedrockProvider) TransformRequest(
 	return newBody, targetURL, nil
 }
 
+// TransformCountTokensRequest wraps the request for Bedrock's CountTokens API.
+// The body is transformed exactly as for InvokeModel and embedded, base64
+// encoded, as input.invokeModel.body. InvokeModel requires max_tokens, which
+// count_tokens requests omit, so a placeholder is added when missing.
+// Format: /model/{model}/count-tokens.
+func (p *BedrockProvider) TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error) {
+	invokeBody, model, err := TransformBodyForCloudProvider(body, BedrockAnthropicVersion)
+	if err != nil {
+		return nil, "", fmt.Errorf("bedrock: count tokens transform failed: %w", err)
+	}
+
+	if !gjson.GetBytes(invokeBody, "max_tokens").Exists() {
+		invokeBody, err = sjson.SetBytes(invokeBody, "max_tokens", bedrockCountTokensMaxTokens)
+		if err != nil {
+			return nil, "", fmt.Errorf("bedrock: count tokens transform failed: %w", err)
+		}
+	}
+
+	newBody, err = sjson.SetBytes([]byte(`{}`), "input.invokeModel.body",
+		base64.StdEncoding.EncodeToString(invokeBody))
+	if err != nil {
+		return nil, "", fmt.Errorf("bedrock: count tokens transform failed: %w", err)
+	}
+
+	targetURL = fmt.Sprintf("%s/model/%s/count-tokens", p.baseURL, url.PathEscape(p.MapModel(model)))
+
+	return newBody, targetURL, nil
+}
+
+// TransformCountTokensResponse converts Bedrock's {"inputTokens": N} to
+// Anthropic's {"input_tokens": N}.
+func (p *BedrockProvider) TransformCountTokensResponse(body []byte) ([]byte, error) {
+	inputTokens := gjson.GetBytes(body, "inputTokens")
+	if !inputTokens.Exists() {
+		return nil, fmt.Errorf("bedrock: count tokens response missing inputTokens")
+	}
+	return sjson.SetBytes([]byte(`{}`), "input_tokens", inputTokens.Int())
+}
+
 // TransformResponse handles Bedrock's Event Stream response format.
 // Converts Event Stream to SSE for Claude Code compatibility.
 func (p *BedrockProvider) TransformResponse(resp *http.Response, writer http.ResponseWriter) error {
@@ -415,6 +415,49 @@ func TestBedrockProviderTransformRequestURL(t *testing.T) {
 	})
 }
 
+func TestBedrockProviderTransformCountTokensRequest(t *testing.T) {
+	t.Parallel()
+	provider := testBedrockProviderWithDefaultCreds(t, testBedrockConfig(nil))
+
+	body := []byte(`{"model":"anthropic.claude-sonnet-4-5-20250514-v1:0",` +
+		`"messages":[{"role":"user","content":"Hello"}]}`)
+
+	newBody, targetURL, err := provider.TransformCountTokensRequest(body)
+	require.NoError(t, err)
+
+	expected := "https://bedrock-runtime.us-east-1.amazonaws.com" +
+		"/model/anthropic.claude-sonnet-4-5-20250514-v1:0/count-tokens"
+	assert.Equal(t, expected, targetURL)
+
+	var wrapper struct {
+		Input struct {
+			InvokeModel struct {
+				Body []byte `json:"body"`
+			} `json:"invokeModel"`
+		} `json:"input"`
+	}
+	require.NoError(t, json.Unmarshal(newBody, &wrapper))
+
+	var invokeBody map[string]any
+	require.NoError(t, json.Unmarshal(wrapper.Input.InvokeModel.Body, &invokeBody))
+	assert.Equal(t, providers.BedrockAnthropicVersion, invokeBody["anthropic_version"])
+	assert.NotContains(t, invokeBody, "model")
+	assert.Contains(t, invokeBody, "max_tokens", "InvokeModel requires max_tokens")
+	assert.NotNil(t, invokeBody["messages"])
+}
+
+func TestBedrockProviderTransformCountTokensResponse(t *testing.T) {
+	t.Parallel()
+	provider := testBedrockProviderWithDefaultCreds(t, testBedrockConfig(nil))
+
+	out, err := provider.TransformCountTokensResponse([]byte(`{"inputTokens":42}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"input_tokens":42}`, string(out))
+
+	_, err = provider.TransformCountTokensResponse([]byte(`{"message":"bad"}`))
+	assert.Error(t, err)
+}
+
 func TestBedrockProviderTransformResponse(t *testing.T) {
 	t.Parallel()
 	provider := testBedrockProviderWithDefaultCreds(t, testBedrockConfig(nil))
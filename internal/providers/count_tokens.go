@@ -0,0 +1,17 @@
+package providers
+
+// CountTokensEndpoint is the Anthropic Messages token counting endpoint.
+const CountTokensEndpoint = "/v1/messages/count_tokens"
+
+// TokenCounter is implemented by providers that expose a native token counting API.
+// Requests to CountTokensEndpoint for providers that do not implement it are
+// answered by the proxy with a local estimate instead.
+type TokenCounter interface {
+	// TransformCountTokensRequest rewrites an Anthropic count_tokens body for the
+	// provider and returns the URL to send it to.
+	TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error)
+
+	// TransformCountTokensResponse converts a successful provider response body
+	// to the Anthropic {"input_tokens": N} shape.
+	TransformCountTokensResponse(body []byte) ([]byte, error)
+}
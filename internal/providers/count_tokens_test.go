@@ -0,0 +1,50 @@
+package providers_test
+
+import (
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+func TestAnthropicTransformCountTokensRequest(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewAnthropicProvider("test", "https://api.example.com", nil, nil)
+	body := []byte(`{"model":"claude-sonnet-4","messages":[]}`)
+
+	newBody, targetURL, err := provider.TransformCountTokensRequest(body)
+	if err != nil {
+		t.Fatalf("TransformCountTokensRequest() error = %v", err)
+	}
+	if string(newBody) != string(body) {
+		t.Errorf("body = %s, want unchanged %s", newBody, body)
+	}
+	if want := "https://api.example.com" + providers.CountTokensEndpoint; targetURL != want {
+		t.Errorf("targetURL = %q, want %q", targetURL, want)
+	}
+}
+
+func TestTokenCounterImplementations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		provider providers.Provider
+		name     string
+		native   bool
+	}{
+		{name: "anthropic", provider: providers.NewAnthropicProvider("a", "", nil, nil), native: true},
+		{name: "zai", provider: providers.NewZAIProvider("z", "", nil, nil), native: false},
+		{name: "ollama", provider: providers.NewOllamaProvider("o", "", nil, nil), native: false},
+		{name: "minimax", provider: providers.NewMiniMaxProvider("m", "", nil, nil), native: false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, native := testCase.provider.(providers.TokenCounter)
+			if native != testCase.native {
+				t.Errorf("implements TokenCounter = %v, want %v", native, testCase.native)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/omarluq/cc-relay/internal/gemini"
+)
+
+const (
+	// DefaultGeminiBaseURL is the default Gemini API base URL.
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+	// GeminiOwner is the owner identifier for the Gemini provider.
+	GeminiOwner = "google"
+
+	// geminiAPIVersion is the Gemini API version segment. Thought signatures
+	// and JSON schema tool parameters are only available on v1beta.
+	geminiAPIVersion = "v1beta"
+
+	// maxGeminiResponseSize bounds non-streaming response bodies read into memory.
+	maxGeminiResponseSize = 32 << 20
+)
+
+// DefaultGeminiModels are the default models available from the Gemini API.
+var DefaultGeminiModels = []string{
+	"gemini-2.5-pro",
+	"gemini-2.5-flash",
+	"gemini-2.5-flash-lite",
+}
+
+// GeminiProvider implements the Provider interface for the Google Gemini API.
+//
+// Messages requests are translated into generateContent requests, and
+// responses, including SSE streams and errors, are translated back. Gemini
+// thought signatures are returned in thinking blocks, so multi-turn tool use
+// with thinking models keeps working when clients echo their history.
+type GeminiProvider struct {
+	BaseProvider
+}
+
+// NewGeminiProvider creates a new Gemini provider instance.
+// If baseURL is empty, DefaultGeminiBaseURL is used.
+// If models is empty, DefaultGeminiModels are used.
+func NewGeminiProvider(name, baseURL string, models []string, modelMapping map[string]string) *GeminiProvider {
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+
+	if len(models) == 0 {
+		models = DefaultGeminiModels
+	}
+
+	return &GeminiProvider{
+		BaseProvider: NewBaseProviderWithMapping(
+			name, strings.TrimSuffix(baseURL, "/"), GeminiOwner, models, modelMapping),
+	}
+}
+
+// Authenticate adds the Gemini API key header to the request.
+func (p *GeminiProvider) Authenticate(req *http.Request, key string) error {
+	req.Header.Set("x-goog-api-key", key)
+
+	log.Ctx(req.Context()).Debug().
+		Str("provider", p.name).
+		Msg("added authentication header")
+
+	return nil
+}
+
+// ForwardHeaders returns headers to forward to the backend.
+// anthropic-* headers mean nothing to Gemini and are dropped.
+func (p *GeminiProvider) ForwardHeaders(_ http.Header) http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	return headers
+}
+
+// TransformRequest translates a Messages request into a generateContent request.
+// The model goes in the URL path, and streaming requests use
+// streamGenerateContent with alt=sse so the response is an SSE stream.
+func (p *GeminiProvider) TransformRequest(body []byte, _ string) (newBody []byte, targetURL string, err error) {
+	newBody, err = gemini.FromAnthropicRequest(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("gemini: transform request: %w", err)
+	}
+
+	action := "generateContent"
+	if IsStreamingRequest(body) {
+		action = "streamGenerateContent?alt=sse"
+	}
+	return newBody, p.modelURL(p.MapModel(ExtractModel(body)), action), nil
+}
+
+// TransformCountTokensRequest targets Gemini's countTokens method. The request
+// is wrapped in generateContentRequest so that the system prompt and tools
+// are counted along with the messages.
+func (p *GeminiProvider) TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error) {
+	request, err := gemini.FromAnthropicRequest(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("gemini: count tokens transform failed: %w", err)
+	}
+
+	model := p.MapModel(ExtractModel(body))
+	request, err = sjson.SetBytes(request, "model", "models/"+model)
+	if err != nil {
+		return nil, "", fmt.Errorf("gemini: count tokens transform failed: %w", err)
+	}
+	newBody, err = sjson.SetRawBytes([]byte(`{}`), "generateContentRequest", request)
+	if err != nil {
+		return nil, "", fmt.Errorf("gemini: count tokens transform failed: %w", err)
+	}
+	return newBody, p.modelURL(model, "countTokens"), nil
+}
+
+// TransformCountTokensResponse converts {"totalTokens": N} to {"input_tokens": N}.
+func (p *GeminiProvider) TransformCountTokensResponse(body []byte) ([]byte, error) {
+	total := gjson.GetBytes(body, "totalTokens")
+	if !total.Exists() {
+		return nil, fmt.Errorf("gemini: count tokens response missing totalTokens: %s", body)
+	}
+	return sjson.SetBytes([]byte(`{}`), "input_tokens", total.Int())
+}
+
+// TransformResponse replaces the body of a Gemini response with the equivalent
+// Messages body. SSE streams are converted lazily as they are read; JSON
+// bodies and errors are converted up front. The writer is not used.
+func (p *GeminiProvider) TransformResponse(resp *http.Response, _ http.ResponseWriter) error {
+	if resp.Body == nil {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil && mediaType == ContentTypeSSE && resp.StatusCode == http.StatusOK {
+		resp.Body = gemini.NewMessagesStream(resp.Body)
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGeminiResponseSize))
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("failed to close gemini response body")
+	}
+	if err != nil {
+		return fmt.Errorf("gemini: read response: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body = gemini.ToAnthropicError(resp.StatusCode, body)
+	} else if body, err = gemini.ToAnthropicResponse(body); err != nil {
+		return fmt.Errorf("gemini: transform response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// RequiresBodyTransform returns true: every request body is translated.
+func (p *GeminiProvider) RequiresBodyTransform() bool {
+	return true
+}
+
+// modelURL builds the URL of a model method.
+// Format: {baseURL}/v1beta/models/{model}:{action}.
+func (p *GeminiProvider) modelURL(model, action string) string {
+	return fmt.Sprintf("%s/%s/models/%s:%s", p.baseURL, geminiAPIVersion, url.PathEscape(model), action)
+}
@@ -0,0 +1,272 @@
+package providers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+const testGeminiName = "test-gemini"
+
+func TestNewGeminiProvider(t *testing.T) {
+	t.Parallel()
+
+	assertNewProvider(t,
+		func(name, baseURL string) providers.Provider {
+			return providers.NewGeminiProvider(name, baseURL, nil, nil)
+		},
+		[]providerTestCase{
+			{
+				name:         testNameCustomBaseURL,
+				providerName: "gemini-custom",
+				baseURL:      "https://gemini.example.com/",
+				wantBaseURL:  "https://gemini.example.com",
+			},
+			{
+				name:         testNameEmptyBaseURL,
+				providerName: "gemini-default",
+				baseURL:      "",
+				wantBaseURL:  providers.DefaultGeminiBaseURL,
+			},
+		},
+	)
+}
+
+func TestGeminiDefaultModels(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+
+	models := provider.ListModels()
+	if len(models) != len(providers.DefaultGeminiModels) {
+		t.Fatalf("Expected %d default models, got %d", len(providers.DefaultGeminiModels), len(models))
+	}
+	if models[0].OwnedBy != providers.GeminiOwner {
+		t.Errorf("Expected owner %s, got %s", providers.GeminiOwner, models[0].OwnedBy)
+	}
+}
+
+func TestGeminiAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, providers.DefaultGeminiBaseURL, http.NoBody,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	if err := provider.Authenticate(req, "AIza-test"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	if got := req.Header.Get("x-goog-api-key"); got != "AIza-test" {
+		t.Errorf("Expected x-goog-api-key=AIza-test, got %s", got)
+	}
+	if req.Header.Get("x-api-key") != "" || req.Header.Get("Authorization") != "" {
+		t.Error("Expected only x-goog-api-key to be set for Gemini")
+	}
+}
+
+func TestGeminiForwardHeadersDropsAnthropicHeaders(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+
+	original := http.Header{}
+	original.Set("Anthropic-Version", anthropicVersionDate)
+	original.Set("Anthropic-Beta", featureHeaderValue)
+
+	headers := provider.ForwardHeaders(original)
+
+	if headers.Get("Anthropic-Version") != "" || headers.Get("Anthropic-Beta") != "" {
+		t.Errorf("Expected anthropic-* headers to be dropped, got %v", headers)
+	}
+	if headers.Get("Content-Type") != jsonContentType {
+		t.Errorf("Expected Content-Type=%s, got %s", jsonContentType, headers.Get("Content-Type"))
+	}
+}
+
+func TestGeminiTransformRequest(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil,
+		map[string]string{"claude-sonnet-4-5": "gemini-2.5-pro"})
+
+	if !provider.RequiresBodyTransform() {
+		t.Fatal("Expected GeminiProvider to require body transform")
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantURL string
+	}{
+		{
+			name:    "non-streaming",
+			body:    `{"model":"gemini-2.5-flash","max_tokens":32,"messages":[{"role":"user","content":"Hi"}]}`,
+			wantURL: providers.DefaultGeminiBaseURL + "/v1beta/models/gemini-2.5-flash:generateContent",
+		},
+		{
+			name: "streaming with mapped model",
+			body: `{"model":"claude-sonnet-4-5","max_tokens":32,"stream":true,` +
+				`"messages":[{"role":"user","content":"Hi"}]}`,
+			wantURL: providers.DefaultGeminiBaseURL +
+				"/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			newBody, targetURL, err := provider.TransformRequest([]byte(tt.body), "/v1/messages")
+			if err != nil {
+				t.Fatalf("TransformRequest failed: %v", err)
+			}
+			if targetURL != tt.wantURL {
+				t.Errorf("Expected URL %s, got %s", tt.wantURL, targetURL)
+			}
+			if got := gjson.GetBytes(newBody, "contents.0.parts.0.text").String(); got != "Hi" {
+				t.Errorf("Expected user text in contents, got %s", newBody)
+			}
+			if gjson.GetBytes(newBody, "model").Exists() {
+				t.Error("Expected model to be removed from body")
+			}
+		})
+	}
+}
+
+func TestGeminiCountTokens(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+
+	body := `{"model":"gemini-2.5-pro","system":"Be terse.","messages":[{"role":"user","content":"Hi"}]}`
+	newBody, targetURL, err := provider.TransformCountTokensRequest([]byte(body))
+	if err != nil {
+		t.Fatalf("TransformCountTokensRequest failed: %v", err)
+	}
+
+	if targetURL != providers.DefaultGeminiBaseURL+"/v1beta/models/gemini-2.5-pro:countTokens" {
+		t.Errorf("Expected countTokens URL, got %s", targetURL)
+	}
+	if got := gjson.GetBytes(newBody, "generateContentRequest.model").String(); got != "models/gemini-2.5-pro" {
+		t.Errorf("Expected model=models/gemini-2.5-pro, got %s", got)
+	}
+	if !gjson.GetBytes(newBody, "generateContentRequest.systemInstruction").Exists() {
+		t.Error("Expected system prompt to be counted")
+	}
+
+	out, err := provider.TransformCountTokensResponse([]byte(`{"totalTokens":17,"promptTokensDetails":[]}`))
+	if err != nil {
+		t.Fatalf("TransformCountTokensResponse failed: %v", err)
+	}
+	if string(out) != `{"input_tokens":17}` {
+		t.Errorf("Expected {\"input_tokens\":17}, got %s", out)
+	}
+
+	if _, err := provider.TransformCountTokensResponse([]byte(`{}`)); err == nil {
+		t.Error("Expected error for response without totalTokens")
+	}
+}
+
+func TestGeminiTransformResponseJSON(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusOK, jsonContentType,
+		`{"responseId":"r1","modelVersion":"gemini-2.5-flash","candidates":[{"content":{"role":"model",`+
+			`"parts":[{"text":"Hello"}]},"finishReason":"STOP"}],`+
+			`"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":1}}`)
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if got := gjson.GetBytes(body, "content.0.text").String(); got != "Hello" {
+		t.Errorf("Expected text=Hello, got %s", got)
+	}
+	if got := gjson.GetBytes(body, "usage.input_tokens").Int(); got != 5 {
+		t.Errorf("Expected input_tokens=5, got %d", got)
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Errorf("Expected ContentLength=%d, got %d", len(body), resp.ContentLength)
+	}
+}
+
+func TestGeminiTransformResponseError(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusBadRequest, jsonContentType,
+		`{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT"}}`)
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if got := gjson.GetBytes(body, "error.type").String(); got != "invalid_request_error" {
+		t.Errorf("Expected invalid_request_error, got %s", got)
+	}
+	if got := gjson.GetBytes(body, "error.message").String(); got != "API key not valid." {
+		t.Errorf("Expected upstream message, got %s", got)
+	}
+}
+
+func TestGeminiTransformResponseStream(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewGeminiProvider(testGeminiName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusOK, providers.ContentTypeSSE,
+		`data: {"responseId":"r","candidates":[{"content":{"parts":[{"text":"Hi"}]},"finishReason":"STOP"}]}`+
+			"\r\n\r\n")
+	resp.Header.Set("Content-Length", "123")
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" {
+		t.Error("Expected Content-Length to be cleared for converted stream")
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	for _, event := range []string{"message_start", "content_block_delta", "message_delta", "message_stop"} {
+		if !strings.Contains(string(body), "event: "+event+"\n") {
+			t.Errorf("Expected %s event in converted stream, got:\n%s", event, body)
+		}
+	}
+}
+
+func TestGeminiProviderInterface(t *testing.T) {
+	t.Parallel()
+
+	var _ providers.Provider = (*providers.GeminiProvider)(nil)
+	var _ providers.TokenCounter = (*providers.GeminiProvider)(nil)
+}
+
+func TestGeminiTranslatesResponses(t *testing.T) {
+	t.Parallel()
+
+	if !providers.TranslatesResponses(providers.NewGeminiProvider(testGeminiName, "", nil, nil)) {
+		t.Error("TranslatesResponses(gemini) = false, want true")
+	}
+}
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+)
+
+const (
+	// DefaultOpenAIBaseURL is the default OpenAI API base URL.
+	// Unlike Anthropic-compatible providers, the base URL includes the API
+	// version, matching how OpenAI-compatible servers document their endpoints.
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+	// OpenAIOwner is the owner identifier for OpenAI-compatible providers.
+	OpenAIOwner = "openai"
+
+	// openAIChatCompletionsEndpoint is appended to the base URL for every request.
+	openAIChatCompletionsEndpoint = "/chat/completions"
+
+	// maxOpenAIResponseSize bounds non-streaming response bodies read into memory.
+	maxOpenAIResponseSize = 32 << 20
+)
+
+// OpenAIProvider implements the Provider interface for OpenAI-compatible
+// Chat Completions APIs such as OpenAI, OpenRouter, DeepSeek, Groq, vLLM and
+// LM Studio.
+//
+// Messages requests are translated into Chat Completions requests, and
+// responses, including SSE streams and errors, are translated back, so
+// clients keep speaking the Anthropic Messages API.
+type OpenAIProvider struct {
+	BaseProvider
+}
+
+// NewOpenAIProvider creates a new OpenAI-compatible provider instance.
+// If baseURL is empty, DefaultOpenAIBaseURL is used.
+// If models is nil, an empty slice is used (each compatible server has its own catalog).
+func NewOpenAIProvider(name, baseURL string, models []string, modelMapping map[string]string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+
+	if models == nil {
+		models = []string{}
+	}
+
+	return &OpenAIProvider{
+		BaseProvider: NewBaseProviderWithMapping(
+			name, strings.TrimSuffix(baseURL, "/"), OpenAIOwner, models, modelMapping),
+	}
+}
+
+// Authenticate adds OpenAI-style Bearer token authentication to the request.
+func (p *OpenAIProvider) Authenticate(req *http.Request, key string) error {
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	log.Ctx(req.Context()).Debug().
+		Str("provider", p.name).
+		Msg("added authentication header")
+
+	return nil
+}
+
+// ForwardHeaders returns headers to forward to the backend.
+// anthropic-* headers mean nothing to OpenAI-compatible servers and are dropped.
+func (p *OpenAIProvider) ForwardHeaders(_ http.Header) http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	return headers
+}
+
+// TransformRequest translates a Messages request into a Chat Completions request.
+// Every endpoint maps onto Chat Completions, so endpoint is ignored.
+func (p *OpenAIProvider) TransformRequest(body []byte, _ string) (newBody []byte, targetURL string, err error) {
+	newBody, err = openai.FromAnthropicRequest(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("openai: transform request: %w", err)
+	}
+	return newBody, p.baseURL + openAIChatCompletionsEndpoint, nil
+}
+
+// TransformResponse replaces the body of a Chat Completions response with the
+// equivalent Messages body. SSE streams are converted lazily as they are read;
+// JSON bodies and errors are converted up front. The writer is not used.
+func (p *OpenAIProvider) TransformResponse(resp *http.Response, _ http.ResponseWriter) error {
+	if resp.Body == nil {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil && mediaType == ContentTypeSSE && resp.StatusCode == http.StatusOK {
+		resp.Body = openai.NewMessagesStream(resp.Body)
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenAIResponseSize))
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("failed to close openai response body")
+	}
+	if err != nil {
+		return fmt.Errorf("openai: read response: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body = openai.ToAnthropicError(resp.StatusCode, body)
+	} else if body, err = openai.ToAnthropicResponse(body); err != nil {
+		return fmt.Errorf("openai: transform response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// RequiresBodyTransform returns true: every request body is translated.
+func (p *OpenAIProvider) RequiresBodyTransform() bool {
+	return true
+}
@@ -0,0 +1,213 @@
+package providers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+const testOpenAIName = "test-openai"
+
+func TestNewOpenAIProvider(t *testing.T) {
+	t.Parallel()
+
+	assertNewProvider(t,
+		func(name, baseURL string) providers.Provider {
+			return providers.NewOpenAIProvider(name, baseURL, nil, nil)
+		},
+		[]providerTestCase{
+			{
+				name:         testNameCustomBaseURL,
+				providerName: "openrouter",
+				baseURL:      "https://openrouter.ai/api/v1/",
+				wantBaseURL:  "https://openrouter.ai/api/v1",
+			},
+			{
+				name:         testNameEmptyBaseURL,
+				providerName: "openai-default",
+				baseURL:      "",
+				wantBaseURL:  providers.DefaultOpenAIBaseURL,
+			},
+		},
+	)
+}
+
+func TestOpenAIAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)
+
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, "https://api.openai.com/v1/chat/completions", http.NoBody,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	if err := provider.Authenticate(req, "sk-test"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	if got := req.Header.Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Expected Authorization=Bearer sk-test, got %s", got)
+	}
+	if req.Header.Get("x-api-key") != "" {
+		t.Error("Expected x-api-key header to not be set for OpenAI")
+	}
+}
+
+func TestOpenAIForwardHeadersDropsAnthropicHeaders(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)
+
+	original := http.Header{}
+	original.Set("Anthropic-Version", anthropicVersionDate)
+	original.Set("Anthropic-Beta", featureHeaderValue)
+
+	headers := provider.ForwardHeaders(original)
+
+	if headers.Get("Anthropic-Version") != "" || headers.Get("Anthropic-Beta") != "" {
+		t.Errorf("Expected anthropic-* headers to be dropped, got %v", headers)
+	}
+	if headers.Get("Content-Type") != jsonContentType {
+		t.Errorf("Expected Content-Type=%s, got %s", jsonContentType, headers.Get("Content-Type"))
+	}
+}
+
+func TestOpenAITransformRequest(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "http://localhost:1234/v1", nil, nil)
+
+	if !provider.RequiresBodyTransform() {
+		t.Fatal("Expected OpenAIProvider to require body transform")
+	}
+
+	body := `{"model":"qwen3","max_tokens":32,"system":"Be terse.","messages":[{"role":"user","content":"Hi"}]}`
+	newBody, targetURL, err := provider.TransformRequest([]byte(body), "/v1/messages")
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+
+	if targetURL != "http://localhost:1234/v1/chat/completions" {
+		t.Errorf("Expected chat completions URL, got %s", targetURL)
+	}
+	if got := gjson.GetBytes(newBody, "messages.0.role").String(); got != "system" {
+		t.Errorf("Expected leading system message, got %s", got)
+	}
+	if got := gjson.GetBytes(newBody, "max_tokens").Int(); got != 32 {
+		t.Errorf("Expected max_tokens=32, got %d", got)
+	}
+}
+
+func newTranslatedResponse(status int, contentType, body string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		StatusCode: status,
+	}
+}
+
+func TestOpenAITransformResponseJSON(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusOK, jsonContentType,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":5,"completion_tokens":1}}`)
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if got := gjson.GetBytes(body, "content.0.text").String(); got != "Hello" {
+		t.Errorf("Expected text=Hello, got %s", got)
+	}
+	if got := gjson.GetBytes(body, "usage.input_tokens").Int(); got != 5 {
+		t.Errorf("Expected input_tokens=5, got %d", got)
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Errorf("Expected ContentLength=%d, got %d", len(body), resp.ContentLength)
+	}
+}
+
+func TestOpenAITransformResponseError(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusUnauthorized, jsonContentType,
+		`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`)
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if got := gjson.GetBytes(body, "error.type").String(); got != "authentication_error" {
+		t.Errorf("Expected authentication_error, got %s", got)
+	}
+	if got := gjson.GetBytes(body, "error.message").String(); got != "Incorrect API key provided" {
+		t.Errorf("Expected upstream message, got %s", got)
+	}
+}
+
+func TestOpenAITransformResponseStream(t *testing.T) {
+	t.Parallel()
+
+	provider := providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)
+	resp := newTranslatedResponse(http.StatusOK, providers.ContentTypeSSE+"; charset=utf-8",
+		`data: {"id":"c","model":"m","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}`+
+			"\n\ndata: [DONE]\n\n")
+	resp.Header.Set("Content-Length", "123")
+
+	if err := provider.TransformResponse(resp, nil); err != nil {
+		t.Fatalf("TransformResponse failed: %v", err)
+	}
+
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" {
+		t.Error("Expected Content-Length to be cleared for converted stream")
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	for _, event := range []string{"message_start", "content_block_delta", "message_delta", "message_stop"} {
+		if !strings.Contains(string(body), "event: "+event+"\n") {
+			t.Errorf("Expected %s event in converted stream, got:\n%s", event, body)
+		}
+	}
+}
+
+func TestOpenAIProviderInterface(t *testing.T) {
+	t.Parallel()
+
+	var _ providers.Provider = (*providers.OpenAIProvider)(nil)
+}
+
+func TestOpenAITranslatesResponses(t *testing.T) {
+	t.Parallel()
+
+	if !providers.TranslatesResponses(providers.NewOpenAIProvider(testOpenAIName, "", nil, nil)) {
+		t.Error("TranslatesResponses(openai) = false, want true")
+	}
+	if providers.TranslatesResponses(providers.NewAnthropicProvider("anthropic", "", nil, nil)) {
+		t.Error("TranslatesResponses(anthropic) = true, want false")
+	}
+}
@@ -62,6 +62,7 @@ type Provider interface {
 
 	// TransformResponse modifies the response for provider-specific requirements.
 	// For Bedrock: converts Event Stream to SSE format.
+	// For OpenAI and Gemini: replaces the body with its Messages equivalent.
 	// Other providers return response unchanged.
 	// The writer is used for streaming responses; non-streaming returns modified body.
 	TransformResponse(resp *http.Response, w http.ResponseWriter) error
@@ -75,3 +76,17 @@ type Provider interface {
 	// Bedrock: "application/vnd.amazon.eventstream"
 	StreamingContentType() string
 }
+
+// TranslatesResponses reports whether provider's responses are in another
+// API's format, which its TransformResponse replaces with the Messages
+// equivalent. The proxy translates these responses before any other response
+// processing, so key pools, usage accounting and clients only ever see
+// Messages bodies and events.
+func TranslatesResponses(provider Provider) bool {
+	switch provider.(type) {
+	case *OpenAIProvider, *GeminiProvider:
+		return true
+	default:
+		return false
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/rs/zerolog/log"
+	"github.com/tidwall/sjson"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
@@ -190,6 +191,30 @@ func (p *VertexProvider) TransformRequest(
 	return newBody, targetURL, nil
 }
 
+// TransformCountTokensRequest targets Vertex AI's count-tokens endpoint.
+// Unlike rawPredict, the model stays in the body (mapped to its Vertex name)
+// and the URL uses the fixed "count-tokens" model segment.
+// Format: /v1/projects/{project}/locations/{region}/publishers/anthropic/models/count-tokens:rawPredict
+func (p *VertexProvider) TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error) {
+	newBody, err = sjson.SetBytes(body, "model", p.MapModel(ExtractModel(body)))
+	if err != nil {
+		return nil, "", fmt.Errorf("vertex: count tokens transform failed: %w", err)
+	}
+
+	targetURL = fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/anthropic/models/count-tokens:rawPredict",
+		p.baseURL,
+		url.PathEscape(p.projectID),
+		url.PathEscape(p.region))
+
+	return newBody, targetURL, nil
+}
+
+// TransformCountTokensResponse returns the response unchanged; Vertex AI
+// already replies with {"input_tokens": N}.
+func (p *VertexProvider) TransformCountTokensResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
+
 // RequiresBodyTransform returns true for Vertex AI.
 // Model is removed from body and added to URL path.
 func (p *VertexProvider) RequiresBodyTransform() bool {
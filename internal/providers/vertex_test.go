@@ -333,6 +333,28 @@ func TestVertexTransformRequestURLConstruction(t *testing.T) {
 	})
 }
 
+func TestVertexTransformCountTokensRequest(t *testing.T) {
+	t.Parallel()
+
+	cfg := newTestVertexConfig()
+	cfg.ModelMapping = map[string]string{modelClaude4: "claude-sonnet-4-5@20250514"}
+	provider := providers.NewVertexProviderWithTokenSource(cfg, newMockTokenSource("test-token"))
+
+	newBody, targetURL, err := provider.TransformCountTokensRequest(
+		[]byte(`{"model":"claude-4","messages":[{"role":"user","content":"Hi"}]}`))
+
+	require.NoError(t, err)
+	expected := "https://us-central1-aiplatform.googleapis.com" +
+		"/v1/projects/my-project/locations/us-central1" +
+		"/publishers/anthropic/models/count-tokens:rawPredict"
+	assert.Equal(t, expected, targetURL)
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(newBody, &result))
+	assert.Equal(t, "claude-sonnet-4-5@20250514", result["model"], "model stays in body, mapped")
+	assert.NotContains(t, result, "anthropic_version")
+}
+
 func TestVertexTransformRequestModelMapping(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+// errAccessDenied is returned when access policies deny the request on every
+// provider that could serve it.
+var errAccessDenied = errors.New("access denied")
+
+// accessRequestFromContext returns the request stored by checkAccess for
+// evaluating policies with provider conditions during routing.
+func accessRequestFromContext(ctx context.Context) (*policy.Request, bool) {
+	req, ok := ctx.Value(accessRequestContextKey).(*policy.Request)
+	return req, ok && req != nil
+}
+
+// checkAccess evaluates the access policies against the request as it
+// arrives, before anything is spent on it. If a policy with provider
+// conditions may decide, the request is stored in its context and decided
+// per provider during routing. Writes a permission_error and returns false
+// if the request is denied.
+func (h *Handler) checkAccess(writer http.ResponseWriter, request *http.Request, model string) (*http.Request, bool) {
+	cfg := h.getRuntimeConfigGetter()
+	if cfg == nil || !cfg.Access.IsEnabled() {
+		return request, true
+	}
+
+	body, err := readBody(request)
+	if err != nil {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return request, false
+	}
+
+	req := accessRequest(auth.ClientFromContext(request.Context()), ruleRequest(request, body, model))
+	decision := policy.Decide(cfg.Access.Policies, cfg.Access.GetDefaultEffect(), req)
+	if decision.Pending {
+		return request.WithContext(context.WithValue(request.Context(), accessRequestContextKey, req)), true
+	}
+	if !decision.Allowed {
+		logAccessDenied(request.Context(), decision)
+		WriteError(writer, http.StatusForbidden, "permission_error", decision.Message())
+		return request, false
+	}
+	return request, true
+}
+
+// accessRequest collects what access policies match on.
+func accessRequest(client *auth.Client, ruleReq *rules.Request) *policy.Request {
+	var metadata map[string]string
+	if client != nil {
+		metadata = client.Metadata
+	}
+	return &policy.Request{
+		Metadata:      metadata,
+		Provider:      "",
+		ProviderModel: "",
+		Request:       *ruleReq,
+	}
+}
+
+// decideForProvider evaluates the access policies for sending the request to
+// provider, which is asked for model after its model mapping.
+func decideForProvider(
+	cfg *config.Config, req *policy.Request, provider providers.Provider, model string,
+) policy.Decision {
+	forProvider := *req
+	forProvider.Provider = provider.Name()
+	forProvider.ProviderModel = provider.MapModel(model)
+	return policy.Decide(cfg.Access.Policies, cfg.Access.GetDefaultEffect(), &forProvider)
+}
+
+// filterAccess returns the candidates the access policies allow the request on.
+func (h *Handler) filterAccess(
+	ctx context.Context, model string, candidates []router.ProviderInfo,
+) []router.ProviderInfo {
+	req, pending := accessRequestFromContext(ctx)
+	cfg := h.getRuntimeConfigGetter()
+	if !pending || cfg == nil {
+		return candidates
+	}
+
+	allowed := lo.Filter(candidates, func(info router.ProviderInfo, _ int) bool {
+		return decideForProvider(cfg, req, info.Provider, model).Allowed
+	})
+	if excluded := len(candidates) - len(allowed); excluded > 0 {
+		zerolog.Ctx(ctx).Debug().Int("excluded", excluded).Msg("excluded providers denied by access policies")
+	}
+	return allowed
+}
+
+// accessDeniedError returns errAccessDenied with the message of the policy
+// that denies the request on provider.
+func (h *Handler) accessDeniedError(ctx context.Context, model string, provider providers.Provider) error {
+	req, _ := accessRequestFromContext(ctx)
+	decision := decideForProvider(h.getRuntimeConfigGetter(), req, provider, model)
+	return fmt.Errorf("%w: %s", errAccessDenied, decision.Message())
+}
+
+func logAccessDenied(ctx context.Context, decision policy.Decision) {
+	event := zerolog.Ctx(ctx).Info()
+	if decision.Policy != nil {
+		event = event.Str("policy", decision.Policy.Name)
+	}
+	event.Msg("request denied by access policy")
+}
+
+// allowsListedModel reports whether client may use model on provider, judged
+// by the client's restrictions and by access policies for a request for model
+// with no tools, thinking or max_tokens.
+func allowsListedModel(cfg *config.Config, client *auth.Client, provider providers.Provider, model string) bool {
+	if !client.AllowsModel(model) || !client.AllowsProvider(provider.Name()) {
+		return false
+	}
+	if cfg == nil || !cfg.Access.IsEnabled() {
+		return true
+	}
+	clientName := ""
+	if client != nil {
+		clientName = client.Name
+	}
+	req := accessRequest(client, &rules.Request{
+		Time:   time.Now(),
+		Header: nil,
+		Model:  model,
+		Client: clientName,
+		Body:   nil,
+	})
+	return decideForProvider(cfg, req, provider, model).Allowed
+}
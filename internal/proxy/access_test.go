@@ -0,0 +1,154 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/policy"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+func accessPolicy(name, effect string, match *policy.Match) policy.Policy {
+	return policy.Policy{Name: name, Effect: effect, Message: "", Match: *match}
+}
+
+func accessMatch() *policy.Match {
+	return &policy.Match{
+		Metadata:       nil,
+		HasTools:       nil,
+		HasThinking:    nil,
+		Clients:        nil,
+		Models:         nil,
+		ProviderModels: nil,
+		Providers:      nil,
+		MaxTokensAbove: 0,
+	}
+}
+
+// accessConfig returns a live config with routing debug headers and the given policies.
+func accessConfig(defaultEffect string, policies ...policy.Policy) *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Debug = true
+	cfg.Access = config.AccessConfig{DefaultEffect: defaultEffect, Policies: policies}
+	return cfg
+}
+
+func accessClient(name string) *auth.Client {
+	return &auth.Client{Metadata: nil, Name: name, AllowedModels: nil, AllowedProviders: nil}
+}
+
+func TestHandlerAccessDeniesOnArrival(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0), proxy.NewNamedProvider(providerAName, backend.URL))
+	noSonnet := accessMatch()
+	noSonnet.Clients = []string{"alice"}
+	noSonnet.Models = []string{"claude-sonnet"}
+	denied := accessPolicy("no-sonnet", policy.EffectDeny, noSonnet)
+	denied.Message = "sonnet is not available to alice"
+	runtime := config.NewRuntime(accessConfig("", denied))
+	handler.SetRuntimeConfigGetter(runtime)
+
+	rr := serveAsClient(t, handler, accessClient("alice"))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "permission_error", gjson.Get(rr.Body.String(), "error.type").String())
+	assert.Equal(t, denied.Message, gjson.Get(rr.Body.String(), "error.message").String())
+
+	rr = serveAsClient(t, handler, accessClient("bob"))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// A default deny applies to requests no policy allows, including those without a client
+	bob := accessMatch()
+	bob.Clients = []string{"bob"}
+	runtime.Store(accessConfig(policy.EffectDeny, accessPolicy("bob", policy.EffectAllow, bob)))
+	rr = serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "request denied by the default access policy", gjson.Get(rr.Body.String(), "error.message").String())
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, accessClient("bob")).Code)
+}
+
+func TestHandlerAccessProviderPolicies(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	mapped := providers.NewAnthropicProvider(providerAName, backend.URL, nil,
+		map[string]string{"claude-sonnet-4-20250514": "internal-sonnet"})
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		mapped, proxy.NewNamedProvider(providerBName, backend.URL))
+	runtime := config.NewRuntime(accessConfig(""))
+	handler.SetRuntimeConfigGetter(runtime)
+
+	rr := serveAsClient(t, handler, accessClient("alice"))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+
+	// provider_models match the model after the provider's model mapping
+	internal := accessMatch()
+	internal.ProviderModels = []string{"internal-"}
+	runtime.Store(accessConfig("", accessPolicy("no-internal", policy.EffectDeny, internal)))
+	rr = serveAsClient(t, handler, accessClient("alice"))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+
+	both := accessMatch()
+	both.Providers = []string{providerAName, providerBName}
+	runtime.Store(accessConfig("", accessPolicy("no-providers", policy.EffectDeny, both)))
+	rr = serveAsClient(t, handler, accessClient("alice"))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "permission_error", gjson.Get(rr.Body.String(), "error.type").String())
+	assert.Contains(t, gjson.Get(rr.Body.String(), "error.message").String(), `access policy "no-providers"`)
+}
+
+func TestClientModelsHandlerFiltersModels(t *testing.T) {
+	t.Parallel()
+
+	models := []string{"claude-opus-4", "claude-sonnet-4"}
+	provs := []providers.Provider{
+		providers.NewAnthropicProvider(providerAName, "", models, nil),
+		providers.NewAnthropicProvider(providerBName, "", models, nil),
+	}
+	noOpus := accessMatch()
+	noOpus.Clients = []string{"alice"}
+	noOpus.Models = []string{"claude-opus"}
+	noB := accessMatch()
+	noB.Providers = []string{providerBName}
+	runtime := config.NewRuntime(accessConfig("",
+		accessPolicy("no-opus", policy.EffectDeny, noOpus), accessPolicy("no-b", policy.EffectDeny, noB)))
+	handler := proxy.NewClientModelsHandler(func() []providers.Provider { return provs }, runtime)
+
+	listModels := func(client *auth.Client) []string {
+		req := httptest.NewRequestWithContext(context.Background(), "GET", "/v1/models", http.NoBody)
+		if client != nil {
+			req = req.WithContext(auth.WithClient(req.Context(), client))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response proxy.ModelsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		ids := make([]string, 0, len(response.Data))
+		for _, model := range response.Data {
+			ids = append(ids, model.Provider+"/"+model.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"provider-a/claude-opus-4", "provider-a/claude-sonnet-4"}, listModels(nil))
+	assert.Equal(t, []string{"provider-a/claude-sonnet-4"}, listModels(accessClient("alice")))
+
+	restricted := accessClient("bob")
+	restricted.AllowedModels = []string{"claude-opus"}
+	assert.Equal(t, []string{"provider-a/claude-opus-4"}, listModels(restricted))
+}
@@ -0,0 +1,275 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// ConfigReloader reloads the configuration on demand and reports which
+// configuration is running. The DI config service implements it.
+type ConfigReloader interface {
+	Reload() error
+	Version() config.Version
+}
+
+// AdminOptions configures the admin API.
+type AdminOptions struct {
+	ConfigProvider     config.RuntimeConfigGetter
+	Reloader           ConfigReloader
+	ProviderRouter     router.ProviderRouter
+	HealthTracker      *health.Tracker
+	ConcurrencyLimiter *ConcurrencyLimiter
+	GetProviders       ProvidersGetter
+	GetProviderPools   KeyPoolsFunc
+}
+
+// AdminStatus is the response of GET /admin/status.
+type AdminStatus struct {
+	Config      *config.Version        `json:"config,omitempty"`
+	Routing     AdminRoutingStatus     `json:"routing"`
+	Providers   []AdminProviderStatus  `json:"providers"`
+	Concurrency AdminConcurrencyStatus `json:"concurrency"`
+}
+
+// AdminRoutingStatus reports the active routing strategy.
+type AdminRoutingStatus struct {
+	Strategy string `json:"strategy"`
+}
+
+// AdminConcurrencyStatus reports the global concurrency limiter.
+// A limit of 0 means unlimited.
+type AdminConcurrencyStatus struct {
+	InFlight int64 `json:"in_flight"`
+	Limit    int64 `json:"limit"`
+}
+
+// AdminProviderStatus is the runtime state of one provider.
+type AdminProviderStatus struct {
+	Name       string             `json:"name"`
+	Circuit    string             `json:"circuit"`
+	Keys       []keypool.KeyStats `json:"keys"`
+	ForcedOpen bool               `json:"forced_open"`
+	Drained    bool               `json:"drained"`
+}
+
+// AdminHandler serves the admin API under /admin.
+// Requests must carry the live config's admin token as a bearer token;
+// client credentials never grant access.
+//
+// Routes:
+//   - GET /admin/status - Circuits, keys, in-flight requests, strategy and config version
+//   - POST /admin/config/reload - Reload the config file now
+//   - POST /admin/providers/{provider}/circuit/open - Pin the circuit open
+//   - POST /admin/providers/{provider}/circuit/close - Release and reset the circuit
+//   - POST, DELETE /admin/providers/{provider}/drain - Drain or undrain the provider
+//   - POST, DELETE /admin/providers/{provider}/keys/{key}/disable - Disable or enable a key
+type AdminHandler struct {
+	mux  *http.ServeMux
+	opts AdminOptions
+}
+
+// NewAdminHandler creates the admin API handler.
+// Nil getters are treated as returning no providers or key pools.
+func NewAdminHandler(opts *AdminOptions) *AdminHandler {
+	handler := &AdminHandler{mux: http.NewServeMux(), opts: *opts}
+	if handler.opts.GetProviders == nil {
+		handler.opts.GetProviders = func() []providers.Provider { return nil }
+	}
+	if handler.opts.GetProviderPools == nil {
+		handler.opts.GetProviderPools = func() map[string]*keypool.KeyPool { return nil }
+	}
+
+	const providerPath = config.AdminPath + "/providers/{provider}"
+	handler.mux.HandleFunc("GET "+config.AdminPath+"/status", handler.serveStatus)
+	handler.mux.HandleFunc("POST "+config.AdminPath+"/config/reload", handler.serveReload)
+	handler.mux.HandleFunc("POST "+providerPath+"/circuit/open", handler.serveCircuit(true))
+	handler.mux.HandleFunc("POST "+providerPath+"/circuit/close", handler.serveCircuit(false))
+	handler.mux.HandleFunc("POST "+providerPath+"/drain", handler.serveDrain(true))
+	handler.mux.HandleFunc("DELETE "+providerPath+"/drain", handler.serveDrain(false))
+	handler.mux.HandleFunc("POST "+providerPath+"/keys/{key}/disable", handler.serveKeyDisable(true))
+	handler.mux.HandleFunc("DELETE "+providerPath+"/keys/{key}/disable", handler.serveKeyDisable(false))
+	return handler
+}
+
+// ServeHTTP authenticates the request against the live admin config before routing it,
+// so unauthenticated callers cannot probe which routes exist. Without an admin
+// token every request is refused.
+func (h *AdminHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	cfg := getRuntimeConfig(h.opts.ConfigProvider)
+	if cfg == nil || !cfg.Admin.Enabled {
+		WriteError(writer, http.StatusNotFound, "not_found_error", "admin API is disabled")
+		return
+	}
+	// Config validation requires a token, but a bearer authenticator without
+	// one accepts every request, so do not rely on it here.
+	if cfg.Admin.Token == "" {
+		zerolog.Ctx(request.Context()).Warn().Msg("admin API has no token configured, refusing request")
+		WriteError(writer, http.StatusForbidden, "permission_error", "admin API has no token configured")
+		return
+	}
+	result := auth.NewBearerAuthenticator(cfg.Admin.Token).Validate(request)
+	if !handleAuthResult(request.Context(), writer, result) {
+		return
+	}
+	h.mux.ServeHTTP(writer, request)
+}
+
+func (h *AdminHandler) serveStatus(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, http.StatusOK, h.status())
+}
+
+func (h *AdminHandler) serveReload(writer http.ResponseWriter, request *http.Request) {
+	if h.opts.Reloader == nil {
+		WriteError(writer, http.StatusServiceUnavailable, "api_error", "config reload is not available")
+		return
+	}
+	if err := h.opts.Reloader.Reload(); err != nil {
+		zerolog.Ctx(request.Context()).Error().Err(err).Msg("admin config reload failed")
+		WriteError(writer, http.StatusInternalServerError, "api_error", "config reload failed: "+err.Error())
+		return
+	}
+	zerolog.Ctx(request.Context()).Info().
+		Uint64("config_generation", h.opts.Reloader.Version().Generation).
+		Msg("admin reloaded config")
+	writeJSON(writer, http.StatusOK, h.status())
+}
+
+func (h *AdminHandler) serveCircuit(open bool) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name, ok := h.requireProvider(writer, request)
+		if !ok || !h.requireHealthTracker(writer) {
+			return
+		}
+		if open {
+			h.opts.HealthTracker.ForceOpen(name)
+		} else {
+			h.opts.HealthTracker.ForceClose(name)
+		}
+		zerolog.Ctx(request.Context()).Info().Str("provider", name).Bool("open", open).Msg("admin forced circuit")
+		writeJSON(writer, http.StatusOK, h.providerStatus(name))
+	}
+}
+
+func (h *AdminHandler) serveDrain(drained bool) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name, ok := h.requireProvider(writer, request)
+		if !ok || !h.requireHealthTracker(writer) {
+			return
+		}
+		h.opts.HealthTracker.SetDrained(name, drained)
+		zerolog.Ctx(request.Context()).Info().Str("provider", name).Bool("drained", drained).Msg("admin drained provider")
+		writeJSON(writer, http.StatusOK, h.providerStatus(name))
+	}
+}
+
+func (h *AdminHandler) serveKeyDisable(disabled bool) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name, ok := h.requireProvider(writer, request)
+		if !ok {
+			return
+		}
+		keyID := request.PathValue("key")
+		pool := h.opts.GetProviderPools()[name]
+		if pool == nil {
+			WriteError(writer, http.StatusNotFound, "not_found_error",
+				fmt.Sprintf("provider %q has no key pool", name))
+			return
+		}
+		if err := pool.SetKeyDisabled(keyID, disabled); err != nil {
+			if errors.Is(err, keypool.ErrKeyNotFound) {
+				WriteError(writer, http.StatusNotFound, "not_found_error",
+					fmt.Sprintf("key %q not found in provider %q", keyID, name))
+				return
+			}
+			WriteError(writer, http.StatusInternalServerError, "api_error", err.Error())
+			return
+		}
+		zerolog.Ctx(request.Context()).Info().
+			Str("provider", name).
+			Str("key_id", keyID).
+			Bool("disabled", disabled).
+			Msg("admin changed key availability")
+		writeJSON(writer, http.StatusOK, h.providerStatus(name))
+	}
+}
+
+// requireProvider returns the provider named in the path, or writes a 404.
+func (h *AdminHandler) requireProvider(writer http.ResponseWriter, request *http.Request) (string, bool) {
+	name := request.PathValue("provider")
+	_, found := lo.Find(h.opts.GetProviders(), func(prov providers.Provider) bool {
+		return prov.Name() == name
+	})
+	if !found {
+		WriteError(writer, http.StatusNotFound, "not_found_error", fmt.Sprintf("provider %q not found", name))
+	}
+	return name, found
+}
+
+func (h *AdminHandler) requireHealthTracker(writer http.ResponseWriter) bool {
+	if h.opts.HealthTracker == nil {
+		WriteError(writer, http.StatusServiceUnavailable, "api_error", "health tracking is not available")
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) status() AdminStatus {
+	status := AdminStatus{
+		Config:  nil,
+		Routing: AdminRoutingStatus{Strategy: h.strategy()},
+		Providers: lo.Map(h.opts.GetProviders(), func(prov providers.Provider, _ int) AdminProviderStatus {
+			return h.providerStatus(prov.Name())
+		}),
+		Concurrency: AdminConcurrencyStatus{InFlight: 0, Limit: 0},
+	}
+	if h.opts.Reloader != nil {
+		version := h.opts.Reloader.Version()
+		status.Config = &version
+	}
+	if limiter := h.opts.ConcurrencyLimiter; limiter != nil {
+		status.Concurrency = AdminConcurrencyStatus{InFlight: limiter.CurrentInFlight(), Limit: limiter.GetLimit()}
+	}
+	return status
+}
+
+func (h *AdminHandler) providerStatus(name string) AdminProviderStatus {
+	status := AdminProviderStatus{
+		Keys:       []keypool.KeyStats{},
+		Name:       name,
+		Circuit:    health.StateClosed.String(),
+		ForcedOpen: false,
+		Drained:    false,
+	}
+	if tracker := h.opts.HealthTracker; tracker != nil {
+		status.Circuit = tracker.GetState(name).String()
+		status.ForcedOpen = tracker.IsForcedOpen(name)
+		status.Drained = tracker.IsDrained(name)
+	}
+	if pool := h.opts.GetProviderPools()[name]; pool != nil {
+		status.Keys = pool.GetKeyStats()
+	}
+	return status
+}
+
+// strategy returns the name of the router in use, which follows config
+// reloads, or the configured strategy when there is no router.
+func (h *AdminHandler) strategy() string {
+	if h.opts.ProviderRouter != nil {
+		return h.opts.ProviderRouter.Name()
+	}
+	if cfg := getRuntimeConfig(h.opts.ConfigProvider); cfg != nil {
+		return cfg.Routing.GetEffectiveStrategy()
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/health"
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const testAdminToken = "admin-secret"
+
+func newAdminTestTracker() *health.Tracker {
+	logger := zerolog.Nop()
+	cfg := health.CircuitBreakerConfig{OpenDurationMS: 0, FailureThreshold: 0, HalfOpenProbes: 0}
+	return health.NewTracker(cfg, &logger)
+}
+
+type fakeReloader struct {
+	err        error
+	generation atomic.Uint64
+}
+
+func (f *fakeReloader) Reload() error {
+	if f.err != nil {
+		return f.err
+	}
+	f.generation.Add(1)
+	return nil
+}
+
+func (f *fakeReloader) Version() config.Version {
+	return config.Version{LoadedAt: time.Time{}, Path: "/etc/cc-relay.yaml", Generation: f.generation.Load() + 1}
+}
+
+type adminFixture struct {
+	handler  http.Handler
+	runtime  *config.Runtime
+	tracker  *health.Tracker
+	pool     *keypool.KeyPool
+	limiter  *proxy.ConcurrencyLimiter
+	reloader *fakeReloader
+}
+
+func newAdminFixture(t *testing.T) *adminFixture {
+	t.Helper()
+
+	cfg := proxy.TestConfig(testAPIKey)
+	cfg.Admin = config.AdminConfig{Token: testAdminToken, Enabled: true}
+	pool, err := keypool.NewKeyPool(providerAName, keypool.PoolConfig{
+		Strategy: testStrategyLeastLoaded,
+		Keys:     []keypool.KeyConfig{proxy.TestKeyConfig("sk-a-1"), proxy.TestKeyConfig("sk-a-2")},
+	})
+	require.NoError(t, err)
+
+	fix := &adminFixture{
+		handler:  nil,
+		runtime:  config.NewRuntime(cfg),
+		tracker:  newAdminTestTracker(),
+		pool:     pool,
+		limiter:  proxy.NewConcurrencyLimiter(10),
+		reloader: &fakeReloader{err: nil, generation: atomic.Uint64{}},
+	}
+
+	providerA := proxy.NewNamedProvider(providerAName, "http://provider-a.invalid")
+	providerB := proxy.NewNamedProvider(providerBName, "http://provider-b.invalid")
+	opts := proxy.TestRoutesOptions(nil)
+	opts.ConfigProvider = fix.runtime
+	opts.ConfigReloader = fix.reloader
+	opts.Provider = providerA
+	opts.ProviderRouter = router.NewFailoverRouter(0)
+	opts.GetAllProviders = func() []providers.Provider { return []providers.Provider{providerA, providerB} }
+	opts.GetProviderPools = func() map[string]*keypool.KeyPool { return map[string]*keypool.KeyPool{providerAName: pool} }
+	opts.HealthTracker = fix.tracker
+	opts.ConcurrencyLimiter = fix.limiter
+
+	fix.handler, err = proxy.SetupRoutesWithLiveKeyPools(&opts)
+	require.NoError(t, err)
+	return fix
+}
+
+func (f *adminFixture) do(t *testing.T, method, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), method, path, http.NoBody)
+	req.Header.Set(testHeaderAuth, "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeAdmin[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var out T
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out), rec.Body.String())
+	return out
+}
+
+func TestAdminRequiresAdminToken(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+	tests := []struct {
+		name   string
+		header string
+		value  string
+	}{
+		{name: "no credentials", header: "", value: ""},
+		{name: "wrong token", header: testHeaderAuth, value: "Bearer wrong"},
+		{name: "proxy api key", header: proxy.APIKeyHeader, value: testAPIKey},
+		{name: "proxy api key as bearer", header: testHeaderAuth, value: "Bearer " + testAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/status", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			fix.handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		})
+	}
+}
+
+func TestAdminRefusesRequestsWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+	cfg := *fix.runtime.Get()
+	cfg.Admin.Token = ""
+	fix.runtime.Store(&cfg)
+
+	for _, value := range []string{"", "Bearer ", "Bearer anything"} {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/status", http.NoBody)
+		if value != "" {
+			req.Header.Set(testHeaderAuth, value)
+		}
+		rec := httptest.NewRecorder()
+		fix.handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, "authorization %q", value)
+	}
+}
+
+func TestAdminDisabled(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not routed when disabled at startup", func(t *testing.T) {
+		t.Parallel()
+		backend := proxy.NewBackendServer(t, `{"ok":true}`)
+		runtimeCfg := config.NewRuntime(proxy.TestConfig(""))
+		handler := newLiveKeyPoolsHandler(t, runtimeCfg, proxy.NewTestProvider(backend.URL), nil)
+
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/status", http.NoBody)
+		req.Header.Set(testHeaderAuth, "Bearer "+testAdminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("refused when disabled by reload", func(t *testing.T) {
+		t.Parallel()
+		fix := newAdminFixture(t)
+		cfg := *fix.runtime.Get()
+		cfg.Admin.Enabled = false
+		fix.runtime.Store(&cfg)
+
+		assert.Equal(t, http.StatusNotFound, fix.do(t, http.MethodGet, "/admin/status").Code)
+	})
+}
+
+func TestAdminStatus(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+	require.True(t, fix.limiter.TryAcquire())
+	defer fix.limiter.Release()
+	keyID := fix.pool.Keys()[0].ID
+	fix.pool.MarkKeyExhausted(keyID, time.Minute)
+
+	rec := fix.do(t, http.MethodGet, "/admin/status")
+	require.Equal(t, http.StatusOK, rec.Code)
+	status := decodeAdmin[proxy.AdminStatus](t, rec)
+
+	require.NotNil(t, status.Config)
+	assert.Equal(t, uint64(1), status.Config.Generation)
+	assert.Equal(t, router.StrategyFailover, status.Routing.Strategy)
+	assert.Equal(t, proxy.AdminConcurrencyStatus{InFlight: 1, Limit: 10}, status.Concurrency)
+
+	require.Len(t, status.Providers, 2)
+	providerA := status.Providers[0]
+	assert.Equal(t, providerAName, providerA.Name)
+	assert.Equal(t, "closed", providerA.Circuit)
+	require.Len(t, providerA.Keys, 2)
+	assert.Equal(t, keyID, providerA.Keys[0].ID)
+	assert.False(t, providerA.Keys[0].Available)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), providerA.Keys[0].CooldownUntil, 5*time.Second)
+	assert.Equal(t, providerBName, status.Providers[1].Name)
+	assert.Empty(t, status.Providers[1].Keys)
+	assert.NotContains(t, rec.Body.String(), "sk-a-1", "key values must never be exposed")
+}
+
+func TestAdminForceCircuit(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+	isHealthy := fix.tracker.IsHealthyFunc(providerAName)
+
+	rec := fix.do(t, http.MethodPost, "/admin/providers/provider-a/circuit/open")
+	require.Equal(t, http.StatusOK, rec.Code)
+	status := decodeAdmin[proxy.AdminProviderStatus](t, rec)
+	assert.Equal(t, "open", status.Circuit)
+	assert.True(t, status.ForcedOpen)
+	assert.False(t, isHealthy())
+
+	rec = fix.do(t, http.MethodPost, "/admin/providers/provider-a/circuit/close")
+	require.Equal(t, http.StatusOK, rec.Code)
+	status = decodeAdmin[proxy.AdminProviderStatus](t, rec)
+	assert.Equal(t, "closed", status.Circuit)
+	assert.False(t, status.ForcedOpen)
+	assert.True(t, isHealthy())
+
+	assert.Equal(t, http.StatusNotFound, fix.do(t, http.MethodPost, "/admin/providers/unknown/circuit/open").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed,
+		fix.do(t, http.MethodGet, "/admin/providers/provider-a/circuit/open").Code)
+}
+
+func TestAdminDrainProvider(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+
+	rec := fix.do(t, http.MethodPost, "/admin/providers/provider-b/drain")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, decodeAdmin[proxy.AdminProviderStatus](t, rec).Drained)
+	assert.True(t, fix.tracker.IsDrained(providerBName))
+
+	rec = fix.do(t, http.MethodDelete, "/admin/providers/provider-b/drain")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, decodeAdmin[proxy.AdminProviderStatus](t, rec).Drained)
+	assert.False(t, fix.tracker.IsDrained(providerBName))
+}
+
+func TestAdminDrainedProviderGetsNoRequests(t *testing.T) {
+	t.Parallel()
+
+	tracker := newAdminTestTracker()
+	drainedBackend := proxy.NewStatusBackend(t, http.StatusInternalServerError, `{"error":"drained"}`, nil)
+	healthyBackend := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	providerA := proxy.NewNamedProvider(providerAName, drainedBackend.URL)
+	providerB := proxy.NewNamedProvider(providerBName, healthyBackend.URL)
+
+	infoA := proxy.TestProviderInfoWithHealth(providerA, tracker.IsHealthyFunc(providerAName))
+	infoA.Priority = 2
+	infoB := proxy.TestProviderInfoWithHealth(providerB, tracker.IsHealthyFunc(providerBName))
+	infoB.Priority = 1
+
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = providerA
+	opts.ProviderInfos = []router.ProviderInfo{infoA, infoB}
+	opts.ProviderRouter = router.NewFailoverRouter(0)
+	opts.ProviderKeys = map[string]string{providerAName: testKey, providerBName: testKey}
+	opts.HealthTracker = tracker
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	tracker.SetDrained(providerAName, true)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "msg_ok")
+}
+
+func TestAdminDisableKey(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+	keyID := fix.pool.Keys()[1].ID
+
+	rec := fix.do(t, http.MethodPost, "/admin/providers/provider-a/keys/"+keyID+"/disable")
+	require.Equal(t, http.StatusOK, rec.Code)
+	status := decodeAdmin[proxy.AdminProviderStatus](t, rec)
+	require.Len(t, status.Keys, 2)
+	assert.True(t, status.Keys[1].Disabled)
+	assert.False(t, status.Keys[1].Available)
+	assert.False(t, fix.pool.Keys()[1].IsAvailable())
+
+	rec = fix.do(t, http.MethodDelete, "/admin/providers/provider-a/keys/"+keyID+"/disable")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, decodeAdmin[proxy.AdminProviderStatus](t, rec).Keys[1].Disabled)
+	assert.True(t, fix.pool.Keys()[1].IsAvailable())
+
+	assert.Equal(t, http.StatusNotFound,
+		fix.do(t, http.MethodPost, "/admin/providers/provider-a/keys/deadbeef/disable").Code)
+	assert.Equal(t, http.StatusNotFound,
+		fix.do(t, http.MethodPost, "/admin/providers/provider-b/keys/"+keyID+"/disable").Code,
+		"provider-b has no key pool")
+}
+
+func TestAdminReloadConfig(t *testing.T) {
+	t.Parallel()
+
+	fix := newAdminFixture(t)
+
+	rec := fix.do(t, http.MethodPost, "/admin/config/reload")
+	require.Equal(t, http.StatusOK, rec.Code)
+	status := decodeAdmin[proxy.AdminStatus](t, rec)
+	require.NotNil(t, status.Config)
+	assert.Equal(t, uint64(2), status.Config.Generation)
+
+	fix.reloader.err = errors.New("yaml: line 3: mapping values are not allowed")
+	rec = fix.do(t, http.MethodPost, "/admin/config/reload")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "config reload failed: yaml: line 3")
+}
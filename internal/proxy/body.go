@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// readBody reads the request body and puts it back, so later handlers and
+// the provider still see it. The body is restored with the bytes read even
+// if reading fails. A request without a body reads as nil.
+func readBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(request.Body)
+	closeBody(request.Body)
+	setBody(request, body)
+	return body, err
+}
+
+// setBody replaces the request body and its ContentLength.
+func setBody(request *http.Request, body []byte) {
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	request.ContentLength = int64(len(body))
+}
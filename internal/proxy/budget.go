@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/config"
+)
+
+// budgetScopes returns the budgets that apply to a request from client,
+// which is nil for requests not authenticated with a virtual key or JWT.
+// Client budgets are looked up in the live config so reloads apply at once.
+func budgetScopes(cfg *config.Config, client *auth.Client) []budget.Scope {
+	var scopes []budget.Scope
+	if !cfg.Budgets.Global.IsZero() {
+		scopes = append(scopes, budget.GlobalScope(cfg.Budgets.Global))
+	}
+	if client == nil {
+		return scopes
+	}
+	clientCfg, found := lo.Find(cfg.Server.Auth.Clients, func(candidate config.ClientKeyConfig) bool {
+		return candidate.Name == client.Name
+	})
+	if found && !clientCfg.Budget.IsZero() {
+		scopes = append(scopes, budget.ClientScope(client.Name, clientCfg.Budget))
+	}
+	return scopes
+}
+
+// checkBudgets refuses the request with a 429 once one of its budgets is used
+// up, and adds a warning header for each budget past its soft limit.
+// Budgets fail open: if usage cannot be read, the request is allowed.
+func (h *Handler) checkBudgets(writer http.ResponseWriter, request *http.Request) bool {
+	cfg := h.getRuntimeConfigGetter()
+	if h.budgets == nil || cfg == nil {
+		return true
+	}
+	scopes := budgetScopes(cfg, auth.ClientFromContext(request.Context()))
+	if len(scopes) == 0 {
+		return true
+	}
+
+	logger := zerolog.Ctx(request.Context())
+	status, err := h.budgets.Check(request.Context(), scopes, cfg.Budgets.GetSoftLimitPercent())
+	if err != nil {
+		logger.Warn().Err(err).Msg("budget check failed, allowing request")
+		return true
+	}
+
+	for _, warning := range status.Warnings {
+		writer.Header().Add(HeaderRelayBudget, fmt.Sprintf("%s %s=%d%%", warning.Scope, warning.Limit, warning.Percent()))
+	}
+	if len(status.Exceeded) == 0 {
+		return true
+	}
+
+	// The request is refused until every exhausted budget has reset.
+	exceeded := lo.MaxBy(status.Exceeded, func(a, b budget.LimitStatus) bool {
+		return a.ResetAt.After(b.ResetAt)
+	})
+	retryAfter := max(int(time.Until(exceeded.ResetAt).Seconds()), 1)
+	writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	logger.Warn().
+		Str("budget_scope", exceeded.Scope).
+		Str("budget_limit", exceeded.Limit).
+		Time("budget_reset", exceeded.ResetAt).
+		Msg("budget exhausted, rejecting request")
+
+	WriteError(writer, http.StatusTooManyRequests, "rate_limit_error",
+		fmt.Sprintf("%s budget %s exhausted (used %s of %s), resets at %s",
+			exceeded.Scope, exceeded.Limit,
+			formatBudgetAmount(exceeded.Limit, exceeded.Used), formatBudgetAmount(exceeded.Limit, exceeded.Max),
+			exceeded.ResetAt.Format(time.RFC3339)))
+	return false
+}
+
+// budgetCharger returns a charger recording usage against the request's
+// budgets, or nil if no budget applies. Spend is priced by the requested model.
+func (h *Handler) budgetCharger(ctx context.Context) usageCharger {
+	cfg := h.getRuntimeConfigGetter()
+	if h.budgets == nil || cfg == nil {
+		return nil
+	}
+	scopes := budgetScopes(cfg, auth.ClientFromContext(ctx))
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	model := requestModel(ctx)
+	price, priced := spendPrice(ctx, cfg, model)
+	return func(chargeCtx context.Context, usage tokenUsage) {
+		spent := budget.Usage{
+			InputTokens:         usage.inputTokens,
+			OutputTokens:        usage.outputTokens,
+			CacheCreationTokens: usage.cacheCreationTokens,
+			CacheReadTokens:     usage.cacheReadTokens,
+		}
+		cost := 0.0
+		if priced {
+			cost = price.Cost(spent)
+		} else {
+			zerolog.Ctx(chargeCtx).Debug().Str("model", model).Msg("no budget price for model, spend not counted")
+		}
+		if err := h.budgets.Record(chargeCtx, scopes, spent, cost); err != nil {
+			zerolog.Ctx(chargeCtx).Warn().Err(err).Msg("failed to record budget usage")
+		}
+	}
+}
+
+// spendPrice returns the price of a request for model: the serving
+// provider's price if it has one, otherwise the budgets price table.
+func spendPrice(ctx context.Context, cfg *config.Config, model string) (budget.Price, bool) {
+	if providerName, ok := ctx.Value(providerNameContextKey).(string); ok {
+		providerCfg, found := lo.Find(cfg.Providers, func(candidate config.ProviderConfig) bool {
+			return candidate.Name == providerName
+		})
+		if price, priced := providerCfg.PriceFor(model); found && priced {
+			return price.BudgetPrice(), true
+		}
+	}
+	return cfg.Budgets.PriceFor(model)
+}
+
+// formatBudgetAmount formats a budget amount in the unit of its limit.
+func formatBudgetAmount(limit string, amount float64) string {
+	if limit == budget.LimitUSDPerMonth {
+		return fmt.Sprintf("$%.2f", amount)
+	}
+	return strconv.FormatFloat(amount, 'f', 0, 64) + " tokens"
+}
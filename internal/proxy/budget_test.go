@@ -0,0 +1,189 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+)
+
+// budgetUsageBody reports 120 tokens: 80 input and 40 output.
+const budgetUsageBody = `{"id":"msg_1","usage":{"input_tokens":80,"output_tokens":40}}`
+
+func newBudgetHandler(t *testing.T, cfg *config.Config) (*proxy.Handler, *budget.Tracker) {
+	t.Helper()
+	backend := proxy.NewJSONBackend(t, budgetUsageBody)
+
+	tracker := budget.NewTracker(nil)
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = proxy.NewNamedProvider(providerAName, backend.URL)
+	opts.APIKey = testKey
+	opts.Budgets = tracker
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+	handler.SetRuntimeConfigGetter(config.NewRuntime(cfg))
+	return handler, tracker
+}
+
+// awaitBudgetStatus waits until usage is recorded, which happens once the
+// response body has been read, so that the next request sees it.
+func awaitBudgetStatus(t *testing.T, tracker *budget.Tracker, scope budget.Scope, softPercent, exceeded, warnings int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		status, err := tracker.Check(context.Background(), []budget.Scope{scope}, softPercent)
+		return err == nil && len(status.Exceeded) == exceeded && len(status.Warnings) == warnings
+	}, time.Second, 10*time.Millisecond, "usage should be recorded")
+}
+
+func budgetClient(name string) *auth.Client {
+	return &auth.Client{Metadata: nil, Name: name, AllowedModels: nil, AllowedProviders: nil}
+}
+
+func budgetConfig(clientLimits budget.Limits) *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Server.Auth.Clients = []config.ClientKeyConfig{{
+		Metadata:         nil,
+		Name:             "alice",
+		KeyHash:          auth.HashKey("alice-key"),
+		CertNames:        nil,
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+		Budget:           clientLimits,
+		RateLimit:        ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0},
+	}}
+	return cfg
+}
+
+func TestHandlerEnforcesClientTokenBudget(t *testing.T) {
+	t.Parallel()
+
+	limits := budget.Limits{TokensPerDay: 100, TokensPerMonth: 0, USDPerMonth: 0}
+	handler, tracker := newBudgetHandler(t, budgetConfig(limits))
+
+	first := serveAsClient(t, handler, budgetClient("alice"))
+	require.Equal(t, http.StatusOK, first.Code, "budget is not used up before the first response")
+	awaitBudgetStatus(t, tracker, budget.ClientScope("alice", limits), 80, 1, 0)
+
+	refused := serveAsClient(t, handler, budgetClient("alice"))
+	require.Equal(t, http.StatusTooManyRequests, refused.Code)
+
+	retryAfter, err := strconv.Atoi(refused.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Positive(t, retryAfter)
+	assert.LessOrEqual(t, retryAfter, 24*60*60, "a daily budget resets within a day")
+
+	var errResp proxy.ErrorResponse
+	require.NoError(t, json.NewDecoder(refused.Body).Decode(&errResp))
+	assert.Equal(t, "rate_limit_error", errResp.Error.Type)
+	assert.Contains(t, errResp.Error.Message,
+		"client:alice budget tokens_per_day exhausted (used 120 tokens of 100 tokens)")
+
+	other := serveAsClient(t, handler, budgetClient("bob"))
+	assert.Equal(t, http.StatusOK, other.Code, "clients without a budget are not limited")
+}
+
+func TestHandlerBudgetSoftLimitWarning(t *testing.T) {
+	t.Parallel()
+
+	cfg := budgetConfig(budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0})
+	cfg.Budgets.Global.TokensPerMonth = 200
+	cfg.Budgets.SoftLimitPercent = 50
+	handler, tracker := newBudgetHandler(t, cfg)
+
+	first := serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(proxy.HeaderRelayBudget))
+	awaitBudgetStatus(t, tracker, budget.GlobalScope(cfg.Budgets.Global), 50, 0, 1)
+
+	second := serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "global tokens_per_month=60%", second.Header().Get(proxy.HeaderRelayBudget))
+}
+
+func TestHandlerEnforcesSpendBudget(t *testing.T) {
+	t.Parallel()
+
+	// 80 input and 40 output tokens cost $0.00084
+	cfg := budgetConfig(budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0.0008})
+	cfg.Budgets.Prices = map[string]budget.Price{
+		"claude-sonnet-4": {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 0, CacheReadPerMTok: 0},
+	}
+	handler, tracker := newBudgetHandler(t, cfg)
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	awaitBudgetStatus(t, tracker, budget.ClientScope("alice", cfg.Server.Auth.Clients[0].Budget), 80, 1, 0)
+
+	assert.Equal(t, http.StatusTooManyRequests, serveAsClient(t, handler, budgetClient("alice")).Code)
+}
+
+func TestHandlerSpendBudgetUsesProviderPrices(t *testing.T) {
+	t.Parallel()
+
+	// Priced by the budgets table the response costs $0.000016, by the
+	// serving provider's price $0.00084.
+	cfg := budgetConfig(budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0.0008})
+	cfg.Budgets.Prices = map[string]budget.Price{
+		"claude": {InputPerMTok: 0.1, OutputPerMTok: 0.2, CacheWritePerMTok: 0, CacheReadPerMTok: 0},
+	}
+	providerPrice := config.ModelPrice{
+		InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 0, CacheReadPerMTok: 0, Quality: 0,
+	}
+	cfg.Providers = []config.ProviderConfig{{
+		ModelMapping:       nil,
+		Prices:             map[string]config.ModelPrice{"claude-sonnet": providerPrice},
+		ModelLimits:        nil,
+		AWSRegion:          "",
+		GCPProjectID:       "",
+		AzureAPIVersion:    "",
+		Name:               providerAName,
+		Type:               "anthropic",
+		BaseURL:            "",
+		AzureDeploymentID:  "",
+		AWSAccessKeyID:     "",
+		AzureResourceName:  "",
+		AWSSecretAccessKey: "",
+		GCPRegion:          "",
+		Trust:              "",
+		Keys:               nil,
+		Models:             nil,
+		Pooling:            config.PoolingConfig{Strategy: "", Enabled: false},
+		Enabled:            true,
+	}}
+	handler, tracker := newBudgetHandler(t, cfg)
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	awaitBudgetStatus(t, tracker, budget.ClientScope("alice", cfg.Server.Auth.Clients[0].Budget), 80, 1, 0)
+
+	assert.Equal(t, http.StatusTooManyRequests, serveAsClient(t, handler, budgetClient("alice")).Code)
+}
+
+func TestHandlerBudgetsFollowLiveConfig(t *testing.T) {
+	t.Parallel()
+
+	unlimited := budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0}
+	limited := budget.Limits{TokensPerDay: 100, TokensPerMonth: 0, USDPerMonth: 0}
+	runtime := config.NewRuntime(budgetConfig(unlimited))
+	handler, tracker := newBudgetHandler(t, runtime.Get())
+	handler.SetRuntimeConfigGetter(runtime)
+
+	// Without a budget usage is not recorded
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+
+	// A budget added by a reload applies to the next request
+	runtime.Store(budgetConfig(limited))
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	awaitBudgetStatus(t, tracker, budget.ClientScope("alice", limited), 80, 1, 0)
+
+	assert.Equal(t, http.StatusTooManyRequests, serveAsClient(t, handler, budgetClient("alice")).Code)
+}
@@ -0,0 +1,273 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/openai"
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+// ChatCompletionsPath is the OpenAI-compatible Chat Completions endpoint.
+const ChatCompletionsPath = "/v1/chat/completions"
+
+const (
+	// anthropicMessagesPath is the path translated Chat Completions requests are served on.
+	anthropicMessagesPath = "/v1/messages"
+
+	// defaultAnthropicVersion is sent for translated requests, since OpenAI clients never set it.
+	defaultAnthropicVersion = "2023-06-01"
+)
+
+// ChatCompletionsHandler serves OpenAI Chat Completions requests through the
+// Messages pipeline. Requests are translated into Messages bodies and handed to
+// next as POST /v1/messages, so routing, key pools, failover and thinking
+// handling behave exactly as for Anthropic clients. Responses, including SSE
+// streams and errors, are translated back into the OpenAI format.
+type ChatCompletionsHandler struct {
+	authenticate func(http.Handler) http.Handler
+	next         http.Handler
+}
+
+// NewChatCompletionsHandler creates a Chat Completions handler in front of a
+// Messages handler. Requests pass through authenticate before their body is
+// read, so only authenticated requests are translated.
+func NewChatCompletionsHandler(
+	authenticate func(http.Handler) http.Handler, next http.Handler,
+) *ChatCompletionsHandler {
+	return &ChatCompletionsHandler{authenticate: authenticate, next: next}
+}
+
+// ServeHTTP implements http.Handler. Everything written on the way, auth
+// failures included, is in Messages format and translated by the writer.
+func (h *ChatCompletionsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	chatWriter := newChatCompletionsWriter(writer)
+	h.authenticate(http.HandlerFunc(func(_ http.ResponseWriter, authenticated *http.Request) {
+		h.translate(chatWriter, authenticated)
+	})).ServeHTTP(chatWriter, request)
+	chatWriter.finish()
+}
+
+// translate serves an authenticated Chat Completions request as a Messages request.
+func (h *ChatCompletionsHandler) translate(chatWriter *chatCompletionsWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	closeBody(request.Body)
+	if err != nil {
+		if IsBodyTooLargeError(err) {
+			WriteError(chatWriter, http.StatusRequestEntityTooLarge, "invalid_request_error",
+				"Request body exceeds the maximum allowed size")
+			return
+		}
+		WriteError(chatWriter, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return
+	}
+
+	translated, err := openai.ToAnthropicRequest(body)
+	if err != nil {
+		zerolog.Ctx(request.Context()).Debug().Err(err).Msg("rejected chat completions request")
+		WriteError(chatWriter, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	includeUsage := gjson.GetBytes(body, "stream_options.include_usage").Bool()
+	chatWriter.converter = openai.NewStreamConverter(time.Now().Unix(), includeUsage)
+	h.next.ServeHTTP(chatWriter, toMessagesRequest(request, translated))
+}
+
+// toMessagesRequest clones request as a Messages request carrying body.
+func toMessagesRequest(request *http.Request, body []byte) *http.Request {
+	messagesReq := request.Clone(request.Context())
+	messagesReq.URL.Path = anthropicMessagesPath
+	messagesReq.URL.RawPath = ""
+	messagesReq.Body = io.NopCloser(bytes.NewReader(body))
+	messagesReq.ContentLength = int64(len(body))
+
+	header := messagesReq.Header
+	header.Set("Content-Type", mediaTypeJSON)
+	header.Del("Content-Length")
+	// Let the transport negotiate compression so response bodies can be translated.
+	header.Del("Accept-Encoding")
+	if header.Get("anthropic-version") == "" {
+		header.Set("anthropic-version", defaultAnthropicVersion)
+	}
+	return messagesReq
+}
+
+// withoutClientCredentials drops client credentials after relay authentication.
+// An OpenAI client's key is never a valid provider credential, so translated
+// requests always use the configured provider keys instead of transparent auth.
+func withoutClientCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		request.Header.Del("Authorization")
+		request.Header.Del("x-api-key")
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// writeOpenAIError writes a JSON error response in OpenAI API format.
+func writeOpenAIError(writer http.ResponseWriter, statusCode int, errorType, message string) {
+	writeJSON(writer, statusCode, openai.NewErrorResponse(errorType, message))
+}
+
+// chatCompletionsWriter translates a Messages response written by the proxy
+// pipeline into a Chat Completions response. Successful SSE streams are
+// converted event by event as they arrive; JSON bodies, including errors, are
+// buffered and converted in finish. The stream converter is set once the
+// request has been translated.
+type chatCompletionsWriter struct {
+	dst         http.ResponseWriter
+	writeErr    error
+	converter   *openai.StreamConverter
+	lines       sseLineSplitter
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	streaming   bool
+}
+
+func newChatCompletionsWriter(dst http.ResponseWriter) *chatCompletionsWriter {
+	return &chatCompletionsWriter{
+		dst:         dst,
+		writeErr:    nil,
+		converter:   nil,
+		lines:       sseLineSplitter{pending: nil},
+		body:        bytes.Buffer{},
+		status:      0,
+		wroteHeader: false,
+		streaming:   false,
+	}
+}
+
+// Header returns the client response headers.
+func (w *chatCompletionsWriter) Header() http.Header {
+	return w.dst.Header()
+}
+
+// WriteHeader commits successful streams immediately and defers everything else to finish.
+func (w *chatCompletionsWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+
+	header := w.dst.Header()
+	if statusCode != http.StatusOK || !strings.HasPrefix(header.Get("Content-Type"), providers.ContentTypeSSE) {
+		return
+	}
+
+	w.streaming = true
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	SetSSEHeaders(header)
+	w.dst.WriteHeader(statusCode)
+}
+
+// Write converts stream events as they arrive, or buffers non-stream bodies.
+func (w *chatCompletionsWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.streaming {
+		return w.body.Write(data)
+	}
+	if w.writeErr == nil {
+		w.lines.feed(data, w.writeEvent)
+	}
+	if w.writeErr != nil {
+		return 0, w.writeErr
+	}
+	return len(data), nil
+}
+
+// Flush flushes translated stream chunks to the client.
+func (w *chatCompletionsWriter) Flush() {
+	if !w.streaming {
+		return
+	}
+	//nolint:errcheck // Flush is best-effort; unsupported writers simply buffer
+	http.NewResponseController(w.dst).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *chatCompletionsWriter) Unwrap() http.ResponseWriter {
+	return w.dst
+}
+
+// writeEvent converts one Messages SSE payload and writes the resulting chunks.
+func (w *chatCompletionsWriter) writeEvent(payload []byte) {
+	if w.writeErr != nil {
+		return
+	}
+	chunks, err := w.converter.Convert(payload)
+	if err != nil {
+		w.writeErr = err
+		return
+	}
+	for _, chunk := range chunks {
+		w.writeData(chunk)
+	}
+}
+
+func (w *chatCompletionsWriter) writeData(payload []byte) {
+	if w.writeErr != nil {
+		return
+	}
+	frame := make([]byte, 0, len(payload)+len("data: \n\n"))
+	frame = append(frame, "data: "...)
+	frame = append(frame, payload...)
+	frame = append(frame, "\n\n"...)
+	_, w.writeErr = w.dst.Write(frame)
+}
+
+// finish completes the response once the Messages pipeline has returned.
+// Streams that ended cleanly are terminated with [DONE]; truncated streams are
+// left open-ended so clients can tell they are incomplete. A pipeline that
+// wrote no body is reported as an upstream error.
+func (w *chatCompletionsWriter) finish() {
+	if w.streaming {
+		if w.converter.Done() {
+			w.writeData([]byte(openai.StreamDone))
+			w.Flush()
+		}
+		return
+	}
+
+	header := w.dst.Header()
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+
+	switch {
+	case w.wroteHeader && w.status != http.StatusOK:
+		writeJSON(w.dst, w.status, openai.FromAnthropicError(w.status, w.body.Bytes()))
+	case w.body.Len() == 0:
+		writeOpenAIError(w.dst, http.StatusBadGateway, "api_error", "upstream returned an empty response")
+	default:
+		w.writeCompletion()
+	}
+}
+
+// writeCompletion converts a successful Messages body into a completion.
+func (w *chatCompletionsWriter) writeCompletion() {
+	header := w.dst.Header()
+	converted, err := openai.FromAnthropicResponse(w.body.Bytes(), time.Now().Unix())
+	if err != nil {
+		writeOpenAIError(w.dst, http.StatusBadGateway, "api_error", "upstream returned an invalid response")
+		return
+	}
+
+	header.Set("Content-Type", mediaTypeJSON)
+	header.Set("Content-Length", strconv.Itoa(len(converted)))
+	w.dst.WriteHeader(http.StatusOK)
+	if _, err := w.dst.Write(converted); err != nil {
+		log.Error().Err(err).Msg("failed to write chat completions response")
+	}
+}
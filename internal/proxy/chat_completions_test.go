@@ -0,0 +1,286 @@
+package proxy_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const (
+	chatRelayKey    = "relay-client-key"
+	chatProviderKey = "sk-ant-provider-key"
+	chatRequestBody = `{"model":"claude-sonnet-4","messages":[` +
+		`{"role":"system","content":"Be terse."},{"role":"user","content":"Hi"}],"max_tokens":64}`
+	chatMessageResponse = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4",` +
+		`"content":[{"type":"text","text":"Hello!"}],"stop_reason":"end_turn",` +
+		`"usage":{"input_tokens":12,"output_tokens":3}}`
+	chatMessageStream = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4",` +
+		`"usage":{"input_tokens":12,"output_tokens":1}}}` + "\n\n" +
+		"event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello!"}}` + "\n\n" +
+		"event: content_block_stop\n" +
+		`data: {"type":"content_block_stop","index":0}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}` + "\n\n" +
+		"event: message_stop\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+)
+
+// chatBackend records the Messages request produced by the Chat Completions ingress.
+type chatBackend struct {
+	header http.Header
+	path   string
+	body   []byte
+	calls  int
+	mu     sync.Mutex
+}
+
+func (b *chatBackend) snapshot() (path string, header http.Header, body []byte, calls int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.path, b.header, b.body, b.calls
+}
+
+func newChatBackend(t *testing.T, status int, contentType, response string) (*httptest.Server, *chatBackend) {
+	t.Helper()
+
+	backend := &chatBackend{header: nil, path: "", body: nil, calls: 0, mu: sync.Mutex{}}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return
+		}
+		backend.mu.Lock()
+		backend.header = request.Header.Clone()
+		backend.path = request.URL.Path
+		backend.body = body
+		backend.calls++
+		backend.mu.Unlock()
+
+		writer.Header().Set(proxy.ContentTypeHeader, contentType)
+		writer.WriteHeader(status)
+		if _, err := writer.Write([]byte(response)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, backend
+}
+
+// chatBearerConfig returns a config accepting chatRelayKey as a bearer token,
+// the way OpenAI SDKs send their API key.
+func chatBearerConfig() *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Server.Auth.AllowBearer = true
+	cfg.Server.Auth.BearerSecret = chatRelayKey
+	return cfg
+}
+
+func newChatCompletionsRoutes(t *testing.T, backendURL string) http.Handler {
+	t.Helper()
+	return newChatCompletionsRoutesWithConfig(t, backendURL, chatBearerConfig())
+}
+
+func newChatCompletionsRoutesWithConfig(t *testing.T, backendURL string, cfg *config.Config) http.Handler {
+	t.Helper()
+
+	provider := proxy.NewTestProvider(backendURL)
+	routerInstance, err := router.NewRouter(router.StrategyRoundRobin, 5*time.Second)
+	require.NoError(t, err)
+
+	handler, err := proxy.SetupRoutesWithLiveKeyPools(&proxy.RoutesOptions{
+		ConfigProvider:     config.NewRuntime(cfg),
+		ConfigReloader:     nil,
+		Provider:           provider,
+		ProviderInfosFunc:  func() []router.ProviderInfo { return []router.ProviderInfo{proxy.TestProviderInfo(provider)} },
+		ProviderRouter:     routerInstance,
+		ProviderKey:        "",
+		Pool:               nil,
+		GetProviderPools:   nil,
+		GetProviderKeys:    func() map[string]string { return map[string]string{provider.Name(): chatProviderKey} },
+		AllProviders:       []providers.Provider{provider},
+		HealthTracker:      nil,
+		SignatureCache:     nil,
+		Metrics:            nil,
+		Budgets:            nil,
+		ClientLimits:       nil,
+		Sessions:           nil,
+		ResponseCache:      nil,
+		Tracing:            nil,
+		ProviderPools:      nil,
+		ProviderKeys:       nil,
+		GetAllProviders:    nil,
+		ConcurrencyLimiter: nil,
+		ProviderInfos:      nil,
+	})
+	require.NoError(t, err)
+	return handler
+}
+
+func newChatCompletionsRequest(body, bearer string) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		proxy.ChatCompletionsPath, strings.NewReader(body))
+	req.Header.Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	return req
+}
+
+func TestChatCompletionsTranslatesJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	server, backend := newChatBackend(t, http.StatusOK, proxy.JSONContentType, chatMessageResponse)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(chatRequestBody, chatRelayKey))
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	resp := gjson.Parse(rr.Body.String())
+	assert.Equal(t, "chat.completion", resp.Get("object").String())
+	assert.Equal(t, "chatcmpl-msg_1", resp.Get("id").String())
+	assert.Equal(t, "Hello!", resp.Get("choices.0.message.content").String())
+	assert.Equal(t, "stop", resp.Get("choices.0.finish_reason").String())
+	assert.Equal(t, int64(15), resp.Get("usage.total_tokens").Int())
+
+	path, header, body, _ := backend.snapshot()
+	assert.Equal(t, proxy.MessagesPath, path)
+	assert.Equal(t, chatProviderKey, header.Get(proxy.APIKeyHeader), "configured provider key must be used")
+	assert.Empty(t, header.Get("Authorization"), "client bearer token must not reach the provider")
+	assert.Equal(t, proxy.AnthropicVersion, header.Get("anthropic-version"))
+	assert.JSONEq(t, `{"model":"claude-sonnet-4","system":"Be terse.","max_tokens":64,`+
+		`"messages":[{"role":"user","content":[{"type":"text","text":"Hi"}]}]}`, string(body))
+}
+
+func TestChatCompletionsTranslatesStream(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newChatBackend(t, http.StatusOK, providers.ContentTypeSSE, chatMessageStream)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	body := `{"model":"claude-sonnet-4","stream":true,"stream_options":{"include_usage":true},` +
+		`"messages":[{"role":"user","content":"Hi"}]}`
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(body, chatRelayKey))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providers.ContentTypeSSE, rr.Header().Get(proxy.ContentTypeHeader))
+
+	var payloads []string
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			payloads = append(payloads, payload)
+		}
+	}
+	require.Len(t, payloads, 5)
+	assert.Equal(t, "assistant", gjson.Get(payloads[0], "choices.0.delta.role").String())
+	assert.Equal(t, "Hello!", gjson.Get(payloads[1], "choices.0.delta.content").String())
+	assert.Equal(t, "stop", gjson.Get(payloads[2], "choices.0.finish_reason").String())
+	assert.Equal(t, int64(3), gjson.Get(payloads[3], "usage.completion_tokens").Int())
+	assert.Equal(t, "[DONE]", payloads[4])
+	assert.NotContains(t, rr.Body.String(), "event:", "Anthropic event names must not leak")
+}
+
+func TestChatCompletionsTranslatesUpstreamError(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newChatBackend(t, http.StatusTooManyRequests, proxy.JSONContentType,
+		`{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(chatRequestBody, chatRelayKey))
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.JSONEq(t, `{"error":{"message":"Number of requests has exceeded your rate limit",`+
+		`"type":"rate_limit_error","param":null,"code":null}}`, rr.Body.String())
+}
+
+func TestChatCompletionsRejectsInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	server, backend := newChatBackend(t, http.StatusOK, proxy.JSONContentType, chatMessageResponse)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(`{"model":"claude-sonnet-4"}`, chatRelayKey))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "invalid_request_error", gjson.Get(rr.Body.String(), "error.type").String())
+	_, _, _, calls := backend.snapshot()
+	assert.Zero(t, calls)
+}
+
+func TestChatCompletionsRequiresRelayAuth(t *testing.T) {
+	t.Parallel()
+
+	server, backend := newChatBackend(t, http.StatusOK, proxy.JSONContentType, chatMessageResponse)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(chatRequestBody, "wrong-key"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "authentication_error", gjson.Get(rr.Body.String(), "error.type").String())
+	_, _, _, calls := backend.snapshot()
+	assert.Zero(t, calls)
+}
+
+func TestChatCompletionsAuthenticatesBeforeTranslating(t *testing.T) {
+	t.Parallel()
+
+	server, backend := newChatBackend(t, http.StatusOK, proxy.JSONContentType, chatMessageResponse)
+	handler := newChatCompletionsRoutes(t, server.URL)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(`{"model":"claude-sonnet-4"}`, "wrong-key"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "an invalid body must not be reported before auth")
+	assert.Equal(t, "authentication_error", gjson.Get(rr.Body.String(), "error.type").String())
+	_, _, _, calls := backend.snapshot()
+	assert.Zero(t, calls)
+}
+
+func TestChatCompletionsDoesNotTreatBearerAsAPIKey(t *testing.T) {
+	t.Parallel()
+
+	server, backend := newChatBackend(t, http.StatusOK, proxy.JSONContentType, chatMessageResponse)
+	handler := newChatCompletionsRoutesWithConfig(t, server.URL, proxy.TestConfig(chatRelayKey))
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(chatRequestBody, chatRelayKey))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "api_key is only accepted as x-api-key")
+	_, _, _, calls := backend.snapshot()
+	assert.Zero(t, calls)
+
+	req := newChatCompletionsRequest(chatRequestBody, "")
+	req.Header.Del("Authorization")
+	req.Header.Set(proxy.APIKeyHeader, chatRelayKey)
+	rr = proxy.ServeRequest(t, handler, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestChatCompletionsReportsEmptyResponse(t *testing.T) {
+	t.Parallel()
+
+	passThrough := func(next http.Handler) http.Handler { return next }
+	silent := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	handler := proxy.NewChatCompletionsHandler(passThrough, silent)
+
+	rr := proxy.ServeRequest(t, handler, newChatCompletionsRequest(chatRequestBody, chatRelayKey))
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Equal(t, "api_error", gjson.Get(rr.Body.String(), "error.type").String())
+	assert.Equal(t, "upstream returned an empty response", gjson.Get(rr.Body.String(), "error.message").String())
+}
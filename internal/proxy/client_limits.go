@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+)
+
+// concurrencyRetryAfter is the Retry-After sent when a client has too many
+// requests in flight; a slot frees up as soon as one of them completes.
+const concurrencyRetryAfter = time.Second
+
+// ClientRateLimiter enforces the per-client and per-IP rate limits of the live
+// config. Requests and tokens per minute are counted in the cache's counters,
+// so they are shared across instances when the cache is distributed.
+// Requests in flight are counted per instance.
+type ClientRateLimiter struct {
+	counter  cache.Counter
+	inFlight map[string]*ConcurrencyLimiter
+	mu       sync.Mutex
+}
+
+// NewClientRateLimiter creates a limiter counting in c if it is a
+// cache.Counter, otherwise in process memory.
+func NewClientRateLimiter(c cache.Cache) *ClientRateLimiter {
+	counter, ok := c.(cache.Counter)
+	if !ok {
+		counter = cache.NewLocalCounter(time.Now)
+	}
+	return &ClientRateLimiter{
+		counter:  counter,
+		inFlight: make(map[string]*ConcurrencyLimiter),
+		mu:       sync.Mutex{},
+	}
+}
+
+// acquire takes one of scope's maxConcurrent slots.
+func (l *ClientRateLimiter) acquire(scope string, maxConcurrent int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.inFlight[scope]
+	if !ok {
+		limiter = NewConcurrencyLimiter(int64(maxConcurrent))
+	}
+	limiter.SetLimit(int64(maxConcurrent))
+	if !limiter.TryAcquire() {
+		return false
+	}
+	l.inFlight[scope] = limiter
+	return true
+}
+
+// release frees a slot taken by acquire, and forgets scope once it has
+// nothing in flight so clients that went away do not accumulate.
+func (l *ClientRateLimiter) release(scope string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.inFlight[scope]
+	if !ok {
+		return
+	}
+	limiter.Release()
+	if limiter.CurrentInFlight() <= 0 {
+		delete(l.inFlight, scope)
+	}
+}
+
+// rateLimitScope returns the scope and limits that apply to a request: those
+// of its client, or those of its remote IP address if it has no client.
+// The remote address is the peer's, forwarding headers are not trusted.
+func rateLimitScope(cfg *config.Config, request *http.Request) (scope string, limits ratelimit.Limits) {
+	if client := auth.ClientFromContext(request.Context()); client != nil {
+		return "client:" + client.Name, cfg.RateLimits.ClientLimits(cfg.Server.Auth.Clients, client.Name)
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	return "ip:" + host, cfg.RateLimits.IPs
+}
+
+// ClientRateLimitMiddleware refuses requests over their client's, or their IP
+// address's, rate limits with a 429. It must run after authentication so the
+// client is known. Limits are read from the live config on every request.
+// The requests limiter is stored in the request context so the handler can
+// count the tokens the response reports.
+func ClientRateLimitMiddleware(
+	cfgProvider config.RuntimeConfigGetter, limiter *ClientRateLimiter,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			cfg := getRuntimeConfig(cfgProvider)
+			if cfg == nil || limiter == nil {
+				next.ServeHTTP(writer, request)
+				return
+			}
+			scope, limits := rateLimitScope(cfg, request)
+			if limits.IsZero() {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			logger := zerolog.Ctx(request.Context())
+			if limits.MaxConcurrent > 0 {
+				if !limiter.acquire(scope, limits.MaxConcurrent) {
+					logger.Warn().Str("rate_limit_scope", scope).Int("max_concurrent", limits.MaxConcurrent).
+						Msg("request rejected: client concurrency limit reached")
+					writeRateLimitError(writer, concurrencyRetryAfter, scope+" has too many requests in flight")
+					return
+				}
+				defer limiter.release(scope)
+			}
+
+			windows := ratelimit.NewWindowLimiter(limiter.counter, scope, limits.RPM, limits.TPM)
+			if !windows.ReserveContext(request.Context(), 1) || !windows.Allow(request.Context()) {
+				logger.Warn().Str("rate_limit_scope", scope).Int("rpm", limits.RPM).Int("tpm", limits.TPM).
+					Msg("request rejected: client rate limit reached")
+				writeRateLimitError(writer, windows.RetryAfter(request.Context()),
+					scope+" is over its rate limit. Please retry after the specified time.")
+				return
+			}
+
+			if limits.TPM > 0 {
+				request = request.WithContext(withClientRateLimiter(request.Context(), windows))
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+func withClientRateLimiter(ctx context.Context, limiter ratelimit.RateLimiter) context.Context {
+	return context.WithValue(ctx, clientRateLimiterContextKey, limiter)
+}
+
+// clientRateLimitCharger returns a charger counting usage against the
+// client's or IP address's TPM limit, or nil if it has none.
+func clientRateLimitCharger(ctx context.Context) usageCharger {
+	limiter, ok := ctx.Value(clientRateLimiterContextKey).(ratelimit.RateLimiter)
+	if !ok || limiter == nil {
+		return nil
+	}
+	return func(chargeCtx context.Context, usage tokenUsage) {
+		if err := limiter.RecordTokens(chargeCtx, usage.rateLimitedInput()+int(usage.outputTokens)); err != nil {
+			zerolog.Ctx(chargeCtx).Warn().Err(err).Msg("dropped client rate limit token charge")
+		}
+	}
+}
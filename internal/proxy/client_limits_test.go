@@ -0,0 +1,153 @@
+package proxy_test
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+)
+
+// sharedCounterCache is a cache.Cache supporting cache.Counter, standing in
+// for the Olric cache shared by every instance.
+type sharedCounterCache struct {
+	cache.Cache
+	*cache.LocalCounter
+}
+
+func newSharedCounterCache() *sharedCounterCache {
+	return &sharedCounterCache{Cache: nil, LocalCounter: cache.NewLocalCounter(time.Now)}
+}
+
+func rateLimits(rpm, tpm, maxConcurrent int) ratelimit.Limits {
+	return ratelimit.Limits{RPM: rpm, TPM: tpm, MaxConcurrent: maxConcurrent}
+}
+
+// rateLimitedHandler serves the budget handler's 120 token responses behind
+// the client rate limits of cfg, where alice has the given limits.
+func rateLimitedHandler(
+	t *testing.T, cfg *config.Config, limiter *proxy.ClientRateLimiter,
+) (http.Handler, *config.Runtime) {
+	t.Helper()
+	handler, _ := newBudgetHandler(t, cfg)
+	runtime := config.NewRuntime(cfg)
+	handler.SetRuntimeConfigGetter(runtime)
+	return proxy.ClientRateLimitMiddleware(runtime, limiter)(handler), runtime
+}
+
+func clientRateLimitConfig(alice ratelimit.Limits) *config.Config {
+	cfg := budgetConfig(budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0})
+	cfg.Server.Auth.Clients[0].RateLimit = alice
+	return cfg
+}
+
+func TestClientRateLimitRPM(t *testing.T) {
+	t.Parallel()
+
+	handler, runtime := rateLimitedHandler(t, clientRateLimitConfig(rateLimits(1, 0, 0)), proxy.NewClientRateLimiter(nil))
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	refused := serveAsClient(t, handler, budgetClient("alice"))
+	require.Equal(t, http.StatusTooManyRequests, refused.Code)
+	assert.Equal(t, "rate_limit_error", gjson.Get(refused.Body.String(), "error.type").String())
+	assert.Contains(t, gjson.Get(refused.Body.String(), "error.message").String(), "client:alice")
+	retryAfter, err := strconv.Atoi(refused.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 60, "the request counts until its share of the sliding window decays")
+	assert.LessOrEqual(t, retryAfter, 120)
+
+	// Clients without their own limits are unlimited, until rate_limits.clients
+	// applies to each of them separately
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("bob")).Code)
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("bob")).Code)
+	cfg := clientRateLimitConfig(rateLimits(1, 0, 0))
+	cfg.RateLimits.Clients = rateLimits(1, 0, 0)
+	runtime.Store(cfg)
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("bob")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveAsClient(t, handler, budgetClient("bob")).Code)
+	assert.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("carol")).Code)
+}
+
+func TestClientRateLimitTPM(t *testing.T) {
+	t.Parallel()
+
+	handler, _ := rateLimitedHandler(t, clientRateLimitConfig(rateLimits(0, 100, 0)), proxy.NewClientRateLimiter(nil))
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	require.Eventually(t, func() bool {
+		return serveAsClient(t, handler, budgetClient("alice")).Code == http.StatusTooManyRequests
+	}, time.Second, 10*time.Millisecond, "the 120 tokens of the first response exceed the TPM limit")
+}
+
+func TestClientRateLimitIPFallback(t *testing.T) {
+	t.Parallel()
+
+	cfg := clientRateLimitConfig(rateLimits(0, 0, 0))
+	cfg.RateLimits.IPs = rateLimits(1, 0, 0)
+	handler, _ := rateLimitedHandler(t, cfg, proxy.NewClientRateLimiter(nil))
+
+	fromAddr := func(addr string) int {
+		req := newJSONMessagesRequest(failoverRequestBody)
+		req.RemoteAddr = addr
+		return proxy.ServeRequest(t, handler, req).Code
+	}
+	require.Equal(t, http.StatusOK, fromAddr("10.0.0.1:40000"))
+	assert.Equal(t, http.StatusTooManyRequests, fromAddr("10.0.0.1:40001"), "the port is not part of the scope")
+	assert.Equal(t, http.StatusOK, fromAddr("10.0.0.2:40000"))
+
+	// IP limits do not apply to requests with a client
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+}
+
+func TestClientRateLimitSharedAcrossInstances(t *testing.T) {
+	t.Parallel()
+
+	shared := newSharedCounterCache()
+	cfg := clientRateLimitConfig(rateLimits(1, 0, 0))
+	first, _ := rateLimitedHandler(t, cfg, proxy.NewClientRateLimiter(shared))
+	second, _ := rateLimitedHandler(t, cfg, proxy.NewClientRateLimiter(shared))
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, first, budgetClient("alice")).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveAsClient(t, second, budgetClient("alice")).Code)
+}
+
+func TestClientRateLimitConcurrency(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		started <- struct{}{}
+		<-release
+		writer.WriteHeader(http.StatusOK)
+	})
+	runtime := config.NewRuntime(clientRateLimitConfig(rateLimits(0, 0, 1)))
+	handler := proxy.ClientRateLimitMiddleware(runtime, proxy.NewClientRateLimiter(nil))(blocking)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		assert.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	})
+	<-started
+
+	refused := serveAsClient(t, handler, budgetClient("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, refused.Code)
+	assert.Equal(t, "1", refused.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+	go func() { <-started }()
+	assert.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code,
+		"the slot is freed once the request completes")
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/tokenizer"
+)
+
+// CountTokensResponse matches Anthropic's count_tokens response format.
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// isCountTokensRequest reports whether request targets the token counting endpoint.
+func isCountTokensRequest(request *http.Request) bool {
+	return request.URL.Path == providers.CountTokensEndpoint
+}
+
+// serveLocalTokenCount answers a count_tokens request with a local estimate
+// when the selected provider has no native endpoint. It returns false, leaving
+// the response untouched, for other requests and for providers implementing
+// providers.TokenCounter, which are proxied like /v1/messages.
+func (h *Handler) serveLocalTokenCount(
+	writer http.ResponseWriter, request *http.Request, selectedProvider providers.Provider,
+) bool {
+	if !isCountTokensRequest(request) {
+		return false
+	}
+	if _, native := selectedProvider.(providers.TokenCounter); native {
+		return false
+	}
+
+	logger := h.createProviderLoggerWithProvider(request, selectedProvider)
+	h.logAndSetDebugHeaders(writer, request, &logger, selectedProvider)
+
+	body, err := io.ReadAll(request.Body)
+	closeBody(request.Body)
+	if err != nil {
+		if IsBodyTooLargeError(err) {
+			WriteBodyTooLargeError(writer)
+			return true
+		}
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return true
+	}
+
+	if !gjson.ValidBytes(body) {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "request body must be valid JSON")
+		return true
+	}
+
+	inputTokens := tokenizer.CountRequest(body)
+	logger.Debug().Int("input_tokens", inputTokens).Msg("estimated token count locally")
+
+	writer.Header().Set(HeaderRelayEstimated, "true")
+	writeJSON(writer, http.StatusOK, CountTokensResponse{InputTokens: inputTokens})
+	return true
+}
@@ -0,0 +1,204 @@
+package proxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const countTokensBody = `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"How many tokens is this?"}]}`
+
+func newCountTokensRequest(body string) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost,
+		providers.CountTokensEndpoint, strings.NewReader(body))
+	req.Header.Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+	return req
+}
+
+func serveCountTokens(t *testing.T, provider providers.Provider, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	providerInfos := []router.ProviderInfo{
+		proxy.TestProviderInfoWithHealth(provider, func() bool { return true }),
+	}
+	handler := newLiveKeyPoolsHandler(t, config.NewRuntime(proxy.TestConfig("")), provider, providerInfos)
+	return proxy.ServeRequest(t, handler, newCountTokensRequest(body))
+}
+
+func TestCountTokensForwardsToNativeEndpoint(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		gotPath  string
+		gotBody  []byte
+		gotCalls int
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		gotPath, gotBody = request.URL.Path, body
+		gotCalls++
+		mu.Unlock()
+
+		writer.Header().Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+		if _, err := writer.Write([]byte(`{"input_tokens":17}`)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	rr := serveCountTokens(t, proxy.NewTestProvider(backend.URL), countTokensBody)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"input_tokens":17}`, rr.Body.String())
+	assert.Empty(t, rr.Header().Get(proxy.HeaderRelayEstimated))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, gotCalls)
+	assert.Equal(t, providers.CountTokensEndpoint, gotPath)
+	assert.JSONEq(t, countTokensBody, string(gotBody))
+}
+
+func TestCountTokensEstimatesForProvidersWithoutEndpoint(t *testing.T) {
+	t.Parallel()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		t.Error("backend must not be called for local estimates")
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(backend.Close)
+
+	tests := []struct {
+		provider providers.Provider
+		name     string
+	}{
+		{name: "zai", provider: providers.NewZAIProvider("zai", backend.URL, nil, nil)},
+		{name: "ollama", provider: providers.NewOllamaProvider("ollama", backend.URL, nil, nil)},
+		{name: "minimax", provider: providers.NewMiniMaxProvider("minimax", backend.URL, nil, nil)},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := serveCountTokens(t, testCase.provider, countTokensBody)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "true", rr.Header().Get(proxy.HeaderRelayEstimated))
+
+			var resp proxy.CountTokensResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Positive(t, resp.InputTokens)
+		})
+	}
+}
+
+func TestCountTokensEstimateRejectsInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	rr := serveCountTokens(t, providers.NewOllamaProvider("ollama", "http://127.0.0.1:1", nil, nil), `{"messages":`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid_request_error")
+}
+
+// camelCaseCounter mimics a provider whose token counting API uses its own
+// path and response shape, as Bedrock does.
+type camelCaseCounter struct {
+	providers.Provider
+}
+
+func (c camelCaseCounter) TransformCountTokensRequest(body []byte) (newBody []byte, targetURL string, err error) {
+	return body, c.BaseURL() + "/model/test/count-tokens", nil
+}
+
+func (c camelCaseCounter) TransformCountTokensResponse(body []byte) ([]byte, error) {
+	var native struct {
+		InputTokens int `json:"inputTokens"`
+	}
+	if err := json.Unmarshal(body, &native); err != nil {
+		return nil, err
+	}
+	return json.Marshal(proxy.CountTokensResponse{InputTokens: native.InputTokens})
+}
+
+func TestCountTokensTransformsNativeRequestAndResponse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		gotPath string
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		gotPath = request.URL.Path
+		mu.Unlock()
+
+		writer.Header().Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+		if _, err := writer.Write([]byte(`{"inputTokens":9}`)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	rr := serveCountTokens(t, camelCaseCounter{Provider: proxy.NewTestProvider(backend.URL)}, countTokensBody)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"input_tokens":9}`, rr.Body.String())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "/model/test/count-tokens", gotPath)
+}
+
+// TestCountTokensWithTranslatingProvider tests that successful count_tokens
+// replies from a provider that also translates responses reach the client
+// through the TokenCounter rather than the response converter.
+func TestCountTokensWithTranslatingProvider(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		gotPath string
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		gotPath = request.URL.Path
+		mu.Unlock()
+
+		writer.Header().Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+		if _, err := writer.Write([]byte(`{"totalTokens":12}`)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	provider := providers.NewGeminiProvider("gemini", backend.URL, nil, nil)
+	rr := serveCountTokens(t, provider,
+		`{"model":"gemini-2.5-pro","messages":[{"role":"user","content":"How many tokens is this?"}]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"input_tokens":12}`, rr.Body.String())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "/v1beta/models/gemini-2.5-pro:countTokens", gotPath)
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// errNoTrustedProvider is returned when a request containing sensitive data
+// may only go to trusted providers, and none can serve it.
+var errNoTrustedProvider = errors.New("no trusted provider for request with sensitive data")
+
+// sensitiveDataFromContext returns what DLP found in a request restricted to
+// trusted providers.
+func sensitiveDataFromContext(ctx context.Context) ([]dlp.Finding, bool) {
+	findings, ok := ctx.Value(sensitiveDataContextKey).([]dlp.Finding)
+	return findings, ok && len(findings) > 0
+}
+
+// checkSensitiveData scans the request as it arrives for the block and
+// restrict DLP actions. A blocked request gets an invalid_request_error and
+// false is returned. A restricted request has its findings stored in its
+// context, where routing reads them. Masking happens per provider attempt.
+func (h *Handler) checkSensitiveData(writer http.ResponseWriter, request *http.Request) (*http.Request, bool) {
+	cfg := h.getRuntimeConfigGetter()
+	if cfg == nil || !cfg.DLP.IsEnabled() || cfg.DLP.GetAction() == dlp.ActionMask || request.Body == nil {
+		return request, true
+	}
+
+	body, err := readBody(request)
+	if err != nil {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return request, false
+	}
+
+	findings, err := dlp.Scan(&cfg.DLP, body)
+	if err != nil {
+		// Requests that cannot be scanned are not let through unchecked
+		zerolog.Ctx(request.Context()).Error().Err(err).Msg("failed to scan request for sensitive data")
+		WriteError(writer, http.StatusInternalServerError, "internal_error", "failed to scan request for sensitive data")
+		return request, false
+	}
+	if len(findings) == 0 {
+		return request, true
+	}
+
+	logSensitiveData(request.Context(), cfg.DLP.GetAction(), findings)
+	if cfg.DLP.GetAction() == dlp.ActionBlock {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error",
+			"request contains sensitive data: "+dlp.Describe(findings))
+		return request, false
+	}
+	return request.WithContext(context.WithValue(request.Context(), sensitiveDataContextKey, findings)), true
+}
+
+// maskSensitiveData masks the request body before it is sent to a provider
+// that is not trusted, when the DLP action is mask. Writes an error and
+// returns false if the body cannot be masked, so it is never sent unmasked.
+func (h *Handler) maskSensitiveData(
+	writer http.ResponseWriter, request *http.Request, logger *zerolog.Logger, provider providers.Provider,
+) bool {
+	cfg := h.getRuntimeConfigGetter()
+	if cfg == nil || !cfg.DLP.IsEnabled() || cfg.DLP.GetAction() != dlp.ActionMask ||
+		request.Body == nil || isTrustedProvider(cfg, provider.Name()) {
+		return true
+	}
+
+	body, err := readBody(request)
+	if err == nil {
+		var findings []dlp.Finding
+		body, findings, err = dlp.Mask(&cfg.DLP, body)
+		if len(findings) > 0 {
+			logSensitiveData(logger.WithContext(request.Context()), dlp.ActionMask, findings)
+		}
+		setBody(request, body)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to mask sensitive data")
+		WriteError(writer, http.StatusInternalServerError, "internal_error", "failed to mask sensitive data")
+		return false
+	}
+	return true
+}
+
+// filterTrusted returns the trusted candidates if the request is restricted
+// to them.
+func (h *Handler) filterTrusted(ctx context.Context, candidates []router.ProviderInfo) []router.ProviderInfo {
+	cfg := h.getRuntimeConfigGetter()
+	if _, restricted := sensitiveDataFromContext(ctx); !restricted || cfg == nil {
+		return candidates
+	}
+	return lo.Filter(candidates, func(info router.ProviderInfo, _ int) bool {
+		return isTrustedProvider(cfg, info.Provider.Name())
+	})
+}
+
+// untrustedProviderError returns errNoTrustedProvider naming what was found
+// in the request.
+func untrustedProviderError(ctx context.Context) error {
+	findings, _ := sensitiveDataFromContext(ctx)
+	return fmt.Errorf("%w: %s", errNoTrustedProvider, dlp.Describe(findings))
+}
+
+// isTrustedProvider reports whether the named provider is trusted with
+// sensitive data.
+func isTrustedProvider(cfg *config.Config, name string) bool {
+	return lo.ContainsBy(cfg.Providers, func(provider config.ProviderConfig) bool {
+		return provider.Name == name && provider.IsTrusted()
+	})
+}
+
+func logSensitiveData(ctx context.Context, action string, findings []dlp.Finding) {
+	zerolog.Ctx(ctx).Info().
+		Str("dlp_action", action).
+		Str("dlp_findings", dlp.Describe(findings)).
+		Msg("sensitive data detected in request")
+}
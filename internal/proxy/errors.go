@@ -4,6 +4,7 @@ package proxy
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -28,6 +29,11 @@ const (
 	HeaderRelayKeyID     = "X-CC-Relay-Key-ID"         // Selected key ID (first 8 chars)
 	HeaderRelayKeysTotal = "X-CC-Relay-Keys-Total"     // Total keys in pool
 	HeaderRelayKeysAvail = "X-CC-Relay-Keys-Available" // Available keys
+	HeaderRelayAttempts  = "X-CC-Relay-Attempts"       // Provider attempts (routing debug only)
+	HeaderRelayEstimated = "X-CC-Relay-Estimated"      // "true" when count_tokens was estimated locally
+	HeaderRelayBudget    = "X-CC-Relay-Budget-Warning" // Budget past its soft limit, one value per budget
+	HeaderRelayHedge     = "X-CC-Relay-Hedge"          // "primary" or "hedge" attempt sent (routing debug only)
+	HeaderRelayCache     = "X-CC-Relay-Cache"          // Response cache HIT or MISS; requests send "true" to opt in
 )
 
 // logLevelError is the string representation of the "error" log level used
@@ -68,8 +74,15 @@ func WriteError(writer http.ResponseWriter, statusCode int, errorType, message s
 // WriteRateLimitError writes a 429 Too Many Requests response in Anthropic format.
 // The retryAfter parameter specifies when capacity will be available.
 func WriteRateLimitError(writer http.ResponseWriter, retryAfter time.Duration) {
+	writeRateLimitError(writer, retryAfter,
+		"All API keys are currently at rate limit capacity. Please retry after the specified time.")
+}
+
+// writeRateLimitError writes a 429 with message, and a Retry-After of
+// retryAfter rounded up to whole seconds so clients never retry too early.
+func writeRateLimitError(writer http.ResponseWriter, retryAfter time.Duration, message string) {
 	// Set Retry-After header (RFC 6585)
-	seconds := max(int(retryAfter.Seconds()),
+	seconds := max(int(math.Ceil(retryAfter.Seconds())),
 		// Minimum 1 second
 		1)
 	writer.Header().Set("Retry-After", strconv.Itoa(seconds))
@@ -79,8 +92,7 @@ func WriteRateLimitError(writer http.ResponseWriter, retryAfter time.Duration) {
 		Int("retry_after_seconds", seconds).
 		Msg("Returning 429 rate limit error")
 
-	WriteError(writer, http.StatusTooManyRequests, "rate_limit_error",
-		"All API keys are currently at rate limit capacity. Please retry after the specified time.")
+	WriteError(writer, http.StatusTooManyRequests, "rate_limit_error", message)
 }
 
 func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
@@ -12,11 +12,14 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/cache"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/dlp"
 	"github.com/omarluq/cc-relay/internal/health"
 	"github.com/omarluq/cc-relay/internal/keypool"
 	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 	"github.com/omarluq/cc-relay/internal/router"
 )
 
@@ -418,6 +421,7 @@ func testServerConfig(apiKey string) config.ServerConfig {
 	return config.ServerConfig{
 		Listen:        "",
 		APIKey:        apiKey,
+		TLS:           testTLSConfig(),
 		Auth:          testAuthConfig(),
 		TimeoutMS:     0,
 		MaxConcurrent: 0,
@@ -431,6 +435,10 @@ func testServerConfig(apiKey string) config.ServerConfig {
 func testRoutingConfig() config.RoutingConfig {
 	return config.RoutingConfig{
 		ModelMapping:    nil,
+		QualityFloor:    nil,
+		Rules:           nil,
+		SessionAffinity: config.SessionAffinityConfig{Header: "", TTLMS: 0, Enabled: false},
+		Hedging:         config.HedgingConfig{Models: nil, Percentile: 0, DelayMS: 0, Enabled: false},
 		Strategy:        "",
 		DefaultProvider: "",
 		FailoverTimeout: 0,
@@ -442,13 +450,27 @@ func testRoutingConfig() config.RoutingConfig {
 // All fields are explicitly initialized to satisfy exhaustruct linter.
 func testAuthConfig() config.AuthConfig {
 	return config.AuthConfig{
+		Clients:           nil,
 		APIKey:            "",
 		BearerSecret:      "",
+		JWT:               testJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	}
 }
 
+// testJWTConfig returns a disabled JWTConfig for testing.
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer: "", JWKSURL: "", JWKSFile: "", ClientClaim: "", GroupsClaim: "",
+		Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
+	}
+}
+
+func testTLSConfig() config.TLSConfig {
+	return config.TLSConfig{CertFile: "", KeyFile: "", ClientCAFile: "", ClientAuth: "", MinVersion: ""}
+}
+
 // testHealthConfig returns a minimal health.Config for testing.
 // All fields are explicitly initialized to satisfy exhaustruct linter.
 func testHealthConfig() health.Config {
@@ -491,16 +513,77 @@ func testCacheConfig() cache.Config {
 	}
 }
 
+// testMetricsConfig returns a disabled config.MetricsConfig for testing.
+// All fields are explicitly initialized to satisfy exhaustruct linter.
+func testObservabilityConfig() config.ObservabilityConfig {
+	return config.ObservabilityConfig{
+		Tracing: config.TracingConfig{
+			Headers:     nil,
+			Endpoint:    "",
+			Protocol:    "",
+			ServiceName: "",
+			SampleRatio: 0,
+			Enabled:     false,
+			Insecure:    false,
+		},
+	}
+}
+
+func testResponseCacheConfig() config.ResponseCacheConfig {
+	return config.ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: 0, Enabled: false}
+}
+
+func testAdminConfig() config.AdminConfig {
+	return config.AdminConfig{
+		Token:   "",
+		Enabled: false,
+	}
+}
+
+func testBudgetsConfig() budget.Config {
+	return budget.Config{
+		Prices:           nil,
+		StatePath:        "",
+		Global:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+		SoftLimitPercent: 0,
+	}
+}
+
+func testDLPConfig() dlp.Config {
+	return dlp.Config{Action: "", Detectors: nil, Patterns: nil, MinEntropy: 0}
+}
+
+func testRateLimitsConfig() config.RateLimitsConfig {
+	none := ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0}
+	return config.RateLimitsConfig{Clients: none, IPs: none}
+}
+
+func testMetricsConfig() config.MetricsConfig {
+	return config.MetricsConfig{
+		Path:    "",
+		Enabled: false,
+	}
+}
+
 // testConfig returns a minimal config.Config for testing.
 // All fields are explicitly initialized to satisfy exhaustruct linter.
 func testConfig(apiKey string) *config.Config {
 	return &config.Config{
-		Providers: nil,
-		Server:    testServerConfig(apiKey),
-		Routing:   testRoutingConfig(),
-		Logging:   testLoggingConfig(),
-		Health:    testHealthConfig(),
-		Cache:     testCacheConfig(),
+		Providers:     nil,
+		Server:        testServerConfig(apiKey),
+		Routing:       testRoutingConfig(),
+		Logging:       testLoggingConfig(),
+		Health:        testHealthConfig(),
+		Cache:         testCacheConfig(),
+		Metrics:       testMetricsConfig(),
+		Observability: testObservabilityConfig(),
+		Budgets:       testBudgetsConfig(),
+		Admin:         testAdminConfig(),
+		ResponseCache: testResponseCacheConfig(),
+		KeyState:      config.KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: false},
+		Access:        config.AccessConfig{DefaultEffect: "", Policies: nil},
+		DLP:           testDLPConfig(),
+		RateLimits:    testRateLimitsConfig(),
 	}
 }
 
@@ -512,16 +595,26 @@ func testConfigWithAuth(authCfg config.AuthConfig) *config.Config {
 		Server: config.ServerConfig{
 			Listen:        "",
 			APIKey:        "",
+			TLS:           testTLSConfig(),
 			Auth:          authCfg,
 			TimeoutMS:     0,
 			MaxConcurrent: 0,
 			MaxBodyBytes:  0,
 			EnableHTTP2:   false,
 		},
-		Routing: testRoutingConfig(),
-		Logging: testLoggingConfig(),
-		Health:  testHealthConfig(),
-		Cache:   testCacheConfig(),
+		Routing:       testRoutingConfig(),
+		Logging:       testLoggingConfig(),
+		Health:        testHealthConfig(),
+		Cache:         testCacheConfig(),
+		Metrics:       testMetricsConfig(),
+		Observability: testObservabilityConfig(),
+		Budgets:       testBudgetsConfig(),
+		Admin:         testAdminConfig(),
+		ResponseCache: testResponseCacheConfig(),
+		KeyState:      config.KeyStateConfig{Path: "", SaveIntervalMS: 0, Enabled: false},
+		Access:        config.AccessConfig{DefaultEffect: "", Policies: nil},
+		DLP:           testDLPConfig(),
+		RateLimits:    testRateLimitsConfig(),
 	}
 }
 
@@ -542,6 +635,10 @@ func testHandlerOptions(opts *HandlerOptions) *HandlerOptions {
 			RoutingConfig:     nil,
 			HealthTracker:     nil,
 			SignatureCache:    nil,
+			Metrics:           nil,
+			Budgets:           nil,
+			Sessions:          nil,
+			ResponseCache:     nil,
 			APIKey:            "",
 			ProviderInfos:     nil,
 			DebugOptions:      testDebugOptions(),
@@ -560,6 +657,10 @@ func testHandlerOptions(opts *HandlerOptions) *HandlerOptions {
 		RoutingConfig:     opts.RoutingConfig,
 		HealthTracker:     opts.HealthTracker,
 		SignatureCache:    opts.SignatureCache,
+		Metrics:           opts.Metrics,
+		Budgets:           opts.Budgets,
+		Sessions:          opts.Sessions,
+		ResponseCache:     opts.ResponseCache,
 		APIKey:            opts.APIKey,
 		ProviderInfos:     opts.ProviderInfos,
 		DebugOptions:      testDebugOptions(),
@@ -582,6 +683,10 @@ func mustTestHandlerOptions(provider providers.Provider, apiKey string) *Handler
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            apiKey,
 		ProviderInfos:     nil,
 		DebugOptions:      testDebugOptions(),
@@ -610,6 +715,7 @@ func testProviderInfo(provider providers.Provider) router.ProviderInfo {
 		IsHealthy: func() bool { return true },
 		Weight:    0,
 		Priority:  0,
+		Prices:    nil,
 	}
 }
 
@@ -621,6 +727,7 @@ func testProviderInfoWithHealth(provider providers.Provider, isHealthy func() bo
 		IsHealthy: isHealthy,
 		Weight:    0,
 		Priority:  0,
+		Prices:    nil,
 	}
 }
 
@@ -633,6 +740,7 @@ func testRoutesOptions(opts *RoutesOptions) RoutesOptions {
 			ProviderRouter:     nil,
 			Provider:           nil,
 			ConfigProvider:     nil,
+			ConfigReloader:     nil,
 			Pool:               nil,
 			ProviderInfosFunc:  nil,
 			ProviderPools:      nil,
@@ -642,6 +750,12 @@ func testRoutesOptions(opts *RoutesOptions) RoutesOptions {
 			GetAllProviders:    nil,
 			HealthTracker:      nil,
 			SignatureCache:     nil,
+			Metrics:            nil,
+			Budgets:            nil,
+			ClientLimits:       nil,
+			Sessions:           nil,
+			ResponseCache:      nil,
+			Tracing:            nil,
 			ConcurrencyLimiter: nil,
 			ProviderKey:        "",
 			ProviderInfos:      nil,
@@ -652,6 +766,7 @@ func testRoutesOptions(opts *RoutesOptions) RoutesOptions {
 		ProviderRouter:     opts.ProviderRouter,
 		Provider:           opts.Provider,
 		ConfigProvider:     opts.ConfigProvider,
+		ConfigReloader:     opts.ConfigReloader,
 		Pool:               opts.Pool,
 		ProviderInfosFunc:  opts.ProviderInfosFunc,
 		ProviderPools:      opts.ProviderPools,
@@ -661,6 +776,12 @@ func testRoutesOptions(opts *RoutesOptions) RoutesOptions {
 		GetAllProviders:    opts.GetAllProviders,
 		HealthTracker:      opts.HealthTracker,
 		SignatureCache:     opts.SignatureCache,
+		Metrics:            opts.Metrics,
+		Budgets:            opts.Budgets,
+		ClientLimits:       opts.ClientLimits,
+		Sessions:           opts.Sessions,
+		ResponseCache:      opts.ResponseCache,
+		Tracing:            opts.Tracing,
 		ConcurrencyLimiter: opts.ConcurrencyLimiter,
 		ProviderKey:        opts.ProviderKey,
 		ProviderInfos:      opts.ProviderInfos,
@@ -686,6 +807,10 @@ func newHandlerWithSignatureCache(
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    sigCache,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "test-key",
 		ProviderInfos:     nil,
 		DebugOptions: config.DebugOptions{
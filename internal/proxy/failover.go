@@ -0,0 +1,324 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/tracing"
+)
+
+// errProviderNotAllowed is returned when the authenticated client may not use
+// any provider that could serve the request.
+var errProviderNotAllowed = errors.New("no provider allowed for client")
+
+// upstreamErrorRecorder is implemented by response writers that want to see the
+// transport error behind a proxy ErrorHandler response (connection refused, timeout).
+type upstreamErrorRecorder interface {
+	recordUpstreamError(err error)
+}
+
+// attemptWriter holds back a single upstream attempt until its status is known.
+// If the status (or the transport error that produced it) matches a failover
+// trigger and another provider is available, the response is discarded so the
+// handler can re-dispatch. Otherwise headers are committed to the client and
+// the body is passed through unbuffered, so streaming latency is unaffected.
+type attemptWriter struct {
+	dst         http.ResponseWriter
+	header      http.Header
+	upstreamErr error
+	trigger     router.FailoverTrigger
+	triggers    []router.FailoverTrigger
+	status      int
+	canRetry    bool
+	committed   bool
+	discarded   bool
+}
+
+func newAttemptWriter(dst http.ResponseWriter, triggers []router.FailoverTrigger, canRetry bool) *attemptWriter {
+	return &attemptWriter{
+		dst:         dst,
+		header:      dst.Header().Clone(),
+		upstreamErr: nil,
+		trigger:     nil,
+		triggers:    triggers,
+		status:      0,
+		canRetry:    canRetry,
+		committed:   false,
+		discarded:   false,
+	}
+}
+
+// Header returns the attempt-local header map until the response is committed.
+func (w *attemptWriter) Header() http.Header {
+	if w.committed {
+		return w.dst.Header()
+	}
+	return w.header
+}
+
+// WriteHeader commits the response unless it should trigger a failover.
+func (w *attemptWriter) WriteHeader(statusCode int) {
+	if w.committed || w.discarded {
+		return
+	}
+	w.status = statusCode
+
+	if w.canRetry {
+		if trigger := w.matchTrigger(statusCode); trigger != nil {
+			w.trigger = trigger
+			w.discarded = true
+			return
+		}
+	}
+
+	dstHeader := w.dst.Header()
+	for key := range dstHeader {
+		if _, ok := w.header[key]; !ok {
+			dstHeader.Del(key)
+		}
+	}
+	for key, values := range w.header {
+		dstHeader[key] = values
+	}
+	w.committed = true
+	w.dst.WriteHeader(statusCode)
+}
+
+// matchTrigger prefers error-based triggers for transport failures so the log
+// reports "connection" or "timeout" rather than the synthesized 502 status.
+func (w *attemptWriter) matchTrigger(statusCode int) router.FailoverTrigger {
+	if w.upstreamErr != nil {
+		if trigger := router.FindMatchingTrigger(w.triggers, w.upstreamErr, 0); trigger != nil {
+			return trigger
+		}
+	}
+	return router.FindMatchingTrigger(w.triggers, w.upstreamErr, statusCode)
+}
+
+// Write forwards body bytes once committed and drops them for discarded attempts.
+func (w *attemptWriter) Write(data []byte) (int, error) {
+	if !w.committed && !w.discarded {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discarded {
+		return len(data), nil
+	}
+	return w.dst.Write(data)
+}
+
+// Flush flushes committed responses so SSE events reach the client immediately.
+func (w *attemptWriter) Flush() {
+	if !w.committed {
+		return
+	}
+	//nolint:errcheck // Flush is best-effort; unsupported writers simply buffer
+	http.NewResponseController(w.dst).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *attemptWriter) Unwrap() http.ResponseWriter {
+	return w.dst
+}
+
+func (w *attemptWriter) recordUpstreamError(err error) {
+	w.upstreamErr = err
+}
+
+// failedOver reports whether this attempt was discarded in favor of the next provider.
+func (w *attemptWriter) failedOver() bool {
+	return w.discarded
+}
+
+// providerAttempts is the ordered list of providers a request may be sent to.
+// Only failover-capable routers produce more than one entry.
+type providerAttempts struct {
+	triggers  []router.FailoverTrigger
+	providers []router.ProviderInfo
+}
+
+// canFailover reports whether the request may be re-dispatched on failure.
+func (a providerAttempts) canFailover() bool {
+	return len(a.providers) > 1 && len(a.triggers) > 0
+}
+
+// selectProviderAttempts resolves the providers to try for this request.
+// For failover strategies this is the router's full failover order, with the
+// primary first; for all other strategies it is the single selected provider.
+func (h *Handler) selectProviderAttempts(
+	request *http.Request, model string, hasThinkingAffinity bool,
+) (providerAttempts, error) {
+	failover, isFailover := router.AsFailover(h.routerFor(request.Context()))
+	if !isFailover || request.Body == nil {
+		selected, err := h.selectProvider(request.Context(), model, hasThinkingAffinity)
+		if err != nil {
+			return providerAttempts{triggers: nil, providers: nil}, err
+		}
+		return providerAttempts{triggers: nil, providers: []router.ProviderInfo{selected}}, nil
+	}
+
+	start := time.Now()
+	if timings := getRequestTimings(request.Context()); timings != nil {
+		defer func() {
+			timings.Routing = time.Since(start)
+		}()
+	}
+
+	candidates, ok := h.routingCandidates(request.Context(), model, hasThinkingAffinity)
+	if !ok {
+		fallback, err := h.fallbackProvider(request.Context(), model)
+		if err != nil {
+			return providerAttempts{triggers: nil, providers: nil}, err
+		}
+		return providerAttempts{triggers: nil, providers: []router.ProviderInfo{fallback}}, nil
+	}
+
+	order, err := failover.FailoverOrder(candidates)
+	if err != nil {
+		return providerAttempts{triggers: nil, providers: nil}, err
+	}
+	return providerAttempts{triggers: failover.Triggers(), providers: pinSessionFirst(request.Context(), order)}, nil
+}
+
+// tracedSelectProviderAttempts wraps selectProviderAttempts in a span that
+// records the strategy and the providers the request may be sent to.
+func (h *Handler) tracedSelectProviderAttempts(
+	request *http.Request, model string, hasThinkingAffinity bool,
+) (providerAttempts, error) {
+	_, span := tracing.Start(request.Context(), spanSelectProvider,
+		attrThinkingAffinity.Bool(hasThinkingAffinity))
+	attempts, err := h.selectProviderAttempts(request, model, hasThinkingAffinity)
+	if size, measured := requestSizeFromContext(request.Context()); measured {
+		span.SetAttributes(attrInputTokens.Int(size.inputTokens))
+	}
+	if rtr := h.routerFor(request.Context()); rtr != nil {
+		span.SetAttributes(attrStrategy.String(rtr.Name()))
+	}
+	if rule, ok := routingRuleFromContext(request.Context()); ok {
+		span.SetAttributes(attrRoutingRule.String(rule.Name))
+	}
+	if len(attempts.providers) > 0 {
+		span.SetAttributes(
+			attrProvider.String(attempts.providers[0].Provider.Name()),
+			attrCandidates.Int(len(attempts.providers)),
+		)
+	}
+	endSpan(span, err)
+	return attempts, err
+}
+
+// filterClientProviders returns the providers the client may be routed to.
+func filterClientProviders(client *auth.Client, candidates []router.ProviderInfo) []router.ProviderInfo {
+	if client == nil || len(client.AllowedProviders) == 0 {
+		return candidates
+	}
+	return lo.Filter(candidates, func(info router.ProviderInfo, _ int) bool {
+		return client.AllowsProvider(info.Provider.Name())
+	})
+}
+
+// clientNotAllowedError returns errProviderNotAllowed for client.
+func clientNotAllowedError(client *auth.Client) error {
+	return fmt.Errorf("%w: client %q", errProviderNotAllowed, client.Name)
+}
+
+// serveWithFailover proxies the request to each provider in order until one
+// returns a response that does not match a failover trigger, or the list is exhausted.
+// The request body is buffered once so model rewrite, key selection and
+// TransformRequest all run again against the original payload for every attempt.
+// Returns the name of the provider whose response was sent to the client.
+func (h *Handler) serveWithFailover(
+	writer http.ResponseWriter, request *http.Request, attempts providerAttempts, start time.Time,
+) string {
+	body, err := io.ReadAll(request.Body)
+	closeBody(request.Body)
+	if err != nil {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return ""
+	}
+
+	logger := zerolog.Ctx(request.Context())
+	total := len(attempts.providers)
+	var backendTime time.Duration
+
+	for idx, selected := range attempts.providers {
+		attemptNum := idx + 1
+		attemptReq := request.Clone(request.Context())
+		attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		attemptReq.ContentLength = int64(len(body))
+
+		attempt := newAttemptWriter(writer, attempts.triggers, attemptNum < total)
+		if h.isRoutingDebugEnabled() {
+			attempt.Header().Set(HeaderRelayAttempts, strconv.Itoa(attemptNum))
+		}
+
+		if h.serveLocalTokenCount(attempt, attemptReq, selected.Provider) {
+			return selected.Provider.Name()
+		}
+
+		release := h.acquireProvider(attemptReq.Context(), selected)
+		proxyCtx, ok := h.prepareProxyRequest(attempt, attemptReq, selected.Provider)
+		if ok {
+			backendTime += forward(attempt, proxyCtx, selected.Provider)
+		}
+		if release != nil {
+			release()
+		}
+
+		if !attempt.failedOver() {
+			if ok {
+				h.logMetricsIfEnabled(proxyCtx.request, &proxyCtx.logger, start, backendTime, proxyCtx.getTLSMetrics)
+			}
+			if attemptNum > 1 {
+				logger.Info().
+					Str("provider", selected.Provider.Name()).
+					Int("attempt", attemptNum).
+					Int("status", attempt.status).
+					Msg("failover attempt completed")
+			}
+			return selected.Provider.Name()
+		}
+
+		h.metrics.IncFailover(selected.Provider.Name(), attempt.trigger.Name())
+		trace.SpanFromContext(request.Context()).AddEvent("failover", trace.WithAttributes(
+			attrProvider.String(selected.Provider.Name()),
+			attrAttempt.Int(attemptNum),
+			attrFailoverTrigger.String(attempt.trigger.Name()),
+		))
+		event := logger.Warn().
+			Str("provider", selected.Provider.Name()).
+			Int("attempt", attemptNum).
+			Int("max_attempts", total).
+			Int("status", attempt.status).
+			Str("trigger", attempt.trigger.Name()).
+			Str("next_provider", attempts.providers[idx+1].Provider.Name())
+		if attempt.upstreamErr != nil {
+			event = event.Err(attempt.upstreamErr)
+		}
+		event.Msg("provider attempt failed, failing over")
+	}
+
+	// Unreachable: the last attempt cannot fail over, so it always returns above.
+	return ""
+}
+
+// acquireProvider records an in-flight request for load-tracking routers.
+// Returns the matching release function, or nil if the router does not track load.
+func (h *Handler) acquireProvider(ctx context.Context, selected router.ProviderInfo) func() {
+	if tracker, ok := h.routerFor(ctx).(router.ProviderLoadTracker); ok {
+		tracker.Acquire(selected.Provider)
+		return func() { tracker.Release(selected.Provider) }
+	}
+	return nil
+}
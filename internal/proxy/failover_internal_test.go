@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+func TestAttemptWriterCommitsNonTriggerStatus(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), true)
+	attempt.Header().Set("X-Test", "value")
+
+	attempt.WriteHeader(http.StatusOK)
+	_, err := attempt.Write([]byte("data: ok\n\n"))
+
+	assert.NoError(t, err)
+	assert.False(t, attempt.failedOver())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "value", rec.Header().Get("X-Test"))
+	assert.Equal(t, "data: ok\n\n", rec.Body.String())
+}
+
+func TestAttemptWriterDiscardsTriggerStatus(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), true)
+	attempt.Header().Set("X-Attempt-Only", "1")
+
+	attempt.WriteHeader(http.StatusServiceUnavailable)
+	written, err := attempt.Write([]byte(`{"error":"overloaded"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, len(`{"error":"overloaded"}`), written)
+	assert.True(t, attempt.failedOver())
+	assert.Equal(t, router.TriggerStatusCode, attempt.trigger.Name())
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Attempt-Only"), "discarded attempt headers must not leak")
+}
+
+func TestAttemptWriterFinalAttemptAlwaysCommits(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), false)
+
+	attempt.WriteHeader(http.StatusServiceUnavailable)
+
+	assert.False(t, attempt.failedOver())
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestAttemptWriterPrefersErrorTrigger(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), true)
+
+	attempt.recordUpstreamError(context.DeadlineExceeded)
+	attempt.WriteHeader(http.StatusBadGateway)
+
+	assert.True(t, attempt.failedOver())
+	assert.Equal(t, router.TriggerTimeout, attempt.trigger.Name())
+}
+
+func TestAttemptWriterImplicitOKOnWrite(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), true)
+
+	_, err := attempt.Write([]byte("body"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, attempt.status)
+	assert.Equal(t, "body", rec.Body.String())
+}
+
+func TestProviderAttemptsCanFailover(t *testing.T) {
+	t.Parallel()
+
+	info := testProviderInfo(newMockProvider("p1"))
+	tests := []struct {
+		name     string
+		attempts providerAttempts
+		want     bool
+	}{
+		{
+			name:     "single provider",
+			attempts: providerAttempts{triggers: router.DefaultTriggers(), providers: []router.ProviderInfo{info}},
+			want:     false,
+		},
+		{
+			name:     "no triggers",
+			attempts: providerAttempts{triggers: nil, providers: []router.ProviderInfo{info, info}},
+			want:     false,
+		},
+		{
+			name:     "multiple providers with triggers",
+			attempts: providerAttempts{triggers: router.DefaultTriggers(), providers: []router.ProviderInfo{info, info}},
+			want:     true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.want, testCase.attempts.canFailover())
+		})
+	}
+}
+
+func TestProviderProxyErrorHandlerRecordsUpstreamError(t *testing.T) {
+	t.Parallel()
+
+	provider := newMockProvider("p1")
+	provider.baseURL = "http://127.0.0.1:1"
+	providerProxy, err := NewProviderProxy(provider, "", nil, testDebugOptions(), nil)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	attempt := newAttemptWriter(rec, router.DefaultTriggers(), false)
+	upstreamErr := errors.New("dial failed")
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, messagesPath, nil)
+	providerProxy.Proxy.ErrorHandler(attempt, req, upstreamErr)
+
+	assert.Equal(t, upstreamErr, attempt.upstreamErr)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
@@ -0,0 +1,161 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const failoverRequestBody = `{"model":"claude-sonnet-4-20250514","messages":[{"role":"user","content":"hi"}]}`
+
+// newFailoverHandler builds a handler whose providers are tried in the given order.
+func newFailoverHandler(t *testing.T, rtr router.ProviderRouter, provs ...providers.Provider) *proxy.Handler {
+	t.Helper()
+
+	infos := make([]router.ProviderInfo, 0, len(provs))
+	keys := make(map[string]string, len(provs))
+	for idx, prov := range provs {
+		info := proxy.TestProviderInfo(prov)
+		info.Priority = len(provs) - idx
+		infos = append(infos, info)
+		keys[prov.Name()] = testKey
+	}
+
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = provs[0]
+	opts.ProviderInfos = infos
+	opts.ProviderRouter = rtr
+	opts.ProviderKeys = keys
+	opts.RoutingDebug = true
+
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+	return handler
+}
+
+func TestHandlerFailoverOnTriggerStatus(t *testing.T) {
+	t.Parallel()
+
+	failing := proxy.NewStatusBackend(t, http.StatusServiceUnavailable, `{"error":"overloaded"}`, nil)
+	healthy, recorder := proxy.NewRecordingBackend(t)
+
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, failing.URL),
+		proxy.NewNamedProvider(providerBName, healthy.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get(proxy.HeaderRelayAttempts))
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.JSONEq(t, failoverRequestBody, string(recorder.Body()), "second attempt must receive the original body")
+}
+
+func TestHandlerFailoverSkipsNonTriggerStatus(t *testing.T) {
+	t.Parallel()
+
+	badRequest := proxy.NewStatusBackend(t, http.StatusBadRequest, `{"error":"bad"}`, nil)
+	healthy, recorder := proxy.NewRecordingBackend(t)
+
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, badRequest.URL),
+		proxy.NewNamedProvider(providerBName, healthy.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get(proxy.HeaderRelayAttempts))
+	assert.Nil(t, recorder.Body(), "second provider should not be called")
+}
+
+func TestHandlerFailoverOnConnectionError(t *testing.T) {
+	t.Parallel()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	healthy := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+
+	// Only the connection trigger is configured, so the failover must come from
+	// the transport error rather than the synthesized 502 status.
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0, router.NewConnectionTrigger()),
+		proxy.NewNamedProvider(providerAName, closedURL),
+		proxy.NewNamedProvider(providerBName, healthy.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"id":"msg_ok"}`, rr.Body.String())
+	assert.Equal(t, "2", rr.Header().Get(proxy.HeaderRelayAttempts))
+}
+
+func TestHandlerFailoverAllProvidersFail(t *testing.T) {
+	t.Parallel()
+
+	first := proxy.NewStatusBackend(t, http.StatusInternalServerError, `{"error":"first"}`, nil)
+	last := proxy.NewStatusBackend(t, http.StatusBadGateway, `{"error":"last"}`, map[string]string{
+		"X-Upstream": "last",
+	})
+
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, first.URL),
+		proxy.NewNamedProvider(providerBName, last.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.JSONEq(t, `{"error":"last"}`, rr.Body.String())
+	assert.Equal(t, "last", rr.Header().Get("X-Upstream"))
+	assert.Equal(t, "2", rr.Header().Get(proxy.HeaderRelayAttempts))
+}
+
+func TestHandlerNoFailoverForNonFailoverStrategy(t *testing.T) {
+	t.Parallel()
+
+	failing := proxy.NewStatusBackend(t, http.StatusServiceUnavailable, `{"error":"overloaded"}`, nil)
+	healthy, recorder := proxy.NewRecordingBackend(t)
+
+	// round_robin starts at the first provider and never re-dispatches.
+	handler := newFailoverHandler(t, router.NewRoundRobinRouter(),
+		proxy.NewNamedProvider(providerAName, failing.URL),
+		proxy.NewNamedProvider(providerBName, healthy.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Empty(t, rr.Header().Get(proxy.HeaderRelayAttempts))
+	assert.Nil(t, recorder.Body())
+}
+
+func TestHandlerFailoverThroughLiveRouter(t *testing.T) {
+	t.Parallel()
+
+	failing := proxy.NewStatusBackend(t, http.StatusTooManyRequests, `{"error":"rate_limited"}`, nil)
+	healthy := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+
+	failover := router.NewFailoverRouter(0)
+	live := router.NewLiveRouter(func() router.ProviderRouter { return failover })
+
+	handler := newFailoverHandler(t, live,
+		proxy.NewNamedProvider(providerAName, failing.URL),
+		proxy.NewNamedProvider(providerBName, healthy.URL),
+	)
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+}
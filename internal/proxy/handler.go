@@ -7,33 +7,59 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/health"
 	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/metrics"
 	"github.com/omarluq/cc-relay/internal/providers"
 	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/tracing"
 )
 
 // contextKey is used for storing values in request context.
 type contextKey string
 
 const (
-	keyIDContextKey           contextKey = "keyID"
-	providerNameContextKey    contextKey = "providerName"
-	modelNameContextKey       contextKey = "modelName"
-	thinkingContextContextKey contextKey = "thinkingContext"
-	handlerOptionsRequiredMsg            = "handler options are required"
+	keyIDContextKey             contextKey = "keyID"
+	providerNameContextKey      contextKey = "providerName"
+	modelNameContextKey         contextKey = "modelName"
+	upstreamStartContextKey     contextKey = "upstreamStart"
+	routingRuleContextKey       contextKey = "routingRule"
+	requestSizeContextKey       contextKey = "requestSize"
+	sessionContextKey           contextKey = "session"
+	hedgeRoleContextKey         contextKey = "hedge_role"
+	responseCacheContextKey     contextKey = "responseCacheKey"
+	thinkingContextContextKey   contextKey = "thinkingContext"
+	accessRequestContextKey     contextKey = "accessRequest"
+	clientRateLimiterContextKey contextKey = "clientRateLimiter"
+	sensitiveDataContextKey     contextKey = "sensitiveData"
+	handlerOptionsRequiredMsg              = "handler options are required"
 )
 
+// requestModel returns the model the client requested, or "" if unknown.
+func requestModel(ctx context.Context) string {
+	if model, ok := ctx.Value(modelNameContextKey).(string); ok {
+		return model
+	}
+	return ""
+}
+
 // ProviderInfoFunc is a function that returns current provider routing information.
 // This enables hot-reload of provider inputs (enabled/disabled, weights, priorities)
 // without recreating the handler.
@@ -58,6 +84,10 @@ type HandlerOptions struct {
 	RoutingConfig     *config.RoutingConfig
 	HealthTracker     *health.Tracker
 	SignatureCache    *SignatureCache
+	Metrics           *metrics.Metrics
+	Budgets           *budget.Tracker
+	Sessions          *SessionStore
+	ResponseCache     *ResponseCache
 	APIKey            string `json:"-"`
 	ProviderInfos     []router.ProviderInfo
 	DebugOptions      config.DebugOptions
@@ -69,15 +99,22 @@ type Handler struct {
 	router           router.ProviderRouter
 	runtimeCfg       config.RuntimeConfigGetter
 	defaultProvider  providers.Provider
-	routingConfig    *config.RoutingConfig
-	healthTracker    *health.Tracker
+	hedgeDelays      *router.HedgeDelays
+	providers        ProviderInfoFunc
 	signatureCache   *SignatureCache
+	metrics          *metrics.Metrics
+	budgets          *budget.Tracker
+	sessions         *SessionStore
+	responses        *ResponseCache
+	routingConfig    *config.RoutingConfig
 	providerProxies  map[string]*ProviderProxy
-	providers        ProviderInfoFunc
+	healthTracker    *health.Tracker
 	getProviderPools KeyPoolsFunc
 	getProviderKeys  KeysFunc
 	providerPools    map[string]*keypool.KeyPool
 	providerKeys     map[string]string
+	ruleRouters      ruleRouters
+	knownModels      knownModels
 	debugOpts        config.DebugOptions
 	proxyMu          sync.RWMutex
 	routingDebug     bool
@@ -91,6 +128,10 @@ type Handler struct {
 // RoutingConfig contains model-based routing configuration (may be nil).
 // If HealthTracker is provided, success/failure will be reported to circuit breakers.
 // If SignatureCache is provided, thinking signatures are cached for cross-provider reuse.
+// If Metrics is provided, request counts, latency and failovers are recorded.
+// If Budgets is provided, the live config's budgets are enforced.
+// If Sessions is provided, session affinity pins conversations when enabled.
+// If ResponseCache is provided, deterministic responses are cached when response_cache is enabled.
 //
 // For hot-reloadable provider inputs, set ProviderInfosFunc. Otherwise, ProviderInfos is used.
 // For hot-reloadable key pools, set GetProviderPools and GetProviderKeys.
@@ -127,10 +168,17 @@ func newHandlerWithOptions(opts *HandlerOptions) (*Handler, error) {
 		routingDebug:     opts.RoutingDebug,
 		healthTracker:    opts.HealthTracker,
 		signatureCache:   opts.SignatureCache,
+		metrics:          opts.Metrics,
+		budgets:          opts.Budgets,
+		sessions:         opts.Sessions,
+		responses:        opts.ResponseCache,
+		hedgeDelays:      router.NewHedgeDelays(),
 		getProviderPools: opts.GetProviderPools,
 		getProviderKeys:  opts.GetProviderKeys,
 		providerPools:    providerPools,
 		providerKeys:     providerKeys,
+		ruleRouters:      ruleRouters{routers: nil, mu: sync.Mutex{}},
+		knownModels:      knownModels{models: nil, providers: nil, mu: sync.Mutex{}},
 		proxyMu:          sync.RWMutex{},
 	}
 
@@ -247,22 +295,64 @@ func (h *Handler) modifyResponse(resp *http.Response) error {
 	if name, ok := resp.Request.Context().Value(providerNameContextKey).(string); ok {
 		providerName = name
 	}
+	var pool *keypool.KeyPool
 	if providerName != "" {
 		// Thread-safe read of provider proxy
 		h.proxyMu.RLock()
 		pp, ok := h.providerProxies[providerName]
 		h.proxyMu.RUnlock()
 		if ok && pp.KeyPool != nil {
-			h.updateKeyPoolFromResponse(resp, pp.KeyPool)
+			pool = pp.KeyPool
+			h.updateKeyPoolFromResponse(resp, pool)
+		}
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		chargers := h.usageChargers(resp.Request.Context(), pool)
+		if charger := h.tapLatency(resp); charger != nil {
+			chargers = append(chargers, charger)
 		}
+		h.tapTokenUsage(resp, chargers)
+		h.rememberSession(resp)
+		h.tapResponseCache(resp)
 	}
 
+	traceUpstreamResponse(resp)
+
 	// Report outcome to circuit breaker
 	h.reportOutcome(resp)
 
+	h.tapThinkingSignatures(resp)
+	traceStream(resp)
+
 	return nil
 }
 
+// tapThinkingSignatures wraps successful SSE and JSON bodies so thinking
+// signatures are cached for reuse when the client replays the conversation.
+// Bedrock responses arrive here already converted from Event Stream to SSE.
+func (h *Handler) tapThinkingSignatures(resp *http.Response) {
+	if h.signatureCache == nil || resp.Body == nil || resp.StatusCode != http.StatusOK {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != providers.ContentTypeSSE && mediaType != mediaTypeJSON) {
+		return
+	}
+	ctx := resp.Request.Context()
+	model, ok := ctx.Value(modelNameContextKey).(string)
+	if !ok || model == "" {
+		return
+	}
+	if mediaType == mediaTypeJSON {
+		onJSONBody(resp, maxUsageJSONBody, func(body []byte, complete bool) {
+			cacheJSONSignatures(ctx, h.signatureCache, model, body, complete)
+		})
+		return
+	}
+	resp.Body = newSignatureTapBody(ctx, resp.Body, h.signatureCache, model)
+}
+
 // updateKeyPoolFromResponse updates key pool state from response headers.
 func (h *Handler) updateKeyPoolFromResponse(resp *http.Response, pool *keypool.KeyPool) {
 	keyID, ok := resp.Request.Context().Value(keyIDContextKey).(string)
@@ -288,6 +378,66 @@ func (h *Handler) updateKeyPoolFromResponse(resp *http.Response, pool *keypool.K
 	}
 }
 
+// usageCharger consumes the token usage reported by a successful response.
+type usageCharger func(ctx context.Context, usage tokenUsage)
+
+// usageChargers returns what a successful response's usage is charged to:
+// the budgets that apply to the request and the key that served it.
+func (h *Handler) usageChargers(ctx context.Context, pool *keypool.KeyPool) []usageCharger {
+	var chargers []usageCharger
+	if charger := h.budgetCharger(ctx); charger != nil {
+		chargers = append(chargers, charger)
+	}
+	if charger := clientRateLimitCharger(ctx); charger != nil {
+		chargers = append(chargers, charger)
+	}
+
+	keyID, ok := ctx.Value(keyIDContextKey).(string)
+	if pool == nil || !ok || keyID == "" {
+		return chargers
+	}
+	return append(chargers, func(chargeCtx context.Context, usage tokenUsage) {
+		chargeErr := pool.RecordUsage(chargeCtx, keyID, usage.rateLimitedInput(), int(usage.outputTokens))
+		if chargeErr != nil {
+			zerolog.Ctx(chargeCtx).Warn().Err(chargeErr).Str("key_id", keyID).Msg("dropped token usage charge")
+		}
+	})
+}
+
+// tapTokenUsage wraps a successful body so the tokens it reports are passed
+// to chargers. Charging happens off the response path because budgets may
+// be counted in a remote cache.
+func (h *Handler) tapTokenUsage(resp *http.Response, chargers []usageCharger) {
+	if resp.Body == nil || len(chargers) == 0 {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != providers.ContentTypeSSE && mediaType != mediaTypeJSON) {
+		return
+	}
+
+	ctx := context.WithoutCancel(resp.Request.Context())
+	chargeUsage := func(usage tokenUsage) {
+		go func() {
+			chargeCtx, cancel := context.WithTimeout(ctx, usageChargeTimeout)
+			defer cancel()
+			for _, charge := range chargers {
+				charge(chargeCtx, usage)
+			}
+		}()
+	}
+	if mediaType == providers.ContentTypeSSE {
+		resp.Body = newUsageTapBody(resp.Body, chargeUsage)
+		return
+	}
+	// A body closed early is still charged, since the upstream billed it.
+	onJSONBody(resp, maxUsageJSONBody, func(body []byte, _ bool) {
+		if usage, found := jsonUsage(body); found {
+			chargeUsage(usage)
+		}
+	})
+}
+
 // reportOutcome records success or failure to the circuit breaker.
 func (h *Handler) reportOutcome(resp *http.Response) {
 	if h.healthTracker == nil {
@@ -444,23 +594,93 @@ func (h *Handler) selectProvider(
 		}()
 	}
 
-	candidates, hasCandidates := h.providerCandidates()
+	candidates, hasCandidates := h.routingCandidates(ctx, model, hasThinkingAffinity)
 	if !hasCandidates {
-		return h.defaultProviderInfo(), nil
+		return h.fallbackProvider(ctx, model)
+	}
+	if pinned, ok := sessionProvider(ctx, candidates); ok {
+		return pinned, nil
 	}
 
-	// Filter providers if model-based routing is enabled
-	candidates, hasCandidates = h.applyModelRouting(candidates, model)
+	ctx = router.WithModel(ctx, model)
+	if routingConfig := h.getRoutingConfig(); routingConfig != nil {
+		ctx = router.WithQualityFloor(ctx, routingConfig.QualityFloorFor(model))
+	}
+	return h.routerFor(ctx).Select(ctx, candidates)
+}
+
+// routingCandidates returns the providers eligible for this request after
+// routing rule or model-based filtering, client provider restrictions, model
+// limits, access policies, provider trust and thinking affinity are applied.
+// Returns false when the handler should fall back to the default provider.
+func (h *Handler) routingCandidates(
+	ctx context.Context, model string, hasThinkingAffinity bool,
+) ([]router.ProviderInfo, bool) {
+	candidates, hasCandidates := h.providerCandidates()
 	if !hasCandidates {
-		return h.defaultProviderInfo(), nil
+		return nil, false
+	}
+
+	// A routing rule's providers take the place of model-based routing
+	if ruleCandidates, hasRule := applyRuleProviders(ctx, candidates); hasRule {
+		candidates = ruleCandidates
+	} else if candidates, hasCandidates = h.applyModelRouting(candidates, model); !hasCandidates {
+		return nil, false
+	}
+
+	if candidates, _ = h.filterCandidates(ctx, model, candidates); len(candidates) == 0 {
+		return nil, false
+	}
+
+	// A pinned session already keeps the conversation on one provider
+	if _, pinned := sessionProvider(ctx, candidates); pinned {
+		return candidates, true
 	}
 
 	// If thinking affinity is required, use deterministic selection.
 	// This ensures that thinking-enabled conversations always route to the same
 	// provider (the first healthy one), preventing signature validation failures.
-	candidates = h.applyThinkingAffinity(candidates, hasThinkingAffinity)
+	return h.applyThinkingAffinity(candidates, hasThinkingAffinity), true
+}
 
-	return h.router.Select(ctx, candidates)
+// filterCandidates drops the candidates the request may not be sent to: those
+// the client may not use, whose limits the request exceeds, that access
+// policies deny, or that are not trusted with its sensitive data. If none
+// remain, the error says which filter removed the last of them.
+func (h *Handler) filterCandidates(
+	ctx context.Context, model string, candidates []router.ProviderInfo,
+) ([]router.ProviderInfo, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	client := auth.ClientFromContext(ctx)
+	if candidates = filterClientProviders(client, candidates); len(candidates) == 0 {
+		return nil, clientNotAllowedError(client)
+	}
+	if candidates = h.filterModelLimits(ctx, model, candidates); len(candidates) == 0 {
+		return nil, requestTooLargeError(ctx, model)
+	}
+	allowed := h.filterAccess(ctx, model, candidates)
+	if len(allowed) == 0 {
+		return nil, h.accessDeniedError(ctx, model, candidates[0].Provider)
+	}
+	if candidates = h.filterTrusted(ctx, allowed); len(candidates) == 0 {
+		return nil, untrustedProviderError(ctx)
+	}
+	return candidates, nil
+}
+
+// fallbackProvider returns the default provider, used when routing leaves no
+// candidate, after checking the request may be sent to it.
+func (h *Handler) fallbackProvider(ctx context.Context, model string) (router.ProviderInfo, error) {
+	fallback := h.defaultProviderInfo()
+	if _, err := h.filterCandidates(ctx, model, []router.ProviderInfo{fallback}); err != nil {
+		if errors.Is(err, errAccessDenied) {
+			zerolog.Ctx(ctx).Info().Err(err).Msg("request denied by access policy")
+		}
+		return router.ProviderInfo{}, err
+	}
+	return fallback, nil
 }
 
 func (h *Handler) providerCandidates() ([]router.ProviderInfo, bool) {
@@ -480,6 +700,7 @@ func (h *Handler) defaultProviderInfo() router.ProviderInfo {
 		IsHealthy: func() bool { return true },
 		Weight:    0,
 		Priority:  0,
+		Prices:    nil,
 	}
 }
 
@@ -530,8 +751,10 @@ func (h *Handler) selectKeyFromPool(
 	writer http.ResponseWriter, request *http.Request, logger *zerolog.Logger,
 	pool *keypool.KeyPool,
 ) (keyID, selectedKey string, updatedReq *http.Request, ok bool) {
-	var err error
-	keyID, selectedKey, err = pool.GetKey(request.Context())
+	ctx, span := tracing.Start(request.Context(), spanSelectKey)
+	keyID, selectedKey, err := pool.GetKey(preferSessionKey(ctx))
+	span.SetAttributes(attrKeyID.String(keyID))
+	endSpan(span, err)
 	if errors.Is(err, keypool.ErrAllKeysExhausted) {
 		retryAfter := pool.GetEarliestResetTime()
 		WriteRateLimitError(writer, retryAfter)
@@ -562,33 +785,111 @@ func (h *Handler) selectKeyFromPool(
 // ServeHTTP handles the proxy request.
 func (h *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	start := time.Now()
+	if h.metrics == nil {
+		h.serve(writer, request, start)
+		return
+	}
 
+	observed := newObservedWriter(writer)
+	served := h.serve(observed, request, start)
+	h.observeRequest(observed, request, served, start)
+}
+
+// serve proxies the request and reports which provider served it.
+func (h *Handler) serve(writer http.ResponseWriter, request *http.Request, start time.Time) servedRequest {
 	prep, requestOK := h.prepareRequest(writer, request)
 	if !requestOK {
-		return
+		return servedRequest{provider: "", model: ""}
 	}
 	request = prep.request
 
-	selected, release, err := h.selectProviderWithTracking(request.Context(), prep.model, prep.hasThinking)
+	if client := auth.ClientFromContext(request.Context()); !client.AllowsModel(prep.model) {
+		WriteError(writer, http.StatusForbidden, "permission_error",
+			fmt.Sprintf("client %q is not allowed to use model %q", client.Name, prep.model))
+		return servedRequest{provider: "", model: prep.model}
+	}
+	request, allowed := h.checkAccess(writer, request, prep.model)
+	if !allowed {
+		return servedRequest{provider: "", model: prep.model}
+	}
+	if request, allowed = h.checkSensitiveData(writer, request); !allowed {
+		return servedRequest{provider: "", model: prep.model}
+	}
+	request, cached := h.serveCachedResponse(writer, request)
+	if cached {
+		return servedRequest{provider: "", model: prep.model}
+	}
+	if !h.checkBudgets(writer, request) {
+		return servedRequest{provider: "", model: prep.model}
+	}
+
+	request, prep.model = h.applyRoutingRule(request, prep.model)
+	request = h.resolveSession(request)
+	attempts, err := h.tracedSelectProviderAttempts(request, prep.model, prep.hasThinking)
 	if err != nil {
-		WriteError(writer, http.StatusServiceUnavailable, "api_error",
-			fmt.Sprintf("failed to select provider: %v", err))
-		return
+		writeSelectProviderError(writer, err)
+		return servedRequest{provider: "", model: prep.model}
 	}
-	if release != nil {
+	if plan, hedged := h.planHedge(request, prep, attempts); hedged {
+		return servedRequest{provider: h.serveHedged(writer, request, plan, start), model: prep.model}
+	}
+	if attempts.canFailover() {
+		return servedRequest{provider: h.serveWithFailover(writer, request, attempts, start), model: prep.model}
+	}
+	return servedRequest{provider: h.serveSingle(writer, request, attempts.providers[0], start), model: prep.model}
+}
+
+// serveSingle proxies the request to selected without failover and returns
+// the provider's name.
+func (h *Handler) serveSingle(
+	writer http.ResponseWriter, request *http.Request, selected router.ProviderInfo, start time.Time,
+) string {
+	if h.serveLocalTokenCount(writer, request, selected.Provider) {
+		return selected.Provider.Name()
+	}
+	if release := h.acquireProvider(request.Context(), selected); release != nil {
 		defer release()
 	}
 
 	proxyCtx, requestOK := h.prepareProxyRequest(writer, request, selected.Provider)
 	if !requestOK {
-		return
+		return selected.Provider.Name()
 	}
 
-	backendStart := time.Now()
-	serveReverseProxy(proxyCtx.proxy.Proxy, writer, proxyCtx.request)
-	backendTime := time.Since(backendStart)
+	backendTime := forward(writer, proxyCtx, selected.Provider)
 
 	h.logMetricsIfEnabled(proxyCtx.request, &proxyCtx.logger, start, backendTime, proxyCtx.getTLSMetrics)
+	return selected.Provider.Name()
+}
+
+// writeSelectProviderError writes the error response for a request no
+// provider could be selected for.
+func writeSelectProviderError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errProviderNotAllowed), errors.Is(err, errAccessDenied), errors.Is(err, errNoTrustedProvider):
+		WriteError(writer, http.StatusForbidden, "permission_error", err.Error())
+	case errors.Is(err, errContextWindowExceeded):
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", err.Error())
+	default:
+		WriteError(writer, http.StatusServiceUnavailable, "api_error",
+			fmt.Sprintf("failed to select provider: %v", err))
+	}
+}
+
+// forward sends the prepared request to the provider inside an upstream span
+// and returns how long the provider took, including the streamed body.
+func forward(writer http.ResponseWriter, proxyCtx proxyContext, provider providers.Provider) time.Duration {
+	ctx, span := tracing.StartClient(proxyCtx.request.Context(), spanUpstream, attrProvider.String(provider.Name()))
+	defer span.End()
+	if baseURL, err := url.Parse(provider.BaseURL()); err == nil && baseURL.Hostname() != "" {
+		span.SetAttributes(semconv.ServerAddress(baseURL.Hostname()))
+	}
+
+	backendStart := time.Now()
+	ctx = context.WithValue(ctx, upstreamStartContextKey, backendStart)
+	serveReverseProxy(proxyCtx.proxy.Proxy, writer, proxyCtx.request.WithContext(ctx))
+	recordTLSTiming(span, proxyCtx.getTLSMetrics)
+	return time.Since(backendStart)
 }
 
 // serveReverseProxy forwards the request via the pre-configured reverse proxy.
@@ -607,7 +908,13 @@ type requestPrep struct {
 }
 
 func (h *Handler) prepareRequest(writer http.ResponseWriter, request *http.Request) (requestPrep, bool) {
+	_, span := tracing.Start(request.Context(), spanExtractModel)
 	modelOpt, bodyTooLarge := ExtractModelWithBodyCheck(request)
+	span.SetAttributes(semconv.GenAIRequestModel(modelOpt.OrEmpty()))
+	if bodyTooLarge {
+		span.SetStatus(codes.Error, "request body too large")
+	}
+	span.End()
 	if bodyTooLarge {
 		WriteBodyTooLargeError(writer)
 		return requestPrep{request: nil, model: "", hasThinking: false}, false
@@ -622,6 +929,7 @@ func (h *Handler) prepareRequest(writer http.ResponseWriter, request *http.Reque
 	}
 
 	request = h.processThinkingSignatures(request, model)
+	request = h.estimateRequestSize(request)
 
 	hasThinking := h.detectThinkingAffinity(&request)
 	return requestPrep{request: request, model: model, hasThinking: hasThinking}, true
@@ -641,20 +949,6 @@ func (h *Handler) detectThinkingAffinity(request **http.Request) bool {
 	return true
 }
 
-func (h *Handler) selectProviderWithTracking(
-	ctx context.Context, model string, hasThinking bool,
-) (router.ProviderInfo, func(), error) {
-	selected, err := h.selectProvider(ctx, model, hasThinking)
-	if err != nil {
-		return router.ProviderInfo{}, nil, err
-	}
-	if tracker, ok := h.router.(router.ProviderLoadTracker); ok {
-		tracker.Acquire(selected.Provider)
-		return selected, func() { tracker.Release(selected.Provider) }, nil
-	}
-	return selected, nil, nil
-}
-
 type proxyContext struct {
 	request       *http.Request
 	proxy         *ProviderProxy
@@ -676,6 +970,10 @@ func (h *Handler) prepareProxyRequest(
 	request = request.WithContext(logger.WithContext(request.Context()))
 	request = request.WithContext(context.WithValue(request.Context(), providerNameContextKey, selectedProvider.Name()))
 
+	if !h.maskSensitiveData(writer, request, &logger, selectedProvider) {
+		return proxyContext{request: nil, proxy: nil, logger: zerolog.Logger{}, getTLSMetrics: nil}, false
+	}
+
 	h.logAndSetDebugHeaders(writer, request, &logger, selectedProvider)
 
 	var authOK bool
@@ -704,13 +1002,22 @@ func (h *Handler) logAndSetDebugHeaders(
 	hasThinking := GetThinkingAffinityFromContext(request.Context())
 
 	// Log routing strategy if router is available
-	if h.router != nil {
+	rtr := h.routerFor(request.Context())
+	rule, hasRule := routingRuleFromContext(request.Context())
+	affinity := sessionAffinity(request.Context(), selectedProvider.Name())
+	if rtr != nil {
 		event := logger.Debug().
-			Str("strategy", h.router.Name()).
+			Str("strategy", rtr.Name()).
 			Str("selected_provider", selectedProvider.Name())
 		if hasThinking {
 			event.Bool("thinking_affinity", true)
 		}
+		if hasRule {
+			event.Str("rule", rule.Name)
+		}
+		if affinity != "" {
+			event.Str("session_affinity", affinity)
+		}
 		event.Msg("provider selected by router")
 	}
 
@@ -719,12 +1026,8 @@ func (h *Handler) logAndSetDebugHeaders(
 	}
 
 	// Add debug headers if routing debug is enabled
-	if h.router != nil {
-		writer.Header().Set("X-CC-Relay-Strategy", h.router.Name())
-		writer.Header().Set("X-CC-Relay-Provider", selectedProvider.Name())
-		if hasThinking {
-			writer.Header().Set("X-CC-Relay-Thinking-Affinity", "true")
-		}
+	if rtr != nil {
+		setRoutingDebugHeaders(request.Context(), writer.Header(), rtr.Name(), selectedProvider.Name())
 	}
 
 	// Add health debug header
@@ -734,6 +1037,21 @@ func (h *Handler) logAndSetDebugHeaders(
 	}
 }
 
+// setRoutingDebugHeaders reports how the request was routed.
+func setRoutingDebugHeaders(ctx context.Context, header http.Header, strategy, providerName string) {
+	header.Set("X-CC-Relay-Strategy", strategy)
+	header.Set("X-CC-Relay-Provider", providerName)
+	if GetThinkingAffinityFromContext(ctx) {
+		header.Set("X-CC-Relay-Thinking-Affinity", "true")
+	}
+	if rule, ok := routingRuleFromContext(ctx); ok {
+		header.Set("X-CC-Relay-Rule", rule.Name)
+	}
+	if affinity := sessionAffinity(ctx, providerName); affinity != "" {
+		header.Set("X-CC-Relay-Session-Affinity", affinity)
+	}
+}
+
 // rewriteModelIfNeeded rewrites model name if provider has model mapping configured.
 func (h *Handler) rewriteModelIfNeeded(
 	request *http.Request, logger *zerolog.Logger, selectedProvider providers.Provider,
@@ -807,10 +1125,11 @@ func (h *Handler) handleKeyPoolSelection(
 	return updatedReq, true
 }
 
-// attachTLSTraceIfEnabled attaches TLS trace if debug metrics are enabled.
+// attachTLSTraceIfEnabled attaches TLS trace if debug metrics are enabled or the
+// request is being traced, so connection timing can be added to the upstream span.
 func (h *Handler) attachTLSTraceIfEnabled(request *http.Request) (req *http.Request, getMetrics func() TLSMetrics) {
 	debugOpts := h.getDebugOptions()
-	if !debugOpts.LogTLSMetrics {
+	if !debugOpts.LogTLSMetrics && !trace.SpanFromContext(request.Context()).IsRecording() {
 		return request, nil
 	}
 	newCtx, metricsFunc := AttachTLSTrace(request.Context(), request)
@@ -877,6 +1196,9 @@ func (h *Handler) processThinkingSignatures(request *http.Request, model string)
 	if request.Body == nil {
 		return request
 	}
+
+	ctx, span := tracing.Start(request.Context(), spanThinking)
+	defer span.End()
 	body, err := io.ReadAll(request.Body)
 	if closeErr := request.Body.Close(); closeErr != nil {
 		zerolog.Ctx(request.Context()).Error().Err(closeErr).Msg("failed to close request body")
@@ -903,13 +1225,17 @@ func (h *Handler) processThinkingSignatures(request *http.Request, model string)
 
 	// Process thinking blocks
 	logger := zerolog.Ctx(request.Context())
-	modifiedBody, thinkingCtx, err := ProcessRequestThinking(
-		request.Context(), body, modelName, h.signatureCache,
-	)
+	modifiedBody, thinkingCtx, err := ProcessRequestThinking(ctx, body, modelName, h.signatureCache)
 	if err != nil {
 		logger.Warn().Err(err).Msg("failed to process thinking signatures")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return request
 	}
+	span.SetAttributes(
+		attrDroppedBlocks.Int(thinkingCtx.DroppedBlocks),
+		attrReorderedBlocks.Bool(thinkingCtx.ReorderedBlocks),
+	)
 
 	// Log processing results
 	if thinkingCtx.DroppedBlocks > 0 {
@@ -49,6 +49,7 @@ func setupLiveRoutes(
 
 	return proxy.SetupRoutesWithLiveKeyPools(&proxy.RoutesOptions{
 		ConfigProvider:     config.NewRuntime(cfg),
+		ConfigReloader:     nil,
 		Provider:           provider,
 		ProviderInfosFunc:  func() []router.ProviderInfo { return nil },
 		ProviderRouter:     routerInstance,
@@ -59,6 +60,12 @@ func setupLiveRoutes(
 		AllProviders:       []providers.Provider{provider},
 		HealthTracker:      nil,
 		SignatureCache:     nil,
+		Metrics:            nil,
+		Budgets:            nil,
+		ClientLimits:       nil,
+		Sessions:           nil,
+		ResponseCache:      nil,
+		Tracing:            nil,
 		ProviderPools:      nil,
 		ProviderKeys:       nil,
 		GetAllProviders:    nil,
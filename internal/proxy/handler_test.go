@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/omarluq/cc-relay/internal/auth"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/health"
 	"github.com/omarluq/cc-relay/internal/keypool"
@@ -61,6 +62,10 @@ func newTestHandler(
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		DebugOptions: config.DebugOptions{
 			LogRequestBody:     false,
 			LogResponseHeaders: false,
@@ -118,6 +123,10 @@ func newHandlerWithPool(t *testing.T, provider providers.Provider, pool *keypool
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "",
 		ProviderInfos:     nil,
 		DebugOptions: config.DebugOptions{
@@ -172,6 +181,7 @@ func newTrackedHandler(
 			IsHealthy: tracker.IsHealthyFunc(providerName),
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -188,6 +198,10 @@ func newTrackedHandler(
 		GetProviderKeys:   nil,
 		RoutingConfig:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		DebugOptions: config.DebugOptions{
 			LogRequestBody:     false,
 			LogResponseHeaders: false,
@@ -229,6 +243,10 @@ func TestNewHandlerInvalidURL(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            testKey,
 		ProviderInfos:     nil,
 		DebugOptions: config.DebugOptions{
@@ -262,6 +280,10 @@ func TestNewHandlerNilProviderInfosFunc(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            testKey,
 		ProviderInfos:     nil,
 		DebugOptions: config.DebugOptions{
@@ -965,8 +987,8 @@ func TestHandlerMultiProviderModeUsesRouter(t *testing.T) {
 	provider2 := proxy.NewNamedProvider(testProvider2, backend.URL)
 
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	// Mock router that always selects provider2
@@ -978,6 +1000,7 @@ func TestHandlerMultiProviderModeUsesRouter(t *testing.T) {
 			IsHealthy: func() bool { return true },
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -1029,6 +1052,45 @@ func TestHandlerModelBasedRoutingHotReload(t *testing.T) {
 	assert.Equal(t, providerBName, capture.lastSeen[0].Provider.Name())
 }
 
+func TestHandlerCostOptimizedRouting(t *testing.T) {
+	t.Parallel()
+
+	cheap := proxy.NewJSONBackend(t, `{"id":"cheap"}`)
+	premium := proxy.NewJSONBackend(t, `{"id":"premium"}`)
+	cheapInfo := proxy.TestProviderInfo(proxy.NewNamedProvider(providerAName, cheap.URL))
+	cheapInfo.Prices = map[string]router.ModelPrice{"claude": {InputPerMTok: 1, OutputPerMTok: 5, Quality: 5}}
+	premiumInfo := proxy.TestProviderInfo(proxy.NewNamedProvider(providerBName, premium.URL))
+	premiumInfo.Prices = map[string]router.ModelPrice{"claude": {InputPerMTok: 3, OutputPerMTok: 15, Quality: 9}}
+
+	runtimeCfg := config.NewRuntime(costOptimizedConfig(nil))
+	handler := setupModelBasedHandler(t, cheapInfo.Provider, []router.ProviderInfo{premiumInfo, cheapInfo},
+		router.NewCostOptimizedRouter(), runtimeCfg)
+	serveOpus := func() *httptest.ResponseRecorder {
+		return proxy.ServeRequest(t, handler, newJSONMessagesRequest(`{"model":"claude-opus-4","messages":[]}`))
+	}
+
+	resp := serveOpus()
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"id":"cheap"}`, resp.Body.String())
+
+	// A quality floor added by a reload keeps opus requests on the premium provider
+	runtimeCfg.Store(costOptimizedConfig(map[string]int{"claude-opus": 8}))
+	resp = serveOpus()
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"id":"premium"}`, resp.Body.String())
+
+	// No provider meets a floor above every quality
+	runtimeCfg.Store(costOptimizedConfig(map[string]int{"claude-opus": 10}))
+	assert.Equal(t, http.StatusServiceUnavailable, serveOpus().Code)
+}
+
+func costOptimizedConfig(qualityFloor map[string]int) *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Strategy = router.StrategyCostOptimized
+	cfg.Routing.QualityFloor = qualityFloor
+	return cfg
+}
+
 // setupModelBasedHandler creates a handler with live providers for model-based routing tests.
 func setupModelBasedHandler(
 	t *testing.T,
@@ -1051,6 +1113,10 @@ func setupModelBasedHandler(
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
 		RoutingDebug:      false,
@@ -1070,7 +1136,7 @@ func TestHandlerLazyProxyForNewProvider(t *testing.T) {
 	providerB := proxy.NewNamedProvider(providerBName, backendB.URL)
 
 	infos := []router.ProviderInfo{
-		{Provider: providerA, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: providerA, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 	providerInfosFunc := func() []router.ProviderInfo { return infos }
 
@@ -1082,6 +1148,7 @@ func TestHandlerLazyProxyForNewProvider(t *testing.T) {
 			IsHealthy: func() bool { return true },
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -1105,14 +1172,18 @@ func TestHandlerLazyProxyForNewProvider(t *testing.T) {
 		RoutingConfig:    nil,
 		HealthTracker:    nil,
 		SignatureCache:   nil,
+		Metrics:          nil,
+		Budgets:          nil,
+		Sessions:         nil,
+		ResponseCache:    nil,
 		ProviderInfos:    nil,
 	})
 	require.NoError(t, err)
 
 	// Simulate reload: provider B becomes enabled
 	infos = []router.ProviderInfo{
-		{Provider: providerA, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: providerB, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: providerA, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: providerB, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	req := proxy.NewMessagesRequestWithHeaders(`{"model":"test","messages":[]}`)
@@ -1129,7 +1200,7 @@ func TestHandlerDebugHeadersDisabledByDefault(t *testing.T) {
 
 	provider := proxy.NewTestProvider(backend.URL)
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	mockR := &mockRouter{
@@ -1140,6 +1211,7 @@ func TestHandlerDebugHeadersDisabledByDefault(t *testing.T) {
 			IsHealthy: func() bool { return true },
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -1163,7 +1235,7 @@ func TestHandlerDebugHeadersWhenEnabled(t *testing.T) {
 
 	provider := proxy.NewNamedProvider(testProviderName, backend.URL)
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	mockR := &mockRouter{
@@ -1174,6 +1246,7 @@ func TestHandlerDebugHeadersWhenEnabled(t *testing.T) {
 			IsHealthy: func() bool { return true },
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -1194,14 +1267,14 @@ func TestHandlerRouterSelectionError(t *testing.T) {
 
 	provider := proxy.NewTestProvider(proxy.AnthropicBaseURL)
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider, IsHealthy: func() bool { return false }, Weight: 0, Priority: 0},
+		{Provider: provider, IsHealthy: func() bool { return false }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	// Mock router that returns error
 	mockR := &mockRouter{
 		name:     "failover",
 		err:      router.ErrAllProvidersUnhealthy,
-		selected: router.ProviderInfo{Provider: nil, IsHealthy: nil, Weight: 0, Priority: 0},
+		selected: router.ProviderInfo{Provider: nil, IsHealthy: nil, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	handler := newTestHandler(t, provider, providerInfos, mockR, testKey, false)
@@ -1243,8 +1316,8 @@ func TestHandlerSelectProviderMultiMode(t *testing.T) {
 	provider2 := proxy.NewNamedProvider(testProvider2, proxy.AnthropicBaseURL)
 
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	mockR := &mockRouter{
@@ -1255,6 +1328,7 @@ func TestHandlerSelectProviderMultiMode(t *testing.T) {
 			IsHealthy: func() bool { return true },
 			Weight:    0,
 			Priority:  0,
+			Prices:    nil,
 		},
 	}
 
@@ -1388,8 +1462,8 @@ func TestHandlerThinkingAffinityUsesConsistentProvider(t *testing.T) {
 	provider2 := proxy.NewNamedProvider(testProvider2, backend2.URL)
 
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	tracker := &trackingRouter{name: "tracking", receivedProviders: nil}
@@ -1410,6 +1484,10 @@ func TestHandlerThinkingAffinityUsesConsistentProvider(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 	})
 	require.NoError(t, err)
 
@@ -1457,8 +1535,8 @@ func TestHandlerThinkingAffinityFallsBackToSecondProvider(t *testing.T) {
 
 	// Provider1 is unhealthy, provider2 is healthy
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return false }, Weight: 0, Priority: 0}, // UNHEALTHY
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return false }, Prices: nil, Weight: 0, Priority: 0}, // UNHEALTHY
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	tracker := &trackingRouter{name: "tracking", receivedProviders: nil}
@@ -1479,6 +1557,10 @@ func TestHandlerThinkingAffinityFallsBackToSecondProvider(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 	})
 	require.NoError(t, err)
 
@@ -1507,8 +1589,8 @@ func TestHandlerNoThinkingUsesNormalRouting(t *testing.T) {
 	provider2 := proxy.NewNamedProvider(testProvider2, backend2.URL)
 
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	// Tracker that counts how many providers were passed
@@ -1537,6 +1619,10 @@ func TestHandlerNoThinkingUsesNormalRouting(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 	})
 	require.NoError(t, err)
 
@@ -1606,6 +1692,10 @@ func TestHandlerGetOrCreateProxyNilKeysMapPreservesExistingProxy(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            initialKey,
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -1650,6 +1740,10 @@ func TestHandlerGetOrCreateProxyNilPoolsMapPreservesExistingProxy(t *testing.T)
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "",
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -1690,6 +1784,10 @@ func TestHandlerGetOrCreateProxyMultiProviderModeDetectsKeyChange(t *testing.T)
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "",
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -1746,6 +1844,10 @@ func TestHandlerGetOrCreateProxyMultiProviderModeDetectsPoolChange(t *testing.T)
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "",
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -1787,6 +1889,10 @@ func TestHandlerGetOrCreateProxyProviderBaseURLChangeCreatesNewProxy(t *testing.
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            testKey,
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -1843,6 +1949,10 @@ func TestHandlerGetOrCreateProxyLiveKeysFuncUsedOverStaticMap(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "",
 		ProviderInfos:     nil,
 	})
@@ -1865,3 +1975,57 @@ func TestHandlerGetOrCreateProxyLiveKeysFuncUsedOverStaticMap(t *testing.T) {
 	assert.NotSame(t, pp1, pp2)
 	assert.Equal(t, "live-key-v2", pp2.APIKey)
 }
+
+func serveAsClient(t *testing.T, handler http.Handler, client *auth.Client) *httptest.ResponseRecorder {
+	t.Helper()
+	req := newJSONMessagesRequest(failoverRequestBody)
+	req = req.WithContext(auth.WithClient(req.Context(), client))
+	return proxy.ServeRequest(t, handler, req)
+}
+
+func TestHandlerClientRestrictions(t *testing.T) {
+	t.Parallel()
+
+	backendA := proxy.NewJSONBackend(t, `{"id":"msg_a"}`)
+	backendB := proxy.NewJSONBackend(t, `{"id":"msg_b"}`)
+	providerA := proxy.NewNamedProvider(providerAName, backendA.URL)
+	providerB := proxy.NewNamedProvider(providerBName, backendB.URL)
+	client := func(models, provs []string) *auth.Client {
+		return &auth.Client{Metadata: nil, Name: "alice", AllowedModels: models, AllowedProviders: provs}
+	}
+
+	t.Run("routes only to allowed providers", func(t *testing.T) {
+		t.Parallel()
+		handler := newFailoverHandler(t, router.NewFailoverRouter(0), providerA, providerB)
+
+		rr := serveAsClient(t, handler, client(nil, []string{providerBName}))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	})
+
+	t.Run("rejects default provider that is not allowed", func(t *testing.T) {
+		t.Parallel()
+		handler := newHandlerWithAPIKey(t, providerA)
+
+		rr := serveAsClient(t, handler, client(nil, []string{providerBName}))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "permission_error")
+	})
+
+	t.Run("rejects model that is not allowed", func(t *testing.T) {
+		t.Parallel()
+		handler := newHandlerWithAPIKey(t, providerA)
+
+		rr := serveAsClient(t, handler, client([]string{"claude-haiku"}, nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), `client \"alice\" is not allowed to use model \"claude-sonnet-4-20250514\"`)
+	})
+
+	t.Run("allows model by prefix", func(t *testing.T) {
+		t.Parallel()
+		handler := newHandlerWithAPIKey(t, providerA)
+
+		rr := serveAsClient(t, handler, client([]string{"claude-sonnet"}, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
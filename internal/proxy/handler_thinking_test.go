@@ -325,8 +325,8 @@ func TestHandlerThinkingSignatureCrossProviderRouting(t *testing.T) {
 	provider2 := proxy.NewNamedProvider(testProvider2, backend2.URL)
 
 	providerInfos := []router.ProviderInfo{
-		{Provider: provider1, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
-		{Provider: provider2, IsHealthy: func() bool { return true }, Weight: 0, Priority: 0},
+		{Provider: provider1, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
+		{Provider: provider2, IsHealthy: func() bool { return true }, Prices: nil, Weight: 0, Priority: 0},
 	}
 
 	// Create round-robin router
@@ -341,6 +341,10 @@ func TestHandlerThinkingSignatureCrossProviderRouting(t *testing.T) {
 		ProviderKeys:      map[string]string{testProvider1: testKey1, testProvider2: testKey2},
 		DebugOptions:      proxy.TestDebugOptions(),
 		SignatureCache:    sigCache,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		ProviderInfosFunc: nil,
 		Pool:              nil,
 		GetProviderPools:  nil,
@@ -404,6 +408,10 @@ func TestHandlerNoSignatureCachePassesThrough(t *testing.T) {
 		RoutingConfig:     nil,
 		HealthTracker:     nil,
 		SignatureCache:    nil,
+		Metrics:           nil,
+		Budgets:           nil,
+		Sessions:          nil,
+		ResponseCache:     nil,
 		APIKey:            "test-key",
 		ProviderInfos:     nil,
 		DebugOptions:      proxy.TestDebugOptions(),
@@ -435,3 +443,64 @@ func TestHandlerNoSignatureCachePassesThrough(t *testing.T) {
 	receivedSig := gjson.GetBytes(recorder.Body(), signatureJSONPath).String()
 	assert.Equal(t, sig, receivedSig, "signature should pass through unchanged")
 }
+
+func TestHandlerCachesSignatureFromStreamedResponse(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := proxy.NewTestSignatureCache(t)
+	defer cleanup()
+
+	thinkingText := "Streaming thoughts."
+	sig := proxy.ValidTestSignature
+	stream := "event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"` +
+		thinkingText + `"}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"` +
+		sig + `"}}` + "\n\n" +
+		"event: content_block_stop\n" +
+		`data: {"type":"content_block_stop","index":0}` + "\n\n"
+
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set(proxy.ContentTypeHeader, "text/event-stream")
+		writer.WriteHeader(http.StatusOK)
+		if _, err := writer.Write([]byte(stream)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	handler := proxy.NewHandlerWithSignatureCache(t, proxy.NewTestProvider(backend.URL), sigCache)
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","stream":true,"messages":[]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, stream, rr.Body.String())
+	require.Eventually(t, func() bool {
+		return sigCache.Get(context.Background(), "claude-sonnet-4", thinkingText) == sig
+	}, time.Second, 10*time.Millisecond, "streamed signature should be cached")
+}
+
+func TestHandlerCachesSignatureFromJSONResponse(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := proxy.NewTestSignatureCache(t)
+	defer cleanup()
+
+	thinkingText := "Buffered thoughts."
+	sig := proxy.ValidTestSignature
+	response := `{"id":"msg_1","type":"message","role":"assistant","content":[` +
+		`{"type":"thinking","thinking":"` + thinkingText + `","signature":"` + sig + `"},` +
+		`{"type":"text","text":"Answer"}]}`
+	backend := proxy.NewJSONBackend(t, response)
+
+	handler := proxy.NewHandlerWithSignatureCache(t, proxy.NewTestProvider(backend.URL), sigCache)
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","messages":[]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, response, rr.Body.String())
+	require.Eventually(t, func() bool {
+		return sigCache.Get(context.Background(), "claude-sonnet-4", thinkingText) == sig
+	}, time.Second, 10*time.Millisecond, "signature from a JSON response should be cached")
+}
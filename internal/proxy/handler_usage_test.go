@@ -0,0 +1,86 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+func newUsagePool(t *testing.T) *keypool.KeyPool {
+	t.Helper()
+	return newKeyPool(t, []keypool.KeyConfig{
+		{APIKey: poolKey1, RPMLimit: 50, ITPMLimit: 10000, OTPMLimit: 5000, Priority: 0, Weight: 0},
+	})
+}
+
+func TestHandlerChargesUsageFromJSONResponse(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1","usage":{"input_tokens":120,"output_tokens":30}}`)
+	pool := newUsagePool(t)
+	handler := newHandlerWithPool(t, proxy.NewTestProvider(backend.URL), pool)
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","messages":[]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	key := pool.Keys()[0]
+	require.Eventually(t, func() bool {
+		return strings.Contains(key.String(), "itpm=9880/10000 otpm=4970/5000")
+	}, time.Second, 10*time.Millisecond, "usage should be deducted from the key")
+}
+
+func TestHandlerChargesUsageFromStreamedResponse(t *testing.T) {
+	t.Parallel()
+
+	stream := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":200,"output_tokens":1}}}` +
+		"\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":75}}` + "\n\n" +
+		"event: message_stop\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set(proxy.ContentTypeHeader, "text/event-stream")
+		writer.WriteHeader(http.StatusOK)
+		if _, err := writer.Write([]byte(stream)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	pool := newUsagePool(t)
+	handler := newHandlerWithPool(t, proxy.NewTestProvider(backend.URL), pool)
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","stream":true,"messages":[]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, stream, rr.Body.String())
+	key := pool.Keys()[0]
+	require.Eventually(t, func() bool {
+		return strings.Contains(key.String(), "itpm=9800/10000 otpm=4925/5000")
+	}, time.Second, 10*time.Millisecond, "streamed usage should be deducted from the key")
+}
+
+func TestHandlerDoesNotChargeErrorResponses(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewStatusBackend(t, http.StatusBadRequest,
+		`{"type":"error","usage":{"input_tokens":120,"output_tokens":30}}`, nil)
+	pool := newUsagePool(t)
+	handler := newHandlerWithPool(t, proxy.NewTestProvider(backend.URL), pool)
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","messages":[]}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	time.Sleep(20 * time.Millisecond)
+	assert.Contains(t, pool.Keys()[0].String(), "itpm=10000/10000 otpm=5000/5000")
+}
@@ -0,0 +1,398 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// Hedge outcomes, reported in logs, metrics and the request span.
+const (
+	hedgeOutcomePrimary = "primary"
+	hedgeOutcomeHedge   = "hedge"
+	hedgeOutcomeFailed  = "failed"
+)
+
+// errHedgeLost stops the reverse proxy from copying a response that lost the race.
+var errHedgeLost = errors.New("hedged attempt lost to a faster provider")
+
+// hedgePlan is how a request is hedged: where it goes first, where it goes if
+// the primary is slow, and after how long.
+type hedgePlan struct {
+	triggers []router.FailoverTrigger
+	primary  router.ProviderInfo
+	hedge    router.ProviderInfo
+	delay    time.Duration
+}
+
+// planHedge decides whether the request is hedged. Requests are hedged when
+// routing.hedging applies to the model and another provider could serve them.
+// Thinking requests and pinned sessions are not hedged, since they must stay
+// on one provider.
+func (h *Handler) planHedge(request *http.Request, prep requestPrep, attempts providerAttempts) (hedgePlan, bool) {
+	routingConfig := h.getRoutingConfig()
+	if routingConfig == nil || !routingConfig.Hedging.AppliesTo(prep.model) || !hedgeable(request, prep) {
+		return hedgePlan{}, false
+	}
+	hedge, ok := h.selectHedge(request.Context(), prep.model, attempts)
+	if !ok {
+		return hedgePlan{}, false
+	}
+	primary := attempts.providers[0]
+	delay, measured := h.hedgeDelays.Delay(
+		primary.Provider.Name(), prep.model, routingConfig.Hedging.GetPercentile())
+	if !measured {
+		delay = routingConfig.Hedging.GetDelay()
+	}
+	triggers := attempts.triggers
+	if len(triggers) == 0 {
+		triggers = router.DefaultTriggers()
+	}
+	return hedgePlan{triggers: triggers, primary: primary, hedge: hedge, delay: delay}, true
+}
+
+// hedgeable reports whether the request may be sent to two providers.
+func hedgeable(request *http.Request, prep requestPrep) bool {
+	if prep.hasThinking || request.Body == nil || isCountTokensRequest(request) {
+		return false
+	}
+	sess, ok := sessionFromContext(request.Context())
+	return !ok || !sess.pinned
+}
+
+// selectHedge returns the provider the request is hedged to: the next
+// provider of a failover order, or the one the router picks among the other
+// candidates.
+func (h *Handler) selectHedge(
+	ctx context.Context, model string, attempts providerAttempts,
+) (router.ProviderInfo, bool) {
+	if len(attempts.providers) > 1 {
+		return attempts.providers[1], true
+	}
+	rtr := h.routerFor(ctx)
+	candidates, ok := h.routingCandidates(ctx, model, false)
+	if rtr == nil || !ok {
+		return router.ProviderInfo{}, false
+	}
+
+	ctx = router.WithModel(ctx, model)
+	if routingConfig := h.getRoutingConfig(); routingConfig != nil {
+		ctx = router.WithQualityFloor(ctx, routingConfig.QualityFloorFor(model))
+	}
+	hedge, err := router.SelectHedge(ctx, rtr, candidates, attempts.providers[0])
+	return hedge, err == nil
+}
+
+// serveHedged sends the request to the primary provider and, if it has not
+// started responding within the plan's delay, to the hedge provider as well.
+// The first response to produce body bytes is sent to the client and the
+// other attempt is cancelled. If the primary fails before the delay, the
+// hedge is sent at once. Returns the name of the provider whose response was
+// sent to the client.
+func (h *Handler) serveHedged(
+	writer http.ResponseWriter, request *http.Request, plan hedgePlan, start time.Time,
+) string {
+	body, err := io.ReadAll(request.Body)
+	closeBody(request.Body)
+	if err != nil {
+		WriteError(writer, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return ""
+	}
+
+	race := newHedgeRace(writer, plan.triggers)
+	primary := h.startHedgeAttempt(race, request, body, plan.primary, hedgeOutcomePrimary)
+	timer := time.NewTimer(plan.delay)
+	defer timer.Stop()
+
+	select {
+	case <-race.won:
+	case <-primary.done:
+	case <-timer.C:
+	}
+	if race.winner() == primary.writer {
+		// The primary started in time, so no hedge was needed.
+		return h.finishHedgeAttempt(primary, nil, start)
+	}
+
+	logHedge(request.Context(), plan)
+	hedge := h.startHedgeAttempt(race, request, body, plan.hedge, hedgeOutcomeHedge)
+	waitHedgeRace(race, primary, hedge)
+
+	winner, loser, outcome := primary, hedge, hedgeOutcomePrimary
+	switch race.winner() {
+	case hedge.writer:
+		winner, loser, outcome = hedge, primary, hedgeOutcomeHedge
+	case nil:
+		// Both failed: report the hedge's response, as failover reports the last attempt.
+		winner, loser, outcome = hedge, primary, hedgeOutcomeFailed
+		<-primary.done
+		<-hedge.done
+		race.commitFailure(hedge.writer)
+	}
+
+	h.metrics.IncHedge(plan.primary.Provider.Name(), outcome)
+	trace.SpanFromContext(request.Context()).SetAttributes(attrHedgeOutcome.String(outcome))
+	zerolog.Ctx(request.Context()).Info().
+		Str("provider", plan.primary.Provider.Name()).
+		Str("hedge_provider", plan.hedge.Provider.Name()).
+		Dur("hedge_delay", plan.delay).
+		Str("outcome", outcome).
+		Msg("hedged request completed")
+	return h.finishHedgeAttempt(winner, loser, start)
+}
+
+// logHedge records that the request is being hedged.
+func logHedge(ctx context.Context, plan hedgePlan) {
+	trace.SpanFromContext(ctx).AddEvent("hedge", trace.WithAttributes(
+		attrProvider.String(plan.hedge.Provider.Name()),
+		attrHedgeDelayMS.Int64(plan.delay.Milliseconds()),
+	))
+	zerolog.Ctx(ctx).Debug().
+		Str("provider", plan.primary.Provider.Name()).
+		Str("hedge_provider", plan.hedge.Provider.Name()).
+		Dur("hedge_delay", plan.delay).
+		Msg("hedging request to second provider")
+}
+
+// waitHedgeRace waits until an attempt wins the race or both have finished.
+func waitHedgeRace(race *hedgeRace, primary, hedge *hedgeAttempt) {
+	primaryDone, hedgeDone := primary.done, hedge.done
+	for race.winner() == nil && (primaryDone != nil || hedgeDone != nil) {
+		select {
+		case <-race.won:
+		case <-primaryDone:
+			primaryDone = nil
+		case <-hedgeDone:
+			hedgeDone = nil
+		}
+	}
+}
+
+// finishHedgeAttempt cancels the loser, waits for both attempts to finish and
+// logs the winner's metrics. A panic in the winner, such as the reverse proxy
+// aborting on a client disconnect, is re-raised on the handler goroutine.
+func (h *Handler) finishHedgeAttempt(winner, loser *hedgeAttempt, start time.Time) string {
+	if loser != nil {
+		loser.cancel()
+		<-loser.done
+	}
+	<-winner.done
+	if winner.panicked != nil {
+		panic(winner.panicked)
+	}
+	if winner.proxyCtx.request != nil {
+		if winner.writer.status == http.StatusOK {
+			h.pinSession(winner.proxyCtx.request.Context())
+		}
+		h.logMetricsIfEnabled(winner.proxyCtx.request, &winner.proxyCtx.logger, start, winner.backendTime,
+			winner.proxyCtx.getTLSMetrics)
+	}
+	return winner.info.Provider.Name()
+}
+
+// hedgeAttempt is one of the concurrent attempts of a hedged request.
+type hedgeAttempt struct {
+	panicked    any
+	writer      *hedgeWriter
+	done        chan struct{}
+	cancel      context.CancelFunc
+	proxyCtx    proxyContext
+	info        router.ProviderInfo
+	backendTime time.Duration
+}
+
+// startHedgeAttempt proxies the request to selected in its own goroutine,
+// with a context that is cancelled if the attempt loses the race.
+func (h *Handler) startHedgeAttempt(
+	race *hedgeRace, request *http.Request, body []byte, selected router.ProviderInfo, role string,
+) *hedgeAttempt {
+	ctx, cancel := context.WithCancel(context.WithValue(request.Context(), hedgeRoleContextKey, role))
+	attemptReq := request.Clone(ctx)
+	attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+	attemptReq.ContentLength = int64(len(body))
+
+	attempt := &hedgeAttempt{
+		panicked:    nil,
+		writer:      race.newWriter(),
+		done:        make(chan struct{}),
+		cancel:      cancel,
+		proxyCtx:    proxyContext{request: nil, proxy: nil, logger: zerolog.Logger{}, getTLSMetrics: nil},
+		info:        selected,
+		backendTime: 0,
+	}
+	if h.isRoutingDebugEnabled() {
+		attempt.writer.Header().Set(HeaderRelayHedge, role)
+	}
+
+	go func() {
+		defer close(attempt.done)
+		defer cancel()
+		defer func() {
+			// The reverse proxy panics with http.ErrAbortHandler when a
+			// cancelled attempt's body copy fails.
+			attempt.panicked = recover()
+		}()
+
+		if release := h.acquireProvider(ctx, selected); release != nil {
+			defer release()
+		}
+		proxyCtx, ok := h.prepareProxyRequest(attempt.writer, attemptReq, selected.Provider)
+		if ok {
+			attempt.proxyCtx = proxyCtx
+			attempt.backendTime = forward(attempt.writer, proxyCtx, selected.Provider)
+		}
+		attempt.writer.finish()
+	}()
+	return attempt
+}
+
+// hedgeRace decides which attempt of a hedged request is sent to the client.
+// The first attempt to write body bytes, or to finish, without matching a
+// failover trigger wins; its headers are committed and its body streams
+// through. Attempts that lose, or fail, are held back.
+type hedgeRace struct {
+	dst      http.ResponseWriter
+	first    *hedgeWriter
+	won      chan struct{}
+	triggers []router.FailoverTrigger
+	mu       sync.Mutex
+}
+
+func newHedgeRace(dst http.ResponseWriter, triggers []router.FailoverTrigger) *hedgeRace {
+	return &hedgeRace{
+		dst:      dst,
+		first:    nil,
+		won:      make(chan struct{}),
+		triggers: triggers,
+		mu:       sync.Mutex{},
+	}
+}
+
+// winner returns the attempt that won the race, or nil if none has yet.
+func (r *hedgeRace) winner() *hedgeWriter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.first
+}
+
+// claim makes w the winner if no attempt has won yet, committing its headers.
+// Reports whether w is the winner.
+func (r *hedgeRace) claim(w *hedgeWriter) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.first == nil {
+		r.first = w
+		r.commit(w)
+		close(r.won)
+	}
+	return r.first == w
+}
+
+// commitFailure sends a failed attempt's held back response to the client.
+// Only called once both attempts have finished without a winner.
+func (r *hedgeRace) commitFailure(w *hedgeWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commit(w)
+	//nolint:errcheck // the client may have gone away; nothing else can be done
+	r.dst.Write(w.held.Bytes())
+}
+
+func (r *hedgeRace) commit(w *hedgeWriter) {
+	dstHeader := r.dst.Header()
+	for key := range dstHeader {
+		if _, ok := w.header[key]; !ok {
+			dstHeader.Del(key)
+		}
+	}
+	for key, values := range w.header {
+		dstHeader[key] = values
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	r.dst.WriteHeader(status)
+}
+
+func (r *hedgeRace) newWriter() *hedgeWriter {
+	return &hedgeWriter{
+		race:        r,
+		header:      r.dst.Header().Clone(),
+		upstreamErr: nil,
+		held:        bytes.Buffer{},
+		status:      0,
+		failed:      false,
+	}
+}
+
+// hedgeWriter is the response writer of one hedged attempt. A response that
+// matches a failover trigger is held back in case the other attempt succeeds.
+type hedgeWriter struct {
+	race        *hedgeRace
+	header      http.Header
+	upstreamErr error
+	held        bytes.Buffer
+	status      int
+	failed      bool
+}
+
+// Header returns the attempt-local header map.
+func (w *hedgeWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader records the status and whether the attempt failed.
+func (w *hedgeWriter) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	w.failed = router.FindMatchingTrigger(w.race.triggers, w.upstreamErr, statusCode) != nil
+}
+
+// Write streams body bytes to the client if this attempt wins the race.
+func (w *hedgeWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed {
+		return w.held.Write(data)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if !w.race.claim(w) {
+		return 0, errHedgeLost
+	}
+	return w.race.dst.Write(data)
+}
+
+// Flush flushes the winner's response so SSE events reach the client immediately.
+func (w *hedgeWriter) Flush() {
+	if w.race.winner() != w {
+		return
+	}
+	//nolint:errcheck // Flush is best-effort; unsupported writers simply buffer
+	http.NewResponseController(w.race.dst).Flush()
+}
+
+func (w *hedgeWriter) recordUpstreamError(err error) {
+	w.upstreamErr = err
+}
+
+// finish claims the race for an attempt that succeeded without writing a body.
+func (w *hedgeWriter) finish() {
+	if w.status != 0 && !w.failed {
+		w.race.claim(w)
+	}
+}
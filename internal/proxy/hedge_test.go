@@ -0,0 +1,154 @@
+package proxy_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const hedgeBody = `{"model":"claude-haiku-4-5","messages":[{"role":"user","content":"title this"}]}`
+
+// hedgeBackend answers after delay with status. It counts the requests it
+// received and those cancelled before it answered.
+type hedgeBackend struct {
+	*httptest.Server
+	requests  atomic.Int32
+	cancelled atomic.Int32
+}
+
+func newHedgeBackend(t *testing.T, delay time.Duration, status int) *hedgeBackend {
+	t.Helper()
+	backend := &hedgeBackend{Server: nil, requests: atomic.Int32{}, cancelled: atomic.Int32{}}
+	backend.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		backend.requests.Add(1)
+		// Reading the body lets the server notice the client going away.
+		if _, err := io.Copy(io.Discard, request.Body); err != nil {
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-request.Context().Done():
+			backend.cancelled.Add(1)
+			return
+		}
+		writer.Header().Set(proxy.ContentTypeHeader, proxy.JSONContentType)
+		writer.WriteHeader(status)
+		if _, err := writer.Write([]byte(`{"id":"msg_1"}`)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func newHedgeHandler(t *testing.T, rtr router.ProviderRouter, delayMS int, primary, hedge *hedgeBackend) http.Handler {
+	t.Helper()
+	handler := newFailoverHandler(t, rtr,
+		proxy.NewNamedProvider(providerAName, primary.URL),
+		proxy.NewNamedProvider(providerBName, hedge.URL),
+	)
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Debug = true
+	cfg.Routing.Hedging = config.HedgingConfig{
+		Models: []string{"claude-haiku"}, Percentile: 0, DelayMS: delayMS, Enabled: true,
+	}
+	handler.SetRuntimeConfigGetter(config.NewRuntime(cfg))
+	return handler
+}
+
+func TestHandlerHedgesSlowPrimary(t *testing.T) {
+	t.Parallel()
+
+	slow := newHedgeBackend(t, 5*time.Second, http.StatusOK)
+	fast := newHedgeBackend(t, 0, http.StatusOK)
+	handler := newHedgeHandler(t, router.NewFailoverRouter(0), 20, slow, fast)
+
+	start := time.Now()
+	rr := serveJSONMessagesBody(t, handler, hedgeBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Less(t, time.Since(start), 2*time.Second, "the hedge should answer before the slow primary")
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "hedge", rr.Header().Get(proxy.HeaderRelayHedge))
+	assert.JSONEq(t, `{"id":"msg_1"}`, rr.Body.String())
+	assert.Eventually(t, func() bool { return slow.cancelled.Load() == 1 }, time.Second, 10*time.Millisecond,
+		"the losing attempt should be cancelled")
+}
+
+func TestHandlerHedgeNotSentForFastPrimary(t *testing.T) {
+	t.Parallel()
+
+	primary := newHedgeBackend(t, 0, http.StatusOK)
+	hedge := newHedgeBackend(t, 0, http.StatusOK)
+	handler := newHedgeHandler(t, router.NewFailoverRouter(0), 2000, primary, hedge)
+
+	rr := serveJSONMessagesBody(t, handler, hedgeBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "primary", rr.Header().Get(proxy.HeaderRelayHedge))
+	assert.Equal(t, int32(0), hedge.requests.Load())
+}
+
+func TestHandlerHedgeSentAtOnceWhenPrimaryFails(t *testing.T) {
+	t.Parallel()
+
+	failing := newHedgeBackend(t, 0, http.StatusServiceUnavailable)
+	healthy := newHedgeBackend(t, 0, http.StatusOK)
+	handler := newHedgeHandler(t, router.NewFailoverRouter(0), 5000, failing, healthy)
+
+	start := time.Now()
+	rr := serveJSONMessagesBody(t, handler, hedgeBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Less(t, time.Since(start), 2*time.Second, "a failed primary should not wait for the hedge delay")
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+}
+
+func TestHandlerHedgeBothFail(t *testing.T) {
+	t.Parallel()
+
+	primary := newHedgeBackend(t, 0, http.StatusServiceUnavailable)
+	hedge := newHedgeBackend(t, 0, http.StatusBadGateway)
+	handler := newHedgeHandler(t, router.NewFailoverRouter(0), 20, primary, hedge)
+
+	rr := serveJSONMessagesBody(t, handler, hedgeBody)
+	assert.Equal(t, http.StatusBadGateway, rr.Code, "the hedge's response is reported")
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+}
+
+func TestHandlerHedgeOnlyConfiguredModels(t *testing.T) {
+	t.Parallel()
+
+	slow := newHedgeBackend(t, 100*time.Millisecond, http.StatusOK)
+	fast := newHedgeBackend(t, 0, http.StatusOK)
+	handler := newHedgeHandler(t, router.NewFailoverRouter(0), 20, slow, fast)
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-opus-4","messages":[{"role":"user","content":"hi"}]}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Empty(t, rr.Header().Get(proxy.HeaderRelayHedge))
+	assert.Equal(t, int32(0), fast.requests.Load())
+}
+
+func TestHandlerHedgeWithRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	slow := newHedgeBackend(t, 5*time.Second, http.StatusOK)
+	fast := newHedgeBackend(t, 0, http.StatusOK)
+	handler := newHedgeHandler(t, router.NewRoundRobinRouter(), 20, slow, fast)
+
+	// Whichever provider round robin picks first, the fast one answers.
+	for range 2 {
+		rr := serveJSONMessagesBody(t, handler, hedgeBody)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	}
+}
@@ -225,6 +225,40 @@ func TestCloseBody_Error(t *testing.T) {
 	// Should not panic, error is discarded
 }
 
+// Test readBody
+
+func TestReadBody_RestoresBody(t *testing.T) {
+	t.Parallel()
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"m"}`))
+	body, err := readBody(request)
+	if err != nil {
+		t.Fatalf("readBody() error: %v", err)
+	}
+	if string(body) != `{"model":"m"}` {
+		t.Errorf("readBody() = %q", body)
+	}
+
+	again, err := io.ReadAll(request.Body)
+	if err != nil || string(again) != string(body) {
+		t.Errorf("body after readBody() = %q, %v, want it restored", again, err)
+	}
+	if request.ContentLength != int64(len(body)) {
+		t.Errorf("ContentLength = %d, want %d", request.ContentLength, len(body))
+	}
+}
+
+func TestReadBody_NilBody(t *testing.T) {
+	t.Parallel()
+
+	request := httptest.NewRequest(http.MethodGet, "/v1/models", http.NoBody)
+	request.Body = nil
+	body, err := readBody(request)
+	if body != nil || err != nil || request.Body != nil {
+		t.Errorf("readBody() = %q, %v, want nil and the body left unset", body, err)
+	}
+}
+
 // Test addDurationFields, addDurationFieldsCtx
 
 func TestAddDurationFields_Positive(t *testing.T) {
@@ -793,10 +827,6 @@ func TestLogMetricsIfEnabled_WithTLSMetrics(t *testing.T) {
 	}
 }
 
-// Test selectProviderWithTracking
-
-// selectProviderWithTracking is tested indirectly through handler integration tests.
-
 // Test surgicalUpdate (thinking.go)
 
 func TestSurgicalUpdate(t *testing.T) {
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/samber/lo"
+)
+
+// jsonBodyTap passes a JSON response body through unchanged while keeping a
+// single copy of it, shared by every consumer registered with onJSONBody.
+// Consumers are called once, in the order they were registered, when the
+// body ends. The copy is dropped as soon as it outgrows every consumer.
+type jsonBodyTap struct {
+	original  io.ReadCloser
+	consumers []jsonBodyConsumer
+	body      []byte
+	done      bool
+}
+
+// jsonBodyConsumer is a callback registered with onJSONBody.
+type jsonBodyConsumer struct {
+	onDone   func(body []byte, complete bool)
+	maxBytes int
+}
+
+// onJSONBody calls onDone with the JSON body of resp once it has been read,
+// unless it is larger than maxBytes. complete is false if the body was closed
+// before it was read to the end. Consumers must not keep the body after
+// onDone returns.
+func onJSONBody(resp *http.Response, maxBytes int, onDone func(body []byte, complete bool)) {
+	tap, tapped := resp.Body.(*jsonBodyTap)
+	if !tapped {
+		tap = &jsonBodyTap{original: resp.Body, consumers: nil, body: nil, done: false}
+		resp.Body = tap
+	}
+	tap.consumers = append(tap.consumers, jsonBodyConsumer{onDone: onDone, maxBytes: maxBytes})
+}
+
+// Read implements io.Reader, copying each chunk and reporting the body at EOF.
+func (t *jsonBodyTap) Read(p []byte) (int, error) {
+	n, err := t.original.Read(p)
+	if n > 0 && !t.done {
+		t.keep(p[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		t.finish(true)
+	}
+	return n, err
+}
+
+// Close implements io.Closer, reporting a body that was not read to the end.
+func (t *jsonBodyTap) Close() error {
+	t.finish(false)
+	return t.original.Close()
+}
+
+// keep appends chunk to the copy, dropping the consumers it has outgrown.
+func (t *jsonBodyTap) keep(chunk []byte) {
+	size := len(t.body) + len(chunk)
+	t.consumers = lo.Filter(t.consumers, func(consumer jsonBodyConsumer, _ int) bool {
+		return size <= consumer.maxBytes
+	})
+	if len(t.consumers) == 0 {
+		t.body = nil
+		return
+	}
+	t.body = append(t.body, chunk...)
+}
+
+// finish reports the body to the remaining consumers exactly once.
+func (t *jsonBodyTap) finish(complete bool) {
+	if t.done {
+		return
+	}
+	t.done = true
+	for _, consumer := range t.consumers {
+		consumer.onDone(t.body, complete)
+	}
+	t.consumers = nil
+	t.body = nil
+}
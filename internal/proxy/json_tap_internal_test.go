@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonBodyResult is what a consumer registered with onJSONBody was given.
+type jsonBodyResult struct {
+	body     string
+	calls    int
+	complete bool
+}
+
+func (r *jsonBodyResult) consume(body []byte, complete bool) {
+	r.body = string(body)
+	r.complete = complete
+	r.calls++
+}
+
+func TestOnJSONBodySharesOneTap(t *testing.T) {
+	t.Parallel()
+
+	body := `{"type":"message","usage":{"input_tokens":1}}`
+	resp := &http.Response{Body: io.NopCloser(&chunkedReader{data: body, size: 7})}
+	var first, second jsonBodyResult
+	onJSONBody(resp, 1024, first.consume)
+	tap := resp.Body
+	onJSONBody(resp, 1024, second.consume)
+	assert.Same(t, tap, resp.Body, "consumers must share the tap")
+
+	out, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, body, string(out), "body must pass through unchanged")
+	for _, result := range []jsonBodyResult{first, second} {
+		assert.Equal(t, 1, result.calls, "each consumer must be called once")
+		assert.Equal(t, body, result.body)
+		assert.True(t, result.complete)
+	}
+}
+
+func TestOnJSONBodyReportsEarlyClose(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(`{"type":"message"}`))}
+	var result jsonBodyResult
+	onJSONBody(resp, 1024, result.consume)
+
+	buf := make([]byte, 5)
+	_, err := io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, 1, result.calls)
+	assert.Equal(t, `{"typ`, result.body)
+	assert.False(t, result.complete)
+}
+
+func TestOnJSONBodySkipsOversizedConsumers(t *testing.T) {
+	t.Parallel()
+
+	body := `{"type":"message","content":[]}`
+	resp := &http.Response{Body: io.NopCloser(&chunkedReader{data: body, size: 4})}
+	var small, large jsonBodyResult
+	onJSONBody(resp, 10, small.consume)
+	onJSONBody(resp, len(body), large.consume)
+
+	_, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Zero(t, small.calls, "bodies larger than maxBytes must not be reported")
+	assert.Equal(t, 1, large.calls)
+	assert.Equal(t, body, large.body)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// latencyTapBody records when the first and the last bytes of an upstream
+// response body were read.
+type latencyTapBody struct {
+	original  io.ReadCloser
+	firstByte time.Time
+	lastByte  time.Time
+}
+
+// Read implements io.Reader, timing each chunk that carries data.
+func (t *latencyTapBody) Read(p []byte) (int, error) {
+	n, err := t.original.Read(p)
+	if n > 0 {
+		t.lastByte = time.Now()
+		if t.firstByte.IsZero() {
+			t.firstByte = t.lastByte
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (t *latencyTapBody) Close() error {
+	return t.original.Close()
+}
+
+// sample builds the latency sample of a response sent at start. Streams are
+// timed from their first event; non-streaming responses arrive all at once,
+// so their throughput is measured over the whole request instead.
+func (t *latencyTapBody) sample(start time.Time, stream bool, outputTokens int64) router.LatencySample {
+	if !stream {
+		return router.LatencySample{
+			TimeToFirstToken: 0,
+			Generation:       t.lastByte.Sub(start),
+			OutputTokens:     outputTokens,
+		}
+	}
+	return router.LatencySample{
+		TimeToFirstToken: t.firstByte.Sub(start),
+		Generation:       t.lastByte.Sub(t.firstByte),
+		OutputTokens:     outputTokens,
+	}
+}
+
+// tapLatency times a successful body for routers that learn from latency and
+// for hedging delays. It returns a charger reporting the sample once the
+// response's usage is known, or nil if nothing observes latency.
+func (h *Handler) tapLatency(resp *http.Response) usageCharger {
+	ctx := resp.Request.Context()
+	observer, observes := router.AsLatencyObserver(h.routerFor(ctx))
+	routingConfig := h.getRoutingConfig()
+	hedging := routingConfig != nil && routingConfig.Hedging.Enabled
+	if (!observes && !hedging) || resp.Body == nil {
+		return nil
+	}
+	start, hasStart := ctx.Value(upstreamStartContextKey).(time.Time)
+	providerName, hasProvider := ctx.Value(providerNameContextKey).(string)
+	if !hasStart || !hasProvider || providerName == "" {
+		return nil
+	}
+	model := requestModel(ctx)
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	stream := err == nil && mediaType == providers.ContentTypeSSE
+
+	timer := &latencyTapBody{original: resp.Body, firstByte: time.Time{}, lastByte: time.Time{}}
+	resp.Body = timer
+	return func(_ context.Context, usage tokenUsage) {
+		if observes {
+			observer.ObserveLatency(providerName, model, timer.sample(start, stream, usage.outputTokens))
+		}
+		if hedging && !timer.firstByte.IsZero() {
+			h.hedgeDelays.Observe(providerName, model, timer.firstByte.Sub(start))
+		}
+	}
+}
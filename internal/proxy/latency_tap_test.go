@@ -0,0 +1,121 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// latencyObservation is one sample reported to recordingLatencyRouter.
+type latencyObservation struct {
+	provider string
+	model    string
+	sample   router.LatencySample
+}
+
+// recordingLatencyRouter routes like its embedded router and records the
+// latency samples the handler reports.
+type recordingLatencyRouter struct {
+	router.ProviderRouter
+	observations []latencyObservation
+	mu           sync.Mutex
+}
+
+func newRecordingLatencyRouter() *recordingLatencyRouter {
+	return &recordingLatencyRouter{ProviderRouter: router.NewFailoverRouter(0), observations: nil, mu: sync.Mutex{}}
+}
+
+func (r *recordingLatencyRouter) ObserveLatency(provider, model string, sample router.LatencySample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, latencyObservation{provider: provider, model: model, sample: sample})
+}
+
+// awaitObservation waits for the single sample reported after a response.
+func (r *recordingLatencyRouter) awaitObservation(t *testing.T) latencyObservation {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.observations) == 1
+	}, time.Second, 10*time.Millisecond, "latency should be observed")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.observations[0]
+}
+
+func TestHandlerObservesStreamLatency(t *testing.T) {
+	t.Parallel()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		writer.Header().Set(proxy.ContentTypeHeader, "text/event-stream")
+		writer.WriteHeader(http.StatusOK)
+		if _, err := writer.Write([]byte("event: message_start\n" +
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":20,"output_tokens":1}}}` + "\n\n")); err != nil {
+			return
+		}
+		http.NewResponseController(writer).Flush() //nolint:errcheck // test backend
+		time.Sleep(50 * time.Millisecond)
+		if _, err := writer.Write([]byte("event: message_delta\n" +
+			`data: {"type":"message_delta","usage":{"output_tokens":40}}` + "\n\n")); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	rtr := newRecordingLatencyRouter()
+	handler := newFailoverHandler(t, rtr, proxy.NewNamedProvider(providerAName, backend.URL))
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-sonnet-4","stream":true,"messages":[]}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	observed := rtr.awaitObservation(t)
+	assert.Equal(t, providerAName, observed.provider)
+	assert.Equal(t, "claude-sonnet-4", observed.model)
+	assert.Equal(t, int64(40), observed.sample.OutputTokens)
+	assert.GreaterOrEqual(t, observed.sample.TimeToFirstToken, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, observed.sample.Generation, 50*time.Millisecond)
+}
+
+func TestHandlerObservesJSONLatency(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1","usage":{"input_tokens":120,"output_tokens":30}}`)
+	rtr := newRecordingLatencyRouter()
+	live := router.NewLiveRouter(func() router.ProviderRouter { return rtr })
+	handler := newFailoverHandler(t, live, proxy.NewNamedProvider(providerAName, backend.URL))
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	observed := rtr.awaitObservation(t)
+	assert.Equal(t, int64(30), observed.sample.OutputTokens)
+	assert.Zero(t, observed.sample.TimeToFirstToken, "non-streaming responses have no time to first token")
+	assert.Positive(t, observed.sample.Generation)
+}
+
+func TestHandlerDoesNotObserveErrorLatency(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewStatusBackend(t, http.StatusBadRequest,
+		`{"type":"error","usage":{"input_tokens":120,"output_tokens":30}}`, nil)
+	rtr := newRecordingLatencyRouter()
+	handler := newFailoverHandler(t, rtr, proxy.NewNamedProvider(providerAName, backend.URL))
+
+	rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	time.Sleep(20 * time.Millisecond)
+	rtr.mu.Lock()
+	defer rtr.mu.Unlock()
+	assert.Empty(t, rtr.observations)
+}
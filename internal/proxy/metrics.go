@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// otherLabel is the model label of requests for models no provider lists or
+// maps, and the client label of clients that are not configured.
+const otherLabel = "other"
+
+// servedRequest identifies who served a request for the metrics labels.
+// Provider is empty when the request failed before a provider was selected.
+type servedRequest struct {
+	provider string
+	model    string
+}
+
+// observedWriter records the status a request finished with and when the
+// first body bytes of a streaming response were written.
+type observedWriter struct {
+	dst       http.ResponseWriter
+	firstByte time.Time
+	status    int
+	streaming bool
+}
+
+func newObservedWriter(dst http.ResponseWriter) *observedWriter {
+	return &observedWriter{
+		dst:       dst,
+		firstByte: time.Time{},
+		status:    0,
+		streaming: false,
+	}
+}
+
+// Header returns the underlying header map.
+func (w *observedWriter) Header() http.Header {
+	return w.dst.Header()
+}
+
+// WriteHeader records the status and whether the response is an SSE stream.
+func (w *observedWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+		mediaType, _, err := mime.ParseMediaType(w.dst.Header().Get("Content-Type"))
+		w.streaming = err == nil && mediaType == providers.ContentTypeSSE
+	}
+	w.dst.WriteHeader(statusCode)
+}
+
+// Write records the time of the first body bytes.
+func (w *observedWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.firstByte.IsZero() && len(data) > 0 {
+		w.firstByte = time.Now()
+	}
+	return w.dst.Write(data)
+}
+
+// Flush passes flushes through so SSE events are not held back.
+func (w *observedWriter) Flush() {
+	//nolint:errcheck // Flush is best-effort; unsupported writers simply buffer
+	http.NewResponseController(w.dst).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *observedWriter) Unwrap() http.ResponseWriter {
+	return w.dst
+}
+
+// observeRequest records a finished request. Time to first byte is only
+// recorded for SSE responses that wrote something.
+func (h *Handler) observeRequest(
+	writer *observedWriter, request *http.Request, served servedRequest, start time.Time,
+) {
+	status := writer.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	client := h.metricsClient(request.Context())
+	model := h.metricsModel(served.model)
+	h.metrics.ObserveRequest(served.provider, model, client, status, time.Since(start))
+	if writer.streaming && !writer.firstByte.IsZero() {
+		h.metrics.ObserveTimeToFirstByte(served.provider, model, writer.firstByte.Sub(start))
+	}
+}
+
+// metricsModel returns the model label for a request. Clients choose model
+// names freely, so only models a provider lists or maps keep their name.
+// Others are labeled with the longest routing.model_mapping prefix they
+// match, or "other", which keeps the label's cardinality bounded.
+func (h *Handler) metricsModel(model string) string {
+	if model == "" {
+		return ""
+	}
+	if h.isKnownModel(model) {
+		return model
+	}
+	label := otherLabel
+	if routingConfig := h.getRoutingConfig(); routingConfig != nil {
+		for prefix := range routingConfig.ModelMapping {
+			if strings.HasPrefix(model, prefix) && (label == otherLabel || len(prefix) > len(label)) {
+				label = prefix
+			}
+		}
+	}
+	return label
+}
+
+// metricsClient returns the client label for a request. Only clients
+// configured in server.auth.clients keep their name. Others, such as JWT
+// subjects, are labeled "other" so token issuers cannot create unbounded
+// label values.
+func (h *Handler) metricsClient(ctx context.Context) string {
+	client := auth.ClientFromContext(ctx)
+	if client == nil {
+		return ""
+	}
+	cfg := h.getRuntimeConfigGetter()
+	if cfg == nil || !lo.ContainsBy(cfg.Server.Auth.Clients, func(configured config.ClientKeyConfig) bool {
+		return configured.Name == client.Name
+	}) {
+		return otherLabel
+	}
+	return client.Name
+}
+
+// isKnownModel reports whether a configured provider lists or maps model.
+func (h *Handler) isKnownModel(model string) bool {
+	current := lo.Map(h.providers(), func(info router.ProviderInfo, _ int) providers.Provider {
+		return info.Provider
+	})
+	if h.defaultProvider != nil {
+		current = append(current, h.defaultProvider)
+	}
+	return h.knownModels.contains(current, model)
+}
+
+// knownModels caches the models that providers list or map, so labeling a
+// request does not list every provider's models. Providers are recreated on
+// config reload, which rebuilds the set.
+type knownModels struct {
+	models    map[string]struct{}
+	providers []providers.Provider
+	mu        sync.Mutex
+}
+
+// contains reports whether one of current lists or maps model.
+func (k *knownModels) contains(current []providers.Provider, model string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.models == nil || !slices.Equal(k.providers, current) {
+		k.models = modelSet(current)
+		k.providers = current
+	}
+	_, known := k.models[model]
+	return known
+}
+
+// modelSet returns the models the providers list or map.
+func modelSet(provs []providers.Provider) map[string]struct{} {
+	models := make(map[string]struct{})
+	for _, provider := range provs {
+		for model := range provider.GetModelMapping() {
+			models[model] = struct{}{}
+		}
+		for _, listed := range provider.ListModels() {
+			models[listed.ID] = struct{}{}
+		}
+	}
+	return models
+}
@@ -0,0 +1,242 @@
+package proxy_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/budget"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/metrics"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const (
+	metricsModel       = "claude-sonnet-4-20250514"
+	metricsRequestBody = `{"model":"` + metricsModel + `","messages":[{"role":"user","content":"hi"}]}`
+)
+
+// newMetricsProvider returns a provider listing the model of metricsRequestBody,
+// so it is labeled by name.
+func newMetricsProvider(name, providerURL string) providers.Provider {
+	return providers.NewAnthropicProvider(name, providerURL, []string{metricsModel}, nil)
+}
+
+func newMetrics() *metrics.Metrics {
+	return metrics.New(metrics.Options{KeyPools: nil, InFlight: nil, HealthTracker: nil, Cache: nil})
+}
+
+func scrapeMetrics(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func newHandlerWithMetrics(t *testing.T, provider providers.Provider, recorder *metrics.Metrics) *proxy.Handler {
+	t.Helper()
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = provider
+	opts.APIKey = testKey
+	opts.Metrics = recorder
+
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+	return handler
+}
+
+func TestHandlerRecordsRequestMetrics(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewStatusBackend(t, http.StatusTooManyRequests, `{"error":"slow down"}`, nil)
+	recorder := newMetrics()
+	handler := newHandlerWithMetrics(t, newMetricsProvider(providerAName, backend.URL), recorder)
+
+	rr := serveJSONMessagesBody(t, handler, metricsRequestBody)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="",model="claude-sonnet-4-20250514",provider="provider-a",status="429"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_request_duration_seconds_count{client="",model="claude-sonnet-4-20250514",provider="provider-a",`+
+			`status="429"} 1`)
+	assert.NotContains(t, out, "cc_relay_stream_time_to_first_byte_seconds_count",
+		"non-streaming responses have no time to first byte")
+}
+
+func TestHandlerRecordsClientMetrics(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	recorder := newMetrics()
+	handler := newHandlerWithMetrics(t, newMetricsProvider(providerAName, backend.URL), recorder)
+	handler.SetRuntimeConfigGetter(config.NewRuntime(budgetConfig(budget.Limits{
+		TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0,
+	})))
+
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("alice")).Code)
+	require.Equal(t, http.StatusOK, serveAsClient(t, handler, budgetClient("jwt-subject-1")).Code)
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="alice",model="claude-sonnet-4-20250514",provider="provider-a",status="200"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="other",model="claude-sonnet-4-20250514",provider="provider-a",status="200"} 1`,
+		"clients that are not configured, such as JWT subjects, share one label")
+	assert.NotContains(t, out, "jwt-subject-1")
+}
+
+func TestHandlerRecordsStreamTimeToFirstByte(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewStatusBackend(t, http.StatusOK, "event: ping\ndata: {\"type\":\"ping\"}\n\n",
+		map[string]string{"Content-Type": providers.ContentTypeSSE})
+	recorder := newMetrics()
+	handler := newHandlerWithMetrics(t, newMetricsProvider(providerAName, backend.URL), recorder)
+
+	rr := serveJSONMessagesBody(t, handler, metricsRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "event: ping")
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	assert.Contains(t, out,
+		`cc_relay_stream_time_to_first_byte_seconds_count{model="claude-sonnet-4-20250514",provider="provider-a"} 1`)
+}
+
+func TestHandlerRecordsFailoverMetrics(t *testing.T) {
+	t.Parallel()
+
+	failing := proxy.NewStatusBackend(t, http.StatusServiceUnavailable, `{"error":"overloaded"}`, nil)
+	healthy := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	providerA := newMetricsProvider(providerAName, failing.URL)
+	providerB := newMetricsProvider(providerBName, healthy.URL)
+
+	infoA := proxy.TestProviderInfo(providerA)
+	infoA.Priority = 2
+	infoB := proxy.TestProviderInfo(providerB)
+	infoB.Priority = 1
+
+	recorder := newMetrics()
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = providerA
+	opts.ProviderInfos = []router.ProviderInfo{infoA, infoB}
+	opts.ProviderRouter = router.NewFailoverRouter(0)
+	opts.ProviderKeys = map[string]string{providerAName: testKey, providerBName: testKey}
+	opts.Metrics = recorder
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	rr := serveJSONMessagesBody(t, handler, metricsRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	assert.Contains(t, out, `cc_relay_failover_attempts_total{provider="provider-a",trigger="status_code"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="",model="claude-sonnet-4-20250514",provider="provider-b",status="200"} 1`)
+	assert.NotContains(t, out, `cc_relay_requests_total{client="",model="claude-sonnet-4-20250514",provider="provider-a"`)
+}
+
+func TestHandlerBoundsMetricsModelLabel(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	recorder := newMetrics()
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = providers.NewAnthropicProvider(providerAName, backend.URL, []string{metricsModel},
+		map[string]string{"claude-opus-4-5": "claude-opus-4-5-20251101"})
+	opts.APIKey = testKey
+	opts.Metrics = recorder
+	routing := proxy.TestRoutingConfig()
+	routing.ModelMapping = map[string]string{"claude-haiku": providerAName, "claude-haiku-4": providerAName}
+	opts.RoutingConfig = &routing
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	for _, model := range []string{metricsModel, "claude-opus-4-5", "claude-haiku-4-5-20251001", "made-up-model-1"} {
+		body := `{"model":"` + model + `","messages":[{"role":"user","content":"hi"}]}`
+		require.Equal(t, http.StatusOK, serveJSONMessagesBody(t, handler, body).Code)
+	}
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	for _, label := range []string{metricsModel, "claude-opus-4-5", "claude-haiku-4", "other"} {
+		assert.Contains(t, out,
+			`cc_relay_requests_total{client="",model="`+label+`",provider="provider-a",status="200"} 1`)
+	}
+	assert.NotContains(t, out, "made-up-model-1")
+	assert.NotContains(t, out, "claude-haiku-4-5-20251001")
+}
+
+func TestHandlerRebuildsMetricsModelsOnReload(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	infos := []router.ProviderInfo{proxy.TestProviderInfo(newMetricsProvider(providerAName, backend.URL))}
+	var live atomic.Pointer[[]router.ProviderInfo]
+	live.Store(&infos)
+
+	recorder := newMetrics()
+	opts := proxy.TestHandlerOptions(nil)
+	opts.ProviderInfosFunc = func() []router.ProviderInfo { return *live.Load() }
+	opts.ProviderRouter = router.NewFailoverRouter(0)
+	opts.ProviderKeys = map[string]string{providerAName: testKey}
+	opts.Metrics = recorder
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	body := `{"model":"claude-opus-4-5","messages":[{"role":"user","content":"hi"}]}`
+	require.Equal(t, http.StatusOK, serveJSONMessagesBody(t, handler, body).Code)
+
+	reloaded := []router.ProviderInfo{proxy.TestProviderInfo(
+		providers.NewAnthropicProvider(providerAName, backend.URL, []string{"claude-opus-4-5"}, nil))}
+	live.Store(&reloaded)
+	require.Equal(t, http.StatusOK, serveJSONMessagesBody(t, handler, body).Code)
+
+	out := scrapeMetrics(t, recorder.Handler(), "/metrics").Body.String()
+	assert.Contains(t, out, `cc_relay_requests_total{client="",model="other",provider="provider-a",status="200"} 1`)
+	assert.Contains(t, out,
+		`cc_relay_requests_total{client="",model="claude-opus-4-5",provider="provider-a",status="200"} 1`,
+		"models listed by reloaded providers keep their name")
+}
+
+func TestSetupRoutesServesMetrics(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewBackendServer(t, `{"ok":true}`)
+	provider := proxy.NewTestProvider(backend.URL)
+
+	cfg := proxy.TestConfig(testAPIKey)
+	cfg.Metrics = config.MetricsConfig{Path: "/internal/metrics", Enabled: true}
+
+	opts := proxy.TestRoutesOptions(nil)
+	opts.ConfigProvider = config.NewRuntime(cfg)
+	opts.Provider = provider
+	opts.AllProviders = []providers.Provider{provider}
+	opts.Metrics = newMetrics()
+	handler, err := proxy.SetupRoutesWithLiveKeyPools(&opts)
+	require.NoError(t, err)
+
+	rec := scrapeMetrics(t, handler, "/internal/metrics")
+	assert.Equal(t, http.StatusOK, rec.Code, "metrics do not require proxy authentication")
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+
+	assert.Equal(t, http.StatusNotFound, scrapeMetrics(t, handler, config.DefaultMetricsPath).Code)
+}
+
+func TestSetupRoutesWithoutMetrics(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewBackendServer(t, `{"ok":true}`)
+	provider := proxy.NewTestProvider(backend.URL)
+	handler := newLiveKeyPoolsHandler(t, config.NewRuntime(proxy.TestConfig("")), provider, nil)
+
+	assert.Equal(t, http.StatusNotFound, scrapeMetrics(t, handler, config.DefaultMetricsPath).Code)
+}
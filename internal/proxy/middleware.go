@@ -15,7 +15,10 @@ import (
 	"github.com/omarluq/cc-relay/internal/auth"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/tracing"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const authSucceededMsg = "authentication succeeded"
@@ -76,6 +79,9 @@ func logRequestCompletion(
 		Str("duration", durationStr)
 
 	if timings := getRequestTimings(ctx); timings != nil {
+		if timings.Client != "" {
+			logCtx = logCtx.Str("client", timings.Client)
+		}
 		addDurationFieldsCtx(&logCtx, "auth_time", timings.Auth)
 		addDurationFieldsCtx(&logCtx, "route_time", timings.Routing)
 	}
@@ -175,6 +181,19 @@ func (s *authCacheStore) cached(fingerprint string) *authCache {
 
 func buildAuthCache(fingerprint string, authConfig config.AuthConfig, effectiveKey string) *authCache {
 	var authenticators []auth.Authenticator
+	// A verified client certificate identifies the client before any key does.
+	if certs := clientCerts(authConfig.Clients); len(certs) > 0 {
+		authenticators = append(authenticators, auth.NewClientCertAuthenticator(certs))
+	}
+	// Virtual keys go next so a key that is also accepted as a passthrough
+	// bearer token still resolves to its client.
+	if len(authConfig.Clients) > 0 {
+		authenticators = append(authenticators, auth.NewVirtualKeyAuthenticator(virtualKeys(authConfig.Clients)))
+	}
+	// JWTs go before bearer passthrough, which would accept them unverified.
+	if authConfig.JWT.IsEnabled() {
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(jwtOptions(&authConfig.JWT)))
+	}
 	if authConfig.IsBearerEnabled() {
 		authenticators = append(authenticators, auth.NewBearerAuthenticator(authConfig.BearerSecret))
 	}
@@ -198,6 +217,74 @@ func buildAuthCache(fingerprint string, authConfig config.AuthConfig, effectiveK
 	}
 }
 
+// virtualKeys converts configured clients to the authenticator's key list.
+func virtualKeys(clients []config.ClientKeyConfig) []auth.VirtualKey {
+	keys := make([]auth.VirtualKey, 0, len(clients))
+	for idx := range clients {
+		clientCfg := &clients[idx]
+		keys = append(keys, auth.VirtualKey{
+			Client: &auth.Client{
+				Metadata:         clientCfg.Metadata,
+				Name:             clientCfg.Name,
+				AllowedModels:    clientCfg.AllowedModels,
+				AllowedProviders: clientCfg.AllowedProviders,
+			},
+			KeyHash: clientCfg.KeyHash,
+		})
+	}
+	return keys
+}
+
+// clientCerts converts the certificate names of configured clients to the
+// authenticator's mappings.
+func clientCerts(clients []config.ClientKeyConfig) []auth.ClientCert {
+	var certs []auth.ClientCert
+	for idx := range clients {
+		clientCfg := &clients[idx]
+		if len(clientCfg.CertNames) == 0 {
+			continue
+		}
+		certs = append(certs, auth.ClientCert{
+			Client: &auth.Client{
+				Metadata:         clientCfg.Metadata,
+				Name:             clientCfg.Name,
+				AllowedModels:    clientCfg.AllowedModels,
+				AllowedProviders: clientCfg.AllowedProviders,
+			},
+			Names: clientCfg.CertNames,
+		})
+	}
+	return certs
+}
+
+// jwtOptions converts the JWT config to the authenticator's options.
+func jwtOptions(jwtCfg *config.JWTConfig) auth.JWTOptions {
+	var keys *auth.JWKS
+	if jwtCfg.JWKSFile != "" {
+		keys = auth.NewFileJWKS(jwtCfg.JWKSFile, jwtCfg.GetJWKSRefresh())
+	} else {
+		keys = auth.NewURLJWKS(jwtCfg.JWKSURL, jwtCfg.GetJWKSRefresh())
+	}
+	groups := make([]auth.JWTGroup, 0, len(jwtCfg.Groups))
+	for idx := range jwtCfg.Groups {
+		groupCfg := &jwtCfg.Groups[idx]
+		groups = append(groups, auth.JWTGroup{
+			Name:             groupCfg.Name,
+			AllowedModels:    groupCfg.AllowedModels,
+			AllowedProviders: groupCfg.AllowedProviders,
+		})
+	}
+	return auth.JWTOptions{
+		Keys:        keys,
+		Issuer:      jwtCfg.Issuer,
+		ClientClaim: jwtCfg.GetClientClaim(),
+		GroupsClaim: jwtCfg.GetGroupsClaim(),
+		Audiences:   jwtCfg.Audiences,
+		Groups:      groups,
+		Leeway:      jwtCfg.GetLeeway(),
+	}
+}
+
 func (s *authCacheStore) getOrBuild(
 	fingerprint string,
 	authConfig config.AuthConfig,
@@ -225,7 +312,13 @@ func (s *authCacheStore) getOrBuild(
 // authFingerprint computes a small fingerprint of auth-related config fields.
 // This avoids relying on config pointer equality for cache invalidation.
 // Uses length-prefixed format to avoid delimiter collision vulnerabilities.
-func authFingerprint(bearerEnabled bool, bearerSecret, apiKey string) string {
+// Clients and JWT settings are appended in Go syntax, which quotes every string and is unambiguous as well.
+func authFingerprint(
+	bearerEnabled bool,
+	bearerSecret, apiKey string,
+	clients []config.ClientKeyConfig,
+	jwtCfg *config.JWTConfig,
+) string {
 	// Format: "b<0|1>|<len>:<bearerSecret>|<len>:<apiKey>"
 	// Length-prefix prevents collision when secrets contain delimiters.
 	bearerByte := byte('0')
@@ -242,6 +335,14 @@ func authFingerprint(bearerEnabled bool, bearerSecret, apiKey string) string {
 	buffer = strconv.AppendInt(buffer, int64(len(apiKey)), 10)
 	buffer = append(buffer, ':')
 	buffer = append(buffer, apiKey...)
+	if len(clients) > 0 {
+		buffer = append(buffer, '|')
+		buffer = fmt.Appendf(buffer, "%#v", clients)
+	}
+	if jwtCfg != nil && jwtCfg.IsEnabled() {
+		buffer = append(buffer, '|')
+		buffer = fmt.Appendf(buffer, "%#v", *jwtCfg)
+	}
 	return string(buffer)
 }
 
@@ -268,35 +369,99 @@ func LiveAuthMiddleware(cfgProvider config.RuntimeConfigGetter) func(http.Handle
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			cfg := getRuntimeConfig(cfgProvider)
-			if cfg == nil {
+			result, enforced := store.authenticate(cfgProvider, request)
+			if !enforced {
 				next.ServeHTTP(writer, request)
 				return
 			}
-
-			authConfig := cfg.Server.Auth
-			effectiveKey := cfg.Server.GetEffectiveAPIKey()
-			fpValue := authFingerprint(authConfig.IsBearerEnabled(), authConfig.BearerSecret, effectiveKey)
-
-			start := time.Now()
-			cached := store.getOrBuild(fpValue, authConfig, effectiveKey)
-			recordAuthTiming(request.Context(), start)
-
-			if cached.chain == nil {
-				next.ServeHTTP(writer, request)
-				return
-			}
-
-			result := cached.chain.Validate(request)
 			if !handleAuthResult(request.Context(), writer, result) {
 				return
 			}
+			if result.Client != nil {
+				request = withClient(request, result.Client)
+			}
 
 			next.ServeHTTP(writer, request)
 		})
 	}
 }
 
+// IdentifyClientMiddleware resolves the client of requests to public
+// endpoints without enforcing auth: requests with valid client credentials
+// carry their client in the context, all others pass through unchanged.
+func IdentifyClientMiddleware(cfgProvider config.RuntimeConfigGetter) func(http.Handler) http.Handler {
+	store := &authCacheStore{
+		cache: atomic.Value{},
+		mu:    sync.Mutex{},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if result, enforced := store.authenticate(cfgProvider, request); enforced && result.Valid &&
+				result.Client != nil {
+				request = withClient(request, result.Client)
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// authenticate validates the request against the authenticator chain for the
+// live config. Reports false if no auth is configured.
+func (s *authCacheStore) authenticate(
+	cfgProvider config.RuntimeConfigGetter, request *http.Request,
+) (auth.Result, bool) {
+	cfg := getRuntimeConfig(cfgProvider)
+	if cfg == nil {
+		return auth.Result{Client: nil, Valid: true, Type: "", Error: ""}, false
+	}
+
+	authConfig := cfg.Server.Auth
+	effectiveKey := cfg.Server.GetEffectiveAPIKey()
+	fpValue := authFingerprint(authConfig.IsBearerEnabled(), authConfig.BearerSecret, effectiveKey,
+		authConfig.Clients, &authConfig.JWT)
+
+	start := time.Now()
+	cached := s.getOrBuild(fpValue, authConfig, effectiveKey)
+	recordAuthTiming(request.Context(), start)
+
+	if cached.chain == nil {
+		return auth.Result{Client: nil, Valid: true, Type: "", Error: ""}, false
+	}
+
+	_, span := tracing.Start(request.Context(), spanAuth)
+	result := cached.chain.Validate(request)
+	span.SetAttributes(attrAuthType.String(string(result.Type)))
+	if !result.Valid {
+		span.SetStatus(codes.Error, result.Error)
+	}
+	span.End()
+	return result, true
+}
+
+// withClient stores the client resolved from a virtual key or JWT in the request
+// context and adds its name to the request logger and server span.
+// The credential is removed from the request: it is only meaningful to
+// cc-relay, so the provider is always called with the configured keys.
+func withClient(request *http.Request, client *auth.Client) *http.Request {
+	ctx := auth.WithClient(request.Context(), client)
+	logCtx := zerolog.Ctx(ctx).With().Str("client", client.Name)
+	if len(client.Metadata) > 0 {
+		logCtx = logCtx.Any("client_metadata", client.Metadata)
+	}
+	logger := logCtx.Logger()
+	ctx = logger.WithContext(ctx)
+	trace.SpanFromContext(ctx).SetAttributes(attrClient.String(client.Name))
+	if timings := getRequestTimings(ctx); timings != nil {
+		timings.Client = client.Name
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Del("Authorization")
+	request.Header.Del("x-api-key")
+	return request
+}
+
 // RequestIDMiddleware adds X-Request-ID header and logger with request ID to context.
 func RequestIDMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -3,19 +3,31 @@ package proxy_test
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
 )
 
 const (
@@ -42,13 +54,23 @@ func newMiddlewareTestConfigWithLegacyKey(apiKey string) *config.Config {
 // emptyAuthConfig returns a fully-initialized AuthConfig with all fields at zero values.
 func emptyAuthConfig() config.AuthConfig {
 	return config.AuthConfig{
+		Clients:           nil,
 		APIKey:            "",
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	}
 }
 
+// emptyJWTConfig returns a JWTConfig with JWT authentication disabled.
+func emptyJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer: "", JWKSURL: "", JWKSFile: "", ClientClaim: "", GroupsClaim: "",
+		Audiences: nil, Groups: nil, JWKSRefreshMS: 0, LeewayMS: 0,
+	}
+}
+
 func assertStatus(t *testing.T, rec *httptest.ResponseRecorder, expected int, msg string) {
 	t.Helper()
 	if rec.Code != expected {
@@ -362,11 +384,11 @@ func TestAuthFingerprint(t *testing.T) {
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
 			t.Parallel()
-			fp := proxy.AuthFingerprint(testCase.bearerEnabled, testCase.bearerSecret, testCase.apiKey)
+			fp := proxy.AuthFingerprint(testCase.bearerEnabled, testCase.bearerSecret, testCase.apiKey, nil, nil)
 			require.NotEmpty(t, fp, "fingerprint should not be empty")
 
 			// Same inputs produce same fingerprint
-			fp2 := proxy.AuthFingerprint(testCase.bearerEnabled, testCase.bearerSecret, testCase.apiKey)
+			fp2 := proxy.AuthFingerprint(testCase.bearerEnabled, testCase.bearerSecret, testCase.apiKey, nil, nil)
 			assert.Equalf(t, fp, fp2, "fingerprint not deterministic: %q != %q", fp, fp2)
 		})
 	}
@@ -374,29 +396,43 @@ func TestAuthFingerprint(t *testing.T) {
 	// Different inputs produce different fingerprints
 	t.Run("different inputs differ", func(t *testing.T) {
 		t.Parallel()
-		fp1 := proxy.AuthFingerprint(true, "secret1", "key1")
-		fp2 := proxy.AuthFingerprint(true, "secret2", "key1")
-		fp3 := proxy.AuthFingerprint(false, "secret1", "key1")
-		fp4 := proxy.AuthFingerprint(true, "secret1", "key2")
+		fp1 := proxy.AuthFingerprint(true, "secret1", "key1", nil, nil)
+		fp2 := proxy.AuthFingerprint(true, "secret2", "key1", nil, nil)
+		fp3 := proxy.AuthFingerprint(false, "secret1", "key1", nil, nil)
+		fp4 := proxy.AuthFingerprint(true, "secret1", "key2", nil, nil)
 
 		assert.NotEqual(t, fp1, fp2, "different bearer secrets should produce different fingerprints")
 		assert.NotEqual(t, fp1, fp3, "different bearer enabled should produce different fingerprints")
 		assert.NotEqual(t, fp1, fp4, "different api keys should produce different fingerprints")
 	})
 
+	t.Run("client changes differ", func(t *testing.T) {
+		t.Parallel()
+		alice := clientKeyConfig("alice", keyAlpha)
+		restricted := clientKeyConfig("alice", keyAlpha)
+		restricted.AllowedProviders = []string{"anthropic"}
+
+		fp1 := proxy.AuthFingerprint(false, "", "", []config.ClientKeyConfig{alice}, nil)
+		fp2 := proxy.AuthFingerprint(false, "", "", []config.ClientKeyConfig{restricted}, nil)
+		fp3 := proxy.AuthFingerprint(false, "", "", nil, nil)
+
+		assert.NotEqual(t, fp1, fp2, "client restrictions should be part of the fingerprint")
+		assert.NotEqual(t, fp1, fp3, "adding a client should change the fingerprint")
+	})
+
 	// Delimiter collision resistance - secrets containing delimiters must not collide
 	t.Run("delimiter collision resistance", func(t *testing.T) {
 		t.Parallel()
 		// These would collide with naive delimiter-based format
-		fp1 := proxy.AuthFingerprint(true, "secret|5:fake", "real")
-		fp2 := proxy.AuthFingerprint(true, "secret", "fake|5:real")
+		fp1 := proxy.AuthFingerprint(true, "secret|5:fake", "real", nil, nil)
+		fp2 := proxy.AuthFingerprint(true, "secret", "fake|5:real", nil, nil)
 		if fp1 == fp2 {
 			t.Error("fingerprints should not collide when secrets contain delimiters")
 		}
 
 		// Additional edge case with length-like patterns
-		fp3 := proxy.AuthFingerprint(true, "a|3:bcd", "ef")
-		fp4 := proxy.AuthFingerprint(true, "a", "bcd|2:ef")
+		fp3 := proxy.AuthFingerprint(true, "a|3:bcd", "ef", nil, nil)
+		fp4 := proxy.AuthFingerprint(true, "a", "bcd|2:ef", nil, nil)
 		if fp3 == fp4 {
 			t.Error("fingerprints should not collide with length-like patterns")
 		}
@@ -482,8 +518,10 @@ func TestLiveAuthMiddlewareAPIKeyAuth(t *testing.T) {
 	newWrappedHandler := func() http.Handler {
 		handler := okHandler()
 		cfg := newMiddlewareTestConfig(config.AuthConfig{
+			Clients:           nil,
 			APIKey:            testAPIKey,
 			BearerSecret:      "",
+			JWT:               emptyJWTConfig(),
 			AllowBearer:       false,
 			AllowSubscription: false,
 		})
@@ -536,8 +574,10 @@ func TestLiveAuthMiddlewareConfigSwitching(t *testing.T) {
 
 	// Start with API key auth
 	cfg1 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            keyV1,
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -550,8 +590,10 @@ func TestLiveAuthMiddlewareConfigSwitching(t *testing.T) {
 
 	// Switch to new API key
 	cfg2 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            keyV2,
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -571,8 +613,10 @@ func TestLiveAuthMiddlewareSwitchAuthMethods(t *testing.T) {
 
 	// Start with API key auth
 	cfg1 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            "my-api-key",
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -591,8 +635,10 @@ func TestLiveAuthMiddlewareSwitchAuthMethods(t *testing.T) {
 
 	// Switch to bearer auth
 	cfg2 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            "",
 		BearerSecret:      "my-bearer-token",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       true,
 		AllowSubscription: false,
 	})
@@ -624,8 +670,10 @@ func TestLiveAuthMiddlewareSwitchToNoAuth(t *testing.T) {
 
 	// Start with API key auth
 	cfg1 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            "required-key",
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -660,8 +708,10 @@ func TestLiveAuthMiddlewareConcurrentAccess(t *testing.T) {
 	handler := okHandler()
 
 	cfg := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            concurrentKey,
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -686,14 +736,18 @@ func TestLiveAuthMiddlewareConcurrentConfigSwitch(t *testing.T) {
 	handler := okHandler()
 
 	cfg1 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            keyAlpha,
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
 	cfg2 := newMiddlewareTestConfig(config.AuthConfig{
+		Clients:           nil,
 		APIKey:            keyBeta,
 		BearerSecret:      "",
+		JWT:               emptyJWTConfig(),
 		AllowBearer:       false,
 		AllowSubscription: false,
 	})
@@ -1039,3 +1093,188 @@ func TestMaxBodyBytesMiddlewareNilBody(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, resp.Code)
 }
+
+func virtualKeyAuthConfig(clients ...config.ClientKeyConfig) config.AuthConfig {
+	authCfg := emptyAuthConfig()
+	authCfg.Clients = clients
+	return authCfg
+}
+
+func clientKeyConfig(name, key string) config.ClientKeyConfig {
+	return config.ClientKeyConfig{
+		Metadata:         map[string]string{"team": "platform"},
+		Name:             name,
+		KeyHash:          auth.HashKey(key),
+		CertNames:        nil,
+		AllowedModels:    nil,
+		AllowedProviders: nil,
+		Budget:           budget.Limits{TokensPerDay: 0, TokensPerMonth: 0, USDPerMonth: 0},
+		RateLimit:        ratelimit.Limits{RPM: 0, TPM: 0, MaxConcurrent: 0},
+	}
+}
+
+func TestLiveAuthMiddlewareVirtualKeys(t *testing.T) {
+	t.Parallel()
+
+	var gotClient *auth.Client
+	var gotHeaders http.Header
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		gotClient = auth.ClientFromContext(request.Context())
+		gotHeaders = request.Header.Clone()
+		writer.WriteHeader(http.StatusOK)
+	})
+	runtime := config.NewRuntime(newMiddlewareTestConfig(virtualKeyAuthConfig(clientKeyConfig("alice", keyAlpha))))
+	wrappedHandler := proxy.LiveAuthMiddleware(runtime)(handler)
+
+	rec := doAPIKeyRequest(t, wrappedHandler, keyAlpha)
+	assertStatus(t, rec, http.StatusOK, expectedStatusOKMsg)
+	require.NotNil(t, gotClient)
+	assert.Equal(t, "alice", gotClient.Name)
+	assert.Equal(t, map[string]string{"team": "platform"}, gotClient.Metadata)
+	assert.Empty(t, gotHeaders.Get(proxy.APIKeyHeader), "virtual key is not forwarded")
+
+	req := proxy.NewMessagesRequest(http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+keyAlpha)
+	rec = httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(rec, req)
+	assertStatus(t, rec, http.StatusOK, "bearer virtual key should work")
+	assert.Empty(t, gotHeaders.Get("Authorization"), "virtual key is not forwarded")
+
+	assertKeyWithHandler(t, wrappedHandler, keyBeta, http.StatusUnauthorized, "unknown key should fail")
+
+	// Hot-reload: adding a client takes effect on the next request
+	runtime.Store(newMiddlewareTestConfig(virtualKeyAuthConfig(
+		clientKeyConfig("alice", keyAlpha), clientKeyConfig("bob", keyBeta))))
+	assertKeyWithHandler(t, wrappedHandler, keyBeta, http.StatusOK, "new client should work after reload")
+	assert.Equal(t, "bob", gotClient.Name)
+}
+
+func TestLiveAuthMiddlewareSharedKeyHasNoClient(t *testing.T) {
+	t.Parallel()
+
+	clientSeen := true
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		clientSeen = auth.ClientFromContext(request.Context()) != nil
+		writer.WriteHeader(http.StatusOK)
+	})
+	authCfg := virtualKeyAuthConfig(clientKeyConfig("alice", keyAlpha))
+	authCfg.APIKey = testAPIKey
+	wrappedHandler := proxy.LiveAuthMiddleware(config.NewRuntime(newMiddlewareTestConfig(authCfg)))(handler)
+
+	assertKeyWithHandler(t, wrappedHandler, testAPIKey, http.StatusOK, "shared key should still work")
+	assert.False(t, clientSeen)
+}
+
+func TestIdentifyClientMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var gotClient *auth.Client
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		gotClient = auth.ClientFromContext(request.Context())
+		writer.WriteHeader(http.StatusOK)
+	})
+	runtime := config.NewRuntime(newMiddlewareTestConfig(virtualKeyAuthConfig(clientKeyConfig("alice", keyAlpha))))
+	wrappedHandler := proxy.IdentifyClientMiddleware(runtime)(handler)
+
+	assertKeyWithHandler(t, wrappedHandler, keyAlpha, http.StatusOK, "client key should work")
+	require.NotNil(t, gotClient)
+	assert.Equal(t, "alice", gotClient.Name)
+
+	assertKeyWithHandler(t, wrappedHandler, keyBeta, http.StatusOK, "unknown key is not rejected")
+	assert.Nil(t, gotClient)
+
+	assertKeyWithHandler(t, wrappedHandler, "", http.StatusOK, "missing key is not rejected")
+	assert.Nil(t, gotClient)
+}
+
+func TestLiveAuthMiddlewareClientCert(t *testing.T) {
+	t.Parallel()
+
+	var gotClient *auth.Client
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		gotClient = auth.ClientFromContext(request.Context())
+		writer.WriteHeader(http.StatusOK)
+	})
+	certClient := clientKeyConfig("ci", "")
+	certClient.KeyHash = ""
+	certClient.CertNames = []string{"ci-runner"}
+	authCfg := virtualKeyAuthConfig(clientKeyConfig("alice", keyAlpha), certClient)
+	wrappedHandler := proxy.LiveAuthMiddleware(config.NewRuntime(newMiddlewareTestConfig(authCfg)))(handler)
+
+	serveCert := func(commonName string) *httptest.ResponseRecorder {
+		req := proxy.NewMessagesRequest(http.NoBody)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+			{Subject: pkix.Name{CommonName: commonName}},
+		}}}
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serveCert("ci-runner")
+	assertStatus(t, rec, http.StatusOK, "a mapped certificate should authenticate")
+	require.NotNil(t, gotClient)
+	assert.Equal(t, "ci", gotClient.Name)
+
+	rec = serveCert("laptop")
+	assertStatus(t, rec, http.StatusUnauthorized, "an unmapped certificate needs a key")
+
+	assertKeyWithHandler(t, wrappedHandler, keyAlpha, http.StatusOK, "virtual keys should still work")
+	assert.Equal(t, "alice", gotClient.Name)
+}
+
+// signEdDSAJWT returns a JWT for subject in groups, signed with key.
+func signEdDSAJWT(key ed25519.PrivateKey, subject string, groups ...string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	header := encode([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+	groupsJSON := `"` + strings.Join(groups, `","`) + `"`
+	payload := encode(fmt.Appendf(nil, `{"sub":%q,"exp":%d,"groups":[%s]}`,
+		subject, time.Now().Add(time.Hour).Unix(), groupsJSON))
+	return header + "." + payload + "." + encode(ed25519.Sign(key, []byte(header+"."+payload)))
+}
+
+func TestLiveAuthMiddlewareJWT(t *testing.T) {
+	t.Parallel()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"OKP","crv":"Ed25519","x":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(public))
+	require.NoError(t, os.WriteFile(jwksPath, []byte(jwks), 0o600))
+
+	var gotClient *auth.Client
+	var gotHeaders http.Header
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		gotClient = auth.ClientFromContext(request.Context())
+		gotHeaders = request.Header.Clone()
+		writer.WriteHeader(http.StatusOK)
+	})
+	authCfg := emptyAuthConfig()
+	authCfg.AllowSubscription = true
+	authCfg.JWT.JWKSFile = jwksPath
+	authCfg.JWT.Groups = []config.JWTGroupConfig{
+		{Name: "ml", AllowedModels: []string{"claude-sonnet"}, AllowedProviders: nil},
+	}
+	wrappedHandler := proxy.LiveAuthMiddleware(config.NewRuntime(newMiddlewareTestConfig(authCfg)))(handler)
+
+	serveBearer := func(token string) *httptest.ResponseRecorder {
+		req := proxy.NewMessagesRequest(http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serveBearer(signEdDSAJWT(private, "alice", "ml"))
+	assertStatus(t, rec, http.StatusOK, expectedStatusOKMsg)
+	require.NotNil(t, gotClient)
+	assert.Equal(t, "alice", gotClient.Name)
+	assert.Equal(t, []string{"claude-sonnet"}, gotClient.AllowedModels)
+	assert.Empty(t, gotHeaders.Get("Authorization"), "the JWT is not forwarded")
+
+	rec = serveBearer("sk-ant-oat01-subscription")
+	assertStatus(t, rec, http.StatusOK, "subscription tokens should still pass through")
+	assert.Nil(t, gotClient)
+	assert.Equal(t, "Bearer sk-ant-oat01-subscription", gotHeaders.Get("Authorization"))
+}
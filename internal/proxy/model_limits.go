@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/tokenizer"
+)
+
+// errContextWindowExceeded is returned when the request is larger than the
+// model limits of every provider that could serve it.
+var errContextWindowExceeded = errors.New("request exceeds the context window of every provider")
+
+// requestSize is how large a request is, compared against provider model limits.
+type requestSize struct {
+	inputTokens int
+	maxTokens   int
+}
+
+// requestSizeFromContext returns the request size estimated by estimateRequestSize.
+func requestSizeFromContext(ctx context.Context) (requestSize, bool) {
+	size, ok := ctx.Value(requestSizeContextKey).(requestSize)
+	return size, ok
+}
+
+// estimateRequestSize stores the estimated input tokens and the max_tokens of
+// the request in its context. Requests are only measured when a provider
+// declares model limits, since counting tokens walks the whole body.
+func (h *Handler) estimateRequestSize(request *http.Request) *http.Request {
+	cfg := h.getRuntimeConfigGetter()
+	if cfg == nil || request.Body == nil || isCountTokensRequest(request) || !hasModelLimits(cfg) {
+		return request
+	}
+
+	body, err := readBody(request)
+	if err != nil {
+		return request
+	}
+
+	size := requestSize{
+		inputTokens: tokenizer.CountRequest(body),
+		maxTokens:   int(gjson.GetBytes(body, "max_tokens").Int()),
+	}
+	return request.WithContext(context.WithValue(request.Context(), requestSizeContextKey, size))
+}
+
+// hasModelLimits reports whether any provider declares model limits.
+func hasModelLimits(cfg *config.Config) bool {
+	return lo.ContainsBy(cfg.Providers, func(provider config.ProviderConfig) bool {
+		return len(provider.ModelLimits) > 0
+	})
+}
+
+// fitsModelLimits reports whether the named provider's limits for model admit
+// a request of size. Providers without limits for the model always fit.
+func fitsModelLimits(cfg *config.Config, providerName, model string, size requestSize) bool {
+	providerCfg, found := lo.Find(cfg.Providers, func(candidate config.ProviderConfig) bool {
+		return candidate.Name == providerName
+	})
+	if !found {
+		return true
+	}
+	limits, limited := providerCfg.LimitsFor(model)
+	return !limited || limits.Fits(size.inputTokens, size.maxTokens)
+}
+
+// filterModelLimits returns the candidates whose model limits admit the request.
+func (h *Handler) filterModelLimits(
+	ctx context.Context, model string, candidates []router.ProviderInfo,
+) []router.ProviderInfo {
+	size, measured := requestSizeFromContext(ctx)
+	cfg := h.getRuntimeConfigGetter()
+	if !measured || cfg == nil {
+		return candidates
+	}
+
+	fitting := lo.Filter(candidates, func(info router.ProviderInfo, _ int) bool {
+		return fitsModelLimits(cfg, info.Provider.Name(), model, size)
+	})
+	if excluded := len(candidates) - len(fitting); excluded > 0 {
+		zerolog.Ctx(ctx).Debug().
+			Int("input_tokens", size.inputTokens).
+			Int("max_tokens", size.maxTokens).
+			Int("excluded", excluded).
+			Msg("excluded providers too small for request")
+	}
+	return fitting
+}
+
+// requestTooLargeError returns errContextWindowExceeded with the measured
+// size of the request.
+func requestTooLargeError(ctx context.Context, model string) error {
+	size, _ := requestSizeFromContext(ctx)
+	return fmt.Errorf("%w: about %d input tokens with max_tokens %d for model %q",
+		errContextWindowExceeded, size.inputTokens, size.maxTokens, model)
+}
@@ -0,0 +1,98 @@
+package proxy_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+// limitedProvider returns a provider config with the given limits for claude-* models.
+func limitedProvider(name string, limits config.ModelLimits) config.ProviderConfig {
+	return config.ProviderConfig{
+		ModelMapping:       nil,
+		Prices:             nil,
+		ModelLimits:        map[string]config.ModelLimits{"claude": limits},
+		AWSRegion:          "",
+		GCPProjectID:       "",
+		AzureAPIVersion:    "",
+		Name:               name,
+		Type:               "anthropic",
+		BaseURL:            "",
+		AzureDeploymentID:  "",
+		AWSAccessKeyID:     "",
+		AzureResourceName:  "",
+		AWSSecretAccessKey: "",
+		GCPRegion:          "",
+		Trust:              "",
+		Keys:               nil,
+		Models:             nil,
+		Pooling:            config.PoolingConfig{Strategy: "", Enabled: false},
+		Enabled:            true,
+	}
+}
+
+// messagesBody returns a request body with a user message of about words tokens.
+func messagesBody(words, maxTokens int) string {
+	return fmt.Sprintf(`{"model":"claude-sonnet-4","max_tokens":%d,"messages":[{"role":"user","content":%q}]}`,
+		maxTokens, strings.Repeat("word ", words))
+}
+
+func newModelLimitsHandler(t *testing.T, limitsA, limitsB config.ModelLimits) http.Handler {
+	t.Helper()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, backend.URL),
+		proxy.NewNamedProvider(providerBName, backend.URL),
+	)
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Debug = true
+	cfg.Providers = []config.ProviderConfig{
+		limitedProvider(providerAName, limitsA),
+		limitedProvider(providerBName, limitsB),
+	}
+	handler.SetRuntimeConfigGetter(config.NewRuntime(cfg))
+	return handler
+}
+
+func TestHandlerModelLimitsExcludeSmallProviders(t *testing.T) {
+	t.Parallel()
+
+	handler := newModelLimitsHandler(t,
+		config.ModelLimits{ContextWindow: 100, MaxOutputTokens: 1024},
+		config.ModelLimits{ContextWindow: 0, MaxOutputTokens: 0},
+	)
+
+	rr := serveJSONMessagesBody(t, handler, messagesBody(10, 1024))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+
+	rr = serveJSONMessagesBody(t, handler, messagesBody(500, 1024))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"), "the prompt exceeds the context window")
+
+	rr = serveJSONMessagesBody(t, handler, messagesBody(10, 4096))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"), "max_tokens exceeds the output limit")
+}
+
+func TestHandlerModelLimitsNoProviderFits(t *testing.T) {
+	t.Parallel()
+
+	small := config.ModelLimits{ContextWindow: 100, MaxOutputTokens: 0}
+	handler := newModelLimitsHandler(t, small, small)
+
+	rr := serveJSONMessagesBody(t, handler, messagesBody(500, 1024))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "invalid_request_error", gjson.Get(rr.Body.String(), "error.type").String())
+	assert.Contains(t, gjson.Get(rr.Body.String(), "error.message").String(), "context window")
+}
@@ -4,6 +4,8 @@ package proxy
 import (
 	"net/http"
 
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/providers"
 	"github.com/samber/lo"
 )
@@ -18,6 +20,7 @@ type ModelsResponse struct {
 // ModelsHandler handles requests to /v1/models endpoint.
 type ModelsHandler struct {
 	getProviders ProvidersGetter
+	cfgProvider  config.RuntimeConfigGetter
 }
 
 // ProvidersGetter returns the current provider list for live updates.
@@ -31,18 +34,39 @@ func NewModelsHandler(getProviders ProvidersGetter) *ModelsHandler {
 	}
 	return &ModelsHandler{
 		getProviders: getProviders,
+		cfgProvider:  nil,
 	}
 }
 
+// NewClientModelsHandler creates a models handler that lists only the models
+// the requesting client may use, by its model and provider restrictions and
+// the live access policies.
+func NewClientModelsHandler(getProviders ProvidersGetter, cfgProvider config.RuntimeConfigGetter) *ModelsHandler {
+	handler := NewModelsHandler(getProviders)
+	handler.cfgProvider = cfgProvider
+	return handler
+}
+
 func (h *ModelsHandler) providerList() []providers.Provider {
 	return h.getProviders()
 }
 
 // ServeHTTP handles GET /v1/models requests.
-func (h *ModelsHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+func (h *ModelsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var cfg *config.Config
+	if h.cfgProvider != nil {
+		cfg = h.cfgProvider.Get()
+	}
+	client := auth.ClientFromContext(request.Context())
+
 	// Collect all models from all providers using lo.FlatMap
 	allModels := lo.FlatMap(h.providerList(), func(provider providers.Provider, _ int) []providers.Model {
-		return provider.ListModels()
+		if h.cfgProvider == nil {
+			return provider.ListModels()
+		}
+		return lo.Filter(provider.ListModels(), func(model providers.Model, _ int) bool {
+			return allowsListedModel(cfg, client, provider, model.ID)
+		})
 	})
 
 	response := ModelsResponse{
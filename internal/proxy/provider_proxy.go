@@ -3,6 +3,7 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 
 	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
@@ -17,6 +19,7 @@ import (
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/keypool"
 	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/tracing"
 )
 
 // ModifyResponseFunc is a callback for additional response processing.
@@ -64,7 +67,11 @@ func NewProviderProxy(
 		Rewrite:        providerProxy.rewrite,
 		FlushInterval:  -1, // Immediate flush for SSE
 		ModifyResponse: providerProxy.modifyResponse,
-		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, _ error) {
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			recordSpanError(r.Context(), err)
+			if recorder, ok := w.(upstreamErrorRecorder); ok {
+				recorder.recordUpstreamError(err)
+			}
 			WriteError(w, http.StatusBadGateway, "api_error", "upstream connection failed")
 		},
 	}
@@ -74,35 +81,20 @@ func NewProviderProxy(
 
 // modifyResponse handles SSE headers, Event Stream conversion, and calls the optional hook.
 func (pp *ProviderProxy) modifyResponse(resp *http.Response) error {
-	ct := resp.Header.Get("Content-Type")
-	if ct != "" {
-		mediaType, _, err := mime.ParseMediaType(ct)
-		if err == nil {
-			// Standard SSE: set headers
-			if mediaType == providers.ContentTypeSSE {
-				SetSSEHeaders(resp.Header)
-			}
-
-			// Bedrock Event Stream: needs conversion to SSE
-			// The provider's StreamingContentType tells us what to expect
-			providerStreamType := pp.Provider.StreamingContentType()
-			if providerStreamType == providers.ContentTypeEventStream && mediaType == providers.ContentTypeEventStream {
-				// Mark response for Event Stream conversion
-				// The actual conversion happens via TransformResponse
-				// We need to convert the Content-Type for the client
-				resp.Header.Set("Content-Type", providers.ContentTypeSSE)
-				SetSSEHeaders(resp.Header)
-
-				// Store original response body for conversion
-				// The TransformResponse needs http.ResponseWriter which we don't have here
-				// Instead, we wrap the body to convert Event Stream to SSE on read
-				if resp.Body != nil {
-					resp.Body = newEventStreamToSSEBody(resp.Body)
-				}
-			}
+	// Non-Anthropic APIs are translated first so everything below sees Messages format.
+	// Successful count_tokens replies are left to the provider's TokenCounter.
+	if providers.TranslatesResponses(pp.Provider) && !isCountTokensSuccess(resp) {
+		if err := pp.Provider.TransformResponse(resp, nil); err != nil {
+			return err
 		}
 	}
 
+	pp.handleStreamContentType(resp)
+
+	if err := transformCountTokensResponse(resp); err != nil {
+		return err
+	}
+
 	// Call the hook for additional processing (key pool updates, circuit breaker)
 	if pp.modifyResponseHook != nil {
 		return pp.modifyResponseHook(resp)
@@ -111,12 +103,62 @@ func (pp *ProviderProxy) modifyResponse(resp *http.Response) error {
 	return nil
 }
 
+// handleStreamContentType sets SSE headers and converts Bedrock Event Stream bodies to SSE.
+func (pp *ProviderProxy) handleStreamContentType(resp *http.Response) {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return
+	}
+
+	// Standard SSE: set headers
+	if mediaType == providers.ContentTypeSSE {
+		SetSSEHeaders(resp.Header)
+	}
+
+	// Bedrock Event Stream: needs conversion to SSE
+	// The provider's StreamingContentType tells us what to expect
+	providerStreamType := pp.Provider.StreamingContentType()
+	if providerStreamType != providers.ContentTypeEventStream || mediaType != providers.ContentTypeEventStream {
+		return
+	}
+
+	// Mark response for Event Stream conversion
+	// The actual conversion happens via TransformResponse
+	// We need to convert the Content-Type for the client
+	resp.Header.Set("Content-Type", providers.ContentTypeSSE)
+	SetSSEHeaders(resp.Header)
+
+	// Store original response body for conversion
+	// The TransformResponse needs http.ResponseWriter which we don't have here
+	// Instead, we wrap the body to convert Event Stream to SSE on read
+	if resp.Body != nil {
+		resp.Body = newEventStreamToSSEBody(resp.Body)
+	}
+}
+
 // rewrite creates the Rewrite function for this provider's proxy.
 func (pp *ProviderProxy) rewrite(proxyRequest *httputil.ProxyRequest) {
+	// Replace the client's traceparent so the provider sees the upstream span as parent.
+	tracing.Inject(proxyRequest.Out.Context(), proxyRequest.Out.Header)
+
+	// Token counting has its own endpoint and body shape on every native provider.
+	// The handler never proxies count_tokens to providers without one.
+	if counter, ok := pp.Provider.(providers.TokenCounter); ok &&
+		proxyRequest.In.URL.Path == providers.CountTokensEndpoint {
+		proxyRequest.Out = proxyRequest.Out.WithContext(
+			context.WithValue(proxyRequest.Out.Context(), tokenCounterContextKey{}, counter))
+		pp.rewriteWithTransform(proxyRequest, func(body []byte, _ string) ([]byte, string, error) {
+			return counter.TransformCountTokensRequest(body)
+		})
+		pp.requestIdentityEncoding(proxyRequest)
+		return
+	}
+
 	// Handle body transformation for cloud providers (Bedrock, Vertex)
 	// This must happen before SetURL because cloud providers return a dynamic target URL
 	if pp.Provider.RequiresBodyTransform() {
-		pp.rewriteWithTransform(proxyRequest)
+		pp.rewriteWithTransform(proxyRequest, pp.Provider.TransformRequest)
+		pp.requestIdentityEncoding(proxyRequest)
 		return
 	}
 
@@ -126,12 +168,23 @@ func (pp *ProviderProxy) rewrite(proxyRequest *httputil.ProxyRequest) {
 	pp.setAuth(proxyRequest)
 }
 
-// rewriteWithTransform handles cloud providers that need body transformation.
+// requestIdentityEncoding drops the client's Accept-Encoding for providers whose
+// responses are translated, so the transport decompresses the body for us.
+func (pp *ProviderProxy) requestIdentityEncoding(proxyRequest *httputil.ProxyRequest) {
+	if providers.TranslatesResponses(pp.Provider) {
+		proxyRequest.Out.Header.Del("Accept-Encoding")
+	}
+}
+
+// rewriteWithTransform handles requests that need body transformation.
 // Cloud providers like Bedrock and Vertex need to:
 // 1. Extract model from request body
 // 2. Remove model from body and add anthropic_version
 // 3. Construct dynamic URL with model in path.
-func (pp *ProviderProxy) rewriteWithTransform(proxyRequest *httputil.ProxyRequest) {
+func (pp *ProviderProxy) rewriteWithTransform(
+	proxyRequest *httputil.ProxyRequest,
+	transform func(body []byte, endpoint string) (newBody []byte, targetURL string, err error),
+) {
 	// Read the original body
 	var originalBody []byte
 	if proxyRequest.In.Body != nil {
@@ -153,7 +206,7 @@ func (pp *ProviderProxy) rewriteWithTransform(proxyRequest *httputil.ProxyReques
 	endpoint := proxyRequest.In.URL.Path
 
 	// Transform the request body and get the dynamic target URL
-	newBody, targetURLStr, err := pp.Provider.TransformRequest(originalBody, endpoint)
+	newBody, targetURLStr, err := transform(originalBody, endpoint)
 	if err != nil {
 		// On transform error, fall back to static URL with original body
 		proxyRequest.Out.Body = io.NopCloser(bytes.NewReader(originalBody))
@@ -188,6 +241,47 @@ func (pp *ProviderProxy) rewriteWithTransform(proxyRequest *httputil.ProxyReques
 	pp.setAuth(proxyRequest)
 }
 
+// tokenCounterContextKey marks outgoing count_tokens requests with the
+// provider's TokenCounter so the response can be normalized.
+type tokenCounterContextKey struct{}
+
+// maxCountTokensResponse bounds the count_tokens response read into memory.
+const maxCountTokensResponse = 1 << 20
+
+// isCountTokensSuccess reports whether resp is a successful native count_tokens response.
+func isCountTokensSuccess(resp *http.Response) bool {
+	_, ok := resp.Request.Context().Value(tokenCounterContextKey{}).(providers.TokenCounter)
+	return ok && resp.StatusCode == http.StatusOK
+}
+
+// transformCountTokensResponse rewrites successful native count_tokens
+// responses into Anthropic's {"input_tokens": N} shape.
+func transformCountTokensResponse(resp *http.Response) error {
+	counter, ok := resp.Request.Context().Value(tokenCounterContextKey{}).(providers.TokenCounter)
+	if !ok || resp.StatusCode != http.StatusOK || resp.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCountTokensResponse))
+	if closeErr := resp.Body.Close(); closeErr != nil {
+		log.Error().Err(closeErr).Msg("failed to close count_tokens response body")
+	}
+	if err != nil {
+		return fmt.Errorf("read count_tokens response: %w", err)
+	}
+
+	body, err = counter.TransformCountTokensResponse(body)
+	if err != nil {
+		return fmt.Errorf("transform count_tokens response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
 // setAuth handles authentication and header forwarding.
 func (pp *ProviderProxy) setAuth(proxyReq *httputil.ProxyRequest) {
 	// Remove internal header before proxying to avoid key leakage
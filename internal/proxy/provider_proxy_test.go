@@ -446,6 +446,108 @@ func TestProviderProxyEventStreamConversion(t *testing.T) {
 	assert.Equal(t, "no-cache, no-transform", recorder.Header().Get("Cache-Control"))
 }
 
+// TestProviderProxyOpenAIProviderTranslatesBothWays tests that an OpenAI-compatible
+// provider receives Chat Completions requests and that the response hook and
+// client only see Messages responses.
+func TestProviderProxyOpenAIProviderTranslatesBothWays(t *testing.T) {
+	t.Parallel()
+
+	var receivedBody, receivedPath, receivedAuth string
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		bodyBytes, readErr := io.ReadAll(request.Body)
+		require.NoError(t, readErr)
+		receivedBody = string(bodyBytes)
+		receivedPath = request.URL.Path
+		receivedAuth = request.Header.Get("Authorization")
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		if _, writeErr := writer.Write([]byte(`{"id":"chatcmpl-9","model":"gpt-4o","choices":[{"index":0,` +
+			`"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":7,"completion_tokens":2}}`)); writeErr != nil {
+			return
+		}
+	}))
+	defer backend.Close()
+
+	provider := providers.NewOpenAIProvider("openai", backend.URL+"/v1", nil, nil)
+
+	var hookBody string
+	hook := func(resp *http.Response) error {
+		bodyBytes, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return readErr
+		}
+		hookBody = string(bodyBytes)
+		resp.Body = io.NopCloser(strings.NewReader(hookBody))
+		return nil
+	}
+	providerProxy, err := proxy.NewProviderProxy(provider, "sk-openai", nil, proxy.TestDebugOptions(), hook)
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/v1/messages",
+		strings.NewReader(`{"model":"gpt-4o","max_tokens":16,"messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("X-Selected-Key", "sk-openai")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	recorder := httptest.NewRecorder()
+	providerProxy.Proxy.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "/v1/chat/completions", receivedPath)
+	assert.Equal(t, "Bearer sk-openai", receivedAuth)
+	assert.JSONEq(t, `{"model":"gpt-4o","max_tokens":16,"messages":[{"role":"user","content":"Hello"}]}`,
+		receivedBody)
+	assert.Contains(t, hookBody, `"input_tokens":7`, "hook must see Messages usage")
+	assert.JSONEq(t, `{"id":"msg_9","type":"message","role":"assistant","model":"gpt-4o",`+
+		`"content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","stop_sequence":null,`+
+		`"usage":{"input_tokens":7,"cache_read_input_tokens":0,"output_tokens":2}}`, recorder.Body.String())
+}
+
+// TestProviderProxyGeminiProviderStreams tests that a Gemini provider receives
+// streamGenerateContent requests and that its SSE stream reaches the client
+// as Messages events with the thought signature preserved.
+func TestProviderProxyGeminiProviderStreams(t *testing.T) {
+	t.Parallel()
+
+	var receivedBody, receivedURI, receivedKey string
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		bodyBytes, readErr := io.ReadAll(request.Body)
+		require.NoError(t, readErr)
+		receivedBody = string(bodyBytes)
+		receivedURI = request.URL.RequestURI()
+		receivedKey = request.Header.Get("x-goog-api-key")
+		writer.Header().Set("Content-Type", "text/event-stream")
+		writer.WriteHeader(http.StatusOK)
+		if _, writeErr := writer.Write([]byte(`data: {"responseId":"r1","modelVersion":"gemini-2.5-pro",` +
+			`"candidates":[{"content":{"role":"model","parts":[{"text":"Hi","thoughtSignature":"sig-1"}]},` +
+			`"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":1}}` +
+			"\r\n\r\n")); writeErr != nil {
+			return
+		}
+	}))
+	defer backend.Close()
+
+	provider := providers.NewGeminiProvider("gemini", backend.URL, nil, nil)
+	providerProxy, err := proxy.NewProviderProxy(provider, "AIza-test", nil, proxy.TestDebugOptions(), nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequestWithContext(context.Background(), "POST", "/v1/messages",
+		strings.NewReader(`{"model":"gemini-2.5-pro","max_tokens":16,"stream":true,`+
+			`"messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("X-Selected-Key", "AIza-test")
+	recorder := httptest.NewRecorder()
+	providerProxy.Proxy.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", receivedURI)
+	assert.Equal(t, "AIza-test", receivedKey)
+	assert.JSONEq(t, `{"contents":[{"role":"user","parts":[{"text":"Hello"}]}],`+
+		`"generationConfig":{"maxOutputTokens":16}}`, receivedBody)
+	assert.Equal(t, "no-cache, no-transform", recorder.Header().Get("Cache-Control"))
+	assert.Contains(t, recorder.Body.String(), `"signature":"sig-1"`)
+	assert.Contains(t, recorder.Body.String(), `"text":"Hi"`)
+	assert.Contains(t, recorder.Body.String(), "event: message_stop\n")
+}
+
 func TestEventStreamToSSEBodyNoProgress(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,332 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+const (
+	// responseCacheKeyPrefix namespaces cached responses in the shared cache.
+	responseCacheKeyPrefix = "response:"
+
+	// X-CC-Relay-Cache response values.
+	responseCacheHit  = "HIT"
+	responseCacheMiss = "MISS"
+)
+
+// volatileRequestFields are request fields that do not change the response,
+// so requests differing only in them share a cache entry.
+var volatileRequestFields = []string{"metadata"}
+
+var sseEventSeparator = []byte("\n\n")
+
+// ResponseCache stores complete /v1/messages responses so identical
+// deterministic requests are answered without calling a provider.
+// Uses cc-relay's cache.Cache interface for storage, so cached responses are
+// shared between instances in HA mode.
+type ResponseCache struct {
+	cache cache.Cache
+}
+
+// NewResponseCache creates a response cache using the provided cache backend.
+// Returns nil if the cache is nil (no-op mode).
+func NewResponseCache(c cache.Cache) *ResponseCache {
+	if c == nil {
+		return nil
+	}
+	return &ResponseCache{cache: c}
+}
+
+// cachedResponse is a stored response. Streamed responses keep their SSE
+// events so a hit is replayed as the same event sequence.
+type cachedResponse struct {
+	ContentType string   `json:"content_type"`
+	Body        []byte   `json:"body,omitempty"`
+	Events      []string `json:"events,omitempty"`
+}
+
+// get returns the response cached under key. Misses and cache errors both report false.
+func (c *ResponseCache) get(ctx context.Context, key string) (cachedResponse, bool) {
+	var entry cachedResponse
+	data, err := c.cache.Get(ctx, responseCacheKeyPrefix+key)
+	if err != nil || json.Unmarshal(data, &entry) != nil || (len(entry.Body) == 0 && len(entry.Events) == 0) {
+		return cachedResponse{ContentType: "", Body: nil, Events: nil}, false
+	}
+	return entry, true
+}
+
+// set caches entry under key for ttl. Errors are logged, since a lost entry
+// only costs a provider call.
+func (c *ResponseCache) set(ctx context.Context, key string, entry cachedResponse, ttl time.Duration) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = c.cache.SetWithTTL(ctx, responseCacheKeyPrefix+key, data, ttl)
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("failed to store cached response")
+	}
+}
+
+// serveCachedResponse answers the request from the response cache when the
+// response cache is enabled and the request may be cached. On a miss, the
+// cache key is stored in the returned request's context so the response is
+// cached once it completes. Reports whether the request was answered.
+func (h *Handler) serveCachedResponse(writer http.ResponseWriter, request *http.Request) (*http.Request, bool) {
+	cfg := h.getRuntimeConfigGetter()
+	if h.responses == nil || cfg == nil || !cfg.ResponseCache.Enabled || isCountTokensRequest(request) {
+		return request, false
+	}
+	key, ok := responseCacheKey(request)
+	if !ok {
+		return request, false
+	}
+
+	ctx := request.Context()
+	span := trace.SpanFromContext(ctx)
+	if entry, hit := h.responses.get(ctx, key); hit {
+		span.SetAttributes(attrResponseCache.String(responseCacheHit))
+		zerolog.Ctx(ctx).Debug().Msg("serving cached response")
+		replayResponse(writer, entry)
+		return request, true
+	}
+
+	span.SetAttributes(attrResponseCache.String(responseCacheMiss))
+	writer.Header().Set(HeaderRelayCache, responseCacheMiss)
+	return request.WithContext(context.WithValue(ctx, responseCacheContextKey, key)), false
+}
+
+// responseCacheKey returns the cache key of a request that may be cached:
+// one with temperature 0, or one that opts in with the X-CC-Relay-Cache
+// header. Opting out with the header disables caching even at temperature 0.
+func responseCacheKey(request *http.Request) (string, bool) {
+	if request.Body == nil {
+		return "", false
+	}
+	body, err := readBody(request)
+	if err != nil || !responseCacheable(request.Header.Get(HeaderRelayCache), body) {
+		return "", false
+	}
+
+	canonical, err := canonicalRequest(body)
+	if err != nil {
+		return "", false
+	}
+	clientName := ""
+	if client := auth.ClientFromContext(request.Context()); client != nil {
+		clientName = client.Name
+	}
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		clientName,
+		request.Header.Get("anthropic-version"),
+		request.Header.Get("anthropic-beta"),
+		string(canonical),
+	}, "\x00")))
+	return hex.EncodeToString(hash[:]), true
+}
+
+// responseCacheable reports whether a request may be cached, given its
+// X-CC-Relay-Cache header.
+func responseCacheable(optIn string, body []byte) bool {
+	if optIn != "" {
+		enabled, err := strconv.ParseBool(optIn)
+		return err == nil && enabled
+	}
+	temperature := gjson.GetBytes(body, "temperature")
+	return temperature.Type == gjson.Number && temperature.Float() == 0
+}
+
+// canonicalRequest re-encodes a request body with sorted keys, without
+// volatile fields and without cache_control markers, so requests that differ
+// only in formatting or prompt caching hints share a key.
+func canonicalRequest(body []byte) ([]byte, error) {
+	var parsed map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	for _, field := range volatileRequestFields {
+		delete(parsed, field)
+	}
+	stripCacheControl(parsed)
+	return json.Marshal(parsed)
+}
+
+// stripCacheControl removes cache_control markers from every object in value.
+func stripCacheControl(value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		delete(typed, "cache_control")
+		for _, child := range typed {
+			stripCacheControl(child)
+		}
+	case []any:
+		for _, child := range typed {
+			stripCacheControl(child)
+		}
+	}
+}
+
+// replayResponse writes a cached response. Streams are written event by
+// event, flushing each, as the provider sent them.
+func replayResponse(writer http.ResponseWriter, entry cachedResponse) {
+	if len(entry.Events) > 0 {
+		SetSSEHeaders(writer.Header())
+	} else {
+		writer.Header().Set("Content-Type", entry.ContentType)
+	}
+	writer.Header().Set(HeaderRelayCache, responseCacheHit)
+	writer.WriteHeader(http.StatusOK)
+
+	if len(entry.Events) == 0 {
+		//nolint:errcheck // the client may have gone away; nothing else can be done
+		writer.Write(entry.Body)
+		return
+	}
+	controller := http.NewResponseController(writer)
+	for _, event := range entry.Events {
+		if _, err := io.WriteString(writer, event); err != nil {
+			return
+		}
+		//nolint:errcheck // Flush is best-effort; unsupported writers simply buffer
+		controller.Flush()
+	}
+}
+
+// tapResponseCache wraps a successful body whose request missed the response
+// cache, so the response is cached once it has been read completely.
+func (h *Handler) tapResponseCache(resp *http.Response) {
+	ctx := resp.Request.Context()
+	key, ok := ctx.Value(responseCacheContextKey).(string)
+	cfg := h.getRuntimeConfigGetter()
+	if !ok || h.responses == nil || cfg == nil || resp.Body == nil {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != providers.ContentTypeSSE && mediaType != mediaTypeJSON) {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	contentType := resp.Header.Get("Content-Type")
+	ttl := cfg.ResponseCache.GetTTL()
+	if mediaType == mediaTypeJSON {
+		onJSONBody(resp, cfg.ResponseCache.GetMaxEntryBytes(), func(body []byte, complete bool) {
+			h.responses.storeMessage(ctx, key, cachedResponse{ContentType: contentType, Body: body, Events: nil},
+				complete, ttl)
+		})
+		return
+	}
+	resp.Body = &responseCacheTapBody{
+		original:    resp.Body,
+		ctx:         ctx,
+		cache:       h.responses,
+		key:         key,
+		contentType: contentType,
+		body:        bytes.Buffer{},
+		ttl:         ttl,
+		maxBytes:    cfg.ResponseCache.GetMaxEntryBytes(),
+		skipped:     false,
+	}
+}
+
+// store caches entry under key, logging that it did.
+func (c *ResponseCache) store(ctx context.Context, key string, entry cachedResponse, ttl time.Duration) {
+	c.set(ctx, key, entry, ttl)
+	zerolog.Ctx(ctx).Debug().Dur("ttl", ttl).Msg("cached response")
+}
+
+// storeMessage caches a non-streaming response if it was read completely and
+// is a message.
+func (c *ResponseCache) storeMessage(
+	ctx context.Context, key string, entry cachedResponse, complete bool, ttl time.Duration,
+) {
+	if complete && gjson.GetBytes(entry.Body, "type").String() == "message" {
+		c.store(ctx, key, entry, ttl)
+	}
+}
+
+// responseCacheTapBody passes a streamed response body through unchanged
+// while keeping a copy, which is cached when the stream ends. Streams larger
+// than maxBytes are not cached, nor are streams the client stopped reading
+// early. Non-streaming bodies are cached through onJSONBody instead.
+type responseCacheTapBody struct {
+	original    io.ReadCloser
+	ctx         context.Context
+	cache       *ResponseCache
+	key         string
+	contentType string
+	body        bytes.Buffer
+	ttl         time.Duration
+	maxBytes    int
+	skipped     bool
+}
+
+// Read implements io.Reader, copying each chunk and caching the body at EOF.
+func (t *responseCacheTapBody) Read(p []byte) (int, error) {
+	n, err := t.original.Read(p)
+	if n > 0 && !t.skipped {
+		if t.body.Len()+n > t.maxBytes {
+			t.skipped = true
+			t.body = bytes.Buffer{}
+		} else {
+			t.body.Write(p[:n])
+		}
+	}
+	if errors.Is(err, io.EOF) && !t.skipped {
+		t.skipped = true
+		t.store()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (t *responseCacheTapBody) Close() error {
+	return t.original.Close()
+}
+
+// store caches the stream if it is a complete message.
+func (t *responseCacheTapBody) store() {
+	if !completeStream(t.body.Bytes()) {
+		return
+	}
+	entry := cachedResponse{ContentType: t.contentType, Body: nil, Events: nil}
+	for event := range bytes.SplitAfterSeq(t.body.Bytes(), sseEventSeparator) {
+		if len(bytes.TrimSpace(event)) > 0 {
+			entry.Events = append(entry.Events, string(event))
+		}
+	}
+	t.cache.store(t.ctx, t.key, entry, t.ttl)
+}
+
+// completeStream reports whether an SSE body ends its message without an error event.
+func completeStream(body []byte) bool {
+	var lines sseLineSplitter
+	stopped, failed := false, false
+	lines.feed(body, func(payload []byte) {
+		switch gjson.GetBytes(payload, "type").String() {
+		case sseEventMessageStop:
+			stopped = true
+		case errorResponseType:
+			failed = true
+		}
+	})
+	return stopped && !failed
+}
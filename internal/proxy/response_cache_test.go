@@ -0,0 +1,177 @@
+package proxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+const (
+	deterministicBody = `{"model":"claude-sonnet-4","temperature":0,"max_tokens":64,` +
+		`"metadata":{"user_id":"u-1"},"messages":[{"role":"user","content":[{"type":"text","text":"2+2?"}]}]}`
+
+	// The same request with another user ID, a cache_control marker and its keys reordered.
+	equivalentBody = `{"messages":[{"role":"user","content":[{"type":"text","text":"2+2?",` +
+		`"cache_control":{"type":"ephemeral"}}]}],"max_tokens":64,"temperature":0,` +
+		`"model":"claude-sonnet-4","metadata":{"user_id":"u-2"}}`
+
+	sampledBody = `{"model":"claude-sonnet-4","max_tokens":64,"messages":[{"role":"user","content":"2+2?"}]}`
+
+	cachedMessage = `{"id":"msg_1","type":"message","content":[{"type":"text","text":"4"}]}`
+
+	cachedStream = "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+)
+
+// countingBackend answers every request with body and counts the requests.
+type countingBackend struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newCountingBackend(t *testing.T, contentType, body string) *countingBackend {
+	t.Helper()
+	backend := &countingBackend{Server: nil, requests: atomic.Int32{}}
+	backend.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		backend.requests.Add(1)
+		writer.Header().Set(proxy.ContentTypeHeader, contentType)
+		writer.WriteHeader(http.StatusOK)
+		if _, err := writer.Write([]byte(body)); err != nil {
+			return
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func newResponseCacheHandler(t *testing.T, backend *countingBackend, maxEntryBytes int) *proxy.Handler {
+	t.Helper()
+
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = proxy.NewTestProvider(backend.URL)
+	opts.APIKey = testKey
+	opts.ResponseCache = proxy.NewResponseCache(newMapCache())
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	cfg := proxy.TestConfig("")
+	cfg.ResponseCache = config.ResponseCacheConfig{TTLMS: 0, MaxEntryBytes: maxEntryBytes, Enabled: true}
+	handler.SetRuntimeConfigGetter(config.NewRuntime(cfg))
+	return handler
+}
+
+func serveWithCacheHeader(t *testing.T, handler http.Handler, body, value string) *httptest.ResponseRecorder {
+	t.Helper()
+	return proxy.ServeRequest(t, handler, proxy.NewMessagesRequestWithHeaders(body,
+		proxy.HeaderPair{Key: proxy.ContentTypeHeader, Value: proxy.JSONContentType},
+		proxy.HeaderPair{Key: proxy.HeaderRelayCache, Value: value},
+	))
+}
+
+func TestHandlerResponseCacheReplaysJSON(t *testing.T) {
+	t.Parallel()
+
+	backend := newCountingBackend(t, proxy.JSONContentType, cachedMessage)
+	handler := newResponseCacheHandler(t, backend, 0)
+
+	first := serveJSONMessagesBody(t, handler, deterministicBody)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get(proxy.HeaderRelayCache))
+
+	rr := serveJSONMessagesBody(t, handler, equivalentBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "HIT", rr.Header().Get(proxy.HeaderRelayCache))
+	assert.Equal(t, proxy.JSONContentType, rr.Header().Get(proxy.ContentTypeHeader))
+	assert.JSONEq(t, cachedMessage, rr.Body.String())
+	assert.Equal(t, int32(1), backend.requests.Load(), "the hit should not reach the provider")
+}
+
+func TestHandlerResponseCacheReplaysStream(t *testing.T) {
+	t.Parallel()
+
+	backend := newCountingBackend(t, "text/event-stream", cachedStream)
+	handler := newResponseCacheHandler(t, backend, 0)
+	body := `{"model":"claude-sonnet-4","temperature":0,"stream":true,"messages":[{"role":"user","content":"2+2?"}]}`
+
+	first := serveJSONMessagesBody(t, handler, body)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get(proxy.HeaderRelayCache))
+
+	rr := serveJSONMessagesBody(t, handler, body)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "HIT", rr.Header().Get(proxy.HeaderRelayCache))
+	assert.Equal(t, "text/event-stream", rr.Header().Get(proxy.ContentTypeHeader))
+	assert.Equal(t, cachedStream, rr.Body.String())
+	assert.Equal(t, int32(1), backend.requests.Load())
+}
+
+func TestHandlerResponseCacheSkipsIncompleteStream(t *testing.T) {
+	t.Parallel()
+
+	backend := newCountingBackend(t, "text/event-stream",
+		"event: message_start\ndata: {\"type\":\"message_start\"}\n\n")
+	handler := newResponseCacheHandler(t, backend, 0)
+	body := `{"model":"claude-sonnet-4","temperature":0,"stream":true,"messages":[{"role":"user","content":"2+2?"}]}`
+
+	for range 2 {
+		rr := serveJSONMessagesBody(t, handler, body)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "MISS", rr.Header().Get(proxy.HeaderRelayCache))
+	}
+	assert.Equal(t, int32(2), backend.requests.Load(), "a stream without message_stop is not cached")
+}
+
+func TestHandlerResponseCacheEligibility(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		body      string
+		header    string
+		wantCache string
+		wantCalls int32
+	}{
+		{name: "sampled request", body: sampledBody, header: "", wantCache: "", wantCalls: 2},
+		{name: "sampled request opts in", body: sampledBody, header: "true", wantCache: "HIT", wantCalls: 1},
+		{name: "deterministic request opts out", body: deterministicBody, header: "false", wantCache: "", wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := newCountingBackend(t, proxy.JSONContentType, cachedMessage)
+			handler := newResponseCacheHandler(t, backend, 0)
+
+			var rr *httptest.ResponseRecorder
+			for range 2 {
+				rr = serveWithCacheHeader(t, handler, tt.body, tt.header)
+				require.Equal(t, http.StatusOK, rr.Code)
+			}
+			assert.Equal(t, tt.wantCache, rr.Header().Get(proxy.HeaderRelayCache))
+			assert.Equal(t, tt.wantCalls, backend.requests.Load())
+		})
+	}
+}
+
+func TestHandlerResponseCacheMaxEntryBytes(t *testing.T) {
+	t.Parallel()
+
+	backend := newCountingBackend(t, proxy.JSONContentType, cachedMessage)
+	handler := newResponseCacheHandler(t, backend, len(cachedMessage)-1)
+
+	for range 2 {
+		rr := serveJSONMessagesBody(t, handler, deterministicBody)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, cachedMessage, rr.Body.String(), "oversized responses still reach the client")
+	}
+	assert.Equal(t, int32(2), backend.requests.Load(), "responses over max_entry_bytes are not cached")
+}
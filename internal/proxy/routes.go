@@ -4,13 +4,17 @@ package proxy
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 
+	"github.com/omarluq/cc-relay/internal/budget"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/health"
 	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/metrics"
 	"github.com/omarluq/cc-relay/internal/providers"
 	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/tracing"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,6 +23,7 @@ type RoutesOptions struct {
 	ProviderRouter     router.ProviderRouter
 	Provider           providers.Provider
 	ConfigProvider     config.RuntimeConfigGetter
+	ConfigReloader     ConfigReloader
 	Pool               *keypool.KeyPool
 	ProviderInfosFunc  ProviderInfoFunc
 	ProviderPools      map[string]*keypool.KeyPool
@@ -29,6 +34,12 @@ type RoutesOptions struct {
 	HealthTracker      *health.Tracker
 	SignatureCache     *SignatureCache
 	ConcurrencyLimiter *ConcurrencyLimiter
+	Metrics            *metrics.Metrics
+	Budgets            *budget.Tracker
+	ClientLimits       *ClientRateLimiter
+	Sessions           *SessionStore
+	ResponseCache      *ResponseCache
+	Tracing            *tracing.Tracing
 	ProviderKey        string
 	ProviderInfos      []router.ProviderInfo
 	AllProviders       []providers.Provider
@@ -43,62 +54,104 @@ const routesOptionsRequiredMsg = "routes options are required"
 // - Hot-reloadable key pools (newly enabled providers get keys immediately)
 // Routes:
 //   - POST /v1/messages - Proxy to backend provider with router-based selection
-//   - GET /v1/models - List available models from all providers (no auth required)
+//   - POST /v1/messages/count_tokens - Native token counting, or a local estimate
+//   - POST /v1/chat/completions - OpenAI Chat Completions, translated to /v1/messages
+//   - GET /v1/models - List the models available to the client, if it identifies itself (no auth required)
 //   - GET /v1/providers - List active providers with metadata (no auth required)
 //   - GET /health - Health check endpoint (no auth required)
+//   - GET /metrics - Prometheus metrics when Metrics is set (no auth required, path configurable)
+//   - /admin/... - Admin API when admin.enabled is set at startup (admin token required)
 func SetupRoutesWithLiveKeyPools(opts *RoutesOptions) (http.Handler, error) {
 	if opts == nil {
 		return nil, errors.New(routesOptionsRequiredMsg)
 	}
 	mux := http.NewServeMux()
 
-	messagesHandler, err := buildMessagesHandler(opts)
+	handler, err := buildProxyHandler(opts)
 	if err != nil {
 		return nil, err
 	}
+
+	messagesHandler := buildMessagesHandler(opts, handler)
 	mux.Handle("POST /v1/messages", messagesHandler)
+	mux.Handle("POST "+providers.CountTokensEndpoint, messagesHandler)
+	mux.Handle("POST "+ChatCompletionsPath, buildChatCompletionsHandler(opts, handler))
 
 	providersGetter := liveProvidersGetter(opts)
-	mux.Handle("GET /v1/models", NewModelsHandler(providersGetter))
+	mux.Handle("GET /v1/models",
+		IdentifyClientMiddleware(opts.ConfigProvider)(NewClientModelsHandler(providersGetter, opts.ConfigProvider)))
 	mux.Handle("GET /v1/providers", NewProvidersHandler(providersGetter))
 
 	registerHealthRoute(mux)
+	if opts.Metrics != nil {
+		mux.Handle("GET "+cfgMetricsPath(opts), opts.Metrics.Handler())
+	}
+	if cfg := opts.ConfigProvider.Get(); cfg != nil && cfg.Admin.Enabled {
+		mux.Handle(config.AdminPath+"/", buildAdminHandler(opts, providersGetter))
+	}
 
 	return mux, nil
 }
 
-func buildMessagesHandler(opts *RoutesOptions) (http.Handler, error) {
-	handler, err := buildProxyHandler(opts)
-	if err != nil {
-		return nil, err
+func buildMessagesHandler(opts *RoutesOptions, handler *Handler) http.Handler {
+	return applyRequestMiddleware(opts, LiveAuthMiddleware(opts.ConfigProvider)(withClientRateLimits(opts, handler)))
+}
+
+// buildChatCompletionsHandler serves OpenAI clients through the same proxy handler,
+// so both API families share key pools, routing and health tracking.
+// Requests are authenticated before they are translated, and auth failures are
+// reported in OpenAI format; client credentials are dropped once validated.
+func buildChatCompletionsHandler(opts *RoutesOptions, handler *Handler) http.Handler {
+	limited := withClientRateLimits(opts, withoutClientCredentials(handler))
+	return applyRequestMiddleware(opts, NewChatCompletionsHandler(LiveAuthMiddleware(opts.ConfigProvider), limited))
+}
+
+// withClientRateLimits applies the per-client and per-IP rate limits, if a
+// limiter is provided. It runs after authentication, which identifies the client.
+func withClientRateLimits(opts *RoutesOptions, next http.Handler) http.Handler {
+	if opts.ClientLimits == nil {
+		return next
 	}
+	return ClientRateLimitMiddleware(opts.ConfigProvider, opts.ClientLimits)(next)
+}
 
+// applyRequestMiddleware wraps an API handler with the shared middleware chain.
+func applyRequestMiddleware(opts *RoutesOptions, next http.Handler) http.Handler {
 	// Apply middleware in order (outermost first):
 	// 1. RequestIDMiddleware - generates request ID
-	// 2. LoggingMiddleware - logs with request ID
-	// 3. ConcurrencyMiddleware - enforces max_concurrent limit (early rejection)
-	// 4. MaxBodyBytesMiddleware - enforces max_body_bytes limit
-	// 5. AuthMiddleware - validates authentication
-	// 6. Handler
-	var messagesHandler http.Handler = handler
-
-	messagesHandler = LiveAuthMiddleware(opts.ConfigProvider)(messagesHandler)
+	// 2. TracingMiddleware - starts the server span (when tracing is enabled)
+	// 3. LoggingMiddleware - logs with request ID
+	// 4. ConcurrencyMiddleware - enforces max_concurrent limit (early rejection)
+	// 5. MaxBodyBytesMiddleware - enforces max_body_bytes limit
+	// 6. next - AuthMiddleware, ClientRateLimitMiddleware and the endpoint handler
+	wrapped := next
 
 	// Apply max_body_bytes limit (hot-reloadable)
-	messagesHandler = MaxBodyBytesMiddleware(func() int64 {
+	wrapped = MaxBodyBytesMiddleware(func() int64 {
 		cfg := opts.ConfigProvider.Get()
 		if cfg == nil {
 			return 0
 		}
 		return cfg.Server.MaxBodyBytes
-	})(messagesHandler)
+	})(wrapped)
 
 	// Apply concurrency limit if limiter provided
 	if opts.ConcurrencyLimiter != nil {
-		messagesHandler = ConcurrencyMiddleware(opts.ConcurrencyLimiter)(messagesHandler)
+		wrapped = ConcurrencyMiddleware(opts.ConcurrencyLimiter)(wrapped)
 	}
 
-	messagesHandler = LoggingMiddlewareWithProvider(func() config.DebugOptions {
+	wrapped = LoggingMiddlewareWithProvider(liveDebugOptions(opts))(wrapped)
+	if opts.Tracing != nil {
+		wrapped = TracingMiddleware(opts.Tracing)(wrapped)
+	}
+	wrapped = RequestIDMiddleware()(wrapped)
+
+	return wrapped
+}
+
+// liveDebugOptions returns the debug options of the live config.
+func liveDebugOptions(opts *RoutesOptions) DebugOptionsProvider {
+	return func() config.DebugOptions {
 		cfg := opts.ConfigProvider.Get()
 		if cfg == nil {
 			return config.DebugOptions{
@@ -109,10 +162,36 @@ func buildMessagesHandler(opts *RoutesOptions) (http.Handler, error) {
 			}
 		}
 		return cfg.Logging.DebugOptions
-	})(messagesHandler)
-	messagesHandler = RequestIDMiddleware()(messagesHandler)
+	}
+}
 
-	return messagesHandler, nil
+// buildAdminHandler serves the admin API with request IDs and logging, but
+// outside the concurrency limit, so operators can act on a saturated relay.
+func buildAdminHandler(opts *RoutesOptions, providersGetter ProvidersGetter) http.Handler {
+	admin := NewAdminHandler(&AdminOptions{
+		ConfigProvider:     opts.ConfigProvider,
+		Reloader:           opts.ConfigReloader,
+		ProviderRouter:     opts.ProviderRouter,
+		HealthTracker:      opts.HealthTracker,
+		ConcurrencyLimiter: opts.ConcurrencyLimiter,
+		GetProviders:       providersGetter,
+		GetProviderPools:   liveKeyPoolsGetter(opts),
+	})
+	return RequestIDMiddleware()(LoggingMiddlewareWithProvider(liveDebugOptions(opts))(admin))
+}
+
+// liveKeyPoolsGetter returns the key pools by provider name. Without a live
+// accessor, the static pools are used, including the primary provider's pool.
+func liveKeyPoolsGetter(opts *RoutesOptions) KeyPoolsFunc {
+	if opts.GetProviderPools != nil {
+		return opts.GetProviderPools
+	}
+	pools := make(map[string]*keypool.KeyPool, len(opts.ProviderPools)+1)
+	if opts.Pool != nil && opts.Provider != nil {
+		pools[opts.Provider.Name()] = opts.Pool
+	}
+	maps.Copy(pools, opts.ProviderPools)
+	return func() map[string]*keypool.KeyPool { return pools }
 }
 
 func buildProxyHandler(opts *RoutesOptions) (*Handler, error) {
@@ -135,6 +214,10 @@ func buildProxyHandler(opts *RoutesOptions) (*Handler, error) {
 		RoutingDebug:      cfg.Routing.IsDebugEnabled(),
 		HealthTracker:     opts.HealthTracker,
 		SignatureCache:    opts.SignatureCache,
+		Metrics:           opts.Metrics,
+		Budgets:           opts.Budgets,
+		Sessions:          opts.Sessions,
+		ResponseCache:     opts.ResponseCache,
 		ProviderInfos:     nil,
 	},
 	)
@@ -145,6 +228,15 @@ func buildProxyHandler(opts *RoutesOptions) (*Handler, error) {
 	return handler, nil
 }
 
+// cfgMetricsPath returns the configured metrics path. The route is registered
+// once, so changing metrics.path requires a restart.
+func cfgMetricsPath(opts *RoutesOptions) string {
+	if cfg := opts.ConfigProvider.Get(); cfg != nil {
+		return cfg.Metrics.GetPath()
+	}
+	return config.DefaultMetricsPath
+}
+
 func liveProvidersGetter(opts *RoutesOptions) func() []providers.Provider {
 	return func() []providers.Provider {
 		if opts.GetAllProviders != nil {
@@ -92,6 +92,7 @@ func TestSetupRoutesWithLiveKeyPoolsNilConfigProvider(t *testing.T) {
 
 	handler, err := proxy.SetupRoutesWithLiveKeyPools(&proxy.RoutesOptions{
 		ConfigProvider:     nilRuntimeConfigGetter{},
+		ConfigReloader:     nil,
 		Provider:           provider,
 		ProviderInfosFunc:  func() []router.ProviderInfo { return nil },
 		ProviderRouter:     routerInstance,
@@ -102,6 +103,12 @@ func TestSetupRoutesWithLiveKeyPoolsNilConfigProvider(t *testing.T) {
 		AllProviders:       []providers.Provider{provider},
 		HealthTracker:      nil,
 		SignatureCache:     nil,
+		Metrics:            nil,
+		Budgets:            nil,
+		ClientLimits:       nil,
+		Sessions:           nil,
+		ResponseCache:      nil,
+		Tracing:            nil,
 		ProviderPools:      nil,
 		ProviderKeys:       nil,
 		GetAllProviders:    nil,
@@ -125,6 +132,7 @@ func newLiveKeyPoolsHandler(
 
 	handler, err := proxy.SetupRoutesWithLiveKeyPools(&proxy.RoutesOptions{
 		ConfigProvider:     runtimeCfg,
+		ConfigReloader:     nil,
 		Provider:           provider,
 		ProviderInfosFunc:  func() []router.ProviderInfo { return providerInfos },
 		ProviderRouter:     routerInstance,
@@ -135,6 +143,12 @@ func newLiveKeyPoolsHandler(
 		AllProviders:       []providers.Provider{provider},
 		HealthTracker:      nil,
 		SignatureCache:     nil,
+		Metrics:            nil,
+		Budgets:            nil,
+		ClientLimits:       nil,
+		Sessions:           nil,
+		ResponseCache:      nil,
+		Tracing:            nil,
 		ProviderPools:      nil,
 		ProviderKeys:       nil,
 		GetAllProviders:    nil,
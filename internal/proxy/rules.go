@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tidwall/sjson"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+// applyRoutingRule evaluates the live routing rules against the request. The
+// first matching rule is stored in the request context, where provider
+// selection reads it, and its model rewrite is applied to the body.
+// Returns the request and the model to route by.
+func (h *Handler) applyRoutingRule(request *http.Request, model string) (*http.Request, string) {
+	routingConfig := h.getRoutingConfig()
+	if routingConfig == nil || len(routingConfig.Rules) == 0 || request.Body == nil {
+		return request, model
+	}
+
+	body, err := readBody(request)
+	if err != nil {
+		return request, model
+	}
+
+	rule, matched := rules.Find(routingConfig.Rules, ruleRequest(request, body, model))
+	if !matched {
+		return request, model
+	}
+
+	logger := zerolog.Ctx(request.Context())
+	event := logger.Debug().Str("rule", rule.Name)
+	ctx := context.WithValue(request.Context(), routingRuleContextKey, rule)
+	if rule.RewriteModel != "" && rule.RewriteModel != model {
+		if rewriteErr := setRequestModel(request, body, rule.RewriteModel); rewriteErr != nil {
+			logger.Warn().Err(rewriteErr).Str("rule", rule.Name).Msg("failed to rewrite model for routing rule")
+			return request.WithContext(ctx), model
+		}
+		event = event.Str("original_model", model).Str("model", rule.RewriteModel)
+		model = rule.RewriteModel
+		ctx = context.WithValue(CacheModelInContext(ctx, model), modelNameContextKey, model)
+	}
+	event.Msg("routing rule matched")
+	return request.WithContext(ctx), model
+}
+
+// ruleRequest collects what routing rules match on.
+func ruleRequest(request *http.Request, body []byte, model string) *rules.Request {
+	clientName := ""
+	if client := auth.ClientFromContext(request.Context()); client != nil {
+		clientName = client.Name
+	}
+	return &rules.Request{
+		Time:   time.Now(),
+		Header: request.Header,
+		Model:  model,
+		Client: clientName,
+		Body:   body,
+	}
+}
+
+// setRequestModel replaces the model in body and makes it the request body.
+func setRequestModel(request *http.Request, body []byte, model string) error {
+	rewritten, err := sjson.SetBytes(body, "model", model)
+	if err != nil {
+		return err
+	}
+	setBody(request, rewritten)
+	return nil
+}
+
+// routingRuleFromContext returns the routing rule the request matched, if any.
+func routingRuleFromContext(ctx context.Context) (*rules.Rule, bool) {
+	rule, ok := ctx.Value(routingRuleContextKey).(*rules.Rule)
+	return rule, ok && rule != nil
+}
+
+// applyRuleProviders restricts candidates to the providers named by the
+// request's routing rule. Reports false if the rule names no providers, in
+// which case candidates are returned unchanged.
+func applyRuleProviders(ctx context.Context, candidates []router.ProviderInfo) ([]router.ProviderInfo, bool) {
+	rule, ok := routingRuleFromContext(ctx)
+	if !ok || len(rule.Providers) == 0 {
+		return candidates, false
+	}
+	return slices.DeleteFunc(slices.Clone(candidates), func(info router.ProviderInfo) bool {
+		return !slices.Contains(rule.Providers, info.Provider.Name())
+	}), true
+}
+
+// routerFor returns the router for the request: one for its routing rule's
+// strategy if the rule sets one, otherwise the handler's router.
+func (h *Handler) routerFor(ctx context.Context) router.ProviderRouter {
+	rule, ok := routingRuleFromContext(ctx)
+	if h.router == nil || !ok || rule.Strategy == "" {
+		return h.router
+	}
+	timeout := time.Duration(0)
+	if routingConfig := h.getRoutingConfig(); routingConfig != nil {
+		timeout = routingConfig.GetFailoverTimeoutOption().OrEmpty()
+	}
+	return h.ruleRouters.get(rule, timeout, h.router)
+}
+
+// ruleRouters holds a router per routing rule, so stateful strategies such as
+// round_robin keep their state across requests. A rule's router is rebuilt
+// when its strategy or the failover timeout changes.
+type ruleRouters struct {
+	routers map[string]ruleRouter
+	mu      sync.Mutex
+}
+
+type ruleRouter struct {
+	router   router.ProviderRouter
+	strategy string
+	timeout  time.Duration
+}
+
+// get returns the router for rule, or fallback if its strategy is unknown.
+func (c *ruleRouters) get(
+	rule *rules.Rule, timeout time.Duration, fallback router.ProviderRouter,
+) router.ProviderRouter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.routers[rule.Name]; ok && cached.strategy == rule.Strategy && cached.timeout == timeout {
+		return cached.router
+	}
+	rtr, err := router.NewRouter(rule.Strategy, timeout)
+	if err != nil {
+		return fallback
+	}
+	if c.routers == nil {
+		c.routers = make(map[string]ruleRouter)
+	}
+	c.routers[rule.Name] = ruleRouter{router: rtr, strategy: rule.Strategy, timeout: timeout}
+	return rtr
+}
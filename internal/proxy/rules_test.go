@@ -0,0 +1,115 @@
+package proxy_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+const toolsRequestBody = `{"model":"claude-sonnet-4-20250514","tools":[{"name":"bash"}],"messages":[]}`
+
+func routingRule(name string, match *rules.Match, providerNames ...string) rules.Rule {
+	return rules.Rule{Match: *match, Name: name, Strategy: "", RewriteModel: "", Providers: providerNames}
+}
+
+func ruleMatch(models ...string) *rules.Match {
+	return &rules.Match{
+		Headers:        nil,
+		HasTools:       nil,
+		HasThinking:    nil,
+		HasImages:      nil,
+		TimeOfDay:      "",
+		Timezone:       "",
+		Models:         models,
+		Clients:        nil,
+		MinInputTokens: 0,
+		MaxInputTokens: 0,
+	}
+}
+
+// rulesConfig returns a live config with routing debug headers and the given rules.
+func rulesConfig(ruleList ...rules.Rule) *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Debug = true
+	cfg.Routing.Rules = ruleList
+	return cfg
+}
+
+func TestHandlerRoutingRuleRestrictsProviders(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, backend.URL),
+		proxy.NewNamedProvider(providerBName, backend.URL),
+	)
+	agents := ruleMatch()
+	hasTools := true
+	agents.HasTools = &hasTools
+	runtime := config.NewRuntime(rulesConfig(routingRule("agents", agents, providerBName)))
+	handler.SetRuntimeConfigGetter(runtime)
+
+	rr := serveJSONMessagesBody(t, handler, toolsRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "agents", rr.Header().Get("X-CC-Relay-Rule"))
+
+	rr = serveJSONMessagesBody(t, handler, failoverRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"), "requests no rule matches use all providers")
+	assert.Empty(t, rr.Header().Get("X-CC-Relay-Rule"))
+
+	// Rules removed by a reload no longer apply
+	runtime.Store(rulesConfig())
+	rr = serveJSONMessagesBody(t, handler, toolsRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+}
+
+func TestHandlerRoutingRuleStrategy(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0),
+		proxy.NewNamedProvider(providerAName, backend.URL),
+		proxy.NewNamedProvider(providerBName, backend.URL),
+	)
+	spread := routingRule("spread", ruleMatch("claude-sonnet"))
+	spread.Strategy = router.StrategyRoundRobin
+	handler.SetRuntimeConfigGetter(config.NewRuntime(rulesConfig(spread)))
+
+	served := make(map[string]int)
+	for range 4 {
+		rr := serveJSONMessagesBody(t, handler, failoverRequestBody)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, router.StrategyRoundRobin, rr.Header().Get("X-CC-Relay-Strategy"))
+		served[rr.Header().Get("X-CC-Relay-Provider")]++
+	}
+	assert.Equal(t, map[string]int{providerAName: 2, providerBName: 2}, served,
+		"the rule's round_robin router keeps its state across requests")
+}
+
+func TestHandlerRoutingRuleRewritesModel(t *testing.T) {
+	t.Parallel()
+
+	backend, recorder := proxy.NewRecordingBackend(t)
+	handler := newFailoverHandler(t, router.NewFailoverRouter(0), proxy.NewNamedProvider(providerAName, backend.URL))
+	downgrade := routingRule("downgrade", ruleMatch("claude-opus"))
+	downgrade.RewriteModel = "claude-haiku-4-5"
+	handler.SetRuntimeConfigGetter(config.NewRuntime(rulesConfig(downgrade)))
+
+	rr := serveJSONMessagesBody(t, handler, `{"model":"claude-opus-4","max_tokens":10,"messages":[]}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	sent := recorder.Body()
+	assert.Equal(t, "claude-haiku-4-5", gjson.GetBytes(sent, "model").String())
+	assert.Equal(t, int64(10), gjson.GetBytes(sent, "max_tokens").Int(), "the rest of the body is kept")
+}
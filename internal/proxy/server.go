@@ -3,6 +3,7 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"time"
 )
@@ -30,13 +31,16 @@ const (
 // want to override. Negative values are rejected upstream by config.Validate;
 // the <= 0 fallback here is defense-in-depth, not the user contract.
 //
-// Field order: pointer-bearing fields first (Handler, then Addr), then int64
-// timeouts, then bool. This satisfies govet fieldalignment by keeping the GC
-// scan window to 24 pointer bytes.
+// Field order: pointer-bearing fields first (Handler, TLSConfig, then Addr),
+// then int64 timeouts, then bool. This satisfies govet fieldalignment by
+// keeping the GC scan window to 32 pointer bytes.
 type ServerOptions struct {
 	// Handler is the root HTTP handler (mux + middleware chain).
 	Handler http.Handler
 
+	// TLSConfig enables TLS termination when non-nil. See TLSReloader.
+	TLSConfig *tls.Config
+
 	// Addr is the TCP listen address (e.g., "127.0.0.1:8787").
 	Addr string
 
@@ -53,8 +57,8 @@ type ServerOptions struct {
 	// defaultIdleTimeout (120s).
 	IdleTimeout time.Duration
 
-	// EnableHTTP2 enables HTTP/2 cleartext (h2c). Recommended for Claude Code's
-	// concurrent tool calls.
+	// EnableHTTP2 enables HTTP/2: cleartext (h2c), or over TLS when TLSConfig
+	// is set. Recommended for Claude Code's concurrent tool calls.
 	EnableHTTP2 bool
 }
 
@@ -74,7 +78,8 @@ type Server struct {
 //
 // When EnableHTTP2 is true, the server advertises unencrypted HTTP/2 (h2c) via
 // http.Server.Protocols (Go 1.24+), giving Claude Code's parallel tool calls
-// proper stream multiplexing. HTTP/1.1 stays enabled for legacy clients.
+// proper stream multiplexing. HTTP/1.1 stays enabled for legacy clients. With
+// TLSConfig set, HTTP/2 is negotiated over TLS instead of h2c.
 func NewServer(opts ServerOptions) *Server {
 	readTimeout := opts.ReadTimeout
 	if readTimeout <= 0 {
@@ -95,8 +100,15 @@ func NewServer(opts ServerOptions) *Server {
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 		IdleTimeout:  idleTimeout,
+		TLSConfig:    opts.TLSConfig,
 	}
-	if opts.EnableHTTP2 {
+	switch {
+	case opts.TLSConfig != nil:
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(opts.EnableHTTP2)
+		httpServer.Protocols = protocols
+	case opts.EnableHTTP2:
 		protocols := new(http.Protocols)
 		protocols.SetHTTP1(true)
 		protocols.SetUnencryptedHTTP2(true)
@@ -109,8 +121,12 @@ func NewServer(opts ServerOptions) *Server {
 	}
 }
 
-// ListenAndServe starts the server (blocks).
+// ListenAndServe starts the server (blocks), serving TLS when configured.
 func (s *Server) ListenAndServe() error {
+	if s.httpServer.TLSConfig != nil {
+		// Certificates come from TLSConfig, not files.
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
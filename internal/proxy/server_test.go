@@ -25,12 +25,13 @@ func newServerTestHandler() http.Handler {
 }
 
 // defaultServerOptions returns a ServerOptions with zero-valued timeouts and
-// HTTP2 disabled. Tests that only care about a subset of fields can use this
+// HTTP2 and TLS disabled. Tests that only care about a subset of fields can use this
 // to satisfy the exhaustruct linter without repeating boilerplate.
 func defaultServerOptions(addr string, handler http.Handler) proxy.ServerOptions {
 	return proxy.ServerOptions{
 		Addr:         addr,
 		Handler:      handler,
+		TLSConfig:    nil,
 		WriteTimeout: 0,
 		ReadTimeout:  0,
 		IdleTimeout:  0,
@@ -0,0 +1,242 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const (
+	// sessionKeyPrefix namespaces session pins in the shared cache.
+	sessionKeyPrefix = "session:"
+
+	// sessionIDLen is the number of hex characters kept from the session hash.
+	sessionIDLen = 32
+)
+
+// SessionStore remembers which provider and key served each session, so
+// session affinity can send the next turn of a conversation to the same place.
+// Uses cc-relay's cache.Cache interface for storage, so pins are shared
+// between instances in HA mode.
+type SessionStore struct {
+	cache cache.Cache
+}
+
+// NewSessionStore creates a session store using the provided cache backend.
+// Returns nil if the cache is nil (no-op mode).
+func NewSessionStore(c cache.Cache) *SessionStore {
+	if c == nil {
+		return nil
+	}
+	return &SessionStore{cache: c}
+}
+
+// sessionPin is the provider and key a session is pinned to.
+type sessionPin struct {
+	Provider string `json:"provider"`
+	KeyID    string `json:"key_id,omitempty"`
+}
+
+// get returns the pin for session id. Misses and cache errors both report false.
+func (s *SessionStore) get(ctx context.Context, id string) (sessionPin, bool) {
+	var pin sessionPin
+	data, err := s.cache.Get(ctx, sessionKeyPrefix+id)
+	if err != nil || json.Unmarshal(data, &pin) != nil || pin.Provider == "" {
+		return sessionPin{Provider: "", KeyID: ""}, false
+	}
+	return pin, true
+}
+
+// set pins session id for ttl. Errors are logged, since a lost pin only costs
+// a prompt cache miss.
+func (s *SessionStore) set(ctx context.Context, id string, pin sessionPin, ttl time.Duration) {
+	data, err := json.Marshal(pin)
+	if err == nil {
+		err = s.cache.SetWithTTL(ctx, sessionKeyPrefix+id, data, ttl)
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("failed to store session pin")
+	}
+}
+
+// session is the conversation a request belongs to and where it was last served.
+type session struct {
+	pin    sessionPin
+	id     string
+	pinned bool
+}
+
+// resolveSession identifies the request's session and looks up its pin when
+// session affinity is enabled. The session is stored in the request context
+// for provider and key selection.
+func (h *Handler) resolveSession(request *http.Request) *http.Request {
+	routingConfig := h.getRoutingConfig()
+	if h.sessions == nil || routingConfig == nil || !routingConfig.SessionAffinity.Enabled {
+		return request
+	}
+
+	id := sessionID(request, routingConfig.SessionAffinity.Header)
+	if id == "" {
+		return request
+	}
+	pin, pinned := h.sessions.get(request.Context(), id)
+	sess := &session{pin: pin, id: id, pinned: pinned}
+	return request.WithContext(context.WithValue(request.Context(), sessionContextKey, sess))
+}
+
+// sessionFromContext returns the request's session, if it has one.
+func sessionFromContext(ctx context.Context) (*session, bool) {
+	sess, ok := ctx.Value(sessionContextKey).(*session)
+	return sess, ok && sess != nil
+}
+
+// sessionID derives the session ID from the header named by header if the
+// request carries it, otherwise from the system prompt and first user message.
+// Both are scoped to the authenticated client. Returns "" if the request has
+// no user message to identify it by.
+func sessionID(request *http.Request, header string) string {
+	clientName := ""
+	if client := auth.ClientFromContext(request.Context()); client != nil {
+		clientName = client.Name
+	}
+	if header != "" {
+		if value := request.Header.Get(header); value != "" {
+			return hashSession("header", clientName, value)
+		}
+	}
+	if request.Body == nil {
+		return ""
+	}
+
+	body, err := readBody(request)
+	if err != nil {
+		return ""
+	}
+
+	firstUser := contentText(gjson.GetBytes(body, `messages.#(role=="user").content`))
+	if firstUser == "" {
+		return ""
+	}
+	return hashSession("prompt", clientName, contentText(gjson.GetBytes(body, "system")), firstUser)
+}
+
+// contentText returns the text of a string or content block array. Only text
+// is used, since clients move cache_control markers between turns.
+func contentText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return content.String()
+	}
+	var texts []string
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() == "text" {
+			texts = append(texts, block.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(texts, "\n")
+}
+
+func hashSession(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(hash[:])[:sessionIDLen]
+}
+
+// sessionProvider returns the candidate the request's session is pinned to,
+// if it is still a healthy candidate. Otherwise the request is routed
+// normally and the session is pinned to wherever it is served.
+func sessionProvider(ctx context.Context, candidates []router.ProviderInfo) (router.ProviderInfo, bool) {
+	sess, ok := sessionFromContext(ctx)
+	if !ok || !sess.pinned {
+		return router.ProviderInfo{}, false
+	}
+	for _, candidate := range candidates {
+		if candidate.Provider.Name() == sess.pin.Provider && candidate.Healthy() {
+			return candidate, true
+		}
+	}
+	return router.ProviderInfo{}, false
+}
+
+// sessionAffinity describes how session affinity applied to a request sent to
+// providerName: "pinned" if it went where its session is pinned, "new" if the
+// session is being pinned, or "" without a session.
+func sessionAffinity(ctx context.Context, providerName string) string {
+	sess, ok := sessionFromContext(ctx)
+	switch {
+	case !ok:
+		return ""
+	case sess.pinned && sess.pin.Provider == providerName:
+		return "pinned"
+	default:
+		return "new"
+	}
+}
+
+// pinSessionFirst moves the provider the session is pinned to to the front of
+// a failover order, keeping the rest as fallbacks.
+func pinSessionFirst(ctx context.Context, order []router.ProviderInfo) []router.ProviderInfo {
+	pinned, ok := sessionProvider(ctx, order)
+	if !ok {
+		return order
+	}
+	reordered := make([]router.ProviderInfo, 0, len(order))
+	reordered = append(reordered, pinned)
+	for _, info := range order {
+		if info.Provider.Name() != pinned.Provider.Name() {
+			reordered = append(reordered, info)
+		}
+	}
+	return reordered
+}
+
+// preferSessionKey asks the key pool for the key the session is pinned to
+// when the request is going to the session's provider.
+func preferSessionKey(ctx context.Context) context.Context {
+	sess, ok := sessionFromContext(ctx)
+	if !ok || !sess.pinned || sess.pin.KeyID == "" {
+		return ctx
+	}
+	if providerName, named := ctx.Value(providerNameContextKey).(string); !named || providerName != sess.pin.Provider {
+		return ctx
+	}
+	return keypool.WithPreferredKey(ctx, sess.pin.KeyID)
+}
+
+// rememberSession pins the response's session to the provider and key that
+// served it. Every successful turn renews the pin's TTL. Hedged attempts are
+// pinned by serveHedged once the race is decided, so a cancelled loser does
+// not take the pin.
+func (h *Handler) rememberSession(resp *http.Response) {
+	if _, hedged := resp.Request.Context().Value(hedgeRoleContextKey).(string); hedged {
+		return
+	}
+	h.pinSession(resp.Request.Context())
+}
+
+// pinSession pins the session in ctx to the provider and key in ctx.
+func (h *Handler) pinSession(ctx context.Context) {
+	sess, ok := sessionFromContext(ctx)
+	routingConfig := h.getRoutingConfig()
+	if !ok || h.sessions == nil || routingConfig == nil {
+		return
+	}
+	providerName, named := ctx.Value(providerNameContextKey).(string)
+	if !named || providerName == "" {
+		return
+	}
+	keyID, _ := ctx.Value(keyIDContextKey).(string)
+	h.sessions.set(ctx, sess.id, sessionPin{Provider: providerName, KeyID: keyID},
+		routingConfig.SessionAffinity.GetTTL())
+}
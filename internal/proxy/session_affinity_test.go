@@ -0,0 +1,217 @@
+package proxy_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/keypool"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const (
+	sessionHeader = "X-CC-Relay-Session-Affinity"
+
+	firstTurnBody = `{"model":"claude-sonnet-4","system":"You are a coding agent.",` +
+		`"messages":[{"role":"user","content":"fix the tests"}]}`
+
+	// The next turn repeats the first user message with a cache_control marker.
+	secondTurnBody = `{"model":"claude-sonnet-4","system":[{"type":"text","text":"You are a coding agent."}],` +
+		`"messages":[{"role":"user","content":[{"type":"text","text":"fix the tests",` +
+		`"cache_control":{"type":"ephemeral"}}]},{"role":"assistant","content":"done"},` +
+		`{"role":"user","content":"thanks"}]}`
+
+	otherSessionBody = `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"write docs"}]}`
+)
+
+// mapCache is a synchronous cache.Cache, so pins are visible to the next request.
+type mapCache struct {
+	values map[string][]byte
+	mu     sync.Mutex
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{values: make(map[string][]byte), mu: sync.Mutex{}}
+}
+
+func (c *mapCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c *mapCache) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *mapCache) SetWithTTL(ctx context.Context, key string, value []byte, _ time.Duration) error {
+	return c.Set(ctx, key, value)
+}
+
+func (c *mapCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *mapCache) Exists(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.values[key]
+	return ok, nil
+}
+
+func (c *mapCache) Close() error { return nil }
+
+func sessionConfig(header string) *config.Config {
+	cfg := proxy.TestConfig("")
+	cfg.Routing.Debug = true
+	cfg.Routing.SessionAffinity = config.SessionAffinityConfig{Header: header, TTLMS: 0, Enabled: true}
+	return cfg
+}
+
+func newSessionHandler(t *testing.T, rtr router.ProviderRouter, infos ...router.ProviderInfo) *proxy.Handler {
+	t.Helper()
+
+	keys := make(map[string]string, len(infos))
+	for _, info := range infos {
+		keys[info.Provider.Name()] = testKey
+	}
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = infos[0].Provider
+	opts.ProviderInfos = infos
+	opts.ProviderRouter = rtr
+	opts.ProviderKeys = keys
+	opts.RoutingDebug = true
+	opts.Sessions = proxy.NewSessionStore(newMapCache())
+
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+	handler.SetRuntimeConfigGetter(config.NewRuntime(sessionConfig("")))
+	return handler
+}
+
+func namedProviders(t *testing.T) (providerA, providerB providers.Provider) {
+	t.Helper()
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	return proxy.NewNamedProvider(providerAName, backend.URL), proxy.NewNamedProvider(providerBName, backend.URL)
+}
+
+func TestHandlerSessionAffinityPinsProvider(t *testing.T) {
+	t.Parallel()
+
+	providerA, providerB := namedProviders(t)
+	handler := newSessionHandler(t, router.NewRoundRobinRouter(),
+		proxy.TestProviderInfo(providerA), proxy.TestProviderInfo(providerB))
+
+	first := serveJSONMessagesBody(t, handler, firstTurnBody)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "new", first.Header().Get(sessionHeader))
+	pinned := first.Header().Get("X-CC-Relay-Provider")
+
+	for range 3 {
+		rr := serveJSONMessagesBody(t, handler, secondTurnBody)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, pinned, rr.Header().Get("X-CC-Relay-Provider"), "later turns stay on the pinned provider")
+		assert.Equal(t, "pinned", rr.Header().Get(sessionHeader))
+	}
+
+	rr := serveJSONMessagesBody(t, handler, otherSessionBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "new", rr.Header().Get(sessionHeader), "another conversation is a new session")
+}
+
+func TestHandlerSessionAffinityFallsBackWhenUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	var healthyA atomic.Bool
+	healthyA.Store(true)
+	providerA, providerB := namedProviders(t)
+	infoA := proxy.TestProviderInfoWithHealth(providerA, healthyA.Load)
+	infoA.Priority = 2
+	handler := newSessionHandler(t, router.NewFailoverRouter(0), infoA, proxy.TestProviderInfo(providerB))
+
+	rr := serveJSONMessagesBody(t, handler, firstTurnBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, providerAName, rr.Header().Get("X-CC-Relay-Provider"))
+
+	healthyA.Store(false)
+	rr = serveJSONMessagesBody(t, handler, secondTurnBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "new", rr.Header().Get(sessionHeader))
+
+	// The session is now pinned to the provider that took over
+	healthyA.Store(true)
+	rr = serveJSONMessagesBody(t, handler, secondTurnBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, providerBName, rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "pinned", rr.Header().Get(sessionHeader))
+}
+
+func TestHandlerSessionAffinityHeader(t *testing.T) {
+	t.Parallel()
+
+	providerA, providerB := namedProviders(t)
+	handler := newSessionHandler(t, router.NewRoundRobinRouter(),
+		proxy.TestProviderInfo(providerA), proxy.TestProviderInfo(providerB))
+	handler.SetRuntimeConfigGetter(config.NewRuntime(sessionConfig("X-Session-ID")))
+
+	contentType := proxy.HeaderPair{Key: proxy.ContentTypeHeader, Value: proxy.JSONContentType}
+	session := proxy.HeaderPair{Key: "X-Session-ID", Value: "abc"}
+	first := proxy.ServeRequest(t, handler, proxy.NewMessagesRequestWithHeaders(firstTurnBody, contentType, session))
+	require.Equal(t, http.StatusOK, first.Code)
+
+	rr := proxy.ServeRequest(t, handler, proxy.NewMessagesRequestWithHeaders(otherSessionBody, contentType, session))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, first.Header().Get("X-CC-Relay-Provider"), rr.Header().Get("X-CC-Relay-Provider"))
+	assert.Equal(t, "pinned", rr.Header().Get(sessionHeader), "the header identifies the session")
+}
+
+func TestHandlerSessionAffinityPinsKey(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewJSONBackend(t, `{"id":"msg_1"}`)
+	pool, err := keypool.NewKeyPool(testProviderName, keypool.PoolConfig{
+		Strategy: keypool.StrategyRoundRobin,
+		Keys:     []keypool.KeyConfig{proxy.TestKeyConfig("sk-key-1"), proxy.TestKeyConfig("sk-key-2")},
+	})
+	require.NoError(t, err)
+
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = proxy.NewNamedProvider(testProviderName, backend.URL)
+	opts.Pool = pool
+	opts.Sessions = proxy.NewSessionStore(newMapCache())
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+	handler.SetRuntimeConfigGetter(config.NewRuntime(sessionConfig("")))
+
+	first := serveJSONMessagesBody(t, handler, firstTurnBody)
+	require.Equal(t, http.StatusOK, first.Code)
+	keyID := first.Header().Get(proxy.HeaderRelayKeyID)
+	require.NotEmpty(t, keyID)
+
+	for range 3 {
+		rr := serveJSONMessagesBody(t, handler, secondTurnBody)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, keyID, rr.Header().Get(proxy.HeaderRelayKeyID), "later turns use the pinned key")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/gemini"
 	"github.com/rs/zerolog/log"
 )
 
@@ -24,7 +25,7 @@ const (
 	MinSignatureLen = 50
 
 	// GeminiSignatureSentinel is a special sentinel value for Gemini models.
-	GeminiSignatureSentinel = "skip_thought_signature_validator"
+	GeminiSignatureSentinel = gemini.SkipThoughtSignature
 )
 
 // GetModelGroup returns the model group for signature sharing.
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// SSE event and delta types carrying thinking content.
+const (
+	sseEventContentBlockStart = "content_block_start"
+	sseEventContentBlockDelta = "content_block_delta"
+	sseEventContentBlockStop  = "content_block_stop"
+	deltaTypeThinking         = "thinking_delta"
+	deltaTypeSignature        = "signature_delta"
+)
+
+var contentBlockMarker = []byte(`"content_block_`)
+
+// thinkingBlockCapture accumulates a single streamed thinking block.
+type thinkingBlockCapture struct {
+	signature string
+	text      strings.Builder
+}
+
+// signatureTapBody passes an SSE response body through unchanged while
+// recording completed thinking blocks in the SignatureCache.
+// Parsing happens inline on bytes already handed to the caller, so the
+// stream is never held back waiting for a block to finish.
+type signatureTapBody struct {
+	original io.ReadCloser
+	ctx      context.Context
+	cache    *SignatureCache
+	blocks   map[int64]*thinkingBlockCapture
+	model    string
+	lines    sseLineSplitter
+}
+
+// newSignatureTapBody wraps body so thinking signatures for model are cached as they stream.
+func newSignatureTapBody(
+	ctx context.Context, body io.ReadCloser, cache *SignatureCache, model string,
+) *signatureTapBody {
+	return &signatureTapBody{
+		original: body,
+		ctx:      ctx,
+		cache:    cache,
+		blocks:   make(map[int64]*thinkingBlockCapture),
+		model:    model,
+		lines:    sseLineSplitter{pending: nil},
+	}
+}
+
+// Read implements io.Reader, inspecting each chunk after it is read.
+func (t *signatureTapBody) Read(p []byte) (int, error) {
+	n, err := t.original.Read(p)
+	if n > 0 {
+		t.consume(p[:n])
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (t *signatureTapBody) Close() error {
+	return t.original.Close()
+}
+
+// consume feeds a chunk of the stream to the line splitter.
+func (t *signatureTapBody) consume(chunk []byte) {
+	t.lines.feed(chunk, t.handlePayload)
+}
+
+// handlePayload processes a single SSE data payload, ignoring everything but content blocks.
+func (t *signatureTapBody) handlePayload(payload []byte) {
+	// Fast path: pings and message-level events are skipped without parsing.
+	if !bytes.Contains(payload, contentBlockMarker) {
+		return
+	}
+
+	event := gjson.ParseBytes(payload)
+	index := event.Get("index").Int()
+
+	switch event.Get("type").String() {
+	case sseEventContentBlockStart:
+		block := event.Get("content_block")
+		if block.Get("type").String() != blockTypeThinking {
+			return
+		}
+		capture := &thinkingBlockCapture{signature: block.Get("signature").String(), text: strings.Builder{}}
+		capture.text.WriteString(block.Get("thinking").String())
+		t.blocks[index] = capture
+
+	case sseEventContentBlockDelta:
+		capture, ok := t.blocks[index]
+		if !ok {
+			return
+		}
+		delta := event.Get("delta")
+		switch delta.Get("type").String() {
+		case deltaTypeThinking:
+			capture.text.WriteString(delta.Get("thinking").String())
+		case deltaTypeSignature:
+			capture.signature = delta.Get("signature").String()
+		}
+
+	case sseEventContentBlockStop:
+		capture, ok := t.blocks[index]
+		if !ok {
+			return
+		}
+		delete(t.blocks, index)
+		if capture.signature == "" || capture.text.Len() == 0 {
+			return
+		}
+		t.cache.Set(t.ctx, t.model, capture.text.String(), capture.signature)
+	}
+}
+
+// cacheJSONSignatures records the signed thinking blocks of a non-streaming
+// response body in cache. A body closed before it was read to the end is
+// incomplete, so nothing is recorded from it.
+func cacheJSONSignatures(ctx context.Context, cache *SignatureCache, model string, body []byte, complete bool) {
+	if !complete {
+		return
+	}
+	gjson.GetBytes(body, "content").ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() != blockTypeThinking {
+			return true
+		}
+		thinking, signature := block.Get("thinking").String(), block.Get("signature").String()
+		if thinking != "" && signature != "" {
+			cache.Set(ctx, model, thinking, signature)
+		}
+		return true
+	})
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tapTestSignature = "sig_0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOP"
+
+const tapThinkingStream = "event: message_start\n" +
+	`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4"}}` + "\n\n" +
+	"event: content_block_start\n" +
+	`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}` +
+	"\n\n" +
+	"event: content_block_delta\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}` + "\n\n" +
+	"event: content_block_delta\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"reason."}}` + "\n\n" +
+	"event: content_block_delta\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"` +
+	tapTestSignature + `"}}` + "\n\n" +
+	"event: content_block_stop\n" +
+	`data: {"type":"content_block_stop","index":0}` + "\n\n" +
+	"event: content_block_start\n" +
+	`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}` + "\n\n" +
+	"event: content_block_delta\n" +
+	`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Answer"}}` + "\n\n" +
+	"event: content_block_stop\n" +
+	`data: {"type":"content_block_stop","index":1}` + "\n\n" +
+	"event: message_stop\n" +
+	`data: {"type":"message_stop"}` + "\n\n"
+
+// chunkedReader returns at most size bytes per Read to exercise line reassembly.
+type chunkedReader struct {
+	data string
+	size int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.data == "" {
+		return 0, io.EOF
+	}
+	n := min(c.size, len(p), len(c.data))
+	copy(p, c.data[:n])
+	c.data = c.data[n:]
+	return n, nil
+}
+
+func TestSignatureTapBodyCachesCompletedThinkingBlock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		chunkSize int
+	}{
+		{name: "tiny chunks", chunkSize: 7},
+		{name: "medium chunks", chunkSize: 64},
+		{name: "single read", chunkSize: 4096},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			sigCache, cleanup := newTestSignatureCache(t)
+			defer cleanup()
+
+			source := io.NopCloser(&chunkedReader{data: tapThinkingStream, size: testCase.chunkSize})
+			tap := newSignatureTapBody(context.Background(), source, sigCache, "claude-sonnet-4")
+
+			out, err := io.ReadAll(tap)
+			require.NoError(t, err)
+			assert.Equal(t, tapThinkingStream, string(out), "stream must pass through unchanged")
+
+			require.Eventually(t, func() bool {
+				return sigCache.Get(context.Background(), "claude-opus-4", "Let me reason.") == tapTestSignature
+			}, time.Second, 10*time.Millisecond, "signature should be cached under the claude model group")
+			assert.Empty(t, tap.blocks, "completed blocks should be released")
+		})
+	}
+}
+
+func TestSignatureTapBodyIgnoresIncompleteBlock(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := newTestSignatureCache(t)
+	defer cleanup()
+
+	// Stream ends before content_block_stop, e.g. client disconnect.
+	truncated := tapThinkingStream[:strings.Index(tapThinkingStream, "event: content_block_stop")]
+	tap := newSignatureTapBody(context.Background(), io.NopCloser(strings.NewReader(truncated)),
+		sigCache, "claude-sonnet-4")
+
+	_, err := io.ReadAll(tap)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond) // Wait for Ristretto
+	assert.Empty(t, sigCache.Get(context.Background(), "claude-sonnet-4", "Let me reason."))
+}
+
+func TestSignatureTapBodyHandlesCRLF(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := newTestSignatureCache(t)
+	defer cleanup()
+
+	crlf := strings.ReplaceAll(tapThinkingStream, "\n", "\r\n")
+	tap := newSignatureTapBody(context.Background(), io.NopCloser(strings.NewReader(crlf)),
+		sigCache, "claude-sonnet-4")
+
+	_, err := io.ReadAll(tap)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return sigCache.Get(context.Background(), "claude-sonnet-4", "Let me reason.") == tapTestSignature
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCacheJSONSignatures(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := newTestSignatureCache(t)
+	defer cleanup()
+
+	body := `{"id":"msg_1","type":"message","content":[` +
+		`{"type":"thinking","thinking":"Let me reason.","signature":"` + tapTestSignature + `"},` +
+		`{"type":"redacted_thinking","data":"opaque"},` +
+		`{"type":"thinking","thinking":"Unsigned.","signature":""},` +
+		`{"type":"text","text":"Answer"}]}`
+	cacheJSONSignatures(context.Background(), sigCache, "claude-sonnet-4", []byte(body), true)
+
+	require.Eventually(t, func() bool {
+		return sigCache.Get(context.Background(), "claude-sonnet-4", "Let me reason.") == tapTestSignature
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, sigCache.Get(context.Background(), "claude-sonnet-4", "Unsigned."))
+}
+
+func TestCacheJSONSignaturesIgnoresIncompleteBody(t *testing.T) {
+	t.Parallel()
+
+	sigCache, cleanup := newTestSignatureCache(t)
+	defer cleanup()
+
+	body := `{"content":[{"type":"thinking","thinking":"Let me reason.","signature":"` + tapTestSignature + `"}]}`
+	cacheJSONSignatures(context.Background(), sigCache, "claude-sonnet-4", []byte(body), false)
+
+	time.Sleep(10 * time.Millisecond) // Wait for Ristretto
+	assert.Empty(t, sigCache.Get(context.Background(), "claude-sonnet-4", "Let me reason."))
+}
+
+func TestSignatureTapBodyDropsOversizedPartialLine(t *testing.T) {
+	t.Parallel()
+
+	tap := newSignatureTapBody(context.Background(), io.NopCloser(strings.NewReader("")), nil, "claude")
+	tap.consume([]byte(strings.Repeat("x", maxPendingSSELine+1)))
+
+	assert.Empty(t, tap.lines.pending)
+}
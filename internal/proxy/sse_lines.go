@@ -0,0 +1,43 @@
+package proxy
+
+import "bytes"
+
+// maxPendingSSELine bounds the partial-line buffer so a malformed stream
+// without newlines cannot grow memory without limit.
+const maxPendingSSELine = 1 << 20
+
+var sseDataPrefix = []byte("data:")
+
+// sseLineSplitter reassembles SSE data lines from arbitrarily sized chunks.
+// Body taps feed it each chunk after handing the bytes to the caller, so the
+// stream itself is never delayed.
+type sseLineSplitter struct {
+	pending []byte
+}
+
+// feed splits chunk into lines, carrying partial lines across calls, and
+// invokes onData with the trimmed payload of every complete "data:" line.
+func (s *sseLineSplitter) feed(chunk []byte, onData func(payload []byte)) {
+	for len(chunk) > 0 {
+		idx := bytes.IndexByte(chunk, '\n')
+		if idx < 0 {
+			if len(s.pending)+len(chunk) > maxPendingSSELine {
+				s.pending = s.pending[:0]
+				return
+			}
+			s.pending = append(s.pending, chunk...)
+			return
+		}
+
+		line := chunk[:idx]
+		if len(s.pending) > 0 {
+			line = append(s.pending, line...)
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if bytes.HasPrefix(line, sseDataPrefix) {
+			onData(bytes.TrimSpace(line[len(sseDataPrefix):]))
+		}
+		s.pending = s.pending[:0]
+		chunk = chunk[idx+1:]
+	}
+}
@@ -6,6 +6,8 @@ import (
 )
 
 type requestTimings struct {
+	// Client is the virtual key client, recorded by auth for the completion log.
+	Client  string
 	Auth    time.Duration
 	Routing time.Duration
 }
@@ -14,6 +16,7 @@ type timingsKey struct{}
 
 func withRequestTimings(ctx context.Context) (context.Context, *requestTimings) {
 	timings := &requestTimings{
+		Client:  "",
 		Auth:    0,
 		Routing: 0,
 	}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/omarluq/cc-relay/internal/config"
+)
+
+// TLSReloader serves the listener's TLS settings and swaps them on config
+// reload, so a renewed certificate or client CA bundle takes effect for new
+// connections without restarting. Established connections keep the settings
+// they were accepted with.
+type TLSReloader struct {
+	current    atomic.Pointer[tls.Config]
+	nextProtos []string
+}
+
+// NewTLSReloader loads the certificate, key and client CAs named by cfg.
+// enableHTTP2 offers HTTP/2 in ALPN alongside HTTP/1.1.
+func NewTLSReloader(cfg config.TLSConfig, enableHTTP2 bool) (*TLSReloader, error) {
+	reloader := &TLSReloader{
+		current:    atomic.Pointer[tls.Config]{},
+		nextProtos: []string{"http/1.1"},
+	}
+	if enableHTTP2 {
+		reloader.nextProtos = []string{"h2", "http/1.1"}
+	}
+	if err := reloader.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload loads the files named by cfg and uses them for new connections.
+// On error the previous settings stay in use. TLS cannot be turned off
+// without restarting the listener.
+func (r *TLSReloader) Reload(cfg config.TLSConfig) error {
+	if !cfg.IsEnabled() {
+		return errors.New("server.tls cannot be disabled without a restart")
+	}
+	tlsConfig, err := buildTLSConfig(cfg, r.nextProtos)
+	if err != nil {
+		return err
+	}
+	r.current.Store(tlsConfig)
+	return nil
+}
+
+// TLSConfig returns the config to serve with. It defers every handshake to
+// the settings loaded last.
+func (r *TLSReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: r.nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+func buildTLSConfig(cfg config.TLSConfig, nextProtos []string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Clean(cfg.CertFile), filepath.Clean(cfg.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   nextProtos,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.GetMinVersion() == config.TLSVersion13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(filepath.Clean(cfg.ClientCAFile))
+	if err != nil {
+		return nil, fmt.Errorf("read client CA file: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client CA file %s contains no PEM certificates", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if cfg.GetClientAuth() == config.TLSClientAuthOptional {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
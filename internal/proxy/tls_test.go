@@ -0,0 +1,194 @@
+package proxy_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/proxy"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cc-relay test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for commonName and its key to dir, returning
+// their paths.
+func (ca *testCA) issue(t *testing.T, dir, commonName string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, commonName+".crt")
+	keyFile = filepath.Join(dir, commonName+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// tlsClient returns a client trusting ca that presents the given certificate,
+// if any. Keep-alives are off so every request makes a new handshake.
+func tlsClient(t *testing.T, ca *testCA, certFile, keyFile string) *http.Client {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tlsConfig := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   tlsConfig,
+		DisableKeepAlives: true,
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+// startTLSServer serves the common name of the verified client certificate.
+func startTLSServer(t *testing.T, reloader *proxy.TLSReloader) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.VerifiedChains) > 0 {
+			_, err := io.WriteString(w, r.TLS.VerifiedChains[0][0].Subject.CommonName)
+			assert.NoError(t, err)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTLSReloaderClientCertificates(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	certFile, keyFile := ca.issue(t, dir, "relay", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "ci-runner", x509.ExtKeyUsageClientAuth)
+
+	reloader, err := proxy.NewTLSReloader(config.TLSConfig{
+		CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "", MinVersion: "",
+	}, true)
+	require.NoError(t, err)
+	server := startTLSServer(t, reloader)
+
+	resp, err := tlsClient(t, ca, clientCert, clientKey).Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "ci-runner", string(body))
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	_, err = tlsClient(t, ca, "", "").Get(server.URL) //nolint:bodyclose // the request fails
+	require.Error(t, err, "a client certificate is required by default")
+}
+
+func TestTLSReloaderReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "relay-1", x509.ExtKeyUsageServerAuth)
+	tlsCfg := config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: "", ClientAuth: "", MinVersion: ""}
+
+	reloader, err := proxy.NewTLSReloader(tlsCfg, false)
+	require.NoError(t, err)
+	server := startTLSServer(t, reloader)
+	client := tlsClient(t, ca, "", "")
+
+	serverName := func() string {
+		resp, getErr := client.Get(server.URL)
+		require.NoError(t, getErr)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, "HTTP/1.1", resp.Proto, "HTTP/2 is not offered when disabled")
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "relay-1", serverName())
+
+	tlsCfg.CertFile, tlsCfg.KeyFile = ca.issue(t, dir, "relay-2", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, reloader.Reload(tlsCfg))
+	assert.Equal(t, "relay-2", serverName(), "new connections use the reloaded certificate")
+
+	missing := tlsCfg
+	missing.CertFile = filepath.Join(dir, "missing.crt")
+	require.Error(t, reloader.Reload(missing))
+	require.Error(t, reloader.Reload(config.TLSConfig{
+		CertFile: "", KeyFile: "", ClientCAFile: "", ClientAuth: "", MinVersion: "",
+	}), "TLS cannot be turned off by a reload")
+	assert.Equal(t, "relay-2", serverName(), "a failed reload keeps the previous certificate")
+}
+
+func TestNewTLSReloaderInvalidClientCA(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := newTestCA(t).issue(t, dir, "relay", x509.ExtKeyUsageServerAuth)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+	_, err := proxy.NewTLSReloader(config.TLSConfig{
+		CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "", MinVersion: "",
+	}, false)
+	require.ErrorContains(t, err, "contains no PEM certificates")
+}
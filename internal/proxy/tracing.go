@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/tracing"
+)
+
+// Span names for the request pipeline stages.
+const (
+	spanAuth           = "auth"
+	spanExtractModel   = "extract_model"
+	spanThinking       = "process_thinking"
+	spanSelectProvider = "select_provider"
+	spanSelectKey      = "select_key"
+	spanUpstream       = "upstream"
+	spanStream         = "stream"
+)
+
+// Span attribute keys specific to cc-relay.
+const (
+	attrRequestID        = attribute.Key("cc_relay.request_id")
+	attrAuthType         = attribute.Key("cc_relay.auth.type")
+	attrClient           = attribute.Key("cc_relay.client")
+	attrProvider         = attribute.Key("cc_relay.provider")
+	attrStrategy         = attribute.Key("cc_relay.routing.strategy")
+	attrCandidates       = attribute.Key("cc_relay.routing.candidates")
+	attrThinkingAffinity = attribute.Key("cc_relay.routing.thinking_affinity")
+	attrRoutingRule      = attribute.Key("cc_relay.routing.rule")
+	attrInputTokens      = attribute.Key("cc_relay.request.input_tokens_estimate")
+	attrKeyID            = attribute.Key("cc_relay.key_id")
+	attrDroppedBlocks    = attribute.Key("cc_relay.thinking.dropped_blocks")
+	attrReorderedBlocks  = attribute.Key("cc_relay.thinking.reordered_blocks")
+	attrFailoverTrigger  = attribute.Key("cc_relay.failover.trigger")
+	attrAttempt          = attribute.Key("cc_relay.failover.attempt")
+	attrHedgeDelayMS     = attribute.Key("cc_relay.hedge.delay_ms")
+	attrHedgeOutcome     = attribute.Key("cc_relay.hedge.outcome")
+	attrResponseCache    = attribute.Key("cc_relay.response_cache")
+	attrDNSTimeMS        = attribute.Key("cc_relay.upstream.dns_ms")
+	attrConnectTimeMS    = attribute.Key("cc_relay.upstream.connect_ms")
+	attrTLSTimeMS        = attribute.Key("cc_relay.upstream.tls_handshake_ms")
+	attrTLSResumed       = attribute.Key("tls.resumed")
+	attrStreamBytes      = attribute.Key("cc_relay.stream.bytes")
+)
+
+// TracingMiddleware starts the server span for each request, continuing the
+// client's trace when it sends a W3C traceparent header. The trace ID is added
+// to the request logger so log lines can be joined with traces.
+func TracingMiddleware(tracer *tracing.Tracing) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			ctx, span := tracer.StartServer(request)
+			defer span.End()
+
+			if requestID := GetRequestID(ctx); requestID != "" {
+				span.SetAttributes(attrRequestID.String(requestID))
+			}
+			if spanCtx := span.SpanContext(); spanCtx.IsValid() {
+				logger := zerolog.Ctx(ctx).With().Str("trace_id", spanCtx.TraceID().String()).Logger()
+				ctx = logger.WithContext(ctx)
+			}
+
+			observed := newObservedWriter(writer)
+			next.ServeHTTP(observed, request.WithContext(ctx))
+
+			status := observed.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
+
+// endSpan ends span, marking it failed when err is non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordSpanError marks the span in ctx failed, e.g. on upstream transport errors.
+func recordSpanError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// recordTLSTiming adds connection timing collected by AttachTLSTrace to the upstream span.
+func recordTLSTiming(span trace.Span, getTLSMetrics func() TLSMetrics) {
+	if getTLSMetrics == nil {
+		return
+	}
+	tlsMetrics := getTLSMetrics()
+	if tlsMetrics.DNSTime > 0 {
+		span.SetAttributes(attrDNSTimeMS.Float64(durationMS(tlsMetrics.DNSTime)))
+	}
+	if tlsMetrics.ConnectTime > 0 {
+		span.SetAttributes(attrConnectTimeMS.Float64(durationMS(tlsMetrics.ConnectTime)))
+	}
+	if tlsMetrics.HasMetrics {
+		span.SetAttributes(
+			attrTLSTimeMS.Float64(durationMS(tlsMetrics.TLSTime)),
+			semconv.TLSProtocolVersion(tlsMetrics.Version),
+			attrTLSResumed.Bool(tlsMetrics.Reused),
+		)
+	}
+}
+
+// durationMS converts d to fractional milliseconds.
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// traceUpstreamResponse records the provider's status on the upstream span.
+func traceUpstreamResponse(resp *http.Response) {
+	span := trace.SpanFromContext(resp.Request.Context())
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+}
+
+// traceStream wraps successful SSE bodies in a span that lasts until the
+// stream has been fully relayed to the client.
+func traceStream(resp *http.Response) {
+	if resp.Body == nil || resp.StatusCode != http.StatusOK {
+		return
+	}
+	ctx := resp.Request.Context()
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != providers.ContentTypeSSE {
+		return
+	}
+	resp.Body = newStreamSpanBody(ctx, resp.Body)
+}
+
+// streamSpanBody ends its span when the stream is closed.
+type streamSpanBody struct {
+	body  io.ReadCloser
+	span  trace.Span
+	bytes int64
+	ended bool
+}
+
+func newStreamSpanBody(ctx context.Context, body io.ReadCloser) *streamSpanBody {
+	_, span := tracing.Start(ctx, spanStream)
+	return &streamSpanBody{body: body, span: span, bytes: 0, ended: false}
+}
+
+// Read counts the bytes relayed to the client.
+func (b *streamSpanBody) Read(data []byte) (int, error) {
+	n, err := b.body.Read(data)
+	b.bytes += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.span.RecordError(err)
+		b.span.SetStatus(codes.Error, err.Error())
+	}
+	return n, err
+}
+
+// Close ends the stream span.
+func (b *streamSpanBody) Close() error {
+	if !b.ended {
+		b.ended = true
+		b.span.SetAttributes(attrStreamBytes.Int64(b.bytes))
+		b.span.End()
+	}
+	return b.body.Close()
+}
@@ -0,0 +1,186 @@
+package proxy_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/providers"
+	"github.com/omarluq/cc-relay/internal/proxy"
+	"github.com/omarluq/cc-relay/internal/router"
+	"github.com/omarluq/cc-relay/internal/tracing"
+)
+
+const (
+	clientTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	clientParentID    = "00f067aa0ba902b7"
+	clientTraceparent = "00-" + clientTraceID + "-" + clientParentID + "-01"
+)
+
+func newTestTracing(recorder *tracetest.SpanRecorder) *tracing.Tracing {
+	return tracing.NewWithProcessor(&config.TracingConfig{
+		Headers:     nil,
+		Endpoint:    "",
+		Protocol:    "",
+		ServiceName: "",
+		SampleRatio: 0,
+		Enabled:     true,
+		Insecure:    false,
+	}, recorder)
+}
+
+func newTracedRoutes(
+	t *testing.T, cfg *config.Config, provider providers.Provider, tracer *tracing.Tracing,
+) http.Handler {
+	t.Helper()
+	opts := proxy.TestRoutesOptions(nil)
+	opts.ConfigProvider = config.NewRuntime(cfg)
+	opts.Provider = provider
+	opts.AllProviders = []providers.Provider{provider}
+	opts.Tracing = tracer
+	handler, err := proxy.SetupRoutesWithLiveKeyPools(&opts)
+	require.NoError(t, err)
+	return handler
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	byName := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+	return byName
+}
+
+func TestTracingContinuesClientTrace(t *testing.T) {
+	t.Parallel()
+
+	backend, captured := proxy.NewHeaderCaptureBackend(t)
+	recorder := tracetest.NewSpanRecorder()
+	handler := newTracedRoutes(t, proxy.TestConfig(testAPIKey), proxy.NewTestProvider(backend.URL),
+		newTestTracing(recorder))
+
+	req := proxy.NewMessagesRequestWithHeaders(metricsRequestBody,
+		proxy.HeaderPair{Key: "x-api-key", Value: testAPIKey},
+		proxy.HeaderPair{Key: "traceparent", Value: clientTraceparent},
+	)
+	rec := proxy.ServeRequest(t, handler, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	spans := spansByName(recorder.Ended())
+	for _, name := range []string{"auth", "extract_model", "select_provider", "upstream"} {
+		assert.Contains(t, spans, name)
+	}
+
+	server, ok := spans["POST /v1/messages"]
+	require.True(t, ok, "server span is named after the route")
+	assert.Equal(t, clientTraceID, server.SpanContext().TraceID().String())
+	assert.Equal(t, clientParentID, server.Parent().SpanID().String())
+
+	upstream := spans["upstream"]
+	assert.Equal(t, server.SpanContext().SpanID(), upstream.Parent().SpanID())
+	assert.Equal(t, "00-"+clientTraceID+"-"+upstream.SpanContext().SpanID().String()+"-01",
+		captured.Get("traceparent"), "provider sees the upstream span as parent")
+}
+
+func TestTracingDisabledForwardsClientTraceparent(t *testing.T) {
+	t.Parallel()
+
+	backend, captured := proxy.NewHeaderCaptureBackend(t)
+	handler := newTracedRoutes(t, proxy.TestConfig(""), proxy.NewTestProvider(backend.URL), nil)
+
+	req := proxy.NewMessagesRequestWithHeaders(metricsRequestBody,
+		proxy.HeaderPair{Key: "traceparent", Value: clientTraceparent})
+	rec := proxy.ServeRequest(t, handler, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, clientTraceparent, captured.Get("traceparent"))
+}
+
+func TestTracingRecordsAuthFailure(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewBackendServer(t, `{"ok":true}`)
+	recorder := tracetest.NewSpanRecorder()
+	handler := newTracedRoutes(t, proxy.TestConfig(testAPIKey), proxy.NewTestProvider(backend.URL),
+		newTestTracing(recorder))
+
+	rec := proxy.ServeRequest(t, handler, proxy.NewMessagesRequestWithHeaders(metricsRequestBody))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	spans := spansByName(recorder.Ended())
+	assert.Equal(t, codes.Error, spans["auth"].Status().Code)
+	assert.NotEqual(t, codes.Error, spans["POST /v1/messages"].Status().Code,
+		"client errors do not fail the server span")
+	assert.NotContains(t, spans, "upstream")
+}
+
+func TestTracingRecordsStreamSpan(t *testing.T) {
+	t.Parallel()
+
+	backend := proxy.NewStatusBackend(t, http.StatusOK, "event: ping\ndata: {\"type\":\"ping\"}\n\n",
+		map[string]string{"Content-Type": providers.ContentTypeSSE})
+	recorder := tracetest.NewSpanRecorder()
+	handler := newTracedRoutes(t, proxy.TestConfig(""), proxy.NewTestProvider(backend.URL),
+		newTestTracing(recorder))
+
+	rec := proxy.ServeRequest(t, handler, proxy.NewMessagesRequestWithHeaders(metricsRequestBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	spans := spansByName(recorder.Ended())
+	stream, ok := spans["stream"]
+	require.True(t, ok)
+	assert.Equal(t, spans["upstream"].SpanContext().SpanID(), stream.Parent().SpanID())
+	assert.Contains(t, stream.Attributes(), attribute.Int64("cc_relay.stream.bytes", int64(rec.Body.Len())))
+}
+
+func TestTracingRecordsFailover(t *testing.T) {
+	t.Parallel()
+
+	failing := proxy.NewStatusBackend(t, http.StatusServiceUnavailable, `{"error":"overloaded"}`, nil)
+	healthy := proxy.NewJSONBackend(t, `{"id":"msg_ok"}`)
+	providerA := proxy.NewNamedProvider(providerAName, failing.URL)
+	providerB := proxy.NewNamedProvider(providerBName, healthy.URL)
+
+	infoA := proxy.TestProviderInfo(providerA)
+	infoA.Priority = 2
+	infoB := proxy.TestProviderInfo(providerB)
+	infoB.Priority = 1
+
+	opts := proxy.TestHandlerOptions(nil)
+	opts.Provider = providerA
+	opts.ProviderInfos = []router.ProviderInfo{infoA, infoB}
+	opts.ProviderRouter = router.NewFailoverRouter(0)
+	opts.ProviderKeys = map[string]string{providerAName: testKey, providerBName: testKey}
+	handler, err := proxy.NewHandler(opts)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	traced := proxy.TracingMiddleware(newTestTracing(recorder))(handler)
+	rr := serveJSONMessagesBody(t, traced, metricsRequestBody)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var upstreamStatuses []codes.Code
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "upstream":
+			upstreamStatuses = append(upstreamStatuses, span.Status().Code)
+		case "POST /v1/messages":
+			server = span
+		}
+	}
+	assert.ElementsMatch(t, []codes.Code{codes.Error, codes.Unset}, upstreamStatuses,
+		"one upstream span per attempt")
+	require.NotNil(t, server)
+	require.Len(t, server.Events(), 1)
+	event := server.Events()[0]
+	assert.Equal(t, "failover", event.Name)
+	assert.Contains(t, event.Attributes, attribute.String("cc_relay.failover.trigger", "status_code"))
+}
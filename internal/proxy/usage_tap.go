@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// SSE event types carrying message-level token usage.
+const (
+	sseEventMessageStart = "message_start"
+	sseEventMessageDelta = "message_delta"
+	sseEventMessageStop  = "message_stop"
+)
+
+// mediaTypeJSON is the media type of non-streaming Messages API responses.
+const mediaTypeJSON = "application/json"
+
+// usageChargeTimeout bounds how long charging a response's usage may take,
+// including budget and rate limit counters kept in a remote cache.
+const usageChargeTimeout = time.Minute
+
+// maxUsageJSONBody caps how much of a non-streaming response is retained to
+// read its usage block. Larger responses pass through without being charged.
+const maxUsageJSONBody = 8 << 20
+
+// bedrockMetricsField is appended by Bedrock to the final stream event.
+// It is only consulted when the stream carried no Anthropic usage block.
+const bedrockMetricsField = "amazon-bedrock-invocationMetrics"
+
+var (
+	usageMarker          = []byte(`"usage"`)
+	bedrockMetricsMarker = []byte(bedrockMetricsField)
+)
+
+// tokenUsage is the token accounting reported by a Messages API response.
+type tokenUsage struct {
+	inputTokens         int64
+	outputTokens        int64
+	cacheCreationTokens int64
+	cacheReadTokens     int64
+}
+
+// rateLimitedInput returns the input tokens that count toward a key's ITPM.
+// Cache reads are excluded, matching how Anthropic applies input rate limits.
+func (u tokenUsage) rateLimitedInput() int {
+	return int(u.inputTokens + u.cacheCreationTokens)
+}
+
+// merge overwrites the counters present in a usage object and reports
+// whether there were any. Streaming counts are cumulative, so the latest
+// value always wins.
+func (u *tokenUsage) merge(usage gjson.Result) bool {
+	if !usage.IsObject() {
+		return false
+	}
+	fields := []struct {
+		dst  *int64
+		name string
+	}{
+		{dst: &u.inputTokens, name: "input_tokens"},
+		{dst: &u.outputTokens, name: "output_tokens"},
+		{dst: &u.cacheCreationTokens, name: "cache_creation_input_tokens"},
+		{dst: &u.cacheReadTokens, name: "cache_read_input_tokens"},
+	}
+	found := false
+	for _, field := range fields {
+		if value := usage.Get(field.name); value.Exists() {
+			*field.dst = value.Int()
+			found = true
+		}
+	}
+	return found
+}
+
+// jsonUsage returns the usage reported by a non-streaming response body.
+func jsonUsage(body []byte) (tokenUsage, bool) {
+	var usage tokenUsage
+	found := usage.merge(gjson.GetBytes(body, "usage"))
+	return usage, found
+}
+
+// usageTapBody passes an SSE response body through unchanged while extracting
+// the usage it reports. Events are parsed line by line, where message_start
+// carries input usage and each message_delta carries the cumulative output
+// count. onDone is invoked at most once, on EOF or Close, and only if usage
+// was seen.
+type usageTapBody struct {
+	original io.ReadCloser
+	onDone   func(tokenUsage)
+	lines    sseLineSplitter
+	usage    tokenUsage
+	found    bool
+	done     bool
+}
+
+// newUsageTapBody wraps an SSE body, reporting its token usage to onDone.
+func newUsageTapBody(body io.ReadCloser, onDone func(tokenUsage)) *usageTapBody {
+	return &usageTapBody{
+		original: body,
+		onDone:   onDone,
+		lines:    sseLineSplitter{pending: nil},
+		usage:    tokenUsage{inputTokens: 0, outputTokens: 0, cacheCreationTokens: 0, cacheReadTokens: 0},
+		found:    false,
+		done:     false,
+	}
+}
+
+// Read implements io.Reader, inspecting each chunk after it is read.
+func (t *usageTapBody) Read(p []byte) (int, error) {
+	n, err := t.original.Read(p)
+	if n > 0 {
+		t.lines.feed(p[:n], t.handlePayload)
+	}
+	if errors.Is(err, io.EOF) {
+		t.finish()
+	}
+	return n, err
+}
+
+// Close implements io.Closer. Streams closed early still report the usage
+// seen so far, since the upstream has already billed the input tokens.
+func (t *usageTapBody) Close() error {
+	t.finish()
+	return t.original.Close()
+}
+
+// handlePayload merges usage from message-level SSE events.
+func (t *usageTapBody) handlePayload(payload []byte) {
+	// Fast path: content deltas and pings never carry usage.
+	if !bytes.Contains(payload, usageMarker) && !bytes.Contains(payload, bedrockMetricsMarker) {
+		return
+	}
+
+	event := gjson.ParseBytes(payload)
+	switch event.Get("type").String() {
+	case sseEventMessageStart:
+		t.found = t.usage.merge(event.Get("message.usage")) || t.found
+	case sseEventMessageDelta:
+		t.found = t.usage.merge(event.Get("usage")) || t.found
+	case sseEventMessageStop:
+		metrics := event.Get(bedrockMetricsField)
+		if t.found || !metrics.Exists() {
+			return
+		}
+		t.usage.inputTokens = metrics.Get("inputTokenCount").Int()
+		t.usage.outputTokens = metrics.Get("outputTokenCount").Int()
+		t.found = true
+	}
+}
+
+// finish reports usage exactly once.
+func (t *usageTapBody) finish() {
+	if t.done {
+		return
+	}
+	t.done = true
+
+	if t.found && t.onDone != nil {
+		t.onDone(t.usage)
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tapUsageStream = "event: message_start\n" +
+	`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":120,` +
+	`"cache_creation_input_tokens":30,"cache_read_input_tokens":500,"output_tokens":1}}}` + "\n\n" +
+	"event: content_block_delta\n" +
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}` + "\n\n" +
+	"event: message_delta\n" +
+	`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":42}}` + "\n\n" +
+	"event: message_stop\n" +
+	`data: {"type":"message_stop"}` + "\n\n"
+
+// readUsage drains a usage tap and returns what it reported.
+func readUsage(t *testing.T, body string, chunkSize int) (usage tokenUsage, calls int) {
+	t.Helper()
+
+	source := io.NopCloser(&chunkedReader{data: body, size: chunkSize})
+	tap := newUsageTapBody(source, func(reported tokenUsage) {
+		usage = reported
+		calls++
+	})
+
+	out, err := io.ReadAll(tap)
+	require.NoError(t, err)
+	require.NoError(t, tap.Close())
+	assert.Equal(t, body, string(out), "body must pass through unchanged")
+	return usage, calls
+}
+
+func TestUsageTapBodyStream(t *testing.T) {
+	t.Parallel()
+
+	for _, chunkSize := range []int{5, 64, 4096} {
+		usage, calls := readUsage(t, tapUsageStream, chunkSize)
+
+		assert.Equal(t, 1, calls, "usage must be reported once")
+		assert.Equal(t, int64(120), usage.inputTokens)
+		assert.Equal(t, int64(42), usage.outputTokens, "cumulative delta count should win")
+		assert.Equal(t, int64(500), usage.cacheReadTokens)
+		assert.Equal(t, 150, usage.rateLimitedInput(), "cache reads do not count toward ITPM")
+	}
+}
+
+func TestJSONUsage(t *testing.T) {
+	t.Parallel()
+
+	body := `{"id":"msg_1","content":[{"type":"text","text":"usage"}],` +
+		`"usage":{"input_tokens":10,"output_tokens":7}}`
+	usage, found := jsonUsage([]byte(body))
+
+	assert.True(t, found)
+	assert.Equal(t, int64(10), usage.inputTokens)
+	assert.Equal(t, int64(7), usage.outputTokens)
+}
+
+func TestUsageTapBodyBedrockMetricsFallback(t *testing.T) {
+	t.Parallel()
+
+	stream := "event: message_stop\n" +
+		`data: {"type":"message_stop","amazon-bedrock-invocationMetrics":` +
+		`{"inputTokenCount":11,"outputTokenCount":22}}` + "\n\n"
+	usage, calls := readUsage(t, stream, 4096)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(11), usage.inputTokens)
+	assert.Equal(t, int64(22), usage.outputTokens)
+}
+
+func TestUsageTapBodyNoUsage(t *testing.T) {
+	t.Parallel()
+
+	_, found := jsonUsage([]byte(`{"id":"msg_1"}`))
+	assert.False(t, found)
+
+	_, calls := readUsage(t, "event: ping\ndata: {\"type\":\"ping\"}\n\n", 4096)
+	assert.Zero(t, calls)
+}
+
+func TestUsageTapBodyReportsOnEarlyClose(t *testing.T) {
+	t.Parallel()
+
+	var reported []tokenUsage
+	truncated := tapUsageStream[:strings.Index(tapUsageStream, "event: message_delta")]
+	tap := newUsageTapBody(io.NopCloser(strings.NewReader(truncated+tapUsageStream)),
+		func(usage tokenUsage) { reported = append(reported, usage) })
+
+	buf := make([]byte, len(truncated))
+	_, err := io.ReadFull(tap, buf)
+	require.NoError(t, err)
+	require.NoError(t, tap.Close())
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, int64(120), reported[0].inputTokens)
+	assert.Equal(t, int64(1), reported[0].outputTokens)
+}
@@ -1,5 +1,7 @@
 package ratelimit
 
+import "time"
+
 // GetRPMLimit returns the RPM limit (for testing).
 func (l *TokenBucketLimiter) GetRPMLimit() int {
 	l.mu.RLock()
@@ -13,3 +15,8 @@ func (l *TokenBucketLimiter) GetTPMLimit() int {
 	defer l.mu.RUnlock()
 	return l.tpmLimit
 }
+
+// SetClock replaces the clock of a WindowLimiter (for testing).
+func (l *WindowLimiter) SetClock(now func() time.Time) {
+	l.now = now
+}
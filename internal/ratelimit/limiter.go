@@ -99,4 +99,10 @@ type RateLimiter interface {
 	// tokens: actual token count from response (input + output tokens)
 	// Returns ErrContextCancelled if the context is canceled while waiting.
 	ConsumeTokens(ctx context.Context, tokens int) error
+
+	// RecordTokens records tokens that were already used without waiting.
+	// A limiter over its TPM limit goes into debt, which counts against
+	// later requests until it is repaid.
+	// Returns an error if the tokens could not be recorded.
+	RecordTokens(ctx context.Context, tokens int) error
 }
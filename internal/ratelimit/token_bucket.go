@@ -89,7 +89,8 @@ func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
 // This is used to learn actual limits from provider response headers.
 //
 // The method is thread-safe and creates new limiters with updated rates.
-// Zero or negative values are treated as unlimited.
+// Zero or negative values are treated as unlimited. Calls that do not change
+// either limit are no-ops, so tokens already consumed are not refunded.
 func (l *TokenBucketLimiter) SetLimit(rpm, tpm int) {
 	const unlimitedRate = 1_000_000
 
@@ -104,6 +105,10 @@ func (l *TokenBucketLimiter) SetLimit(rpm, tpm int) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if rpm == l.rpmLimit && tpm == l.tpmLimit {
+		return
+	}
+
 	// Create new limiters with updated rates
 	l.requestLimiter = rate.NewLimiter(rate.Limit(float64(rpm)/60.0), rpm)
 	l.tokenLimiter = rate.NewLimiter(rate.Limit(float64(tpm)/60.0), tpm)
@@ -180,3 +185,22 @@ func (l *TokenBucketLimiter) ConsumeTokens(ctx context.Context, tokens int) erro
 	}
 	return nil
 }
+
+// RecordTokens records actual token usage after a response is received
+// without blocking. The tokens are reserved immediately, leaving the bucket in
+// debt if they exceed what is available, so no tokens remain until it has
+// refilled. Usage larger than the bucket is reserved one bucket at a time,
+// so the whole of it is repaid before tokens are available again.
+func (l *TokenBucketLimiter) RecordTokens(_ context.Context, tokens int) error {
+	l.mu.RLock()
+	limiter := l.tokenLimiter
+	l.mu.RUnlock()
+
+	now := time.Now()
+	for tokens > 0 {
+		chunk := min(tokens, limiter.Burst())
+		limiter.ReserveN(now, chunk)
+		tokens -= chunk
+	}
+	return nil
+}
@@ -229,6 +229,23 @@ func TestSetLimitUpdates(t *testing.T) {
 			t.Error("Allow() failed after increasing limit")
 		}
 	})
+
+	t.Run("unchanged limit keeps consumed tokens", func(t *testing.T) {
+		t.Parallel()
+		limiter := ratelimit.NewTokenBucketLimiter(50, 30000)
+		ctx := context.Background()
+
+		if err := limiter.ConsumeTokens(ctx, 10000); err != nil {
+			t.Fatalf("ConsumeTokens failed: %v", err)
+		}
+
+		// Header updates repeat the same limits on every response.
+		limiter.SetLimit(50, 30000)
+
+		if used := limiter.GetUsage().TokensUsed; used <= 0 {
+			t.Errorf("TokensUsed = %d after no-op SetLimit, want > 0", used)
+		}
+	})
 }
 
 func TestSetLimitThreadSafety(t *testing.T) {
@@ -443,6 +460,46 @@ func TestConsumeTokens(t *testing.T) {
 	})
 }
 
+func TestRecordTokens(t *testing.T) {
+	t.Parallel()
+	t.Run("goes into debt without blocking", func(t *testing.T) {
+		t.Parallel()
+		limiter := ratelimit.NewTokenBucketLimiter(100, 60) // 1 token per second
+		ctx := context.Background()
+
+		start := time.Now()
+		for range 3 {
+			if err := limiter.RecordTokens(ctx, 60); err != nil {
+				t.Fatalf("RecordTokens(60) failed: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("RecordTokens() blocked for %v", elapsed)
+		}
+
+		// Two minutes of debt must be repaid before tokens are available again
+		if remaining := limiter.GetUsage().TokensRemaining; remaining != 0 {
+			t.Errorf("TokensRemaining = %d while in debt, want 0", remaining)
+		}
+	})
+
+	t.Run("charges more tokens than the bucket holds", func(t *testing.T) {
+		t.Parallel()
+		limiter := ratelimit.NewTokenBucketLimiter(100, 600) // 10 tokens per second
+
+		if err := limiter.RecordTokens(context.Background(), 1200); err != nil {
+			t.Fatalf("RecordTokens(1200) failed: %v", err)
+		}
+
+		// A minute of debt remains, so the next token is out of reach of a short deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := limiter.ConsumeTokens(ctx, 1); err == nil {
+			t.Error("ConsumeTokens(1) succeeded, want the oversized charge to keep the bucket in debt")
+		}
+	})
+}
+
 func TestReserve(t *testing.T) {
 	t.Parallel()
 	t.Run("returns true when tokens available", func(t *testing.T) {
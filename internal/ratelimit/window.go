@@ -0,0 +1,298 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+)
+
+const (
+	// window is the period RPM and TPM limits are counted over.
+	window = time.Minute
+
+	// windowTTL keeps a window's counters while it is the previous window,
+	// plus a grace period for nodes whose clocks lag slightly behind.
+	windowTTL = 2*window + 30*time.Second
+
+	// counterTimeout bounds the counter reads of GetUsage and Reserve, which
+	// take no context, so a slow distributed counter cannot stall callers.
+	counterTimeout = time.Second
+
+	requestsCounter = "requests"
+	tokensCounter   = "tokens"
+)
+
+// Limits are the rate limits for one client or IP address. Zero values are unlimited.
+type Limits struct {
+	// RPM caps requests per minute.
+	RPM int `yaml:"rpm" toml:"rpm"`
+
+	// TPM caps input and output tokens per minute.
+	TPM int `yaml:"tpm" toml:"tpm"`
+
+	// MaxConcurrent caps requests in flight.
+	MaxConcurrent int `yaml:"max_concurrent" toml:"max_concurrent"`
+}
+
+// IsZero returns true if no limit is set.
+func (l Limits) IsZero() bool {
+	return l.RPM == 0 && l.TPM == 0 && l.MaxConcurrent == 0
+}
+
+// WindowLimiter implements RateLimiter with sliding window counters kept in a
+// cache.Counter. With a distributed counter (Olric) every node enforces the
+// same limits; with cache.LocalCounter the limits are per process.
+//
+// Each minute is counted separately. The count of the last minute is
+// estimated from the current minute's count plus the previous minute's count
+// weighted by how much of it still lies within the last 60 seconds, which
+// avoids the burst a fixed window allows at its boundary.
+//
+// Unlike TokenBucketLimiter, ConsumeTokens never blocks: tokens are recorded
+// as they are reported, and Reserve refuses requests until the estimated
+// tokens of the last minute fall below the TPM limit again.
+//
+// Counter errors fail open: requests are allowed if usage cannot be read.
+// The errors are logged.
+//
+// Every counter operation is a round trip to a distributed counter, and the
+// current and previous minute are separate counters: Allow and reading one
+// limit cost two round trips each, GetUsage and RetryAfter four. Callers on
+// the request path should use the context-taking methods, so the request's
+// deadline bounds them.
+type WindowLimiter struct {
+	counter  cache.Counter
+	now      func() time.Time
+	scope    string
+	rpmLimit int
+	tpmLimit int
+	mu       sync.RWMutex
+}
+
+// NewWindowLimiter creates a limiter counting in counter under scope, e.g.
+// "client:alice". Limiters sharing a counter and scope share their usage.
+// Zero or negative limits are unlimited.
+func NewWindowLimiter(counter cache.Counter, scope string, rpm, tpm int) *WindowLimiter {
+	return &WindowLimiter{
+		counter:  counter,
+		now:      time.Now,
+		scope:    scope,
+		rpmLimit: max(rpm, 0),
+		tpmLimit: max(tpm, 0),
+		mu:       sync.RWMutex{},
+	}
+}
+
+// windowCounts are the counts of the current and previous minute.
+type windowCounts struct {
+	previous int64
+	current  int64
+}
+
+// estimate returns the count of the last minute, elapsed into the current one.
+func (c windowCounts) estimate(elapsed time.Duration) float64 {
+	return float64(c.previous)*(1-float64(elapsed)/float64(window)) + float64(c.current)
+}
+
+// retryAfter returns how long until one more unit fits under limit, if no
+// more are counted in the meantime.
+func (c windowCounts) retryAfter(elapsed time.Duration, limit int) time.Duration {
+	room := float64(limit - 1)
+	if c.estimate(elapsed) <= room {
+		return 0
+	}
+	if float64(c.current) <= room {
+		// The previous minute's share decays below the room left in this one
+		needed := 1 - (room-float64(c.current))/float64(c.previous)
+		return max(time.Duration(needed*float64(window))-elapsed, 0)
+	}
+	// This minute alone is over the limit; wait until its share decays in the next
+	needed := 1 - room/float64(c.current)
+	return window - elapsed + time.Duration(needed*float64(window))
+}
+
+// Allow counts a request against the RPM limit and reports whether it is
+// within the limit. Refused requests are not counted.
+func (l *WindowLimiter) Allow(ctx context.Context) bool {
+	rpm, _ := l.limits()
+	if rpm == 0 {
+		return true
+	}
+	now := l.now()
+	key := l.key(requestsCounter, now)
+	current, err := l.counter.Incr(ctx, key, 1, windowTTL)
+	if err != nil {
+		l.logCounterError(err)
+		return true
+	}
+	previous, err := l.counter.Incr(ctx, l.key(requestsCounter, now.Add(-window)), 0, windowTTL)
+	if err != nil {
+		l.logCounterError(err)
+		return true
+	}
+	if (windowCounts{previous: previous, current: current}).estimate(elapsedIn(now)) <= float64(rpm) {
+		return true
+	}
+	if _, undoErr := l.counter.Incr(ctx, key, -1, windowTTL); undoErr != nil {
+		_ = undoErr // the request is refused either way, only the count is off
+	}
+	return false
+}
+
+// Wait blocks until a request is allowed or the context is canceled.
+// Returns ErrContextCancelled if the context is canceled while waiting.
+func (l *WindowLimiter) Wait(ctx context.Context) error {
+	for !l.Allow(ctx) {
+		timer := time.NewTimer(max(l.RetryAfter(ctx), time.Millisecond))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ErrContextCancelled
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// SetLimit updates the rate limits. Zero or negative values are unlimited.
+// Usage already counted is kept.
+func (l *WindowLimiter) SetLimit(rpm, tpm int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rpmLimit = max(rpm, 0)
+	l.tpmLimit = max(tpm, 0)
+}
+
+// GetUsage returns the estimated usage of the last minute. Unlimited limits
+// are reported as 0. Reading the counters is bounded by counterTimeout.
+func (l *WindowLimiter) GetUsage() Usage {
+	ctx, cancel := context.WithTimeout(context.Background(), counterTimeout)
+	defer cancel()
+
+	rpm, tpm := l.limits()
+	now := l.now()
+	requests := l.estimate(ctx, requestsCounter, now)
+	tokens := l.estimate(ctx, tokensCounter, now)
+	return Usage{
+		RequestsUsed:      requests,
+		RequestsLimit:     rpm,
+		TokensUsed:        tokens,
+		TokensLimit:       tpm,
+		RequestsRemaining: max(rpm-requests, 0),
+		TokensRemaining:   max(tpm-tokens, 0),
+	}
+}
+
+// Reserve reports whether tokens more fit under the TPM limit. Nothing is
+// counted until ConsumeTokens. Reading the counters is bounded by
+// counterTimeout; use ReserveContext to bound it by a request instead.
+func (l *WindowLimiter) Reserve(tokens int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), counterTimeout)
+	defer cancel()
+	return l.ReserveContext(ctx, tokens)
+}
+
+// ReserveContext is Reserve with the counter reads bounded by ctx.
+func (l *WindowLimiter) ReserveContext(ctx context.Context, tokens int) bool {
+	_, tpm := l.limits()
+	if tpm == 0 {
+		return true
+	}
+	now := l.now()
+	counts, err := l.counts(ctx, tokensCounter, now)
+	if err != nil {
+		return true
+	}
+	return counts.estimate(elapsedIn(now))+float64(tokens) <= float64(tpm)
+}
+
+// ConsumeTokens records tokens used by a request. It does not block.
+func (l *WindowLimiter) ConsumeTokens(ctx context.Context, tokens int) error {
+	return l.RecordTokens(ctx, tokens)
+}
+
+// RecordTokens records tokens used by a request.
+func (l *WindowLimiter) RecordTokens(ctx context.Context, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	if _, err := l.counter.Incr(ctx, l.key(tokensCounter, l.now()), int64(tokens), windowTTL); err != nil {
+		return fmt.Errorf("ratelimit: record tokens for %s: %w", l.scope, err)
+	}
+	return nil
+}
+
+// RetryAfter returns how long until both another request and another token
+// fit under the limits, or 0 if they fit now.
+func (l *WindowLimiter) RetryAfter(ctx context.Context) time.Duration {
+	rpm, tpm := l.limits()
+	now := l.now()
+	elapsed := elapsedIn(now)
+	var wait time.Duration
+	for _, limit := range []struct {
+		counter string
+		value   int
+	}{{requestsCounter, rpm}, {tokensCounter, tpm}} {
+		if limit.value == 0 {
+			continue
+		}
+		counts, err := l.counts(ctx, limit.counter, now)
+		if err != nil {
+			continue
+		}
+		wait = max(wait, counts.retryAfter(elapsed, limit.value))
+	}
+	return wait
+}
+
+func (l *WindowLimiter) limits() (rpm, tpm int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.rpmLimit, l.tpmLimit
+}
+
+// counts reads the current and previous minute of a counter. Errors are
+// logged before they are returned.
+func (l *WindowLimiter) counts(ctx context.Context, counter string, now time.Time) (windowCounts, error) {
+	current, err := l.counter.Incr(ctx, l.key(counter, now), 0, windowTTL)
+	if err != nil {
+		l.logCounterError(err)
+		return windowCounts{previous: 0, current: 0}, err
+	}
+	previous, err := l.counter.Incr(ctx, l.key(counter, now.Add(-window)), 0, windowTTL)
+	if err != nil {
+		l.logCounterError(err)
+		return windowCounts{previous: 0, current: 0}, err
+	}
+	return windowCounts{previous: previous, current: current}, nil
+}
+
+// estimate returns the estimated count of the last minute, or 0 on error.
+func (l *WindowLimiter) estimate(ctx context.Context, counter string, now time.Time) int {
+	counts, err := l.counts(ctx, counter, now)
+	if err != nil {
+		return 0
+	}
+	return int(math.Ceil(counts.estimate(elapsedIn(now))))
+}
+
+// logCounterError logs a counter error the limiter fails open on.
+func (l *WindowLimiter) logCounterError(err error) {
+	log.Warn().Err(err).Str("rate_limit_scope", l.scope).Msg("rate limit counter unavailable, allowing request")
+}
+
+// key builds the counter key: "ratelimit:{scope}:{counter}:{minute}".
+func (l *WindowLimiter) key(counter string, now time.Time) string {
+	return fmt.Sprintf("ratelimit:%s:%s:%d", l.scope, counter, now.Unix()/int64(window/time.Second))
+}
+
+// elapsedIn returns how far now is into its minute.
+func elapsedIn(now time.Time) time.Duration {
+	return time.Duration(now.UnixNano() % int64(window))
+}
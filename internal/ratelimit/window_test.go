@@ -0,0 +1,199 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/cache"
+	"github.com/omarluq/cc-relay/internal/ratelimit"
+)
+
+// windowStart is on a minute boundary.
+var windowStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testClock is a settable clock shared by limiters and their counter.
+type testClock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func newWindowLimiter(clock *testClock, counter cache.Counter, scope string, rpm, tpm int) *ratelimit.WindowLimiter {
+	limiter := ratelimit.NewWindowLimiter(counter, scope, rpm, tpm)
+	limiter.SetClock(clock.Now)
+	return limiter
+}
+
+func newClock() *testClock {
+	return &testClock{now: windowStart, mu: sync.Mutex{}}
+}
+
+func TestWindowLimiterRPM(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clock := newClock()
+	limiter := newWindowLimiter(clock, cache.NewLocalCounter(clock.Now), "client:alice", 2, 0)
+
+	if !limiter.Allow(ctx) || !limiter.Allow(ctx) {
+		t.Fatal("Allow() refused a request within the RPM limit")
+	}
+	if limiter.Allow(ctx) {
+		t.Fatal("Allow() allowed a third request with an RPM limit of 2")
+	}
+	// Refused requests are not counted, so the previous minute holds 2 requests
+	if got := limiter.GetUsage().RequestsUsed; got != 2 {
+		t.Errorf("RequestsUsed = %d, want 2", got)
+	}
+	// Half way into the next minute, the previous minute counts for 1 request
+	if got := limiter.RetryAfter(ctx); got != 90*time.Second {
+		t.Errorf("RetryAfter() = %v, want 1m30s", got)
+	}
+
+	clock.Set(windowStart.Add(89 * time.Second))
+	if limiter.Allow(ctx) {
+		t.Error("Allow() allowed a request before the retry time")
+	}
+	clock.Set(windowStart.Add(90 * time.Second))
+	if !limiter.Allow(ctx) {
+		t.Error("Allow() refused a request at the retry time")
+	}
+	if limiter.Allow(ctx) {
+		t.Error("Allow() allowed a request over the sliding window estimate")
+	}
+}
+
+func TestWindowLimiterTPM(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clock := newClock()
+	limiter := newWindowLimiter(clock, cache.NewLocalCounter(clock.Now), "ip:10.0.0.1", 0, 100)
+
+	if !limiter.Reserve(100) {
+		t.Fatal("Reserve(100) refused tokens within the TPM limit")
+	}
+	if err := limiter.ConsumeTokens(ctx, 150); err != nil {
+		t.Fatalf("ConsumeTokens() error = %v", err)
+	}
+	if limiter.Reserve(1) {
+		t.Error("Reserve(1) allowed tokens over the TPM limit")
+	}
+	if !limiter.Allow(ctx) {
+		t.Error("Allow() refused a request without an RPM limit")
+	}
+	usage := limiter.GetUsage()
+	if usage.TokensUsed != 150 || usage.TokensRemaining != 0 {
+		t.Errorf("GetUsage() = %+v, want 150 tokens used and none remaining", usage)
+	}
+
+	// 150 tokens decay to 99 after 34% of the next minute
+	wait := limiter.RetryAfter(ctx)
+	if wait <= time.Minute || wait > time.Minute+21*time.Second {
+		t.Fatalf("RetryAfter() = %v, want between 1m and 1m21s", wait)
+	}
+	clock.Set(windowStart.Add(wait))
+	if !limiter.Reserve(1) {
+		t.Errorf("Reserve(1) refused tokens at the retry time %v", wait)
+	}
+}
+
+func TestWindowLimiterSharesScope(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clock := newClock()
+	counter := cache.NewLocalCounter(clock.Now)
+	first := newWindowLimiter(clock, counter, "client:alice", 1, 0)
+	second := newWindowLimiter(clock, counter, "client:alice", 1, 0)
+	other := newWindowLimiter(clock, counter, "client:bob", 1, 0)
+
+	if !first.Allow(ctx) {
+		t.Fatal("Allow() refused the first request")
+	}
+	if second.Allow(ctx) {
+		t.Error("Allow() on a limiter of the same scope ignored the shared count")
+	}
+	if !other.Allow(ctx) {
+		t.Error("Allow() on a limiter of another scope was refused")
+	}
+
+	second.SetLimit(0, 0)
+	if !second.Allow(ctx) || !second.Reserve(1_000_000) {
+		t.Error("SetLimit(0, 0) did not make the limiter unlimited")
+	}
+}
+
+// failingCounter is a cache.Counter whose every call fails.
+type failingCounter struct{}
+
+func (failingCounter) Incr(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errors.New("cluster unavailable")
+}
+
+func TestWindowLimiterFailsOpen(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	limiter := newWindowLimiter(newClock(), failingCounter{}, "client:alice", 1, 1)
+
+	for range 3 {
+		if !limiter.Allow(ctx) || !limiter.Reserve(10) {
+			t.Fatal("limiter refused a request while its counter fails")
+		}
+	}
+	if limiter.RetryAfter(ctx) != 0 {
+		t.Error("RetryAfter() is non-zero while the counter fails")
+	}
+	if err := limiter.ConsumeTokens(ctx, 10); err == nil {
+		t.Error("ConsumeTokens() error = nil, want the counter's error")
+	}
+}
+
+// blockingCounter answers only once its context is done, like an unreachable
+// distributed counter.
+type blockingCounter struct{}
+
+func (blockingCounter) Incr(ctx context.Context, _ string, _ int64, _ time.Duration) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestWindowLimiterReserveContextIsBounded(t *testing.T) {
+	t.Parallel()
+	limiter := newWindowLimiter(newClock(), blockingCounter{}, "client:alice", 0, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if !limiter.ReserveContext(ctx, 10) {
+		t.Error("ReserveContext() = false, want the limiter to fail open once ctx is done")
+	}
+	if ctx.Err() == nil {
+		t.Error("ReserveContext() returned before reading the counter")
+	}
+}
+
+func TestWindowLimiterWait(t *testing.T) {
+	t.Parallel()
+	clock := newClock()
+	limiter := newWindowLimiter(clock, cache.NewLocalCounter(clock.Now), "client:alice", 1, 0)
+
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, ratelimit.ErrContextCancelled) {
+		t.Errorf("Wait() error = %v, want ErrContextCancelled", err)
+	}
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrBelowQualityFloor is returned when no healthy provider serves the
+// requested model at or above the request's quality floor.
+var ErrBelowQualityFloor = errors.New("router: no healthy provider meets the quality floor")
+
+type qualityFloorContextKey struct{}
+
+// WithQualityFloor returns a context carrying the minimum model quality a
+// provider must serve the request with. Zero means no floor.
+func WithQualityFloor(ctx context.Context, floor int) context.Context {
+	return context.WithValue(ctx, qualityFloorContextKey{}, floor)
+}
+
+// QualityFloorFromContext returns the request's quality floor, or 0 if none was set.
+func QualityFloorFromContext(ctx context.Context) int {
+	if floor, ok := ctx.Value(qualityFloorContextKey{}).(int); ok {
+		return floor
+	}
+	return 0
+}
+
+// CostOptimizedRouter selects the cheapest healthy provider for the requested
+// model, priced from ProviderInfo.Prices. Providers are ranked by the price of
+// a million input tokens plus a million output tokens; ties go to the higher priority.
+//
+// Providers with a price below the request's quality floor are never selected.
+// Providers without a price are only selected when no priced provider is
+// healthy and the request has no quality floor.
+type CostOptimizedRouter struct{}
+
+// NewCostOptimizedRouter creates a new cost-optimized router.
+func NewCostOptimizedRouter() *CostOptimizedRouter {
+	return &CostOptimizedRouter{}
+}
+
+// Select chooses the cheapest healthy provider for the model in ctx.
+func (r *CostOptimizedRouter) Select(ctx context.Context, infos []ProviderInfo) (ProviderInfo, error) {
+	if len(infos) == 0 {
+		return ProviderInfo{}, ErrNoProviders
+	}
+
+	healthy := FilterHealthy(infos)
+	if len(healthy) == 0 {
+		return ProviderInfo{}, ErrAllProvidersUnhealthy
+	}
+
+	model := ModelFromContext(ctx)
+	floor := QualityFloorFromContext(ctx)
+
+	var cheapest ProviderInfo
+	var unpriced []ProviderInfo
+	lowestCost := math.Inf(1)
+	for _, info := range sortByPriority(healthy) {
+		price, ok := info.PriceFor(model)
+		switch {
+		case !ok:
+			unpriced = append(unpriced, info)
+		case price.Quality >= floor && price.rank() < lowestCost:
+			cheapest, lowestCost = info, price.rank()
+		}
+	}
+
+	if cheapest.Provider != nil {
+		return cheapest, nil
+	}
+	if floor > 0 || len(unpriced) == 0 {
+		return ProviderInfo{}, ErrBelowQualityFloor
+	}
+	return unpriced[0], nil
+}
+
+// Name returns the strategy name.
+func (r *CostOptimizedRouter) Name() string {
+	return StrategyCostOptimized
+}
@@ -0,0 +1,129 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const opusModel = "claude-opus-4"
+
+func pricedInfo(
+	name string, priority int, healthy func() bool, prices map[string]router.ModelPrice,
+) router.ProviderInfo {
+	info := router.NewTestProviderInfo(name, priority, 1, healthy)
+	info.Prices = prices
+	return info
+}
+
+func opusPrice(input, output float64, quality int) map[string]router.ModelPrice {
+	return map[string]router.ModelPrice{
+		"claude-opus": {InputPerMTok: input, OutputPerMTok: output, Quality: quality},
+	}
+}
+
+func selectCost(ctx context.Context, infos []router.ProviderInfo) (string, error) {
+	selected, err := router.NewCostOptimizedRouter().Select(ctx, infos)
+	if err != nil {
+		return "", err
+	}
+	return selected.Provider.Name(), nil
+}
+
+func TestCostOptimizedRouterName(t *testing.T) {
+	t.Parallel()
+
+	rtr := router.NewCostOptimizedRouter()
+	if rtr.Name() != router.StrategyCostOptimized {
+		t.Errorf("Name() = %q, want %q", rtr.Name(), router.StrategyCostOptimized)
+	}
+}
+
+func TestCostOptimizedRouterSelectErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := selectCost(context.Background(), nil); !errors.Is(err, router.ErrNoProviders) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrNoProviders)
+	}
+
+	unhealthy := []router.ProviderInfo{pricedInfo("p1", 1, router.NeverHealthy(), opusPrice(1, 1, 1))}
+	if _, err := selectCost(context.Background(), unhealthy); !errors.Is(err, router.ErrAllProvidersUnhealthy) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrAllProvidersUnhealthy)
+	}
+}
+
+func TestCostOptimizedRouterSelectsCheapest(t *testing.T) {
+	t.Parallel()
+
+	infos := []router.ProviderInfo{
+		pricedInfo("anthropic", 3, router.AlwaysHealthy(), opusPrice(15, 75, 10)),
+		pricedInfo("bedrock", 2, router.AlwaysHealthy(), opusPrice(15, 70, 10)),
+		pricedInfo("zai", 1, router.AlwaysHealthy(), opusPrice(0.6, 2.2, 6)),
+		pricedInfo("offline", 0, router.NeverHealthy(), opusPrice(0.1, 0.1, 10)),
+	}
+	ctx := router.WithModel(context.Background(), opusModel)
+
+	got, err := selectCost(ctx, infos)
+	if err != nil || got != "zai" {
+		t.Errorf("Select() = %q, %v, want zai", got, err)
+	}
+
+	got, err = selectCost(router.WithQualityFloor(ctx, 9), infos)
+	if err != nil || got != "bedrock" {
+		t.Errorf("Select() with floor = %q, %v, want bedrock", got, err)
+	}
+}
+
+func TestCostOptimizedRouterTieGoesToPriority(t *testing.T) {
+	t.Parallel()
+
+	infos := []router.ProviderInfo{
+		pricedInfo("low", 1, router.AlwaysHealthy(), opusPrice(3, 15, 8)),
+		pricedInfo("high", 2, router.AlwaysHealthy(), opusPrice(3, 15, 8)),
+	}
+
+	got, err := selectCost(router.WithModel(context.Background(), opusModel), infos)
+	if err != nil || got != "high" {
+		t.Errorf("Select() = %q, %v, want high", got, err)
+	}
+}
+
+func TestCostOptimizedRouterUnpricedProviders(t *testing.T) {
+	t.Parallel()
+
+	ctx := router.WithModel(context.Background(), opusModel)
+	infos := []router.ProviderInfo{
+		pricedInfo("unpriced", 2, router.AlwaysHealthy(), nil),
+		pricedInfo("priced", 1, router.AlwaysHealthy(), opusPrice(15, 75, 10)),
+	}
+	if got, err := selectCost(ctx, infos); err != nil || got != "priced" {
+		t.Errorf("Select() = %q, %v, want the priced provider", got, err)
+	}
+
+	// Unpriced providers are the fallback when no priced one is healthy.
+	infos[1].IsHealthy = router.NeverHealthy()
+	if got, err := selectCost(ctx, infos); err != nil || got != "unpriced" {
+		t.Errorf("Select() = %q, %v, want the unpriced fallback", got, err)
+	}
+
+	// But never when the request has a quality floor.
+	if _, err := selectCost(router.WithQualityFloor(ctx, 1), infos); !errors.Is(err, router.ErrBelowQualityFloor) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrBelowQualityFloor)
+	}
+}
+
+func TestCostOptimizedRouterQualityFloorUnmet(t *testing.T) {
+	t.Parallel()
+
+	infos := []router.ProviderInfo{
+		pricedInfo("zai", 1, router.AlwaysHealthy(), opusPrice(0.6, 2.2, 6)),
+		pricedInfo("anthropic", 2, router.NeverHealthy(), opusPrice(15, 75, 10)),
+	}
+	ctx := router.WithQualityFloor(router.WithModel(context.Background(), opusModel), 9)
+
+	if _, err := selectCost(ctx, infos); !errors.Is(err, router.ErrBelowQualityFloor) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrBelowQualityFloor)
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/omarluq/cc-relay/internal/providers"
 )
@@ -52,6 +53,7 @@ func NewTestProviderInfo(name string, priority, weight int, isHealthy func() boo
 		Priority:  priority,
 		Weight:    weight,
 		IsHealthy: isHealthy,
+		Prices:    nil,
 	}
 }
 
@@ -81,3 +83,26 @@ func LiveRouterAcquire(r *LiveRouter, p providers.Provider) {
 func LiveRouterRelease(r *LiveRouter, p providers.Provider) {
 	r.Release(p)
 }
+
+// NewTestLatencyAwareRouter creates a latency-aware router that never explores
+// and reads the time from now (for testing).
+func NewTestLatencyAwareRouter(now func() time.Time) *LatencyAwareRouter {
+	r := NewLatencyAwareRouter()
+	r.now = now
+	r.explorePercent = 0
+	return r
+}
+
+// LatencyInFlight returns the in-flight count of a latency-aware router (for testing).
+func LatencyInFlight(r *LatencyAwareRouter, p providers.Provider) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.inFlight[p.Name()]
+}
+
+// NewTestHedgeDelays creates a hedge delay tracker that reads the time from now (for testing).
+func NewTestHedgeDelays(now func() time.Time) *HedgeDelays {
+	d := NewHedgeDelays()
+	d.now = now
+	return d
+}
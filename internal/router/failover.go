@@ -56,6 +56,21 @@ func (r *FailoverRouter) Select(_ context.Context, providers []ProviderInfo) (Pr
 	return sorted[0], nil
 }
 
+// FailoverOrder returns healthy providers sorted by priority descending.
+// Handlers use this order to retry the request on the next provider when a trigger fires.
+func (r *FailoverRouter) FailoverOrder(providers []ProviderInfo) ([]ProviderInfo, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	healthy := FilterHealthy(providers)
+	if len(healthy) == 0 {
+		return nil, ErrAllProvidersUnhealthy
+	}
+
+	return sortByPriority(healthy), nil
+}
+
 // Name returns the strategy name for logging and configuration.
 func (r *FailoverRouter) Name() string {
 	return StrategyFailover
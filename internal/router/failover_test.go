@@ -125,6 +125,48 @@ func TestFailoverRouterSelectSkipsUnhealthyHighPriority(t *testing.T) {
 	}
 }
 
+func TestFailoverRouterFailoverOrder(t *testing.T) {
+	t.Parallel()
+
+	rtr := router.NewFailoverRouter(0)
+	providers := []router.ProviderInfo{
+		router.NewTestProviderInfo("p1", 1, 1, router.AlwaysHealthy()),
+		router.NewTestProviderInfo("p2", 3, 3, router.AlwaysHealthy()),
+		router.NewTestProviderInfo("p3", 2, 2, router.NeverHealthy()),
+		router.NewTestProviderInfo("p4", 2, 2, router.AlwaysHealthy()),
+	}
+	order, err := rtr.FailoverOrder(providers)
+	if err != nil {
+		t.Fatalf("FailoverOrder() unexpected error: %v", err)
+	}
+
+	want := []string{"p2", "p4", "p1"}
+	if len(order) != len(want) {
+		t.Fatalf("FailoverOrder() returned %d providers, want %d", len(order), len(want))
+	}
+	for idx, name := range want {
+		if order[idx].Provider.Name() != name {
+			t.Errorf("FailoverOrder()[%d] = %q, want %q", idx, order[idx].Provider.Name(), name)
+		}
+	}
+}
+
+func TestFailoverRouterFailoverOrderErrors(t *testing.T) {
+	t.Parallel()
+
+	rtr := router.NewFailoverRouter(0)
+	if _, err := rtr.FailoverOrder(nil); !errors.Is(err, router.ErrNoProviders) {
+		t.Errorf("FailoverOrder(nil) error = %v, want %v", err, router.ErrNoProviders)
+	}
+
+	unhealthy := []router.ProviderInfo{
+		router.NewTestProviderInfo("p1", 1, 1, router.NeverHealthy()),
+	}
+	if _, err := rtr.FailoverOrder(unhealthy); !errors.Is(err, router.ErrAllProvidersUnhealthy) {
+		t.Errorf("FailoverOrder() error = %v, want %v", err, router.ErrAllProvidersUnhealthy)
+	}
+}
+
 func TestFailoverRouterSelectWithRetryEmptyProviders(t *testing.T) {
 	t.Parallel()
 
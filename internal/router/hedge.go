@@ -0,0 +1,125 @@
+package router
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+const (
+	// hedgeWindow is how many recent responses hedge delays are computed over.
+	hedgeWindow = 100
+
+	// hedgeMinSamples is how many recent responses a provider needs before
+	// its percentile is trusted over the configured initial delay.
+	hedgeMinSamples = 10
+)
+
+// hedgeSample is one response's time to first byte.
+type hedgeSample struct {
+	at        time.Time
+	firstByte time.Duration
+}
+
+// HedgeDelays tracks the time to first byte of recent responses per provider
+// and per model, and derives how long a request should wait for a provider
+// before it is hedged to another.
+type HedgeDelays struct {
+	samples map[latencyKey][]hedgeSample
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewHedgeDelays creates an empty hedge delay tracker.
+func NewHedgeDelays() *HedgeDelays {
+	return &HedgeDelays{
+		samples: make(map[latencyKey][]hedgeSample),
+		now:     time.Now,
+		mu:      sync.Mutex{},
+	}
+}
+
+// Observe records how long provider took to send the first bytes of a
+// response for model, both for the model and across all models.
+func (d *HedgeDelays) Observe(provider, model string, firstByte time.Duration) {
+	if firstByte <= 0 {
+		return
+	}
+	sample := hedgeSample{at: d.now(), firstByte: firstByte}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.observe(latencyKey{provider: provider, model: ""}, sample)
+	if model != "" {
+		d.observe(latencyKey{provider: provider, model: model}, sample)
+	}
+}
+
+func (d *HedgeDelays) observe(key latencyKey, sample hedgeSample) {
+	samples := append(d.samples[key], sample)
+	if len(samples) > hedgeWindow {
+		samples = slices.Delete(samples, 0, len(samples)-hedgeWindow)
+	}
+	d.samples[key] = samples
+}
+
+// Delay returns the percentile (between 0 and 100) of provider's recent time
+// to first byte, preferring measurements for model. Reports false until
+// enough recent responses have been timed.
+func (d *HedgeDelays) Delay(provider, model string, percentile float64) (time.Duration, bool) {
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	keys := []latencyKey{{provider: provider, model: model}}
+	if model != "" {
+		keys = append(keys, latencyKey{provider: provider, model: ""})
+	}
+	for _, key := range keys {
+		recent := make([]time.Duration, 0, len(d.samples[key]))
+		for _, sample := range d.samples[key] {
+			if now.Sub(sample.at) <= latencyStaleAfter {
+				recent = append(recent, sample.firstByte)
+			}
+		}
+		if len(recent) >= hedgeMinSamples {
+			return percentileOf(recent, percentile), true
+		}
+	}
+	return 0, false
+}
+
+// percentileOf returns the nearest-rank percentile of values. Sorts values.
+func percentileOf(values []time.Duration, percentile float64) time.Duration {
+	slices.Sort(values)
+	rank := int(math.Ceil(percentile / 100 * float64(len(values))))
+	return values[min(max(rank, 1), len(values))-1]
+}
+
+// SelectHedge chooses the provider a request sent to primary is hedged to:
+// the next provider in the failover order for failover strategies, otherwise
+// the provider providerRouter selects among the other healthy providers.
+func SelectHedge(
+	ctx context.Context, providerRouter ProviderRouter, infos []ProviderInfo, primary ProviderInfo,
+) (ProviderInfo, error) {
+	others := lo.Filter(infos, func(info ProviderInfo, _ int) bool {
+		return info.Healthy() && info.Provider.Name() != primary.Provider.Name()
+	})
+	if len(others) == 0 {
+		return ProviderInfo{}, ErrNoProviders
+	}
+	if failover, ok := AsFailover(providerRouter); ok {
+		order, err := failover.FailoverOrder(others)
+		if err != nil {
+			return ProviderInfo{}, err
+		}
+		return order[0], nil
+	}
+	return providerRouter.Select(ctx, others)
+}
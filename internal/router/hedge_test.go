@@ -0,0 +1,76 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+func newHedgeDelays() (*router.HedgeDelays, *latencyClock) {
+	clock := &latencyClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return router.NewTestHedgeDelays(clock.Now), clock
+}
+
+func TestHedgeDelaysPercentile(t *testing.T) {
+	t.Parallel()
+
+	delays, _ := newHedgeDelays()
+	for i := 1; i <= 9; i++ {
+		delays.Observe("fast", testModel, time.Duration(i)*100*time.Millisecond)
+	}
+	if _, ok := delays.Delay("fast", testModel, 95); ok {
+		t.Fatal("Delay() = true with too few samples, want false")
+	}
+
+	delays.Observe("fast", testModel, time.Second)
+	if got, ok := delays.Delay("fast", testModel, 90); !ok || got != 900*time.Millisecond {
+		t.Errorf("Delay(p90) = %v, %v, want 900ms, true", got, ok)
+	}
+	if got, ok := delays.Delay("fast", testModel, 50); !ok || got != 500*time.Millisecond {
+		t.Errorf("Delay(p50) = %v, %v, want 500ms, true", got, ok)
+	}
+	if got, ok := delays.Delay("fast", "claude-haiku-4-5", 90); !ok || got != 900*time.Millisecond {
+		t.Errorf("Delay() for an unmeasured model = %v, %v, want the provider-wide 900ms", got, ok)
+	}
+}
+
+func TestHedgeDelaysForgetsStaleSamples(t *testing.T) {
+	t.Parallel()
+
+	delays, clock := newHedgeDelays()
+	for range 10 {
+		delays.Observe("fast", testModel, 200*time.Millisecond)
+	}
+	clock.Advance(10 * time.Minute)
+	if _, ok := delays.Delay("fast", testModel, 95); ok {
+		t.Error("Delay() = true after samples went stale, want false")
+	}
+}
+
+func TestSelectHedge(t *testing.T) {
+	t.Parallel()
+
+	infos := []router.ProviderInfo{
+		router.NewTestProviderInfo("primary", 3, 1, router.AlwaysHealthy()),
+		router.NewTestProviderInfo("down", 2, 1, router.NeverHealthy()),
+		router.NewTestProviderInfo("backup", 1, 1, router.AlwaysHealthy()),
+	}
+
+	for _, rtr := range []router.ProviderRouter{router.NewFailoverRouter(0), router.NewRoundRobinRouter()} {
+		hedge, err := router.SelectHedge(context.Background(), rtr, infos, infos[0])
+		if err != nil {
+			t.Fatalf("%s: SelectHedge() unexpected error: %v", rtr.Name(), err)
+		}
+		if hedge.Provider.Name() != "backup" {
+			t.Errorf("%s: SelectHedge() = %q, want the other healthy provider", rtr.Name(), hedge.Provider.Name())
+		}
+	}
+
+	_, err := router.SelectHedge(context.Background(), router.NewRoundRobinRouter(), infos[:2], infos[0])
+	if !errors.Is(err, router.ErrNoProviders) {
+		t.Errorf("SelectHedge() without another healthy provider error = %v, want ErrNoProviders", err)
+	}
+}
@@ -0,0 +1,255 @@
+package router
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+const (
+	// latencyDecay is the time constant of the moving averages. A sample's
+	// weight falls to 1/e after this long, so old measurements fade out.
+	latencyDecay = 30 * time.Second
+
+	// latencyStaleAfter is how long measurements stay usable. Providers not
+	// measured within it are treated as unmeasured and tried again.
+	latencyStaleAfter = 5 * time.Minute
+
+	// latencyExplorePercent is the share of requests sent to a random healthy
+	// provider, so providers that looked slow are re-measured.
+	latencyExplorePercent = 5
+
+	// latencyReferenceTokens is the response length scores are estimated for.
+	// It sets how much throughput counts relative to time to first token.
+	latencyReferenceTokens = 256
+)
+
+// LatencySample is one response's observed speed.
+type LatencySample struct {
+	// TimeToFirstToken is how long the provider took to start the response,
+	// or 0 when unknown, as for non-streaming responses.
+	TimeToFirstToken time.Duration
+	// Generation is how long the provider took to produce OutputTokens.
+	Generation time.Duration
+	// OutputTokens is the number of tokens the response generated.
+	OutputTokens int64
+}
+
+// LatencyObserver is implemented by routers that learn from response latency.
+// The proxy handler reports a sample for every successful response.
+type LatencyObserver interface {
+	ObserveLatency(provider, model string, sample LatencySample)
+}
+
+type modelContextKey struct{}
+
+// WithModel returns a context carrying the requested model, which
+// model-aware routers use to look up per-model measurements.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelContextKey{}, model)
+}
+
+// ModelFromContext returns the requested model, or "" if none was set.
+func ModelFromContext(ctx context.Context) string {
+	if model, ok := ctx.Value(modelContextKey{}).(string); ok {
+		return model
+	}
+	return ""
+}
+
+// latencyKey identifies measurements. An empty model holds the provider's
+// measurements across all models.
+type latencyKey struct {
+	provider string
+	model    string
+}
+
+// latencyStats are the moving averages of one provider (and model).
+type latencyStats struct {
+	updated time.Time
+	// ttft is the peak-EWMA of time to first token in seconds: slower samples
+	// are taken at once, faster ones are averaged in.
+	ttft float64
+	// tokensPerSec is the EWMA of output throughput, 0 until measured.
+	tokensPerSec float64
+}
+
+// LatencyAwareRouter selects the provider expected to respond fastest,
+// based on time to first token and output throughput measured per provider
+// and per model. Expected latency is scaled by in-flight requests so load
+// spreads before a provider saturates.
+//
+// Providers without recent measurements are tried when idle, and a small
+// share of requests goes to a random provider so slow ones are re-measured.
+type LatencyAwareRouter struct {
+	stats          map[latencyKey]*latencyStats
+	inFlight       map[string]int64
+	now            func() time.Time
+	mu             sync.Mutex
+	explorePercent int
+}
+
+// NewLatencyAwareRouter creates a new latency-aware router.
+func NewLatencyAwareRouter() *LatencyAwareRouter {
+	return &LatencyAwareRouter{
+		stats:          make(map[latencyKey]*latencyStats),
+		inFlight:       make(map[string]int64),
+		now:            time.Now,
+		mu:             sync.Mutex{},
+		explorePercent: latencyExplorePercent,
+	}
+}
+
+// Select chooses the healthy provider with the lowest expected latency for
+// the model in ctx.
+func (r *LatencyAwareRouter) Select(ctx context.Context, infos []ProviderInfo) (ProviderInfo, error) {
+	if len(infos) == 0 {
+		return ProviderInfo{}, ErrNoProviders
+	}
+
+	healthy := FilterHealthy(infos)
+	if len(healthy) == 0 {
+		return ProviderInfo{}, ErrAllProvidersUnhealthy
+	}
+	if len(healthy) == 1 {
+		return healthy[0], nil
+	}
+	if randIntn(100) < r.explorePercent {
+		return healthy[randIntn(len(healthy))], nil
+	}
+
+	model := ModelFromContext(ctx)
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var best, leastBusy ProviderInfo
+	bestScore, fewestInFlight := math.Inf(1), int64(math.MaxInt64)
+	for _, info := range sortByPriority(healthy) {
+		name := info.Provider.Name()
+		inFlight := r.inFlight[name]
+		stats := r.lookup(name, model, now)
+		if stats == nil {
+			// Unmeasured: measure it with the next request if it is idle.
+			if inFlight == 0 {
+				return info, nil
+			}
+			if inFlight < fewestInFlight {
+				leastBusy, fewestInFlight = info, inFlight
+			}
+			continue
+		}
+		if score := stats.expectedSeconds() * float64(inFlight+1); score < bestScore {
+			best, bestScore = info, score
+		}
+	}
+
+	if best.Provider != nil {
+		return best, nil
+	}
+	// Every provider is unmeasured and already being measured.
+	return leastBusy, nil
+}
+
+// Name returns the strategy name.
+func (r *LatencyAwareRouter) Name() string {
+	return StrategyLatencyAware
+}
+
+// Acquire increments the in-flight count for a provider.
+func (r *LatencyAwareRouter) Acquire(provider providers.Provider) {
+	r.mu.Lock()
+	r.inFlight[provider.Name()]++
+	r.mu.Unlock()
+}
+
+// Release decrements the in-flight count for a provider.
+func (r *LatencyAwareRouter) Release(provider providers.Provider) {
+	r.mu.Lock()
+	if r.inFlight[provider.Name()] > 0 {
+		r.inFlight[provider.Name()]--
+	}
+	r.mu.Unlock()
+}
+
+// ObserveLatency folds a response's latency into the provider's measurements,
+// both for the model and across all models.
+func (r *LatencyAwareRouter) ObserveLatency(provider, model string, sample LatencySample) {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observe(latencyKey{provider: provider, model: ""}, sample, now)
+	if model != "" {
+		r.observe(latencyKey{provider: provider, model: model}, sample, now)
+	}
+}
+
+func (r *LatencyAwareRouter) observe(key latencyKey, sample LatencySample, now time.Time) {
+	ttft := sample.TimeToFirstToken.Seconds()
+	tokensPerSec := 0.0
+	if sample.OutputTokens > 0 && sample.Generation > 0 {
+		tokensPerSec = float64(sample.OutputTokens) / sample.Generation.Seconds()
+	}
+
+	stats, ok := r.stats[key]
+	if !ok || now.Sub(stats.updated) > latencyStaleAfter {
+		// A sample without a time to first token cannot start measurements,
+		// or the provider would look as if it responded instantly.
+		if ttft <= 0 {
+			return
+		}
+		r.stats[key] = &latencyStats{updated: now, ttft: ttft, tokensPerSec: tokensPerSec}
+		return
+	}
+
+	// Weight the previous averages by how recently they were updated.
+	weight := math.Exp(-float64(now.Sub(stats.updated)) / float64(latencyDecay))
+	stats.updated = now
+	if ttft > stats.ttft {
+		stats.ttft = ttft
+	} else if ttft > 0 {
+		stats.ttft = ewma(stats.ttft, ttft, weight)
+	}
+	if tokensPerSec > 0 {
+		stats.tokensPerSec = ewma(stats.tokensPerSec, tokensPerSec, weight)
+	}
+}
+
+// ewma folds sample into average, keeping weight of the average.
+// An unset average takes the sample as is.
+func ewma(average, sample, weight float64) float64 {
+	if average <= 0 {
+		return sample
+	}
+	return average*weight + sample*(1-weight)
+}
+
+// lookup returns fresh measurements for provider, preferring those for
+// model. Returns nil if the provider has no recent measurements.
+func (r *LatencyAwareRouter) lookup(provider, model string, now time.Time) *latencyStats {
+	keys := []latencyKey{{provider: provider, model: model}}
+	if model != "" {
+		keys = append(keys, latencyKey{provider: provider, model: ""})
+	}
+	for _, key := range keys {
+		if stats, ok := r.stats[key]; ok && now.Sub(stats.updated) <= latencyStaleAfter {
+			return stats
+		}
+	}
+	return nil
+}
+
+// expectedSeconds estimates how long a response of latencyReferenceTokens
+// takes. Until throughput is measured only time to first token counts.
+func (s *latencyStats) expectedSeconds() float64 {
+	if s.tokensPerSec <= 0 {
+		return s.ttft
+	}
+	return s.ttft + latencyReferenceTokens/s.tokensPerSec
+}
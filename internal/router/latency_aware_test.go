@@ -0,0 +1,243 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/router"
+)
+
+const testModel = "claude-sonnet-4"
+
+// latencyClock is a settable clock for latency-aware router tests.
+type latencyClock struct {
+	now time.Time
+}
+
+func (c *latencyClock) Now() time.Time { return c.now }
+
+func (c *latencyClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newLatencyRouter() (*router.LatencyAwareRouter, *latencyClock) {
+	clock := &latencyClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return router.NewTestLatencyAwareRouter(clock.Now), clock
+}
+
+func streamSample(ttft, generation time.Duration, tokens int64) router.LatencySample {
+	return router.LatencySample{TimeToFirstToken: ttft, Generation: generation, OutputTokens: tokens}
+}
+
+func selectName(t *testing.T, rtr router.ProviderRouter, model string, infos []router.ProviderInfo) string {
+	t.Helper()
+	selected, err := rtr.Select(router.WithModel(context.Background(), model), infos)
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	return selected.Provider.Name()
+}
+
+func latencyInfos(names ...string) []router.ProviderInfo {
+	infos := make([]router.ProviderInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, router.NewTestProviderInfo(name, 1, 1, router.AlwaysHealthy()))
+	}
+	return infos
+}
+
+func TestLatencyAwareRouterName(t *testing.T) {
+	t.Parallel()
+
+	rtr := router.NewLatencyAwareRouter()
+	if rtr.Name() != router.StrategyLatencyAware {
+		t.Errorf("Name() = %q, want %q", rtr.Name(), router.StrategyLatencyAware)
+	}
+}
+
+func TestLatencyAwareRouterSelectErrors(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	if _, err := rtr.Select(context.Background(), nil); !errors.Is(err, router.ErrNoProviders) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrNoProviders)
+	}
+
+	unhealthy := []router.ProviderInfo{router.NewTestProviderInfo("p1", 1, 1, router.NeverHealthy())}
+	if _, err := rtr.Select(context.Background(), unhealthy); !errors.Is(err, router.ErrAllProvidersUnhealthy) {
+		t.Errorf("Select() error = %v, want %v", err, router.ErrAllProvidersUnhealthy)
+	}
+}
+
+func TestLatencyAwareRouterPrefersFasterProvider(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	rtr.ObserveLatency("slow", testModel, streamSample(2*time.Second, 4*time.Second, 200))
+	rtr.ObserveLatency("fast", testModel, streamSample(300*time.Millisecond, 2*time.Second, 200))
+
+	if got := selectName(t, rtr, testModel, latencyInfos("slow", "fast")); got != "fast" {
+		t.Errorf("Select() = %q, want fast", got)
+	}
+}
+
+func TestLatencyAwareRouterWeighsThroughput(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	// quick-start starts first but generates 10 tokens/s, steady generates 100 tokens/s.
+	rtr.ObserveLatency("quick-start", testModel, streamSample(200*time.Millisecond, 10*time.Second, 100))
+	rtr.ObserveLatency("steady", testModel, streamSample(time.Second, time.Second, 100))
+
+	if got := selectName(t, rtr, testModel, latencyInfos("quick-start", "steady")); got != "steady" {
+		t.Errorf("Select() = %q, want steady", got)
+	}
+}
+
+func TestLatencyAwareRouterMeasuresUnknownProviders(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	rtr.ObserveLatency("measured", testModel, streamSample(100*time.Millisecond, time.Second, 100))
+	infos := latencyInfos("measured", "new")
+
+	if got := selectName(t, rtr, testModel, infos); got != "new" {
+		t.Fatalf("Select() = %q, want the unmeasured provider", got)
+	}
+
+	// While the first measurement is in flight, measured providers are preferred.
+	rtr.Acquire(infos[1].Provider)
+	if got := selectName(t, rtr, testModel, infos); got != "measured" {
+		t.Errorf("Select() = %q, want measured while new is being measured", got)
+	}
+	rtr.Release(infos[1].Provider)
+}
+
+func TestLatencyAwareRouterIgnoresSamplesWithoutFirstToken(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	// Non-streaming responses report no time to first token.
+	rtr.ObserveLatency("unstreamed", testModel, streamSample(0, 0, 0))
+	rtr.ObserveLatency("streamed", testModel, streamSample(time.Second, 0, 0))
+	infos := latencyInfos("unstreamed", "streamed")
+
+	// unstreamed is still unmeasured, so once it is busy the measured provider wins.
+	rtr.Acquire(infos[0].Provider)
+	if got := selectName(t, rtr, testModel, infos); got != "streamed" {
+		t.Errorf("Select() = %q, want streamed over a provider measured at zero latency", got)
+	}
+	rtr.Release(infos[0].Provider)
+}
+
+func TestLatencyAwareRouterPerModel(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	rtr.ObserveLatency("p1", "claude-opus-4", streamSample(3*time.Second, 0, 0))
+	rtr.ObserveLatency("p2", "claude-opus-4", streamSample(time.Second, 0, 0))
+	rtr.ObserveLatency("p1", testModel, streamSample(100*time.Millisecond, 0, 0))
+	rtr.ObserveLatency("p2", testModel, streamSample(500*time.Millisecond, 0, 0))
+	infos := latencyInfos("p1", "p2")
+
+	if got := selectName(t, rtr, "claude-opus-4", infos); got != "p2" {
+		t.Errorf("Select(opus) = %q, want p2", got)
+	}
+	if got := selectName(t, rtr, testModel, infos); got != "p1" {
+		t.Errorf("Select(sonnet) = %q, want p1", got)
+	}
+	// Unseen models fall back to each provider's measurements across models,
+	// where p1's slow opus sample still dominates its peak.
+	if got := selectName(t, rtr, "claude-haiku-4", infos); got != "p2" {
+		t.Errorf("Select(haiku) = %q, want p2", got)
+	}
+}
+
+func TestLatencyAwareRouterScalesByInFlight(t *testing.T) {
+	t.Parallel()
+
+	rtr, _ := newLatencyRouter()
+	rtr.ObserveLatency("p1", testModel, streamSample(time.Second, 0, 0))
+	rtr.ObserveLatency("p2", testModel, streamSample(1500*time.Millisecond, 0, 0))
+	infos := latencyInfos("p1", "p2")
+
+	if got := selectName(t, rtr, testModel, infos); got != "p1" {
+		t.Fatalf("Select() = %q, want p1 when idle", got)
+	}
+
+	rtr.Acquire(infos[0].Provider)
+	if got := selectName(t, rtr, testModel, infos); got != "p2" {
+		t.Errorf("Select() = %q, want p2 while p1 is busy", got)
+	}
+	rtr.Release(infos[0].Provider)
+	if count := router.LatencyInFlight(rtr, infos[0].Provider); count != 0 {
+		t.Errorf("in-flight after Release = %d, want 0", count)
+	}
+}
+
+func TestLatencyAwareRouterPeakDecays(t *testing.T) {
+	t.Parallel()
+
+	rtr, clock := newLatencyRouter()
+	infos := latencyInfos("p1", "p2")
+	rtr.ObserveLatency("p1", testModel, streamSample(200*time.Millisecond, 0, 0))
+	rtr.ObserveLatency("p2", testModel, streamSample(400*time.Millisecond, 0, 0))
+
+	// A single slow response is taken at once.
+	clock.Advance(time.Second)
+	rtr.ObserveLatency("p1", testModel, streamSample(3*time.Second, 0, 0))
+	if got := selectName(t, rtr, testModel, infos); got != "p2" {
+		t.Fatalf("Select() = %q, want p2 after p1's slow response", got)
+	}
+
+	// Fast responses spread out over time pull the average back down.
+	for range 5 {
+		clock.Advance(time.Minute)
+		rtr.ObserveLatency("p1", testModel, streamSample(200*time.Millisecond, 0, 0))
+		rtr.ObserveLatency("p2", testModel, streamSample(400*time.Millisecond, 0, 0))
+	}
+	if got := selectName(t, rtr, testModel, infos); got != "p1" {
+		t.Errorf("Select() = %q, want p1 once its slow response has decayed", got)
+	}
+}
+
+func TestLatencyAwareRouterRemeasuresStaleProviders(t *testing.T) {
+	t.Parallel()
+
+	rtr, clock := newLatencyRouter()
+	infos := latencyInfos("p1", "p2")
+	rtr.ObserveLatency("p1", testModel, streamSample(5*time.Second, 0, 0))
+	rtr.ObserveLatency("p2", testModel, streamSample(time.Second, 0, 0))
+
+	if got := selectName(t, rtr, testModel, infos); got != "p2" {
+		t.Fatalf("Select() = %q, want p2", got)
+	}
+
+	// Only p2 keeps being measured; p1's measurement goes stale and it is tried again.
+	clock.Advance(4 * time.Minute)
+	rtr.ObserveLatency("p2", testModel, streamSample(time.Second, 0, 0))
+	clock.Advance(2 * time.Minute)
+	if got := selectName(t, rtr, testModel, infos); got != "p1" {
+		t.Errorf("Select() = %q, want stale p1 to be re-measured", got)
+	}
+}
+
+func TestAsLatencyObserver(t *testing.T) {
+	t.Parallel()
+
+	latency := router.NewLatencyAwareRouter()
+	live := router.NewLiveRouter(func() router.ProviderRouter { return latency })
+
+	if observer, ok := router.AsLatencyObserver(live); !ok || observer != latency {
+		t.Errorf("AsLatencyObserver(live) = %v, %v, want the latency-aware router", observer, ok)
+	}
+	if _, ok := router.AsLatencyObserver(router.NewLeastLoadedRouter()); ok {
+		t.Error("AsLatencyObserver(least_loaded) = true, want false")
+	}
+}
+
+// Verify interface compliance.
+var (
+	_ router.LatencyObserver     = (*router.LatencyAwareRouter)(nil)
+	_ router.ProviderLoadTracker = (*router.LatencyAwareRouter)(nil)
+)
@@ -155,5 +155,55 @@ func TestLiveRouterAcquireReleaseWithNonTrackerRouter(t *testing.T) {
 	router.LiveRouterRelease(liveRouter, provider)
 }
 
+func TestAsFailoverResolvesLiveRouter(t *testing.T) {
+	t.Parallel()
+
+	useFailover := true
+	live := router.NewLiveRouter(func() router.ProviderRouter {
+		if useFailover {
+			return router.NewFailoverRouter(0)
+		}
+		return router.NewRoundRobinRouter()
+	})
+
+	if _, ok := router.AsFailover(live); !ok {
+		t.Error("AsFailover() = false for live failover router, want true")
+	}
+
+	useFailover = false
+
+	if _, ok := router.AsFailover(live); ok {
+		t.Error("AsFailover() = true for live round_robin router, want false")
+	}
+}
+
+func TestAsFailoverStrategies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rtr  router.ProviderRouter
+		name string
+		want bool
+	}{
+		{name: "failover", rtr: router.NewFailoverRouter(0), want: true},
+		{name: "weighted_failover", rtr: router.NewWeightedFailoverRouter(0), want: true},
+		{name: "round_robin", rtr: router.NewRoundRobinRouter(), want: false},
+		{name: "least_loaded", rtr: router.NewLeastLoadedRouter(), want: false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			if _, ok := router.AsFailover(testCase.rtr); ok != testCase.want {
+				t.Errorf("AsFailover() = %v, want %v", ok, testCase.want)
+			}
+		})
+	}
+}
+
 // Verify interface compliance.
-var _ router.ProviderRouter = (*router.LiveRouter)(nil)
+var (
+	_ router.ProviderRouter  = (*router.LiveRouter)(nil)
+	_ router.FailoverCapable = (*router.FailoverRouter)(nil)
+	_ router.FailoverCapable = (*router.WeightedFailoverRouter)(nil)
+)
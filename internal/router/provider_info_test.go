@@ -119,3 +119,25 @@ func TestFilterHealthyDynamic(t *testing.T) {
 		t.Errorf("Second FilterHealthy() = %d, want 0", len(healthy2))
 	}
 }
+
+func TestProviderInfoPriceFor(t *testing.T) {
+	t.Parallel()
+
+	info := router.NewTestProviderInfo("p1", 1, 1, nil)
+	info.Prices = map[string]router.ModelPrice{
+		"claude":      {InputPerMTok: 1, OutputPerMTok: 5, Quality: 5},
+		"claude-opus": {InputPerMTok: 15, OutputPerMTok: 75, Quality: 10},
+	}
+
+	price, ok := info.PriceFor("claude-opus-4-20250514")
+	if !ok || price.Quality != 10 {
+		t.Errorf("PriceFor(opus) = %+v, %v, want the claude-opus price", price, ok)
+	}
+	price, ok = info.PriceFor("claude-haiku-4")
+	if !ok || price.Quality != 5 {
+		t.Errorf("PriceFor(haiku) = %+v, %v, want the claude price", price, ok)
+	}
+	if _, ok := info.PriceFor("glm-4.6"); ok {
+		t.Error("PriceFor(glm) found a price, want none")
+	}
+}
@@ -203,6 +203,7 @@ func createTestProviders(n int, allHealthy bool) []router.ProviderInfo {
 			Weight:    idx + 1, // Use weight as identifier (1, 2, 3, ...)
 			Priority:  idx,
 			IsHealthy: func() bool { return healthy },
+			Prices:    nil,
 		}
 	}
 	return providers
@@ -12,12 +12,16 @@
 //   - weighted_round_robin: Rotate with weights (higher weight = more requests)
 //   - shuffle: Random selection for load distribution
 //   - failover: Try providers in priority order until one succeeds (default)
+//   - least_loaded: Pick the provider with the fewest in-flight requests
+//   - latency_aware: Pick the provider expected to respond fastest
+//   - cost_optimized: Pick the cheapest provider for the requested model
 package router
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/omarluq/cc-relay/internal/providers"
@@ -33,6 +37,8 @@ const (
 	StrategyModelBased         = "model_based"
 	StrategyLeastLoaded        = "least_loaded"
 	StrategyWeightedFailover   = "weighted_failover"
+	StrategyLatencyAware       = "latency_aware"
+	StrategyCostOptimized      = "cost_optimized"
 )
 
 // Common errors returned by routers.
@@ -65,13 +71,42 @@ type ProviderLoadTracker interface {
 	Release(provider providers.Provider)
 }
 
+// FailoverCapable is implemented by routers that can re-dispatch a request
+// to alternate providers when the selected one fails a trigger condition.
+// The proxy handler uses it to drive sequential cross-provider retries.
+type FailoverCapable interface {
+	// FailoverOrder returns the healthy providers in the order they should be attempted.
+	// The first element is the provider Select would return.
+	FailoverOrder(providers []ProviderInfo) ([]ProviderInfo, error)
+
+	// Triggers returns the conditions that cause a failover to the next provider.
+	Triggers() []FailoverTrigger
+}
+
 // ProviderInfo wraps a provider with routing metadata.
 // This contains all information needed for routing decisions.
 type ProviderInfo struct {
 	Provider  providers.Provider
 	IsHealthy func() bool
-	Weight    int
-	Priority  int
+	// Prices maps model name prefixes to what the provider charges for
+	// requests for those models. Used by cost_optimized routing.
+	Prices   map[string]ModelPrice
+	Weight   int
+	Priority int
+}
+
+// ModelPrice is what a provider charges for requests for a model, in USD per
+// million tokens, and the quality of the model it serves them with.
+type ModelPrice struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+	// Quality rates the model the provider serves; higher is more capable.
+	Quality int
+}
+
+// rank is the price cost_optimized routing compares providers by.
+func (p ModelPrice) rank() float64 {
+	return p.InputPerMTok + p.OutputPerMTok
 }
 
 // Healthy returns true if the provider is currently healthy.
@@ -83,6 +118,22 @@ func (p ProviderInfo) Healthy() bool {
 	return p.IsHealthy()
 }
 
+// PriceFor returns the provider's price for requests for model, using the
+// longest matching prefix in Prices.
+func (p ProviderInfo) PriceFor(model string) (ModelPrice, bool) {
+	var (
+		best  ModelPrice
+		found bool
+		depth int
+	)
+	for prefix, price := range p.Prices {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > depth) {
+			best, found, depth = price, true, len(prefix)
+		}
+	}
+	return best, found
+}
+
 // FilterHealthy returns only healthy providers from the input slice.
 // Uses lo.Filter for functional-style filtering.
 func FilterHealthy(providerInfos []ProviderInfo) []ProviderInfo {
@@ -124,6 +175,10 @@ func NewRouter(strategy string, timeout time.Duration) (ProviderRouter, error) {
 		return NewLeastLoadedRouter(), nil
 	case StrategyWeightedFailover:
 		return NewWeightedFailoverRouter(timeout), nil
+	case StrategyLatencyAware:
+		return NewLatencyAwareRouter(), nil
+	case StrategyCostOptimized:
+		return NewCostOptimizedRouter(), nil
 	default:
 		return nil, fmt.Errorf("router: unknown strategy %q", strategy)
 	}
@@ -155,6 +210,16 @@ func (l *LiveRouter) Name() string {
 	return l.fn().Name()
 }
 
+// AsFailover returns the router as a FailoverCapable if its current strategy supports failover.
+// LiveRouter is resolved to the router active at call time.
+func AsFailover(providerRouter ProviderRouter) (FailoverCapable, bool) {
+	if live, ok := providerRouter.(*LiveRouter); ok {
+		providerRouter = live.fn()
+	}
+	failover, ok := providerRouter.(FailoverCapable)
+	return failover, ok
+}
+
 // Acquire increments in-flight count for least_loaded routers when supported.
 func (l *LiveRouter) Acquire(provider providers.Provider) {
 	if tracker, ok := l.fn().(ProviderLoadTracker); ok {
@@ -168,3 +233,13 @@ func (l *LiveRouter) Release(provider providers.Provider) {
 		tracker.Release(provider)
 	}
 }
+
+// AsLatencyObserver returns the router as a LatencyObserver if its current
+// strategy learns from latency. LiveRouter is resolved to the router active at call time.
+func AsLatencyObserver(providerRouter ProviderRouter) (LatencyObserver, bool) {
+	if live, ok := providerRouter.(*LiveRouter); ok {
+		providerRouter = live.fn()
+	}
+	observer, ok := providerRouter.(LatencyObserver)
+	return observer, ok
+}
@@ -51,6 +51,16 @@ func TestStrategyConstants(t *testing.T) {
 			constant: router.StrategyWeightedFailover,
 			expected: "weighted_failover",
 		},
+		{
+			name:     "latency aware",
+			constant: router.StrategyLatencyAware,
+			expected: "latency_aware",
+		},
+		{
+			name:     "cost optimized",
+			constant: router.StrategyCostOptimized,
+			expected: "cost_optimized",
+		},
 	}
 
 	for _, testCase := range tests {
@@ -123,6 +133,36 @@ func TestNewRouterLeastLoaded(t *testing.T) {
 	}
 }
 
+func TestNewRouterLatencyAware(t *testing.T) {
+	t.Parallel()
+
+	rtr, err := router.NewRouter(router.StrategyLatencyAware, 0)
+	if err != nil {
+		t.Fatalf("router.NewRouter(%q) unexpected error: %v", router.StrategyLatencyAware, err)
+	}
+
+	if _, ok := rtr.(*router.LatencyAwareRouter); !ok {
+		t.Errorf("router.NewRouter() returned %T, want *router.LatencyAwareRouter", rtr)
+	}
+
+	if rtr.Name() != router.StrategyLatencyAware {
+		t.Errorf("Name() = %q, want %q", rtr.Name(), router.StrategyLatencyAware)
+	}
+}
+
+func TestNewRouterCostOptimized(t *testing.T) {
+	t.Parallel()
+
+	rtr, err := router.NewRouter(router.StrategyCostOptimized, 0)
+	if err != nil {
+		t.Fatalf("router.NewRouter(%q) unexpected error: %v", router.StrategyCostOptimized, err)
+	}
+
+	if _, ok := rtr.(*router.CostOptimizedRouter); !ok {
+		t.Errorf("router.NewRouter() returned %T, want *router.CostOptimizedRouter", rtr)
+	}
+}
+
 func TestNewRouterWeightedFailover(t *testing.T) {
 	t.Parallel()
 
@@ -236,6 +276,7 @@ func (m *mockRouter) Select(_ context.Context, _ []router.ProviderInfo) (router.
 		Weight:    0,
 		Priority:  0,
 		IsHealthy: nil,
+		Prices:    nil,
 	}, nil
 }
 
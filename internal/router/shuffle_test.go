@@ -40,12 +40,14 @@ func TestShuffleRouterAllUnhealthy(t *testing.T) {
 		Weight:    5,
 		Priority:  1,
 		IsHealthy: func() bool { return false },
+		Prices:    nil,
 	}
 	prov2 := router.ProviderInfo{
 		Provider:  router.NewTestProvider("b"),
 		Weight:    10,
 		Priority:  2,
 		IsHealthy: func() bool { return false },
+		Prices:    nil,
 	}
 	providers := []router.ProviderInfo{prov1, prov2}
 
@@ -291,6 +293,7 @@ func createShuffleTestProviders(n int) []router.ProviderInfo {
 			Weight:    idx + 1, // Use weight as identifier (1, 2, 3, ...)
 			Priority:  idx,
 			IsHealthy: func() bool { return true },
+			Prices:    nil,
 		}
 	}
 	return providers
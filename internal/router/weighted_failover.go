@@ -42,6 +42,20 @@ func (r *WeightedFailoverRouter) Select(_ context.Context, providers []ProviderI
 	return order[0], nil
 }
 
+// FailoverOrder returns healthy providers in a freshly drawn weighted order.
+func (r *WeightedFailoverRouter) FailoverOrder(providers []ProviderInfo) ([]ProviderInfo, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	healthy := FilterHealthy(providers)
+	if len(healthy) == 0 {
+		return nil, ErrAllProvidersUnhealthy
+	}
+
+	return r.weightedOrder(healthy), nil
+}
+
 // Name returns the strategy name.
 func (r *WeightedFailoverRouter) Name() string {
 	return StrategyWeightedFailover
@@ -57,6 +57,33 @@ func TestWeightedFailoverRouterCustomTriggers(t *testing.T) {
 	}
 }
 
+func TestWeightedFailoverRouterFailoverOrder(t *testing.T) {
+	t.Parallel()
+
+	rtr := router.NewWeightedFailoverRouter(0)
+	providers := []router.ProviderInfo{
+		router.NewTestProviderInfo("p1", 0, 1, router.AlwaysHealthy()),
+		router.NewTestProviderInfo("p2", 0, 5, router.NeverHealthy()),
+		router.NewTestProviderInfo("p3", 0, 2, router.AlwaysHealthy()),
+	}
+	order, err := rtr.FailoverOrder(providers)
+	if err != nil {
+		t.Fatalf("FailoverOrder() unexpected error: %v", err)
+	}
+	if len(order) != 2 {
+		t.Fatalf("FailoverOrder() returned %d providers, want 2 healthy", len(order))
+	}
+	for _, info := range order {
+		if info.Provider.Name() == "p2" {
+			t.Error("FailoverOrder() included unhealthy provider p2")
+		}
+	}
+
+	if _, err := rtr.FailoverOrder(nil); !errors.Is(err, router.ErrNoProviders) {
+		t.Errorf("FailoverOrder(nil) error = %v, want %v", err, router.ErrNoProviders)
+	}
+}
+
 func TestWeightedFailoverRouterSelectNoProviders(t *testing.T) {
 	t.Parallel()
 
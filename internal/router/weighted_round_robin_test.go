@@ -26,6 +26,7 @@ func newWRRInfo(name string, weight int, isHealthy func() bool) router.ProviderI
 		IsHealthy: isHealthy,
 		Weight:    weight,
 		Priority:  0,
+		Prices:    nil,
 	}
 }
 
@@ -429,6 +430,7 @@ func TestGetEffectiveWeight(t *testing.T) {
 				Weight:    testCase.weight,
 				Priority:  0,
 				IsHealthy: nil,
+				Prices:    nil,
 			}
 			if got := router.GetEffectiveWeight(prov); got != testCase.expected {
 				t.Errorf("router.GetEffectiveWeight() = %d, want %d", got, testCase.expected)
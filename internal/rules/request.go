@@ -0,0 +1,72 @@
+package rules
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/omarluq/cc-relay/internal/tokenizer"
+)
+
+// Request holds what rules match on. The body is only inspected when a rule
+// asks for one of its features, and input tokens are estimated at most once.
+type Request struct {
+	// Time is when the request was received.
+	Time time.Time
+	// Header is the client's request header.
+	Header http.Header
+	// Model is the model the client requested.
+	Model string
+	// Client is the virtual key client name, or "" if unauthenticated.
+	Client string
+	// Body is the Anthropic Messages request body.
+	Body []byte
+
+	inputTokens int
+	counted     bool
+}
+
+// HasTools reports whether the request defines tools.
+func (r *Request) HasTools() bool {
+	return len(gjson.GetBytes(r.Body, "tools").Array()) > 0
+}
+
+// HasThinking reports whether the request enables extended thinking.
+func (r *Request) HasThinking() bool {
+	thinking := gjson.GetBytes(r.Body, "thinking.type").String()
+	return thinking != "" && thinking != "disabled"
+}
+
+// HasImages reports whether any message contains an image, including images
+// returned in tool results.
+func (r *Request) HasImages() bool {
+	found := false
+	gjson.GetBytes(r.Body, "messages.#.content").ForEach(func(_, content gjson.Result) bool {
+		found = containsImage(content)
+		return !found
+	})
+	return found
+}
+
+func containsImage(content gjson.Result) bool {
+	found := false
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "image":
+			found = true
+		case "tool_result":
+			found = containsImage(block.Get("content"))
+		}
+		return !found
+	})
+	return found
+}
+
+// InputTokens returns the estimated input tokens of the request.
+func (r *Request) InputTokens() int {
+	if !r.counted {
+		r.inputTokens, r.counted = tokenizer.CountRequest(r.Body), true
+	}
+	return r.inputTokens
+}
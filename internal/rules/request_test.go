@@ -0,0 +1,54 @@
+package rules_test
+
+import "testing"
+
+func TestRequestHasThinking(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"thinking":{"type":"enabled","budget_tokens":2048}}`, true},
+		{`{"thinking":{"type":"disabled"}}`, false},
+		{`{"messages":[]}`, false},
+	}
+	for _, tt := range tests {
+		if got := newRequest("claude", tt.body).HasThinking(); got != tt.want {
+			t.Errorf("HasThinking(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRequestHasImages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"text only", `{"messages":[{"role":"user","content":"hi"}]}`, false},
+		{"image block", `{"messages":[{"role":"user","content":[` +
+			`{"type":"text","text":"what is this"},{"type":"image","source":{"type":"base64"}}]}]}`, true},
+		{"image in tool result", `{"messages":[{"role":"user","content":[` +
+			`{"type":"tool_result","content":[{"type":"image","source":{"type":"base64"}}]}]}]}`, true},
+		{"no messages", `{}`, false},
+	}
+	for _, tt := range tests {
+		if got := newRequest("claude", tt.body).HasImages(); got != tt.want {
+			t.Errorf("HasImages(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequestHasTools(t *testing.T) {
+	t.Parallel()
+
+	if !newRequest("claude", toolsBody).HasTools() {
+		t.Error("HasTools() = false, want true")
+	}
+	if newRequest("claude", `{"tools":[]}`).HasTools() {
+		t.Error("HasTools() = true for an empty tools list")
+	}
+}
@@ -0,0 +1,183 @@
+// Package rules evaluates declarative request routing rules for cc-relay.
+//
+// Rules are evaluated in order and the first rule whose conditions all match
+// a request decides how it is routed: which providers it may be sent to,
+// which routing strategy picks among them, and which model is requested.
+// A request no rule matches is routed by the global routing settings.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTimeOfDay is returned for time_of_day ranges that are not HH:MM-HH:MM.
+var ErrInvalidTimeOfDay = errors.New("rules: time_of_day must be HH:MM-HH:MM")
+
+// Rule routes the requests its Match selects.
+type Rule struct {
+	// Name identifies the rule in logs and debug headers.
+	Name string `yaml:"name" toml:"name"`
+
+	// Strategy is the routing strategy that picks among the rule's providers.
+	// Empty uses routing.strategy.
+	Strategy string `yaml:"strategy" toml:"strategy"`
+
+	// RewriteModel replaces the requested model before provider model mapping.
+	RewriteModel string `yaml:"rewrite_model" toml:"rewrite_model"`
+
+	// Providers restricts routing to the named providers. Empty allows all,
+	// subject to routing.model_mapping.
+	Providers []string `yaml:"providers" toml:"providers"`
+
+	// Match selects the requests the rule applies to. An empty match applies to all.
+	Match Match `yaml:"match" toml:"match"`
+}
+
+// Match holds a rule's conditions. Every condition that is set must hold.
+type Match struct {
+	// Headers maps request header names to the values they must have.
+	// "*" only requires the header to be present.
+	Headers map[string]string `yaml:"headers" toml:"headers"`
+
+	// HasTools, HasThinking and HasImages require the request to define tools,
+	// enable extended thinking or contain images, or, if false, not to.
+	HasTools    *bool `yaml:"has_tools" toml:"has_tools"`
+	HasThinking *bool `yaml:"has_thinking" toml:"has_thinking"`
+	HasImages   *bool `yaml:"has_images" toml:"has_images"`
+
+	// TimeOfDay is a HH:MM-HH:MM range, which may wrap past midnight.
+	TimeOfDay string `yaml:"time_of_day" toml:"time_of_day"`
+
+	// Timezone is the IANA zone TimeOfDay is in. Default: UTC.
+	Timezone string `yaml:"timezone" toml:"timezone"`
+
+	// Models are requested model name prefixes, any of which must match.
+	Models []string `yaml:"models" toml:"models"`
+
+	// Clients are virtual key client names, any of which must match.
+	Clients []string `yaml:"clients" toml:"clients"`
+
+	// MinInputTokens and MaxInputTokens bound the estimated input tokens. Zero is unbounded.
+	MinInputTokens int `yaml:"min_input_tokens" toml:"min_input_tokens"`
+	MaxInputTokens int `yaml:"max_input_tokens" toml:"max_input_tokens"`
+}
+
+// Find returns the first rule that matches req, or false if none does.
+func Find(rules []Rule, req *Request) (*Rule, bool) {
+	for idx := range rules {
+		if rules[idx].Match.Matches(req) {
+			return &rules[idx], true
+		}
+	}
+	return nil, false
+}
+
+// Matches reports whether req meets every condition of m.
+// Cheap conditions are checked first so the body is only inspected when needed.
+func (m *Match) Matches(req *Request) bool {
+	return m.matchesIdentity(req) &&
+		m.matchesTime(req.Time) &&
+		matchesFlag(m.HasTools, req.HasTools) &&
+		matchesFlag(m.HasThinking, req.HasThinking) &&
+		matchesFlag(m.HasImages, req.HasImages) &&
+		m.matchesInputTokens(req)
+}
+
+// matchesIdentity checks the model, client and header conditions.
+func (m *Match) matchesIdentity(req *Request) bool {
+	if len(m.Models) > 0 && !slices.ContainsFunc(m.Models, func(prefix string) bool {
+		return strings.HasPrefix(req.Model, prefix)
+	}) {
+		return false
+	}
+	if len(m.Clients) > 0 && !slices.Contains(m.Clients, req.Client) {
+		return false
+	}
+	for name, want := range m.Headers {
+		if !matchesHeader(req.Header, name, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesHeader(header http.Header, name, want string) bool {
+	values := header.Values(name)
+	if want == "*" {
+		return len(values) > 0
+	}
+	return slices.Contains(values, want)
+}
+
+// matchesFlag checks a presence condition, which holds if unset.
+func matchesFlag(want *bool, has func() bool) bool {
+	return want == nil || *want == has()
+}
+
+func (m *Match) matchesInputTokens(req *Request) bool {
+	if m.MinInputTokens <= 0 && m.MaxInputTokens <= 0 {
+		return true
+	}
+	tokens := req.InputTokens()
+	return tokens >= m.MinInputTokens && (m.MaxInputTokens <= 0 || tokens <= m.MaxInputTokens)
+}
+
+// matchesTime checks the time of day condition. Invalid ranges and zones,
+// which config validation rejects, never match.
+func (m *Match) matchesTime(now time.Time) bool {
+	if m.TimeOfDay == "" {
+		return true
+	}
+	start, end, err := ParseTimeOfDay(m.TimeOfDay)
+	if err != nil {
+		return false
+	}
+	loc, err := loadLocation(m.Timezone)
+	if err != nil {
+		return false
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// ParseTimeOfDay parses a HH:MM-HH:MM range into minutes after midnight.
+// The end is exclusive; an end before the start wraps past midnight.
+func ParseTimeOfDay(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w (got %q)", ErrInvalidTimeOfDay, value)
+	}
+	fromTime, fromErr := time.Parse("15:04", strings.TrimSpace(from))
+	toTime, toErr := time.Parse("15:04", strings.TrimSpace(to))
+	if fromErr != nil || toErr != nil || fromTime.Equal(toTime) {
+		return 0, 0, fmt.Errorf("%w (got %q)", ErrInvalidTimeOfDay, value)
+	}
+	return fromTime.Hour()*60 + fromTime.Minute(), toTime.Hour()*60 + toTime.Minute(), nil
+}
+
+// locations caches time zones by name, since loading one reads the zone database.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		if cached, isLoc := loc.(*time.Location); isLoc {
+			return cached, nil
+		}
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
@@ -0,0 +1,172 @@
+package rules_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/rules"
+)
+
+const toolsBody = `{"model":"claude-sonnet-4","tools":[{"name":"bash"}],"messages":[]}`
+
+func newRequest(model, body string) *rules.Request {
+	return &rules.Request{
+		Time:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Header: http.Header{},
+		Model:  model,
+		Client: "",
+		Body:   []byte(body),
+	}
+}
+
+func flag(value bool) *bool {
+	return &value
+}
+
+func rule(name string, match *rules.Match) rules.Rule {
+	return rules.Rule{Match: *match, Name: name, Strategy: "", RewriteModel: "", Providers: nil}
+}
+
+func emptyMatch() *rules.Match {
+	return &rules.Match{
+		Headers:        nil,
+		HasTools:       nil,
+		HasThinking:    nil,
+		HasImages:      nil,
+		TimeOfDay:      "",
+		Timezone:       "",
+		Models:         nil,
+		Clients:        nil,
+		MinInputTokens: 0,
+		MaxInputTokens: 0,
+	}
+}
+
+func TestFindFirstMatchWins(t *testing.T) {
+	t.Parallel()
+
+	opus := emptyMatch()
+	opus.Models = []string{"claude-opus"}
+	claude := emptyMatch()
+	claude.Models = []string{"claude"}
+	ruleList := []rules.Rule{rule("opus", opus), rule("claude", claude), rule("all", emptyMatch())}
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-opus-4", "opus"},
+		{"claude-sonnet-4", "claude"},
+		{"gpt-4o", "all"},
+	}
+	for _, tt := range tests {
+		got, ok := rules.Find(ruleList, newRequest(tt.model, "{}"))
+		if !ok || got.Name != tt.want {
+			t.Errorf("Find(%q) = %v, %v, want rule %q", tt.model, got, ok, tt.want)
+		}
+	}
+
+	if got, ok := rules.Find(ruleList[:2], newRequest("gpt-4o", "{}")); ok {
+		t.Errorf("Find(gpt-4o) = %q, want no match", got.Name)
+	}
+}
+
+func TestMatchClientsAndHeaders(t *testing.T) {
+	t.Parallel()
+
+	match := emptyMatch()
+	match.Clients = []string{"ci"}
+	match.Headers = map[string]string{"X-Team": "infra", "X-Priority": "*"}
+
+	req := newRequest("claude-sonnet-4", "{}")
+	req.Client = "ci"
+	req.Header.Set("X-Team", "infra")
+	if match.Matches(req) {
+		t.Error("Matches() = true without the required X-Priority header")
+	}
+
+	req.Header.Set("X-Priority", "high")
+	if !match.Matches(req) {
+		t.Error("Matches() = false, want true with client and headers set")
+	}
+
+	req.Client = "alice"
+	if match.Matches(req) {
+		t.Error("Matches() = true for a client not in the rule")
+	}
+}
+
+func TestMatchBodyFeatures(t *testing.T) {
+	t.Parallel()
+
+	withTools := emptyMatch()
+	withTools.HasTools = flag(true)
+	withoutTools := emptyMatch()
+	withoutTools.HasTools = flag(false)
+
+	if !withTools.Matches(newRequest("claude", toolsBody)) {
+		t.Error("has_tools: true did not match a request with tools")
+	}
+	if withoutTools.Matches(newRequest("claude", toolsBody)) {
+		t.Error("has_tools: false matched a request with tools")
+	}
+	if !withoutTools.Matches(newRequest("claude", `{"messages":[]}`)) {
+		t.Error("has_tools: false did not match a request without tools")
+	}
+}
+
+func TestMatchInputTokens(t *testing.T) {
+	t.Parallel()
+
+	large := emptyMatch()
+	large.MinInputTokens = 1000
+	small := emptyMatch()
+	small.MaxInputTokens = 1000
+
+	short := newRequest("claude", `{"messages":[{"role":"user","content":"hi"}]}`)
+	if large.Matches(short) || !small.Matches(short) {
+		t.Errorf("short request (%d tokens) matched the wrong bound", short.InputTokens())
+	}
+}
+
+func TestMatchTimeOfDay(t *testing.T) {
+	t.Parallel()
+
+	night := emptyMatch()
+	night.TimeOfDay = "22:00-06:00"
+	night.Timezone = "America/New_York"
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 1, 1, 4, 0, 0, 0, time.UTC), true},   // 23:00 in New York
+		{time.Date(2026, 1, 1, 10, 59, 0, 0, time.UTC), true}, // 05:59
+		{time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC), false}, // 06:00, the end is exclusive
+		{time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC), false}, // 13:00
+	}
+	for _, tt := range tests {
+		req := newRequest("claude", "{}")
+		req.Time = tt.at
+		if got := night.Matches(req); got != tt.want {
+			t.Errorf("Matches(%s) = %v, want %v", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	t.Parallel()
+
+	start, end, err := rules.ParseTimeOfDay("09:30 - 17:00")
+	if err != nil || start != 570 || end != 1020 {
+		t.Errorf("ParseTimeOfDay() = %d, %d, %v, want 570, 1020, nil", start, end, err)
+	}
+
+	for _, value := range []string{"9-17", "09:00", "25:00-26:00", "10:00-10:00"} {
+		if _, _, err := rules.ParseTimeOfDay(value); !errors.Is(err, rules.ErrInvalidTimeOfDay) {
+			t.Errorf("ParseTimeOfDay(%q) error = %v, want %v", value, err, rules.ErrInvalidTimeOfDay)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package tokenizer estimates Claude token counts offline.
+//
+// Claude's tokenizer is not public, so counts are approximated with a
+// byte-pair-style heuristic: words are split into sub-word pieces of a few
+// characters, punctuation and symbols are single tokens, and CJK characters
+// count one token each. Estimates are intended for providers without a native
+// count_tokens endpoint and for capacity checks, not for billing.
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// charsPerPiece is the average number of word characters per sub-word token.
+	charsPerPiece = 4
+
+	// messageOverhead covers role markers and separators around each message.
+	messageOverhead = 4
+
+	// requestOverhead covers the fixed framing of every request.
+	requestOverhead = 3
+
+	// toolsOverhead approximates the system prompt Anthropic injects when tools are present.
+	toolsOverhead = 346
+
+	// toolOverhead covers the framing around each tool definition.
+	toolOverhead = 8
+
+	// imageTokens approximates an image at the API's default maximum resolution.
+	imageTokens = 1600
+)
+
+// CountText returns the estimated number of tokens in text.
+func CountText(text string) int {
+	tokens := 0
+	wordLen := 0
+	flushWord := func() {
+		if wordLen > 0 {
+			tokens += (wordLen + charsPerPiece - 1) / charsPerPiece
+			wordLen = 0
+		}
+	}
+
+	prevNewline := false
+	for idx := 0; idx < len(text); {
+		r, size := utf8.DecodeRuneInString(text[idx:])
+		idx += size
+
+		switch {
+		case isCJK(r):
+			flushWord()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			wordLen++
+		case r == '\n':
+			flushWord()
+			// Runs of newlines merge into a single token.
+			if !prevNewline {
+				tokens++
+			}
+		case unicode.IsSpace(r):
+			// Single spaces are absorbed into the following word.
+			flushWord()
+		default:
+			flushWord()
+			tokens++
+		}
+		prevNewline = r == '\n'
+	}
+	flushWord()
+
+	return tokens
+}
+
+// isCJK reports whether r belongs to a script that tokenizes roughly per character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CountRequest estimates the input tokens of an Anthropic Messages request body:
+// system prompt, messages (text, tool calls, tool results, images) and tool definitions.
+func CountRequest(body []byte) int {
+	request := gjson.ParseBytes(body)
+
+	tokens := requestOverhead + countContent(request.Get("system"))
+
+	request.Get("messages").ForEach(func(_, message gjson.Result) bool {
+		tokens += messageOverhead + countContent(message.Get("content"))
+		return true
+	})
+
+	tools := request.Get("tools").Array()
+	if len(tools) > 0 {
+		tokens += toolsOverhead
+	}
+	for _, tool := range tools {
+		tokens += toolOverhead +
+			CountText(tool.Get("name").String()) +
+			CountText(tool.Get("description").String()) +
+			CountText(tool.Get("input_schema").Raw)
+	}
+
+	return tokens
+}
+
+// countContent counts a content field, which is either a string or an array of blocks.
+func countContent(content gjson.Result) int {
+	if content.Type == gjson.String {
+		return CountText(content.String())
+	}
+
+	tokens := 0
+	content.ForEach(func(_, block gjson.Result) bool {
+		tokens += countBlock(block)
+		return true
+	})
+	return tokens
+}
+
+func countBlock(block gjson.Result) int {
+	switch block.Get("type").String() {
+	case "text":
+		return CountText(block.Get("text").String())
+	case "image":
+		return imageTokens
+	case "tool_use":
+		return CountText(block.Get("name").String()) + CountText(block.Get("input").Raw)
+	case "tool_result":
+		return countContent(block.Get("content"))
+	case "thinking", "redacted_thinking":
+		// Thinking from previous turns is stripped by the API and not counted.
+		return 0
+	default:
+		return CountText(block.Raw)
+	}
+}
@@ -0,0 +1,88 @@
+package tokenizer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/omarluq/cc-relay/internal/tokenizer"
+)
+
+func TestCountText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "short words", text: "the cat sat", want: 3},
+		{name: "long word splits into pieces", text: "internationalization", want: 5},
+		{name: "punctuation", text: "Hi, you!", want: 4},
+		{name: "newline runs merge", text: "a\n\n\nb", want: 3},
+		{name: "cjk per character", text: "你好世界", want: 4},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.want, tokenizer.CountText(testCase.text))
+		})
+	}
+}
+
+func TestCountTextScalesWithLength(t *testing.T) {
+	t.Parallel()
+
+	sentence := "The quick brown fox jumps over the lazy dog. "
+	single := tokenizer.CountText(sentence)
+	assert.Equal(t, single*10, tokenizer.CountText(strings.Repeat(sentence, 10)))
+}
+
+func TestCountRequest(t *testing.T) {
+	t.Parallel()
+
+	base := tokenizer.CountRequest([]byte(`{"model":"claude","messages":[{"role":"user","content":"hello"}]}`))
+	assert.Positive(t, base)
+
+	t.Run("system prompt adds tokens", func(t *testing.T) {
+		t.Parallel()
+		withSystem := tokenizer.CountRequest([]byte(`{"system":"You are terse.",` +
+			`"messages":[{"role":"user","content":"hello"}]}`))
+		assert.Greater(t, withSystem, base)
+	})
+
+	t.Run("block content matches string content", func(t *testing.T) {
+		t.Parallel()
+		blocks := tokenizer.CountRequest([]byte(`{"messages":[{"role":"user",` +
+			`"content":[{"type":"text","text":"hello"}]}]}`))
+		assert.Equal(t, base, blocks)
+	})
+
+	t.Run("images use a fixed estimate", func(t *testing.T) {
+		t.Parallel()
+		withImage := tokenizer.CountRequest([]byte(`{"messages":[{"role":"user","content":[` +
+			`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},` +
+			`{"type":"text","text":"hello"}]}]}`))
+		assert.Equal(t, base+1600, withImage)
+	})
+
+	t.Run("tool results and tool definitions are counted", func(t *testing.T) {
+		t.Parallel()
+		withTools := tokenizer.CountRequest([]byte(`{"tools":[{"name":"get_weather",` +
+			`"description":"Get the weather","input_schema":{"type":"object"}}],` +
+			`"messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1",` +
+			`"content":"sunny"}]}]}`))
+		assert.Greater(t, withTools, base+346)
+	})
+
+	t.Run("previous thinking is not counted", func(t *testing.T) {
+		t.Parallel()
+		withThinking := tokenizer.CountRequest([]byte(`{"messages":[{"role":"user","content":[` +
+			`{"type":"thinking","thinking":"long internal reasoning","signature":"sig"},` +
+			`{"type":"text","text":"hello"}]}]}`))
+		assert.Equal(t, base, withThinking)
+	})
+}
@@ -0,0 +1,154 @@
+// Package tracing exports OpenTelemetry traces for the proxy pipeline.
+//
+// The Tracing value owns the SDK tracer provider and starts the server span for
+// each request. Everything below it creates child spans with Start, which uses
+// the tracer of the span already in the context, so code paths run without a
+// server span (tracing disabled, unit tests) only ever see no-op spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/vinfo"
+)
+
+// instrumentationName identifies cc-relay as the instrumentation scope of its spans.
+const instrumentationName = "github.com/omarluq/cc-relay"
+
+// propagator reads and writes W3C traceparent/tracestate headers.
+var propagator = propagation.TraceContext{}
+
+// Tracing owns the tracer provider used for request spans.
+type Tracing struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// New creates a Tracing that batches spans to the OTLP collector configured in cfg.
+// The exporter connects lazily, so an unreachable collector does not fail startup.
+func New(ctx context.Context, cfg *config.TracingConfig) (*Tracing, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP %s exporter: %w", cfg.GetProtocol(), err)
+	}
+	return NewWithProcessor(cfg, sdktrace.NewBatchSpanProcessor(exporter)), nil
+}
+
+// NewWithProcessor creates a Tracing that hands finished spans to processor.
+// Exporter settings in cfg are ignored; it is used for custom pipelines and tests.
+func NewWithProcessor(cfg *config.TracingConfig, processor sdktrace.SpanProcessor) *Tracing {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetSampleRatio()))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(cfg.GetServiceName()),
+			semconv.ServiceVersion(vinfo.Version),
+		)),
+	)
+	return &Tracing{
+		provider: provider,
+		tracer:   provider.Tracer(instrumentationName),
+	}
+}
+
+func newExporter(ctx context.Context, cfg *config.TracingConfig) (sdktrace.SpanExporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+
+	if cfg.GetProtocol() == config.TracingProtocolHTTP {
+		var opts []otlptracehttp.Option
+		switch {
+		case isURL:
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		case cfg.Endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	var opts []otlptracegrpc.Option
+	switch {
+	case isURL:
+		opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+	case cfg.Endpoint != "":
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// StartServer starts the server span for an incoming request. A valid
+// traceparent header from the client makes the span a child of the client's trace.
+// A nil Tracing returns the request context and a no-op span.
+func (t *Tracing) StartServer(request *http.Request) (context.Context, trace.Span) {
+	ctx := request.Context()
+	if t == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	ctx = propagator.Extract(ctx, propagation.HeaderCarrier(request.Header))
+	return t.tracer.Start(ctx, request.Method+" "+request.URL.Path,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(request.Method),
+			semconv.URLPath(request.URL.Path),
+		),
+	)
+}
+
+// Shutdown flushes buffered spans and stops the exporter.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// Start starts a child of the span in ctx using that span's tracer.
+// Without a recording span in ctx the returned span is a no-op.
+func Start(
+	ctx context.Context, name string, attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartClient is like Start for spans that represent an outgoing request.
+func StartClient(
+	ctx context.Context, name string, attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(instrumentationName)
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// Inject writes the traceparent of the span in ctx into header, replacing any
+// value the client sent. Header is left untouched when ctx has no valid span.
+func Inject(ctx context.Context, header http.Header) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,149 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/tracing"
+)
+
+const (
+	clientTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	clientTraceparent = "00-" + clientTraceID + "-00f067aa0ba902b7-01"
+)
+
+func tracingConfig(protocol, endpoint string, sampleRatio float64) *config.TracingConfig {
+	return &config.TracingConfig{
+		Headers:     map[string]string{"x-honeycomb-team": "secret"},
+		Endpoint:    endpoint,
+		Protocol:    protocol,
+		ServiceName: "",
+		SampleRatio: sampleRatio,
+		Enabled:     true,
+		Insecure:    true,
+	}
+}
+
+func newRequest(traceparent string) *http.Request {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/v1/messages", http.NoBody)
+	if traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+	return req
+}
+
+func TestNewCreatesExporters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		protocol string
+		endpoint string
+	}{
+		{name: "grpc default endpoint", protocol: "", endpoint: ""},
+		{name: "grpc host port", protocol: config.TracingProtocolGRPC, endpoint: "127.0.0.1:4317"},
+		{name: "http host port", protocol: config.TracingProtocolHTTP, endpoint: "127.0.0.1:4318"},
+		{name: "http url", protocol: config.TracingProtocolHTTP, endpoint: "http://127.0.0.1:4318/v1/traces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tracer, err := tracing.New(context.Background(), tracingConfig(tt.protocol, tt.endpoint, 0))
+			require.NoError(t, err, "exporters connect lazily")
+			require.NoError(t, tracer.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestStartServerContinuesClientTrace(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := tracing.NewWithProcessor(tracingConfig("", "", 0), recorder)
+
+	_, span := tracer.StartServer(newRequest(clientTraceparent))
+	span.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "POST /v1/messages", ended[0].Name())
+	assert.Equal(t, trace.SpanKindServer, ended[0].SpanKind())
+	assert.Equal(t, clientTraceID, ended[0].SpanContext().TraceID().String())
+	serviceName, ok := ended[0].Resource().Set().Value(attribute.Key("service.name"))
+	require.True(t, ok)
+	assert.Equal(t, config.DefaultTracingServiceName, serviceName.AsString())
+}
+
+func TestSampleRatioHonorsClientDecision(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := tracing.NewWithProcessor(tracingConfig("", "", 0.000001), recorder)
+
+	_, sampled := tracer.StartServer(newRequest(clientTraceparent))
+	sampled.End()
+	_, notSampled := tracer.StartServer(newRequest("00-" + clientTraceID + "-00f067aa0ba902b7-00"))
+	notSampled.End()
+
+	require.Len(t, recorder.Ended(), 1, "only the span the client sampled is recorded")
+	assert.True(t, sampled.SpanContext().IsSampled())
+}
+
+func TestStartCreatesChildSpans(t *testing.T) {
+	t.Parallel()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := tracing.NewWithProcessor(tracingConfig("", "", 0), recorder)
+
+	ctx, server := tracer.StartServer(newRequest(""))
+	_, child := tracing.Start(ctx, "select_key")
+	child.End()
+	_, client := tracing.StartClient(ctx, "upstream")
+	client.End()
+	server.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 3)
+	assert.Equal(t, server.SpanContext().SpanID(), ended[0].Parent().SpanID())
+	assert.Equal(t, trace.SpanKindInternal, ended[0].SpanKind())
+	assert.Equal(t, trace.SpanKindClient, ended[1].SpanKind())
+}
+
+func TestSpansWithoutTracingAreNoop(t *testing.T) {
+	t.Parallel()
+	var tracer *tracing.Tracing
+
+	ctx, server := tracer.StartServer(newRequest(clientTraceparent))
+	assert.False(t, server.IsRecording())
+	_, child := tracing.Start(ctx, "auth")
+	assert.False(t, child.IsRecording())
+	child.End()
+	server.End()
+
+	header := http.Header{}
+	tracing.Inject(ctx, header)
+	assert.Empty(t, header.Get("traceparent"))
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+}
+
+func TestInjectReplacesTraceparent(t *testing.T) {
+	t.Parallel()
+	tracer := tracing.NewWithProcessor(tracingConfig("", "", 0), tracetest.NewSpanRecorder())
+
+	ctx, server := tracer.StartServer(newRequest(clientTraceparent))
+	defer server.End()
+
+	header := http.Header{}
+	header.Set("traceparent", clientTraceparent)
+	tracing.Inject(ctx, header)
+
+	want := "00-" + clientTraceID + "-" + server.SpanContext().SpanID().String() + "-01"
+	assert.Equal(t, want, header.Get("traceparent"))
+}